@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	kmspb "cloud.google.com/go/kms/apiv1/kmspb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// aliceKMS encrypts for alice, by prefixing the plaintext, and denies
+// everyone else
+type aliceKMS struct {
+	kmspb.UnimplementedKeyManagementServiceServer
+}
+
+func (aliceKMS) Encrypt(ctx context.Context, req *kmspb.EncryptRequest) (*kmspb.EncryptResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if principal := md.Get("x-emulator-principal"); len(principal) != 1 || principal[0] != "user:alice@example.com" {
+		st, _ := status.New(codes.PermissionDenied, "permission denied").WithDetails(&errdetails.ErrorInfo{
+			Reason:   "IAM_PERMISSION_DENIED",
+			Metadata: map[string]string{"decision_id": "d7"},
+		})
+		return nil, st.Err()
+	}
+	return &kmspb.EncryptResponse{Name: req.GetName(), Ciphertext: append([]byte("enc:"), req.GetPlaintext()...)}, nil
+}
+
+func TestCall(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, ".config"))
+	t.Chdir(dir)
+	content := "groups:\n  developers:\n    members: [user:alice@example.com, user:bob@example.com]\nprojects:\n  p: {}\n"
+	if err := os.WriteFile("policy.yaml", []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	kmspb.RegisterKeyManagementServiceServer(server, aliceKMS{})
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	port := strconv.Itoa(lis.Addr().(*net.TCPAddr).Port)
+
+	key := "projects/p/locations/global/keyRings/app/cryptoKeys/data"
+	stdout, stderr := runCLI(t, "call", "kms", "encrypt", key, "--data", "hello", "--as", "user:alice@example.com", "--set", "port-kms="+port)
+	if stderr != "" {
+		t.Fatalf("Expected the call to succeed, got %q", stderr)
+	}
+	if want := "✓ Encrypted with " + key + "\n\n  Ciphertext: ZW5jOmhlbGxv\n"; stdout != want {
+		t.Errorf("got\n%s\nwant\n%s", stdout, want)
+	}
+
+	stdout, _ = runCLI(t, "call", "kms", "encrypt", key, "--data", "hello", "--as", "user:alice@example.com", "--set", "port-kms="+port, "-o", "json")
+	if want := "{\n  \"name\": \"" + key + "\",\n  \"ciphertext\": \"ZW5jOmhlbGxv\"\n}\n"; stdout != want {
+		t.Errorf("got\n%s\nwant\n%s", stdout, want)
+	}
+
+	stdout, stderr = runCLI(t, "call", "kms", "encrypt", key, "--data", "hello", "--as", "user:bob@example.com", "--set", "port-kms="+port)
+	for _, want := range []string{"✗ PERMISSION_DENIED: permission denied", "Reason:    IAM_PERMISSION_DENIED", "decision_id: d7", "gcp-emulator explain d7"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected the denial to show %q, got\n%s", want, stdout)
+		}
+	}
+	if stderr != "Error: kms encrypt denied for user:bob@example.com\n" {
+		t.Errorf("Expected a denied error, got %q", stderr)
+	}
+
+	if _, stderr := runCLI(t, "call", "kms", "access", key, "--as", "user:alice@example.com"); stderr != "Error: invalid kms verb: access (must be encrypt, decrypt)\n" {
+		t.Errorf("Expected an invalid verb error, got %q", stderr)
+	}
+}
@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish|powershell",
+	Short: "Generate a shell completion script",
+	Long: `Generate the completion script of gcp-emulator for a shell.
+
+Besides commands and flags, the script completes service names for
+start --services, stop --services, restart, logs, pull, and events;
+config keys for config get, set, and unset; profile names for --profile
+and config use; and role names, from the configured policy file and the
+built-in catalog, for policy roles describe. A policy file that is
+missing or invalid completes no roles rather than printing an error.
+
+To load completions:
+
+Bash:
+  source <(gcp-emulator completion bash)
+  # For every session, on Linux:
+  gcp-emulator completion bash > /etc/bash_completion.d/gcp-emulator
+
+Zsh:
+  gcp-emulator completion zsh > "${fpath[1]}/_gcp-emulator"
+
+Fish:
+  gcp-emulator completion fish > ~/.config/fish/completions/gcp-emulator.fish
+
+PowerShell:
+  gcp-emulator completion powershell | Out-String | Invoke-Expression`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch args[0] {
+		case "bash":
+			return cmd.Root().GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			return cmd.Root().GenZshCompletion(os.Stdout)
+		case "fish":
+			return cmd.Root().GenFishCompletion(os.Stdout, true)
+		default:
+			return cmd.Root().GenPowerShellCompletionWithDesc(os.Stdout)
+		}
+	},
+}
+
+// isCompletion reports whether cmd generates completions: the completion
+// command, or the hidden one the shell scripts call
+func isCompletion(cmd *cobra.Command) bool {
+	switch cmd.Name() {
+	case completionCmd.Name(), cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return true
+	}
+	return false
+}
+
+// completeServices completes the services not given yet, for commands
+// that take services as arguments
+func completeServices(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var services []string
+	for _, service := range docker.Services {
+		if !slices.Contains(args, service) && strings.HasPrefix(service, toComplete) {
+			services = append(services, service)
+		}
+	}
+	return services, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeServiceList completes the comma separated services of a
+// --services flag, leaving out those already listed
+func completeServiceList(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	listed := strings.Split(toComplete, ",")
+	prefix := strings.Join(listed[:len(listed)-1], ",")
+	if prefix != "" {
+		prefix += ","
+	}
+
+	var services []string
+	for _, service := range docker.Services {
+		if !slices.Contains(listed[:len(listed)-1], service) && strings.HasPrefix(service, listed[len(listed)-1]) {
+			services = append(services, prefix+service)
+		}
+	}
+	return services, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// completeConfigKeys completes the first argument with the config keys,
+// each with its description
+func completeConfigKeys(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var keys []string
+	for _, key := range config.Keys() {
+		if strings.HasPrefix(key.Name, toComplete) {
+			keys = append(keys, key.Name+"\t"+key.Description)
+		}
+	}
+	return keys, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProfiles completes the names of the profiles. Profiles that
+// can't be listed complete nothing.
+func completeProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	profiles, _ := config.ListProfiles()
+	return filterPrefix(profiles, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeStacks completes the names of the named stacks. Stacks that
+// can't be listed complete nothing.
+func completeStacks(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	stacks, _ := config.ListStacks()
+	return filterPrefix(stacks, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProfileArg completes the first argument with the names of the
+// profiles
+func completeProfileArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeProfiles(cmd, args, toComplete)
+}
+
+// completeRoles completes the first argument with the roles defined in
+// the configured policy file, then the built-in roles. A policy file
+// that is missing or invalid adds no roles, so the shell never sees an
+// error.
+func completeRoles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	roles := policyRoles()
+	for _, role := range policy.BuiltinRoles() {
+		// A policy may redefine a built-in role
+		if !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	return filterPrefix(roles, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// policyRoles returns the names of the roles defined in the configured
+// policy file, sorted, or none if it can't be loaded
+func policyRoles() []string {
+	// The root hook is skipped for completion, so the config is read
+	// here, with the --config, --profile, and --set of the command line
+	// being completed
+	if err := config.SetOverrides(setFlags); err != nil {
+		return nil
+	}
+	if err := config.Init(); err != nil {
+		return nil
+	}
+	pol, _, err := loadConfiguredPolicy()
+	if err != nil {
+		return nil
+	}
+	roles := make([]string, 0, len(pol.Roles))
+	for name := range pol.Roles {
+		roles = append(roles, name)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// filterPrefix returns the completions that start with toComplete
+func filterPrefix(completions []string, toComplete string) []string {
+	var matched []string
+	for _, completion := range completions {
+		if strings.HasPrefix(completion, toComplete) {
+			matched = append(matched, completion)
+		}
+	}
+	return matched
+}
+
+// completeOutputFormats completes --output
+func completeOutputFormats(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return []string{
+		OutputText + "\tFor people (default)",
+		OutputJSON + "\tAn indented JSON document",
+		OutputYAML + "\tA YAML document",
+	}, cobra.ShellCompDirectiveNoFileComp
+}
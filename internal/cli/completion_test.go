@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+func TestCompleteServiceList(t *testing.T) {
+	tests := []struct {
+		toComplete string
+		want       []string
+	}{
+		{"", []string{"iam", "secret-manager", "kms"}},
+		{"s", []string{"secret-manager"}},
+		{"iam,", []string{"iam,secret-manager", "iam,kms"}},
+		{"iam,k", []string{"iam,kms"}},
+		{"iam,kms,", []string{"iam,kms,secret-manager"}},
+	}
+	for _, tt := range tests {
+		got, _ := completeServiceList(startCmd, nil, tt.toComplete)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("completeServiceList(%q) = %v, want %v", tt.toComplete, got, tt.want)
+		}
+	}
+}
+
+func TestCompleteRoles(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, ".config"))
+	t.Chdir(dir)
+
+	valid := filepath.Join(dir, "policy.yaml")
+	content := "roles:\n  roles/custom.ci:\n    permissions:\n      - secretmanager.secrets.get\nprojects:\n  test-project: {}\n"
+	if err := os.WriteFile(valid, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	invalid := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("roles: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	complete := func(policyFile, toComplete string) []string {
+		t.Helper()
+		t.Setenv("GCP_EMULATOR_POLICY_FILE", policyFile)
+		viper.Reset()
+		roles, _ := completeRoles(policyRolesDescribeCmd, nil, toComplete)
+		return roles
+	}
+
+	if got := complete(valid, "roles/c"); len(got) == 0 || got[0] != "roles/custom.ci" {
+		t.Errorf("Expected the policy's roles first, got %v", got)
+	}
+
+	// A policy file that can't be loaded completes the built-in roles only
+	builtins := policy.BuiltinRoles()
+	for _, policyFile := range []string{filepath.Join(dir, "missing.yaml"), invalid} {
+		if got := complete(policyFile, ""); !slices.Equal(got, builtins) {
+			t.Errorf("%s: expected only the built-in roles, got %v", filepath.Base(policyFile), got)
+		}
+	}
+
+	if got := complete(valid, "roles/secretmanager.admin"); !slices.Equal(got, []string{"roles/secretmanager.admin"}) {
+		t.Errorf("Expected the completions to match the prefix, got %v", got)
+	}
+	if got, _ := completeRoles(policyRolesDescribeCmd, []string{"roles/owner"}, ""); got != nil {
+		t.Errorf("Expected no completions after the role, got %v", got)
+	}
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+)
+
+var composeCmd = &cobra.Command{
+	Use:   "compose",
+	Short: "Inspect the compose file the stack runs with",
+	Long: `The stack runs from a compose file generated from the config: ports,
+images, IAM mode, policy file, and network. It is written to
+compose/<project>.yaml in the state directory (~/.local/state/gcp-emulator)
+before each docker compose command, so the CLI works from any directory.
+
+Set compose-file, or pass --compose-file, to use a compose file of your
+own instead. To keep the generated file but add to it, such as a sidecar
+service or extra volumes, list override files in compose-overrides, or
+pass --compose-override for each: they are passed to compose as further
+-f files after the base file, so compose's usual merge rules apply.
+Relative paths in them are resolved against the directory of the first
+override. An override that changes a service that doesn't exist, or
+refers to one, is an error before compose is run.`,
+}
+
+var composeRenderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Print the generated compose file",
+	Long: `Print the compose file generated from the config, for the ports the
+stack is running on (or the configured ports if it isn't running).
+
+With --merged, print the effective config compose runs the stack with
+instead: the base file with the compose-overrides merged over it, as
+resolved by 'docker compose config'.`,
+	Example: `  gcp-emulator compose render
+  gcp-emulator --set port-kms=19091 compose render
+  gcp-emulator compose render > docker-compose.override.yml
+  gcp-emulator --compose-override metadata.yaml compose render --merged`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		merged, _ := cmd.Flags().GetBool("merged")
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		if merged {
+			data, err := docker.RenderMerged(cfg, docker.ActivePorts(cfg))
+			if err != nil {
+				return err
+			}
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+
+		data, err := docker.RenderCompose(cfg, docker.ActivePorts(cfg))
+		if err != nil {
+			return err
+		}
+		if cfg.Docker.ComposeFile != "" {
+			color.New(color.FgYellow).Fprintf(os.Stderr, "⚠ compose-file is set, so the stack runs with %s instead of this file\n", cfg.Docker.ComposeFile)
+		}
+		if len(cfg.Docker.ComposeOverrides) > 0 {
+			color.New(color.FgYellow).Fprintf(os.Stderr, "⚠ compose-overrides are set, so the stack runs with them merged over this file; see --merged\n")
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	},
+}
+
+func init() {
+	composeRenderCmd.Flags().Bool("merged", false, "Print the effective config, with compose-overrides merged in, from docker compose config")
+	composeCmd.AddCommand(composeRenderCmd)
+	rootCmd.AddCommand(composeCmd)
+}
@@ -2,6 +2,8 @@ package cli
 
 import (
 	"fmt"
+	"os"
+	"text/tabwriter"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -12,41 +14,184 @@ import (
 var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Configuration management",
-	Long:  `Get, set, or reset configuration values.`,
+	Long:  `Get, set, unset, list, or reset configuration values.`,
 }
 
 var configGetCmd = &cobra.Command{
-	Use:   "get [key]",
-	Short: "Get configuration values",
+	Use:         "get [key]",
+	Short:       "Get configuration values",
+	Annotations: renders,
 	Long: `Display configuration values.
 
-Without arguments, shows all configuration.
-Specify a key to show only that value.`,
+Without arguments, shows all configuration and where each value comes
+from. Specify a key to show only that value, and --show-source to also
+show where it comes from. --output json and yaml always include the
+source.
+
+Values are taken, highest first, from --set, flags, GCP_EMULATOR_*
+environment variables, the nearest .gcp-emulator.yaml in the current
+directory or a parent, the active profile, the config file, and the
+defaults.`,
+	Example: `  gcp-emulator config get
+  gcp-emulator config get iam-mode
+  gcp-emulator config get policy-file --show-source
+  gcp-emulator config get --output json
+  gcp-emulator --set port-kms=19091 config get port-kms --show-source`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeConfigKeys,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		display, err := config.Display()
+		if len(args) == 0 {
+			var report configReport
+			var display string
+			var err error
+			if structuredOutput() {
+				report, err = newConfigReport()
+			} else {
+				display, err = config.Display()
+			}
+			if err != nil {
+				return err
+			}
+			return render(report, func() { fmt.Print(display) })
+		}
+
+		key, err := config.LookupKey(args[0])
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Load()
 		if err != nil {
 			return err
 		}
 
-		fmt.Print(display)
-		return nil
+		report := configKeyReport{Key: key.Name, Value: key.Get(cfg)}
+		var source config.Source
+		if configGetShowSource || structuredOutput() {
+			if source, err = config.SourceOf(key.Name); err != nil {
+				return err
+			}
+			report.Source = newSourceReport(source)
+		}
+		return render(report, func() {
+			if !configGetShowSource {
+				fmt.Println(report.Value)
+				return
+			}
+			fmt.Printf("%s\t(%s)\n", report.Value, source)
+		})
+	},
+}
+
+// configReport is what config get --output json and yaml print without a
+// key. Scripts parse it, so fields are only ever added.
+type configReport struct {
+	// Profile is the active profile, if any
+	Profile string            `json:"profile,omitempty"`
+	Keys    []configKeyReport `json:"keys"`
+}
+
+// configKeyReport is a config key as config get and list print it. The
+// value is as config get prints it, so lists are comma separated.
+type configKeyReport struct {
+	Key         string        `json:"key"`
+	Value       string        `json:"value"`
+	Description string        `json:"description,omitempty"`
+	Source      *sourceReport `json:"source,omitempty"`
+}
+
+// sourceReport is where a key's value comes from
+type sourceReport struct {
+	// Kind is --set, flag, environment, local file, profile, config
+	// file, or default
+	Kind string `json:"kind"`
+
+	// Name is the --set key=value, flag, environment variable, or file
+	Name string `json:"name,omitempty"`
+}
+
+func newSourceReport(source config.Source) *sourceReport {
+	return &sourceReport{Kind: string(source.Kind), Name: source.Name}
+}
+
+// newConfigReport returns every config key with its value and source
+func newConfigReport() (configReport, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return configReport{}, err
+	}
+	report := configReport{Profile: cfg.Profile, Keys: []configKeyReport{}}
+	for _, key := range config.Keys() {
+		source, err := config.SourceOf(key.Name)
+		if err != nil {
+			return configReport{}, err
+		}
+		report.Keys = append(report.Keys, configKeyReport{
+			Key:    key.Name,
+			Value:  key.Get(cfg),
+			Source: newSourceReport(source),
+		})
+	}
+	return report, nil
+}
+
+var (
+	configGetShowSource bool
+	configSetFromFile   bool
+)
+
+var configListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "List configuration keys and their values",
+	Annotations: renders,
+	Args:        cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		keys := []configKeyReport{}
+		for _, key := range config.Keys() {
+			keys = append(keys, configKeyReport{Key: key.Name, Value: key.Get(cfg), Description: key.Description})
+		}
+		return render(keys, func() {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "KEY\tVALUE\tDESCRIPTION")
+			for _, key := range keys {
+				value := key.Value
+				if value == "" {
+					value = "(none)"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", key.Key, value, key.Description)
+			}
+			w.Flush()
+		})
 	},
 }
 
 var configSetCmd = &cobra.Command{
 	Use:   "set <key> <value>",
 	Short: "Set a configuration value",
-	Long: `Set a configuration value and save to config file.
-
-Available keys:
-  iam-mode         IAM mode (off|permissive|strict)
-  trace            Enable trace logging (true|false)
-  pull-on-start    Pull images before starting (true|false)
-  policy-file      Path to policy.yaml`,
-	Args: cobra.ExactArgs(2),
+	Long: `Set a configuration value and save to config file. With a profile
+active, the value is saved to the profile instead.
+
+The value is checked before anything is written, so an invalid mode or
+port leaves the file unchanged. Run 'gcp-emulator config list' for the
+available keys.
+
+With --from-file, the second argument is a file the value is read from,
+without its trailing newline, so values needn't be quoted for the shell.`,
+	Example: `  gcp-emulator config set iam-mode strict
+  gcp-emulator config set port-iam 18080
+  gcp-emulator config set lint.disable GCP001,GCP004
+  gcp-emulator config set --from-file health-url-iam /run/secrets/iam-health-url`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeConfigKeys,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		key := args[0]
-		value := args[1]
+		key, err := config.LookupKey(args[0])
+		if err != nil {
+			return err
+		}
 
 		// Load current config
 		cfg, err := config.Load()
@@ -54,53 +199,71 @@ Available keys:
 			return err
 		}
 
-		// Update based on key
-		switch key {
-		case "iam-mode":
-			if value != "off" && value != "permissive" && value != "strict" {
-				return fmt.Errorf("invalid iam-mode: %s (must be off, permissive, or strict)", value)
+		value := args[1]
+		if configSetFromFile {
+			if value, err = config.ReadValueFile(args[1]); err != nil {
+				return err
 			}
-			cfg.IAMMode = value
-		case "trace":
-			cfg.Trace = value == "true"
-		case "pull-on-start":
-			cfg.PullOnStart = value == "true"
-		case "policy-file":
-			cfg.PolicyFile = value
-		default:
-			return fmt.Errorf("unknown config key: %s", key)
 		}
 
-		// Save
-		if err := config.Save(cfg); err != nil {
-			return fmt.Errorf("failed to save config: %w", err)
+		if err := key.Set(cfg, value); err != nil {
+			return err
 		}
 
-		color.Green("✓ Configuration updated")
-		fmt.Printf("\n%s: %s\n", key, value)
-		color.Cyan("\nRestart the stack for changes to take effect:")
-		color.Cyan("  gcp-emulator restart")
+		return saveConfig(cfg, key)
+	},
+}
 
-		return nil
+var configUnsetCmd = &cobra.Command{
+	Use:               "unset <key>",
+	Short:             "Reset a configuration value to its default",
+	Example:           `  gcp-emulator config unset pull-on-start`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeConfigKeys,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, err := config.LookupKey(args[0])
+		if err != nil {
+			return err
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		key.Unset(cfg)
+		return saveConfig(cfg, key)
 	},
 }
 
+// saveConfig validates and saves cfg after key was changed
+func saveConfig(cfg *config.Config, key config.Key) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	if err := config.SaveKey(cfg, key); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	color.Green("✓ Configuration updated")
+	fmt.Printf("\n%s: %s\n", key.Name, key.Get(cfg))
+	if source, err := config.SourceOf(key.Name); err == nil && source.Kind == config.SourceLocal {
+		color.Yellow("⚠ %s is overridden by %s in this directory", key.Name, source.Name)
+	}
+	info("\nRestart the stack for changes to take effect:")
+	info("  gcp-emulator restart")
+
+	return nil
+}
+
 var configResetCmd = &cobra.Command{
 	Use:   "reset",
 	Short: "Reset configuration to defaults",
 	Long:  `Reset all configuration values to their defaults.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg := &config.Config{
-			IAMMode:     "permissive",
-			Trace:       false,
-			PullOnStart: false,
-			PolicyFile:  "./policy.yaml",
-			Ports: config.PortConfig{
-				IAM:           8080,
-				SecretManager: 9090,
-				KMS:           9091,
-			},
-		}
+		cfg := config.Defaults()
+		cfg.Profile = config.ActiveProfile()
 
 		if err := config.Save(cfg); err != nil {
 			return fmt.Errorf("failed to save config: %w", err)
@@ -112,7 +275,12 @@ var configResetCmd = &cobra.Command{
 }
 
 func init() {
+	configGetCmd.Flags().BoolVar(&configGetShowSource, "show-source", false, "Also show where the value comes from")
+	configSetCmd.Flags().BoolVar(&configSetFromFile, "from-file", false, "Read the value from the file given instead of the value")
+
 	configCmd.AddCommand(configGetCmd)
 	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configListCmd)
 	configCmd.AddCommand(configResetCmd)
 }
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+var (
+	configInitInteractive bool
+	configInitForce       bool
+)
+
+// configInitPrompts are the keys --interactive asks for
+var configInitPrompts = []string{"iam-mode", "port-iam", "port-secret-manager", "port-kms"}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a config file with the defaults",
+	Long: `Write a commented config file with the default IAM mode, ports,
+policy file, and trace settings, to ~/.config/gcp-emulator/config.yaml
+(or the file named by --config).
+
+With --interactive, the IAM mode and ports are prompted for, with the
+defaults offered. An existing file is only replaced with --force.`,
+	Example: `  gcp-emulator config init
+  gcp-emulator config init --interactive
+  gcp-emulator --config ./ci/emulator-config.yaml config init --force`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := config.InitPath()
+		if err != nil {
+			return err
+		}
+		if !configInitForce {
+			if _, err := os.Stat(path); err == nil {
+				return fmt.Errorf("config file %s already exists (use --force to overwrite)", path)
+			}
+		}
+
+		cfg := config.Defaults()
+		if configInitInteractive {
+			if err := promptConfig(cfg); err != nil {
+				return err
+			}
+		}
+
+		if err := config.WriteInitFile(path, cfg, configInitForce); err != nil {
+			return err
+		}
+
+		color.Green("✓ Created %s", path)
+		fmt.Println("\nChange a value with:")
+		fmt.Println("  gcp-emulator config set iam-mode strict")
+		return nil
+	},
+}
+
+// promptConfig asks for each of configInitPrompts, offering cfg's value,
+// and asks again until the answer is valid
+func promptConfig(cfg *config.Config) error {
+	reader := bufio.NewReader(os.Stdin)
+	for _, name := range configInitPrompts {
+		key, err := config.LookupKey(name)
+		if err != nil {
+			return err
+		}
+
+		for {
+			answer, err := prompt(reader, key.Description, key.Get(cfg))
+			if err != nil {
+				return err
+			}
+			if err := key.Set(cfg, answer); err != nil {
+				color.Red("✗ %v", err)
+				continue
+			}
+			if err := cfg.Validate(); err != nil {
+				color.Red("✗ %v", err)
+				key.Unset(cfg)
+				continue
+			}
+			break
+		}
+	}
+	return nil
+}
+
+func init() {
+	configInitCmd.Flags().BoolVarP(&configInitInteractive, "interactive", "i", false, "Prompt for the IAM mode and ports")
+	configInitCmd.Flags().BoolVarP(&configInitForce, "force", "f", false, "Overwrite an existing config file")
+	configCmd.AddCommand(configInitCmd)
+}
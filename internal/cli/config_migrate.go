@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+var configMigrateDryRun bool
+
+var configMigratePathsCmd = &cobra.Command{
+	Use:   "migrate-paths",
+	Short: "Move configuration out of ~/.gcp-emulator",
+	Long: `Move the config file, profiles, and state from the legacy ~/.gcp-emulator
+directory to the XDG locations:
+
+  config.yaml, profiles/  →  $XDG_CONFIG_HOME/gcp-emulator (~/.config/gcp-emulator)
+  state.json              →  $XDG_STATE_HOME/gcp-emulator (~/.local/state/gcp-emulator)
+
+On macOS and Windows both go to gcp-emulator in the user config directory.
+Files that already exist at the new location are left in place. Until they
+are moved, files in ~/.gcp-emulator are still read.`,
+	Example: `  gcp-emulator config migrate-paths --dry-run
+  gcp-emulator config migrate-paths`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		moves, err := config.MigratePaths(configMigrateDryRun)
+		for _, move := range moves {
+			switch {
+			case move.Skipped != "":
+				color.Yellow("⚠ Skipped %s: %s at %s", move.From, move.Skipped, move.To)
+			case configMigrateDryRun:
+				color.Cyan("→ Would move %s to %s", move.From, move.To)
+			default:
+				color.Green("✓ Moved %s to %s", move.From, move.To)
+			}
+		}
+		if err != nil {
+			color.Red("✗ Migration failed: %v", err)
+			return err
+		}
+
+		if len(moves) == 0 {
+			legacy, _ := config.LegacyDir()
+			fmt.Printf("Nothing to migrate in %s\n", legacy)
+		}
+		return nil
+	},
+}
+
+func init() {
+	configMigratePathsCmd.Flags().BoolVar(&configMigrateDryRun, "dry-run", false, "Show what would move without moving anything")
+	configCmd.AddCommand(configMigratePathsCmd)
+}
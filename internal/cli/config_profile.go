@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+var configProfilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "Manage named configuration profiles",
+	Long: `Manage named profiles for running several stacks side by side, such as
+one for unit tests and one mirroring staging.
+
+Each profile is a config file in ~/.config/gcp-emulator/profiles/<name>.yaml
+whose values override the main config file. Select one per command with
+--profile, with GCP_EMULATOR_PROFILE, or for every command with
+'gcp-emulator config use <name>'. Each profile runs as its own compose
+project, gcp-emulator-<name>, so stacks for different profiles don't
+collide; give them different ports.`,
+}
+
+var configProfilesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names, err := config.ListProfiles()
+		if err != nil {
+			return err
+		}
+
+		active := config.ActiveProfile()
+		printProfile := func(name string, current bool) {
+			if current {
+				color.Green("* %s", name)
+			} else {
+				fmt.Printf("  %s\n", name)
+			}
+		}
+
+		printProfile(config.DefaultProfile, active == "")
+		for _, name := range names {
+			printProfile(name, name == active)
+		}
+		if active != "" && !slices.Contains(names, active) {
+			color.Yellow("\n⚠ Active profile %s does not exist", active)
+		}
+		return nil
+	},
+}
+
+var configProfilesCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a profile from the current configuration",
+	Long: `Create a profile holding the current configuration values, then
+adjust it with 'gcp-emulator --profile <name> config set'.`,
+	Example: `  gcp-emulator config profiles create staging
+  gcp-emulator --profile staging config set iam-mode strict`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		if err := config.CreateProfile(name, cfg); err != nil {
+			color.Red("✗ Failed to create profile: %v", err)
+			return err
+		}
+
+		path, _ := config.ProfilePath(name)
+		color.Green("✓ Created profile %s (%s)", name, path)
+		fmt.Printf("  Use it with: gcp-emulator --profile %s start\n", name)
+		return nil
+	},
+}
+
+var configProfilesDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a profile",
+	Long: `Delete a profile. Stop its stack first with
+'gcp-emulator --profile <name> stop'; the active profile cannot be deleted.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProfileArg,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if name == config.ActiveProfile() {
+			return fmt.Errorf("profile %s is active (switch with gcp-emulator config use %s first)", name, config.DefaultProfile)
+		}
+
+		if err := config.DeleteProfile(name); err != nil {
+			color.Red("✗ Failed to delete profile: %v", err)
+			return err
+		}
+
+		color.Green("✓ Deleted profile %s", name)
+		return nil
+	},
+}
+
+var configUseCmd = &cobra.Command{
+	Use:   "use <profile>",
+	Short: "Set the active profile",
+	Long: `Set the profile used by later commands, recording it in the config
+file. Use 'default' to go back to the configuration without a profile.
+--profile and GCP_EMULATOR_PROFILE still override it.`,
+	Example: `  gcp-emulator config use staging
+  gcp-emulator config use default`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProfileArg,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+
+		if err := config.UseProfile(name); err != nil {
+			color.Red("✗ Failed to switch profile: %v", err)
+			return err
+		}
+
+		color.Green("✓ Active profile: %s", name)
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configProfilesCmd)
+	configCmd.AddCommand(configUseCmd)
+
+	configProfilesCmd.AddCommand(configProfilesListCmd)
+	configProfilesCmd.AddCommand(configProfilesCreateCmd)
+	configProfilesCmd.AddCommand(configProfilesDeleteCmd)
+}
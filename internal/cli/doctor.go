@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/doctor"
+)
+
+// doctorInitErr is the error from initializing the configuration, which
+// doctor reports rather than failing on
+var doctorInitErr error
+
+var doctorCmd = &cobra.Command{
+	Use:         "doctor",
+	Short:       "Diagnose the local setup",
+	Annotations: renders,
+	Long: `Check everything the emulator stack needs and report pass, warn, or
+fail for each, with a hint for fixing problems:
+
+  Config file     the config file is readable and valid
+  Docker daemon   docker is installed and the daemon is reachable
+  Docker compose  the compose v2 plugin or docker-compose is installed
+  Images          the configured images can be pulled (with --network)
+  Ports           the configured ports are free, or held by the stack
+  Policy file     the policy file exists and is valid
+
+Exits with status 1 if any check fails. Warnings don't affect the exit
+status.`,
+	Example: `  gcp-emulator doctor
+  gcp-emulator doctor --network
+  gcp-emulator doctor --output json`,
+	Args: cobra.NoArgs,
+	// Replaces the root hook, so a broken config file is reported instead
+	// of stopping doctor before it runs
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupOutput(cmd); err != nil {
+			return err
+		}
+		if err := setupLogging(); err != nil {
+			return err
+		}
+		if err := config.SetOverrides(setFlags); err != nil {
+			return err
+		}
+		doctorInitErr = config.Init()
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		network, _ := cmd.Flags().GetBool("network")
+
+		cfg, err := config.Defaults(), doctorInitErr
+		if err == nil {
+			var loaded *config.Config
+			if loaded, err = config.Load(); err == nil {
+				cfg = loaded
+			}
+		}
+
+		report := doctor.Run(doctor.Checks(cfg, err, doctor.Options{Network: network, PolicyLoad: policyLoadOptions()}))
+
+		if err := render(report, func() { printDoctorReport(report) }); err != nil {
+			return err
+		}
+
+		if report.Failed() {
+			return fmt.Errorf("%d check(s) failed", report.Count(doctor.Fail))
+		}
+		return nil
+	},
+}
+
+func printDoctorReport(report doctor.Report) {
+	for _, result := range report.Results {
+		switch result.Status {
+		case doctor.Pass:
+			color.Green("✓ %-16s %s", result.Name, result.Message)
+		case doctor.Warn:
+			color.Yellow("⚠ %-16s %s", result.Name, result.Message)
+		default:
+			color.Red("✗ %-16s %s", result.Name, result.Message)
+		}
+		if result.Hint != "" && result.Status != doctor.Pass {
+			color.Cyan("  → %s", result.Hint)
+		}
+	}
+
+	fmt.Printf("\n%d passed, %d warning(s), %d failed\n",
+		report.Count(doctor.Pass), report.Count(doctor.Warn), report.Count(doctor.Fail))
+}
+
+func init() {
+	doctorCmd.Flags().Bool("network", false, "Also check that the configured images can be pulled")
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+)
+
+// envVar is an environment variable client libraries read
+type envVar struct {
+	name, value string
+}
+
+// clientEnv returns the variables pointing client libraries at the stack
+// on ports, and at project when it isn't ""
+func clientEnv(cfg *config.Config, ports docker.Ports, project string) []envVar {
+	vars := []envVar{
+		{"SECRET_MANAGER_EMULATOR_HOST", cfg.Docker.Address(ports.SecretManager)},
+		{"KMS_EMULATOR_HOST", cfg.Docker.Address(ports.KMS)},
+		{"IAM_EMULATOR_HOST", cfg.Docker.Address(ports.IAM)},
+	}
+	if project != "" {
+		vars = append(vars, envVar{"GOOGLE_CLOUD_PROJECT", project})
+	}
+	return vars
+}
+
+// envShells format an export for each --shell of env
+var envShells = map[string]func(v envVar) string{
+	"bash":       func(v envVar) string { return "export " + v.name + "=" + shellQuote(v.value) },
+	"fish":       func(v envVar) string { return "set -gx " + v.name + " " + shellQuote(v.value) + ";" },
+	"powershell": func(v envVar) string { return "$Env:" + v.name + " = '" + strings.ReplaceAll(v.value, "'", "''") + "'" },
+	"dotenv":     func(v envVar) string { return v.name + "=" + dotenvQuote(v.value) },
+}
+
+// shellSafe matches values a POSIX shell or fish reads as they are
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]+$`)
+
+// shellQuote quotes s for a POSIX shell or fish, unless it needs none
+func shellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// dotenvQuote double quotes s for a .env file, unless it needs none
+func dotenvQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return strconv.Quote(s)
+}
+
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Print environment exports pointing client libraries at the stack",
+	Long: `Print the environment variables client libraries need to reach the
+stack, as exports to eval before running an app:
+
+  SECRET_MANAGER_EMULATOR_HOST  Secret Manager gRPC endpoint
+  KMS_EMULATOR_HOST             KMS gRPC endpoint
+  IAM_EMULATOR_HOST             IAM gRPC endpoint
+  GOOGLE_CLOUD_PROJECT          The project key, when set
+
+Endpoints use the host key and the ports the stack was started on, or
+the configured ports when it isn't running, so they follow automatic
+ports, profiles, and a remote host.
+
+--shell bash's exports also work in zsh and sh.`,
+	Example: `  eval "$(gcp-emulator env)"
+  gcp-emulator env --shell fish | source
+  gcp-emulator env --shell powershell | Invoke-Expression
+  gcp-emulator env --shell dotenv > .env`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		shell, _ := cmd.Flags().GetString("shell")
+		format, ok := envShells[shell]
+		if !ok {
+			return fmt.Errorf("invalid shell: %s (must be bash, fish, powershell, or dotenv)", shell)
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		for _, v := range clientEnv(cfg, docker.ActivePorts(cfg), cfg.Project) {
+			fmt.Println(format(v))
+		}
+		return nil
+	},
+}
+
+func init() {
+	envCmd.Flags().String("shell", "bash", "Syntax of the exports (bash|fish|powershell|dotenv)")
+	_ = envCmd.RegisterFlagCompletionFunc("shell", cobra.FixedCompletions([]string{"bash", "fish", "powershell", "dotenv"}, cobra.ShellCompDirectiveNoFileComp))
+}
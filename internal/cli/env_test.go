@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, ".config"))
+	t.Chdir(dir)
+
+	tests := []struct {
+		shell string
+		want  string
+	}{
+		{"bash", `export SECRET_MANAGER_EMULATOR_HOST=devvm.internal:9090
+export KMS_EMULATOR_HOST=devvm.internal:19091
+export IAM_EMULATOR_HOST=devvm.internal:8080
+export GOOGLE_CLOUD_PROJECT='my proj'
+`},
+		{"fish", `set -gx SECRET_MANAGER_EMULATOR_HOST devvm.internal:9090;
+set -gx KMS_EMULATOR_HOST devvm.internal:19091;
+set -gx IAM_EMULATOR_HOST devvm.internal:8080;
+set -gx GOOGLE_CLOUD_PROJECT 'my proj';
+`},
+		{"powershell", `$Env:SECRET_MANAGER_EMULATOR_HOST = 'devvm.internal:9090'
+$Env:KMS_EMULATOR_HOST = 'devvm.internal:19091'
+$Env:IAM_EMULATOR_HOST = 'devvm.internal:8080'
+$Env:GOOGLE_CLOUD_PROJECT = 'my proj'
+`},
+		{"dotenv", `SECRET_MANAGER_EMULATOR_HOST=devvm.internal:9090
+KMS_EMULATOR_HOST=devvm.internal:19091
+IAM_EMULATOR_HOST=devvm.internal:8080
+GOOGLE_CLOUD_PROJECT="my proj"
+`},
+	}
+	for _, tt := range tests {
+		stdout, stderr := runCLI(t, "env", "--shell", tt.shell, "--set", "host=devvm.internal", "--set", "port-kms=19091", "--set", "project=my proj")
+		if stderr != "" {
+			t.Fatalf("%s: env failed: %s", tt.shell, stderr)
+		}
+		if stdout != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.shell, stdout, tt.want)
+		}
+	}
+
+	// Without a project, GOOGLE_CLOUD_PROJECT is left alone
+	stdout, _ := runCLI(t, "env", "--shell", "dotenv", "--set", "project=")
+	if want := "SECRET_MANAGER_EMULATOR_HOST=localhost:9090\nKMS_EMULATOR_HOST=localhost:9091\nIAM_EMULATOR_HOST=localhost:8080\n"; stdout != want {
+		t.Errorf("got\n%s\nwant\n%s", stdout, want)
+	}
+
+	if _, stderr := runCLI(t, "env", "--shell", "tcsh"); stderr != "Error: invalid shell: tcsh (must be bash, fish, powershell, or dotenv)\n" {
+		t.Errorf("Expected an invalid shell error, got %q", stderr)
+	}
+}
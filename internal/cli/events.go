@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+)
+
+var (
+	eventsFollow bool
+	eventsSince  string
+)
+
+var eventsCmd = &cobra.Command{
+	Use:         "events [service...]",
+	Short:       "Show lifecycle events of the stack's containers",
+	Annotations: renders,
+	Long: `Show when the stack's containers started, stopped, were killed, died
+with their exit code, ran out of memory, or changed health, so a service
+that went down can be explained after the fact.
+
+Only the containers of the stack's compose project are shown. Without
+--follow, events of the last --since (default 1h) are shown; with it, new
+events are shown until Ctrl-C, after those since --since if given.
+Specify service names to show only theirs.
+
+--output json prints each event as a JSON object on its own line, and
+--output yaml as a YAML document of its own.
+
+Services: iam, secret-manager, kms`,
+	Example: `  gcp-emulator events
+  gcp-emulator events --follow --since 1h
+  gcp-emulator events kms --since 24h
+  gcp-emulator events --follow --output json`,
+	ValidArgsFunction: completeServices,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		services := make([]string, 0, len(args))
+		for _, arg := range args {
+			service, err := docker.LookupService(arg)
+			if err != nil {
+				return err
+			}
+			services = append(services, service)
+		}
+
+		// Ctrl-C stops following without an error
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		shown := 0
+		show := func(event docker.Event) error {
+			shown++
+			return printEvent(event)
+		}
+		if structuredOutput() {
+			show = func(event docker.Event) error { return renderStream(os.Stdout, newEventReport(event)) }
+		}
+		err = docker.StreamEvents(ctx, cfg, services, docker.EventOptions{Follow: eventsFollow, Since: eventsSince}, show)
+		if err != nil {
+			color.Red("✗ Failed to get events: %v", err)
+			printDockerHint(err)
+			return err
+		}
+		if shown == 0 && !eventsFollow && !structuredOutput() {
+			since := eventsSince
+			if since == "" {
+				since = "1h"
+			}
+			if _, err := time.ParseDuration(since); err == nil {
+				fmt.Printf("No events in the last %s\n", since)
+			} else {
+				fmt.Printf("No events since %s\n", since)
+			}
+		}
+		return nil
+	},
+}
+
+// eventReport is an event as events --output json prints it. Scripts
+// parse it, so fields are only ever added.
+type eventReport struct {
+	Time      time.Time `json:"time"`
+	Service   string    `json:"service"`
+	Container string    `json:"container"`
+
+	// Action is start, restart, stop, kill, die, oom, or health_status
+	Action string `json:"action"`
+
+	ExitCode *int   `json:"exitCode,omitempty"`
+	Signal   string `json:"signal,omitempty"`
+	Health   string `json:"health,omitempty"`
+}
+
+func newEventReport(event docker.Event) eventReport {
+	return eventReport{
+		Time:      event.Time,
+		Service:   event.Service,
+		Container: event.Container,
+		Action:    event.Action,
+		ExitCode:  event.ExitCode,
+		Signal:    event.Signal,
+		Health:    event.Health,
+	}
+}
+
+// printEvent shows an event as a line, colored by what it means for the
+// service
+func printEvent(event docker.Event) error {
+	line := fmt.Sprintf("%s  %-14s  %-13s  %s", event.Time.Local().Format("2006-01-02 15:04:05"), event.Service, event.Action, eventDetail(event))
+	line = strings.TrimRight(line, " ")
+	switch {
+	case event.Action == "start" || event.Action == "restart" || event.Health == "healthy":
+		color.Green("%s", line)
+	case event.Action == "stop" || event.Health == "starting":
+		color.Yellow("%s", line)
+	default:
+		color.Red("%s", line)
+	}
+	return nil
+}
+
+// eventDetail describes what an event tells of the container: the code
+// it died with, the signal it was sent, or what its health became
+func eventDetail(event docker.Event) string {
+	switch event.Action {
+	case "die":
+		if event.ExitCode == nil {
+			return ""
+		}
+		detail := fmt.Sprintf("exit code %d", *event.ExitCode)
+		if *event.ExitCode == 137 {
+			detail += " (killed: SIGKILL or out of memory)"
+		}
+		return detail
+	case "oom":
+		return "out of memory"
+	case "kill":
+		if event.Signal != "" {
+			return "signal " + event.Signal
+		}
+	case "health_status":
+		return event.Health
+	}
+	return ""
+}
+
+func init() {
+	eventsCmd.Flags().BoolVarP(&eventsFollow, "follow", "f", false, "Show new events until interrupted")
+	eventsCmd.Flags().StringVar(&eventsSince, "since", "", "Show events since timestamp (e.g. 10m, 1h); default 1h without --follow")
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"errors"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/upgrade"
+)
+
+// Exit codes, so scripts can tell failures apart
+const (
+	ExitOK     = 0
+	ExitError  = 1 // unexpected error, or policy simulate denied the request
+	ExitConfig = 2 // config file not found or malformed, or an invalid value
+	ExitDocker = 3 // docker command failed, a port is in use, an image couldn't be pulled, or a service exited or stayed unhealthy on start
+	ExitPolicy = 4 // policy file missing, malformed, or invalid
+	ExitDaemon = 5 // docker daemon not running or not reachable
+
+	ExitUpdates = 10 // upgrade --check found updates
+
+	// run exits with the code of its command when that fails
+)
+
+// exitCode returns the exit code for an error returned by a command
+func exitCode(err error) int {
+	var commandErr *docker.CommandError
+	var portErr *docker.PortInUseError
+	var exitedErr *docker.ExitedError
+	var unhealthyErr *docker.UnhealthyError
+	var digestErr *docker.DigestMismatchError
+	var childErr *ChildExitError
+
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &childErr):
+		return childErr.Code
+	case errors.Is(err, upgrade.ErrUpdatesAvailable):
+		return ExitUpdates
+	case errors.Is(err, policy.ErrDenied):
+		return ExitError
+	case errors.Is(err, config.ErrNoPolicyFile), errors.Is(err, policy.ErrInvalidPolicy):
+		return ExitPolicy
+	case errors.Is(err, config.ErrInvalidMode),
+		errors.Is(err, config.ErrInvalidPort),
+		errors.Is(err, config.ErrInvalidValue),
+		errors.Is(err, config.ErrConfigNotFound),
+		errors.Is(err, config.ErrConfigParse):
+		return ExitConfig
+	case errors.Is(err, docker.ErrDaemonUnreachable):
+		return ExitDaemon
+	case errors.As(err, &commandErr),
+		errors.As(err, &portErr),
+		errors.As(err, &exitedErr),
+		errors.As(err, &unhealthyErr),
+		errors.As(err, &digestErr),
+		errors.Is(err, docker.ErrImageNotPresent):
+		return ExitDocker
+	default:
+		return ExitError
+	}
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/upgrade"
+)
+
+func TestExitCode(t *testing.T) {
+	invalidMode := config.Defaults()
+	invalidMode.IAMMode = "lenient"
+
+	invalidPort := config.Defaults()
+	invalidPort.Ports.KMS = 70000
+
+	missingPolicy := config.Defaults()
+	missingPolicy.PolicyFile = filepath.Join(t.TempDir(), "policy.yaml")
+
+	_, loadErr := policy.Load(filepath.Join(t.TempDir(), "missing.yaml"))
+
+	up := docker.ServiceInfo{Service: "iam", State: "running", Health: docker.ServiceUp}
+	down := docker.ServiceInfo{Service: "kms", State: "exited", Health: docker.ServiceDown}
+	apiFailed := map[string][]emulator.LayerResult{"kms": {{Layer: emulator.LayerAPI, Status: emulator.LayerFail, Err: errors.New("unimplemented")}}}
+	unreachable := &docker.CommandError{Msg: "docker compose ps failed", Err: &exec.ExitError{}, Kind: docker.ErrDaemonUnreachable}
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, ExitOK},
+		{"unexpected", errors.New("boom"), ExitError},
+		{"invalid mode", invalidMode.Validate(), ExitConfig},
+		{"invalid port", invalidPort.Validate(), ExitConfig},
+		{"config not found", fmt.Errorf("error initializing config: %w", fmt.Errorf("config file x: %w", config.ErrConfigNotFound)), ExitConfig},
+		{"config parse", fmt.Errorf("%w: yaml: line 2", config.ErrConfigParse), ExitConfig},
+		{"compose failed", &docker.CommandError{Msg: "docker compose up failed", Err: &exec.ExitError{}}, ExitDocker},
+		{"port in use", errors.Join(&docker.PortInUseError{Port: 8080, Name: "IAM"}), ExitDocker},
+		{"unhealthy", &docker.UnhealthyError{Services: []string{"iam"}, Timeout: time.Minute}, ExitDocker},
+		{"service exited", errors.Join(&docker.ExitedError{Service: "kms", ExitCode: 1}), ExitDocker},
+		{"digest mismatch", &docker.DigestMismatchError{Service: "kms", Ref: "kms@sha256:abc"}, ExitDocker},
+		{"image not present", fmt.Errorf("%w: kms", docker.ErrImageNotPresent), ExitDocker},
+		{"missing policy", missingPolicy.ValidatePolicyFile(policy.LoadOptions{}), ExitPolicy},
+		{"policy load", loadErr, ExitPolicy},
+		{"policy validation", policy.ErrInvalidPolicy, ExitPolicy},
+		{"status healthy", statusError(&docker.StackStatus{IAM: up, SecretManager: up, KMS: up}), ExitOK},
+		{"status down", statusError(&docker.StackStatus{IAM: up, SecretManager: up, KMS: down}), ExitError},
+		{"status unreachable", statusError(&docker.StackStatus{RuntimeErr: unreachable}), ExitDaemon},
+		{"daemon down", &docker.DaemonDownError{Runtime: "docker", Err: unreachable}, ExitDaemon},
+		{"deep check failed", layersError(apiFailed), ExitError},
+		{"simulate denied", policy.ErrDenied, ExitError},
+		{"simulate invalid flag", fmt.Errorf("%w for --request-time: bad time", config.ErrInvalidValue), ExitConfig},
+		{"updates available", fmt.Errorf("2 updates %w", upgrade.ErrUpdatesAvailable), ExitUpdates},
+		{"run command failed", &ChildExitError{Command: "go", Code: 42}, 42},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("%s: exitCode(%v) = %d, want %d", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+// TestPolicySimulateDeny checks a DENY is printed as a verdict, not as an
+// error, and is told apart from a request that could not be evaluated
+func TestPolicySimulateDeny(t *testing.T) {
+	valid, _ := writePolicies(t)
+	args := []string{"policy", "simulate", "--file", valid, "--principal", "user:alice@example.com", "--permission", "secretmanager.secrets.get"}
+
+	stdout, stderr := runCLI(t, append(args, "--resource", "projects/test-project/secrets/db")...)
+	if !strings.Contains(stdout, "DENY") {
+		t.Errorf("Expected DENY, got\n%s", stdout)
+	}
+	if stderr != "" {
+		t.Errorf("Expected no error line for a DENY, got %q", stderr)
+	}
+
+	_, stderr = runCLI(t, append(args, "--resource", "secrets/db")...)
+	if !strings.Contains(stderr, "invalid value for --resource") {
+		t.Errorf("Expected an invalid --resource error, got %q", stderr)
+	}
+}
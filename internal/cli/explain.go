@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain <decision-id>",
+	Short: "Explain an IAM emulator decision using the local policy",
+	Long: `Fetch a decision from the IAM emulator's decision log and explain it
+against the local policy file.
+
+PERMISSION_DENIED errors from the Secret Manager and KMS emulators
+include the IAM decision ID in their error details. This command shows
+which bindings apply to the principal, which conditions failed and the
+values they were evaluated with, which deny bindings matched, and for a
+denial, each single policy change that would have allowed the request.
+
+Decisions are only recorded in trace mode. If the decision is not in the
+log, the request can still be checked with 'gcp-emulator policy simulate'.`,
+	Example: `  gcp-emulator explain 7f3c9a2e
+  gcp-emulator explain 7f3c9a2e --file staging.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		d, err := newIAMClient(cfg).Decision(args[0])
+		if err != nil {
+			printDecisionLookupError(err, cfg)
+			return err
+		}
+
+		if d.Result == "allow" {
+			color.Green("✓ Decision %s: ALLOW", d.ID)
+		} else {
+			color.Red("✗ Decision %s: DENY", d.ID)
+		}
+		fmt.Printf("\n  Principal:  %s\n", d.Principal)
+		fmt.Printf("  Permission: %s\n", d.Permission)
+		fmt.Printf("  Resource:   %s\n", d.Resource)
+		fmt.Printf("  Time:       %s\n", d.Time.Local().Format("2006-01-02 15:04:05"))
+
+		pol, err := loadPolicyFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		req := policy.SimulateRequest{
+			Principal:   d.Principal,
+			Permission:  d.Permission,
+			Resource:    d.Resource,
+			RequestTime: d.Time,
+		}
+		decision, err := policy.Simulate(pol, req)
+		if err != nil {
+			return err
+		}
+
+		if decision.Allowed != (d.Result == "allow") {
+			local := "denies"
+			if decision.Allowed {
+				local = "allows"
+			}
+			color.Yellow("\n⚠ The local policy %s this request; the emulator may be enforcing a different policy (see 'gcp-emulator policy drift')", local)
+		}
+
+		printEvaluatedBindings(pol, req, decision)
+
+		if len(decision.Denials) > 0 {
+			fmt.Println("\nDeny bindings:")
+			for _, deny := range decision.Denials {
+				status := color.RedString("denied")
+				if !deny.Applied {
+					status = color.GreenString("not applied: %s", deny.Reason)
+				} else if deny.Reason != "" {
+					status = color.RedString("denied: %s", deny.Reason)
+				}
+				fmt.Printf("  [%d] %s%s — %s\n", deny.Index, describeMatch(deny.Member, deny.Via), conditionSuffix(deny.DenyBinding.Condition), status)
+			}
+		}
+
+		if decision.Allowed {
+			return nil
+		}
+
+		flips, err := policy.Flips(pol, req)
+		if err != nil {
+			return err
+		}
+		if len(flips) == 0 {
+			fmt.Printf("\nNo single policy change would allow this request. Grant %s to %s with a new binding.\n", d.Permission, d.Principal)
+			return nil
+		}
+		color.Cyan("\nAny one of these changes would allow the request:")
+		for _, flip := range flips {
+			fmt.Printf("  - %s\n", flip)
+		}
+
+		return nil
+	},
+}
+
+// printEvaluatedBindings lists the bindings that apply to the principal,
+// whether each grants the permission, and the result of its condition
+func printEvaluatedBindings(pol *policy.Policy, req policy.SimulateRequest, decision *policy.Decision) {
+	grants := policy.EffectivePermissions(pol, req.Principal, decision.Project)
+	if len(grants) == 0 {
+		fmt.Printf("\nNo binding in project %s applies to %s\n", decision.Project, req.Principal)
+		return
+	}
+
+	fmt.Println("\nBindings evaluated:")
+	conditional := false
+	for _, grant := range grants {
+		label := scopedIndex(grant.Scope, decision.Project, grant.Index)
+		fmt.Printf("  [%s] %s%s via %s — ", label, grant.Binding.Role, conditionSuffix(grant.Binding.Condition), describeMatch(grant.Member, grant.Via))
+
+		if !slices.Contains(grant.Permissions, req.Permission) {
+			fmt.Printf("role does not include %s\n", req.Permission)
+			continue
+		}
+
+		for _, m := range decision.Matches {
+			if m.Scope != grant.Scope || m.Index != grant.Index {
+				continue
+			}
+			if m.Granted {
+				color.Green("granted")
+			} else {
+				color.Red("not granted: %s", m.Reason)
+			}
+			if c := m.Binding.Condition; c != nil {
+				// conditionSuffix shows the title when there is one
+				if c.Title != "" {
+					fmt.Printf("      condition: %s\n", c.Expression)
+				}
+				conditional = true
+			}
+		}
+	}
+
+	if conditional {
+		ctx := decision.Context
+		fmt.Println("\nConditions were evaluated with:")
+		fmt.Printf("  resource.name = %q\n", ctx.ResourceName)
+		fmt.Printf("  resource.type = %q\n", ctx.ResourceType)
+		if ctx.RequestTime.IsZero() {
+			fmt.Println("  request.time  = now")
+		} else {
+			fmt.Printf("  request.time  = %s\n", ctx.RequestTime.UTC().Format("2006-01-02T15:04:05Z"))
+		}
+	}
+}
+
+// printDecisionLookupError explains why a decision could not be fetched
+func printDecisionLookupError(err error, cfg *config.Config) {
+	if !errors.Is(err, emulator.ErrDecisionNotFound) && !errors.Is(err, emulator.ErrTraceUnavailable) {
+		printEmulatorError(err)
+		return
+	}
+
+	color.Red("✗ %v", err)
+	if !cfg.Trace {
+		fmt.Println("\nTrace mode is off, so decisions are not recorded. Enable it and restart the stack:")
+		fmt.Println("  gcp-emulator config set trace true")
+		fmt.Println("  gcp-emulator stop && gcp-emulator start")
+	} else {
+		fmt.Println("\nThe decision may predate trace mode being enabled, or have aged out of the log.")
+	}
+	fmt.Println("\nTo explain the request without the decision log, simulate it against the policy file:")
+	fmt.Println("  gcp-emulator policy simulate --principal <principal> --permission <permission> --resource <resource>")
+}
+
+func init() {
+	explainCmd.Flags().String("file", "", "Policy file (defaults to configured policy-file)")
+}
@@ -0,0 +1,215 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+// Files init writes in the current directory
+const (
+	initPolicyFile   = "policy.yaml"
+	initFixturesFile = "fixtures.yaml"
+)
+
+// initEnvFiles are the env files of --env
+var initEnvFiles = map[string]string{
+	"envrc": ".envrc",
+	"sh":    "env.sh",
+}
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Set up the current directory to use the emulators",
+	Long: `Set up the current directory to use the emulators, writing:
+
+  policy.yaml         A starter policy, as policy init generates it
+  .gcp-emulator.yaml  Local config using the policy and fixtures, on free ports
+  fixtures.yaml       A sample secret and KMS key, seeded after every start
+  .envrc or env.sh    With --env, the endpoints for client libraries
+
+The project, an admin principal, and developer principals are prompted
+for when run in a terminal, or given with --project, --admin, and
+--developers (use --non-interactive to skip prompts).
+
+A file that exists is kept: init asks whether to overwrite it in a
+terminal, and skips it otherwise. --force overwrites them all. When
+.gcp-emulator.yaml is kept, the env file uses the ports it configures.`,
+	Example: `  gcp-emulator init
+  gcp-emulator init --project my-proj --admin user:me@example.com --env envrc --non-interactive`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+		env, _ := cmd.Flags().GetString("env")
+		nonInteractive, _ := cmd.Flags().GetBool("non-interactive")
+		interactive := !nonInteractive && isTerminal(os.Stdin)
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		opts, err := starterOptionsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		reader := bufio.NewReader(os.Stdin)
+		if interactive && !cmd.Flags().Changed("env") {
+			if env, err = prompt(reader, "Env file exporting the endpoints (envrc, sh, none)", env); err != nil {
+				return err
+			}
+		}
+		envFile, ok := initEnvFiles[env]
+		if !ok && env != "none" {
+			return fmt.Errorf("invalid env file: %s (must be envrc, sh, or none)", env)
+		}
+
+		// Decide what to write before picking ports, so a kept local
+		// config keeps its ports for the env file too
+		write := func(path string) (bool, error) {
+			if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) || force {
+				return true, nil
+			}
+			if !interactive {
+				color.Yellow("⚠ Skipping %s: it already exists (use --force to overwrite)", path)
+				return false, nil
+			}
+			answer, err := prompt(reader, fmt.Sprintf("Overwrite %s? [y/N]", path), "")
+			if err != nil {
+				return false, err
+			}
+			a := strings.ToLower(answer)
+			return a == "y" || a == "yes", nil
+		}
+		files := []string{initPolicyFile, config.LocalFileName, initFixturesFile}
+		if envFile != "" {
+			files = append(files, envFile)
+		}
+		writes := map[string]bool{}
+		for _, file := range files {
+			if writes[file], err = write(file); err != nil {
+				return err
+			}
+		}
+
+		ports := docker.ConfiguredPorts(cfg)
+		if writes[config.LocalFileName] {
+			if ports, err = docker.FreePorts(); err != nil {
+				color.Red("✗ %v", err)
+				return err
+			}
+		}
+
+		pol := starterPolicy(opts)
+		if result := policy.Validate(pol); !result.Valid {
+			color.Red("✗ Generated policy is invalid")
+			for _, err := range result.Errors {
+				color.Red("  %s", err)
+			}
+			return policy.ErrInvalidPolicy
+		}
+
+		info("Setting up %s in the current directory...", opts.Project)
+		for _, file := range files {
+			if !writes[file] {
+				continue
+			}
+			var err error
+			switch file {
+			case initPolicyFile:
+				err = policySaveOptions().Save(pol, file)
+			case config.LocalFileName:
+				err = os.WriteFile(file, []byte(localConfigContent(opts.Project, ports)), 0644)
+			case initFixturesFile:
+				err = os.WriteFile(file, []byte(fixturesContent(opts)), 0644)
+			default:
+				err = os.WriteFile(file, []byte(envFileContent(cfg, opts.Project, ports)), 0644)
+			}
+			if err != nil {
+				color.Red("✗ Failed to write %s: %v", file, err)
+				return err
+			}
+			color.Green("✓ Created %s", file)
+		}
+
+		info("\nNext steps:")
+		info("  gcp-emulator start            # seeds %s once the stack is healthy", initFixturesFile)
+		switch envFile {
+		case ".envrc":
+			info("  direnv allow                  # exports the endpoints for client libraries")
+		case "env.sh":
+			info("  source env.sh                 # exports the endpoints for client libraries")
+		}
+		info("  gcp-emulator status")
+		info("\nEdit %s to grant your principals roles, and check it with:", initPolicyFile)
+		info("  gcp-emulator policy validate %s", initPolicyFile)
+		return nil
+	},
+}
+
+// localConfigContent is the .gcp-emulator.yaml init writes
+func localConfigContent(project string, ports docker.Ports) string {
+	return fmt.Sprintf(`# gcp-emulator config for this project, merged over the global config
+# file. 'gcp-emulator config list' describes every key.
+policy-file: %s
+seed: %s
+project: %s
+port-iam: %d
+port-secret-manager: %d
+port-secret-manager-http: %d
+port-kms: %d
+port-kms-http: %d
+`, initPolicyFile, initFixturesFile, project, ports.IAM, ports.SecretManager, ports.SecretManagerHTTP, ports.KMS, ports.KMSHTTP)
+}
+
+// fixturesContent is the sample fixtures.yaml init writes. Resources are
+// created as the admin, when there is one, so seeding works in strict
+// mode too.
+func fixturesContent(opts starterOptions) string {
+	principal := "# principal: user:you@example.com   # needs create permissions outside off mode"
+	if opts.Admin != "" {
+		principal = "principal: " + opts.Admin
+	}
+	return fmt.Sprintf(`# Fixtures seeded after every start that waits for the stack, and by
+# 'gcp-emulator seed'. See 'gcp-emulator seed --help' for the format.
+project: %s
+%s
+secrets:
+  - name: db-password
+    value: change-me
+    labels: {env: dev}
+keyRings:
+  - name: app
+    keys:
+      - name: data
+`, opts.Project, principal)
+}
+
+// envFileContent is the .envrc or env.sh init writes, exporting what env
+// prints
+func envFileContent(cfg *config.Config, project string, ports docker.Ports) string {
+	var b strings.Builder
+	b.WriteString("# Endpoints of the gcp-emulator stack, for client libraries; see 'gcp-emulator env'\n")
+	for _, v := range clientEnv(cfg, ports, project) {
+		b.WriteString(envShells["bash"](v) + "\n")
+	}
+	return b.String()
+}
+
+func init() {
+	initCmd.Flags().String("project", "test-project", "Project ID for the starter bindings and fixtures")
+	initCmd.Flags().String("admin", "", "Principal granted roles/owner, and seeding as (e.g. user:you@example.com)")
+	initCmd.Flags().StringSlice("developers", nil, "Members of the developers group")
+	initCmd.Flags().String("env", "none", "Also write the endpoints for client libraries to .envrc (envrc) or env.sh (sh)")
+	initCmd.Flags().BoolP("force", "f", false, "Overwrite existing files without asking")
+	initCmd.Flags().Bool("non-interactive", false, "Don't prompt; use flag values and defaults, and skip existing files")
+}
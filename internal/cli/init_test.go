@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/seed"
+)
+
+func TestInit(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, ".config"))
+	t.Chdir(dir)
+
+	stdout, stderr := runCLI(t, "init", "--non-interactive", "--project", "my-proj", "--admin", "user:me@example.com", "--env", "sh")
+	if stderr != "" {
+		t.Fatalf("init failed: %s", stderr)
+	}
+	for _, file := range []string{"policy.yaml", ".gcp-emulator.yaml", "fixtures.yaml", "env.sh"} {
+		if !strings.Contains(stdout, "✓ Created "+file) {
+			t.Errorf("Expected %s to be created, got %q", file, stdout)
+		}
+	}
+
+	// The files work with the commands that read them
+	pol, err := policy.Load("policy.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pol.Projects["my-proj"]; !ok {
+		t.Errorf("Expected the policy to bind my-proj, got %v", pol.Projects)
+	}
+	fixtures, err := seed.Load("fixtures.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixtures.Project != "my-proj" || fixtures.Principal != "user:me@example.com" {
+		t.Errorf("Expected fixtures for my-proj seeded as the admin, got %s as %s", fixtures.Project, fixtures.Principal)
+	}
+	if err := config.Init(); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SeedFile != filepath.Join(dir, "fixtures.yaml") {
+		t.Errorf("Expected the local config to seed fixtures.yaml, got %q", cfg.SeedFile)
+	}
+	env, err := os.ReadFile("env.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("export KMS_EMULATOR_HOST=localhost:%d\n", cfg.Ports.KMS); !strings.Contains(string(env), want) {
+		t.Errorf("Expected env.sh to export the configured KMS port, %q, got\n%s", want, env)
+	}
+
+	// Existing files are kept without --force
+	if err := os.WriteFile("fixtures.yaml", []byte("project: mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, _ = runCLI(t, "init", "--non-interactive")
+	if !strings.Contains(stdout, "⚠ Skipping fixtures.yaml") {
+		t.Errorf("Expected fixtures.yaml to be skipped, got %q", stdout)
+	}
+	if data, _ := os.ReadFile("fixtures.yaml"); string(data) != "project: mine\n" {
+		t.Errorf("Expected fixtures.yaml to be kept, got %q", data)
+	}
+
+	runCLI(t, "init", "--non-interactive", "--force")
+	if data, _ := os.ReadFile("fixtures.yaml"); string(data) == "project: mine\n" {
+		t.Error("Expected --force to overwrite fixtures.yaml")
+	}
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+var (
+	logVerbose bool
+	logFormat  string
+)
+
+// Nothing is logged before setupLogging runs, so a flag that fails to
+// parse is only reported as the error
+func init() {
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+}
+
+// setupLogging sets the default slog logger from --verbose and
+// --log-format. Without either, records are discarded and the colored
+// output is all there is. With one, records go to stderr, debug ones
+// too with --verbose, and every line of colored output is also logged.
+func setupLogging() error {
+	if logFormat != "text" && logFormat != "json" {
+		return fmt.Errorf("invalid log format: %s (must be text or json)", logFormat)
+	}
+	if !logVerbose && logFormat == "text" {
+		slog.SetDefault(slog.New(slog.DiscardHandler))
+		return nil
+	}
+
+	opts := &slog.HandlerOptions{Level: slog.LevelInfo}
+	if logVerbose {
+		opts.Level = slog.LevelDebug
+	}
+	var handler slog.Handler = slog.NewTextHandler(os.Stderr, opts)
+	if logFormat == "json" {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	}
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+	if _, ok := color.Output.(*outputLogger); !ok {
+		color.Output = &outputLogger{out: color.Output, logger: logger}
+	}
+	return nil
+}
+
+// outputLogger passes colored output on to out, and logs each line of it
+// at the level its mark means: ✗ an error, ⚠ a warning, ✓ and → info.
+// Lines without a mark, such as table rows, are logged at debug level.
+type outputLogger struct {
+	out    io.Writer
+	logger *slog.Logger
+	buf    []byte
+}
+
+// ansiEscape matches the color codes fatih/color writes around text
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// outputMarks are the marks colored output starts with, and the levels
+// they are logged at
+var outputMarks = []struct {
+	mark  string
+	level slog.Level
+}{
+	{"✗", slog.LevelError},
+	{"⚠", slog.LevelWarn},
+	{"✓", slog.LevelInfo},
+	{"→", slog.LevelInfo},
+}
+
+func (w *outputLogger) Write(p []byte) (int, error) {
+	n, err := w.out.Write(p)
+	w.buf = append(w.buf, p[:n]...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.log(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	return n, err
+}
+
+// log logs a line of output, without its color codes and mark
+func (w *outputLogger) log(line string) {
+	msg := strings.TrimSpace(ansiEscape.ReplaceAllString(line, ""))
+	if msg == "" {
+		return
+	}
+	level := slog.LevelDebug
+	for _, m := range outputMarks {
+		if rest, ok := strings.CutPrefix(msg, m.mark); ok {
+			msg, level = strings.TrimSpace(rest), m.level
+			break
+		}
+	}
+	w.logger.Log(context.Background(), level, msg, "output", true)
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestOutputLogger(t *testing.T) {
+	var out, records bytes.Buffer
+	w := &outputLogger{
+		out:    &out,
+		logger: slog.New(slog.NewJSONHandler(&records, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	}
+
+	// fatih/color writes the color codes apart from the text
+	for _, p := range []string{"\x1b[31m", "✗ Failed to start stack: boom\n", "\x1b[0m", "⚠ Using ", "a legacy config\n", "Service  Status\n"} {
+		if _, err := w.Write([]byte(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !strings.Contains(out.String(), "\x1b[31m✗ Failed to start stack: boom\n") {
+		t.Errorf("Expected the output passed on unchanged, got %q", out.String())
+	}
+	want := []struct{ level, msg string }{
+		{"ERROR", "Failed to start stack: boom"},
+		{"WARN", "Using a legacy config"},
+		{"DEBUG", "Service  Status"},
+	}
+	lines := strings.Split(strings.TrimSpace(records.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("Expected %d records, got %d:\n%s", len(want), len(lines), records.String())
+	}
+	for i, line := range lines {
+		var record struct {
+			Level, Msg string
+		}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Invalid record %q: %v", line, err)
+		}
+		if record.Level != want[i].level || record.Msg != want[i].msg {
+			t.Errorf("Record %d = %s %q, want %s %q", i, record.Level, record.Msg, want[i].level, want[i].msg)
+		}
+	}
+}
@@ -1,65 +1,76 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
+	"os/signal"
+	"regexp"
+	"syscall"
 
 	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
 )
 
 var (
 	logsFollow bool
 	logsTail   int
 	logsSince  string
+	logsGrep   string
 )
 
 var logsCmd = &cobra.Command{
-	Use:   "logs [service]",
+	Use:   "logs [service...]",
 	Short: "Show logs from services",
 	Long: `Show logs from emulator services.
 
-Without arguments, shows logs from all services.
-Specify a service name to show logs from that service only.
+Without arguments, shows the logs of all services interleaved, each line
+prefixed with its service in its own color. Specify service names to
+show only theirs.
+
+--grep shows only the lines matching a regular expression. With
+--follow, new lines are shown until Ctrl-C.
 
 Services: iam, secret-manager, kms`,
-	ValidArgs: []string{"iam", "secret-manager", "kms"},
+	Example: `  gcp-emulator logs
+  gcp-emulator logs kms --follow
+  gcp-emulator logs --since 10m --tail 200
+  gcp-emulator logs iam --grep 'DENY|error'`,
+	ValidArgsFunction: completeServices,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		args = buildLogsArgs(args)
-
-		dcCmd := exec.Command("docker-compose", args...)
-		dcCmd.Stdout = os.Stdout
-		dcCmd.Stderr = os.Stderr
-
-		return dcCmd.Run()
-	},
-}
-
-func buildLogsArgs(services []string) []string {
-	args := []string{"logs"}
-
-	if logsFollow {
-		args = append(args, "--follow")
-	}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
 
-	if logsTail > 0 {
-		args = append(args, "--tail", fmt.Sprintf("%d", logsTail))
-	}
+		services := make([]string, 0, len(args))
+		for _, arg := range args {
+			service, err := docker.LookupService(arg)
+			if err != nil {
+				return err
+			}
+			services = append(services, service)
+		}
 
-	if logsSince != "" {
-		args = append(args, "--since", logsSince)
-	}
+		opts := docker.LogOptions{Follow: logsFollow, Since: logsSince, Tail: logsTail}
+		if logsGrep != "" {
+			if opts.Grep, err = regexp.Compile(logsGrep); err != nil {
+				return fmt.Errorf("invalid --grep pattern: %w", err)
+			}
+		}
+		// Ctrl-C stops following without an error
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
 
-	// Add service names if specified
-	if len(services) > 0 {
-		args = append(args, services...)
-	}
-
-	return args
+		return docker.StreamLogs(ctx, cfg, services, opts, os.Stdout)
+	},
 }
 
 func init() {
 	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Follow log output")
-	logsCmd.Flags().IntVar(&logsTail, "tail", 50, "Number of lines to show from end of logs")
+	logsCmd.Flags().IntVar(&logsTail, "tail", 50, "Number of lines to show from the end of each service's log (0 for all)")
 	logsCmd.Flags().StringVar(&logsSince, "since", "", "Show logs since timestamp (e.g. 2m, 1h)")
+	logsCmd.Flags().StringVar(&logsGrep, "grep", "", "Show only lines matching a regular expression")
 }
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/metrics"
+)
+
+var (
+	metricsListen   string
+	metricsInterval time.Duration
+	metricsTextfile string
+)
+
+// defaultMetricsInterval is how often metrics serve checks the stack
+const defaultMetricsInterval = 15 * time.Second
+
+var metricsCmd = &cobra.Command{
+	Use:   "metrics",
+	Short: "Export the health of the stack as Prometheus metrics",
+	Long: `Export what status finds as Prometheus metrics: whether each service
+is enabled and up, the round trip of its health check, its container's
+restarts and uptime, the stack's uptime, and whether the container
+runtime could be asked.
+
+serve runs an exporter to scrape; write writes the metrics once, for the
+node_exporter textfile collector to pick up.`,
+}
+
+var metricsServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the metrics for Prometheus to scrape",
+	Long: `Check the stack every --interval, as status does, and serve the
+metrics of the latest check on /metrics at --listen until interrupted.
+Scrapes don't run the checks themselves, so they answer at once however
+the stack is doing.`,
+	Example: `  gcp-emulator metrics serve --listen :9100
+  gcp-emulator metrics serve --listen 127.0.0.1:9100 --interval 30s`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if metricsInterval <= 0 {
+			return fmt.Errorf("invalid interval: %s (must be positive)", metricsInterval)
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		lis, err := net.Listen("tcp", metricsListen)
+		if err != nil {
+			color.Red("✗ Failed to serve metrics: %v", err)
+			return err
+		}
+		collector := &metrics.Collector{}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", collector)
+		server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			<-ctx.Done()
+			_ = server.Shutdown(context.Background())
+		}()
+		go docker.Poll(ctx, metricsInterval, metricsInterval, func() bool {
+			// The status of a stack that is down is what the metrics are
+			// for, so errors only show in them
+			status, _ := docker.Status(cfg)
+			collector.Update(status)
+			return false
+		})
+
+		color.Green("✓ Serving metrics on http://%s/metrics, checking every %s", displayListen(lis.Addr()), metricsInterval)
+		if err := server.Serve(lis); !errors.Is(err, http.ErrServerClosed) {
+			color.Red("✗ Failed to serve metrics: %v", err)
+			return err
+		}
+		return nil
+	},
+}
+
+var metricsWriteCmd = &cobra.Command{
+	Use:   "write",
+	Short: "Write the metrics once to a textfile",
+	Long: `Check the stack once, as status does, and write its metrics to
+--textfile, replacing the file in one step so the collector never reads
+one half written. Run it from cron or a systemd timer, with the file in
+the directory node_exporter's --collector.textfile.directory names; the
+collector only reads files ending in .prom.`,
+	Example: `  gcp-emulator metrics write --textfile /var/lib/node_exporter/textfile/gcp_emulator.prom`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		status, err := docker.Status(cfg)
+		if err != nil {
+			color.Red("✗ Failed to get status: %v", err)
+			return err
+		}
+		if err := metrics.WriteTextfile(metricsTextfile, status); err != nil {
+			color.Red("✗ %v", err)
+			return err
+		}
+		color.Green("✓ Wrote metrics to %s", metricsTextfile)
+		if filepath.Ext(metricsTextfile) != ".prom" {
+			color.Yellow("⚠ The textfile collector only reads files ending in .prom")
+		}
+		return nil
+	},
+}
+
+// displayListen returns the address metrics are served on as it can be
+// opened, with localhost for every interface
+func displayListen(addr net.Addr) string {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+func init() {
+	metricsServeCmd.Flags().StringVar(&metricsListen, "listen", ":9100", "Address to serve the metrics on")
+	metricsServeCmd.Flags().DurationVar(&metricsInterval, "interval", defaultMetricsInterval, "How often the stack is checked")
+	metricsWriteCmd.Flags().StringVar(&metricsTextfile, "textfile", "", "File to write the metrics to, ending in .prom")
+
+	metricsWriteCmd.MarkFlagRequired("textfile")
+
+	metricsCmd.AddCommand(metricsServeCmd)
+	metricsCmd.AddCommand(metricsWriteCmd)
+}
@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+// Output formats of --output
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+	OutputYAML = "yaml"
+)
+
+// outputFormat is the --output format
+var outputFormat string
+
+// rendersOutput is the annotation of the commands that print their
+// result in the --output format. The others only print text, and
+// reject json and yaml.
+const rendersOutput = "rendersOutput"
+
+// renders annotates a command as printing its result with render
+var renders = map[string]string{rendersOutput: "true"}
+
+// setupOutput checks --output for cmd, then sets up colors. With json
+// or yaml, the colored output goes to stderr, so stdout holds only the
+// result.
+func setupOutput(cmd *cobra.Command) error {
+	// table was the default of the policy stats and who-can tables
+	if outputFormat == "table" {
+		outputFormat = OutputText
+	}
+	legacyOut := initOutputFile(cmd)
+	switch outputFormat {
+	case OutputText:
+	case OutputJSON, OutputYAML:
+		if cmd.Annotations[rendersOutput] == "" {
+			return fmt.Errorf("%s only prints text, not --output %s", cmd.CommandPath(), outputFormat)
+		}
+		color.Output = os.Stderr
+	default:
+		return fmt.Errorf("invalid output format: %s (must be text, json, or yaml)", outputFormat)
+	}
+	setupColor()
+	if legacyOut != "" {
+		color.Yellow("⚠ policy init --output <file> is deprecated; use --out %s", legacyOut)
+		if !cmd.Flags().Changed("out") {
+			return cmd.Flags().Set("out", legacyOut)
+		}
+	}
+	return nil
+}
+
+// structuredOutput reports whether --output is json or yaml
+func structuredOutput() bool {
+	return outputFormat == OutputJSON || outputFormat == OutputYAML
+}
+
+// render prints the result of a command in the --output format: as an
+// indented JSON or YAML document, or, for text, by calling text
+func render(result any, text func()) error {
+	if !structuredOutput() {
+		text()
+		return nil
+	}
+	data, err := marshalOutput(result, true)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// renderStream prints one result of a stream, such as an event: as a
+// line of JSON, or as a YAML document of its own
+func renderStream(w io.Writer, result any) error {
+	data, err := marshalOutput(result, false)
+	if err != nil {
+		return err
+	}
+	if outputFormat == OutputYAML {
+		data = append([]byte("---\n"), data...)
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// marshalOutput marshals result in the --output format, ending in a
+// newline. YAML is converted from the JSON, so both have the same
+// fields, in the same order, named by the json tags.
+func marshalOutput(result any, indent bool) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	// CEL conditions are full of < and >, which needn't be escaped
+	// outside of HTML
+	encoder.SetEscapeHTML(false)
+	if indent {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(result); err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+	if outputFormat != OutputYAML {
+		return buf.Bytes(), nil
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(buf.Bytes(), &node); err != nil {
+		return nil, fmt.Errorf("failed to convert output to YAML: %w", err)
+	}
+	blockStyle(&node)
+	var out bytes.Buffer
+	yamlEncoder := yaml.NewEncoder(&out)
+	yamlEncoder.SetIndent(2)
+	if err := yamlEncoder.Encode(&node); err != nil {
+		return nil, fmt.Errorf("failed to convert output to YAML: %w", err)
+	}
+	if err := yamlEncoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to convert output to YAML: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// blockStyle drops the flow style and quotes that JSON parsed as YAML
+// has, keeping each scalar's type, so the YAML reads as written by hand
+func blockStyle(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode {
+		node.Tag = node.ShortTag()
+	}
+	node.Style = 0
+	// An empty list or map can only be written in flow style
+	if (node.Kind == yaml.SequenceNode || node.Kind == yaml.MappingNode) && len(node.Content) == 0 {
+		node.Style = yaml.FlowStyle
+	}
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// errorReport is the error --output json and yaml print on stderr.
+// Scripts parse it, so fields are only ever added.
+type errorReport struct {
+	Error struct {
+		Message  string `json:"message"`
+		ExitCode int    `json:"exitCode"`
+	} `json:"error"`
+}
+
+// printError prints the error a command failed with on stderr: as an
+// errorReport with --output json or yaml, else as cobra would. A DENY
+// from policy simulate is not printed, as its verdict already was.
+func printError(err error, code int) {
+	if errors.Is(err, policy.ErrDenied) {
+		return
+	}
+	if !structuredOutput() {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return
+	}
+	var report errorReport
+	report.Error.Message = err.Error()
+	report.Error.ExitCode = code
+	data, marshalErr := marshalOutput(report, outputFormat == OutputYAML)
+	if marshalErr != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return
+	}
+	_, _ = os.Stderr.Write(data)
+}
@@ -0,0 +1,326 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+// TestOutputSchemas pins the fields and casing of what --output json
+// prints, since scripts parse it
+func TestOutputSchemas(t *testing.T) {
+	cfg := config.Defaults()
+	status := &docker.StackStatus{
+		IAM:           docker.ServiceInfo{Service: "iam", State: "running", Health: docker.ServiceUp},
+		SecretManager: docker.ServiceInfo{Service: "secret-manager", State: docker.ContainerNotCreated, Health: docker.ServiceNotEnabled},
+		KMS:           docker.ServiceInfo{Service: "kms", State: "exited", Health: docker.ServiceDown},
+		Ports:         docker.ConfiguredPorts(cfg),
+	}
+	layers := map[string][]emulator.LayerResult{
+		"iam": {{Layer: emulator.LayerGRPC, Status: emulator.LayerFail, Err: errors.New("not reachable"), Latency: 12 * time.Millisecond}},
+	}
+
+	tests := []struct {
+		name   string
+		result any
+		want   string
+	}{
+		{
+			name:   "status",
+			result: newStatusReport(cfg, status, layers),
+			want: `{
+  "services": [
+    {
+      "name": "iam",
+      "state": "running",
+      "health": "up",
+      "ports": {
+        "grpc": 8080,
+        "http": 9080
+      },
+      "url": "http://localhost:9080",
+      "layers": [
+        {
+          "layer": "grpc",
+          "status": "fail",
+          "error": "not reachable",
+          "latencyMs": 12
+        }
+      ]
+    },
+    {
+      "name": "secret-manager",
+      "state": "not created",
+      "health": "not-enabled",
+      "ports": {
+        "grpc": 9090,
+        "http": 8081
+      },
+      "url": "http://localhost:8081"
+    },
+    {
+      "name": "kms",
+      "state": "exited",
+      "health": "down",
+      "ports": {
+        "grpc": 9091,
+        "http": 8082
+      },
+      "url": "http://localhost:8082"
+    }
+  ],
+  "overall": "degraded"
+}`,
+		},
+		{
+			name:   "version",
+			result: versionReport{Version: "1.4.0", Images: &imagesReport{IAM: "ghcr.io/acme/iam:1.4", KMS: "ghcr.io/acme/kms:1.2"}},
+			want: `{
+  "version": "1.4.0",
+  "images": {
+    "iam": "ghcr.io/acme/iam:1.4",
+    "secretManager": "",
+    "kms": "ghcr.io/acme/kms:1.2"
+  }
+}`,
+		},
+		{
+			name: "config",
+			result: configReport{Profile: "ci", Keys: []configKeyReport{
+				{Key: "iam-mode", Value: "strict", Source: newSourceReport(config.Source{Kind: config.SourceEnv, Name: "GCP_EMULATOR_IAM_MODE"})},
+				{Key: "port-kms", Value: "9091", Source: newSourceReport(config.Source{Kind: config.SourceDefault})},
+			}},
+			want: `{
+  "profile": "ci",
+  "keys": [
+    {
+      "key": "iam-mode",
+      "value": "strict",
+      "source": {
+        "kind": "environment",
+        "name": "GCP_EMULATOR_IAM_MODE"
+      }
+    },
+    {
+      "key": "port-kms",
+      "value": "9091",
+      "source": {
+        "kind": "default"
+      }
+    }
+  ]
+}`,
+		},
+		{
+			name:   "config list",
+			result: []configKeyReport{{Key: "trace", Value: "false", Description: "Enable trace mode"}},
+			want: `[
+  {
+    "key": "trace",
+    "value": "false",
+    "description": "Enable trace mode"
+  }
+]`,
+		},
+		{
+			name: "validate findings",
+			result: []validationReport{
+				{File: "policy.yaml", Valid: true, Findings: []policy.Finding{}},
+				{File: "team.yaml", Findings: []policy.Finding{
+					{Severity: policy.SeverityError, Message: "undefined role roles/custom.ghost", Position: policy.Position{File: "team.yaml", Line: 12, Column: 9}},
+				}},
+			},
+			want: `[
+  {
+    "file": "policy.yaml",
+    "valid": true,
+    "findings": []
+  },
+  {
+    "file": "team.yaml",
+    "valid": false,
+    "findings": [
+      {
+        "severity": "error",
+        "message": "undefined role roles/custom.ghost",
+        "file": "team.yaml",
+        "line": 12,
+        "column": 9
+      }
+    ]
+  }
+]`,
+		},
+		{
+			name: "policy diff",
+			result: &policy.PolicyDiff{
+				RolesAdded:   []string{"roles/custom.reader"},
+				RolesChanged: []policy.RoleDiff{{Name: "roles/custom.ci", PermissionsRemoved: []string{"cloudkms.cryptoKeys.decrypt"}}},
+				ProjectsChanged: []policy.ProjectDiff{{
+					Name:          "test-project",
+					BindingsAdded: []policy.Binding{{Role: "roles/custom.reader", Members: []string{"user:bob@example.com"}}},
+					BindingsChanged: []policy.BindingDiff{{
+						Role:         "roles/owner",
+						Condition:    &policy.Condition{Expression: "request.time < timestamp('2027-01-01T00:00:00Z')", Title: "Until 2027"},
+						MembersAdded: []string{"user:alice@example.com"},
+					}},
+				}},
+			},
+			want: `{
+  "rolesAdded": [
+    "roles/custom.reader"
+  ],
+  "rolesChanged": [
+    {
+      "name": "roles/custom.ci",
+      "permissionsRemoved": [
+        "cloudkms.cryptoKeys.decrypt"
+      ]
+    }
+  ],
+  "projectsChanged": [
+    {
+      "name": "test-project",
+      "bindingsAdded": [
+        {
+          "role": "roles/custom.reader",
+          "members": [
+            "user:bob@example.com"
+          ]
+        }
+      ],
+      "bindingsChanged": [
+        {
+          "role": "roles/owner",
+          "condition": {
+            "expression": "request.time < timestamp('2027-01-01T00:00:00Z')",
+            "title": "Until 2027"
+          },
+          "membersAdded": [
+            "user:alice@example.com"
+          ]
+        }
+      ]
+    }
+  ]
+}`,
+		},
+	}
+
+	outputFormat = OutputJSON
+	defer func() { outputFormat = OutputText }()
+	for _, tt := range tests {
+		data, err := marshalOutput(tt.result, true)
+		if err != nil {
+			t.Fatalf("%s: marshalOutput failed: %v", tt.name, err)
+		}
+		if got := strings.TrimSuffix(string(data), "\n"); got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMarshalOutputYAML(t *testing.T) {
+	outputFormat = OutputYAML
+	defer func() { outputFormat = OutputText }()
+
+	report := configReport{Keys: []configKeyReport{
+		{Key: "trace", Value: "false", Source: newSourceReport(config.Source{Kind: config.SourceDefault})},
+		{Key: "port-kms", Value: "9091", Description: "KMS gRPC port"},
+	}}
+	data, err := marshalOutput(report, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Strings stay strings, so scripts read the same types as from JSON
+	want := `keys:
+  - key: trace
+    value: "false"
+    source:
+      kind: default
+  - key: port-kms
+    value: "9091"
+    description: KMS gRPC port
+`
+	if string(data) != want {
+		t.Errorf("got\n%s\nwant\n%s", data, want)
+	}
+
+	data, err = marshalOutput([]validationReport{{File: "policy.yaml", Valid: true, Findings: []policy.Finding{}}}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "- file: policy.yaml\n  valid: true\n  findings: []\n"; string(data) != want {
+		t.Errorf("Expected an empty list to stay one, got\n%s", data)
+	}
+}
+
+func TestErrorReport(t *testing.T) {
+	var report errorReport
+	report.Error.Message = "invalid port for KMS: 70000"
+	report.Error.ExitCode = ExitConfig
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"error":{"message":"invalid port for KMS: 70000","exitCode":2}}`; string(data) != want {
+		t.Errorf("Expected %s, got %s", want, data)
+	}
+}
+
+// TestOutputFlagNotShadowed checks no command defines its own --output or
+// -o, which would hide the global format flag on that command
+func TestOutputFlagNotShadowed(t *testing.T) {
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		for _, sub := range cmd.Commands() {
+			if f := sub.LocalFlags().Lookup("output"); f != nil {
+				t.Errorf("%s defines its own --output flag", sub.CommandPath())
+			}
+			if f := sub.LocalFlags().ShorthandLookup("o"); f != nil {
+				t.Errorf("%s defines its own -o flag (--%s)", sub.CommandPath(), f.Name)
+			}
+			walk(sub)
+		}
+	}
+	walk(rootCmd)
+
+	if policyInitCmd.Flags().Lookup("out") == nil {
+		t.Error("Expected policy init to take the output file as --out")
+	}
+}
+
+// TestPolicyInitLegacyOutput checks policy init still takes its output
+// file as --output, as it did before --out, with a deprecation warning
+func TestPolicyInitLegacyOutput(t *testing.T) {
+	writePolicies(t)
+	file := filepath.Join(t.TempDir(), "advanced.yaml")
+	t.Cleanup(func() {
+		out := policyInitCmd.Flags().Lookup("out")
+		_ = out.Value.Set("")
+		out.Changed = false
+	})
+
+	stdout, stderr := runCLI(t, "policy", "init", "--template", "advanced", "--output", file)
+	if stderr != "" {
+		t.Fatalf("Expected no error, got %q", stderr)
+	}
+	if !strings.Contains(stdout, "--output <file> is deprecated") {
+		t.Errorf("Expected a deprecation warning, got\n%s", stdout)
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("Expected %s to be written: %v", file, err)
+	}
+}
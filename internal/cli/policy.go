@@ -1,8 +1,11 @@
 package cli
 
 import (
+	"bufio"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -11,6 +14,24 @@ import (
 	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
 )
 
+// policyKeyFile and policyDecrypt hold --policy-key-file and --decrypt
+var (
+	policyKeyFile string
+	policyDecrypt bool
+)
+
+// policyLoadOptions reads policy files with the passphrase from
+// --policy-key-file, or GCP_EMULATOR_POLICY_KEY
+func policyLoadOptions() policy.LoadOptions {
+	return policy.LoadOptions{KeyFile: policyKeyFile}
+}
+
+// policySaveOptions writes policy files over encrypted ones only with
+// --decrypt
+func policySaveOptions() policy.SaveOptions {
+	return policy.SaveOptions{AllowDecrypt: policyDecrypt}
+}
+
 var policyCmd = &cobra.Command{
 	Use:   "policy",
 	Short: "Policy management",
@@ -18,74 +39,283 @@ var policyCmd = &cobra.Command{
 }
 
 var policyValidateCmd = &cobra.Command{
-	Use:   "validate [file]",
-	Short: "Validate policy.yaml syntax",
+	Use:         "validate [file...]",
+	Short:       "Validate policy.yaml syntax",
+	Annotations: renders,
 	Long: `Validate policy file syntax and structure.
 
 Without arguments, validates ./policy.yaml
-Specify a file path to validate a different file.`,
+Specify one or more file paths to validate different files. Each file is
+validated on its own and the command fails if any of them is invalid.
+Use - to read a policy from stdin, in the format given by --format.
+
+With --fix, safe corrections are applied and written back before
+validating: whitespace is trimmed, missing roles/ prefixes are added,
+duplicate permissions and members are removed, and lists are sorted.
+The original is kept as <file>.bak unless --no-backup is given.
+Undefined roles, invalid CEL, and other issues that need a decision
+are still reported as errors. Rewriting YAML does not preserve comments.
+
+With --gcp-compat, custom role names that cannot be exported to real GCP
+are reported as errors, with a suggested replacement: role IDs must be
+3 to 64 letters, digits, underscores, or periods, and must not be the
+name of a predefined role.`,
+	Example: `  gcp-emulator policy validate
+  gcp-emulator policy validate policy.yaml --output json
+  gcp-emulator policy validate base.yaml team-*.yaml
+  git show :policy.yaml | gcp-emulator policy validate -`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.Load()
-		if err != nil {
-			return err
+		format, _ := cmd.Flags().GetString("format")
+		if format != "yaml" && format != "json" {
+			return fmt.Errorf("invalid format: %s (must be yaml or json)", format)
+		}
+
+		files := args
+		if len(files) == 0 {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			files = []string{cfg.PolicyFile}
+		}
+
+		fix, _ := cmd.Flags().GetBool("fix")
+		if fix && slices.Contains(files, "-") {
+			return fmt.Errorf("--fix cannot be used with stdin")
 		}
 
-		policyFile := cfg.PolicyFile
-		if len(args) > 0 {
-			policyFile = args[0]
+		skipCEL, _ := cmd.Flags().GetBool("skip-cel")
+		strictLint, _ := cmd.Flags().GetBool("strict-lint")
+		gcpCompat, _ := cmd.Flags().GetBool("gcp-compat")
+		noBackup, _ := cmd.Flags().GetBool("no-backup")
+		opts := policy.ValidateOptions{
+			SkipCEL:    skipCEL,
+			StrictLint: strictLint,
+			GCPCompat:  gcpCompat,
 		}
 
-		color.Cyan("Validating %s...", policyFile)
+		var reports []validationReport
+		failed := 0
+		for i, file := range files {
+			if !structuredOutput() && i > 0 {
+				fmt.Println()
+			}
+			report := validateOne(file, format, fix, !noBackup, opts)
+			if !report.Valid {
+				failed++
+			}
+			reports = append(reports, report)
+		}
 
-		// Load policy
-		pol, err := policy.Load(policyFile)
+		// Text is shown as each file is validated, so only the total is
+		// left
+		err := render(reports, func() {
+			if len(files) == 1 {
+				return
+			}
+			fmt.Println()
+			if failed == 0 {
+				color.Green("✓ All %d files are valid", len(files))
+			} else {
+				color.Red("✗ %d of %d files failed validation", failed, len(files))
+			}
+		})
 		if err != nil {
-			color.Red("✗ Failed to load policy: %v", err)
 			return err
 		}
 
-		// Validate
-		result := policy.Validate(pol)
+		if failed > 0 {
+			return policy.ErrInvalidPolicy
+		}
+		return nil
+	},
+}
+
+// validationReport is the result of validating one policy file, as
+// policy validate --output json and yaml print it. Scripts parse it, so
+// fields are only ever added.
+type validationReport struct {
+	File     string           `json:"file"`
+	Valid    bool             `json:"valid"`
+	Error    string           `json:"error,omitempty"`
+	Findings []policy.Finding `json:"findings"`
+}
 
-		if result.Valid {
-			color.Green("✓ Policy is valid")
-			fmt.Printf("\n%d roles defined\n", len(pol.Roles))
-			fmt.Printf("%d groups defined\n", len(pol.Groups))
-			fmt.Printf("%d projects configured\n", len(pol.Projects))
+// validateOne loads and validates a single policy file, or stdin for "-",
+// printing the result in text mode
+func validateOne(file, format string, fix, backup bool, opts policy.ValidateOptions) validationReport {
+	report := validationReport{File: file, Findings: []policy.Finding{}}
+	text := !structuredOutput()
+	name := file
+	if file == "-" {
+		name = "stdin"
+	}
 
-			// Show warnings if any
-			for _, err := range result.Errors {
-				if len(err) > 0 {
-					color.Yellow("  %s", err)
-				}
-			}
+	if text {
+		info("Validating %s...", name)
+	}
 
-			return nil
+	fail := func(prefix string, err error) validationReport {
+		report.Error = err.Error()
+		if text {
+			color.Red("✗ %s: %v", prefix, err)
 		}
+		return report
+	}
 
-		color.Red("✗ Validation failed")
-		fmt.Println("\nErrors:")
-		for _, err := range result.Errors {
-			color.Red("  %s", err)
+	// Load policy
+	var pol *policy.Policy
+	var err error
+	if file == "-" {
+		pol, err = policy.LoadReader(os.Stdin, format)
+	} else {
+		pol, err = policyLoadOptions().Load(file)
+	}
+	if err != nil {
+		return fail("Failed to load policy", err)
+	}
+
+	if fix {
+		if err := fixPolicyFile(pol, file, backup); err != nil {
+			return fail("Failed to apply fixes", err)
 		}
 
-		return fmt.Errorf("policy validation failed")
-	},
+		// Reload so positions refer to the rewritten file
+		if pol, err = policyLoadOptions().Load(file); err != nil {
+			return fail("Failed to load policy", err)
+		}
+	}
+
+	result := policy.ValidateWithOptions(pol, opts)
+	report.Valid = result.Valid
+	if result.Findings != nil {
+		report.Findings = result.Findings
+	}
+
+	if !text {
+		return report
+	}
+
+	if result.Valid {
+		color.Green("✓ Policy is valid")
+		fmt.Printf("\n%d roles defined\n", len(pol.Roles))
+		fmt.Printf("%d groups defined\n", len(pol.Groups))
+		fmt.Printf("%d service accounts defined\n", len(pol.ServiceAccounts))
+		fmt.Printf("%d projects configured\n", len(pol.Projects))
+		if len(pol.Folders) > 0 || len(pol.Organizations) > 0 {
+			fmt.Printf("%d folders, %d organizations in hierarchy\n", len(pol.Folders), len(pol.Organizations))
+		}
+
+		// Show warnings if any
+		printFindings(result)
+
+		return report
+	}
+
+	color.Red("✗ Validation failed")
+	fmt.Println("\nErrors:")
+	printFindings(result)
+
+	return report
+}
+
+// printFindings prints validation findings compiler style, errors in red
+// and warnings in yellow
+func printFindings(result *policy.ValidationResult) {
+	for _, finding := range result.Findings {
+		if finding.Severity == policy.SeverityError {
+			color.Red("  %s", finding)
+		} else {
+			color.Yellow("  %s", finding)
+		}
+	}
+}
+
+// fixPolicyFile applies policy.Fix and writes the result back to path,
+// copying the original to path.bak first when backup is set
+func fixPolicyFile(pol *policy.Policy, path string, backup bool) error {
+	if pol.IsMerged() {
+		return fmt.Errorf("%s uses includes; fix the included files individually", path)
+	}
+
+	changes := policy.Fix(pol)
+	if len(changes) == 0 {
+		fmt.Println("No fixable issues found")
+		return nil
+	}
+
+	if backup {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read policy for backup: %w", err)
+		}
+		if err := os.WriteFile(path+".bak", data, 0644); err != nil {
+			return fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+
+	if err := policySaveOptions().Save(pol, path); err != nil {
+		return err
+	}
+
+	color.Green("✓ Applied %d fixes", len(changes))
+	for _, change := range changes {
+		fmt.Printf("  %s\n", change)
+	}
+	if backup {
+		fmt.Printf("Original saved to %s.bak\n", path)
+	}
+	fmt.Println()
+	return nil
+}
+
+// initOutputFile returns the --output value of policy init when it is
+// not a format, which is the output file init took as --output before
+// --out, and leaves --output text
+func initOutputFile(cmd *cobra.Command) string {
+	if cmd != policyInitCmd {
+		return ""
+	}
+	switch outputFormat {
+	case OutputText, OutputJSON, OutputYAML:
+		return ""
+	}
+	file := outputFormat
+	outputFormat = OutputText
+	return file
 }
 
 var policyInitCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize a new policy file",
-	Long: `Create a new policy.yaml file from a template.
+	Long: `Create a starter policy file with a custom developer role, a
+developers group, and bindings for one project.
+
+The project, an admin principal, and developer principals are prompted
+for when run in a terminal, or given with --project, --admin, and
+--developers (use --non-interactive to skip prompts). The generated
+policy is validated before it is written to the configured policy file
+(or --out).
 
-Templates:
+Use --template to start from a fixed example instead:
   basic    - Simple developer + CI roles
   advanced - Multiple roles with conditions
   ci       - CI-focused configuration`,
+	Example: `  gcp-emulator policy init
+  gcp-emulator policy init --project my-proj --admin user:me@example.com --non-interactive
+  gcp-emulator policy init --template advanced --out advanced.yaml`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		template, _ := cmd.Flags().GetString("template")
 		force, _ := cmd.Flags().GetBool("force")
-		output, _ := cmd.Flags().GetString("output")
+		output, _ := cmd.Flags().GetString("out")
+
+		if output == "" {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			output = cfg.PolicyFile
+		}
 
 		// Check if file exists
 		if !force {
@@ -94,14 +324,34 @@ Templates:
 			}
 		}
 
-		color.Cyan("Creating policy file: %s", output)
-		color.Cyan("Template: %s", template)
+		var pol *policy.Policy
+		if template != "" {
+			info("Creating policy file: %s", output)
+			info("Template: %s", template)
+
+			// Create policy from template
+			pol = createPolicyFromTemplate(template)
+		} else {
+			opts, err := starterOptionsFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			info("Creating policy file: %s", output)
+			pol = starterPolicy(opts)
 
-		// Create policy from template
-		pol := createPolicyFromTemplate(template)
+			result := policy.Validate(pol)
+			if !result.Valid {
+				color.Red("✗ Generated policy is invalid")
+				for _, err := range result.Errors {
+					color.Red("  %s", err)
+				}
+				return policy.ErrInvalidPolicy
+			}
+		}
 
 		// Save to file
-		if err := policy.Save(pol, output); err != nil {
+		if err := policySaveOptions().Save(pol, output); err != nil {
 			color.Red("✗ Failed to save policy: %v", err)
 			return err
 		}
@@ -109,6 +359,8 @@ Templates:
 		color.Green("✓ Policy file created successfully")
 		fmt.Println("\nEdit the file to customize for your project:")
 		fmt.Printf("  vim %s\n", output)
+		fmt.Println("\nCheck your changes with:")
+		fmt.Printf("  gcp-emulator policy validate %s\n", output)
 		fmt.Println("\nThen start the stack:")
 		fmt.Println("  gcp-emulator start")
 
@@ -116,10 +368,145 @@ Templates:
 	},
 }
 
+// starterOptions are the answers used to generate a starter policy
+type starterOptions struct {
+	Project    string
+	Admin      string
+	Developers []string
+}
+
+// starterOptionsFromFlags reads starter policy options from flags,
+// prompting for the ones not given when stdin is a terminal
+func starterOptionsFromFlags(cmd *cobra.Command) (starterOptions, error) {
+	project, _ := cmd.Flags().GetString("project")
+	admin, _ := cmd.Flags().GetString("admin")
+	developers, _ := cmd.Flags().GetStringSlice("developers")
+	nonInteractive, _ := cmd.Flags().GetBool("non-interactive")
+
+	opts := starterOptions{Project: project, Admin: admin, Developers: developers}
+
+	if !nonInteractive && isTerminal(os.Stdin) {
+		reader := bufio.NewReader(os.Stdin)
+		var err error
+		if !cmd.Flags().Changed("project") {
+			if opts.Project, err = prompt(reader, "Project ID", opts.Project); err != nil {
+				return opts, err
+			}
+		}
+		if !cmd.Flags().Changed("admin") {
+			if opts.Admin, err = prompt(reader, "Admin principal (e.g. user:you@example.com, blank for none)", opts.Admin); err != nil {
+				return opts, err
+			}
+		}
+		if !cmd.Flags().Changed("developers") {
+			answer, err := prompt(reader, "Developer principals, comma-separated", strings.Join(opts.Developers, ","))
+			if err != nil {
+				return opts, err
+			}
+			opts.Developers = splitList(answer)
+		}
+	}
+
+	if opts.Project == "" {
+		return opts, fmt.Errorf("a project ID is required (use --project)")
+	}
+	return opts, nil
+}
+
+// prompt asks for a value on stdout, returning def when the answer is blank
+func prompt(reader *bufio.Reader, label, def string) (string, error) {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", label, def)
+	} else {
+		fmt.Printf("%s: ", label)
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// splitList splits a comma-separated answer, dropping blanks
+func splitList(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// starterPolicy generates a policy with a developer role covering common
+// Secret Manager and KMS permissions, a developers group bound to it, and
+// roles/owner for the admin if one is given
+func starterPolicy(opts starterOptions) *policy.Policy {
+	developers := opts.Developers
+	if len(developers) == 0 {
+		developers = []string{"user:developer@example.com"}
+	}
+
+	bindings := []policy.Binding{
+		{
+			Role:    "roles/custom.developer",
+			Members: []string{"group:developers"},
+		},
+	}
+	if opts.Admin != "" {
+		bindings = append(bindings, policy.Binding{
+			Role:    "roles/owner",
+			Members: []string{opts.Admin},
+		})
+	}
+
+	return &policy.Policy{
+		Version: policy.CurrentVersion,
+		Roles: map[string]policy.Role{
+			"roles/custom.developer": {
+				Permissions: []string{
+					"secretmanager.secrets.create",
+					"secretmanager.secrets.get",
+					"secretmanager.secrets.list",
+					"secretmanager.secrets.update",
+					"secretmanager.versions.add",
+					"secretmanager.versions.access",
+					"secretmanager.versions.list",
+					"cloudkms.keyRings.create",
+					"cloudkms.keyRings.get",
+					"cloudkms.keyRings.list",
+					"cloudkms.cryptoKeys.create",
+					"cloudkms.cryptoKeys.get",
+					"cloudkms.cryptoKeys.list",
+					"cloudkms.cryptoKeys.encrypt",
+					"cloudkms.cryptoKeys.decrypt",
+				},
+			},
+		},
+		Groups: map[string]policy.Group{
+			"developers": {Members: developers},
+		},
+		Projects: map[string]policy.Project{
+			opts.Project: {Bindings: bindings},
+		},
+	}
+}
+
 func createPolicyFromTemplate(template string) *policy.Policy {
 	switch template {
 	case "advanced":
 		return &policy.Policy{
+			Version: policy.CurrentVersion,
 			Roles: map[string]policy.Role{
 				"roles/custom.developer": {
 					Permissions: []string{
@@ -162,6 +549,12 @@ func createPolicyFromTemplate(template string) *policy.Policy {
 					},
 				},
 			},
+			ServiceAccounts: map[string]policy.ServiceAccount{
+				"ci@test-project.iam.gserviceaccount.com": {
+					DisplayName: "CI Runner",
+					Project:     "test-project",
+				},
+			},
 			Projects: map[string]policy.Project{
 				"test-project": {
 					Bindings: []policy.Binding{
@@ -187,6 +580,7 @@ func createPolicyFromTemplate(template string) *policy.Policy {
 		}
 	case "ci":
 		return &policy.Policy{
+			Version: policy.CurrentVersion,
 			Roles: map[string]policy.Role{
 				"roles/custom.ciRunner": {
 					Permissions: []string{
@@ -203,6 +597,16 @@ func createPolicyFromTemplate(template string) *policy.Policy {
 					},
 				},
 			},
+			ServiceAccounts: map[string]policy.ServiceAccount{
+				"ci@test-project.iam.gserviceaccount.com": {
+					DisplayName: "CI Runner",
+					Project:     "test-project",
+				},
+				"github-actions@test-project.iam.gserviceaccount.com": {
+					DisplayName: "GitHub Actions",
+					Project:     "test-project",
+				},
+			},
 			Projects: map[string]policy.Project{
 				"test-project": {
 					Bindings: []policy.Binding{
@@ -216,6 +620,7 @@ func createPolicyFromTemplate(template string) *policy.Policy {
 		}
 	default: // "basic"
 		return &policy.Policy{
+			Version: policy.CurrentVersion,
 			Roles: map[string]policy.Role{
 				"roles/custom.developer": {
 					Permissions: []string{
@@ -253,7 +658,20 @@ func init() {
 	policyCmd.AddCommand(policyValidateCmd)
 	policyCmd.AddCommand(policyInitCmd)
 
-	policyInitCmd.Flags().String("template", "basic", "Template to use (basic|advanced|ci)")
-	policyInitCmd.Flags().BoolP("force", "f", false, "Overwrite existing policy.yaml")
-	policyInitCmd.Flags().String("output", "policy.yaml", "Output file path")
+	policyCmd.PersistentFlags().BoolVar(&policyDecrypt, "decrypt", false, "Allow writing a decrypted policy over an encrypted file")
+
+	policyValidateCmd.Flags().Bool("skip-cel", false, "Skip CEL compilation of condition expressions")
+	policyValidateCmd.Flags().Bool("strict-lint", false, "Treat lint findings such as duplicates as errors")
+	policyValidateCmd.Flags().Bool("gcp-compat", false, "Check that custom role names can be exported to real GCP")
+	policyValidateCmd.Flags().Bool("fix", false, "Apply safe fixes and write the corrected file back")
+	policyValidateCmd.Flags().Bool("no-backup", false, "Don't keep a .bak copy when using --fix")
+	policyValidateCmd.Flags().String("format", "yaml", "Format of a policy read from stdin (yaml|json)")
+
+	policyInitCmd.Flags().String("template", "", "Start from a fixed template instead (basic|advanced|ci)")
+	policyInitCmd.Flags().BoolP("force", "f", false, "Overwrite an existing policy file")
+	policyInitCmd.Flags().String("out", "", "Output file path (defaults to configured policy-file)")
+	policyInitCmd.Flags().String("project", "test-project", "Project ID for the starter bindings")
+	policyInitCmd.Flags().String("admin", "", "Principal granted roles/owner (e.g. user:you@example.com)")
+	policyInitCmd.Flags().StringSlice("developers", nil, "Members of the developers group")
+	policyInitCmd.Flags().Bool("non-interactive", false, "Don't prompt; use flag values and defaults")
 }
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyAnalyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Analyze a policy for structural problems",
+	Long:  `Run analyses that look across bindings for problems no single binding shows.`,
+}
+
+var policyAnalyzeRedundancyCmd = &cobra.Command{
+	Use:         "redundancy [file]",
+	Short:       "Find grants made unnecessary by other bindings",
+	Annotations: renders,
+	Long: `Find member grants that can be deleted without changing what the
+policy allows:
+
+  repeated            the member gets the same role, with the same
+                      condition, from another binding
+  shadowed-condition  the member also gets the role unconditionally, so
+                      the condition never matters
+  subset-role         the role's permissions are a strict subset of
+                      another role the member holds unconditionally
+
+Bindings inherited from folders and the organization are taken into
+account, and each finding names the binding to remove the member from.
+Members are compared as written, without expanding groups.
+
+Without arguments, analyzes the configured policy file. The same checks
+run as lint rule GCP007.`,
+	Example: `  gcp-emulator policy analyze redundancy
+  gcp-emulator policy analyze redundancy policy.yaml --output json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		policyFile := cfg.PolicyFile
+		if len(args) > 0 {
+			policyFile = args[0]
+		}
+
+		pol, err := policyLoadOptions().Load(policyFile)
+		if err != nil {
+			color.Red("✗ Failed to load policy: %v", err)
+			return err
+		}
+
+		found := policy.FindRedundancies(pol)
+
+		if found == nil {
+			found = []policy.Redundancy{}
+		}
+		return render(found, func() {
+			if len(found) == 0 {
+				color.Green("✓ No redundant grants found")
+				return
+			}
+
+			color.Yellow("Found %d redundant grant(s):\n", len(found))
+			for _, r := range found {
+				fmt.Printf("  [%s] %s\n", r.Kind, r.Message)
+			}
+		})
+	},
+}
+
+func init() {
+	policyCmd.AddCommand(policyAnalyzeCmd)
+	policyAnalyzeCmd.AddCommand(policyAnalyzeRedundancyCmd)
+
+}
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyApplyCmd = &cobra.Command{
+	Use:   "apply [file]",
+	Short: "Push a policy to the running IAM emulator",
+	Long: `Validate a policy and hot-load it into the running IAM emulator
+without restarting the stack.
+
+The policy is compared with the one the emulator currently has and the
+differences are printed before anything is changed. Nested groups are
+expanded into individual members, and service accounts, folders,
+organizations, and deny bindings are included.
+
+Without arguments, applies the configured policy file. With --dry-run,
+the diff is shown but the emulator is left unchanged.
+
+If the emulator's policy has changed since this machine last pulled or
+applied it, apply refuses to overwrite it until you pull again; the
+policy is also only replaced if it is unchanged since the diff was taken.
+Use --force to apply regardless.
+
+Requires an IAM emulator that serves the admin policy endpoint. Older
+emulators only read the policy at startup; use 'gcp-emulator restart iam'
+with those instead.`,
+	Example: `  gcp-emulator policy apply
+  gcp-emulator policy apply staging.yaml --dry-run
+  gcp-emulator policy apply --force`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		force, _ := cmd.Flags().GetBool("force")
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		policyFile := cfg.PolicyFile
+		if len(args) > 0 {
+			policyFile = args[0]
+		}
+
+		pol, err := policyLoadOptions().Load(policyFile)
+		if err != nil {
+			color.Red("✗ Failed to load policy: %v", err)
+			return err
+		}
+
+		result := policy.Validate(pol)
+		if !result.Valid {
+			color.Red("✗ Validation failed")
+			fmt.Println("\nErrors:")
+			printFindings(result)
+			return policy.ErrInvalidPolicy
+		}
+
+		desired, err := policy.Flatten(pol)
+		if err != nil {
+			return err
+		}
+
+		client := newIAMClient(cfg)
+
+		current, etag, err := client.PolicyWithETag()
+		if err != nil {
+			printEmulatorError(err)
+			return err
+		}
+
+		if !force {
+			recorded, err := recordedETag(client)
+			if err != nil {
+				return err
+			}
+			if recorded != "" && recorded != etag {
+				printEmulatorError(emulator.ErrPolicyChanged)
+				return emulator.ErrPolicyChanged
+			}
+		}
+
+		color.Cyan("Changes to the IAM emulator policy:")
+		fmt.Println()
+		diff := policy.Diff(current, desired)
+		printPolicyDiff(diff)
+
+		if diff.Empty() {
+			recordETag(client, etag)
+			return nil
+		}
+
+		if dryRun {
+			color.Yellow("\nDry run: policy not applied")
+			return nil
+		}
+
+		precondition := etag
+		if force {
+			precondition = ""
+		}
+		if err := client.ApplyPolicyIfMatch(desired, precondition); err != nil {
+			printEmulatorError(err)
+			return err
+		}
+
+		if _, applied, err := client.PolicyWithETag(); err == nil {
+			recordETag(client, applied)
+		}
+
+		color.Green("\n✓ Policy applied to IAM emulator")
+		return nil
+	},
+}
+
+// printEmulatorError explains an IAM emulator admin API failure
+func printEmulatorError(err error) {
+	switch {
+	case errors.Is(err, emulator.ErrNotRunning):
+		color.Red("✗ %v", err)
+		fmt.Println("\nStart the stack with: gcp-emulator start")
+	case errors.Is(err, emulator.ErrPolicyChanged):
+		color.Red("✗ Policy changed on the emulator since your last pull, re-pull and retry")
+		fmt.Println("\nSee what changed with: gcp-emulator policy drift")
+		fmt.Println("Record the current version with: gcp-emulator policy pull --out <file>")
+		fmt.Println("Or overwrite it anyway with: gcp-emulator policy apply --force")
+	case errors.Is(err, emulator.ErrReloadUnsupported):
+		color.Red("✗ %v", err)
+		fmt.Println("\nUpgrade the IAM emulator image, or restart it to load the policy file:")
+		fmt.Println("  gcp-emulator restart iam")
+	default:
+		color.Red("✗ IAM emulator request failed: %v", err)
+	}
+}
+
+func init() {
+	policyCmd.AddCommand(policyApplyCmd)
+
+	policyApplyCmd.Flags().Bool("dry-run", false, "Show the diff without applying it")
+	policyApplyCmd.Flags().Bool("force", false, "Apply even if the emulator's policy changed since the last pull")
+}
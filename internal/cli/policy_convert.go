@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyConvertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: "Convert a policy file between YAML and JSON",
+	Long: `Convert a policy file between YAML and JSON.
+
+The input and output formats are determined by file extension
+(.yaml, .yml, or .json). The policy is validated before conversion
+unless --no-validate is given.`,
+	Example: `  gcp-emulator policy convert --in policy.yaml --out policy.json
+  gcp-emulator policy convert --in policy.json --out policy.yaml --force`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		in, _ := cmd.Flags().GetString("in")
+		out, _ := cmd.Flags().GetString("out")
+		force, _ := cmd.Flags().GetBool("force")
+		noValidate, _ := cmd.Flags().GetBool("no-validate")
+
+		if in == "" || out == "" {
+			return fmt.Errorf("both --in and --out are required")
+		}
+
+		// Check if output exists
+		if !force {
+			if _, err := os.Stat(out); err == nil {
+				return fmt.Errorf("file %s already exists (use --force to overwrite)", out)
+			}
+		}
+
+		pol, err := policyLoadOptions().Load(in)
+		if err != nil {
+			color.Red("✗ Failed to load policy: %v", err)
+			return err
+		}
+
+		if !noValidate {
+			result := policy.Validate(pol)
+			if !result.Valid {
+				color.Red("✗ Validation failed")
+				fmt.Println("\nErrors:")
+				for _, msg := range result.Errors {
+					if !strings.HasPrefix(msg, "WARNING: ") {
+						color.Red("  %s", msg)
+					}
+				}
+				return fmt.Errorf("%w (use --no-validate to convert anyway)", policy.ErrInvalidPolicy)
+			}
+		}
+
+		if err := policySaveOptions().Save(pol, out); err != nil {
+			color.Red("✗ Failed to save policy: %v", err)
+			return err
+		}
+
+		color.Green("✓ Converted %s → %s", in, out)
+		return nil
+	},
+}
+
+func init() {
+	policyCmd.AddCommand(policyConvertCmd)
+
+	policyConvertCmd.Flags().String("in", "", "Input policy file")
+	policyConvertCmd.Flags().String("out", "", "Output policy file")
+	policyConvertCmd.Flags().BoolP("force", "f", false, "Overwrite existing output file")
+	policyConvertCmd.Flags().Bool("no-validate", false, "Skip validation before converting")
+}
@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyCoverageCmd = &cobra.Command{
+	Use:         "coverage [file]",
+	Short:       "Report which granted permissions were exercised",
+	Annotations: renders,
+	Long: `Pull the IAM emulator's decision log and correlate it with the local
+policy. For each role used by a binding, reports which of its
+permissions were granted to at least one request and which never were;
+also lists bindings that never granted a request and principals that
+never made one. Run it after your test suite to find access that can be
+tightened.
+
+Without arguments, uses the configured policy file. --since limits the
+report to recent decisions, and --out writes the JSON report to a file,
+for example as a CI artifact.
+
+Requires trace mode: run gcp-emulator config set trace true, then stop
+and start the stack.`,
+	Example: `  gcp-emulator policy coverage
+  gcp-emulator policy coverage --since 1h
+  gcp-emulator policy coverage --out coverage.json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		since, _ := cmd.Flags().GetDuration("since")
+		if since < 0 {
+			return fmt.Errorf("invalid --since %s (must be positive)", since)
+		}
+		out, _ := cmd.Flags().GetString("out")
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		policyFile := cfg.PolicyFile
+		if len(args) > 0 {
+			policyFile = args[0]
+		}
+
+		pol, err := policyLoadOptions().Load(policyFile)
+		if err != nil {
+			color.Red("✗ Failed to load policy: %v", err)
+			return err
+		}
+
+		var cutoff time.Time
+		if since > 0 {
+			cutoff = time.Now().Add(-since)
+		}
+
+		var requests []policy.ObservedRequest
+		err = newIAMClient(cfg).Decisions(context.Background(), false, func(d emulator.Decision) error {
+			if d.Time.Before(cutoff) {
+				return nil
+			}
+			requests = append(requests, policy.ObservedRequest{
+				SimulateRequest: policy.SimulateRequest{
+					Principal:   d.Principal,
+					Permission:  d.Permission,
+					Resource:    d.Resource,
+					RequestTime: d.Time,
+				},
+				Allowed: d.Result == "allow",
+			})
+			return nil
+		})
+		if err != nil {
+			printTraceError(err, cfg)
+			return err
+		}
+
+		coverage := policy.ComputeCoverage(pol, requests)
+
+		if out != "" {
+			data, err := json.MarshalIndent(coverage, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal coverage: %w", err)
+			}
+			if err := os.WriteFile(out, append(data, '\n'), 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", out, err)
+			}
+		}
+		return render(coverage, func() { printCoverage(policyFile, since, out, coverage) })
+	},
+}
+
+// printCoverage shows which of a policy file's grants the requests of
+// since exercised
+func printCoverage(policyFile string, since time.Duration, out string, coverage *policy.Coverage) {
+	window := ""
+	if since > 0 {
+		window = fmt.Sprintf(", last %s", since)
+	}
+	color.Cyan("Policy coverage for %s (%d requests%s)", policyFile, coverage.Requests, window)
+
+	if coverage.Requests == 0 {
+		color.Yellow("\n⚠ The decision log has no requests; run your tests against the emulator first")
+	}
+
+	if len(coverage.Roles) > 0 {
+		fmt.Println("\nRoles:")
+		for _, role := range coverage.Roles {
+			total := len(role.Exercised) + len(role.Unexercised)
+			fmt.Printf("  %s  %d/%d permissions exercised (%.0f%%)\n", role.Role, len(role.Exercised), total, role.Percent())
+			for _, perm := range role.Exercised {
+				fmt.Printf("      %s %s\n", color.GreenString("✓"), perm)
+			}
+			for _, perm := range role.Unexercised {
+				fmt.Printf("      %s %s\n", color.RedString("✗"), perm)
+			}
+		}
+	}
+
+	if len(coverage.UnusedBindings) > 0 {
+		fmt.Println("\nBindings that never granted a request:")
+		for _, b := range coverage.UnusedBindings {
+			fmt.Printf("  [%s #%d] %s%s [%s]\n", b.Scope, b.Index, b.Binding.Role, conditionSuffix(b.Binding.Condition), strings.Join(b.Binding.Members, ", "))
+		}
+	}
+
+	if len(coverage.IdlePrincipals) > 0 {
+		fmt.Println("\nPrincipals that made no requests:")
+		for _, principal := range coverage.IdlePrincipals {
+			fmt.Printf("  %s\n", principal)
+		}
+	}
+
+	if coverage.Unmatched > 0 {
+		color.Yellow("\n⚠ %d allowed request(s) are not granted by the local policy; the emulator may be enforcing a different policy (see 'gcp-emulator policy drift')", coverage.Unmatched)
+	}
+
+	if out != "" {
+		color.Green("\n✓ Coverage report written to %s", out)
+	}
+}
+
+func init() {
+	policyCmd.AddCommand(policyCoverageCmd)
+
+	policyCoverageCmd.Flags().Duration("since", 0, "Only count decisions from this long ago or later (e.g. 1h)")
+	policyCoverageCmd.Flags().String("out", "", "Also write the JSON report to this file")
+}
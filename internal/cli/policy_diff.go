@@ -0,0 +1,219 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyDiffCmd = &cobra.Command{
+	Use:         "diff <old> <new>",
+	Short:       "Show a semantic diff between two policy files",
+	Annotations: renders,
+	Long: `Compare two policy files and show what changed.
+
+The comparison is semantic: roles, permissions, group members, service
+accounts, organizations, folders, and the bindings, deny bindings, and
+resource bindings of projects are compared after parsing, so key ordering
+and YAML vs JSON formatting differences are not reported.
+
+Bindings are matched by role and condition expression.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		oldPolicy, err := policyLoadOptions().Load(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", args[0], err)
+		}
+
+		newPolicy, err := policyLoadOptions().Load(args[1])
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", args[1], err)
+		}
+
+		diff := policy.Diff(oldPolicy, newPolicy)
+
+		return render(diff, func() { printPolicyDiff(diff) })
+	},
+}
+
+func printPolicyDiff(diff *policy.PolicyDiff) {
+	if diff.Empty() {
+		color.Green("✓ No changes")
+		return
+	}
+
+	added := color.New(color.FgGreen)
+	removed := color.New(color.FgRed)
+
+	if len(diff.RolesAdded) > 0 || len(diff.RolesRemoved) > 0 || len(diff.RolesChanged) > 0 {
+		color.Cyan("Roles:")
+		for _, name := range diff.RolesAdded {
+			added.Printf("  + %s\n", name)
+		}
+		for _, name := range diff.RolesRemoved {
+			removed.Printf("  - %s\n", name)
+		}
+		for _, role := range diff.RolesChanged {
+			fmt.Printf("  ~ %s\n", role.Name)
+			if role.Description != nil {
+				fmt.Printf("      ~ description %q -> %q\n", role.Description.Old, role.Description.New)
+			}
+			for _, perm := range role.PermissionsAdded {
+				added.Printf("      + %s\n", perm)
+			}
+			for _, perm := range role.PermissionsRemoved {
+				removed.Printf("      - %s\n", perm)
+			}
+		}
+		fmt.Println()
+	}
+
+	if len(diff.GroupsAdded) > 0 || len(diff.GroupsRemoved) > 0 || len(diff.GroupsChanged) > 0 {
+		color.Cyan("Groups:")
+		for _, name := range diff.GroupsAdded {
+			added.Printf("  + %s\n", name)
+		}
+		for _, name := range diff.GroupsRemoved {
+			removed.Printf("  - %s\n", name)
+		}
+		for _, group := range diff.GroupsChanged {
+			fmt.Printf("  ~ %s\n", group.Name)
+			for _, member := range group.MembersAdded {
+				added.Printf("      + %s\n", member)
+			}
+			for _, member := range group.MembersRemoved {
+				removed.Printf("      - %s\n", member)
+			}
+		}
+		fmt.Println()
+	}
+
+	if len(diff.ServiceAccountsAdded) > 0 || len(diff.ServiceAccountsRemoved) > 0 || len(diff.ServiceAccountsChanged) > 0 {
+		color.Cyan("Service accounts:")
+		for _, email := range diff.ServiceAccountsAdded {
+			added.Printf("  + %s\n", email)
+		}
+		for _, email := range diff.ServiceAccountsRemoved {
+			removed.Printf("  - %s\n", email)
+		}
+		for _, email := range diff.ServiceAccountsChanged {
+			fmt.Printf("  ~ %s\n", email)
+		}
+		fmt.Println()
+	}
+
+	if len(diff.OrganizationsAdded) > 0 || len(diff.OrganizationsRemoved) > 0 || len(diff.OrganizationsChanged) > 0 {
+		color.Cyan("Organizations:")
+		printScopeDiffs(diff.OrganizationsAdded, diff.OrganizationsRemoved, diff.OrganizationsChanged, "  ")
+		fmt.Println()
+	}
+
+	if len(diff.FoldersAdded) > 0 || len(diff.FoldersRemoved) > 0 || len(diff.FoldersChanged) > 0 {
+		color.Cyan("Folders:")
+		printScopeDiffs(diff.FoldersAdded, diff.FoldersRemoved, diff.FoldersChanged, "  ")
+		fmt.Println()
+	}
+
+	if len(diff.ProjectsAdded) > 0 || len(diff.ProjectsRemoved) > 0 || len(diff.ProjectsChanged) > 0 {
+		color.Cyan("Projects:")
+		for _, name := range diff.ProjectsAdded {
+			added.Printf("  + %s\n", name)
+		}
+		for _, name := range diff.ProjectsRemoved {
+			removed.Printf("  - %s\n", name)
+		}
+		for _, project := range diff.ProjectsChanged {
+			fmt.Printf("  ~ %s\n", project.Name)
+			printParentChange(project.Parent, "      ")
+			printBindingChanges(project.BindingsAdded, project.BindingsRemoved, project.BindingsChanged, "      ")
+			for _, d := range project.DenyBindingsAdded {
+				added.Printf("      + deny %v to %v%s\n", d.DeniedPermissions, d.DeniedPrincipals, conditionSuffix(d.Condition))
+			}
+			for _, d := range project.DenyBindingsRemoved {
+				removed.Printf("      - deny %v to %v%s\n", d.DeniedPermissions, d.DeniedPrincipals, conditionSuffix(d.Condition))
+			}
+			if len(project.ResourcesAdded) > 0 || len(project.ResourcesRemoved) > 0 || len(project.ResourcesChanged) > 0 {
+				fmt.Println("      resources:")
+				printScopeDiffs(project.ResourcesAdded, project.ResourcesRemoved, project.ResourcesChanged, "        ")
+			}
+		}
+	}
+}
+
+// printScopeDiffs prints the added, removed, and changed folders,
+// organizations, or resources of a diff
+func printScopeDiffs(addedNames, removedNames []string, changed []policy.ScopeDiff, indent string) {
+	added := color.New(color.FgGreen)
+	removed := color.New(color.FgRed)
+
+	for _, name := range addedNames {
+		added.Printf("%s+ %s\n", indent, name)
+	}
+	for _, name := range removedNames {
+		removed.Printf("%s- %s\n", indent, name)
+	}
+	for _, scope := range changed {
+		fmt.Printf("%s~ %s\n", indent, scope.Name)
+		printParentChange(scope.Parent, indent+"    ")
+		printBindingChanges(scope.BindingsAdded, scope.BindingsRemoved, scope.BindingsChanged, indent+"    ")
+	}
+}
+
+// printParentChange prints a changed project or folder parent
+func printParentChange(change *policy.ValueChange, indent string) {
+	if change == nil {
+		return
+	}
+	parent := func(name string) string {
+		if name == "" {
+			return "(none)"
+		}
+		return name
+	}
+	fmt.Printf("%s~ parent %s -> %s\n", indent, parent(change.Old), parent(change.New))
+}
+
+// printBindingChanges prints the binding changes of a project, folder,
+// organization, or resource
+func printBindingChanges(addedBindings, removedBindings []policy.Binding, changed []policy.BindingDiff, indent string) {
+	added := color.New(color.FgGreen)
+	removed := color.New(color.FgRed)
+
+	for _, b := range addedBindings {
+		added.Printf("%s+ binding %s%s %v\n", indent, b.Role, conditionSuffix(b.Condition), b.Members)
+	}
+	for _, b := range removedBindings {
+		removed.Printf("%s- binding %s%s %v\n", indent, b.Role, conditionSuffix(b.Condition), b.Members)
+	}
+	for _, b := range changed {
+		fmt.Printf("%s~ binding %s%s\n", indent, b.Role, conditionSuffix(b.Condition))
+		if b.OldCondition != nil {
+			fmt.Printf("%s    condition title/description changed (title %q -> %q)\n", indent, b.OldCondition.Title, b.Condition.Title)
+		}
+		for _, member := range b.MembersAdded {
+			added.Printf("%s    + %s\n", indent, member)
+		}
+		for _, member := range b.MembersRemoved {
+			removed.Printf("%s    - %s\n", indent, member)
+		}
+	}
+}
+
+// conditionSuffix formats a binding condition for display
+func conditionSuffix(c *policy.Condition) string {
+	if c == nil {
+		return ""
+	}
+	if c.Title != "" {
+		return fmt.Sprintf(" [if %s]", c.Title)
+	}
+	return fmt.Sprintf(" [if %s]", c.Expression)
+}
+
+func init() {
+	policyCmd.AddCommand(policyDiffCmd)
+
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyDocsCmd = &cobra.Command{
+	Use:   "docs [file]",
+	Short: "Generate access documentation from a policy",
+	Long: `Render the policy as a document for security review.
+
+Each project gets a table of principals, the roles they are granted, and
+the permissions those roles give them. Groups are expanded, so every row
+is an individual principal, and grants reached through a group, inherited
+from a folder or organization, or set on a single resource are noted.
+Custom roles and the built-in roles in use are listed with their
+descriptions, and conditions are collected in an appendix and referenced
+by ID (C1, C2, ...).
+
+The output is deterministic, so it can be committed and diffed alongside
+the policy. Without arguments, documents the configured policy file.`,
+	Example: `  gcp-emulator policy docs --out POLICY.md
+  gcp-emulator policy docs policy.yaml --format html --out policy.html`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		out, _ := cmd.Flags().GetString("out")
+
+		if format != "markdown" && format != "html" {
+			return fmt.Errorf("invalid format: %s (must be markdown or html)", format)
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		policyFile := cfg.PolicyFile
+		if len(args) > 0 {
+			policyFile = args[0]
+		}
+
+		pol, err := policyLoadOptions().Load(policyFile)
+		if err != nil {
+			color.Red("✗ Failed to load policy: %v", err)
+			return err
+		}
+
+		docs := policy.BuildDocs(pol)
+
+		data := docs.Markdown()
+		if format == "html" {
+			data, err = docs.HTML()
+			if err != nil {
+				return err
+			}
+		}
+
+		if out == "" {
+			fmt.Print(string(data))
+			return nil
+		}
+
+		if err := os.WriteFile(out, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", out, err)
+		}
+		color.Green("✓ Policy documentation written to %s", out)
+		return nil
+	},
+}
+
+func init() {
+	policyCmd.AddCommand(policyDocsCmd)
+
+	policyDocsCmd.Flags().String("format", "markdown", "Output format (markdown|html)")
+	policyDocsCmd.Flags().String("out", "", "Write the documentation here instead of stdout")
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyEncryptCmd = &cobra.Command{
+	Use:   "encrypt [file]",
+	Short: "Encrypt a policy file with a passphrase",
+	Long: `Encrypt a policy file so it can be committed without exposing service
+account emails or group names. The passphrase is read from
+GCP_EMULATOR_POLICY_KEY, or from the file given by --policy-key-file.
+
+The output defaults to the input with .enc appended. Commands that load
+a policy decrypt files ending in .enc transparently when the passphrase
+is available, so policy.yaml.enc can be used anywhere policy.yaml can.
+Commands that write a policy back refuse to replace an encrypted file
+with plaintext unless --decrypt is given.
+
+The IAM emulator container reads the policy file directly and cannot
+decrypt it; load an encrypted policy into a running stack with
+'gcp-emulator policy apply policy.yaml.enc'.`,
+	Example: `  export GCP_EMULATOR_POLICY_KEY=...
+  gcp-emulator policy encrypt policy.yaml --out policy.yaml.enc
+  gcp-emulator policy validate policy.yaml.enc
+  gcp-emulator policy convert --in policy.yaml.enc --out policy.yaml --decrypt`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out, _ := cmd.Flags().GetString("out")
+		force, _ := cmd.Flags().GetBool("force")
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		policyFile := cfg.PolicyFile
+		if len(args) > 0 {
+			policyFile = args[0]
+		}
+		if out == "" {
+			out = policyFile + policy.EncryptedExt
+		}
+
+		key, err := policyLoadOptions().PolicyKey()
+		if err != nil {
+			color.Red("✗ %v", err)
+			return err
+		}
+
+		data, err := os.ReadFile(policyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read policy file: %w", err)
+		}
+		if policy.IsEncrypted(data) {
+			return fmt.Errorf("%s is already encrypted", policyFile)
+		}
+
+		// Make sure the plaintext is a policy before hiding it
+		if _, err := policyLoadOptions().Load(policyFile); err != nil {
+			color.Red("✗ Failed to load policy: %v", err)
+			return err
+		}
+
+		if !force {
+			if _, err := os.Stat(out); err == nil {
+				return fmt.Errorf("file %s already exists (use --force to overwrite)", out)
+			}
+		}
+
+		encrypted, err := policy.Encrypt(data, key)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(out, encrypted, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", out, err)
+		}
+
+		color.Green("✓ Encrypted %s → %s", policyFile, out)
+		fmt.Printf("  Remove or ignore the plaintext %s before committing\n", policyFile)
+		return nil
+	},
+}
+
+func init() {
+	policyCmd.AddCommand(policyEncryptCmd)
+
+	policyEncryptCmd.Flags().String("out", "", "Encrypted output file (defaults to <file>.enc)")
+	policyEncryptCmd.Flags().BoolP("force", "f", false, "Overwrite an existing output file")
+}
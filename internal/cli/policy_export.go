@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a project's policy in native GCP IAM format",
+	Long: `Export a project's bindings as a google.iam.v1.Policy JSON document.
+
+The output is suitable for 'gcloud projects set-iam-policy'. Custom roles
+are referenced as projects/<project>/roles/<id>; use --roles-dir to also
+write them as role definitions for 'gcloud iam roles create'. Custom
+roles whose IDs GCP would not accept are refused with a suggested name;
+'gcp-emulator policy validate --gcp-compat' checks every role up front.
+
+Local group names (group:developers) are not Google group emails. Either
+replace them before applying, or use --expand-groups to inline members.
+
+Only the project's own bindings are exported by default. Use --effective
+to also include bindings inherited from its folders and organization.
+
+Bindings set on individual secrets and keys are not part of the project
+policy. Use --resources-dir to write one policy per resource, along with
+the gcloud command that applies it.`,
+	Example: `  gcp-emulator policy export --project test-project > iam.json
+  gcp-emulator policy export --project test-project --roles-dir roles/ --out iam.json
+  gcp-emulator policy export --project test-project --resources-dir resources/`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		project, _ := cmd.Flags().GetString("project")
+		format, _ := cmd.Flags().GetString("format")
+		out, _ := cmd.Flags().GetString("out")
+		rolesDir, _ := cmd.Flags().GetString("roles-dir")
+		resourcesDir, _ := cmd.Flags().GetString("resources-dir")
+		expandGroups, _ := cmd.Flags().GetBool("expand-groups")
+		effective, _ := cmd.Flags().GetBool("effective")
+
+		if project == "" {
+			return fmt.Errorf("--project is required")
+		}
+		if format != "gcp-iam" {
+			return fmt.Errorf("unsupported export format: %s (supported: gcp-iam)", format)
+		}
+
+		pol, err := loadPolicyFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		opts := policy.ExportOptions{
+			ExpandGroups: expandGroups,
+			Effective:    effective,
+		}
+		iamPolicy, warnings, err := policy.ExportGCP(pol, project, opts)
+		if err != nil {
+			return err
+		}
+
+		for _, w := range warnings {
+			fmt.Fprintln(os.Stderr, color.YellowString("⚠ %s", w))
+		}
+
+		data, err := json.MarshalIndent(iamPolicy, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal IAM policy: %w", err)
+		}
+		data = append(data, '\n')
+
+		if out == "" {
+			fmt.Print(string(data))
+		} else {
+			if err := os.WriteFile(out, data, 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", out, err)
+			}
+			fmt.Fprintln(os.Stderr, color.GreenString("✓ IAM policy written to %s", out))
+		}
+
+		if rolesDir != "" {
+			if err := exportRoles(pol, project, rolesDir); err != nil {
+				return err
+			}
+		}
+
+		if resourcesDir != "" {
+			if err := exportResources(pol, project, resourcesDir, opts); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
+
+// exportResources writes the policy set on each of the project's resources
+// to resourcesDir
+func exportResources(pol *policy.Policy, project, resourcesDir string, opts policy.ExportOptions) error {
+	if err := os.MkdirAll(resourcesDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", resourcesDir, err)
+	}
+
+	resources := pol.Projects[project].Resources
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		iamPolicy, warnings, err := policy.ExportResourceGCP(pol, project, name, opts)
+		if err != nil {
+			return err
+		}
+		for _, w := range warnings {
+			fmt.Fprintln(os.Stderr, color.YellowString("⚠ %s: %s", name, w))
+		}
+
+		data, err := json.MarshalIndent(iamPolicy, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal IAM policy for %s: %w", name, err)
+		}
+		data = append(data, '\n')
+
+		path := filepath.Join(resourcesDir, strings.ReplaceAll(name, "/", "_")+".json")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+
+		fmt.Fprintln(os.Stderr, color.GreenString("✓ %s → %s", name, path))
+		fmt.Fprintf(os.Stderr, "    %s\n", setIAMPolicyCommand(project, name, path))
+	}
+
+	return nil
+}
+
+// setIAMPolicyCommand returns the gcloud command that applies a resource
+// policy file. Key rings without a location are assumed to be global.
+func setIAMPolicyCommand(project, resource, path string) string {
+	parts := strings.Split(resource, "/")
+	location := "global"
+	if len(parts) > 2 && parts[0] == "locations" {
+		location = parts[1]
+		parts = parts[2:]
+	}
+
+	switch {
+	case len(parts) == 2 && parts[0] == "secrets":
+		return fmt.Sprintf("gcloud secrets set-iam-policy %s %s --project %s", parts[1], path, project)
+	case len(parts) == 2 && parts[0] == "keyRings":
+		return fmt.Sprintf("gcloud kms keyrings set-iam-policy %s %s --location %s --project %s", parts[1], path, location, project)
+	case len(parts) == 4 && parts[0] == "keyRings":
+		return fmt.Sprintf("gcloud kms keys set-iam-policy %s %s --keyring %s --location %s --project %s", parts[3], path, parts[1], location, project)
+	}
+	return fmt.Sprintf("(%s is not a valid resource name; run 'gcp-emulator policy validate')", resource)
+}
+
+// exportRoles writes each custom role bound in the project or on one of its
+// resources to rolesDir
+func exportRoles(pol *policy.Policy, project, rolesDir string) error {
+	if err := os.MkdirAll(rolesDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", rolesDir, err)
+	}
+
+	bindings := append([]policy.Binding{}, pol.Projects[project].Bindings...)
+	for _, resource := range pol.Projects[project].Resources {
+		bindings = append(bindings, resource.Bindings...)
+	}
+
+	seen := make(map[string]bool)
+	for _, binding := range bindings {
+		if _, custom := pol.Roles[binding.Role]; !custom || policy.IsBuiltinRole(binding.Role) || seen[binding.Role] {
+			continue
+		}
+		seen[binding.Role] = true
+
+		id, role, err := policy.ExportRole(pol, binding.Role)
+		if err != nil {
+			return err
+		}
+
+		data, err := yaml.Marshal(role)
+		if err != nil {
+			return fmt.Errorf("failed to marshal role %s: %w", binding.Role, err)
+		}
+
+		path := filepath.Join(rolesDir, id+".yaml")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+
+		fmt.Fprintln(os.Stderr, color.GreenString("✓ %s → %s", binding.Role, path))
+		fmt.Fprintf(os.Stderr, "    gcloud iam roles create %s --project %s --file %s\n", id, project, path)
+	}
+
+	return nil
+}
+
+func init() {
+	policyCmd.AddCommand(policyExportCmd)
+
+	policyExportCmd.Flags().String("file", "", "Policy file (defaults to configured policy-file)")
+	policyExportCmd.Flags().String("project", "", "Project to export")
+	policyExportCmd.Flags().String("format", "gcp-iam", "Export format (gcp-iam)")
+	policyExportCmd.Flags().String("out", "", "Write the IAM policy here instead of stdout")
+	policyExportCmd.Flags().String("roles-dir", "", "Also write custom role definitions to this directory")
+	policyExportCmd.Flags().String("resources-dir", "", "Also write the policy set on each secret and key to this directory")
+	policyExportCmd.Flags().Bool("expand-groups", false, "Replace local group members with the group's principals")
+	policyExportCmd.Flags().Bool("effective", false, "Include bindings inherited from folders and the organization")
+}
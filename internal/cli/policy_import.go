@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import bindings from a GCP IAM policy dump",
+	Long: `Merge the bindings from a real GCP IAM policy into the local policy file.
+
+Reads the output of 'gcloud projects get-iam-policy --format json' and
+merges its bindings under the given project. Empty stub groups are created
+for group: members, and roles that are not built in or defined locally
+produce warnings.
+
+The policy file is created if it does not exist.`,
+	Example: `  gcloud projects get-iam-policy my-real-project --format json > iam-dump.json
+  gcp-emulator policy import --project my-real-project --from iam-dump.json --dry-run`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		project, _ := cmd.Flags().GetString("project")
+		from, _ := cmd.Flags().GetString("from")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		policyFile, _ := cmd.Flags().GetString("file")
+
+		if project == "" || from == "" {
+			return fmt.Errorf("--project and --from are required")
+		}
+
+		if policyFile == "" {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			policyFile = cfg.PolicyFile
+		}
+
+		iamPolicy, err := policy.LoadIAMPolicy(from)
+		if err != nil {
+			return err
+		}
+
+		pol, err := policyLoadOptions().Load(policyFile)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			pol = &policy.Policy{Version: policy.CurrentVersion}
+		case err != nil:
+			color.Red("✗ Failed to load policy: %v", err)
+			return err
+		case pol.IsMerged():
+			return fmt.Errorf("%s uses includes; import into one of the included files instead", policyFile)
+		}
+
+		result := policy.ImportGCP(pol, project, iamPolicy)
+
+		for _, w := range result.Warnings {
+			color.Yellow("⚠ %s", w)
+		}
+
+		if len(result.BindingsAdded) == 0 && len(result.MembersAdded) == 0 && len(result.GroupsCreated) == 0 {
+			color.Green("✓ %s already contains every binding from %s", policyFile, from)
+			return nil
+		}
+
+		color.Cyan("Changes to project %s:", project)
+		for _, b := range result.BindingsAdded {
+			fmt.Printf("  + binding %s%s %v\n", b.Role, conditionSuffix(b.Condition), b.Members)
+		}
+		for _, m := range result.MembersAdded {
+			fmt.Printf("  + member %s\n", m)
+		}
+		for _, g := range result.GroupsCreated {
+			fmt.Printf("  + group stub %s (add members to model it locally)\n", g)
+		}
+
+		if dryRun {
+			info("\nDry run: %s was not modified", policyFile)
+			return nil
+		}
+
+		if err := policySaveOptions().Save(pol, policyFile); err != nil {
+			color.Red("✗ Failed to save policy: %v", err)
+			return err
+		}
+
+		color.Green("\n✓ Imported into %s", policyFile)
+		return nil
+	},
+}
+
+func init() {
+	policyCmd.AddCommand(policyImportCmd)
+
+	policyImportCmd.Flags().String("file", "", "Policy file to import into (defaults to configured policy-file)")
+	policyImportCmd.Flags().String("project", "", "Project key to import bindings under")
+	policyImportCmd.Flags().String("from", "", "GCP IAM policy JSON file")
+	policyImportCmd.Flags().Bool("dry-run", false, "Show what would be imported without writing")
+}
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy/lint"
+)
+
+var policyLintCmd = &cobra.Command{
+	Use:         "lint [file]",
+	Short:       "Lint a policy file for likely mistakes",
+	Annotations: renders,
+	Long: `Check a policy file against style and safety rules.
+
+Without arguments, lints the configured policy file.
+
+Rules can be disabled with --disable or in the config file:
+
+  lint:
+    disable: [GCP005]
+
+Exits non-zero only if an error-severity rule fires, unless
+--warnings-as-errors is given.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		listRules, _ := cmd.Flags().GetBool("list-rules")
+		if listRules {
+			for _, rule := range lint.Rules {
+				fmt.Printf("%s  %-22s %-8s %s\n", rule.ID, rule.Name, rule.Severity, rule.Description)
+			}
+			return nil
+		}
+
+		disable, _ := cmd.Flags().GetStringSlice("disable")
+		warningsAsErrors, _ := cmd.Flags().GetBool("warnings-as-errors")
+
+		for _, id := range disable {
+			if _, ok := lint.LookupRule(id); !ok {
+				return fmt.Errorf("unknown lint rule: %s", id)
+			}
+		}
+
+		policyFile := cfg.PolicyFile
+		if len(args) > 0 {
+			policyFile = args[0]
+		}
+
+		pol, err := policyLoadOptions().Load(policyFile)
+		if err != nil {
+			color.Red("✗ Failed to load policy: %v", err)
+			return err
+		}
+
+		findings := lint.Run(pol, lint.Options{
+			Disable: append(append([]string{}, cfg.Lint.Disable...), disable...),
+		})
+
+		failed := false
+		for _, f := range findings {
+			if f.Severity == lint.SeverityError || warningsAsErrors {
+				failed = true
+			}
+		}
+
+		if findings == nil {
+			findings = []lint.Finding{}
+		}
+		err = render(findings, func() {
+			if len(findings) == 0 {
+				color.Green("✓ No lint findings in %s", policyFile)
+			}
+			for _, f := range findings {
+				line := fmt.Sprintf("  %s %s: %s", f.RuleID, f.Location, f.Message)
+				if f.Severity == lint.SeverityError {
+					color.Red("✗%s", line)
+				} else {
+					color.Yellow("⚠%s", line)
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		if failed {
+			return fmt.Errorf("policy lint failed")
+		}
+		return nil
+	},
+}
+
+func init() {
+	policyCmd.AddCommand(policyLintCmd)
+
+	policyLintCmd.Flags().StringSlice("disable", nil, "Rule IDs to disable (e.g. GCP001,GCP005)")
+	policyLintCmd.Flags().Bool("warnings-as-errors", false, "Exit non-zero on warning-severity findings")
+	policyLintCmd.Flags().Bool("list-rules", false, "List available lint rules")
+}
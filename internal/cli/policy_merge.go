@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyMergeCmd = &cobra.Command{
+	Use:         "merge",
+	Short:       "Three-way merge two edited versions of a policy file",
+	Annotations: renders,
+	Long: `Merge the changes made in two versions of a policy file since their
+common ancestor, working on the policy rather than on YAML lines. Roles,
+groups, bindings, and other entries added on either side are kept;
+permissions and members added on both sides are combined; changes made
+identically on both sides are taken once.
+
+A value changed differently on both sides, such as a role description,
+or an entry changed on one side and deleted on the other, is a conflict.
+The merged policy keeps the value from --ours, the conflicts are written
+with conflict markers to a report next to the output (--report), and the
+command exits with status 1.
+
+Files are merged as written: includes are carried over but not resolved.
+The output format follows the --out extension, or --format when the
+extension is neither .yaml, .yml, nor .json.
+
+To let git resolve policy conflicts with this command, register it as a
+merge driver:
+
+  git config merge.gcp-emulator-policy.name "gcp-emulator policy merge"
+  git config merge.gcp-emulator-policy.driver \
+    "gcp-emulator policy merge --base %O --ours %A --theirs %B --out %A --report %P.conflicts"
+  echo "policy.yaml merge=gcp-emulator-policy" >> .gitattributes
+
+Add --format json to the driver for JSON policy files.`,
+	Example: `  gcp-emulator policy merge --base base.yaml --ours ours.yaml --theirs theirs.yaml --out merged.yaml
+  gcp-emulator policy merge --base base.yaml --ours ours.yaml --theirs theirs.yaml --out merged.yaml --output json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		if format != "" && format != "yaml" && format != "json" {
+			return fmt.Errorf("invalid format: %s (must be yaml or json)", format)
+		}
+		out, _ := cmd.Flags().GetString("out")
+		report, _ := cmd.Flags().GetString("report")
+		if report == "" {
+			report = out + ".conflicts"
+		}
+
+		sides := make(map[string]*policy.Policy)
+		for _, side := range []string{"base", "ours", "theirs"} {
+			file, _ := cmd.Flags().GetString(side)
+			pol, err := policyLoadOptions().LoadFile(file)
+			if err != nil {
+				color.Red("✗ Failed to load %s policy: %v", side, err)
+				return err
+			}
+			sides[side] = pol
+		}
+
+		merged, conflicts := policy.MergeThreeWay(sides["base"], sides["ours"], sides["theirs"])
+
+		switch strings.ToLower(filepath.Ext(out)) {
+		case ".json":
+			format = "json"
+		case ".yaml", ".yml":
+			format = "yaml"
+		}
+		if format == "" {
+			format = "yaml"
+		}
+		data, err := policy.Encode(merged, format)
+		if err != nil {
+			return err
+		}
+		if err := policySaveOptions().CheckPlaintextWrite(out); err != nil {
+			color.Red("✗ Failed to save policy: %v", err)
+			return err
+		}
+		if err := os.WriteFile(out, data, 0644); err != nil {
+			color.Red("✗ Failed to save policy: %v", err)
+			return err
+		}
+
+		if len(conflicts) == 0 {
+			// A report left over from an earlier run no longer applies
+			if err := os.Remove(report); err != nil && !os.IsNotExist(err) {
+				color.Yellow("⚠ Failed to remove stale conflict report %s: %v", report, err)
+			}
+		} else if err := os.WriteFile(report, []byte(policy.FormatConflicts(conflicts)), 0644); err != nil {
+			return fmt.Errorf("failed to write conflict report: %w", err)
+		}
+
+		err = render(conflicts, func() {
+			if len(conflicts) == 0 {
+				color.Green("✓ Merged policy written to %s", out)
+				return
+			}
+			color.Red("✗ %d conflict(s) merging policy; kept ours in %s", len(conflicts), out)
+			for _, c := range conflicts {
+				fmt.Printf("  %s\n", c.Path)
+			}
+			fmt.Printf("\nConflict report written to %s\n", report)
+			fmt.Println("Resolve each conflict in the merged file, then delete the report.")
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(conflicts) > 0 {
+			return fmt.Errorf("%d merge conflict(s)", len(conflicts))
+		}
+		return nil
+	},
+}
+
+func init() {
+	policyCmd.AddCommand(policyMergeCmd)
+
+	policyMergeCmd.Flags().String("base", "", "Common ancestor policy file")
+	policyMergeCmd.Flags().String("ours", "", "Our version of the policy file")
+	policyMergeCmd.Flags().String("theirs", "", "Their version of the policy file")
+	policyMergeCmd.Flags().String("out", "", "Merged policy file to write")
+	policyMergeCmd.Flags().String("report", "", "Conflict report file (defaults to <out>.conflicts)")
+	policyMergeCmd.Flags().String("format", "", "Output format when --out has no .yaml or .json extension (yaml|json)")
+	for _, flag := range []string{"base", "ours", "theirs", "out"} {
+		policyMergeCmd.MarkFlagRequired(flag)
+	}
+}
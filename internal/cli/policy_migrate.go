@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyMigrateCmd = &cobra.Command{
+	Use:   "migrate [file]",
+	Short: "Upgrade a policy file to the current schema version",
+	Long: `Upgrade a policy file to the current schema version.
+
+Renamed or restructured fields from older versions are rewritten and
+the version field is set to the current version. Files without a
+version field, or with version 0, are treated as version 1. A file
+already at the current version is left alone, and nothing is written.
+
+Without arguments, migrates the configured policy file in place.
+Note that rewriting a YAML file does not preserve comments.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		policyFile := cfg.PolicyFile
+		if len(args) > 0 {
+			policyFile = args[0]
+		}
+
+		out, _ := cmd.Flags().GetString("out")
+		if out == "" {
+			out = policyFile
+		}
+
+		pol, from, err := policyLoadOptions().Migrate(policyFile)
+		if err != nil {
+			color.Red("✗ Failed to migrate policy: %v", err)
+			return err
+		}
+
+		if from == policy.CurrentVersion {
+			fmt.Printf("%s is already at version %d; nothing to migrate, no file written\n", policyFile, policy.CurrentVersion)
+			return nil
+		}
+
+		if err := policySaveOptions().Save(pol, out); err != nil {
+			color.Red("✗ Failed to save policy: %v", err)
+			return err
+		}
+
+		color.Green("✓ Migrated %s from version %d to %d", policyFile, from, policy.CurrentVersion)
+		if out != policyFile {
+			fmt.Printf("  Written to %s\n", out)
+		}
+		return nil
+	},
+}
+
+func init() {
+	policyCmd.AddCommand(policyMigrateCmd)
+
+	policyMigrateCmd.Flags().String("out", "", "Write the migrated policy here instead of in place")
+}
@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyPullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Save the policy the running IAM emulator is enforcing",
+	Long: `Fetch the policy currently loaded in the running IAM emulator and write
+it in policy.yaml format (or JSON, for a .json output file).
+
+The result reflects changes made through the emulator's API, such as
+SetIamPolicy calls, as well as anything pushed with 'policy apply'.
+Groups in the pulled policy list individual members, since nested
+groups are expanded when a policy is applied.
+
+The version of the pulled policy is recorded in
+~/.local/state/gcp-emulator/state.json so a later 'policy apply' can
+tell whether someone else changed the emulator in the meantime.`,
+	Example: `  gcp-emulator policy pull --out current.yaml`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out, _ := cmd.Flags().GetString("out")
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		client := newIAMClient(cfg)
+		current, etag, err := client.PolicyWithETag()
+		if err != nil {
+			printEmulatorError(err)
+			return err
+		}
+
+		if err := policySaveOptions().Save(current, out); err != nil {
+			color.Red("✗ Failed to save policy: %v", err)
+			return err
+		}
+		recordETag(client, etag)
+
+		color.Green("✓ Saved emulator policy to %s", out)
+		fmt.Printf("  %d roles, %d groups, %d projects\n", len(current.Roles), len(current.Groups), len(current.Projects))
+		return nil
+	},
+}
+
+var policyDriftCmd = &cobra.Command{
+	Use:         "drift [file]",
+	Short:       "Compare the local policy with the running IAM emulator",
+	Annotations: renders,
+	Long: `Fetch the policy currently loaded in the running IAM emulator and show a
+semantic diff against the local policy file. Lines marked + exist only in
+the emulator; lines marked - exist only in the file.
+
+Nested groups in the local file are expanded before comparing, matching
+what 'policy apply' sends. Exits non-zero when the two differ, so it can
+guard CI jobs against policy changed behind the file's back.
+
+Without arguments, compares the configured policy file.`,
+	Example: `  gcp-emulator policy drift
+  gcp-emulator policy drift staging.yaml --output json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		policyFile := cfg.PolicyFile
+		if len(args) > 0 {
+			policyFile = args[0]
+		}
+
+		pol, err := policyLoadOptions().Load(policyFile)
+		if err != nil {
+			color.Red("✗ Failed to load policy: %v", err)
+			return err
+		}
+
+		local, err := policy.Flatten(pol)
+		if err != nil {
+			return err
+		}
+
+		current, err := newIAMClient(cfg).Policy()
+		if err != nil {
+			printEmulatorError(err)
+			return err
+		}
+
+		diff := policy.Diff(local, current)
+
+		err = render(diff, func() {
+			color.Cyan("Drift between %s and the IAM emulator:", policyFile)
+			fmt.Println()
+			printPolicyDiff(diff)
+		})
+		if err != nil {
+			return err
+		}
+
+		if !diff.Empty() {
+			return fmt.Errorf("policy drift detected")
+		}
+		return nil
+	},
+}
+
+// recordETag saves the version of the emulator's policy this machine last
+// pulled or applied. Failing to save only warns, since the record guards
+// 'policy apply' but is not required for it.
+func recordETag(client *emulator.IAMClient, etag string) {
+	path, err := emulator.DefaultStatePath()
+	if err == nil {
+		var state *emulator.State
+		if state, err = emulator.LoadState(path); err == nil {
+			state.Policies[client.BaseURL] = emulator.PolicyState{ETag: etag, UpdatedAt: time.Now().UTC()}
+			err = state.Save(path)
+		}
+	}
+	if err != nil {
+		color.Yellow("⚠ Could not record the emulator policy version: %v", err)
+	}
+}
+
+// recordedETag returns the version recorded by the last pull or apply
+// against the emulator, or "" if there is none
+func recordedETag(client *emulator.IAMClient) (string, error) {
+	path, err := emulator.DefaultStatePath()
+	if err != nil {
+		return "", err
+	}
+	state, err := emulator.LoadState(path)
+	if err != nil {
+		return "", err
+	}
+	return state.Policies[client.BaseURL].ETag, nil
+}
+
+func init() {
+	policyCmd.AddCommand(policyPullCmd)
+	policyCmd.AddCommand(policyDriftCmd)
+
+	policyPullCmd.Flags().String("out", "", "File to write the pulled policy to (.yaml or .json)")
+	policyPullCmd.MarkFlagRequired("out")
+
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyRolesCmd = &cobra.Command{
+	Use:   "roles",
+	Short: "Inspect the built-in role catalog",
+	Long: `Inspect the catalog of GCP predefined roles known to the policy tools.
+
+Built-in roles can be used in bindings without a roles: definition.
+Their permissions come from this catalog when simulating and when
+listing who holds a permission.`,
+}
+
+var policyRolesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List built-in roles",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, name := range policy.BuiltinRoles() {
+			role, _ := policy.DescribeRole(name)
+			fmt.Printf("%-45s %s\n", name, role.Title)
+		}
+		return nil
+	},
+}
+
+var policyRolesDescribeCmd = &cobra.Command{
+	Use:   "describe <role>",
+	Short: "Print the permissions of a role",
+	Long: `Print the permissions of a built-in role, or of a custom role defined
+in the configured policy file, with those of its includeRoles. A role
+the policy file defines takes precedence over the built-in one.`,
+	Example: `  gcp-emulator policy roles describe roles/secretmanager.admin
+  gcp-emulator policy roles describe roles/custom.developer`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeRoles,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// A role the policy defines takes precedence, as when simulating;
+		// a policy file that can't be loaded leaves the catalog
+		if pol, policyFile, err := loadConfiguredPolicy(); err == nil {
+			if role, ok := pol.Roles[args[0]]; ok {
+				color.Cyan("%s (custom role in %s)", args[0], policyFile)
+				if role.Description != "" {
+					fmt.Println(role.Description)
+				}
+				printPermissions(policy.RolePermissions(pol, args[0]))
+				return nil
+			}
+		}
+
+		if role, ok := policy.DescribeRole(args[0]); ok {
+			color.Cyan("%s (%s)", args[0], role.Title)
+			printPermissions(role.Permissions)
+			return nil
+		}
+
+		color.Red("✗ %s is not in the built-in role catalog or the policy file", args[0])
+		return fmt.Errorf("unknown role: %s", args[0])
+	},
+}
+
+func printPermissions(permissions []string) {
+	fmt.Printf("\n%d permissions:\n", len(permissions))
+	for _, perm := range permissions {
+		fmt.Printf("  %s\n", perm)
+	}
+}
+
+// loadConfiguredPolicy loads the configured policy file, returning it
+// with its path
+func loadConfiguredPolicy() (*policy.Policy, string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, "", err
+	}
+	pol, err := policyLoadOptions().Load(cfg.PolicyFile)
+	if err != nil {
+		return nil, "", err
+	}
+	return pol, cfg.PolicyFile, nil
+}
+
+func init() {
+	policyCmd.AddCommand(policyRolesCmd)
+	policyRolesCmd.AddCommand(policyRolesListCmd)
+	policyRolesCmd.AddCommand(policyRolesDescribeCmd)
+}
@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policySimulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Check whether a principal has a permission on a resource",
+	Long: `Simulate an authorization check against the policy file.
+
+Groups are expanded, roles are resolved to permissions, and binding
+conditions are evaluated against the resource and request time. Bindings
+inherited from the project's folders and organization are included and
+labelled with the level they are attached to.
+
+Prints ALLOW or DENY with the bindings that granted (or would have
+granted) access. A project deny binding covering the principal and
+permission overrides any allow. Exits 0 on allow and 1 on deny; a
+missing flag or an invalid --request-time or --resource exits 2, and a
+policy file that can't be loaded 4.`,
+	Example: `  gcp-emulator policy simulate \
+    --principal user:alice@example.com \
+    --permission secretmanager.secrets.get \
+    --resource projects/test-project/secrets/db-password`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		principal, _ := cmd.Flags().GetString("principal")
+		permission, _ := cmd.Flags().GetString("permission")
+		resource, _ := cmd.Flags().GetString("resource")
+		requestTimeStr, _ := cmd.Flags().GetString("request-time")
+
+		if principal == "" || permission == "" || resource == "" {
+			return fmt.Errorf("%w: --principal, --permission, and --resource are required", config.ErrInvalidValue)
+		}
+
+		var requestTime time.Time
+		if requestTimeStr != "" {
+			t, err := time.Parse(time.RFC3339, requestTimeStr)
+			if err != nil {
+				return fmt.Errorf("%w for --request-time: %w (expected RFC3339, e.g. 2026-01-01T00:00:00Z)", config.ErrInvalidValue, err)
+			}
+			requestTime = t
+		}
+
+		pol, err := loadPolicyFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		decision, err := policy.Simulate(pol, policy.SimulateRequest{
+			Principal:   principal,
+			Permission:  permission,
+			Resource:    resource,
+			RequestTime: requestTime,
+		})
+		if err != nil {
+			return fmt.Errorf("%w for --resource: %w", config.ErrInvalidValue, err)
+		}
+
+		if decision.Allowed {
+			color.Green("✓ ALLOW")
+		} else {
+			color.Red("✗ DENY")
+		}
+		fmt.Printf("\n  Principal:  %s\n", principal)
+		fmt.Printf("  Permission: %s\n", permission)
+		fmt.Printf("  Resource:   %s\n", resource)
+
+		if len(decision.Matches) == 0 {
+			fmt.Printf("\nNo binding in project %s grants %s to %s\n", decision.Project, permission, principal)
+		} else {
+			fmt.Println("\nBindings:")
+			for _, m := range decision.Matches {
+				status := color.GreenString("granted")
+				if !m.Granted {
+					status = color.RedString("not granted: %s", m.Reason)
+				}
+				fmt.Printf("  [%s] %s%s via %s — %s\n", scopedIndex(m.Scope, decision.Project, m.Index), m.Binding.Role, conditionSuffix(m.Binding.Condition), describeMatch(m.Member, m.Via), status)
+			}
+		}
+
+		if len(decision.Denials) > 0 {
+			fmt.Println("\nDeny bindings:")
+			for _, d := range decision.Denials {
+				status := color.RedString("denied")
+				if !d.Applied {
+					status = color.GreenString("not applied: %s", d.Reason)
+				} else if d.Reason != "" {
+					status = color.RedString("denied: %s", d.Reason)
+				}
+				fmt.Printf("  [%d] %s%s — %s\n", d.Index, describeMatch(d.Member, d.Via), conditionSuffix(d.DenyBinding.Condition), status)
+			}
+		}
+
+		if !decision.Allowed {
+			return policy.ErrDenied
+		}
+		return nil
+	},
+}
+
+// loadPolicyFlag loads the policy named by --file, falling back to the
+// configured policy file
+func loadPolicyFlag(cmd *cobra.Command) (*policy.Policy, error) {
+	policyFile, _ := cmd.Flags().GetString("file")
+	if policyFile == "" {
+		cfg, err := config.Load()
+		if err != nil {
+			return nil, err
+		}
+		policyFile = cfg.PolicyFile
+	}
+
+	pol, err := policyLoadOptions().Load(policyFile)
+	if err != nil {
+		color.Red("✗ Failed to load policy: %v", err)
+		return nil, err
+	}
+	return pol, nil
+}
+
+// describeMatch formats how a binding member matched a principal
+func describeMatch(member string, via []string) string {
+	if len(via) <= 1 {
+		return member
+	}
+	return fmt.Sprintf("%s (%s)", member, strings.Join(via, " → "))
+}
+
+// scopedIndex labels a binding index, naming the folder or organization
+// for bindings inherited by the project
+func scopedIndex(scope, project string, index int) string {
+	if scope == "" || scope == "projects/"+project {
+		return fmt.Sprintf("%d", index)
+	}
+	return fmt.Sprintf("%s #%d", scope, index)
+}
+
+func init() {
+	policyCmd.AddCommand(policySimulateCmd)
+
+	policySimulateCmd.Flags().String("file", "", "Policy file (defaults to configured policy-file)")
+	policySimulateCmd.Flags().String("principal", "", "Principal to check (e.g. user:alice@example.com)")
+	policySimulateCmd.Flags().String("permission", "", "Permission to check (e.g. secretmanager.secrets.get)")
+	policySimulateCmd.Flags().String("resource", "", "Full resource name (e.g. projects/test-project/secrets/db-password)")
+	policySimulateCmd.Flags().String("request-time", "", "Request time for condition evaluation (RFC3339, defaults to now)")
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyStatsCmd = &cobra.Command{
+	Use:         "stats [file]",
+	Short:       "Summarize the size and shape of a policy",
+	Annotations: renders,
+	Long: `Report counts of roles, groups, projects, and bindings, the number of
+unique principals (with groups expanded), conditional vs unconditional
+bindings, a histogram of permissions per role, and the ten permissions
+held by the most principals.
+
+Without arguments, summarizes the configured policy file.`,
+	Example: `  gcp-emulator policy stats policy.yaml
+  gcp-emulator policy stats --output json | jq .topPermissions`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		policyFile := cfg.PolicyFile
+		if len(args) > 0 {
+			policyFile = args[0]
+		}
+
+		pol, err := policyLoadOptions().Load(policyFile)
+		if err != nil {
+			color.Red("✗ Failed to load policy: %v", err)
+			return err
+		}
+
+		stats := policy.ComputeStats(pol)
+		return render(stats, func() { printPolicyStats(policyFile, stats) })
+	},
+}
+
+// printPolicyStats shows the statistics of a policy file as tables
+func printPolicyStats(policyFile string, stats policy.Stats) {
+	color.Cyan("Policy statistics for %s", policyFile)
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Roles\t%d\n", stats.Roles)
+	fmt.Fprintf(w, "Groups\t%d\n", stats.Groups)
+	fmt.Fprintf(w, "Service accounts\t%d\n", stats.ServiceAccounts)
+	fmt.Fprintf(w, "Projects\t%d\n", stats.Projects)
+	if stats.Folders > 0 || stats.Organizations > 0 {
+		fmt.Fprintf(w, "Folders\t%d\n", stats.Folders)
+		fmt.Fprintf(w, "Organizations\t%d\n", stats.Organizations)
+	}
+	fmt.Fprintf(w, "Bindings\t%d (%d conditional, %d unconditional)\n", stats.Bindings, stats.ConditionalBindings, stats.UnconditionalBindings)
+	if stats.DenyBindings > 0 {
+		fmt.Fprintf(w, "Deny bindings\t%d\n", stats.DenyBindings)
+	}
+	fmt.Fprintf(w, "Unique members\t%d\n", stats.UniqueMembers)
+	w.Flush()
+
+	if len(stats.PermissionsPerRole) > 0 {
+		color.Cyan("\nPermissions per role")
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PERMISSIONS\tROLES\t")
+		for _, bucket := range stats.PermissionsPerRole {
+			fmt.Fprintf(w, "%d\t%d\t%s\n", bucket.Permissions, bucket.Roles, strings.Repeat("█", bucket.Roles))
+		}
+		w.Flush()
+	}
+
+	if len(stats.TopPermissions) > 0 {
+		color.Cyan("\nMost granted permissions")
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PERMISSION\tPRINCIPALS")
+		for _, p := range stats.TopPermissions {
+			fmt.Fprintf(w, "%s\t%d\n", p.Permission, p.Principals)
+		}
+		w.Flush()
+	}
+}
+
+func init() {
+	policyCmd.AddCommand(policyStatsCmd)
+}
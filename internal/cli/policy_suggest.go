@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policySuggestCmd = &cobra.Command{
+	Use:   "suggest",
+	Short: "Draft a policy from the requests the IAM emulator has seen",
+	Long: `Read the IAM emulator's decision log and draft a policy granting each
+principal exactly the permissions it used, on the projects it used them
+in. Run your app against the stack in permissive mode first, so every
+request goes through and is logged.
+
+Permissions are collected into one custom role per principal, or per
+principal and service with --group-by service. Every generated role is
+described as a draft; review them before switching to strict mode.
+
+By default the draft is printed to stdout and no file is changed. --out
+writes it to a file, and --merge adds the suggested grants to the
+configured policy file (or --file) instead of starting from an empty
+policy, writing it in place unless --out is also given. Running suggest
+--merge again extends the draft roles it created before. The result is
+validated before anything is written.
+
+Requires trace mode: run gcp-emulator config set trace true, then stop
+and start the stack.`,
+	Example: `  gcp-emulator policy suggest --since 30m
+  gcp-emulator policy suggest --group-by service --out draft.yaml
+  gcp-emulator policy suggest --merge`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		groupBy, _ := cmd.Flags().GetString("group-by")
+		since, _ := cmd.Flags().GetDuration("since")
+		if since < 0 {
+			return fmt.Errorf("invalid --since %s (must be positive)", since)
+		}
+		out, _ := cmd.Flags().GetString("out")
+		merge, _ := cmd.Flags().GetBool("merge")
+		policyFile, _ := cmd.Flags().GetString("file")
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		if policyFile == "" {
+			policyFile = cfg.PolicyFile
+		}
+
+		pol := &policy.Policy{Version: policy.CurrentVersion}
+		if merge {
+			pol, err = policyLoadOptions().Load(policyFile)
+			if err != nil {
+				color.Red("✗ Failed to load policy: %v", err)
+				return err
+			}
+			if pol.IsMerged() {
+				return fmt.Errorf("%s uses includes; merge into one of the included files instead", policyFile)
+			}
+			if out == "" {
+				out = policyFile
+			}
+		}
+
+		var cutoff time.Time
+		if since > 0 {
+			cutoff = time.Now().Add(-since)
+		}
+
+		var requests []policy.SimulateRequest
+		err = newIAMClient(cfg).Decisions(context.Background(), false, func(d emulator.Decision) error {
+			if d.Time.Before(cutoff) {
+				return nil
+			}
+			requests = append(requests, policy.SimulateRequest{
+				Principal:  d.Principal,
+				Permission: d.Permission,
+				Resource:   d.Resource,
+			})
+			return nil
+		})
+		if err != nil {
+			printTraceError(err, cfg)
+			return err
+		}
+		if len(requests) == 0 {
+			err := errors.New("the decision log has no requests to suggest a policy from")
+			color.Red("✗ %v", err)
+			fmt.Println("\nRun your app against the stack in permissive mode with trace enabled, then try again.")
+			return err
+		}
+
+		result, err := policy.Suggest(pol, requests, policy.SuggestOptions{GroupBy: groupBy})
+		if err != nil {
+			return err
+		}
+
+		validation := policy.Validate(pol)
+		if !validation.Valid {
+			color.Red("✗ The suggested policy is invalid, so it was not written:")
+			printFindings(validation)
+			return fmt.Errorf("suggested policy failed validation")
+		}
+
+		status := os.Stderr
+		if result.Skipped > 0 {
+			fmt.Fprintln(status, color.YellowString("⚠ Skipped %d request(s) on resources outside a project", result.Skipped))
+		}
+		if merge && result.Empty() {
+			fmt.Fprintln(status, color.GreenString("✓ %s already grants everything in the decision log", policyFile))
+			return nil
+		}
+
+		format := "yaml"
+		if strings.ToLower(filepath.Ext(out)) == ".json" {
+			format = "json"
+		}
+		data, err := policy.Encode(pol, format)
+		if err != nil {
+			return err
+		}
+		if format == "yaml" && !merge {
+			header := fmt.Sprintf("# DRAFT generated by 'gcp-emulator policy suggest' from %d request(s).\n# Review every role before using this policy in strict mode.\n", len(requests))
+			data = append([]byte(header), data...)
+		}
+
+		if out == "" {
+			fmt.Print(string(data))
+			return nil
+		}
+
+		if err := policySaveOptions().CheckPlaintextWrite(out); err != nil {
+			color.Red("✗ Failed to save policy: %v", err)
+			return err
+		}
+		if err := os.WriteFile(out, data, 0644); err != nil {
+			color.Red("✗ Failed to save policy: %v", err)
+			return err
+		}
+
+		for _, role := range result.RolesAdded {
+			fmt.Printf("  + role %s\n", role)
+		}
+		for _, role := range result.RolesExtended {
+			fmt.Printf("  ~ role %s\n", role)
+		}
+		for _, binding := range result.BindingsAdded {
+			fmt.Printf("  + binding %s\n", binding)
+		}
+		if merge {
+			color.Green("\n✓ Suggested grants from %d request(s) merged into %s", len(requests), out)
+		} else {
+			color.Green("\n✓ Draft policy written to %s from %d request(s)", out, len(requests))
+		}
+		fmt.Println("  Review the draft roles before switching to strict mode")
+		return nil
+	},
+}
+
+func init() {
+	policyCmd.AddCommand(policySuggestCmd)
+
+	policySuggestCmd.Flags().String("group-by", policy.SuggestByPrincipal, "Create one role per principal or per principal and service (principal|service)")
+	policySuggestCmd.Flags().Duration("since", 0, "Only use decisions from this long ago or later (e.g. 30m)")
+	policySuggestCmd.Flags().String("out", "", "Write the policy to this file instead of stdout")
+	policySuggestCmd.Flags().Bool("merge", false, "Add the suggestions to the policy file instead of starting from an empty policy")
+	policySuggestCmd.Flags().String("file", "", "Policy file to merge into (defaults to configured policy-file)")
+}
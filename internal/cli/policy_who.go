@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyWhoCmd = &cobra.Command{
+	Use:     "who",
+	Aliases: []string{"explain"},
+	Short:   "List the effective permissions of a principal",
+	Long: `List every permission a principal holds in a project.
+
+Group memberships are expanded and roles are resolved to permissions.
+Permissions are grouped by the binding that grants them, and conditional
+grants are marked with the condition title. Bindings inherited from
+folders and the organization are labelled with their level.`,
+	Example: `  gcp-emulator policy who --principal user:alice@example.com --project test-project`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		principal, _ := cmd.Flags().GetString("principal")
+		project, _ := cmd.Flags().GetString("project")
+
+		if principal == "" || project == "" {
+			return fmt.Errorf("--principal and --project are required")
+		}
+
+		pol, err := loadPolicyFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := pol.Projects[project]; !ok {
+			return fmt.Errorf("project %s is not defined in the policy", project)
+		}
+
+		grants := policy.EffectivePermissions(pol, principal, project)
+
+		color.Cyan("Effective permissions for %s in %s", principal, project)
+		if len(grants) == 0 {
+			fmt.Println("\nNo bindings apply to this principal")
+			return nil
+		}
+
+		unconditional := make(map[string]bool)
+		for _, g := range grants {
+			fmt.Printf("\n[%s] %s via %s\n", scopedIndex(g.Scope, project, g.Index), g.Binding.Role, describeMatch(g.Member, g.Via))
+			if g.Conditional() {
+				title := g.Binding.Condition.Title
+				if title == "" {
+					title = g.Binding.Condition.Expression
+				}
+				color.Yellow("    conditional: %s", title)
+			}
+
+			if len(g.Permissions) == 0 {
+				color.Yellow("    (role has no permissions in this policy or the built-in catalog)")
+			}
+			for _, perm := range g.Permissions {
+				fmt.Printf("    %s\n", perm)
+				if !g.Conditional() {
+					unconditional[perm] = true
+				}
+			}
+		}
+
+		all := make(map[string]bool)
+		for _, g := range grants {
+			for _, perm := range g.Permissions {
+				all[perm] = true
+			}
+		}
+
+		fmt.Printf("\nSummary: %d permissions (%d unconditional)\n", len(all), len(unconditional))
+		return nil
+	},
+}
+
+func init() {
+	policyCmd.AddCommand(policyWhoCmd)
+
+	policyWhoCmd.Flags().String("file", "", "Policy file (defaults to configured policy-file)")
+	policyWhoCmd.Flags().String("principal", "", "Principal to explain (e.g. user:alice@example.com)")
+	policyWhoCmd.Flags().String("project", "", "Project to explain")
+}
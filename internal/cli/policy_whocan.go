@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyWhoCanCmd = &cobra.Command{
+	Use:         "who-can",
+	Short:       "List the principals that hold a permission",
+	Annotations: renders,
+	Long: `List every user and service account holding a permission in a project.
+
+All bindings, including those inherited from folders and the organization
+and those set on the project's secrets, key rings, and crypto keys, are
+walked and groups are expanded into individual members.
+Each row notes whether access comes from an unconditional or conditional
+binding. Principals covered by a deny binding are left out, or marked
+when the deny is conditional. allUsers and allAuthenticatedUsers are
+reported with a warning.`,
+	Example: `  gcp-emulator policy who-can --permission cloudkms.cryptoKeys.decrypt --project test-project
+  gcp-emulator policy who-can --permission secretmanager.versions.access --project test-project --output json | jq`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		permission, _ := cmd.Flags().GetString("permission")
+		project, _ := cmd.Flags().GetString("project")
+
+		if permission == "" || project == "" {
+			return fmt.Errorf("--permission and --project are required")
+		}
+
+		pol, err := loadPolicyFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := pol.Projects[project]; !ok {
+			return fmt.Errorf("project %s is not defined in the policy", project)
+		}
+
+		holders := policy.PrincipalsWithPermission(pol, permission, project)
+
+		if holders == nil {
+			holders = []policy.Holder{}
+		}
+		return render(holders, func() { printHolders(holders, permission, project) })
+	},
+}
+
+// printHolders shows the principals holding permission in project as a
+// table, and warns of public ones
+func printHolders(holders []policy.Holder, permission, project string) {
+	if len(holders) == 0 {
+		fmt.Printf("No principals hold %s in %s\n", permission, project)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PRINCIPAL\tROLE\tBINDING\tVIA\tACCESS")
+	for _, h := range holders {
+		via := "-"
+		if len(h.Via) > 0 {
+			via = strings.Join(h.Via, " → ")
+		}
+		access := "unconditional"
+		if h.Conditional() {
+			title := h.Condition.Title
+			if title == "" {
+				title = h.Condition.Expression
+			}
+			access = "conditional: " + title
+		}
+		for _, deny := range h.DenyConditions {
+			title := deny.Title
+			if title == "" {
+				title = deny.Expression
+			}
+			access += " (denied when: " + title + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", h.Principal, h.Role, scopedIndex(h.Scope, project, h.Index), via, access)
+	}
+	w.Flush()
+
+	for _, h := range holders {
+		if h.Public() {
+			color.Yellow("\n⚠ %s holds %s via %s (binding %s) — this is usually a mistake in a local policy", h.Principal, permission, h.Role, scopedIndex(h.Scope, project, h.Index))
+		}
+	}
+}
+
+func init() {
+	policyCmd.AddCommand(policyWhoCanCmd)
+
+	policyWhoCanCmd.Flags().String("file", "", "Policy file (defaults to configured policy-file)")
+	policyWhoCanCmd.Flags().String("permission", "", "Permission to look up (e.g. cloudkms.cryptoKeys.decrypt)")
+	policyWhoCanCmd.Flags().String("project", "", "Project to search")
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+)
+
+var pullCmd = &cobra.Command{
+	Use:   "pull [service...]",
+	Short: "Pull the emulator images",
+	Long: `Pull the configured images of all services or the services given,
+showing docker's progress for each.
+
+An image reference that pins a digest (image@sha256:...) is checked
+against the pulled image, and a mismatch fails. pull exits non-zero if
+any image can't be pulled.
+
+Services: iam, secret-manager, kms`,
+	Example: `  gcp-emulator pull
+  gcp-emulator pull kms`,
+	ValidArgsFunction: completeServices,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		services := docker.Services
+		if len(args) > 0 {
+			services = make([]string, 0, len(args))
+			for _, arg := range args {
+				service, err := docker.LookupService(arg)
+				if err != nil {
+					return err
+				}
+				services = append(services, service)
+			}
+		}
+		if err := requireDaemon(cfg); err != nil {
+			return err
+		}
+		return pullImages(cfg, services, config.PullAlways)
+	},
+}
+
+// pullImages pulls the images of services as policy says: every one with
+// always, those not present locally with missing. With never, it only
+// checks that they are present.
+func pullImages(cfg *config.Config, services []string, policy string) error {
+	for _, service := range services {
+		ref := docker.ServiceImage(cfg, service)
+		if policy != config.PullAlways {
+			present, err := docker.ImagePresent(cfg, ref)
+			if err != nil {
+				color.Red("✗ Failed to check image %s: %v", ref, err)
+				printDockerHint(err)
+				return err
+			}
+			if present {
+				continue
+			}
+			if policy == config.PullNever {
+				err := fmt.Errorf("%w: %s for %s, and pull-policy is never", docker.ErrImageNotPresent, ref, service)
+				color.Red("✗ %v", err)
+				return err
+			}
+		}
+
+		info("→ Pulling %s (%s)...", service, ref)
+		if err := docker.PullImage(cfg, service, os.Stdout); err != nil {
+			color.Red("✗ Failed to pull %s: %v", service, err)
+			printDockerHint(err)
+			return err
+		}
+		color.Green("✓ Pulled %s", service)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+)
+
+var (
+	resetYes     bool
+	resetRestart bool
+)
+
+var resetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Stop the stack and remove its volumes",
+	Long: `Stop the emulator stack and remove its volumes, wiping the state the
+emulators keep between runs.
+
+Only volumes of this stack's compose project are removed, found by the
+label compose puts on them; volumes of other projects are never touched.
+The volumes removed are listed afterwards.
+
+reset asks for confirmation first, unless --yes is given; without a
+terminal to ask on, --yes is required. --restart starts the stack again
+afterwards, like 'gcp-emulator start'.`,
+	Example: `  gcp-emulator reset
+  gcp-emulator reset --yes --restart`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		volumes, err := docker.Volumes(cfg)
+		if err != nil {
+			color.Red("✗ Failed to list volumes: %v", err)
+			printDockerHint(err)
+			return err
+		}
+
+		if !resetYes {
+			if !isTerminal(os.Stdin) {
+				return errors.New("reset removes the stack's volumes; pass --yes to confirm")
+			}
+			fmt.Printf("This stops project %s and removes %s.\n", docker.ProjectName(cfg), volumeCount(len(volumes)))
+			answer, err := prompt(bufio.NewReader(os.Stdin), "Continue? [y/N]", "")
+			if err != nil {
+				return err
+			}
+			if a := strings.ToLower(answer); a != "y" && a != "yes" {
+				color.Yellow("⚠ Reset cancelled")
+				return nil
+			}
+		}
+
+		info("Resetting GCP Emulator Control Plane...")
+		removed, err := docker.Reset(cfg)
+		if err != nil {
+			color.Red("✗ Failed to reset stack: %v", err)
+			printDockerHint(err)
+			return err
+		}
+		if err := docker.ForgetPorts(cfg); err != nil {
+			color.Yellow("⚠ Could not clear the recorded ports: %v", err)
+		}
+
+		color.Green("✓ Stack stopped and %s removed", volumeCount(len(removed)))
+		for _, volume := range removed {
+			fmt.Printf("  - %s\n", volume)
+		}
+
+		if resetRestart {
+			fmt.Println()
+			return startCmd.RunE(startCmd, nil)
+		}
+		return nil
+	},
+}
+
+// volumeCount returns "no volumes", "1 volume", or "n volumes"
+func volumeCount(n int) string {
+	switch n {
+	case 0:
+		return "no volumes"
+	case 1:
+		return "1 volume"
+	}
+	return fmt.Sprintf("%d volumes", n)
+}
+
+func init() {
+	resetCmd.Flags().BoolVarP(&resetYes, "yes", "y", false, "Don't ask for confirmation")
+	resetCmd.Flags().BoolVar(&resetRestart, "restart", false, "Start the stack again afterwards")
+}
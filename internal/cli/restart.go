@@ -1,6 +1,8 @@
 package cli
 
 import (
+	"strings"
+
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
@@ -8,39 +10,71 @@ import (
 	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
 )
 
+var restartRecreate bool
+
 var restartCmd = &cobra.Command{
-	Use:   "restart [service]",
+	Use:   "restart [service...]",
 	Short: "Restart the emulator stack",
-	Long: `Restart all services or a specific service.
+	Long: `Restart all services or the services given, leaving the others
+running with their state.
 
-Without arguments, restarts entire stack.
-Specify a service name to restart only that service.
+A restart keeps the containers, so a changed image or setting isn't
+picked up; --recreate recreates the containers instead. Afterwards
+restart waits for the restarted services to pass their health checks,
+unless --no-wait is given.
 
 Services: iam, secret-manager, kms`,
-	ValidArgs: []string{"iam", "secret-manager", "kms"},
+	Example: `  gcp-emulator restart
+  gcp-emulator restart kms
+  gcp-emulator restart kms secret-manager --recreate`,
+	ValidArgsFunction: completeServices,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg, err := config.Load()
 		if err != nil {
 			return err
 		}
 
-		if len(args) == 0 {
-			color.Cyan("Restarting entire stack...")
-			if err := docker.Restart(cfg, nil); err != nil {
-				color.Red("✗ Failed to restart: %v", err)
+		services := make([]string, 0, len(args))
+		for _, arg := range args {
+			service, err := docker.LookupService(arg)
+			if err != nil {
 				return err
 			}
-			color.Green("✓ Stack restarted successfully")
+			services = append(services, service)
+		}
+
+		what := "entire stack"
+		if len(services) > 0 {
+			what = strings.Join(services, ", ")
+		}
+		if restartRecreate {
+			info("Recreating %s...", what)
 		} else {
-			service := args[0]
-			color.Cyan("Restarting %s...", service)
-			if err := docker.Restart(cfg, &service); err != nil {
-				color.Red("✗ Failed to restart %s: %v", service, err)
+			info("Restarting %s...", what)
+		}
+		if err := docker.Restart(cfg, services, restartRecreate); err != nil {
+			color.Red("✗ Failed to restart %s: %v", what, err)
+			printDockerHint(err)
+			return err
+		}
+
+		if wait, timeout := waitFlags(cmd); wait {
+			waitFor := services
+			if len(waitFor) == 0 {
+				waitFor = docker.Services
+			}
+			if err := waitHealthy(cfg, waitFor, timeout); err != nil {
+				color.Red("✗ Restarted, but not healthy: %v", err)
 				return err
 			}
-			color.Green("✓ %s restarted successfully", service)
 		}
 
+		color.Green("✓ Restarted %s", what)
 		return nil
 	},
 }
+
+func init() {
+	restartCmd.Flags().BoolVar(&restartRecreate, "recreate", false, "Recreate the containers, picking up changed images and settings")
+	addWaitFlags(restartCmd)
+}
@@ -5,9 +5,19 @@
 package cli
 
 import (
+	"fmt"
+	"log/slog"
+
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
 )
 
+// setFlags are the --set key=value overrides
+var setFlags []string
+
 var rootCmd = &cobra.Command{
 	Use:   "gcp-emulator",
 	Short: "Manage the GCP Emulator Control Plane",
@@ -16,22 +26,105 @@ var rootCmd = &cobra.Command{
 It orchestrates IAM, Secret Manager, and KMS emulators with centralized
 authorization policy.`,
 	SilenceUsage: true,
+	// Execute prints errors, as JSON with --output json
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// A broken config must not break the shell's completion, so the
+		// completion functions load what they need themselves
+		if isCompletion(cmd) {
+			return nil
+		}
+		// Runs after flags are parsed, so --config and --profile apply.
+		// Logging comes before the config, so loading it is logged, and
+		// after the output, so it logs what goes to stderr.
+		if err := setupOutput(cmd); err != nil {
+			return err
+		}
+		if err := setupLogging(); err != nil {
+			return err
+		}
+		if err := config.SetOverrides(setFlags); err != nil {
+			return err
+		}
+		if err := config.Init(); err != nil {
+			return fmt.Errorf("error initializing config: %w", err)
+		}
+		return nil
+	},
 }
 
-// Execute runs the root command
-func Execute(version string) error {
+// Execute runs the root command and returns the process exit code: 0 on
+// success, or one of the Exit* codes for the kind of failure
+func Execute(version string) int {
 	rootCmd.Version = version
-	return rootCmd.Execute()
+	err := rootCmd.Execute()
+	code := exitCode(err)
+	if err != nil {
+		slog.Error("command failed", "error", err, "exit", code)
+		printError(err, code)
+	}
+	return code
 }
 
 func init() {
 	// Add subcommands
+	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(startCmd)
 	rootCmd.AddCommand(stopCmd)
 	rootCmd.AddCommand(restartCmd)
+	rootCmd.AddCommand(resetCmd)
+	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(seedCmd)
+	rootCmd.AddCommand(pullCmd)
+	rootCmd.AddCommand(upgradeCmd)
 	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(metricsCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(stacksCmd)
+	rootCmd.AddCommand(topCmd)
 	rootCmd.AddCommand(logsCmd)
+	rootCmd.AddCommand(eventsCmd)
+	rootCmd.AddCommand(traceCmd)
+	rootCmd.AddCommand(explainCmd)
+	rootCmd.AddCommand(tokenCmd)
+	rootCmd.AddCommand(callCmd)
 	rootCmd.AddCommand(policyCmd)
 	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(envCmd)
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(completionCmd)
+
+	rootCmd.PersistentFlags().String("config", "", "Config file to use instead of searching for one (default $GCP_EMULATOR_CONFIG)")
+	_ = viper.BindPFlag("config", rootCmd.PersistentFlags().Lookup("config"))
+
+	rootCmd.PersistentFlags().String("host", "", "Host the stack runs on, for health checks and endpoints (default localhost)")
+	_ = config.BindFlag("host", rootCmd.PersistentFlags().Lookup("host"))
+
+	rootCmd.PersistentFlags().String("compose-file", "", "Compose file to run the stack with instead of the one generated from the config")
+	_ = config.BindFlag("compose-file", rootCmd.PersistentFlags().Lookup("compose-file"))
+
+	rootCmd.PersistentFlags().StringArray("compose-override", nil, "Compose file merged over the stack's compose file (repeatable; replaces the compose-overrides key)")
+	_ = config.BindFlag("compose-overrides", rootCmd.PersistentFlags().Lookup("compose-override"))
+
+	rootCmd.PersistentFlags().String("profile", "", "Configuration profile to use (default $GCP_EMULATOR_PROFILE, or the one set by config use)")
+	_ = viper.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile"))
+	_ = rootCmd.RegisterFlagCompletionFunc("profile", completeProfiles)
+
+	rootCmd.PersistentFlags().String("stack", "", "Named stack to act on, started next to the others on ports of its own (default $GCP_EMULATOR_STACK, or the default stack)")
+	_ = viper.BindPFlag("stack", rootCmd.PersistentFlags().Lookup("stack"))
+	_ = rootCmd.RegisterFlagCompletionFunc("stack", completeStacks)
+
+	rootCmd.PersistentFlags().StringArrayVar(&setFlags, "set", nil, "Override a config key for this command, as key=value (repeatable; wins over every other source)")
+
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", OutputText, "Output format (text|json|yaml); json and yaml print the result, or the error on stderr, for scripts")
+	_ = rootCmd.RegisterFlagCompletionFunc("output", completeOutputFormats)
+
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Print without colors (also when NO_COLOR is set or the output isn't a terminal)")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Print only errors, warnings, and results, not progress and summaries")
+
+	rootCmd.PersistentFlags().BoolVar(&logVerbose, "verbose", false, "Log what the CLI does to stderr: commands run, the environment given to compose, and each health check")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Format of the log on stderr (text|json); json also logs without --verbose, for CI")
+
+	rootCmd.PersistentFlags().StringVar(&policyKeyFile, "policy-key-file", "", "File holding the passphrase for encrypted (.enc) policy files (default $"+policy.PolicyKeyEnv+")")
 }
@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+)
+
+// runKillGrace is how long a command has to exit once run has passed on
+// a SIGINT or SIGTERM, before it is killed
+const runKillGrace = 10 * time.Second
+
+// ChildExitError is returned by run when its command fails. The CLI
+// exits with the same code.
+type ChildExitError struct {
+	Command string
+	Code    int
+}
+
+func (e *ChildExitError) Error() string {
+	return fmt.Sprintf("%s exited with code %d", e.Command, e.Code)
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run [flags] -- <command> [args...]",
+	Short: "Run a command against the stack, starting and stopping it around it",
+	Long: `Start the stack, wait for it to be healthy, run a command with the
+client library variables of 'gcp-emulator env' in its environment, then
+stop the stack. run exits with the command's exit code.
+
+The stack is started as by 'gcp-emulator start', including seeding. A
+stack that is already running is an error, so a job never tears down
+one it didn't start; with --reuse, run waits for it to be healthy and
+uses it instead.
+
+--teardown (or the run-teardown key, for jobs sharing a stack) says
+when the stack is stopped afterwards:
+
+  started     Stop it if run started it (default)
+  on-success  Like started, but keep it when the command fails, to debug
+  always      Stop it, even one reused
+  never       Leave it running, like --keep
+
+On SIGINT or SIGTERM the command is passed the signal, killed if it
+hasn't exited after 10s, and the stack torn down as --teardown says; an
+interrupted command doesn't count as failed for on-success.`,
+	Example: `  gcp-emulator run -- go test ./...
+  gcp-emulator run --reuse -- npm test
+  gcp-emulator run --keep -- ./integration.sh
+  gcp-emulator --set iam-mode=strict run --teardown on-success -- go test ./e2e/...`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reuse, _ := cmd.Flags().GetBool("reuse")
+		keep, _ := cmd.Flags().GetBool("keep")
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+		teardown := cfg.RunTeardown
+		if keep {
+			teardown = config.TeardownNever
+		}
+
+		running, err := docker.Running(cfg)
+		if err != nil {
+			color.Red("✗ Failed to check the stack: %v", err)
+			printDockerHint(err)
+			return err
+		}
+		started := !running
+		switch {
+		case running && !reuse:
+			return errors.New("the stack is already running; pass --reuse to run against it, or stop it first")
+		case running:
+			info("→ Reusing the running stack")
+			if err := waitHealthy(cfg, docker.EnabledServices(cfg), defaultWaitTimeout); err != nil {
+				color.Red("✗ Stack did not become healthy: %v", err)
+				return err
+			}
+		default:
+			if err := startCmd.RunE(startCmd, nil); err != nil {
+				// A stack that came up part way is torn down like any other
+				if runTearsDown(teardown, started, false) {
+					_ = stopCmd.RunE(stopCmd, nil)
+				}
+				return err
+			}
+		}
+
+		child := exec.Command(args[0], args[1:]...)
+		child.Stdin, child.Stdout, child.Stderr = os.Stdin, os.Stdout, os.Stderr
+		child.Env = os.Environ()
+		for _, v := range clientEnv(cfg, docker.ActivePorts(cfg), cfg.Project) {
+			child.Env = append(child.Env, v.name+"="+v.value)
+		}
+
+		info("\n→ Running %s", strings.Join(args, " "))
+		interrupted, childErr := runChild(child)
+		var exitErr *exec.ExitError
+		switch {
+		case childErr == nil:
+		case errors.As(childErr, &exitErr):
+			code := exitErr.ExitCode()
+			if code < 0 && interrupted != nil {
+				// Killed by the signal, as a shell reports it
+				if signum, ok := interrupted.(syscall.Signal); ok {
+					code = 128 + int(signum)
+				}
+			}
+			childErr = &ChildExitError{Command: args[0], Code: code}
+		default:
+			childErr = fmt.Errorf("failed to run %s: %w", args[0], childErr)
+		}
+
+		if runTearsDown(teardown, started, childErr == nil || interrupted != nil) {
+			fmt.Println()
+			if err := stopCmd.RunE(stopCmd, nil); err != nil && childErr == nil {
+				return err
+			}
+		} else if started || reuse {
+			info("\n→ Leaving the stack running (teardown %s); stop it with 'gcp-emulator stop'", teardown)
+		}
+		return childErr
+	},
+}
+
+// runTearsDown reports whether run stops the stack after its command,
+// as teardown says: started tells whether run started the stack, and
+// succeeded whether the command succeeded or was interrupted
+func runTearsDown(teardown string, started, succeeded bool) bool {
+	switch teardown {
+	case config.TeardownAlways:
+		return true
+	case config.TeardownNever:
+		return false
+	case config.TeardownOnSuccess:
+		return started && succeeded
+	}
+	return started
+}
+
+// runChild runs child until it exits, passing on SIGINT and SIGTERM and
+// killing it if it hasn't exited runKillGrace after one. It returns the
+// signal run received, if any, and the error of the child.
+func runChild(child *exec.Cmd) (os.Signal, error) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	if err := child.Start(); err != nil {
+		return nil, err
+	}
+	done := make(chan error, 1)
+	go func() { done <- child.Wait() }()
+
+	var received os.Signal
+	var kill <-chan time.Time
+	for {
+		select {
+		case err := <-done:
+			return received, err
+		case sig := <-signals:
+			if received != nil {
+				// A second signal doesn't wait for the grace period
+				_ = child.Process.Kill()
+				continue
+			}
+			received = sig
+			color.Yellow("\n⚠ Received %s; stopping %s", sig, child.Args[0])
+			if err := child.Process.Signal(sig); err != nil {
+				_ = child.Process.Kill()
+			}
+			kill = time.After(runKillGrace)
+		case <-kill:
+			color.Yellow("⚠ %s didn't exit after %s; killing it", child.Args[0], runKillGrace)
+			_ = child.Process.Kill()
+		}
+	}
+}
+
+func init() {
+	runCmd.Flags().SetInterspersed(false)
+	runCmd.Flags().Bool("reuse", false, "Use the stack if it is already running, instead of failing")
+	runCmd.Flags().Bool("keep", false, "Leave the stack running afterwards (same as --teardown never)")
+	runCmd.Flags().String("teardown", "", "When to stop the stack afterwards: started, on-success, always, or never (default started)")
+	_ = runCmd.RegisterFlagCompletionFunc("teardown", cobra.FixedCompletions([]string{"started", "on-success", "always", "never"}, cobra.ShellCompDirectiveNoFileComp))
+	_ = config.BindFlag("run-teardown", runCmd.Flags().Lookup("teardown"))
+}
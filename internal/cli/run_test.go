@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+func TestRunTearsDown(t *testing.T) {
+	tests := []struct {
+		teardown           string
+		started, succeeded bool
+		want               bool
+	}{
+		{"", true, false, true},
+		{config.TeardownStarted, true, true, true},
+		{config.TeardownStarted, false, true, false},
+		{config.TeardownOnSuccess, true, true, true},
+		{config.TeardownOnSuccess, true, false, false},
+		{config.TeardownOnSuccess, false, true, false},
+		{config.TeardownAlways, false, false, true},
+		{config.TeardownNever, true, true, false},
+	}
+	for _, tt := range tests {
+		if got := runTearsDown(tt.teardown, tt.started, tt.succeeded); got != tt.want {
+			t.Errorf("runTearsDown(%q, started %t, succeeded %t) = %t, want %t", tt.teardown, tt.started, tt.succeeded, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/snapshot"
+)
+
+var snapshotForce bool
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save and restore the state of the stack",
+	Long: `Save the state of the emulator stack and bring it back later, such as
+a demo environment set up once and restored before each demo.
+
+A snapshot holds the docker volumes of the stack's compose project and
+the policy file, archived in ~/.local/state/gcp-emulator/snapshots.`,
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Snapshot the stack's volumes and policy file",
+	Long: `Snapshot the volumes of the stack's compose project and the policy
+file as <name>.
+
+A running stack is stopped while its volumes are archived, so the
+snapshot is consistent, and started again afterwards.`,
+	Example: `  gcp-emulator snapshot create demo1`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if err := snapshot.ValidateName(name); err != nil {
+			return err
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		running, err := docker.Running(cfg)
+		if err != nil {
+			color.Red("✗ Failed to check the stack: %v", err)
+			printDockerHint(err)
+			return err
+		}
+		if running {
+			info("→ Stopping the stack while it is archived...")
+			if err := docker.Stop(cfg, nil); err != nil {
+				color.Red("✗ Failed to stop stack: %v", err)
+				return err
+			}
+			if err := docker.ForgetPorts(cfg); err != nil {
+				color.Yellow("⚠ Could not clear the recorded ports: %v", err)
+			}
+		}
+
+		info("Creating snapshot %s...", name)
+		m, err := createSnapshot(cfg, name)
+		if err != nil {
+			color.Red("✗ Failed to create snapshot: %v", err)
+		} else {
+			color.Green("✓ Created snapshot %s (%s, %s)", name, volumeCount(len(m.Volumes)), byteCount(m.Size))
+		}
+
+		// Start the stack again even if the snapshot failed
+		if running {
+			fmt.Println()
+			if startErr := startCmd.RunE(startCmd, nil); err == nil {
+				err = startErr
+			}
+		}
+		return err
+	},
+}
+
+// createSnapshot snapshots the volumes of cfg's stack as name
+func createSnapshot(cfg *config.Config, name string) (*snapshot.Manifest, error) {
+	volumes, err := docker.Volumes(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Create(cfg, name, volumes)
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore <name>",
+	Short: "Restore a snapshot and restart the stack",
+	Long: `Replace the stack's volumes and the policy file with those of
+snapshot <name>, then start the stack.
+
+The snapshot's checksum is verified first. A snapshot taken with images
+of a different major version than the ones now configured is refused,
+since those emulators may not read its state, and so is one whose policy
+file isn't the configured one; --force restores it anyway, writing the
+policy file back where it was taken from. A plaintext policy file is
+never restored over an encrypted one.`,
+	Example: `  gcp-emulator snapshot restore demo1
+  gcp-emulator snapshot restore demo1 --force`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		m, err := snapshot.Load(args[0])
+		if err != nil {
+			return err
+		}
+		if err := snapshot.CheckProject(cfg, m); err != nil {
+			return err
+		}
+		if err := snapshot.Verify(m); err != nil {
+			color.Red("✗ %v", err)
+			return err
+		}
+		if err := snapshot.CheckImages(m, docker.Images(cfg)); err != nil {
+			var mismatch *snapshot.ImageMismatchError
+			if !snapshotForce || !errors.As(err, &mismatch) {
+				color.Red("✗ %v", err)
+				return err
+			}
+			for _, image := range mismatch.Mismatches {
+				color.Yellow("⚠ %s was %s, now %s", image.Service, image.Snapshot, image.Current)
+			}
+		}
+		if err := snapshot.CheckPolicyFile(cfg, m); err != nil {
+			var mismatch *snapshot.PolicyFileMismatchError
+			if !snapshotForce || !errors.As(err, &mismatch) {
+				color.Red("✗ %v", err)
+				return err
+			}
+			color.Yellow("⚠ Restoring policy file %s, not the configured %s", mismatch.Snapshot, mismatch.Current)
+		}
+		if err := snapshot.CheckPolicyWrite(m); err != nil {
+			color.Red("✗ %v", err)
+			return err
+		}
+
+		info("Restoring snapshot %s...", m.Name)
+		if _, err := docker.Reset(cfg); err != nil {
+			color.Red("✗ Failed to remove the current volumes: %v", err)
+			printDockerHint(err)
+			return err
+		}
+		if err := docker.ForgetPorts(cfg); err != nil {
+			color.Yellow("⚠ Could not clear the recorded ports: %v", err)
+		}
+		if err := snapshot.Restore(cfg, m); err != nil {
+			color.Red("✗ Failed to restore snapshot: %v", err)
+			return err
+		}
+		color.Green("✓ Restored %s", volumeCount(len(m.Volumes)))
+		if m.PolicyFile != "" {
+			color.Green("✓ Restored policy file %s", m.PolicyFile)
+		}
+
+		fmt.Println()
+		return startCmd.RunE(startCmd, nil)
+	},
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List snapshots",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manifests, err := snapshot.List()
+		if err != nil {
+			return err
+		}
+		if len(manifests) == 0 {
+			fmt.Println("No snapshots. Create one with: gcp-emulator snapshot create <name>")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tCREATED\tPROJECT\tVOLUMES\tSIZE")
+		for _, m := range manifests {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", m.Name, m.Created.Local().Format("2006-01-02 15:04"), m.Project, len(m.Volumes), byteCount(m.Size))
+		}
+		return w.Flush()
+	},
+}
+
+var snapshotDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a snapshot",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := snapshot.Delete(args[0]); err != nil {
+			color.Red("✗ Failed to delete snapshot: %v", err)
+			return err
+		}
+		color.Green("✓ Deleted snapshot %s", args[0])
+		return nil
+	},
+}
+
+// byteCount returns n bytes in the largest unit that keeps it above one
+func byteCount(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func init() {
+	snapshotRestoreCmd.Flags().BoolVar(&snapshotForce, "force", false, "Restore even if the images changed major version or the policy file is another")
+
+	snapshotCmd.AddCommand(snapshotCreateCmd)
+	snapshotCmd.AddCommand(snapshotRestoreCmd)
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotDeleteCmd)
+}
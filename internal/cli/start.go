@@ -1,9 +1,15 @@
 package cli
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 
 	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
 	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
@@ -15,7 +21,43 @@ var startCmd = &cobra.Command{
 	Long: `Start the GCP emulator stack using docker-compose.
 
 This starts IAM, Secret Manager, and KMS emulators with the
-configured IAM mode and policy.`,
+configured IAM mode and policy.
+
+With --services, only the services listed are started. In permissive
+and strict mode the data planes check every request with the IAM
+emulator, so iam is added if it is left out.
+
+Images come from the image-iam, image-secret-manager, and image-kms
+config keys; --image overrides one for this run. Images not present
+locally are pulled first, as by 'gcp-emulator pull'; --pull-policy always
+pulls every image (like --pull), and never fails instead of pulling.
+
+By default start waits until every service passes its health check,
+polling with backoff for up to --timeout; a service that doesn't is
+reported with the end of its log, and start fails. --no-wait returns as
+soon as the containers are created.
+
+In permissive and strict mode IAM is started first, and the data planes
+only once it is healthy, so they never check requests against an IAM
+emulator that isn't up yet. --ordered=false (or ordered-start: false)
+starts every service at once, as off mode always does.
+
+If the seed key names a fixtures file, it is seeded once the stack is
+healthy, as by 'gcp-emulator seed'.
+
+The configured ports are checked before starting, and a port that is
+already taken is reported along with the process holding it, where that
+can be found out. With --auto-ports (or ports.auto: true), free ports
+are picked instead; 'status' and the summary below show the ports in use.`,
+	Example: `  gcp-emulator start
+  gcp-emulator start --mode strict
+  gcp-emulator start --auto-ports
+  gcp-emulator start --services iam,kms
+  gcp-emulator start --timeout 2m
+  gcp-emulator start --no-wait
+  gcp-emulator start --ordered=false
+  gcp-emulator start --pull-policy never
+  gcp-emulator start --image kms=ghcr.io/blackwell-systems/gcp-kms-emulator-dual:v0.4.0-rc1`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Load configuration (Viper resolves behind the scenes)
 		cfg, err := config.Load()
@@ -23,41 +65,290 @@ configured IAM mode and policy.`,
 			return err
 		}
 
-		color.Cyan("Starting GCP Emulator Control Plane...")
-		color.Cyan("IAM Mode: %s", cfg.IAMMode)
+		overrides, _ := cmd.Flags().GetStringArray("image")
+		for _, override := range overrides {
+			if err := cfg.Images.SetOverride(override); err != nil {
+				return err
+			}
+		}
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+		if err := requireDaemon(cfg); err != nil {
+			return err
+		}
 
-		// Pull images if requested
-		if cfg.PullOnStart {
-			color.Cyan("→ Pulling latest images...")
-			if err := docker.Pull(); err != nil {
-				color.Yellow("⚠ Failed to pull images: %v", err)
+		names, _ := cmd.Flags().GetStringSlice("services")
+		services, addedIAM, err := docker.SelectServices(names, cfg.IAMMode)
+		if err != nil {
+			return err
+		}
+		if addedIAM {
+			info("→ Also starting iam: the data planes depend on it in %s mode", cfg.IAMMode)
+		}
+
+		// The IAM emulator crash-loops without a loadable policy, so catch
+		// it before starting anything
+		if err := cfg.ValidatePolicyFile(policyLoadOptions()); err != nil {
+			if cfg.IAMMode != "off" {
+				color.Red("✗ %v", err)
+				if errors.Is(err, config.ErrNoPolicyFile) {
+					fmt.Println("\nCreate one with 'gcp-emulator policy init', or point policy-file at an existing policy:")
+					fmt.Println("  gcp-emulator config set policy-file path/to/policy.yaml")
+				} else {
+					fmt.Printf("\nCheck it with 'gcp-emulator policy validate %s'\n", cfg.PolicyFile)
+				}
+				return err
+			}
+			info("→ %v; ignored because IAM mode is off", err)
+		}
+
+		ports, err := startPorts(cfg)
+		if err != nil {
+			var inUse *docker.PortInUseError
+			if errors.As(err, &inUse) {
+				color.Red("✗ %v", err)
+				fmt.Println("\nFree the port, change it with 'gcp-emulator config set port-iam|port-secret-manager|port-kms <port>',")
+				fmt.Println("or let start pick free ports with --auto-ports")
 			}
+			return err
+		}
+
+		info("Starting GCP Emulator Control Plane...")
+		if cfg.Profile != "" {
+			info("Profile: %s", cfg.Profile)
+		}
+		info("IAM Mode: %s", cfg.IAMMode)
+		if cfg.Docker.Remote() {
+			info("Host: %s (docker: %s)", cfg.Docker.Host, docker.Target(cfg))
+		}
+
+		// Pull images as pull-policy says; compose would otherwise pull
+		// missing ones itself, without checking pinned digests
+		pullServices := services
+		if len(pullServices) == 0 {
+			pullServices = docker.Services
+		}
+		if err := pullImages(cfg, pullServices, cfg.StartPullPolicy()); err != nil {
+			return err
 		}
 
 		// Start the stack
-		if err := docker.Start(cfg); err != nil {
-			color.Red("✗ Failed to start stack: %v", err)
+		wait, timeout := waitFlags(cmd)
+		if err := startStack(cfg, ports, services, timeout); err != nil {
+			return err
+		}
+		if err := checkStarted(cfg); err != nil {
+			color.Red("✗ Stack did not start: %v", err)
 			return err
 		}
 
+		// Wait unless --no-wait, so scripts can use the stack right away
+		if len(services) == 0 {
+			services = docker.Services
+		}
+		if wait {
+			if err := waitHealthy(cfg, services, timeout); err != nil {
+				color.Red("✗ Stack did not become healthy: %v", err)
+				return err
+			}
+		}
+
 		color.Green("✓ Stack started successfully")
-		color.Cyan("\nServices:")
-		color.Cyan("  IAM:            http://localhost:%d", cfg.Ports.IAM)
-		color.Cyan("  Secret Manager: grpc://localhost:%d, http://localhost:%d", cfg.Ports.SecretManager, cfg.Ports.SecretManager+1)
-		color.Cyan("  KMS:            grpc://localhost:%d, http://localhost:%d", cfg.Ports.KMS, cfg.Ports.KMS+1)
-		color.Cyan("\nRun 'gcp-emulator status' to check health")
+
+		// Fixtures need the emulators up, which only a wait makes sure of
+		if cfg.SeedFile != "" {
+			if !wait {
+				color.Yellow("⚠ Not seeding %s with --no-wait; run 'gcp-emulator seed' once the stack is healthy", cfg.SeedFile)
+			} else if err := runSeed(cfg, ports, cfg.SeedFile, services, false); err != nil {
+				return err
+			}
+		}
+		info("\nServices:")
+		address := cfg.Docker.Address
+		for _, service := range []struct {
+			service, label string
+			grpc, http     int
+		}{
+			{"iam", "IAM:           ", ports.IAM, ports.IAMHTTP()},
+			{"secret-manager", "Secret Manager:", ports.SecretManager, ports.SecretManagerHTTP},
+			{"kms", "KMS:           ", ports.KMS, ports.KMSHTTP},
+		} {
+			if slices.Contains(services, service.service) {
+				info("  %s grpc://%s, http://%s", service.label, address(service.grpc), address(service.http))
+			}
+		}
+		info("\nRun 'gcp-emulator status' to check health")
 
 		return nil
 	},
 }
 
+// startStack starts services of cfg's stack, or all of them if none are
+// given, on ports. With ordered startup, IAM is started and waited for
+// first, for up to timeout, so the data planes never start without it.
+func startStack(cfg *config.Config, ports docker.Ports, services []string, timeout time.Duration) error {
+	all := services
+	if len(all) == 0 {
+		all = docker.Services
+	}
+	var rest []string
+	for _, service := range all {
+		if service != "iam" {
+			rest = append(rest, service)
+		}
+	}
+
+	first := services
+	ordered := cfg.StartOrdered() && slices.Contains(all, "iam") && len(rest) > 0
+	if ordered {
+		first = []string{"iam"}
+	}
+	if err := docker.Start(cfg, ports, first, os.Stdout); err != nil {
+		color.Red("✗ Failed to start stack: %v", err)
+		printDockerHint(err)
+		return err
+	}
+	if err := docker.RecordPorts(cfg, ports); err != nil {
+		color.Yellow("⚠ Could not record the ports in use: %v", err)
+	}
+	if !ordered {
+		return nil
+	}
+
+	// The data planes fail closed without IAM in strict mode, so they only
+	// start once it is healthy, even with --no-wait
+	if err := waitHealthy(cfg, first, timeout); err != nil {
+		color.Red("✗ IAM did not become healthy; not starting %s: %v", strings.Join(rest, ", "), err)
+		return err
+	}
+	if err := docker.Start(cfg, ports, rest, os.Stdout); err != nil {
+		color.Red("✗ Failed to start stack: %v", err)
+		printDockerHint(err)
+		return err
+	}
+	return nil
+}
+
+// requireDaemon checks that the docker daemon is running before a command
+// that needs it starts, so that one message says so, rather than the
+// errors of what would fail on it
+func requireDaemon(cfg *config.Config) error {
+	if err := docker.Ping(cfg); err != nil {
+		color.Red("✗ %v", err)
+		printDockerHint(err)
+		return err
+	}
+	return nil
+}
+
+// printDockerHint suggests a fix for the kinds of docker failure that
+// have a usual one
+func printDockerHint(err error) {
+	var daemonDown *docker.DaemonDownError
+	switch {
+	case errors.As(err, &daemonDown):
+		fmt.Println("\nIf it is running elsewhere, check the docker-context key, and DOCKER_HOST or CONTAINER_HOST")
+	case errors.Is(err, docker.ErrComposeNotInstalled):
+		fmt.Println("\nInstall Docker Desktop, or the compose plugin: https://docs.docker.com/compose/install/")
+		fmt.Println("With podman, install podman-compose (pip install podman-compose)")
+	case errors.Is(err, docker.ErrDaemonUnreachable):
+		fmt.Println("\nStart Docker Desktop or the docker service (sudo systemctl start docker),")
+		fmt.Println("or check the docker-context key and DOCKER_HOST")
+		fmt.Println("With podman, run 'podman machine start' or 'systemctl --user start podman.socket'")
+	case errors.Is(err, docker.ErrComposeFileInvalid):
+		fmt.Println("\nCheck the compose-file and compose-overrides settings, or print the file compose runs with")
+		fmt.Println("'gcp-emulator compose render --merged'")
+	}
+}
+
+// startLogLines is how much of the log of a service that exited is shown
+const startLogLines = 30
+
+// checkStarted reports the state of each service once compose is done, and
+// shows the end of the log of each one that exited right away. It returns
+// a *docker.ExitedError for each of those.
+func checkStarted(cfg *config.Config) error {
+	states, err := docker.States(cfg)
+	if err != nil {
+		color.Yellow("⚠ Could not check the services: %v", err)
+		return nil
+	}
+
+	color.Cyan("\nService states:")
+	var exited []docker.ServiceState
+	for _, state := range states {
+		switch {
+		case state.Exited():
+			color.Red("  ✗ %-14s %s", state.Service, state.Status)
+			exited = append(exited, state)
+		case state.State == "running":
+			color.Green("  ✓ %-14s %s", state.Service, state.Status)
+		default:
+			color.Yellow("  ⚠ %-14s %s", state.Service, state.Status)
+		}
+	}
+
+	var errs []error
+	for _, state := range exited {
+		printLogTail(cfg, state.Service)
+		errs = append(errs, &docker.ExitedError{Service: state.Service, ExitCode: state.ExitCode})
+	}
+	return errors.Join(errs...)
+}
+
+// startPorts returns the host ports to start the stack on. A stack that is
+// already running keeps its ports, since compose leaves it as it is. Otherwise
+// free ports are picked with automatic ports, or the configured ports are
+// checked for conflicts.
+func startPorts(cfg *config.Config) (docker.Ports, error) {
+	// If docker can't be asked, the stack is taken to be stopped; compose
+	// reports the underlying problem
+	if running, _ := docker.Running(cfg); running {
+		return docker.ActivePorts(cfg), nil
+	}
+
+	// Ports can only be probed on this machine
+	if cfg.Docker.Remote() {
+		if cfg.Ports.Auto {
+			err := fmt.Errorf("automatic ports need the stack on this machine, not %s", cfg.Docker.Host)
+			color.Red("✗ %v", err)
+			return docker.Ports{}, err
+		}
+		return docker.ConfiguredPorts(cfg), nil
+	}
+
+	if cfg.Ports.Auto {
+		ports, err := docker.FreePorts()
+		if err != nil {
+			color.Red("✗ %v", err)
+			return docker.Ports{}, err
+		}
+		info("→ Picked free ports")
+		return ports, nil
+	}
+
+	ports := docker.ConfiguredPorts(cfg)
+	return ports, docker.CheckPorts(ports)
+}
+
 func init() {
 	// Define flags
 	startCmd.Flags().String("mode", "", "IAM mode (off|permissive|strict)")
-	startCmd.Flags().Bool("pull", false, "Pull latest images before starting")
+	startCmd.Flags().Bool("pull", false, "Pull latest images before starting (same as --pull-policy always)")
+	startCmd.Flags().String("pull-policy", "", "When to pull images: always, missing (only images not present locally), or never")
 	startCmd.Flags().BoolP("detach", "d", true, "Run in background")
+	startCmd.Flags().Bool("auto-ports", false, "Pick free host ports instead of the configured ones")
+	startCmd.Flags().StringSlice("services", nil, "Start only these services (iam, secret-manager, kms); iam is added unless --mode off")
+	_ = startCmd.RegisterFlagCompletionFunc("services", completeServiceList)
+	startCmd.Flags().Bool("ordered", true, "Start IAM and wait for it to be healthy before the data planes (ignored with --mode off)")
+	addWaitFlags(startCmd)
+	startCmd.Flags().StringArray("image", nil, "Override a service image for this run, as service=image (repeatable; services: iam, secret-manager, kms)")
 
-	// Bind flags to viper (errors only happen if flag doesn't exist, which can't happen here)
-	_ = viper.BindPFlag("iam-mode", startCmd.Flags().Lookup("mode"))
-	_ = viper.BindPFlag("pull-on-start", startCmd.Flags().Lookup("pull"))
+	// Bind flags to config keys (errors only happen if flag doesn't exist, which can't happen here)
+	_ = config.BindFlag("iam-mode", startCmd.Flags().Lookup("mode"))
+	_ = config.BindFlag("pull-on-start", startCmd.Flags().Lookup("pull"))
+	_ = config.BindFlag("pull-policy", startCmd.Flags().Lookup("pull-policy"))
+	_ = config.BindFlag("ports.auto", startCmd.Flags().Lookup("auto-ports"))
+	_ = config.BindFlag("ordered-start", startCmd.Flags().Lookup("ordered"))
 }
@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+)
+
+var (
+	statsWatch    bool
+	statsInterval time.Duration
+)
+
+var statsCmd = &cobra.Command{
+	Use:         "stats",
+	Short:       "Show CPU, memory, and network usage of the stack's containers",
+	Annotations: renders,
+	Long: `Show the CPU, memory, network IO, and process count of each running
+container of the stack, by service name, from one sample of docker stats,
+next to the limits and restart policy the resources.* keys give it.
+Memory is shown against the container's limit, or the host's memory when
+it has none, so what the stack costs next to everything else on the
+machine can be seen, and the limits tuned from what the emulators really
+use.
+
+--watch refreshes the table in place every --interval until interrupted,
+as docker stats does; without a terminal, each sample is printed after
+the last. --output json prints the usage and limits of each container
+for scripts.`,
+	Example: `  gcp-emulator stats
+  gcp-emulator stats --watch --interval 5s
+  gcp-emulator stats --output json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch {
+		case statsWatch && structuredOutput():
+			return fmt.Errorf("--watch only shows the table; use --output %s without it", outputFormat)
+		case statsInterval <= 0:
+			return fmt.Errorf("invalid interval: %s (must be positive)", statsInterval)
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		if statsWatch {
+			return watchStats(cfg, statsInterval)
+		}
+
+		stats, err := docker.Stats(cfg)
+		if err != nil {
+			color.Red("✗ Failed to get container stats: %v", err)
+			printDockerHint(err)
+			return err
+		}
+		return render(newStatsReport(cfg, stats), func() {
+			printStats(cfg, stats)
+		})
+	},
+}
+
+// watchStats prints the stats every interval until interrupted, in place
+// on a terminal. A sample that fails is reported and the next one tried.
+func watchStats(cfg *config.Config, interval time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	terminal := isTerminal(os.Stdout)
+	first := true
+	docker.Poll(ctx, interval, interval, func() bool {
+		stats, err := docker.Stats(cfg)
+		switch {
+		case terminal:
+			fmt.Print("\033[H\033[2J")
+			fmt.Printf("Every %s, until Ctrl-C\n\n", interval)
+		case !first:
+			fmt.Println()
+		}
+		first = false
+		if err != nil {
+			color.Red("✗ Failed to get container stats: %v", err)
+			return false
+		}
+		printStats(cfg, stats)
+		return false
+	})
+	return nil
+}
+
+// printStats prints the usage and limits of each container as a table
+func printStats(cfg *config.Config, stats []docker.ContainerStats) {
+	if len(stats) == 0 {
+		color.Yellow("⚠ No containers of project %s are running", docker.ProjectName(cfg))
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tCPU\tMEMORY\tMEM %\tNET I/O\tPIDS\tLIMITS")
+	for _, s := range stats {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.Service, s.CPU, s.Memory, s.MemoryPercent, s.NetIO, s.PIDs, cfg.Resources.For(s.Service))
+	}
+	_ = w.Flush()
+}
+
+// statsReport is what stats --output json and yaml print. Scripts parse
+// it, so fields are only ever added.
+type statsReport struct {
+	// Containers are the running containers, in the order of the services
+	Containers []containerStatsReport `json:"containers"`
+}
+
+// containerStatsReport is the usage of one container in a statsReport,
+// as docker stats formats it, and its configured limits
+type containerStatsReport struct {
+	// Name is the service as compose and --services name it
+	Name string `json:"name"`
+
+	CPU           string `json:"cpu"`
+	Memory        string `json:"memory"`
+	MemoryPercent string `json:"memoryPercent"`
+	NetIO         string `json:"netIO"`
+	PIDs          string `json:"pids"`
+
+	// MemoryLimit, CPULimit, and Restart are those of the resources.*
+	// keys, empty when unlimited
+	MemoryLimit string `json:"memoryLimit,omitempty"`
+	CPULimit    string `json:"cpuLimit,omitempty"`
+	Restart     string `json:"restart,omitempty"`
+}
+
+// newStatsReport returns the report of stats, with the limits each
+// container is configured with
+func newStatsReport(cfg *config.Config, stats []docker.ContainerStats) statsReport {
+	report := statsReport{Containers: []containerStatsReport{}}
+	for _, s := range stats {
+		limits := cfg.Resources.For(s.Service)
+		report.Containers = append(report.Containers, containerStatsReport{
+			Name:          s.Service,
+			CPU:           s.CPU,
+			Memory:        s.Memory,
+			MemoryPercent: s.MemoryPercent,
+			NetIO:         s.NetIO,
+			PIDs:          s.PIDs,
+			MemoryLimit:   limits.Memory,
+			CPULimit:      limits.CPUs,
+			Restart:       limits.Restart,
+		})
+	}
+	return report
+}
+
+func init() {
+	statsCmd.Flags().BoolVarP(&statsWatch, "watch", "w", false, "Refresh the table until interrupted")
+	statsCmd.Flags().DurationVar(&statsInterval, "interval", defaultWatchInterval, "How often --watch refreshes")
+}
@@ -1,53 +1,567 @@
 package cli
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
 	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
 	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator"
+)
+
+var (
+	statusExitCode bool
+	statusWatch    bool
+	statusInterval time.Duration
+	statusWait     bool
+	statusTimeout  time.Duration
+	statusDeep     bool
 )
 
+// defaultWatchInterval is how often status --watch refreshes
+const defaultWatchInterval = 2 * time.Second
+
+// watchTransitions is how many state transitions status --watch keeps
+// under the table
+const watchTransitions = 10
+
 var statusCmd = &cobra.Command{
-	Use:   "status",
-	Short: "Show status of all services",
-	Long:  `Display health status of IAM, Secret Manager, and KMS emulators.`,
+	Use:         "status",
+	Short:       "Show status of all services",
+	Annotations: renders,
+	Long: `Display health status of IAM, Secret Manager, and KMS emulators, with
+the state of each one's container: running, restarting, exited with its
+exit code, or not created. For a service that isn't up, the code its
+container last exited with and when it last started are shown, so a
+crash loop can be told from a service that was never started.
+
+Services left out with start --services, or stopped with stop --services,
+are shown as not enabled. The health checks run at once, each timing out
+after --health-timeout (or health-timeout), and the round trip of each
+one that passes is shown next to UP.
+
+--output json prints the status for scripts: each service's state,
+health, ports, and URL, and the overall state, healthy, degraded, or
+down. With --exit-code, status exits 0 only when every enabled service
+is up, 1 when any is down, and 5 when the docker daemon can't be
+reached, so CI can wait for the stack before running tests. If the
+daemon isn't running, the services aren't checked, and are shown as
+docker unavailable rather than down.
+
+--watch refreshes the table every --interval until interrupted, listing
+each change of a service's state or health under it. --wait blocks until
+every enabled service is healthy, polling with the same backoff as
+start, and exits 0 once they are, or 1 if they aren't after --timeout;
+it is a readiness gate for a stack started elsewhere.
+
+--deep also calls each enabled service over gRPC: the gRPC health
+service, then a call of its API that changes nothing, TestIamPermissions
+for IAM, ListSecrets for Secret Manager, and ListKeyRings for KMS, on a
+project that doesn't exist. Each layer, container, http, grpc, and api,
+is reported on its own, so a failure shows where the service breaks; a
+denial or a missing resource passes, since the API answered. With
+--exit-code, a failed layer exits 1.`,
+	Example: `  gcp-emulator status
+  gcp-emulator status --output json --exit-code
+  gcp-emulator status --watch --interval 5s
+  gcp-emulator status --wait --timeout 90s
+  gcp-emulator status --deep`,
+	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		switch {
+		case statusWatch && statusWait:
+			return errors.New("--watch and --wait can't be combined")
+		case statusWatch && structuredOutput():
+			return fmt.Errorf("--watch only shows the table; use --output %s without it", outputFormat)
+		case statusWatch && statusDeep:
+			return errors.New("--watch and --deep can't be combined")
+		case statusInterval <= 0:
+			return fmt.Errorf("invalid interval: %s (must be positive)", statusInterval)
+		}
 		cfg, err := config.Load()
 		if err != nil {
 			return err
 		}
 
+		if statusWatch {
+			return watchStatus(cfg, statusInterval)
+		}
+		if statusWait {
+			// Services that don't come up are shown as down below, which
+			// exits 1
+			services := docker.EnabledServices(cfg)
+			if structuredOutput() {
+				docker.WaitHealthy(cfg, services, statusTimeout, nil)
+			} else {
+				_ = waitHealthy(cfg, services, statusTimeout)
+				fmt.Println()
+			}
+		}
+
 		status, err := docker.Status(cfg)
 		if err != nil {
 			color.Red("✗ Failed to get status: %v", err)
 			return err
 		}
 
-		// Print status
-		color.Cyan("Service          Status    Ports")
-		color.Cyan("────────────────────────────────────────")
+		var layers map[string][]emulator.LayerResult
+		var daemonDown *docker.DaemonDownError
+		if statusDeep && !errors.As(status.RuntimeErr, &daemonDown) {
+			layers = deepCheck(cfg, status)
+		}
 
-		printServiceStatus("IAM Emulator", status.IAM, cfg.Ports.IAM)
-		printServiceStatus("Secret Manager", status.SecretManager, cfg.Ports.SecretManager)
-		printServiceStatus("KMS", status.KMS, cfg.Ports.KMS)
+		err = render(newStatusReport(cfg, status, layers), func() {
+			printStatus(cfg, status)
+			printLayers(status, layers)
+		})
+		if err != nil {
+			return err
+		}
 
-		return nil
+		if !statusExitCode && !statusWait {
+			return nil
+		}
+		if err := statusError(status); err != nil {
+			return err
+		}
+		return layersError(layers)
 	},
 }
 
-func printServiceStatus(name string, status docker.ServiceStatus, port int) {
+// statusReport is what status --output json and yaml print. Scripts parse it, so
+// fields are only ever added.
+type statusReport struct {
+	Services []serviceReport `json:"services"`
+
+	// Overall is healthy, degraded, or down
+	Overall string `json:"overall"`
+}
+
+// serviceReport is the status of one service in a statusReport
+type serviceReport struct {
+	// Name is the service as compose and --services name it
+	Name string `json:"name"`
+
+	// State is the container's state, "not created", or "unknown"
+	State string `json:"state"`
+
+	// Health is up, down, starting, not-enabled, or docker-unavailable
+	Health string `json:"health"`
+
+	Ports servicePorts `json:"ports"`
+
+	// URL is the service's HTTP endpoint
+	URL string `json:"url"`
+
+	// Layers are the results of --deep, in order: container, http, grpc,
+	// and api
+	Layers []layerReport `json:"layers,omitempty"`
+}
+
+// layerReport is the result of one layer of a deep check
+type layerReport struct {
+	Layer string `json:"layer"`
+
+	// Status is pass, fail, or skipped
+	Status string `json:"status"`
+
+	Detail    string `json:"detail,omitempty"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latencyMs,omitempty"`
+}
+
+// servicePorts are the host ports a service is published on
+type servicePorts struct {
+	GRPC int `json:"grpc"`
+	HTTP int `json:"http"`
+}
+
+// newStatusReport returns the report of status, with the ports the stack
+// is running on and the layers of a deep check, if one ran
+func newStatusReport(cfg *config.Config, status *docker.StackStatus, layers map[string][]emulator.LayerResult) statusReport {
+	report := statusReport{Overall: status.Overall()}
+	for _, info := range status.Services() {
+		ports := servicePortsOf(status.Ports, info.Service)
+		state := info.State
+		if state == "" {
+			state = "unknown"
+		}
+		report.Services = append(report.Services, serviceReport{
+			Name:   info.Service,
+			State:  state,
+			Health: info.Health.String(),
+			Ports:  ports,
+			URL:    "http://" + cfg.Docker.Address(ports.HTTP),
+			Layers: layerReports(layers[info.Service]),
+		})
+	}
+	return report
+}
+
+// servicePortsOf returns the ports of service in ports
+func servicePortsOf(ports docker.Ports, service string) servicePorts {
+	switch service {
+	case "iam":
+		return servicePorts{GRPC: ports.IAM, HTTP: ports.IAMHTTP()}
+	case "secret-manager":
+		return servicePorts{GRPC: ports.SecretManager, HTTP: ports.SecretManagerHTTP}
+	default:
+		return servicePorts{GRPC: ports.KMS, HTTP: ports.KMSHTTP}
+	}
+}
+
+// layerReports returns the reports of a service's layers
+func layerReports(layers []emulator.LayerResult) []layerReport {
+	var reports []layerReport
+	for _, layer := range layers {
+		report := layerReport{
+			Layer:     layer.Layer,
+			Status:    layer.Status,
+			Detail:    layer.Detail,
+			LatencyMs: layer.Latency.Milliseconds(),
+		}
+		if layer.Err != nil {
+			report.Error = layer.Err.Error()
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// statusError returns the error status --exit-code exits with: the
+// runtime's if the daemon can't be reached, one naming the services that
+// are down, or nil if every enabled service is up
+func statusError(status *docker.StackStatus) error {
+	if errors.Is(status.RuntimeErr, docker.ErrDaemonUnreachable) {
+		return status.RuntimeErr
+	}
+	if down := status.Down(); len(down) > 0 {
+		return fmt.Errorf("%s down", strings.Join(down, ", "))
+	}
+	return nil
+}
+
+// layersError returns an error naming the services with a failed layer,
+// or nil if every layer passed or was skipped
+func layersError(layers map[string][]emulator.LayerResult) error {
+	var failed []string
+	for _, service := range docker.Services {
+		for _, layer := range layers[service] {
+			if layer.Status == emulator.LayerFail {
+				failed = append(failed, service+" "+layer.Layer)
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("deep check failed: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// deepCheck checks the layers of every enabled service: the container and
+// HTTP health status found, then the gRPC health service and API. The
+// gRPC layers are skipped when the container is known not to run.
+func deepCheck(cfg *config.Config, status *docker.StackStatus) map[string][]emulator.LayerResult {
+	addresses := map[string]string{}
+	for _, info := range status.Services() {
+		if info.Health != docker.ServiceNotEnabled && (info.State == "" || info.State == "running") {
+			addresses[info.Service] = cfg.Docker.Address(servicePortsOf(status.Ports, info.Service).GRPC)
+		}
+	}
+	deep := emulator.DeepCheckAll(context.Background(), addresses, cfg.Health.Timeout)
+
+	layers := map[string][]emulator.LayerResult{}
+	for _, info := range status.Services() {
+		if info.Health == docker.ServiceNotEnabled {
+			continue
+		}
+		layers[info.Service] = append(serviceLayers(info), deep[info.Service]...)
+		if _, ok := addresses[info.Service]; !ok {
+			for _, layer := range []string{emulator.LayerGRPC, emulator.LayerAPI} {
+				layers[info.Service] = append(layers[info.Service], emulator.LayerResult{
+					Layer: layer, Status: emulator.LayerSkipped, Detail: "container not running",
+				})
+			}
+		}
+	}
+	return layers
+}
+
+// serviceLayers returns the container and http layers of a service, from
+// what status found
+func serviceLayers(info docker.ServiceInfo) []emulator.LayerResult {
+	container := emulator.LayerResult{Layer: emulator.LayerContainer, Status: emulator.LayerPass, Detail: containerState(info)}
+	switch info.State {
+	case "":
+		container.Status = emulator.LayerSkipped
+	case "running":
+	default:
+		container = emulator.LayerResult{Layer: emulator.LayerContainer, Status: emulator.LayerFail, Err: errors.New(containerState(info))}
+	}
+
+	health := emulator.LayerResult{Layer: emulator.LayerHTTP, Status: emulator.LayerPass, Detail: "health endpoint up", Latency: info.Latency}
+	if info.Health != docker.ServiceUp {
+		health = emulator.LayerResult{Layer: emulator.LayerHTTP, Status: emulator.LayerFail, Err: errors.New("health endpoint " + info.Health.String())}
+	}
+	return []emulator.LayerResult{container, health}
+}
+
+// printLayers shows the layers of a deep check under the table, a line
+// per layer
+func printLayers(status *docker.StackStatus, layers map[string][]emulator.LayerResult) {
+	if len(layers) == 0 {
+		return
+	}
+	color.Cyan("\nDeep checks:")
+	for _, info := range status.Services() {
+		if len(layers[info.Service]) == 0 {
+			continue
+		}
+		fmt.Printf("  %s\n", info.Service)
+		for _, layer := range layers[info.Service] {
+			switch layer.Status {
+			case emulator.LayerPass:
+				detail := layer.Detail
+				if layer.Latency > 0 {
+					detail += " in " + latency(layer.Latency)
+				}
+				color.Green("    ✓ %-10s %s", layer.Layer, detail)
+			case emulator.LayerSkipped:
+				fmt.Printf("    - %-10s skipped (%s)\n", layer.Layer, layer.Detail)
+			default:
+				color.Red("    ✗ %-10s %v", layer.Layer, layer.Err)
+			}
+		}
+	}
+}
+
+// printStatus shows status as a table, with what is known of the services
+// that aren't up
+func printStatus(cfg *config.Config, status *docker.StackStatus) {
+	fmt.Printf("Project: %s\n", docker.ProjectName(cfg))
+	fmt.Printf("Host: %s (docker: %s)\n\n", cfg.Docker.HostName(), docker.Target(cfg))
+	color.Cyan("Service          Status        Container      Restarts  Uptime    Ports         Image")
+	color.Cyan("──────────────────────────────────────────────────────────────────────────────────────")
+
+	names := map[string]string{"iam": "IAM Emulator", "secret-manager": "Secret Manager", "kms": "KMS"}
+	for _, info := range status.Services() {
+		ports := servicePortsOf(status.Ports, info.Service)
+		printServiceStatus(names[info.Service], info, ports.GRPC, ports.HTTP)
+	}
+
+	// Containers that ran and went down have logs that tell why
+	first := true
+	var logs []string
+	for _, info := range status.Services() {
+		if !info.Unhealthy() || info.State == "" {
+			continue
+		}
+		if first {
+			fmt.Println()
+			first = false
+		}
+		printUnhealthy(names[info.Service], info)
+		if info.State != docker.ContainerNotCreated {
+			logs = append(logs, info.Service)
+		}
+	}
+	if len(logs) > 0 {
+		color.Cyan("\nSee why with 'gcp-emulator logs %s'", strings.Join(logs, " "))
+	}
+	var daemonDown *docker.DaemonDownError
+	switch {
+	case errors.As(status.RuntimeErr, &daemonDown):
+		color.Red("\n✗ %v", status.RuntimeErr)
+		printDockerHint(status.RuntimeErr)
+	case status.RuntimeErr != nil:
+		color.Yellow("\n⚠ Could not inspect the containers: %v", status.RuntimeErr)
+		printDockerHint(status.RuntimeErr)
+	}
+}
+
+func printServiceStatus(name string, info docker.ServiceInfo, port, httpPort int) {
 	var statusText string
-	switch status {
+	switch info.Health {
 	case docker.ServiceUp:
-		statusText = color.GreenString("✓ UP")
+		statusText = color.GreenString("%-12s", "✓ UP "+latency(info.Latency))
 	case docker.ServiceDown:
-		statusText = color.RedString("✗ DOWN")
+		statusText = color.RedString("%-12s", "✗ DOWN")
 	case docker.ServiceStarting:
-		statusText = color.YellowString("⚠ STARTING")
+		statusText = color.YellowString("%-12s", "⚠ STARTING")
+	case docker.ServiceNotEnabled:
+		color.New().Printf("%-16s - not enabled\n", name)
+		return
+	case docker.ServiceDockerUnavailable:
+		color.New().Printf("%-16s %s\n", name, color.RedString("✗ docker unavailable"))
+		return
 	default:
-		statusText = color.RedString("✗ UNKNOWN")
+		statusText = color.RedString("%-12s", "✗ UNKNOWN")
+	}
+
+	restarts, uptime := "-", "-"
+	if info.State != "" && info.State != docker.ContainerNotCreated {
+		restarts = fmt.Sprint(info.RestartCount)
+	}
+	if info.Uptime > 0 {
+		uptime = info.Uptime.String()
+	}
+	image := info.Image
+	if image == "" {
+		image = "-"
+	}
+	color.New().Printf("%-16s %s  %-14s %-9s %-9s %-13s %s\n", name, statusText, containerState(info), restarts, uptime, fmt.Sprintf("%d, %d", port, httpPort), image)
+}
+
+// latency rounds the round trip of a health check for the table: to the
+// microsecond below a millisecond, and to the millisecond above
+func latency(d time.Duration) string {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond).String()
 	}
+	return d.Round(time.Millisecond).String()
+}
+
+// containerState describes the state of a service's container, with the
+// code it exited with
+func containerState(info docker.ServiceInfo) string {
+	switch info.State {
+	case "":
+		return "unknown"
+	case "exited", "dead":
+		return fmt.Sprintf("%s (%d)", info.State, info.ExitCode)
+	}
+	return info.State
+}
+
+// printUnhealthy shows why a service that isn't up may be down: the code
+// its container last exited with, and when it last started
+func printUnhealthy(name string, info docker.ServiceInfo) {
+	if info.State == docker.ContainerNotCreated {
+		color.Red("✗ %s has no container; start it with 'gcp-emulator start'", name)
+		return
+	}
+
+	details := fmt.Sprintf("✗ %s is %s: last exit code %d", name, info.State, info.ExitCode)
+	if info.State == "running" {
+		details = fmt.Sprintf("✗ %s is running, but its health check fails", name)
+	}
+	if !info.StartedAt.IsZero() {
+		details += ", last started " + info.StartedAt.Local().Format("2006-01-02 15:04:05")
+	}
+	if info.RestartCount > 0 {
+		details += fmt.Sprintf(", restarted %d times", info.RestartCount)
+	}
+	color.Red("%s", details)
+}
+
+// newIAMClient returns a client for the IAM emulator of the running stack,
+// on the ports start recorded, which differ from the config with
+// automatic ports
+func newIAMClient(cfg *config.Config) *emulator.IAMClient {
+	client := emulator.NewIAMClient(cfg)
+	client.BaseURL = "http://" + cfg.Docker.Address(docker.ActivePorts(cfg).IAMHTTP())
+	return client
+}
+
+// watchStatus shows the status every interval until interrupted. On a
+// terminal the table is redrawn, with the latest transitions under it;
+// otherwise it is shown once, followed by a line per transition.
+func watchStatus(cfg *config.Config, interval time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	terminal := isTerminal(os.Stdout)
+	var previous *docker.StackStatus
+	var transitions []string
+	docker.Poll(ctx, interval, interval, func() bool {
+		status, err := docker.Status(cfg)
+		if err != nil {
+			color.Red("✗ Failed to get status: %v", err)
+			return false
+		}
+		changes := statusTransitions(previous, status)
+		if terminal {
+			transitions = append(transitions, changes...)
+			transitions = transitions[max(0, len(transitions)-watchTransitions):]
+			fmt.Print("\033[H\033[2J")
+			fmt.Printf("Every %s, until Ctrl-C\n\n", interval)
+			printStatus(cfg, status)
+			if len(transitions) > 0 {
+				color.Cyan("\nTransitions:")
+				for _, line := range transitions {
+					fmt.Println(line)
+				}
+			}
+		} else if previous == nil {
+			printStatus(cfg, status)
+			fmt.Println()
+		} else {
+			for _, line := range changes {
+				fmt.Println(line)
+			}
+		}
+		previous = status
+		return false
+	})
+	return nil
+}
+
+// statusTransitions returns a line for each service whose container state
+// or health changed from previous to current, colored by what it became
+func statusTransitions(previous, current *docker.StackStatus) []string {
+	if previous == nil {
+		return nil
+	}
+	now := time.Now().Format("15:04:05")
+	before := previous.Services()
+	var lines []string
+	for i, info := range current.Services() {
+		was := before[i]
+		if was.State == info.State && was.Health == info.Health {
+			continue
+		}
+		line := fmt.Sprintf("  %s %-14s %s → %s", now, info.Service, describeService(was), describeService(info))
+		switch {
+		case info.Health == docker.ServiceUp:
+			line = color.GreenString("%s", line)
+		case info.Unhealthy():
+			line = color.RedString("%s", line)
+		default:
+			line = color.YellowString("%s", line)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// describeService names the container state and health of a service
+func describeService(info docker.ServiceInfo) string {
+	switch info.Health {
+	case docker.ServiceNotEnabled:
+		return "not enabled"
+	case docker.ServiceDockerUnavailable:
+		return "docker unavailable"
+	}
+	return containerState(info) + ", " + info.Health.String()
+}
+
+func init() {
+	statusCmd.Flags().BoolVar(&statusExitCode, "exit-code", false, "Exit 1 if any enabled service is down, 5 if docker can't be reached")
+	statusCmd.Flags().BoolVarP(&statusWatch, "watch", "w", false, "Refresh the status until interrupted")
+	statusCmd.Flags().DurationVar(&statusInterval, "interval", defaultWatchInterval, "How often --watch refreshes")
+	statusCmd.Flags().BoolVar(&statusWait, "wait", false, "Wait for every enabled service to become healthy; exit 1 if they don't in time")
+	statusCmd.Flags().DurationVar(&statusTimeout, "timeout", defaultWaitTimeout, "How long --wait waits")
+	statusCmd.Flags().BoolVar(&statusDeep, "deep", false, "Also check each service's gRPC health and API")
+	statusCmd.Flags().Duration("health-timeout", config.DefaultHealthTimeout, "Timeout of each health check")
 
-	color.New().Printf("%-16s %s       %d\n", name, statusText, port)
+	_ = config.BindFlag("health-timeout", statusCmd.Flags().Lookup("health-timeout"))
 }
@@ -1,25 +1,71 @@
 package cli
 
 import (
+	"strings"
+
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
 	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
 )
 
+var stopServices []string
+
 var stopCmd = &cobra.Command{
 	Use:   "stop",
 	Short: "Stop the emulator stack",
-	Long:  `Stop all running emulator services.`,
+	Long: `Stop all running emulator services.
+
+With --services, only the services listed are stopped and the rest keep
+running; status then shows them as not enabled.`,
+	Example: `  gcp-emulator stop
+  gcp-emulator stop --services secret-manager`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		color.Cyan("Stopping GCP Emulator Control Plane...")
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		if err := requireDaemon(cfg); err != nil {
+			return err
+		}
 
-		if err := docker.Stop(); err != nil {
+		var services []string
+		for _, name := range stopServices {
+			service, err := docker.LookupService(name)
+			if err != nil {
+				return err
+			}
+			services = append(services, service)
+		}
+
+		if len(services) > 0 {
+			info("Stopping %s...", strings.Join(services, ", "))
+			if err := docker.Stop(cfg, services); err != nil {
+				color.Red("✗ Failed to stop %s: %v", strings.Join(services, ", "), err)
+				return err
+			}
+			color.Green("✓ Stopped %s", strings.Join(services, ", "))
+			return nil
+		}
+
+		info("Stopping GCP Emulator Control Plane...")
+
+		if err := docker.Stop(cfg, nil); err != nil {
 			color.Red("✗ Failed to stop stack: %v", err)
+			printDockerHint(err)
 			return err
 		}
+		if err := docker.ForgetPorts(cfg); err != nil {
+			color.Yellow("⚠ Could not clear the recorded ports: %v", err)
+		}
 
 		color.Green("✓ Stack stopped successfully")
 		return nil
 	},
 }
+
+func init() {
+	stopCmd.Flags().StringSliceVar(&stopServices, "services", nil, "Stop only these services (iam, secret-manager, kms)")
+	_ = stopCmd.RegisterFlagCompletionFunc("services", completeServiceList)
+}
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/fatih/color"
+)
+
+var (
+	noColor bool
+	quiet   bool
+)
+
+// colorTerminal reports whether f, where the colored output goes, is a
+// terminal. Tests replace it, to check colors are on for one.
+var colorTerminal = isTerminal
+
+// setupColor turns colors off with --no-color, when NO_COLOR is set or
+// TERM is dumb, and when the colored output isn't a terminal, so output
+// piped to tee or a file is plain text
+func setupColor() {
+	out := os.Stdout
+	if structuredOutput() {
+		out = os.Stderr
+	}
+	color.NoColor = noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" || !colorTerminal(out)
+}
+
+// info prints informational chatter in cyan: what a command is about to
+// do, and summaries such as the endpoints of a started stack. --quiet
+// drops it, keeping errors, warnings, and the results asked for.
+func info(format string, a ...any) {
+	if quiet {
+		return
+	}
+	color.Cyan(format, a...)
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/spf13/viper"
+)
+
+// runCLI runs the CLI with args, returning what it printed on stdout,
+// colored output included, and on stderr
+func runCLI(t *testing.T, args ...string) (string, string) {
+	t.Helper()
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderrR, stderrW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	savedStdout, savedStderr, savedOutput, savedNoColor := os.Stdout, os.Stderr, color.Output, color.NoColor
+	os.Stdout, os.Stderr, color.Output = stdoutW, stderrW, stdoutW
+	defer func() {
+		os.Stdout, os.Stderr, color.Output, color.NoColor = savedStdout, savedStderr, savedOutput, savedNoColor
+		noColor, quiet, outputFormat, setFlags = false, false, OutputText, nil
+	}()
+
+	var stdout, stderr bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(&stdout, stdoutR)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(&stderr, stderrR)
+		done <- struct{}{}
+	}()
+
+	viper.Reset()
+	rootCmd.SetArgs(args)
+	if err := rootCmd.Execute(); err != nil {
+		printError(err, exitCode(err))
+	}
+	stdoutW.Close()
+	stderrW.Close()
+	<-done
+	<-done
+	return stdout.String(), stderr.String()
+}
+
+// writePolicies writes a valid and an invalid policy file to a new
+// directory, made the home and working directory
+func writePolicies(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, ".config"))
+	t.Chdir(dir)
+
+	valid := filepath.Join(dir, "policy.yaml")
+	if err := os.WriteFile(valid, []byte("projects:\n  test-project: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	invalid := filepath.Join(dir, "invalid.yaml")
+	content := "projects:\n  test-project:\n    bindings:\n      - role: roles/custom.ghost\n        members: [user:alice@example.com]\n"
+	if err := os.WriteFile(invalid, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return valid, invalid
+}
+
+func TestNoColor(t *testing.T) {
+	valid, _ := writePolicies(t)
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm-256color")
+
+	colorTerminal = func(*os.File) bool { return true }
+	defer func() { colorTerminal = isTerminal }()
+
+	if stdout, _ := runCLI(t, "policy", "validate", valid); !strings.Contains(stdout, "\x1b[") {
+		t.Errorf("Expected colors on a terminal, got %q", stdout)
+	}
+
+	stdout, _ := runCLI(t, "policy", "validate", "--no-color", valid)
+	if strings.Contains(stdout, "\x1b[") {
+		t.Errorf("Expected no colors with --no-color, got %q", stdout)
+	}
+	if !strings.Contains(stdout, "✓ Policy is valid") {
+		t.Errorf("Expected the result with --no-color, got %q", stdout)
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if stdout, _ := runCLI(t, "policy", "validate", valid); strings.Contains(stdout, "\x1b[") {
+		t.Errorf("Expected no colors with NO_COLOR, got %q", stdout)
+	}
+	t.Setenv("NO_COLOR", "")
+
+	// The pipe the output goes to isn't a terminal
+	colorTerminal = isTerminal
+	if stdout, _ := runCLI(t, "policy", "validate", valid); strings.Contains(stdout, "\x1b[") {
+		t.Errorf("Expected no colors when piped, got %q", stdout)
+	}
+}
+
+func TestQuiet(t *testing.T) {
+	valid, invalid := writePolicies(t)
+
+	stdout, _ := runCLI(t, "policy", "validate", valid)
+	if !strings.Contains(stdout, "Validating") {
+		t.Errorf("Expected progress without --quiet, got %q", stdout)
+	}
+
+	stdout, stderr := runCLI(t, "policy", "validate", "--quiet", valid)
+	if strings.Contains(stdout, "Validating") {
+		t.Errorf("Expected no progress with --quiet, got %q", stdout)
+	}
+	if !strings.Contains(stdout, "✓ Policy is valid") || !strings.Contains(stdout, "1 projects configured") {
+		t.Errorf("Expected the result with --quiet, got %q", stdout)
+	}
+	if stderr != "" {
+		t.Errorf("Expected nothing on stderr, got %q", stderr)
+	}
+
+	// Errors are kept
+	stdout, stderr = runCLI(t, "policy", "validate", "-q", invalid)
+	if strings.Contains(stdout, "Validating") {
+		t.Errorf("Expected no progress with -q, got %q", stdout)
+	}
+	if !strings.Contains(stdout, "✗ Validation failed") || !strings.Contains(stdout, "roles/custom.ghost") {
+		t.Errorf("Expected the findings with -q, got %q", stdout)
+	}
+	if !strings.Contains(stderr, "Error:") {
+		t.Errorf("Expected the error on stderr with -q, got %q", stderr)
+	}
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/token"
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Mint a bearer token for a principal, for calling the emulators",
+	Long: `Mint a bearer token naming a principal, to call the emulators as it
+from curl or a script, and print it on stdout.
+
+The token is an unsigned JWT (alg none): its subject is the principal
+and its email claim the principal's email, valid for --ttl. The
+emulators take no real credentials, so anyone can mint one.
+
+The principal is checked like a policy binding member, and must be a
+user or service account. A principal the configured policy doesn't
+mention is warned about on stderr, since outside off mode its requests
+are denied.`,
+	Example: `  gcp-emulator token --principal user:alice@example.com
+  gcp-emulator token --principal serviceAccount:ci@test-project.iam.gserviceaccount.com --ttl 10m
+  curl -H "$(gcp-emulator token --principal user:alice@example.com --header)" \
+    http://localhost:8081/v1/projects/test-project/secrets`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		principal, _ := cmd.Flags().GetString("principal")
+		audience, _ := cmd.Flags().GetString("audience")
+		ttl, _ := cmd.Flags().GetDuration("ttl")
+		header, _ := cmd.Flags().GetBool("header")
+
+		if err := checkTokenPrincipal(principal); err != nil {
+			return err
+		}
+
+		tok, err := token.Mint(principal, audience, ttl, time.Now())
+		if err != nil {
+			return err
+		}
+		if header {
+			fmt.Println("Authorization: Bearer " + tok)
+		} else {
+			fmt.Println(tok)
+		}
+		return nil
+	},
+}
+
+// checkTokenPrincipal checks principal like a policy binding member, and
+// that it's a user or service account. Stdout holds only the result of
+// the command, so a principal the configured policy doesn't mention is
+// warned about on stderr.
+func checkTokenPrincipal(principal string) error {
+	pol, policyFile, loadErr := loadConfiguredPolicy()
+	if loadErr != nil {
+		fmt.Fprintln(os.Stderr, color.YellowString("⚠ Not checking %s against the policy: %v", principal, loadErr))
+		pol = &policy.Policy{}
+	}
+	if err := policy.ValidatePrincipal(principal, pol); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(principal, "user:") && !strings.HasPrefix(principal, "serviceAccount:") {
+		return fmt.Errorf("tokens are for a user: or serviceAccount: principal, not %s", principal)
+	}
+	if loadErr == nil && !policy.MentionsPrincipal(pol, principal) {
+		fmt.Fprintln(os.Stderr, color.YellowString("⚠ %s doesn't appear in %s; outside off mode its requests are denied", principal, policyFile))
+	}
+	return nil
+}
+
+func init() {
+	tokenCmd.Flags().String("principal", "", "Principal the token is for (user:<email> or serviceAccount:<email>)")
+	tokenCmd.Flags().String("audience", "", "Audience (aud claim) of the token, if the caller checks one")
+	tokenCmd.Flags().Duration("ttl", time.Hour, "How long the token is valid")
+	tokenCmd.Flags().Bool("header", false, "Print a full Authorization header instead of the token alone")
+	tokenCmd.MarkFlagRequired("principal")
+}
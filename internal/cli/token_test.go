@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/token"
+)
+
+func TestToken(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, ".config"))
+	t.Chdir(dir)
+	content := "groups:\n  developers:\n    members: [user:alice@example.com]\nprojects:\n  test-project: {}\n"
+	if err := os.WriteFile("policy.yaml", []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr := runCLI(t, "token", "--principal", "user:alice@example.com", "--audience", "kms")
+	if stderr != "" {
+		t.Errorf("Expected no warning for a principal in the policy, got %q", stderr)
+	}
+	claims, err := token.Parse(strings.TrimSuffix(stdout, "\n"))
+	if err != nil {
+		t.Fatalf("Expected a token on stdout, got %q: %v", stdout, err)
+	}
+	if claims.Subject != "user:alice@example.com" || claims.Audience != "kms" {
+		t.Errorf("Expected a token for alice with audience kms, got %+v", claims)
+	}
+
+	stdout, stderr = runCLI(t, "token", "--principal", "user:carol@example.com", "--header", "--audience", "")
+	if !strings.HasPrefix(stdout, "Authorization: Bearer ey") {
+		t.Errorf("Expected an Authorization header, got %q", stdout)
+	}
+	if !strings.Contains(stderr, "⚠ user:carol@example.com doesn't appear in") {
+		t.Errorf("Expected a warning for a principal the policy doesn't mention, got %q", stderr)
+	}
+
+	for principal, want := range map[string]string{
+		"alice@example.com": "did you mean user:alice@example.com?",
+		"user:alice":        "invalid user: alice (expected email format)",
+		"group:developers":  "tokens are for a user: or serviceAccount: principal, not group:developers",
+	} {
+		stdout, stderr := runCLI(t, "token", "--principal", principal, "--header=false")
+		if stdout != "" || !strings.Contains(stderr, want) {
+			t.Errorf("%s: expected the error %q and no token, got %q, %q", principal, want, stdout, stderr)
+		}
+	}
+}
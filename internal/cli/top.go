@@ -0,0 +1,404 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator"
+)
+
+// topLogLines is how many of the last log lines of each service a
+// refresh of top reads, and topDecisions how many recent decisions it
+// keeps
+const (
+	topLogLines  = 100
+	topDecisions = 8
+)
+
+var topCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Show a live dashboard of the stack",
+	Long: `Show a live dashboard of the stack in the terminal, refreshed every
+--refresh: the health of each service, the key config values, the
+recent IAM decisions when trace mode is on, and a merged tail of the
+services' logs.
+
+Keys:
+  ↑/↓, k/j   Select a service
+  r          Restart the selected service
+  f          Pause or resume following the logs
+  q          Quit
+
+When the stack isn't running, the dashboard says so and picks it up once
+it starts.`,
+	Example: `  gcp-emulator top
+  gcp-emulator top --refresh 5s`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		refresh, _ := cmd.Flags().GetDuration("refresh")
+		if refresh <= 0 {
+			return fmt.Errorf("%w: --refresh must be positive, got %s", config.ErrInvalidValue, refresh)
+		}
+		if !isTerminal(os.Stdout) {
+			return errors.New("top needs a terminal; use 'gcp-emulator status --watch' to follow the stack from a script")
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		_, err = tea.NewProgram(newTopModel(cfg, refresh), tea.WithAltScreen()).Run()
+		return err
+	},
+}
+
+// topSnapshot is what a refresh of top found
+type topSnapshot struct {
+	at time.Time
+
+	// err is why the runtime couldn't be asked whether the stack runs
+	err     error
+	running bool
+	status  *docker.StackStatus
+
+	// logs is the tail of the merged logs, nil unless they're followed
+	logs []string
+
+	// decisions are the most recent decisions in trace mode, or why they
+	// couldn't be read
+	decisions    []emulator.Decision
+	decisionsErr error
+}
+
+// Messages of the top model
+type (
+	topTickMsg      struct{}
+	topRefreshMsg   topSnapshot
+	topRestartedMsg struct {
+		service string
+		err     error
+	}
+)
+
+// topModel is the bubbletea model of top. Its fetch and restart talk to
+// the stack; tests replace them.
+type topModel struct {
+	cfg     *config.Config
+	refresh time.Duration
+	fetch   func(follow bool) topSnapshot
+	restart func(service string) error
+
+	snapshot   topSnapshot
+	fetched    bool
+	fetching   bool
+	logs       []string
+	follow     bool
+	selected   int
+	restarting string
+	message    string
+
+	width, height int
+}
+
+func newTopModel(cfg *config.Config, refresh time.Duration) *topModel {
+	return &topModel{
+		cfg:     cfg,
+		refresh: refresh,
+		fetch:   func(follow bool) topSnapshot { return fetchTop(cfg, follow) },
+		restart: func(service string) error { return docker.Restart(cfg, []string{service}, false) },
+		follow:  true,
+		width:   80,
+		height:  24,
+	}
+}
+
+// fetchTop reads the stack's status, and, while it runs, its logs when
+// follow is set and its decisions in trace mode
+func fetchTop(cfg *config.Config, follow bool) topSnapshot {
+	snapshot := topSnapshot{at: time.Now()}
+	snapshot.running, snapshot.err = docker.Running(cfg)
+	if snapshot.err != nil || !snapshot.running {
+		return snapshot
+	}
+	snapshot.status, snapshot.err = docker.Status(cfg)
+
+	if follow {
+		var buf bytes.Buffer
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := docker.StreamLogs(ctx, cfg, nil, docker.LogOptions{Tail: topLogLines}, &buf)
+		cancel()
+		snapshot.logs = strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+		if err != nil {
+			snapshot.logs = append(snapshot.logs, color.RedString("✗ %v", err))
+		}
+	}
+
+	if cfg.Trace {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		snapshot.decisionsErr = newIAMClient(cfg).Decisions(ctx, false, func(d emulator.Decision) error {
+			snapshot.decisions = append(snapshot.decisions, d)
+			if len(snapshot.decisions) > topDecisions {
+				snapshot.decisions = snapshot.decisions[1:]
+			}
+			return nil
+		})
+		cancel()
+	}
+	return snapshot
+}
+
+func (m *topModel) Init() tea.Cmd {
+	return m.fetchCmd()
+}
+
+// fetchCmd refreshes the snapshot in the background
+func (m *topModel) fetchCmd() tea.Cmd {
+	m.fetching = true
+	follow, fetch := m.follow, m.fetch
+	return func() tea.Msg { return topRefreshMsg(fetch(follow)) }
+}
+
+func (m *topModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case topTickMsg:
+		if !m.fetching {
+			return m, m.fetchCmd()
+		}
+	case topRefreshMsg:
+		m.snapshot, m.fetched, m.fetching = topSnapshot(msg), true, false
+		if m.follow && msg.logs != nil {
+			m.logs = msg.logs
+		}
+		return m, tea.Tick(m.refresh, func(time.Time) tea.Msg { return topTickMsg{} })
+	case topRestartedMsg:
+		m.restarting = ""
+		if msg.err != nil {
+			m.message = color.RedString("✗ Restart of %s failed: %v", msg.service, msg.err)
+		} else {
+			m.message = color.GreenString("✓ Restarted %s", msg.service)
+		}
+		if !m.fetching {
+			return m, m.fetchCmd()
+		}
+	case tea.KeyMsg:
+		return m, m.key(msg.String())
+	}
+	return m, nil
+}
+
+// key handles a key press
+func (m *topModel) key(key string) tea.Cmd {
+	switch key {
+	case "q", "esc", "ctrl+c":
+		return tea.Quit
+	case "up", "k":
+		m.selected = (m.selected + len(docker.Services) - 1) % len(docker.Services)
+	case "down", "j":
+		m.selected = (m.selected + 1) % len(docker.Services)
+	case "f":
+		m.follow = !m.follow
+		if m.follow && !m.fetching {
+			return m.fetchCmd()
+		}
+	case "r":
+		service := docker.Services[m.selected]
+		switch {
+		case m.restarting != "":
+			m.message = color.YellowString("⚠ Still restarting %s", m.restarting)
+		case !m.snapshot.running:
+			m.message = color.YellowString("⚠ The stack isn't running")
+		case m.snapshot.status != nil && m.snapshot.status.Services()[m.selected].Health == docker.ServiceNotEnabled:
+			m.message = color.YellowString("⚠ %s isn't enabled in this stack", service)
+		default:
+			m.restarting = service
+			m.message = color.CyanString("Restarting %s...", service)
+			restart := m.restart
+			return func() tea.Msg { return topRestartedMsg{service, restart(service)} }
+		}
+	}
+	return nil
+}
+
+func (m *topModel) View() string {
+	var lines []string
+	add := func(format string, a ...any) { lines = append(lines, fmt.Sprintf(format, a...)) }
+
+	header := color.CyanString("gcp-emulator top") + fmt.Sprintf("  %s on %s", docker.ProjectName(m.cfg), m.cfg.Docker.HostName())
+	if m.fetched {
+		header += "  refreshed " + m.snapshot.at.Local().Format("15:04:05")
+	}
+	add("%s", header)
+	add("")
+
+	services := m.servicesPane()
+	configPane := m.configPane()
+	if m.width >= topServicesWidth+2+40 {
+		for i := 0; i < max(len(services), len(configPane)); i++ {
+			left, right := "", ""
+			if i < len(services) {
+				left = services[i]
+			}
+			if i < len(configPane) {
+				right = configPane[i]
+			}
+			add("%s  %s", left+strings.Repeat(" ", max(0, topServicesWidth-ansi.StringWidth(left))), right)
+		}
+	} else {
+		lines = append(lines, services...)
+		add("")
+		lines = append(lines, configPane...)
+	}
+
+	if m.snapshot.running {
+		add("")
+		lines = append(lines, m.decisionsPane()...)
+
+		add("")
+		if m.follow {
+			add("%s", color.CyanString("Logs (following)"))
+		} else {
+			add("%s", color.CyanString("Logs (paused; f to follow)"))
+		}
+		// The logs take the rest of the screen, above the footer
+		room := m.height - len(lines) - 2
+		logs := m.logs
+		if room < len(logs) {
+			logs = logs[len(logs)-max(room, 0):]
+		}
+		lines = append(lines, logs...)
+		for room > len(logs) {
+			add("")
+			room--
+		}
+	}
+
+	add("")
+	footer := "↑/↓ select  r restart  f follow logs  q quit"
+	if m.message != "" {
+		footer += "   " + m.message
+	}
+	add("%s", footer)
+
+	for i, line := range lines {
+		lines[i] = ansi.Truncate(line, m.width, "…")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// topServicesWidth is the width of the services pane, which the config
+// pane is beside on a wide enough terminal
+const topServicesWidth = 68
+
+// servicesPane shows the health of each service, the selected one marked
+func (m *topModel) servicesPane() []string {
+	lines := []string{color.CyanString("Services")}
+	switch {
+	case !m.fetched:
+		return append(lines, "  Checking the stack...")
+	case m.snapshot.err != nil && !m.snapshot.running:
+		return append(lines, color.RedString("  ✗ Could not ask %s about the stack: %v", docker.RuntimeName(m.cfg), m.snapshot.err))
+	case !m.snapshot.running:
+		return append(lines,
+			color.YellowString("  ⚠ The stack isn't running"),
+			"  Start it with 'gcp-emulator start'; this picks it up once it does.")
+	}
+
+	for i, service := range docker.Services {
+		cursor := "  "
+		if i == m.selected {
+			cursor = color.CyanString("> ")
+		}
+		if m.snapshot.status == nil {
+			lines = append(lines, fmt.Sprintf("%s%-15s unknown", cursor, service))
+			continue
+		}
+		info := m.snapshot.status.Services()[i]
+		var health string
+		switch info.Health {
+		case docker.ServiceUp:
+			health = color.GreenString("%-12s", "✓ UP "+latency(info.Latency))
+		case docker.ServiceDown:
+			health = color.RedString("%-12s", "✗ DOWN")
+		case docker.ServiceStarting:
+			health = color.YellowString("%-12s", "⚠ STARTING")
+		case docker.ServiceNotEnabled:
+			lines = append(lines, fmt.Sprintf("%s%-15s - not enabled", cursor, service))
+			continue
+		case docker.ServiceDockerUnavailable:
+			lines = append(lines, fmt.Sprintf("%s%-15s %s", cursor, service, color.RedString("✗ docker unavailable")))
+			continue
+		default:
+			health = color.RedString("%-12s", "✗ UNKNOWN")
+		}
+		uptime := "-"
+		if info.Uptime > 0 {
+			uptime = info.Uptime.Round(time.Second).String()
+		}
+		lines = append(lines, fmt.Sprintf("%s%-15s %s %-12s restarts %-3d %-8s", cursor, service, health, containerState(info), info.RestartCount, uptime))
+	}
+	return lines
+}
+
+// configPane shows the key config values
+func (m *topModel) configPane() []string {
+	cfg := m.cfg
+	ports := docker.ActivePorts(cfg)
+	if m.snapshot.status != nil {
+		ports = m.snapshot.status.Ports
+	}
+	profile, trace := cfg.Profile, "off"
+	if profile == "" {
+		profile = "(none)"
+	}
+	if cfg.Trace {
+		trace = "on"
+	}
+	return []string{
+		color.CyanString("Config"),
+		fmt.Sprintf("  iam-mode     %s", cfg.IAMMode),
+		fmt.Sprintf("  trace        %s", trace),
+		fmt.Sprintf("  profile      %s", profile),
+		fmt.Sprintf("  policy-file  %s", cfg.PolicyFile),
+		fmt.Sprintf("  ports        %d, %d, %d", ports.IAM, ports.SecretManager, ports.KMS),
+	}
+}
+
+// decisionsPane shows the recent decisions in trace mode
+func (m *topModel) decisionsPane() []string {
+	lines := []string{color.CyanString("Recent IAM decisions")}
+	switch {
+	case !m.cfg.Trace:
+		return append(lines, "  Trace mode is off; turn it on with 'gcp-emulator config set trace true' and restart the stack")
+	case m.snapshot.decisionsErr != nil:
+		return append(lines, color.YellowString("  ⚠ %v", m.snapshot.decisionsErr))
+	case len(m.snapshot.decisions) == 0:
+		return append(lines, "  No decisions yet")
+	}
+	for _, d := range m.snapshot.decisions {
+		result := color.GreenString("ALLOW")
+		if d.Result != "allow" {
+			result = color.RedString("DENY ")
+		}
+		lines = append(lines, fmt.Sprintf("  %s %s %s %s %s", d.Time.Local().Format("15:04:05"), result, d.Principal, d.Permission, d.Resource))
+	}
+	return lines
+}
+
+func init() {
+	topCmd.Flags().Duration("refresh", 2*time.Second, "How often to refresh the dashboard")
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator"
+)
+
+// runTeaCmd runs cmd and its batch, as bubbletea would, returning the
+// messages that aren't ticks
+func runTeaCmd(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	msg := cmd()
+	if batch, ok := msg.(tea.BatchMsg); ok {
+		var msgs []tea.Msg
+		for _, c := range batch {
+			msgs = append(msgs, runTeaCmd(c)...)
+		}
+		return msgs
+	}
+	return []tea.Msg{msg}
+}
+
+func TestTopNotRunning(t *testing.T) {
+	m := newTopModel(&config.Config{IAMMode: "permissive"}, time.Second)
+	m.fetch = func(bool) topSnapshot { return topSnapshot{at: time.Now()} }
+	m.restart = func(string) error {
+		t.Fatal("Expected no restart of a stack that isn't running")
+		return nil
+	}
+
+	if !strings.Contains(m.View(), "Checking the stack...") {
+		t.Errorf("Expected a placeholder before the first refresh, got\n%s", m.View())
+	}
+	for _, msg := range runTeaCmd(m.Init()) {
+		m.Update(msg)
+	}
+	view := m.View()
+	if !strings.Contains(view, "⚠ The stack isn't running") || strings.Contains(view, "Logs") {
+		t.Errorf("Expected a message instead of the panes, got\n%s", view)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	if !strings.Contains(m.message, "isn't running") {
+		t.Errorf("Expected restart to be refused, got %q", m.message)
+	}
+
+	m.fetch = func(bool) topSnapshot { return topSnapshot{err: errors.New("Cannot connect to the Docker daemon")} }
+	for _, msg := range runTeaCmd(m.fetchCmd()) {
+		m.Update(msg)
+	}
+	if !strings.Contains(m.View(), "✗ Could not ask docker about the stack: Cannot connect") {
+		t.Errorf("Expected the runtime error, got\n%s", m.View())
+	}
+}
+
+func TestTop(t *testing.T) {
+	up := docker.ServiceInfo{Health: docker.ServiceUp, State: "running", Latency: 2 * time.Millisecond}
+	status := &docker.StackStatus{IAM: up, SecretManager: up, KMS: docker.ServiceInfo{Health: docker.ServiceDown, State: "restarting", RestartCount: 7}}
+	status.IAM.Service, status.SecretManager.Service, status.KMS.Service = "iam", "secret-manager", "kms"
+
+	fetches := 0
+	m := newTopModel(&config.Config{IAMMode: "strict", Trace: true}, time.Second)
+	m.fetch = func(follow bool) topSnapshot {
+		fetches++
+		snapshot := topSnapshot{running: true, status: status, decisions: []emulator.Decision{
+			{Principal: "user:bob@example.com", Permission: "secretmanager.versions.access", Resource: "projects/p/secrets/s", Result: "deny"},
+		}}
+		if follow {
+			snapshot.logs = []string{"kms | starting", "kms | panic: boom"}
+		}
+		return snapshot
+	}
+	var restarted string
+	m.restart = func(service string) error {
+		restarted = service
+		return nil
+	}
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 30})
+	for _, msg := range runTeaCmd(m.Init()) {
+		m.Update(msg)
+	}
+
+	view := m.View()
+	for _, want := range []string{"> iam", "✗ DOWN", "restarts 7", "iam-mode     strict", "DENY  user:bob@example.com", "Logs (following)", "kms | panic: boom"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected the dashboard to show %q, got\n%s", want, view)
+		}
+	}
+	if lines := strings.Count(view, "\n") + 1; lines != 30 {
+		t.Errorf("Expected the dashboard to fill the 30 lines, got %d", lines)
+	}
+
+	// Paused logs keep the lines they had
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	m.fetch = func(bool) topSnapshot { return topSnapshot{running: true, status: status} }
+	for _, msg := range runTeaCmd(m.fetchCmd()) {
+		m.Update(msg)
+	}
+	if view := m.View(); !strings.Contains(view, "Logs (paused; f to follow)") || !strings.Contains(view, "kms | panic: boom") {
+		t.Errorf("Expected paused logs, got\n%s", view)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyUp})
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	for _, msg := range runTeaCmd(cmd) {
+		m.Update(msg)
+	}
+	if restarted != "kms" || !strings.Contains(m.message, "✓ Restarted kms") {
+		t.Errorf("Expected kms to be restarted, got %q, %q", restarted, m.message)
+	}
+
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")}); cmd == nil {
+		t.Error("Expected q to quit")
+	} else if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Error("Expected q to quit")
+	}
+}
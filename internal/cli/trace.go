@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator"
+)
+
+// traceFilterKeys are the decision fields accepted by --filter
+var traceFilterKeys = []string{"principal", "permission", "resource", "decision"}
+
+var traceCmd = &cobra.Command{
+	Use:         "trace",
+	Short:       "Show authorization decisions from the IAM emulator",
+	Annotations: renders,
+	Long: `Show authorization decisions recorded by the IAM emulator: the
+principal, permission, resource, decision, the binding that granted
+access, and the result of its condition.
+
+Without --follow, prints the recent decision log and exits. With
+--follow, streams decisions as they happen until interrupted.
+
+Filters match decision fields exactly and can be repeated; a decision
+is shown only if it matches every filter. Filter keys: principal,
+permission, resource, decision (allow|deny).
+
+Requires trace mode: run gcp-emulator config set trace true, then stop
+and start the stack.`,
+	Example: `  gcp-emulator trace --follow --filter decision=deny
+  gcp-emulator trace --filter principal=user:alice@example.com --output json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		follow, _ := cmd.Flags().GetBool("follow")
+
+		rawFilters, _ := cmd.Flags().GetStringArray("filter")
+		filters, err := parseTraceFilters(rawFilters)
+		if err != nil {
+			return err
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		if follow && !structuredOutput() {
+			info("Following IAM decisions (Ctrl+C to stop)...")
+		}
+
+		err = newIAMClient(cfg).Decisions(ctx, follow, func(d emulator.Decision) error {
+			if !matchesTraceFilters(d, filters) {
+				return nil
+			}
+			if structuredOutput() {
+				return renderStream(os.Stdout, d)
+			}
+			printDecision(d)
+			return nil
+		})
+		if err != nil {
+			printTraceError(err, cfg)
+			return err
+		}
+
+		return nil
+	},
+}
+
+// parseTraceFilters parses key=value filters
+func parseTraceFilters(raw []string) (map[string]string, error) {
+	filters := make(map[string]string)
+	for _, f := range raw {
+		key, value, ok := strings.Cut(f, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid filter %q (expected key=value)", f)
+		}
+		valid := false
+		for _, k := range traceFilterKeys {
+			if key == k {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid filter key %q (must be one of %s)", key, strings.Join(traceFilterKeys, ", "))
+		}
+		if key == "decision" && value != "allow" && value != "deny" {
+			return nil, fmt.Errorf("invalid decision filter %q (must be allow or deny)", value)
+		}
+		filters[key] = value
+	}
+	return filters, nil
+}
+
+// matchesTraceFilters reports whether d matches every filter
+func matchesTraceFilters(d emulator.Decision, filters map[string]string) bool {
+	fields := map[string]string{
+		"principal":  d.Principal,
+		"permission": d.Permission,
+		"resource":   d.Resource,
+		"decision":   d.Result,
+	}
+	for key, value := range filters {
+		if fields[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// printDecision prints one decision as a log line
+func printDecision(d emulator.Decision) {
+	result := color.GreenString("ALLOW")
+	if d.Result != "allow" {
+		result = color.RedString("DENY ")
+	}
+
+	fmt.Printf("%s %s %s %s %s\n", d.Time.Local().Format("15:04:05"), result, d.Principal, d.Permission, d.Resource)
+
+	if d.Binding != nil {
+		fmt.Printf("         binding: %s %s #%d\n", d.Binding.Project, d.Binding.Role, d.Binding.Index)
+	}
+	if d.Condition != nil {
+		switch {
+		case d.Condition.Error != "":
+			fmt.Printf("         condition: %s (error: %s)\n", d.Condition.Expression, d.Condition.Error)
+		default:
+			fmt.Printf("         condition: %s (%t)\n", d.Condition.Expression, d.Condition.Result)
+		}
+	}
+}
+
+// printTraceError explains a decision log failure
+func printTraceError(err error, cfg *config.Config) {
+	if !errors.Is(err, emulator.ErrTraceUnavailable) {
+		printEmulatorError(err)
+		return
+	}
+
+	color.Red("✗ %v", err)
+	if !cfg.Trace {
+		fmt.Println("\nTrace mode is off. Enable it and restart the stack:")
+		fmt.Println("  gcp-emulator config set trace true")
+		fmt.Println("  gcp-emulator stop && gcp-emulator start")
+		return
+	}
+	fmt.Println("\nThe IAM emulator image may predate the decision log; pull a newer one with:")
+	fmt.Println("  gcp-emulator start --pull")
+}
+
+func init() {
+	traceCmd.Flags().BoolP("follow", "f", false, "Stream decisions as they happen")
+	traceCmd.Flags().StringArray("filter", nil, "Only show decisions matching key=value (repeatable)")
+}
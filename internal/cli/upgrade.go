@@ -0,0 +1,262 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/upgrade"
+)
+
+var (
+	upgradeCheck      bool
+	upgradeYes        bool
+	upgradeIncludeCLI bool
+)
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade the emulator images to their newest versions",
+	Long: `Check the registries for newer versions of the configured images,
+show the plan, and on confirmation pull them and recreate the services
+whose image changed. Volumes are kept, and services that didn't change
+are left running.
+
+An image with a version tag, such as v0.3.1, moves to the newest
+version tag its constraint allows: upgrade.iam, upgrade.secret-manager,
+or upgrade.kms, such as ~0.3 for 0.3.x. The new tag is saved to the
+image's config key. An image with a tag that moves, such as latest, is
+upgraded when the registry has a different build than the one pulled.
+Images pinned to a digest are left alone.
+
+upgrade asks for confirmation first, unless --yes is given; without a
+terminal to ask on, --yes is required. --check only reports, exiting 0
+when everything is up to date and 10 when there are updates, so CI can
+flag them. --include-cli also checks for a newer release of this CLI,
+which is reported but not installed.`,
+	Example: `  gcp-emulator upgrade
+  gcp-emulator upgrade --check --include-cli
+  gcp-emulator config set upgrade.kms "~0.3"`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		ctx := context.Background()
+
+		info("Checking for updates...")
+		results := checkImages(ctx, cfg)
+		failed := 0
+		for _, result := range results {
+			printUpgradeResult(result)
+			if result.Err != nil {
+				failed++
+			}
+		}
+		changes := upgrade.Changes(results)
+
+		updates := len(changes)
+		if upgradeIncludeCLI {
+			newer, err := checkCLI(ctx, cmd.Root().Version)
+			if err != nil {
+				failed++
+			}
+			if newer {
+				updates++
+			}
+		}
+		// Each failure is shown above
+		var checkErr error
+		if failed > 0 {
+			checkErr = fmt.Errorf("%d check(s) failed", failed)
+		}
+
+		if upgradeCheck {
+			if updates > 0 {
+				return fmt.Errorf("%s %w", updateCount(updates), upgrade.ErrUpdatesAvailable)
+			}
+			return checkErr
+		}
+		if len(changes) == 0 {
+			if checkErr == nil {
+				color.Green("✓ The images are up to date")
+			}
+			return checkErr
+		}
+
+		if !upgradeYes {
+			if !isTerminal(os.Stdin) {
+				return errors.New("upgrade pulls images and recreates services; pass --yes to confirm")
+			}
+			answer, err := prompt(bufio.NewReader(os.Stdin), fmt.Sprintf("\nUpgrade %s? [y/N]", strings.Join(changedServices(changes), ", ")), "")
+			if err != nil {
+				return err
+			}
+			if a := strings.ToLower(answer); a != "y" && a != "yes" {
+				color.Yellow("⚠ Upgrade cancelled")
+				return nil
+			}
+		}
+		fmt.Println()
+		return applyUpgrade(cfg, changes)
+	},
+}
+
+// checkImages checks the image of every service for a newer version
+func checkImages(ctx context.Context, cfg *config.Config) []upgrade.Result {
+	reg := upgrade.NewRegistry()
+	results := make([]upgrade.Result, 0, len(docker.Services))
+	for _, service := range docker.Services {
+		ref := docker.ServiceImage(cfg, service)
+		results = append(results, upgrade.Check(ctx, reg, upgrade.Image{
+			Service:    service,
+			Ref:        ref,
+			Constraint: cfg.Upgrade.Constraint(service),
+			Local: func() ([]string, error) {
+				present, err := docker.ImagePresent(cfg, ref)
+				if err != nil || !present {
+					return nil, err
+				}
+				return docker.RepoDigests(cfg, ref)
+			},
+		}))
+	}
+	return results
+}
+
+// printUpgradeResult shows what the check of an image found
+func printUpgradeResult(result upgrade.Result) {
+	switch result.Status {
+	case upgrade.NewVersion:
+		available, _ := upgrade.ParseReference(result.Available)
+		color.Cyan("  → %-14s %s → %s", result.Service, result.Current, available.Tag)
+	case upgrade.NewBuild:
+		color.Cyan("  → %-14s %s (new build %s)", result.Service, result.Current, shortDigest(result.Digest))
+	case upgrade.UpToDate:
+		color.Green("  ✓ %-14s %s (up to date)", result.Service, result.Current)
+	case upgrade.Pinned:
+		fmt.Printf("  - %-14s %s (pinned to a digest)\n", result.Service, result.Current)
+	default:
+		color.Red("  ✗ %-14s %s (%v)", result.Service, result.Current, result.Err)
+	}
+}
+
+// shortDigest returns the first 12 hex digits of a digest, as docker
+// shows image IDs
+func shortDigest(digest string) string {
+	_, hex, _ := strings.Cut(digest, ":")
+	if len(hex) > 12 {
+		hex = hex[:12]
+	}
+	return hex
+}
+
+// checkCLI reports whether there is a newer release of the CLI than the
+// running version
+func checkCLI(ctx context.Context, version string) (bool, error) {
+	result, err := upgrade.CheckCLI(ctx, &http.Client{Timeout: upgrade.Timeout}, upgrade.ReleasesURL, version)
+	switch {
+	case err != nil:
+		color.Red("  ✗ %-14s %s (%v)", "gcp-emulator", version, err)
+		return false, err
+	case result.Newer:
+		color.Cyan("  → %-14s %s → %s", "gcp-emulator", version, result.Latest)
+		fmt.Printf("    Install it with: %s\n", upgrade.InstallCommand)
+	case result.Latest != "" && version == "dev":
+		fmt.Printf("  - %-14s development build (latest release %s)\n", "gcp-emulator", result.Latest)
+	default:
+		color.Green("  ✓ %-14s %s (up to date)", "gcp-emulator", version)
+	}
+	return result.Newer, nil
+}
+
+// applyUpgrade pulls the images of changes, saves the new version tags,
+// and recreates the services that changed if the stack is running
+func applyUpgrade(cfg *config.Config, changes []upgrade.Result) error {
+	for _, change := range changes {
+		if err := cfg.Images.Set(change.Service, change.Available); err != nil {
+			return err
+		}
+	}
+	services := changedServices(changes)
+	if err := pullImages(cfg, services, config.PullAlways); err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		if change.Status != upgrade.NewVersion {
+			continue
+		}
+		key, err := config.LookupKey("image-" + change.Service)
+		if err != nil {
+			return err
+		}
+		if err := config.SaveKey(cfg, key); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		color.Green("✓ Saved %s: %s", key.Name, change.Available)
+		switch source, _ := config.SourceOf(key.Name); source.Kind {
+		case config.SourceSet, config.SourceEnv, config.SourceLocal:
+			color.Yellow("⚠ %s is overridden by %s; update it there too", key.Name, source)
+		}
+	}
+
+	running, err := docker.Running(cfg)
+	if err != nil {
+		color.Red("✗ Failed to check the stack: %v", err)
+		printDockerHint(err)
+		return err
+	}
+	if !running {
+		color.Green("✓ Upgraded %s", strings.Join(services, ", "))
+		info("\nThe stack isn't running; 'gcp-emulator start' uses the new images")
+		return nil
+	}
+
+	what := strings.Join(services, ", ")
+	info("Recreating %s...", what)
+	if err := docker.Restart(cfg, services, true); err != nil {
+		color.Red("✗ Failed to recreate %s: %v", what, err)
+		printDockerHint(err)
+		return err
+	}
+	if err := waitHealthy(cfg, services, defaultWaitTimeout); err != nil {
+		color.Red("✗ Upgraded, but not healthy: %v", err)
+		return err
+	}
+	color.Green("✓ Upgraded %s", what)
+	return nil
+}
+
+// changedServices returns the services of changes
+func changedServices(changes []upgrade.Result) []string {
+	services := make([]string, 0, len(changes))
+	for _, change := range changes {
+		services = append(services, change.Service)
+	}
+	return services
+}
+
+// updateCount returns "1 update" or "n updates"
+func updateCount(n int) string {
+	if n == 1 {
+		return "1 update"
+	}
+	return fmt.Sprintf("%d updates", n)
+}
+
+func init() {
+	upgradeCmd.Flags().BoolVar(&upgradeCheck, "check", false, "Only report updates; exit 10 if there are any")
+	upgradeCmd.Flags().BoolVarP(&upgradeYes, "yes", "y", false, "Don't ask for confirmation")
+	upgradeCmd.Flags().BoolVar(&upgradeIncludeCLI, "include-cli", false, "Also check GitHub for a newer release of the CLI")
+}
@@ -3,18 +3,72 @@ package cli
 import (
 	"fmt"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
 )
 
 var versionCmd = &cobra.Command{
-	Use:   "version",
-	Short: "Show version information",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("gcp-emulator version %s\n", cmd.Root().Version)
-		fmt.Println("\nComponents:")
-		fmt.Println("  IAM Emulator:      v0.8.0")
-		fmt.Println("  Secret Manager:    v1.3.0")
-		fmt.Println("  KMS:               v0.3.0")
-		fmt.Println("  gcp-emulator-auth: v0.3.0")
+	Use:         "version",
+	Short:       "Show version information",
+	Annotations: renders,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		report := versionReport{Version: cmd.Root().Version}
+
+		cfg, loadErr := config.Load()
+		if loadErr == nil {
+			report.Images = &imagesReport{
+				IAM:           cfg.Images.IAM,
+				SecretManager: cfg.Images.SecretManager,
+				KMS:           cfg.Images.KMS,
+			}
+		}
+
+		if err := render(report, func() { printVersion(report) }); err != nil {
+			return err
+		}
+		if loadErr != nil {
+			color.Yellow("\n⚠ Configured images unavailable: %v", loadErr)
+		}
+		return nil
 	},
 }
+
+// versionReport is what version --output json and yaml print. Scripts
+// parse it, so fields are only ever added.
+type versionReport struct {
+	Version string `json:"version"`
+
+	// Images is missing when the config can't be loaded
+	Images *imagesReport `json:"images,omitempty"`
+}
+
+// imagesReport is the configured image of each service, "" for the
+// compose file's default
+type imagesReport struct {
+	IAM           string `json:"iam"`
+	SecretManager string `json:"secretManager"`
+	KMS           string `json:"kms"`
+}
+
+func printVersion(report versionReport) {
+	fmt.Printf("gcp-emulator version %s\n", report.Version)
+	if report.Images == nil {
+		return
+	}
+
+	fmt.Println("\nImages:")
+	fmt.Printf("  IAM Emulator:      %s\n", imageOrDefault(report.Images.IAM))
+	fmt.Printf("  Secret Manager:    %s\n", imageOrDefault(report.Images.SecretManager))
+	fmt.Printf("  KMS:               %s\n", imageOrDefault(report.Images.KMS))
+}
+
+// imageOrDefault describes an image reference, which is empty when the
+// compose file's default is used
+func imageOrDefault(ref string) string {
+	if ref == "" {
+		return "(docker-compose.yml default)"
+	}
+	return ref
+}
@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+)
+
+// defaultWaitTimeout is how long start and restart wait for the services to become
+// healthy unless --timeout is given
+const defaultWaitTimeout = 60 * time.Second
+
+// addWaitFlags adds the flags selecting whether and how long a command
+// waits for the services it starts to become healthy
+func addWaitFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("wait", true, "Wait for the services to pass their health checks")
+	cmd.Flags().Bool("no-wait", false, "Return without waiting for the services to become healthy")
+	cmd.Flags().Duration("timeout", defaultWaitTimeout, "How long to wait for the services to become healthy")
+}
+
+// waitFlags returns whether to wait, as set by the flags of addWaitFlags,
+// and for how long
+func waitFlags(cmd *cobra.Command) (bool, time.Duration) {
+	wait, _ := cmd.Flags().GetBool("wait")
+	noWait, _ := cmd.Flags().GetBool("no-wait")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	return wait && !noWait, timeout
+}
+
+// waitHealthy waits for services to pass their health checks, with a
+// spinner on a terminal. For each one that doesn't within timeout, the end
+// of its log is shown and a *docker.UnhealthyError returned.
+func waitHealthy(cfg *config.Config, services []string, timeout time.Duration) error {
+	info("→ Waiting for %s to become healthy (timeout %s)...", strings.Join(services, ", "), timeout)
+
+	var s *spinner
+	if !quiet && isTerminal(os.Stdout) {
+		s = startSpinner(services)
+	}
+	unhealthy := docker.WaitHealthy(cfg, services, timeout, func(service string, elapsed time.Duration) {
+		s.print(service, func() {
+			color.Green("  ✓ %-14s healthy after %s", service, elapsed.Round(100*time.Millisecond))
+		})
+	})
+	s.stop()
+
+	if len(unhealthy) == 0 {
+		return nil
+	}
+	for _, service := range unhealthy {
+		color.Red("  ✗ %-14s not healthy after %s", service, timeout)
+	}
+	for _, service := range unhealthy {
+		printLogTail(cfg, service)
+	}
+	return &docker.UnhealthyError{Services: unhealthy, Timeout: timeout}
+}
+
+// printLogTail shows the last startLogLines lines of a service's log
+func printLogTail(cfg *config.Config, service string) {
+	color.Red("\nLast %d log lines of %s:", startLogLines, service)
+	logs, err := docker.Logs(cfg, service, startLogLines)
+	if err != nil {
+		color.Yellow("⚠ %v", err)
+		return
+	}
+	fmt.Print(logs)
+}
+
+// spinnerFrames are drawn in turn while waiting
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// spinner redraws a line naming the services still waited for. A nil
+// spinner draws nothing, for output that isn't a terminal.
+type spinner struct {
+	mu      sync.Mutex
+	pending []string
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func startSpinner(services []string) *spinner {
+	s := &spinner{
+		pending: append([]string(nil), services...),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go func() {
+		defer close(s.stopped)
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for frame := 0; ; frame++ {
+			s.mu.Lock()
+			fmt.Printf("\r\033[K  %s waiting for %s", spinnerFrames[frame%len(spinnerFrames)], strings.Join(s.pending, ", "))
+			s.mu.Unlock()
+
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return s
+}
+
+// print clears the spinner line, drops service from it, and runs write to
+// print a line in its place
+func (s *spinner) print(service string, write func()) {
+	if s == nil {
+		write()
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fmt.Print("\r\033[K")
+	write()
+	for i, pending := range s.pending {
+		if pending == service {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			break
+		}
+	}
+}
+
+// stop removes the spinner line
+func (s *spinner) stop() {
+	if s == nil {
+		return
+	}
+	close(s.done)
+	<-s.stopped
+	fmt.Print("\r\033[K")
+}
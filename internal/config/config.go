@@ -2,13 +2,24 @@
 //
 // It implements the disciplined Viper pattern where Viper stays contained
 // in this package and the rest of the codebase receives explicit Config structs.
-// Configuration sources are resolved in this order: flags > env > config file > defaults.
+// Configuration sources are resolved in this order: flags > env > local
+// .gcp-emulator.yaml > profile > config file > defaults.
 package config
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/viper"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
 )
 
 // Config is the explicit configuration struct
@@ -19,6 +30,41 @@ type Config struct {
 	PullOnStart bool
 	PolicyFile  string
 	Ports       PortConfig
+	Docker      DockerConfig
+	Images      ImageConfig
+	Upgrade     UpgradeConfig
+	Health      HealthConfig
+	Lint        LintConfig
+	Resources   ResourceConfig
+
+	// PullPolicy is when start pulls images: always, missing, or never.
+	// "" is always with PullOnStart and missing without; see
+	// StartPullPolicy.
+	PullPolicy string
+
+	// OrderedStart starts the IAM emulator first and the data planes once
+	// it is healthy; see StartOrdered
+	OrderedStart bool
+
+	// SeedFile is a fixtures file seeded into the stack after every
+	// healthy start, or "" for none
+	SeedFile string
+
+	// Project is the default project of client libraries, which env
+	// exports, or "" for none
+	Project string
+
+	// RunTeardown is when run stops the stack after its command: always,
+	// started, on-success, or never. "" is started.
+	RunTeardown string
+
+	// Profile is the active named profile, or "" when none is applied
+	Profile string
+
+	// Stack is the named stack selected by --stack, or "" for the default
+	// stack. A named stack is a compose project of its own, with its own
+	// network, automatic ports, and state directory; see StatePath.
+	Stack string
 }
 
 // PortConfig defines port mappings for all services
@@ -26,6 +72,139 @@ type PortConfig struct {
 	IAM           int
 	SecretManager int
 	KMS           int
+
+	// HTTP ports of Secret Manager and KMS; zero uses the
+	// docker-compose.yml defaults, 8081 and 8082
+	SecretManagerHTTP int
+	KMSHTTP           int
+
+	// Auto makes start pick free ports instead of these
+	Auto bool
+}
+
+// IAMHTTPOffset is how far above its gRPC port the IAM emulator serves its
+// admin and health API
+const IAMHTTPOffset = 1000
+
+// publishedPort is a host port the stack publishes, named for errors
+type publishedPort struct {
+	name string
+	port int
+}
+
+// published returns every host port the stack publishes, including the
+// IAM HTTP port derived from port-iam and the HTTP port defaults
+func (p PortConfig) published() []publishedPort {
+	smHTTP, kmsHTTP := p.SecretManagerHTTP, p.KMSHTTP
+	if smHTTP == 0 {
+		smHTTP = 8081
+	}
+	if kmsHTTP == 0 {
+		kmsHTTP = 8082
+	}
+	return []publishedPort{
+		{"IAM", p.IAM},
+		{fmt.Sprintf("IAM HTTP (port-iam + %d)", IAMHTTPOffset), p.IAM + IAMHTTPOffset},
+		{"Secret Manager", p.SecretManager},
+		{"Secret Manager HTTP", smHTTP},
+		{"KMS", p.KMS},
+		{"KMS HTTP", kmsHTTP},
+	}
+}
+
+// validateUnique checks that no two published ports are the same, which
+// compose would only report as a bind failure. Automatic ports don't use
+// the configured ones, so they aren't checked then.
+func (p PortConfig) validateUnique() error {
+	if p.Auto {
+		return nil
+	}
+
+	used := make(map[int]string)
+	for _, pp := range p.published() {
+		if pp.port > 65535 {
+			return fmt.Errorf("%w for %s: %d", ErrInvalidPort, pp.name, pp.port)
+		}
+		if other, ok := used[pp.port]; ok {
+			return fmt.Errorf("%w: %s and %s both use %d", ErrInvalidPort, other, pp.name, pp.port)
+		}
+		used[pp.port] = pp.name
+	}
+	return nil
+}
+
+// DockerConfig selects the docker daemon the stack runs on
+type DockerConfig struct {
+	// Host is where the stack's ports are reached, for health checks and
+	// printed endpoints; "" means localhost
+	Host string
+
+	// Context is the docker context compose runs against, or "" for the
+	// current one (or DOCKER_HOST). With podman it is the podman system
+	// connection.
+	Context string
+
+	// Runtime is the container runtime: RuntimeDocker, RuntimePodman, or
+	// RuntimeAuto (or "") for the first one with a compose command
+	Runtime string
+
+	// Project is the compose project name, so the stack's containers are
+	// the same wherever the CLI is run from
+	Project string
+
+	// Network is the stack's docker network, or "" for <project>_default
+	Network string
+
+	// ComposeFile is a compose file to use instead of the one generated
+	// from the config, or "" to generate it
+	ComposeFile string
+
+	// ComposeOverrides are compose files merged over the stack's compose
+	// file, in order, as further -f files
+	ComposeOverrides []string
+}
+
+// DefaultProject is the compose project name when none is configured
+const DefaultProject = "gcp-emulator"
+
+// Container runtimes of the container-runtime key
+const (
+	RuntimeAuto   = "auto"
+	RuntimeDocker = "docker"
+	RuntimePodman = "podman"
+)
+
+// Names docker compose and docker accept for projects and networks
+var (
+	projectNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	networkNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+)
+
+// Remote reports whether the stack runs on another machine
+func (d DockerConfig) Remote() bool {
+	switch d.Host {
+	case "", "localhost", "127.0.0.1", "::1":
+		return false
+	}
+	return true
+}
+
+// HostName returns Host, or localhost if it is not set
+func (d DockerConfig) HostName() string {
+	if d.Host == "" {
+		return "localhost"
+	}
+	return d.Host
+}
+
+// Address returns host:port for a port of the stack
+func (d DockerConfig) Address(port int) string {
+	return net.JoinHostPort(d.HostName(), strconv.Itoa(port))
+}
+
+// LintConfig controls policy lint rules
+type LintConfig struct {
+	Disable []string
 }
 
 // Init initializes viper with defaults and config file paths
@@ -34,45 +213,153 @@ func Init() error {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
 
-	// Add config file search paths
-	viper.AddConfigPath("$HOME/.gcp-emulator")
+	// Add config file search paths: the user config directory, then the
+	// legacy ~/.gcp-emulator, then the current directory
+	configDir, err := ConfigDir()
+	if err != nil {
+		return err
+	}
+	legacyDir, err := LegacyDir()
+	if err != nil {
+		return err
+	}
+	viper.AddConfigPath(configDir)
+	viper.AddConfigPath(legacyDir)
 	viper.AddConfigPath(".")
 
 	// Set defaults
-	viper.SetDefault("iam-mode", "permissive")
-	viper.SetDefault("trace", false)
-	viper.SetDefault("pull-on-start", false)
-	viper.SetDefault("policy-file", "./policy.yaml")
-	viper.SetDefault("port-iam", 8080)
-	viper.SetDefault("port-secret-manager", 9090)
-	viper.SetDefault("port-kms", 9091)
-
-	// Bind environment variables with prefix
-	viper.SetEnvPrefix("GCP_EMULATOR")
+	defaults := Defaults()
+	for _, key := range keys {
+		viper.SetDefault(key.Name, key.value(defaults))
+	}
+
+	// Bind environment variables with prefix, so iam-mode is read from
+	// GCP_EMULATOR_IAM_MODE
+	viper.SetEnvPrefix(envPrefix)
+	viper.SetEnvKeyReplacer(envKeyReplacer)
 	viper.AutomaticEnv()
+	for _, key := range keys {
+		if err := viper.BindEnv(key.Name, key.EnvVar()); err != nil {
+			return fmt.Errorf("failed to bind %s: %w", key.EnvVar(), err)
+		}
+	}
+
+	// GCP_EMULATOR_<KEY>_FILE names a file holding the value instead
+	if err := applyEnvFiles(); err != nil {
+		return err
+	}
+
+	// A config file named by --config or GCP_EMULATOR_CONFIG is used as is,
+	// without searching, and must exist
+	if file := viper.GetString("config"); file != "" {
+		if _, err := os.Stat(file); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("config file %s: %w", file, ErrConfigNotFound)
+			}
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+		viper.SetConfigFile(file)
+	}
 
 	// Read config file (ignore if not found)
+	layers.file, layers.profile, layers.local = nil, nil, nil
+	mergedProfile = ""
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return fmt.Errorf("failed to read config file: %w", err)
+			return readError(err)
 		}
 	}
+	if file := viper.ConfigFileUsed(); file != "" {
+		v, err := readLayer(file)
+		if err != nil {
+			return err
+		}
+		layers.file = &layer{path: file, values: v}
+		slog.Debug("config file read", "path", file)
+	}
+
+	// A .gcp-emulator.yaml in this project overrides the config file
+	if err := mergeLocal(); err != nil {
+		return err
+	}
 
+	// --set values override everything else
+	applyOverrides()
 	return nil
 }
 
-// Load reads from all sources and returns explicit Config
+// FileUsed returns the config file that was read, or "" if there is none
+func FileUsed() string {
+	return viper.ConfigFileUsed()
+}
+
+// Load reads from all sources and returns explicit Config. The active
+// profile, if any, is merged over the config file first.
 func Load() (*Config, error) {
+	if err := applyProfile(); err != nil {
+		return nil, err
+	}
+
 	cfg := &Config{
 		IAMMode:     viper.GetString("iam-mode"),
 		Trace:       viper.GetBool("trace"),
 		PullOnStart: viper.GetBool("pull-on-start"),
+		PullPolicy:  viper.GetString("pull-policy"),
 		PolicyFile:  viper.GetString("policy-file"),
 		Ports: PortConfig{
 			IAM:           viper.GetInt("port-iam"),
 			SecretManager: viper.GetInt("port-secret-manager"),
 			KMS:           viper.GetInt("port-kms"),
+			Auto:          viper.GetBool("ports.auto"),
+
+			SecretManagerHTTP: viper.GetInt("port-secret-manager-http"),
+			KMSHTTP:           viper.GetInt("port-kms-http"),
+		},
+		Health: HealthConfig{
+			Timeout: viper.GetDuration("health-timeout"),
+			Retries: viper.GetInt("health-retries"),
+			URLs: HealthURLs{
+				IAM:           viper.GetString("health-url-iam"),
+				SecretManager: viper.GetString("health-url-secret-manager"),
+				KMS:           viper.GetString("health-url-kms"),
+			},
+		},
+		Images: ImageConfig{
+			IAM:           viper.GetString("image-iam"),
+			SecretManager: viper.GetString("image-secret-manager"),
+			KMS:           viper.GetString("image-kms"),
 		},
+		Upgrade: UpgradeConfig{
+			IAM:           viper.GetString("upgrade.iam"),
+			SecretManager: viper.GetString("upgrade.secret-manager"),
+			KMS:           viper.GetString("upgrade.kms"),
+		},
+		Lint: LintConfig{
+			Disable: getList("lint.disable"),
+		},
+		Resources:    loadResources(),
+		OrderedStart: viper.GetBool("ordered-start"),
+		SeedFile:     viper.GetString("seed"),
+		Project:      viper.GetString("project"),
+		RunTeardown:  viper.GetString("run-teardown"),
+		Docker: DockerConfig{
+			Host:    viper.GetString("host"),
+			Context: viper.GetString("docker-context"),
+			Runtime: viper.GetString("container-runtime"),
+			Project: viper.GetString("compose-project"),
+			Network: viper.GetString("network-name"),
+
+			ComposeFile:      viper.GetString("compose-file"),
+			ComposeOverrides: getList("compose-overrides"),
+		},
+		Profile: ActiveProfile(),
+		Stack:   ActiveStack(),
+	}
+
+	// A named stack runs next to others, so its ports are always picked
+	// free rather than configured
+	if cfg.Stack != "" {
+		cfg.Ports.Auto = true
 	}
 
 	// Validate
@@ -83,38 +370,263 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// Errors returned, wrapped with context, when the configuration can't be
+// read or is invalid. Check for them with errors.Is.
+var (
+	ErrInvalidMode    = errors.New("invalid iam-mode")
+	ErrInvalidPort    = errors.New("invalid port")
+	ErrInvalidValue   = errors.New("invalid value")
+	ErrConfigNotFound = errors.New("not found")
+	ErrConfigParse    = errors.New("failed to parse config file")
+)
+
+// readError wraps an error from reading a config file, marking a
+// malformed file with ErrConfigParse
+func readError(err error) error {
+	var parseErr viper.ConfigParseError
+	if errors.As(err, &parseErr) {
+		return fmt.Errorf("%w: %w", ErrConfigParse, err)
+	}
+	return fmt.Errorf("failed to read config file: %w", err)
+}
+
 // Validate ensures config is sane
 func (c *Config) Validate() error {
 	if c.IAMMode != "off" && c.IAMMode != "permissive" && c.IAMMode != "strict" {
-		return fmt.Errorf("invalid iam-mode: %s (must be off, permissive, or strict)", c.IAMMode)
+		return fmt.Errorf("%w: %s (must be off, permissive, or strict)", ErrInvalidMode, c.IAMMode)
 	}
 
 	if c.Ports.IAM < 1 || c.Ports.IAM > 65535 {
-		return fmt.Errorf("invalid IAM port: %d", c.Ports.IAM)
+		return fmt.Errorf("%w for IAM: %d", ErrInvalidPort, c.Ports.IAM)
 	}
 
 	if c.Ports.SecretManager < 1 || c.Ports.SecretManager > 65535 {
-		return fmt.Errorf("invalid Secret Manager port: %d", c.Ports.SecretManager)
+		return fmt.Errorf("%w for Secret Manager: %d", ErrInvalidPort, c.Ports.SecretManager)
 	}
 
 	if c.Ports.KMS < 1 || c.Ports.KMS > 65535 {
-		return fmt.Errorf("invalid KMS port: %d", c.Ports.KMS)
+		return fmt.Errorf("%w for KMS: %d", ErrInvalidPort, c.Ports.KMS)
+	}
+
+	if err := c.Docker.validate(); err != nil {
+		return err
+	}
+
+	for _, p := range []struct {
+		name string
+		port int
+	}{
+		{"Secret Manager HTTP", c.Ports.SecretManagerHTTP},
+		{"KMS HTTP", c.Ports.KMSHTTP},
+	} {
+		if p.port != 0 && (p.port < 1 || p.port > 65535) {
+			return fmt.Errorf("%w for %s: %d", ErrInvalidPort, p.name, p.port)
+		}
+	}
+
+	if err := c.Ports.validateUnique(); err != nil {
+		return err
+	}
+
+	if err := c.Health.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Images.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Upgrade.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Resources.validate(); err != nil {
+		return err
+	}
+
+	switch c.PullPolicy {
+	case "", PullAlways, PullMissing, PullNever:
+	default:
+		return fmt.Errorf("%w for pull-policy: %q (must be always, missing, or never)", ErrInvalidValue, c.PullPolicy)
+	}
+
+	switch c.RunTeardown {
+	case "", TeardownAlways, TeardownStarted, TeardownOnSuccess, TeardownNever:
+	default:
+		return fmt.Errorf("%w for run-teardown: %q (must be always, started, on-success, or never)", ErrInvalidValue, c.RunTeardown)
+	}
+
+	if c.Stack != "" {
+		if err := ValidateStackName(c.Stack); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-// Save writes current config to file
+// Pull policies of start
+const (
+	PullAlways  = "always"
+	PullMissing = "missing"
+	PullNever   = "never"
+)
+
+// Teardowns of run: when it stops the stack after its command
+const (
+	// TeardownAlways stops the stack, even one that was already running
+	TeardownAlways = "always"
+
+	// TeardownStarted stops the stack only if run started it
+	TeardownStarted = "started"
+
+	// TeardownOnSuccess stops a stack run started only if the command
+	// succeeded, keeping it to debug a failure
+	TeardownOnSuccess = "on-success"
+
+	// TeardownNever leaves the stack running
+	TeardownNever = "never"
+)
+
+// StartOrdered reports whether start brings the IAM emulator up before
+// the data planes. Outside off mode they fail closed while IAM can't be
+// reached; in off mode they don't use it, so the stack starts in parallel.
+func (c *Config) StartOrdered() bool {
+	return c.OrderedStart && c.IAMMode != "off"
+}
+
+// StartPullPolicy returns when start pulls images: PullPolicy if set,
+// otherwise always with PullOnStart and missing without
+func (c *Config) StartPullPolicy() string {
+	switch {
+	case c.PullPolicy != "":
+		return c.PullPolicy
+	case c.PullOnStart:
+		return PullAlways
+	}
+	return PullMissing
+}
+
+// validate checks that Host, if set, is a bare host name or IP address,
+// that Runtime is a known runtime, and that the project and network names
+// are ones docker accepts
+func (d DockerConfig) validate() error {
+	if strings.ContainsAny(d.Host, "/ \t") ||
+		(strings.Contains(d.Host, ":") && net.ParseIP(d.Host) == nil) {
+		return fmt.Errorf("%w for host: %q (use a host name or IP address without scheme or port)", ErrInvalidValue, d.Host)
+	}
+	switch d.Runtime {
+	case "", RuntimeAuto, RuntimeDocker, RuntimePodman:
+	default:
+		return fmt.Errorf("%w for container-runtime: %q (must be auto, docker, or podman)", ErrInvalidValue, d.Runtime)
+	}
+	if d.Project != "" && !projectNamePattern.MatchString(d.Project) {
+		return fmt.Errorf("%w for compose-project: %q (use lowercase letters, digits, - and _)", ErrInvalidValue, d.Project)
+	}
+	if d.Network != "" && !networkNamePattern.MatchString(d.Network) {
+		return fmt.Errorf("%w for network-name: %q (use letters, digits, _, . and -)", ErrInvalidValue, d.Network)
+	}
+	return nil
+}
+
+// ErrNoPolicyFile is returned by ValidatePolicyFile when PolicyFile does
+// not exist
+var ErrNoPolicyFile = errors.New("not found")
+
+// ValidatePolicyFile checks that PolicyFile exists and loads as a policy,
+// read with opts. The IAM emulator needs it in permissive and strict modes
+// and ignores it in off mode, so callers decide how much a failure matters.
+func (c *Config) ValidatePolicyFile(opts policy.LoadOptions) error {
+	info, err := os.Stat(c.PolicyFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("policy file %s: %w", c.PolicyFile, ErrNoPolicyFile)
+	}
+	if err != nil {
+		return fmt.Errorf("policy file %s is not readable: %w", c.PolicyFile, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("policy file %s is a directory", c.PolicyFile)
+	}
+
+	if _, err := opts.Load(c.PolicyFile); err != nil {
+		return fmt.Errorf("policy file %s is invalid: %w", c.PolicyFile, err)
+	}
+	return nil
+}
+
+// Save writes current config to file: the profile's file when a profile
+// is active, otherwise the config file it was loaded from, creating
+// config.yaml in ConfigDir if there is none. Other keys in the file, such as the active profile,
+// are kept.
 func Save(cfg *Config) error {
-	viper.Set("iam-mode", cfg.IAMMode)
-	viper.Set("trace", cfg.Trace)
-	viper.Set("pull-on-start", cfg.PullOnStart)
-	viper.Set("policy-file", cfg.PolicyFile)
-	viper.Set("port-iam", cfg.Ports.IAM)
-	viper.Set("port-secret-manager", cfg.Ports.SecretManager)
-	viper.Set("port-kms", cfg.Ports.KMS)
+	path, err := savePath(cfg)
+	if err != nil {
+		return err
+	}
 
-	return viper.WriteConfig()
+	return editConfigFile(path, func(v *viper.Viper) {
+		setValues(v, cfg)
+	})
+}
+
+// SaveKey writes only key's value from cfg to the file Save would write,
+// so values from the environment or a local config file aren't copied
+// into it
+func SaveKey(cfg *Config, key Key) error {
+	path, err := savePath(cfg)
+	if err != nil {
+		return err
+	}
+
+	return editConfigFile(path, func(v *viper.Viper) {
+		v.Set(key.Name, key.value(cfg))
+	})
+}
+
+// savePath returns the file Save writes cfg to
+func savePath(cfg *Config) (string, error) {
+	if cfg.Profile != "" {
+		return ProfilePath(cfg.Profile)
+	}
+	return configFilePath()
+}
+
+// configFilePath returns the config file in use, or config.yaml in
+// ConfigDir if none was found
+func configFilePath() (string, error) {
+	if file := viper.ConfigFileUsed(); file != "" {
+		return file, nil
+	}
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.yaml"), nil
+}
+
+// editConfigFile applies edit to the config file at path as written,
+// without the values viper has merged from other sources, and writes it
+// back. A missing file is created.
+func editConfigFile(path string, edit func(v *viper.Viper)) error {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return readError(err)
+	}
+
+	edit(v)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	return v.WriteConfigAs(path)
+}
+
+// setValues copies cfg into v under the config file keys
+func setValues(v *viper.Viper, cfg *Config) {
+	for _, key := range keys {
+		v.Set(key.Name, key.value(cfg))
+	}
 }
 
 // Display shows current config (for gcp-emulator config get)
@@ -125,33 +637,213 @@ func Display() (string, error) {
 	}
 
 	configFile := viper.ConfigFileUsed()
-	if configFile == "" {
+	switch {
+	case configFile == "":
 		configFile = "(not found)"
+	case viper.GetString("config") == "":
+		// Found by searching the config directories
+	case os.Getenv("GCP_EMULATOR_CONFIG") == viper.GetString("config"):
+		configFile += " (GCP_EMULATOR_CONFIG)"
+	default:
+		configFile += " (--config)"
+	}
+
+	profile := "(none)"
+	if cfg.Profile != "" {
+		path, err := ProfilePath(cfg.Profile)
+		if err != nil {
+			return "", err
+		}
+		profile = fmt.Sprintf("%s (%s)", cfg.Profile, path)
 	}
 
 	return fmt.Sprintf(`Configuration:
   iam-mode:           %s
   trace:              %t
   pull-on-start:      %t
+  pull-policy:        %s
+  ordered-start:      %t
   policy-file:        %s
+  seed:               %s
+  project:            %s
+  run-teardown:       %s
+  host:               %s
+  docker-context:     %s
+  container-runtime:  %s
+  compose-project:    %s
+  network-name:       %s
+  compose-file:       %s
+  compose-overrides:  %s
   
 Ports:
   IAM:                %d
-  Secret Manager:     %d
-  KMS:                %d
+  Secret Manager:     %d (HTTP %d)
+  KMS:                %d (HTTP %d)
+  auto:               %t
+
+Health checks:
+  timeout:            %s
+  retries:            %d
+  IAM URL:            %s
+  Secret Manager URL: %s
+  KMS URL:            %s
+
+Images:
+  IAM:                %s
+  Secret Manager:     %s
+  KMS:                %s
+
+Upgrade constraints:
+  IAM:                %s
+  Secret Manager:     %s
+  KMS:                %s
+
+Lint:
+  disable:            %s
+
+Resources:
+  all services:       %s
+  IAM:                %s
+  Secret Manager:     %s
+  KMS:                %s
   
 Sources:
   Config file:        %s
+  Profile:            %s
+  Stack:              %s
+  Local file:         %s
   Environment:        GCP_EMULATOR_*
   Flags:              (per command)
+  --set:              %s
 `,
 		cfg.IAMMode,
 		cfg.Trace,
 		cfg.PullOnStart,
+		cfg.StartPullPolicy(),
+		cfg.OrderedStart,
 		cfg.PolicyFile,
+		orNone(cfg.SeedFile),
+		orNone(cfg.Project),
+		cfg.RunTeardown,
+		cfg.Docker.Host,
+		orCurrent(cfg.Docker.Context),
+		cfg.Docker.Runtime,
+		cfg.Docker.Project,
+		orProjectNetwork(cfg.Docker.Network),
+		orGenerated(cfg.Docker.ComposeFile),
+		formatList(cfg.Docker.ComposeOverrides),
 		cfg.Ports.IAM,
 		cfg.Ports.SecretManager,
+		cfg.Ports.SecretManagerHTTP,
 		cfg.Ports.KMS,
+		cfg.Ports.KMSHTTP,
+		cfg.Ports.Auto,
+		cfg.Health.Timeout,
+		cfg.Health.Retries,
+		orDerived(cfg.Health.URLs.IAM),
+		orDerived(cfg.Health.URLs.SecretManager),
+		orDerived(cfg.Health.URLs.KMS),
+		cfg.Images.IAM,
+		cfg.Images.SecretManager,
+		cfg.Images.KMS,
+		orAny(cfg.Upgrade.IAM),
+		orAny(cfg.Upgrade.SecretManager),
+		orAny(cfg.Upgrade.KMS),
+		formatList(cfg.Lint.Disable),
+		cfg.Resources.ServiceResources,
+		cfg.Resources.For("iam"),
+		cfg.Resources.For("secret-manager"),
+		cfg.Resources.For("kms"),
 		configFile,
-	), nil
+		profile,
+		orDefaultStack(cfg.Stack),
+		orNone(LocalFile()),
+		orNone(overridePairs()),
+	) + displaySources() + displayEnv(), nil
+}
+
+// displayEnv lists the environment variable for each key, marking those
+// that are set
+func displayEnv() string {
+	var b strings.Builder
+	b.WriteString("\nEnvironment variables (or <name>_FILE to read the value from a file):\n")
+	for _, key := range keys {
+		set := ""
+		if _, ok := os.LookupEnv(key.EnvVar()); ok {
+			set = " (set)"
+		} else if _, ok := os.LookupEnv(key.FileEnvVar()); ok {
+			set = " (set via " + key.FileEnvVar() + ")"
+		}
+		fmt.Fprintf(&b, "  %-22s%s%s\n", key.Name+":", key.EnvVar(), set)
+	}
+	return b.String()
+}
+
+// getList reads a list value. Environment variables give lists as one
+// comma-separated string, as 'config set' does.
+func getList(key string) []string {
+	if s, ok := viper.Get(key).(string); ok {
+		return splitList(s)
+	}
+	return viper.GetStringSlice(key)
+}
+
+// orDefaultStack formats a stack name, where "" means the default stack
+func orDefaultStack(stack string) string {
+	if stack == "" {
+		return DefaultStack
+	}
+	return stack
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
+
+func orAny(constraint string) string {
+	if constraint == "" {
+		return "(any)"
+	}
+	return constraint
+}
+
+func orProjectNetwork(network string) string {
+	if network == "" {
+		return "(<project>_default)"
+	}
+	return network
+}
+
+func orGenerated(file string) string {
+	if file == "" {
+		return "(generated)"
+	}
+	return file
+}
+
+// orCurrent formats a docker context, where "" means the current one
+func orCurrent(context string) string {
+	if context == "" {
+		return "(current)"
+	}
+	return context
+}
+
+// orDerived formats an override that falls back to a derived value
+func orDerived(value string) string {
+	if value == "" {
+		return "(from port)"
+	}
+	return value
+}
+
+// formatList formats a string list for display
+func formatList(values []string) string {
+	if len(values) == 0 {
+		return "(none)"
+	}
+	return strings.Join(values, ", ")
 }
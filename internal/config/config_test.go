@@ -1,7 +1,15 @@
 package config
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
 )
 
 func TestConfigValidation(t *testing.T) {
@@ -130,3 +138,254 @@ func TestDefaultConfig(t *testing.T) {
 		t.Errorf("Default config should be valid, got error: %v", err)
 	}
 }
+
+func TestDockerConfig(t *testing.T) {
+	tests := []struct {
+		host    string
+		remote  bool
+		address string
+		wantErr bool
+	}{
+		{host: "", remote: false, address: "localhost:8080"},
+		{host: "127.0.0.1", remote: false, address: "127.0.0.1:8080"},
+		{host: "docker.internal", remote: true, address: "docker.internal:8080"},
+		{host: "fd00::10", remote: true, address: "[fd00::10]:8080"},
+		{host: "docker.internal:2375", wantErr: true},
+		{host: "tcp://docker.internal", wantErr: true},
+	}
+	for _, tt := range tests {
+		d := DockerConfig{Host: tt.host}
+		if err := d.validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%q: validate() error = %v, wantErr %v", tt.host, err, tt.wantErr)
+		}
+		if tt.wantErr {
+			continue
+		}
+		if d.Remote() != tt.remote {
+			t.Errorf("%q: Remote() = %t, want %t", tt.host, d.Remote(), tt.remote)
+		}
+		if got := d.Address(8080); got != tt.address {
+			t.Errorf("%q: Address() = %s, want %s", tt.host, got, tt.address)
+		}
+	}
+}
+
+func TestValidateContainerRuntime(t *testing.T) {
+	for _, runtime := range []string{"", RuntimeAuto, RuntimeDocker, RuntimePodman} {
+		if err := (DockerConfig{Runtime: runtime}).validate(); err != nil {
+			t.Errorf("%q: expected a valid runtime, got %v", runtime, err)
+		}
+	}
+	err := DockerConfig{Runtime: "containerd"}.validate()
+	if !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Expected an unknown runtime to be an invalid value, got %v", err)
+	}
+}
+
+func TestValidatePolicyFile(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "policy.yaml")
+	if err := os.WriteFile(valid, []byte("roles: {}\ngroups: {}\nprojects: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken.yaml")
+	if err := os.WriteFile(broken, []byte("roles: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		policyFile string
+		wantErr    bool
+		notFound   bool
+	}{
+		{name: "valid policy", policyFile: valid},
+		{name: "missing file", policyFile: filepath.Join(dir, "missing.yaml"), wantErr: true, notFound: true},
+		{name: "directory", policyFile: dir, wantErr: true},
+		{name: "parse error", policyFile: broken, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Defaults()
+			cfg.PolicyFile = tt.policyFile
+
+			err := cfg.ValidatePolicyFile(policy.LoadOptions{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidatePolicyFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := errors.Is(err, ErrNoPolicyFile); got != tt.notFound {
+				t.Errorf("errors.Is(err, ErrNoPolicyFile) = %v, want %v", got, tt.notFound)
+			}
+		})
+	}
+}
+
+func TestExplicitConfigFile(t *testing.T) {
+	home := withTestHome(t)
+
+	// A config file the search would find otherwise
+	if err := os.WriteFile(filepath.Join(home, "config.yaml"), []byte("iam-mode: off\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(home, "ci", "emulator-config.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("iam-mode: strict\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GCP_EMULATOR_CONFIG", path)
+	viper.Reset()
+	if err := Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.IAMMode != "strict" {
+		t.Errorf("Expected the explicit config file to be used, got iam-mode %s", cfg.IAMMode)
+	}
+	out, err := Display()
+	if err != nil {
+		t.Fatalf("Display failed: %v", err)
+	}
+	if !strings.Contains(out, path+" (GCP_EMULATOR_CONFIG)") {
+		t.Errorf("Expected Display to show the explicit config file, got:\n%s", out)
+	}
+
+	t.Setenv("GCP_EMULATOR_CONFIG", filepath.Join(home, "missing.yaml"))
+	viper.Reset()
+	if err := Init(); err == nil {
+		t.Error("Expected a missing explicit config file to fail")
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	cfg := Defaults()
+	cfg.IAMMode = "lenient"
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidMode) {
+		t.Errorf("Expected ErrInvalidMode, got %v", err)
+	}
+
+	cfg = Defaults()
+	cfg.Ports.SecretManagerHTTP = 70000
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidPort) {
+		t.Errorf("Expected ErrInvalidPort, got %v", err)
+	}
+
+	cfg = Defaults()
+	cfg.Docker.Host = "tcp://docker.internal"
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Expected ErrInvalidValue, got %v", err)
+	}
+
+	cfg = Defaults()
+	cfg.PullPolicy = "sometimes"
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Expected ErrInvalidValue for pull-policy, got %v", err)
+	}
+
+	cfg = Defaults()
+	cfg.RunTeardown = "sometimes"
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Expected ErrInvalidValue for run-teardown, got %v", err)
+	}
+
+	dir := withTestHome(t)
+	t.Setenv("GCP_EMULATOR_CONFIG", filepath.Join(dir, "missing.yaml"))
+	viper.Reset()
+	if err := Init(); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected ErrConfigNotFound, got %v", err)
+	}
+
+	malformed := filepath.Join(dir, "malformed.yaml")
+	if err := os.WriteFile(malformed, []byte("iam-mode: [strict\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GCP_EMULATOR_CONFIG", malformed)
+	viper.Reset()
+	if err := Init(); !errors.Is(err, ErrConfigParse) {
+		t.Errorf("Expected ErrConfigParse, got %v", err)
+	}
+}
+
+func TestPortCollisions(t *testing.T) {
+	tests := []struct {
+		name  string
+		ports func(p *PortConfig)
+		want  []string // in the error, or nil for valid
+	}{
+		{"defaults", func(p *PortConfig) {}, nil},
+		{"same gRPC port", func(p *PortConfig) { p.KMS = p.IAM }, []string{"IAM", "KMS", "8080"}},
+		{"derived IAM HTTP port", func(p *PortConfig) { p.IAM = 8091 }, []string{"IAM HTTP (port-iam + 1000)", "KMS", "9091"}},
+		{"HTTP port on a gRPC port", func(p *PortConfig) { p.SecretManagerHTTP = 9091 }, []string{"Secret Manager HTTP", "KMS", "9091"}},
+		{"default HTTP port", func(p *PortConfig) { p.KMSHTTP = 0; p.SecretManager = 8082 }, []string{"Secret Manager", "KMS HTTP", "8082"}},
+		{"derived port out of range", func(p *PortConfig) { p.IAM = 65000 }, []string{"IAM HTTP", "66000"}},
+		{"automatic ports", func(p *PortConfig) { p.KMS = p.IAM; p.Auto = true }, nil},
+	}
+	for _, tt := range tests {
+		cfg := Defaults()
+		tt.ports(&cfg.Ports)
+
+		err := cfg.Validate()
+		if tt.want == nil {
+			if err != nil {
+				t.Errorf("%s: expected valid ports, got %v", tt.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrInvalidPort) {
+			t.Errorf("%s: expected ErrInvalidPort, got %v", tt.name, err)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: expected %q in %q", tt.name, want, err)
+			}
+		}
+	}
+}
+
+func TestStartPullPolicy(t *testing.T) {
+	tests := []struct {
+		policy      string
+		pullOnStart bool
+		want        string
+	}{
+		{"", false, PullMissing},
+		{"", true, PullAlways},
+		{PullNever, true, PullNever},
+		{PullAlways, false, PullAlways},
+	}
+	for _, tt := range tests {
+		cfg := Defaults()
+		cfg.PullPolicy, cfg.PullOnStart = tt.policy, tt.pullOnStart
+		if got := cfg.StartPullPolicy(); got != tt.want {
+			t.Errorf("StartPullPolicy(%q, pull-on-start %t) = %s, want %s", tt.policy, tt.pullOnStart, got, tt.want)
+		}
+	}
+}
+
+func TestStartOrdered(t *testing.T) {
+	tests := []struct {
+		mode    string
+		ordered bool
+		want    bool
+	}{
+		{"strict", true, true},
+		{"permissive", true, true},
+		{"off", true, false},
+		{"strict", false, false},
+	}
+	for _, tt := range tests {
+		cfg := Defaults()
+		cfg.IAMMode, cfg.OrderedStart = tt.mode, tt.ordered
+		if got := cfg.StartOrdered(); got != tt.want {
+			t.Errorf("StartOrdered(%s, ordered-start %t) = %t, want %t", tt.mode, tt.ordered, got, tt.want)
+		}
+	}
+}
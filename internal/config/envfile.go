@@ -0,0 +1,55 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// FileEnvVar returns the environment variable naming a file the key's
+// value is read from, such as GCP_EMULATOR_IAM_MODE_FILE for iam-mode.
+// It suits values mounted as secret files in CI.
+func (k Key) FileEnvVar() string {
+	return k.EnvVar() + "_FILE"
+}
+
+// ReadValueFile returns the contents of path as a config value, without
+// the trailing newline editors and secret mounts add
+func ReadValueFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("value file %s: %w", path, ErrConfigNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read value file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// applyEnvFiles reads the value of every key whose _FILE variable is set.
+// The plain variable still wins when both are set, and a flag wins over
+// either.
+func applyEnvFiles() error {
+	for _, key := range keys {
+		path, ok := os.LookupEnv(key.FileEnvVar())
+		if !ok {
+			continue
+		}
+		if _, ok := os.LookupEnv(key.EnvVar()); ok {
+			continue
+		}
+		if flag, ok := boundFlags[key.Name]; ok && flag.Changed {
+			continue
+		}
+
+		value, err := ReadValueFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key.FileEnvVar(), err)
+		}
+		viper.Set(key.Name, value)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+func TestEnvFiles(t *testing.T) {
+	home := withTestHome(t)
+
+	policyPath := filepath.Join(home, "secrets", "policy-file")
+	writeFile(t, policyPath, "/run/policy.yaml\n")
+	modePath := filepath.Join(home, "secrets", "iam-mode")
+	writeFile(t, modePath, "strict\n")
+
+	t.Setenv("GCP_EMULATOR_POLICY_FILE_FILE", policyPath)
+	t.Setenv("GCP_EMULATOR_IAM_MODE_FILE", modePath)
+	t.Setenv("GCP_EMULATOR_IAM_MODE", "off")
+	viper.Reset()
+	if err := Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.PolicyFile != "/run/policy.yaml" {
+		t.Errorf("Expected policy-file from the _FILE variable without its newline, got %q", cfg.PolicyFile)
+	}
+	if cfg.IAMMode != "off" {
+		t.Errorf("Expected the plain variable to win over the _FILE variable, got iam-mode %s", cfg.IAMMode)
+	}
+	if source, _ := SourceOf("policy-file"); source != (Source{Kind: SourceEnv, Name: "GCP_EMULATOR_POLICY_FILE_FILE"}) {
+		t.Errorf("Expected the _FILE variable as the source, got %v", source)
+	}
+
+	t.Setenv("GCP_EMULATOR_POLICY_FILE_FILE", filepath.Join(home, "missing"))
+	viper.Reset()
+	if err := Init(); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected a missing value file to fail with ErrConfigNotFound, got %v", err)
+	}
+}
+
+func TestEnvFileFlagPrecedence(t *testing.T) {
+	home := withTestHome(t)
+
+	modePath := filepath.Join(home, "iam-mode")
+	writeFile(t, modePath, "permissive")
+	t.Setenv("GCP_EMULATOR_IAM_MODE_FILE", modePath)
+
+	flags := pflag.NewFlagSet("start", pflag.ContinueOnError)
+	flags.String("mode", "", "")
+	if err := flags.Parse([]string{"--mode", "strict"}); err != nil {
+		t.Fatal(err)
+	}
+
+	viper.Reset()
+	if err := BindFlag("iam-mode", flags.Lookup("mode")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { delete(boundFlags, "iam-mode") })
+	if err := Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if cfg, _ := Load(); cfg.IAMMode != "strict" {
+		t.Errorf("Expected the flag to win over the _FILE variable, got iam-mode %s", cfg.IAMMode)
+	}
+}
+
+func TestReadValueFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "value")
+	writeFile(t, path, "a value with spaces & quotes \"\r\n")
+	if got, err := ReadValueFile(path); err != nil || got != "a value with spaces & quotes \"" {
+		t.Errorf("ReadValueFile() = %q, %v", got, err)
+	}
+}
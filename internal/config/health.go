@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// DefaultHealthTimeout bounds each health request when no timeout is set
+const DefaultHealthTimeout = 2 * time.Second
+
+// HealthConfig controls the health checks run by status
+type HealthConfig struct {
+	// Timeout bounds each health request; zero uses DefaultHealthTimeout
+	Timeout time.Duration
+
+	// Retries is how many more times a failing check is tried
+	Retries int
+
+	// URLs override the health endpoint of a service. An empty URL is
+	// derived from the service's HTTP port.
+	URLs HealthURLs
+}
+
+// HealthURLs holds the health endpoint override of each service
+type HealthURLs struct {
+	IAM           string
+	SecretManager string
+	KMS           string
+}
+
+// validate checks the timeout, retries, and every URL that is set
+func (h *HealthConfig) validate() error {
+	if h.Timeout < 0 {
+		return fmt.Errorf("%w for health-timeout: %s", ErrInvalidValue, h.Timeout)
+	}
+	if h.Retries < 0 {
+		return fmt.Errorf("%w for health-retries: %d", ErrInvalidValue, h.Retries)
+	}
+
+	for _, override := range []struct{ service, url string }{
+		{"iam", h.URLs.IAM},
+		{"secret-manager", h.URLs.SecretManager},
+		{"kms", h.URLs.KMS},
+	} {
+		if override.url == "" {
+			continue
+		}
+		u, err := url.Parse(override.url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w for health-url-%s: %q (must be an http or https URL)", ErrInvalidValue, override.service, override.url)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/semver"
+)
+
+// ImageConfig holds the container image for each service, as a reference
+// with a tag or digest such as ghcr.io/blackwell-systems/gcp-kms-emulator-dual:v0.4.0.
+// An empty reference uses the image named in docker-compose.yml.
+type ImageConfig struct {
+	IAM           string
+	SecretManager string
+	KMS           string
+}
+
+// Services whose image can be overridden, as named in docker-compose.yml
+var imageServices = []string{"iam", "secret-manager", "kms"}
+
+// imageRefPattern matches docker image references: an optional registry
+// host and port, lowercase path components, then an optional tag and
+// digest
+var imageRefPattern = regexp.MustCompile(`^` +
+	`(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)*(?::[0-9]+)?/)?` +
+	`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+	`(?::[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127})?` +
+	`(?:@[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-fA-F0-9]{32,})?` +
+	`$`)
+
+// ValidateImageRef checks that ref is a well-formed image reference
+func ValidateImageRef(ref string) error {
+	if !imageRefPattern.MatchString(ref) {
+		return fmt.Errorf("malformed image reference: %q", ref)
+	}
+	return nil
+}
+
+// Set overrides the image of a service (iam, secret-manager, or kms)
+func (i *ImageConfig) Set(service, ref string) error {
+	switch service {
+	case "iam":
+		i.IAM = ref
+	case "secret-manager":
+		i.SecretManager = ref
+	case "kms":
+		i.KMS = ref
+	default:
+		return fmt.Errorf("unknown service: %s (must be one of %s)", service, strings.Join(imageServices, ", "))
+	}
+	return nil
+}
+
+// SetOverride applies an override of the form service=image, as given to
+// gcp-emulator start --image
+func (i *ImageConfig) SetOverride(override string) error {
+	service, ref, ok := strings.Cut(override, "=")
+	if !ok || ref == "" {
+		return fmt.Errorf("invalid image override: %q (use service=image, e.g. kms=ghcr.io/blackwell-systems/gcp-kms-emulator-dual:v0.4.0)", override)
+	}
+	return i.Set(service, ref)
+}
+
+// validate checks every image reference that is set
+func (i *ImageConfig) validate() error {
+	for _, image := range []struct{ service, ref string }{
+		{"iam", i.IAM},
+		{"secret-manager", i.SecretManager},
+		{"kms", i.KMS},
+	} {
+		if image.ref == "" {
+			continue
+		}
+		if err := ValidateImageRef(image.ref); err != nil {
+			return fmt.Errorf("%w for image-%s: %w", ErrInvalidValue, image.service, err)
+		}
+	}
+	return nil
+}
+
+// UpgradeConfig holds a version constraint for each service's image, such
+// as ~0.3, that 'gcp-emulator upgrade' keeps version tags within. An empty
+// constraint allows any newer release.
+type UpgradeConfig struct {
+	IAM           string
+	SecretManager string
+	KMS           string
+}
+
+// Constraint returns the constraint of service
+func (u UpgradeConfig) Constraint(service string) string {
+	switch service {
+	case "iam":
+		return u.IAM
+	case "secret-manager":
+		return u.SecretManager
+	case "kms":
+		return u.KMS
+	}
+	return ""
+}
+
+// validate checks that every constraint parses
+func (u UpgradeConfig) validate() error {
+	for _, service := range imageServices {
+		if _, err := semver.ParseConstraint(u.Constraint(service)); err != nil {
+			return fmt.Errorf("%w for upgrade.%s: %w", ErrInvalidValue, service, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateImageRef(t *testing.T) {
+	valid := []string{
+		"ghcr.io/blackwell-systems/gcp-kms-emulator-dual:v0.4.0-rc1",
+		"ghcr.io/blackwell-systems/gcp-secret-manager-emulator-dual:v1.2.3",
+		"localhost:5000/kms-emulator",
+		"kms-emulator",
+		"ghcr.io/blackwell-systems/gcp-iam-emulator@sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		"ghcr.io/blackwell-systems/gcp-iam-emulator:v0.8.0@sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+	}
+	for _, ref := range valid {
+		if err := ValidateImageRef(ref); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", ref, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"Bad Image",
+		"ghcr.io/Blackwell/kms",
+		"ghcr.io/blackwell-systems/kms:",
+		"ghcr.io/blackwell-systems/kms:-rc1",
+		"ghcr.io/blackwell-systems/kms@sha256:abc",
+		"ghcr.io//kms",
+	}
+	for _, ref := range invalid {
+		if err := ValidateImageRef(ref); err == nil {
+			t.Errorf("Expected %q to be rejected", ref)
+		}
+	}
+}
+
+func TestImageOverride(t *testing.T) {
+	images := Defaults().Images
+
+	if err := images.SetOverride("kms=ghcr.io/blackwell-systems/gcp-kms-emulator-dual:v0.4.0-rc1"); err != nil {
+		t.Fatalf("SetOverride failed: %v", err)
+	}
+	if images.KMS != "ghcr.io/blackwell-systems/gcp-kms-emulator-dual:v0.4.0-rc1" {
+		t.Errorf("Expected KMS image to be overridden, got %s", images.KMS)
+	}
+	if images.SecretManager != Defaults().Images.SecretManager {
+		t.Errorf("Expected other images to be unchanged, got %s", images.SecretManager)
+	}
+
+	for _, override := range []string{"kms", "kms=", "bigquery=ghcr.io/x/y:1"} {
+		if err := images.SetOverride(override); err == nil {
+			t.Errorf("Expected %q to be rejected", override)
+		}
+	}
+}
+
+func TestValidateImages(t *testing.T) {
+	cfg := Defaults()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected default images to be valid, got %v", err)
+	}
+
+	cfg.Images.SecretManager = ""
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected an unset image to fall back to the compose default, got %v", err)
+	}
+
+	cfg.Images.KMS = "kms emulator"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected a malformed image to fail validation")
+	}
+}
+
+func TestValidateUpgradeConstraints(t *testing.T) {
+	cfg := Defaults()
+	cfg.Upgrade.KMS = "~0.3"
+	cfg.Upgrade.IAM = ">=1.2 <2"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected the constraints to be valid, got %v", err)
+	}
+
+	cfg.Upgrade.SecretManager = "~latest"
+	err := cfg.Validate()
+	if !errors.Is(err, ErrInvalidValue) || !strings.Contains(err.Error(), "upgrade.secret-manager") {
+		t.Errorf("Expected an invalid constraint to name its key, got %v", err)
+	}
+}
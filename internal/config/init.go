@@ -0,0 +1,77 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/spf13/viper"
+)
+
+// initTemplate is the config file written by config init. Only the common
+// keys are listed; the rest keep their defaults until set.
+var initTemplate = template.Must(template.New("config").Parse(`# gcp-emulator configuration
+#
+# Environment variables (GCP_EMULATOR_IAM_MODE, ...) and flags override the
+# values here. Run 'gcp-emulator config list' for every key.
+
+# IAM enforcement: off, permissive (fail-open), or strict (fail-closed)
+iam-mode: {{.IAMMode}}
+
+# Log every authorization decision of the IAM emulator
+trace: {{.Trace}}
+
+# Pull the images before each start
+pull-on-start: {{.PullOnStart}}
+
+# Policy file mounted into the IAM emulator
+policy-file: {{printf "%q" .PolicyFile}}
+
+# Host ports. The IAM emulator also serves its admin and health API on
+# port-iam + 1000.
+port-iam: {{.Ports.IAM}}
+port-secret-manager: {{.Ports.SecretManager}}
+port-kms: {{.Ports.KMS}}
+`))
+
+// InitPath returns where config init writes: the file named by --config or
+// GCP_EMULATOR_CONFIG, or config.yaml in ConfigDir
+func InitPath() (string, error) {
+	if file := viper.GetString("config"); file != "" {
+		return file, nil
+	}
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.yaml"), nil
+}
+
+// WriteInitFile writes cfg to path as a commented config file. An existing
+// file is only replaced if force is set.
+func WriteInitFile(path string, cfg *Config, force bool) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	if !force {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("config file %s already exists (use --force to overwrite)", path)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := initTemplate.Execute(&buf, cfg); err != nil {
+		return fmt.Errorf("failed to render config file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestWriteInitFile(t *testing.T) {
+	home := withTestHome(t)
+
+	path, err := InitPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, ".config", "gcp-emulator", "config.yaml"); path != want {
+		t.Errorf("Expected config init to write %s, got %s", want, path)
+	}
+
+	cfg := Defaults()
+	cfg.IAMMode = "strict"
+	cfg.Ports.KMS = 19091
+	if err := WriteInitFile(path, cfg, false); err != nil {
+		t.Fatalf("WriteInitFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "# IAM enforcement") {
+		t.Errorf("Expected the config file to be commented, got:\n%s", data)
+	}
+
+	viper.Reset()
+	if err := Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.IAMMode != "strict" || loaded.Ports.KMS != 19091 || loaded.PolicyFile != cfg.PolicyFile {
+		t.Errorf("Expected the written values to load back, got %+v", loaded)
+	}
+
+	if err := WriteInitFile(path, Defaults(), false); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("Expected an existing file to be refused without force, got %v", err)
+	}
+	if err := WriteInitFile(path, Defaults(), true); err != nil {
+		t.Errorf("Expected force to overwrite, got %v", err)
+	}
+
+	cfg.IAMMode = "lenient"
+	if err := WriteInitFile(filepath.Join(home, "other.yaml"), cfg, false); err == nil {
+		t.Error("Expected an invalid config not to be written")
+	}
+}
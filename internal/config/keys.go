@@ -0,0 +1,365 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Key is a config file key that can be read and changed from the CLI
+type Key struct {
+	Name        string
+	Description string
+
+	// value returns the key's value in cfg
+	value func(cfg *Config) any
+
+	// set parses s into cfg
+	set func(cfg *Config, s string) error
+}
+
+// envPrefix is prepended to a key's environment variable
+const envPrefix = "GCP_EMULATOR"
+
+// envKeyReplacer turns key names into environment variable names, which
+// can't contain - or .
+var envKeyReplacer = strings.NewReplacer("-", "_", ".", "_")
+
+// keys lists every config file key, in display order, ending with the
+// resources.* keys
+var keys = append([]Key{
+	{
+		Name:        "iam-mode",
+		Description: "IAM mode (off|permissive|strict)",
+		value:       func(c *Config) any { return c.IAMMode },
+		set:         func(c *Config, s string) error { c.IAMMode = s; return nil },
+	},
+	{
+		Name:        "trace",
+		Description: "Record authorization decisions (true|false)",
+		value:       func(c *Config) any { return c.Trace },
+		set:         func(c *Config, s string) error { return parseBool(s, &c.Trace) },
+	},
+	{
+		Name:        "pull-on-start",
+		Description: "Pull images before starting (true|false)",
+		value:       func(c *Config) any { return c.PullOnStart },
+		set:         func(c *Config, s string) error { return parseBool(s, &c.PullOnStart) },
+	},
+	{
+		Name:        "pull-policy",
+		Description: "When start pulls images (always|missing|never; default always with pull-on-start, otherwise missing)",
+		value:       func(c *Config) any { return c.PullPolicy },
+		set:         func(c *Config, s string) error { c.PullPolicy = s; return nil },
+	},
+	{
+		Name:        "ordered-start",
+		Description: "Start IAM before the data planes, once it is healthy; ignored in off mode (true|false)",
+		value:       func(c *Config) any { return c.OrderedStart },
+		set:         func(c *Config, s string) error { return parseBool(s, &c.OrderedStart) },
+	},
+	{
+		Name:        "policy-file",
+		Description: "Path to policy.yaml",
+		value:       func(c *Config) any { return c.PolicyFile },
+		set:         func(c *Config, s string) error { c.PolicyFile = s; return nil },
+	},
+	{
+		Name:        "seed",
+		Description: "Fixtures file to seed into the stack after a healthy start",
+		value:       func(c *Config) any { return c.SeedFile },
+		set:         func(c *Config, s string) error { c.SeedFile = s; return nil },
+	},
+	{
+		Name:        "project",
+		Description: "Default project of client libraries, exported as GOOGLE_CLOUD_PROJECT by env",
+		value:       func(c *Config) any { return c.Project },
+		set:         func(c *Config, s string) error { c.Project = s; return nil },
+	},
+	{
+		Name:        "run-teardown",
+		Description: "When run stops the stack after its command (always|started|on-success|never; default started)",
+		value:       func(c *Config) any { return c.RunTeardown },
+		set:         func(c *Config, s string) error { c.RunTeardown = s; return nil },
+	},
+	{
+		Name:        "host",
+		Description: "Host the stack's ports are reached on (default localhost)",
+		value:       func(c *Config) any { return c.Docker.Host },
+		set:         func(c *Config, s string) error { c.Docker.Host = s; return nil },
+	},
+	{
+		Name:        "docker-context",
+		Description: "Docker context to run compose against (default the current context or DOCKER_HOST)",
+		value:       func(c *Config) any { return c.Docker.Context },
+		set:         func(c *Config, s string) error { c.Docker.Context = s; return nil },
+	},
+	{
+		Name:        "container-runtime",
+		Description: "Container runtime to run the stack with (auto|docker|podman; auto prefers docker)",
+		value:       func(c *Config) any { return c.Docker.Runtime },
+		set:         func(c *Config, s string) error { c.Docker.Runtime = s; return nil },
+	},
+	{
+		Name:        "compose-project",
+		Description: "Docker compose project name; -<profile> is appended with a profile active",
+		value:       func(c *Config) any { return c.Docker.Project },
+		set:         func(c *Config, s string) error { c.Docker.Project = s; return nil },
+	},
+	{
+		Name:        "network-name",
+		Description: "Docker network of the stack (default <project>_default)",
+		value:       func(c *Config) any { return c.Docker.Network },
+		set:         func(c *Config, s string) error { c.Docker.Network = s; return nil },
+	},
+	{
+		Name:        "compose-file",
+		Description: "Compose file to use instead of the one generated from the config",
+		value:       func(c *Config) any { return c.Docker.ComposeFile },
+		set:         func(c *Config, s string) error { c.Docker.ComposeFile = s; return nil },
+	},
+	{
+		Name:        "compose-overrides",
+		Description: "Compose files merged over the stack's compose file, in order (comma-separated)",
+		value:       func(c *Config) any { return c.Docker.ComposeOverrides },
+		set:         func(c *Config, s string) error { c.Docker.ComposeOverrides = splitList(s); return nil },
+	},
+	{
+		Name:        "port-iam",
+		Description: "IAM emulator port",
+		value:       func(c *Config) any { return c.Ports.IAM },
+		set:         func(c *Config, s string) error { return parsePort(s, &c.Ports.IAM) },
+	},
+	{
+		Name:        "port-secret-manager",
+		Description: "Secret Manager gRPC port",
+		value:       func(c *Config) any { return c.Ports.SecretManager },
+		set:         func(c *Config, s string) error { return parsePort(s, &c.Ports.SecretManager) },
+	},
+	{
+		Name:        "port-kms",
+		Description: "KMS gRPC port",
+		value:       func(c *Config) any { return c.Ports.KMS },
+		set:         func(c *Config, s string) error { return parsePort(s, &c.Ports.KMS) },
+	},
+	{
+		Name:        "port-secret-manager-http",
+		Description: "Secret Manager HTTP port, also used for health checks",
+		value:       func(c *Config) any { return c.Ports.SecretManagerHTTP },
+		set:         func(c *Config, s string) error { return parsePort(s, &c.Ports.SecretManagerHTTP) },
+	},
+	{
+		Name:        "port-kms-http",
+		Description: "KMS HTTP port, also used for health checks",
+		value:       func(c *Config) any { return c.Ports.KMSHTTP },
+		set:         func(c *Config, s string) error { return parsePort(s, &c.Ports.KMSHTTP) },
+	},
+	{
+		Name:        "ports.auto",
+		Description: "Pick free host ports on start instead of the configured ones (true|false)",
+		value:       func(c *Config) any { return c.Ports.Auto },
+		set:         func(c *Config, s string) error { return parseBool(s, &c.Ports.Auto) },
+	},
+	{
+		Name:        "image-iam",
+		Description: "IAM emulator image, with tag or digest",
+		value:       func(c *Config) any { return c.Images.IAM },
+		set:         func(c *Config, s string) error { c.Images.IAM = s; return nil },
+	},
+	{
+		Name:        "image-secret-manager",
+		Description: "Secret Manager emulator image, with tag or digest",
+		value:       func(c *Config) any { return c.Images.SecretManager },
+		set:         func(c *Config, s string) error { c.Images.SecretManager = s; return nil },
+	},
+	{
+		Name:        "image-kms",
+		Description: "KMS emulator image, with tag or digest",
+		value:       func(c *Config) any { return c.Images.KMS },
+		set:         func(c *Config, s string) error { c.Images.KMS = s; return nil },
+	},
+	{
+		Name:        "upgrade.iam",
+		Description: "Versions upgrade may move image-iam to, e.g. ~0.3 (default any newer release)",
+		value:       func(c *Config) any { return c.Upgrade.IAM },
+		set:         func(c *Config, s string) error { c.Upgrade.IAM = s; return nil },
+	},
+	{
+		Name:        "upgrade.secret-manager",
+		Description: "Versions upgrade may move image-secret-manager to, e.g. ^1.2 (default any newer release)",
+		value:       func(c *Config) any { return c.Upgrade.SecretManager },
+		set:         func(c *Config, s string) error { c.Upgrade.SecretManager = s; return nil },
+	},
+	{
+		Name:        "upgrade.kms",
+		Description: "Versions upgrade may move image-kms to, e.g. ~0.3 (default any newer release)",
+		value:       func(c *Config) any { return c.Upgrade.KMS },
+		set:         func(c *Config, s string) error { c.Upgrade.KMS = s; return nil },
+	},
+	{
+		Name:        "health-timeout",
+		Description: "Timeout of each health check request (e.g. 2s, 10s)",
+		value:       func(c *Config) any { return c.Health.Timeout.String() },
+		set:         func(c *Config, s string) error { return parseDuration(s, &c.Health.Timeout) },
+	},
+	{
+		Name:        "health-retries",
+		Description: "Times to retry a failing health check",
+		value:       func(c *Config) any { return c.Health.Retries },
+		set:         func(c *Config, s string) error { return parseInt(s, &c.Health.Retries) },
+	},
+	{
+		Name:        "health-url-iam",
+		Description: "IAM health URL (default derived from port-iam)",
+		value:       func(c *Config) any { return c.Health.URLs.IAM },
+		set:         func(c *Config, s string) error { c.Health.URLs.IAM = s; return nil },
+	},
+	{
+		Name:        "health-url-secret-manager",
+		Description: "Secret Manager health URL (default derived from port-secret-manager-http)",
+		value:       func(c *Config) any { return c.Health.URLs.SecretManager },
+		set:         func(c *Config, s string) error { c.Health.URLs.SecretManager = s; return nil },
+	},
+	{
+		Name:        "health-url-kms",
+		Description: "KMS health URL (default derived from port-kms-http)",
+		value:       func(c *Config) any { return c.Health.URLs.KMS },
+		set:         func(c *Config, s string) error { c.Health.URLs.KMS = s; return nil },
+	},
+	{
+		Name:        "lint.disable",
+		Description: "Policy lint rules to skip (comma-separated, e.g. GCP001,GCP004)",
+		value:       func(c *Config) any { return c.Lint.Disable },
+		set:         func(c *Config, s string) error { c.Lint.Disable = splitList(s); return nil },
+	},
+}, resourceKeys()...)
+
+// Keys returns every config file key, in display order
+func Keys() []Key {
+	return keys
+}
+
+// LookupKey returns the named key, or an error listing the valid keys
+func LookupKey(name string) (Key, error) {
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key.Name == name {
+			return key, nil
+		}
+		names = append(names, key.Name)
+	}
+	return Key{}, fmt.Errorf("unknown config key: %s (valid keys: %s)", name, strings.Join(names, ", "))
+}
+
+// EnvVar returns the environment variable that sets the key, such as
+// GCP_EMULATOR_IAM_MODE for iam-mode
+func (k Key) EnvVar() string {
+	return envPrefix + "_" + strings.ToUpper(envKeyReplacer.Replace(k.Name))
+}
+
+// Get formats the key's value in cfg as it is written on the command line
+func (k Key) Get(cfg *Config) string {
+	switch v := k.value(cfg).(type) {
+	case []string:
+		return strings.Join(v, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Set parses value into cfg. It checks only the value's type; call
+// Config.Validate for the rest.
+func (k Key) Set(cfg *Config, value string) error {
+	if err := k.set(cfg, value); err != nil {
+		return fmt.Errorf("%w for %s: %w", ErrInvalidValue, k.Name, err)
+	}
+	return nil
+}
+
+// Unset restores the key's default value in cfg
+func (k Key) Unset(cfg *Config) {
+	// Defaults always parse
+	_ = k.set(cfg, k.Get(Defaults()))
+}
+
+// Defaults returns the configuration used when no other source sets a value
+func Defaults() *Config {
+	return &Config{
+		IAMMode:      "permissive",
+		Trace:        false,
+		PullOnStart:  false,
+		OrderedStart: true,
+		PolicyFile:   "./policy.yaml",
+		RunTeardown:  TeardownStarted,
+		Docker: DockerConfig{
+			Host:    "localhost",
+			Runtime: RuntimeAuto,
+			Project: DefaultProject,
+		},
+		Ports: PortConfig{
+			IAM:           8080,
+			SecretManager: 9090,
+			KMS:           9091,
+
+			SecretManagerHTTP: 8081,
+			KMSHTTP:           8082,
+		},
+		Images: ImageConfig{
+			IAM:           "ghcr.io/blackwell-systems/gcp-iam-emulator:latest",
+			SecretManager: "ghcr.io/blackwell-systems/gcp-secret-manager-emulator-dual:latest",
+			KMS:           "ghcr.io/blackwell-systems/gcp-kms-emulator-dual:latest",
+		},
+		Health: HealthConfig{
+			Timeout: DefaultHealthTimeout,
+		},
+	}
+}
+
+func parseBool(s string, dst *bool) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("%q is not true or false", s)
+	}
+	*dst = v
+	return nil
+}
+
+func parseInt(s string, dst *int) error {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", s)
+	}
+	*dst = v
+	return nil
+}
+
+func parseDuration(s string, dst *time.Duration) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("%q is not a duration (e.g. 2s, 500ms)", s)
+	}
+	*dst = v
+	return nil
+}
+
+// splitList parses a comma-separated list, as list keys are given on the
+// command line
+func splitList(s string) []string {
+	var values []string
+	for _, value := range strings.Split(s, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func parsePort(s string, dst *int) error {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("%q is not a port number", s)
+	}
+	*dst = v
+	return nil
+}
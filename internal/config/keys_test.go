@@ -0,0 +1,180 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestLookupKey(t *testing.T) {
+	if _, err := LookupKey("iam-mode"); err != nil {
+		t.Errorf("Expected iam-mode to be a key, got %v", err)
+	}
+
+	_, err := LookupKey("iam_mode")
+	if err == nil {
+		t.Fatal("Expected an unknown key to fail")
+	}
+	for _, key := range Keys() {
+		if !strings.Contains(err.Error(), key.Name) {
+			t.Errorf("Expected the error to list %s, got %v", key.Name, err)
+		}
+	}
+}
+
+func TestKeySet(t *testing.T) {
+	tests := []struct {
+		key     string
+		value   string
+		get     string
+		wantErr bool
+		invalid bool
+	}{
+		{key: "iam-mode", value: "strict", get: "strict"},
+		{key: "iam-mode", value: "bogus", get: "bogus", invalid: true},
+		{key: "trace", value: "true", get: "true"},
+		{key: "trace", value: "yes", wantErr: true},
+		{key: "port-iam", value: "18080", get: "18080"},
+		{key: "port-iam", value: "99999", get: "99999", invalid: true},
+		{key: "port-kms", value: "http", wantErr: true},
+		{key: "lint.disable", value: "GCP001, GCP004", get: "GCP001,GCP004"},
+		{key: "compose-overrides", value: "ci/metadata.yaml,volumes.yaml", get: "ci/metadata.yaml,volumes.yaml"},
+		{key: "health-timeout", value: "10s", get: "10s"},
+		{key: "health-timeout", value: "10", wantErr: true},
+		{key: "health-retries", value: "3", get: "3"},
+		{key: "health-retries", value: "-1", get: "-1", invalid: true},
+		{key: "health-url-kms", value: "http://vm:8082/health", get: "http://vm:8082/health"},
+		{key: "health-url-kms", value: "vm:8082", get: "vm:8082", invalid: true},
+		{key: "port-kms-http", value: "70000", get: "70000", invalid: true},
+		{key: "compose-project", value: "team-a_emulators", get: "team-a_emulators"},
+		{key: "compose-project", value: "Team A", get: "Team A", invalid: true},
+		{key: "network-name", value: "shared.net", get: "shared.net"},
+		{key: "network-name", value: "-net", get: "-net", invalid: true},
+		{key: "resources.memory", value: "512m", get: "512m"},
+		{key: "resources.kms.cpus", value: "0.5", get: "0.5"},
+		{key: "resources.kms.cpus", value: "half", get: "half", invalid: true},
+		{key: "resources.iam.restart", value: "on-failure:3", get: "on-failure:3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			key, err := LookupKey(tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := Defaults()
+			err = key.Set(cfg, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got := key.Get(cfg); got != tt.get {
+				t.Errorf("Expected %s, got %s", tt.get, got)
+			}
+			if err := cfg.Validate(); (err != nil) != tt.invalid {
+				t.Errorf("Validate() error = %v, invalid %v", err, tt.invalid)
+			}
+		})
+	}
+}
+
+func TestKeyUnset(t *testing.T) {
+	cfg := Defaults()
+	cfg.PullOnStart = true
+	cfg.Ports.IAM = 18080
+
+	for _, name := range []string{"pull-on-start", "port-iam"} {
+		key, err := LookupKey(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key.Unset(cfg)
+	}
+
+	if cfg.PullOnStart || cfg.Ports.IAM != 8080 {
+		t.Errorf("Expected defaults after unset, got %+v", cfg)
+	}
+}
+
+func TestSaveKeepsOtherKeys(t *testing.T) {
+	home := withTestHome(t)
+
+	path := filepath.Join(home, "config.yaml")
+	if err := os.WriteFile(path, []byte("profile: staging\niam-mode: off\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	if err := Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	cfg := Defaults()
+	cfg.IAMMode = "strict"
+	if err := Save(cfg); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if got := v.GetString("iam-mode"); got != "strict" {
+		t.Errorf("Expected iam-mode strict, got %s", got)
+	}
+	if got := v.GetString("profile"); got != "staging" {
+		t.Errorf("Expected the active profile to be kept, got %q", got)
+	}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	withTestHome(t)
+
+	t.Setenv("GCP_EMULATOR_IAM_MODE", "strict")
+	t.Setenv("GCP_EMULATOR_PULL_ON_START", "true")
+	t.Setenv("GCP_EMULATOR_PORT_SECRET_MANAGER", "19090")
+	t.Setenv("GCP_EMULATOR_LINT_DISABLE", "GCP001, GCP004")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.IAMMode != "strict" {
+		t.Errorf("Expected iam-mode strict from the environment, got %s", cfg.IAMMode)
+	}
+	if !cfg.PullOnStart {
+		t.Error("Expected pull-on-start true from the environment")
+	}
+	if cfg.Ports.SecretManager != 19090 {
+		t.Errorf("Expected Secret Manager port 19090 from the environment, got %d", cfg.Ports.SecretManager)
+	}
+	if !slices.Equal(cfg.Lint.Disable, []string{"GCP001", "GCP004"}) {
+		t.Errorf("Expected lint.disable [GCP001 GCP004] from the environment, got %v", cfg.Lint.Disable)
+	}
+}
+
+func TestKeyEnvVar(t *testing.T) {
+	for name, want := range map[string]string{
+		"iam-mode":             "GCP_EMULATOR_IAM_MODE",
+		"port-secret-manager":  "GCP_EMULATOR_PORT_SECRET_MANAGER",
+		"lint.disable":         "GCP_EMULATOR_LINT_DISABLE",
+		"image-secret-manager": "GCP_EMULATOR_IMAGE_SECRET_MANAGER",
+		"resources.kms.memory": "GCP_EMULATOR_RESOURCES_KMS_MEMORY",
+	} {
+		key, err := LookupKey(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := key.EnvVar(); got != want {
+			t.Errorf("Expected %s for %s, got %s", want, name, got)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+)
+
+// LocalFileName is the per-project config file, found in the current
+// directory or any parent and merged over the global config file
+const LocalFileName = ".gcp-emulator.yaml"
+
+// FindLocalFile returns the LocalFileName closest to dir, searching dir
+// and then each parent, or "" if there is none
+func FindLocalFile(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+
+	for {
+		path := filepath.Join(dir, LocalFileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// readLayer reads a config file into its own viper, so the keys it sets
+// are known apart from the other sources
+func readLayer(path string) (*viper.Viper, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("config file %s: %w", path, ErrConfigNotFound)
+		}
+		return nil, readError(err)
+	}
+	return v, nil
+}
+
+// mergeLocal finds the local config file and merges it over the global
+// config file and profile. A relative policy-file or seed in it is
+// resolved against the file's directory, so it works from any
+// subdirectory.
+func mergeLocal() error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to find the current directory: %w", err)
+	}
+	path, err := FindLocalFile(cwd)
+	if err != nil || path == "" {
+		return err
+	}
+
+	v, err := readLayer(path)
+	if err != nil {
+		return err
+	}
+	for _, key := range []string{"policy-file", "seed"} {
+		if file := v.GetString(key); file != "" && !filepath.IsAbs(file) {
+			v.Set(key, filepath.Join(filepath.Dir(path), file))
+		}
+	}
+
+	if err := viper.MergeConfigMap(v.AllSettings()); err != nil {
+		return fmt.Errorf("failed to merge %s: %w", path, err)
+	}
+	layers.local = &layer{path: path, values: v}
+	slog.Debug("local config merged", "path", path)
+	return nil
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+func TestFindLocalFile(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "services", "api")
+	writeFile(t, filepath.Join(root, LocalFileName), "iam-mode: strict\n")
+	writeFile(t, filepath.Join(sub, "main.go"), "package main\n")
+
+	if got, err := FindLocalFile(sub); err != nil || got != filepath.Join(root, LocalFileName) {
+		t.Errorf("Expected the local file in a parent directory, got %q (%v)", got, err)
+	}
+	if got, _ := FindLocalFile(t.TempDir()); got != "" {
+		t.Errorf("Expected no local file, got %s", got)
+	}
+}
+
+func TestLocalFilePrecedence(t *testing.T) {
+	home := withTestHome(t)
+
+	global := filepath.Join(home, ".config", "gcp-emulator", "config.yaml")
+	writeFile(t, global, "iam-mode: off\ntrace: true\nport-kms: 19091\n")
+	repo := filepath.Join(home, "repo")
+	local := filepath.Join(repo, LocalFileName)
+	writeFile(t, local, "iam-mode: strict\npolicy-file: iam/policy.yaml\nseed: fixtures.yaml\nport-kms: 29091\n")
+	sub := filepath.Join(repo, "cmd")
+	writeFile(t, filepath.Join(sub, "main.go"), "package main\n")
+	t.Chdir(sub)
+
+	t.Setenv("GCP_EMULATOR_PORT_KMS", "39091")
+	viper.Reset()
+	if err := Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.IAMMode != "strict" {
+		t.Errorf("Expected the local file over the global file, got iam-mode %s", cfg.IAMMode)
+	}
+	if !cfg.Trace {
+		t.Error("Expected values the local file doesn't set to come from the global file")
+	}
+	if cfg.Ports.KMS != 39091 {
+		t.Errorf("Expected the environment over the local file, got port-kms %d", cfg.Ports.KMS)
+	}
+	if want := filepath.Join(repo, "iam", "policy.yaml"); cfg.PolicyFile != want {
+		t.Errorf("Expected policy-file relative to the local file, got %s", cfg.PolicyFile)
+	}
+	if want := filepath.Join(repo, "fixtures.yaml"); cfg.SeedFile != want {
+		t.Errorf("Expected seed relative to the local file, got %s", cfg.SeedFile)
+	}
+
+	for name, want := range map[string]Source{
+		"iam-mode":      {Kind: SourceLocal, Name: local},
+		"trace":         {Kind: SourceFile, Name: global},
+		"port-kms":      {Kind: SourceEnv, Name: "GCP_EMULATOR_PORT_KMS"},
+		"pull-on-start": {Kind: SourceDefault},
+	} {
+		if got, err := SourceOf(name); err != nil || got != want {
+			t.Errorf("SourceOf(%s) = %v (%v), want %v", name, got, err, want)
+		}
+	}
+
+	// Saving one key leaves the local values out of the global file
+	cfg.Trace = false
+	key, _ := LookupKey("trace")
+	if err := SaveKey(cfg, key); err != nil {
+		t.Fatalf("SaveKey failed: %v", err)
+	}
+	v := viper.New()
+	v.SetConfigFile(global)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if v.GetString("iam-mode") != "off" || v.GetBool("trace") {
+		t.Errorf("Expected only trace to change in the global file, got %v", v.AllSettings())
+	}
+}
+
+func TestFlagSource(t *testing.T) {
+	withTestHome(t)
+
+	flags := pflag.NewFlagSet("start", pflag.ContinueOnError)
+	flags.String("mode", "", "")
+	if err := BindFlag("iam-mode", flags.Lookup("mode")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { delete(boundFlags, "iam-mode") })
+	t.Setenv("GCP_EMULATOR_IAM_MODE", "off")
+
+	if got, _ := SourceOf("iam-mode"); got.Kind != SourceEnv {
+		t.Errorf("Expected an unset flag not to be the source, got %v", got)
+	}
+	if err := flags.Parse([]string{"--mode", "strict"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := SourceOf("iam-mode"); got != (Source{Kind: SourceFlag, Name: "--mode"}) {
+		t.Errorf("Expected the flag to be the source, got %v", got)
+	}
+	if cfg, _ := Load(); cfg.IAMMode != "strict" {
+		t.Errorf("Expected the flag over the environment, got iam-mode %s", cfg.IAMMode)
+	}
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// override is a value given with --set
+type override struct {
+	key Key
+
+	// value is the parsed value, typed as the key's default is
+	value any
+
+	// pair is the key=value as given
+	pair string
+}
+
+// overrides are the values given with --set, applied by Init over every
+// other source
+var overrides []override
+
+// SetOverrides parses key=value pairs, as given with --set, to apply over
+// every other source when Init runs. Each key must be known and each
+// value must parse as the key's type; the first that doesn't fails.
+func SetOverrides(pairs []string) error {
+	parsed := make([]override, 0, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("%w for --set: %q (use key=value)", ErrInvalidValue, pair)
+		}
+		key, err := LookupKey(name)
+		if err != nil {
+			return fmt.Errorf("%w for --set: %w", ErrInvalidValue, err)
+		}
+
+		cfg := Defaults()
+		if err := key.Set(cfg, value); err != nil {
+			return err
+		}
+		parsed = append(parsed, override{key: key, value: key.value(cfg), pair: pair})
+	}
+	overrides = parsed
+	return nil
+}
+
+// applyOverrides sets the --set values in viper, where they win over
+// flags, the environment, and the config files. A key given more than
+// once takes the last value.
+func applyOverrides() {
+	for _, o := range overrides {
+		viper.Set(o.key.Name, o.value)
+		slog.Debug("config override", "key", o.key.Name, "value", o.value)
+	}
+}
+
+// overrideOf returns the last --set of the named key
+func overrideOf(name string) (override, bool) {
+	for i := len(overrides) - 1; i >= 0; i-- {
+		if overrides[i].key.Name == name {
+			return overrides[i], true
+		}
+	}
+	return override{}, false
+}
+
+// overridePairs lists the --set values as given, for Display
+func overridePairs() string {
+	pairs := make([]string, 0, len(overrides))
+	for _, o := range overrides {
+		pairs = append(pairs, o.pair)
+	}
+	return strings.Join(pairs, ", ")
+}
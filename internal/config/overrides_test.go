@@ -0,0 +1,88 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+func TestSetOverrides(t *testing.T) {
+	home := withTestHome(t)
+	writeFile(t, filepath.Join(home, LocalFileName), "port-kms: 19090\ntrace: false\n")
+	t.Setenv("GCP_EMULATOR_IAM_MODE", "off")
+
+	flags := pflag.NewFlagSet("start", pflag.ContinueOnError)
+	flags.String("host", "", "")
+	if err := flags.Parse([]string{"--host", "flag-host"}); err != nil {
+		t.Fatal(err)
+	}
+
+	viper.Reset()
+	if err := BindFlag("host", flags.Lookup("host")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { delete(boundFlags, "host") })
+	if err := SetOverrides([]string{
+		"iam-mode=strict",
+		"port-kms=19091",
+		"trace=true",
+		"host=set-host",
+		"lint.disable=a,b",
+		"port-kms=19092",
+	}); err != nil {
+		t.Fatalf("SetOverrides failed: %v", err)
+	}
+	if err := Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.IAMMode != "strict" {
+		t.Errorf("Expected --set to win over the environment, got iam-mode %s", cfg.IAMMode)
+	}
+	if cfg.Ports.KMS != 19092 {
+		t.Errorf("Expected the last --set of port-kms to win over the local file, got %d", cfg.Ports.KMS)
+	}
+	if !cfg.Trace {
+		t.Error("Expected --set trace=true to be read as a bool")
+	}
+	if cfg.Docker.Host != "set-host" {
+		t.Errorf("Expected --set to win over the flag, got host %s", cfg.Docker.Host)
+	}
+	if got := strings.Join(cfg.Lint.Disable, ","); got != "a,b" {
+		t.Errorf("Expected lint.disable [a b], got %v", cfg.Lint.Disable)
+	}
+	if source, _ := SourceOf("port-kms"); source != (Source{Kind: SourceSet, Name: "port-kms=19092"}) {
+		t.Errorf("Expected --set as the source, got %v", source)
+	}
+}
+
+func TestSetOverridesErrors(t *testing.T) {
+	withTestHome(t)
+
+	for _, tt := range []struct {
+		pair string
+		want string
+	}{
+		{"iam-mode", "use key=value"},
+		{"=strict", "use key=value"},
+		{"no-such-key=1", "valid keys: iam-mode"},
+		{"port-kms=abc", "port-kms"},
+		{"trace=maybe", "trace"},
+	} {
+		err := SetOverrides([]string{"trace=true", tt.pair})
+		if !errors.Is(err, ErrInvalidValue) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("SetOverrides(%q) = %v, want ErrInvalidValue mentioning %q", tt.pair, err, tt.want)
+		}
+	}
+	if len(overrides) != 0 {
+		t.Errorf("Expected no overrides kept after a failure, got %d", len(overrides))
+	}
+}
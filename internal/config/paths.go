@@ -0,0 +1,164 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// appDir names the gcp-emulator directory under the user's config and
+// state directories
+const appDir = "gcp-emulator"
+
+// ConfigDir returns the directory holding the config file and profiles:
+// $XDG_CONFIG_HOME/gcp-emulator when set, otherwise gcp-emulator in the
+// platform's user config directory (~/.config on Linux, ~/Library/Application
+// Support on macOS, %AppData% on Windows)
+func ConfigDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, appDir), nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find config directory: %w", err)
+	}
+	return filepath.Join(dir, appDir), nil
+}
+
+// StateDir returns the directory holding runtime state such as the
+// recorded emulator policy versions: $XDG_STATE_HOME/gcp-emulator when set,
+// otherwise ~/.local/state/gcp-emulator. macOS and Windows have no state
+// directory, so ConfigDir is used there.
+func StateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, appDir), nil
+	}
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		return ConfigDir()
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", appDir), nil
+}
+
+// LegacyDir returns ~/.gcp-emulator, which held the config file, profiles,
+// and state before ConfigDir and StateDir. It is still read when the new
+// locations have nothing; 'config migrate-paths' moves its contents.
+func LegacyDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(home, ".gcp-emulator"), nil
+}
+
+// StatePath returns the runtime state file called name: in StateDir, or
+// in LegacyDir if it exists only there
+func StatePath(name string) (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return withLegacy(filepath.Join(dir, name), name)
+}
+
+// withLegacy returns the path name would have in LegacyDir if only that
+// exists, otherwise path
+func withLegacy(path, name string) (string, error) {
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	legacy, err := LegacyDir()
+	if err != nil {
+		return "", err
+	}
+	legacy = filepath.Join(legacy, name)
+	if _, err := os.Stat(legacy); err == nil {
+		return legacy, nil
+	}
+	return path, nil
+}
+
+// PathMove is one file moved, or to be moved, by MigratePaths
+type PathMove struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	// Skipped explains why the file was left in place, or is "" if it was
+	// moved
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// MigratePaths moves the config file, profiles, and state out of LegacyDir
+// into ConfigDir and StateDir. Files that already exist at the new location
+// are left in place rather than overwritten. With dryRun, it only reports
+// what would move. LegacyDir is removed once it is empty.
+func MigratePaths(dryRun bool) ([]PathMove, error) {
+	legacy, err := LegacyDir()
+	if err != nil {
+		return nil, err
+	}
+	configDir, err := ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	stateDir, err := StateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var moves []PathMove
+	plan := func(name, dir string) {
+		moves = append(moves, PathMove{
+			From: filepath.Join(legacy, name),
+			To:   filepath.Join(dir, name),
+		})
+	}
+
+	for _, file := range []struct{ name, dir string }{
+		{"config.yaml", configDir},
+		{"state.json", stateDir},
+	} {
+		if _, err := os.Stat(filepath.Join(legacy, file.name)); err == nil {
+			plan(file.name, file.dir)
+		}
+	}
+
+	profiles, err := os.ReadDir(filepath.Join(legacy, "profiles"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read legacy profiles: %w", err)
+	}
+	for _, entry := range profiles {
+		if !entry.IsDir() {
+			plan(filepath.Join("profiles", entry.Name()), configDir)
+		}
+	}
+
+	for i := range moves {
+		move := &moves[i]
+		if _, err := os.Stat(move.To); err == nil {
+			move.Skipped = "already exists"
+			continue
+		}
+		if dryRun {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(move.To), 0755); err != nil {
+			return moves[:i], fmt.Errorf("failed to create %s: %w", filepath.Dir(move.To), err)
+		}
+		if err := os.Rename(move.From, move.To); err != nil {
+			return moves[:i], fmt.Errorf("failed to move %s: %w", move.From, err)
+		}
+	}
+
+	if !dryRun {
+		// Remove fails, leaving the directories, while anything is left in them
+		_ = os.Remove(filepath.Join(legacy, "profiles"))
+		_ = os.Remove(legacy)
+	}
+	return moves, nil
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestXDGDirs(t *testing.T) {
+	home := withTestHome(t)
+
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(home, "xdg-state"))
+	if dir, _ := ConfigDir(); dir != filepath.Join(home, "xdg-config", "gcp-emulator") {
+		t.Errorf("Expected ConfigDir under XDG_CONFIG_HOME, got %s", dir)
+	}
+	if dir, _ := StateDir(); dir != filepath.Join(home, "xdg-state", "gcp-emulator") {
+		t.Errorf("Expected StateDir under XDG_STATE_HOME, got %s", dir)
+	}
+
+	// Relative XDG paths are invalid and ignored
+	t.Setenv("XDG_STATE_HOME", "state")
+	if runtime.GOOS == "linux" {
+		if dir, _ := StateDir(); dir != filepath.Join(home, ".local", "state", "gcp-emulator") {
+			t.Errorf("Expected the default state directory, got %s", dir)
+		}
+	}
+}
+
+func TestLegacyConfigDir(t *testing.T) {
+	home := withTestHome(t)
+
+	legacy := filepath.Join(home, ".gcp-emulator")
+	writeFile(t, filepath.Join(legacy, "config.yaml"), "iam-mode: strict\n")
+	writeFile(t, filepath.Join(legacy, "profiles", "ci.yaml"), "port-iam: 18080\n")
+	writeFile(t, filepath.Join(legacy, "state.json"), "{}\n")
+
+	viper.Reset()
+	if err := Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if got := viper.ConfigFileUsed(); got != filepath.Join(legacy, "config.yaml") {
+		t.Errorf("Expected the legacy config file to be read, got %q", got)
+	}
+	if names, _ := ListProfiles(); len(names) != 1 || names[0] != "ci" {
+		t.Errorf("Expected legacy profiles to be listed, got %v", names)
+	}
+	if path, _ := StatePath("state.json"); path != filepath.Join(legacy, "state.json") {
+		t.Errorf("Expected the legacy state file, got %s", path)
+	}
+
+	// Saving keeps writing to the file that was loaded
+	cfg := Defaults()
+	if err := Save(cfg); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".config", "gcp-emulator", "config.yaml")); err == nil {
+		t.Error("Expected Save not to create a second config file")
+	}
+}
+
+func TestSaveNewConfigFile(t *testing.T) {
+	home := withTestHome(t)
+
+	if err := Save(Defaults()); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".config", "gcp-emulator", "config.yaml")); err != nil {
+		t.Errorf("Expected a new config file in the config directory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, "config.yaml")); err == nil {
+		t.Error("Expected Save not to write to the current directory")
+	}
+}
+
+func TestMigratePaths(t *testing.T) {
+	home := withTestHome(t)
+
+	legacy := filepath.Join(home, ".gcp-emulator")
+	configDir := filepath.Join(home, ".config", "gcp-emulator")
+	writeFile(t, filepath.Join(legacy, "config.yaml"), "iam-mode: strict\n")
+	writeFile(t, filepath.Join(legacy, "profiles", "ci.yaml"), "port-iam: 18080\n")
+	writeFile(t, filepath.Join(legacy, "profiles", "dev.yaml"), "port-iam: 28080\n")
+	writeFile(t, filepath.Join(configDir, "profiles", "dev.yaml"), "port-iam: 38080\n")
+
+	moves, err := MigratePaths(true)
+	if err != nil {
+		t.Fatalf("MigratePaths(dry run) failed: %v", err)
+	}
+	if len(moves) != 3 {
+		t.Fatalf("Expected 3 planned moves, got %+v", moves)
+	}
+	if _, err := os.Stat(filepath.Join(legacy, "config.yaml")); err != nil {
+		t.Error("Expected a dry run to leave files in place")
+	}
+
+	moves, err = MigratePaths(false)
+	if err != nil {
+		t.Fatalf("MigratePaths failed: %v", err)
+	}
+	for _, move := range moves {
+		wantSkipped := filepath.Base(move.From) == "dev.yaml"
+		if (move.Skipped != "") != wantSkipped {
+			t.Errorf("Unexpected result for %s: %+v", move.From, move)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(configDir, "config.yaml"))
+	if err != nil || string(data) != "iam-mode: strict\n" {
+		t.Errorf("Expected the config file to be moved, got %q (%v)", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(configDir, "profiles", "ci.yaml")); err != nil {
+		t.Errorf("Expected the ci profile to be moved: %v", err)
+	}
+	data, _ = os.ReadFile(filepath.Join(configDir, "profiles", "dev.yaml"))
+	if string(data) != "port-iam: 38080\n" {
+		t.Errorf("Expected an existing profile not to be overwritten, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(legacy, "profiles", "dev.yaml")); err != nil {
+		t.Error("Expected the skipped profile to stay in the legacy directory")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultProfile names the configuration with no profile applied
+const DefaultProfile = "default"
+
+// profileNamePattern keeps profile names usable in file names and docker
+// compose project names
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// mergedProfile is the profile whose file has been merged into viper
+var mergedProfile string
+
+// ProfilesDir returns the directory holding one <name>.yaml per profile,
+// in ConfigDir or, if it exists only there, in LegacyDir
+func ProfilesDir() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return withLegacy(filepath.Join(dir, "profiles"), "profiles")
+}
+
+// ProfilePath returns the file for the named profile
+func ProfilePath(name string) (string, error) {
+	if err := ValidateProfileName(name); err != nil {
+		return "", err
+	}
+	dir, err := ProfilesDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".yaml"), nil
+}
+
+// ValidateProfileName checks that name can be used as a profile
+func ValidateProfileName(name string) error {
+	if name == DefaultProfile {
+		return fmt.Errorf("%q is reserved for the configuration without a profile", DefaultProfile)
+	}
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name: %q (use lowercase letters, digits, - and _)", name)
+	}
+	return nil
+}
+
+// ActiveProfile returns the profile selected by --profile,
+// GCP_EMULATOR_PROFILE, or 'config use', or "" for none
+func ActiveProfile() string {
+	name := viper.GetString("profile")
+	if name == DefaultProfile {
+		return ""
+	}
+	return name
+}
+
+// ListProfiles returns the names of all profiles, sorted
+func ListProfiles() ([]string, error) {
+	dir, err := ProfilesDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".yaml")
+		if ok && !entry.IsDir() && ValidateProfileName(name) == nil {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// CreateProfile writes a new profile holding the values of cfg
+func CreateProfile(name string, cfg *Config) error {
+	path, err := ProfilePath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("profile %s already exists", name)
+	}
+	return editConfigFile(path, func(v *viper.Viper) {
+		setValues(v, cfg)
+	})
+}
+
+// DeleteProfile removes the named profile
+func DeleteProfile(name string) error {
+	path, err := ProfilePath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("profile %s: %w", name, ErrConfigNotFound)
+		}
+		return fmt.Errorf("failed to delete profile: %w", err)
+	}
+	return nil
+}
+
+// UseProfile records name as the active profile in the config file, so it
+// applies to later commands. DefaultProfile clears it.
+func UseProfile(name string) error {
+	if name != DefaultProfile {
+		path, err := ProfilePath(name)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("profile %s: %w (create it with gcp-emulator config profiles create %s)", name, ErrConfigNotFound, name)
+		}
+	}
+
+	file, err := configFilePath()
+	if err != nil {
+		return err
+	}
+
+	if name == DefaultProfile {
+		name = ""
+	}
+	return editConfigFile(file, func(v *viper.Viper) {
+		v.Set("profile", name)
+	})
+}
+
+// applyProfile merges the active profile's values over the config file,
+// and the local config file over both again. Environment variables and
+// flags still take precedence.
+func applyProfile() error {
+	name := ActiveProfile()
+	if name == "" || name == mergedProfile {
+		return nil
+	}
+
+	path, err := ProfilePath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("profile %s: %w (create it with gcp-emulator config profiles create %s)", name, ErrConfigNotFound, name)
+	}
+	v, err := readLayer(path)
+	if err != nil {
+		return fmt.Errorf("failed to read profile %s: %w", name, err)
+	}
+	if err := viper.MergeConfigMap(v.AllSettings()); err != nil {
+		return fmt.Errorf("failed to merge profile %s: %w", name, err)
+	}
+	layers.profile = &layer{path: path, values: v}
+	mergedProfile = name
+	slog.Debug("profile merged", "profile", name, "path", path)
+	return mergeLocal()
+}
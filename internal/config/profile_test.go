@@ -0,0 +1,140 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// withTestHome points the home directory at a temporary directory and
+// starts from fresh viper state
+func withTestHome(t *testing.T) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Chdir(home)
+
+	viper.Reset()
+	mergedProfile = ""
+	overrides = nil
+	t.Cleanup(func() {
+		viper.Reset()
+		mergedProfile = ""
+		overrides = nil
+	})
+
+	if err := Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	return home
+}
+
+func TestValidateProfileName(t *testing.T) {
+	for _, name := range []string{"staging", "unit-tests", "ci_2"} {
+		if err := ValidateProfileName(name); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "default", "Staging", "../etc", "-x", "a b"} {
+		if err := ValidateProfileName(name); err == nil {
+			t.Errorf("Expected %q to be rejected", name)
+		}
+	}
+}
+
+func TestProfileOverridesConfigFile(t *testing.T) {
+	home := withTestHome(t)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	cfg.IAMMode = "strict"
+	cfg.Ports.IAM = 18080
+	if err := CreateProfile("staging", cfg); err != nil {
+		t.Fatalf("CreateProfile failed: %v", err)
+	}
+	if err := CreateProfile("staging", cfg); err == nil {
+		t.Error("Expected creating an existing profile to fail")
+	}
+
+	names, err := ListProfiles()
+	if err != nil || !slices.Equal(names, []string{"staging"}) {
+		t.Fatalf("Expected [staging], got %v (%v)", names, err)
+	}
+
+	t.Setenv("GCP_EMULATOR_PROFILE", "staging")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Profile != "staging" || cfg.IAMMode != "strict" || cfg.Ports.IAM != 18080 {
+		t.Errorf("Expected staging profile values, got %+v", cfg)
+	}
+	if cfg.Ports.KMS != 9091 {
+		t.Errorf("Expected defaults for values the profile doesn't change, got KMS port %d", cfg.Ports.KMS)
+	}
+
+	cfg.Trace = true
+	if err := Save(cfg); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, "config.yaml")); err == nil {
+		t.Error("Expected Save with a profile not to write the config file")
+	}
+
+	if err := DeleteProfile("staging"); err != nil {
+		t.Fatalf("DeleteProfile failed: %v", err)
+	}
+	if err := DeleteProfile("staging"); err == nil {
+		t.Error("Expected deleting a missing profile to fail")
+	}
+}
+
+func TestUseProfile(t *testing.T) {
+	home := withTestHome(t)
+
+	if err := UseProfile("missing"); err == nil {
+		t.Fatal("Expected using a missing profile to fail")
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := CreateProfile("unit", cfg); err != nil {
+		t.Fatalf("CreateProfile failed: %v", err)
+	}
+	if err := UseProfile("unit"); err != nil {
+		t.Fatalf("UseProfile failed: %v", err)
+	}
+
+	// A new process reads the active profile from the config file
+	viper.Reset()
+	if err := Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if got := viper.ConfigFileUsed(); got != filepath.Join(home, ".config", "gcp-emulator", "config.yaml") {
+		t.Errorf("Expected the user config file, got %q", got)
+	}
+	if got := ActiveProfile(); got != "unit" {
+		t.Errorf("Expected active profile unit, got %q", got)
+	}
+
+	if err := UseProfile(DefaultProfile); err != nil {
+		t.Fatalf("UseProfile(default) failed: %v", err)
+	}
+	viper.Reset()
+	if err := Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if got := ActiveProfile(); got != "" {
+		t.Errorf("Expected no active profile, got %q", got)
+	}
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ResourceConfig holds the memory and CPU limits and restart policy of the
+// emulator containers: those of all services, and each service's own,
+// which win over them
+type ResourceConfig struct {
+	ServiceResources
+
+	IAM           ServiceResources
+	SecretManager ServiceResources
+	KMS           ServiceResources
+}
+
+// ServiceResources are the limits and restart policy of a container. An
+// empty value is unlimited, or for Restart compose's default of never
+// restarting.
+type ServiceResources struct {
+	// Memory is a memory limit such as 512m or 1g
+	Memory string
+
+	// CPUs is a limit in CPUs, such as 0.5
+	CPUs string
+
+	// Restart is no, always, unless-stopped, or on-failure[:retries]
+	Restart string
+}
+
+// For returns the resources of service (iam, secret-manager, or kms): its
+// own settings, falling back to those of all services
+func (r ResourceConfig) For(service string) ServiceResources {
+	var own ServiceResources
+	switch service {
+	case "iam":
+		own = r.IAM
+	case "secret-manager":
+		own = r.SecretManager
+	case "kms":
+		own = r.KMS
+	}
+	if own.Memory == "" {
+		own.Memory = r.Memory
+	}
+	if own.CPUs == "" {
+		own.CPUs = r.CPUs
+	}
+	if own.Restart == "" {
+		own.Restart = r.Restart
+	}
+	return own
+}
+
+// String describes the resources, such as "memory 512m, cpus 1", or
+// "(unlimited)"
+func (s ServiceResources) String() string {
+	var parts []string
+	if s.Memory != "" {
+		parts = append(parts, "memory "+s.Memory)
+	}
+	if s.CPUs != "" {
+		parts = append(parts, "cpus "+s.CPUs)
+	}
+	if s.Restart != "" {
+		parts = append(parts, "restart "+s.Restart)
+	}
+	if len(parts) == 0 {
+		return "(unlimited)"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// memoryPattern matches the memory sizes compose takes: a number with an
+// optional unit of bytes, k, m, g, t, or p
+var memoryPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?\s?([kKmMgGtTpP][iI]?)?[bB]?$`)
+
+// validate checks the resources as the keys named resources.<name>.*
+func (s ServiceResources) validate(name string) error {
+	if s.Memory != "" && !memoryPattern.MatchString(s.Memory) {
+		return fmt.Errorf("%w for %s.memory: %q (use a size such as 512m or 1g)", ErrInvalidValue, name, s.Memory)
+	}
+	if s.CPUs != "" {
+		if cpus, err := strconv.ParseFloat(s.CPUs, 64); err != nil || cpus <= 0 {
+			return fmt.Errorf("%w for %s.cpus: %q (use a number of CPUs such as 0.5)", ErrInvalidValue, name, s.CPUs)
+		}
+	}
+	switch policy, retries, hasRetries := strings.Cut(s.Restart, ":"); {
+	case policy == "on-failure" && hasRetries:
+		if n, err := strconv.Atoi(retries); err != nil || n < 0 {
+			return fmt.Errorf("%w for %s.restart: %q (retries must be a number)", ErrInvalidValue, name, s.Restart)
+		}
+	case s.Restart == "", s.Restart == "no", s.Restart == "always", s.Restart == "unless-stopped", s.Restart == "on-failure":
+	default:
+		return fmt.Errorf("%w for %s.restart: %q (must be no, always, unless-stopped, or on-failure[:retries])", ErrInvalidValue, name, s.Restart)
+	}
+	return nil
+}
+
+// validate checks the resources of all services and of each one
+func (r ResourceConfig) validate() error {
+	if err := r.ServiceResources.validate("resources"); err != nil {
+		return err
+	}
+	for _, service := range imageServices {
+		if err := r.service(service).validate("resources." + service); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// service returns a pointer to the own resources of service, or to those
+// of all services for ""
+func (r *ResourceConfig) service(service string) *ServiceResources {
+	switch service {
+	case "iam":
+		return &r.IAM
+	case "secret-manager":
+		return &r.SecretManager
+	case "kms":
+		return &r.KMS
+	}
+	return &r.ServiceResources
+}
+
+// resourceKeys returns the resources.* keys: memory, cpus, and restart,
+// for all services and then for each
+func resourceKeys() []Key {
+	var keys []Key
+	for _, service := range append([]string{""}, imageServices...) {
+		prefix, of := "resources.", "every emulator container"
+		if service != "" {
+			prefix, of = "resources."+service+".", "the "+service+" container, over resources.*"
+		}
+		keys = append(keys,
+			Key{
+				Name:        prefix + "memory",
+				Description: "Memory limit of " + of + ", e.g. 512m (default unlimited)",
+				value:       func(c *Config) any { return c.Resources.service(service).Memory },
+				set:         func(c *Config, s string) error { c.Resources.service(service).Memory = s; return nil },
+			},
+			Key{
+				Name:        prefix + "cpus",
+				Description: "CPU limit of " + of + ", e.g. 0.5 (default unlimited)",
+				value:       func(c *Config) any { return c.Resources.service(service).CPUs },
+				set:         func(c *Config, s string) error { c.Resources.service(service).CPUs = s; return nil },
+			},
+			Key{
+				Name:        prefix + "restart",
+				Description: "Restart policy of " + of + " (no|always|unless-stopped|on-failure[:retries]; default no)",
+				value:       func(c *Config) any { return c.Resources.service(service).Restart },
+				set:         func(c *Config, s string) error { c.Resources.service(service).Restart = s; return nil },
+			},
+		)
+	}
+	return keys
+}
+
+// loadResources reads the resources.* keys
+func loadResources() ResourceConfig {
+	var r ResourceConfig
+	for _, service := range append([]string{""}, imageServices...) {
+		prefix := "resources."
+		if service != "" {
+			prefix += service + "."
+		}
+		*r.service(service) = ServiceResources{
+			Memory:  viper.GetString(prefix + "memory"),
+			CPUs:    viper.GetString(prefix + "cpus"),
+			Restart: viper.GetString(prefix + "restart"),
+		}
+	}
+	return r
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestResourcesFor(t *testing.T) {
+	r := ResourceConfig{
+		ServiceResources: ServiceResources{Memory: "1g", Restart: "unless-stopped"},
+		KMS:              ServiceResources{Memory: "512m", CPUs: "0.5"},
+	}
+	if got, want := r.For("kms"), (ServiceResources{Memory: "512m", CPUs: "0.5", Restart: "unless-stopped"}); got != want {
+		t.Errorf("Expected kms's own settings over those of all services, got %+v", got)
+	}
+	if got, want := r.For("iam"), r.ServiceResources; got != want {
+		t.Errorf("Expected iam to get the settings of all services, got %+v", got)
+	}
+	if got := (ServiceResources{}).String(); got != "(unlimited)" {
+		t.Errorf("Expected (unlimited), got %q", got)
+	}
+	if got := r.For("kms").String(); got != "memory 512m, cpus 0.5, restart unless-stopped" {
+		t.Errorf("Unexpected description %q", got)
+	}
+}
+
+func TestValidateResources(t *testing.T) {
+	cfg := Defaults()
+	cfg.Resources.Memory = "1.5g"
+	cfg.Resources.SecretManager = ServiceResources{Memory: "256MiB", CPUs: "2", Restart: "on-failure:5"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected the resources to be valid, got %v", err)
+	}
+
+	for _, tt := range []struct {
+		r   ServiceResources
+		key string
+	}{
+		{ServiceResources{Memory: "lots"}, "resources.kms.memory"},
+		{ServiceResources{CPUs: "0"}, "resources.kms.cpus"},
+		{ServiceResources{Restart: "sometimes"}, "resources.kms.restart"},
+		{ServiceResources{Restart: "on-failure:x"}, "resources.kms.restart"},
+	} {
+		cfg := Defaults()
+		cfg.Resources.KMS = tt.r
+		err := cfg.Validate()
+		if !errors.Is(err, ErrInvalidValue) || !strings.Contains(err.Error(), tt.key) {
+			t.Errorf("Expected %+v to be invalid for %s, got %v", tt.r, tt.key, err)
+		}
+	}
+}
+
+func TestLoadResources(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("resources.memory", "1g")
+	viper.Set("resources.kms.memory", "512m")
+	viper.Set("resources.kms.restart", "always")
+
+	r := loadResources()
+	if r.Memory != "1g" || r.KMS.Memory != "512m" || r.KMS.Restart != "always" || r.IAM != (ServiceResources{}) {
+		t.Errorf("Unexpected resources %+v", r)
+	}
+}
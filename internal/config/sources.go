@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// SourceKind is where a configuration value comes from. From highest to
+// lowest precedence: --set, flag, environment, local file, profile,
+// config file, default.
+type SourceKind string
+
+const (
+	SourceSet     SourceKind = "--set"
+	SourceFlag    SourceKind = "flag"
+	SourceEnv     SourceKind = "environment"
+	SourceLocal   SourceKind = "local file"
+	SourceProfile SourceKind = "profile"
+	SourceFile    SourceKind = "config file"
+	SourceDefault SourceKind = "default"
+)
+
+// Source is where the effective value of a key comes from
+type Source struct {
+	Kind SourceKind
+
+	// Name is the --set key=value, the flag, the environment variable, or
+	// the file the value is set in; "" for the default
+	Name string
+}
+
+func (s Source) String() string {
+	if s.Kind == SourceDefault {
+		return string(s.Kind)
+	}
+	return fmt.Sprintf("%s %s", s.Kind, s.Name)
+}
+
+// layer is a config file that has been read, with the values it sets
+type layer struct {
+	path   string
+	values *viper.Viper
+}
+
+func (l *layer) sets(name string) bool {
+	return l != nil && l.values.IsSet(name)
+}
+
+// layers are the config files merged into viper, kept to tell which one
+// a value comes from
+var layers struct {
+	file, profile, local *layer
+}
+
+// boundFlags are the flags bound to keys with BindFlag
+var boundFlags = map[string]*pflag.Flag{}
+
+// BindFlag binds a flag to the named key, so the flag's value takes
+// precedence when it is given and Source reports it
+func BindFlag(name string, flag *pflag.Flag) error {
+	boundFlags[name] = flag
+	return viper.BindPFlag(name, flag)
+}
+
+// LocalFile returns the local config file in use, or "" if there is none
+func LocalFile() string {
+	if layers.local == nil {
+		return ""
+	}
+	return layers.local.path
+}
+
+// displaySources lists where each value comes from, for Display
+func displaySources() string {
+	var b strings.Builder
+	b.WriteString("\nValue sources (highest first: --set, flag, environment, local file, profile, config file, default):\n")
+	for _, key := range keys {
+		source, err := SourceOf(key.Name)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "  %-27s%s\n", key.Name+":", source)
+	}
+	return b.String()
+}
+
+// SourceOf returns where the effective value of the named key comes from
+func SourceOf(name string) (Source, error) {
+	key, err := LookupKey(name)
+	if err != nil {
+		return Source{}, err
+	}
+	if err := applyProfile(); err != nil {
+		return Source{}, err
+	}
+
+	if o, ok := overrideOf(key.Name); ok {
+		return Source{Kind: SourceSet, Name: o.pair}, nil
+	}
+	if flag, ok := boundFlags[key.Name]; ok && flag.Changed {
+		return Source{Kind: SourceFlag, Name: "--" + flag.Name}, nil
+	}
+	if _, ok := os.LookupEnv(key.EnvVar()); ok {
+		return Source{Kind: SourceEnv, Name: key.EnvVar()}, nil
+	}
+	if _, ok := os.LookupEnv(key.FileEnvVar()); ok {
+		return Source{Kind: SourceEnv, Name: key.FileEnvVar()}, nil
+	}
+	for _, l := range []struct {
+		kind  SourceKind
+		layer *layer
+	}{
+		{SourceLocal, layers.local},
+		{SourceProfile, layers.profile},
+		{SourceFile, layers.file},
+	} {
+		if l.layer.sets(key.Name) {
+			return Source{Kind: l.kind, Name: l.layer.path}, nil
+		}
+	}
+	return Source{Kind: SourceDefault}, nil
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultStack names the stack run without --stack
+const DefaultStack = "default"
+
+// ValidateStackName checks that name can be used as a named stack. Stack
+// names end up in compose project, network, and directory names, so they
+// follow the rules of profile names.
+func ValidateStackName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid stack name: %q (use lowercase letters, digits, - and _)", name)
+	}
+	return nil
+}
+
+// ActiveStack returns the named stack selected by --stack or
+// GCP_EMULATOR_STACK, or "" for the default stack
+func ActiveStack() string {
+	name := viper.GetString("stack")
+	if name == DefaultStack {
+		return ""
+	}
+	return name
+}
+
+// StacksDir returns the directory holding the state directory of each
+// named stack
+func StacksDir() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "stacks"), nil
+}
+
+// StatePath returns the runtime state file of cfg's stack called name: in
+// the named stack's directory under StacksDir, or as by the package's
+// StatePath for the default stack
+func (c *Config) StatePath(name string) (string, error) {
+	if c.Stack == "" {
+		return StatePath(name)
+	}
+	dir, err := StacksDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, c.Stack, name), nil
+}
+
+// ListStacks returns the names of the named stacks that have a state
+// directory, sorted
+func ListStacks() ([]string, error) {
+	dir, err := StacksDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stacks: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && ValidateStackName(entry.Name()) == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestStackStatePath(t *testing.T) {
+	state := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", state)
+
+	cfg := Defaults()
+	if path, err := cfg.StatePath("ports.json"); err != nil || path != filepath.Join(state, "gcp-emulator", "ports.json") {
+		t.Errorf("Expected the default stack's state in the state directory, got %s, %v", path, err)
+	}
+	cfg.Stack = "tenant-a"
+	if path, err := cfg.StatePath("ports.json"); err != nil || path != filepath.Join(state, "gcp-emulator", "stacks", "tenant-a", "ports.json") {
+		t.Errorf("Expected a named stack's state in a directory of its own, got %s, %v", path, err)
+	}
+}
+
+func TestListStacks(t *testing.T) {
+	state := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", state)
+
+	if names, err := ListStacks(); err != nil || len(names) != 0 {
+		t.Errorf("Expected no stacks without a stacks directory, got %v, %v", names, err)
+	}
+	for _, name := range []string{"tenant-b", "tenant-a", "Not A Stack"} {
+		if err := os.MkdirAll(filepath.Join(state, "gcp-emulator", "stacks", name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if names, err := ListStacks(); err != nil || !slices.Equal(names, []string{"tenant-a", "tenant-b"}) {
+		t.Errorf("Expected tenant-a and tenant-b, got %v, %v", names, err)
+	}
+}
+
+func TestValidateStack(t *testing.T) {
+	cfg := Defaults()
+	cfg.Stack = "tenant_1"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected tenant_1 to be a valid stack, got %v", err)
+	}
+	cfg.Stack = "Tenant A"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected an invalid stack name to fail validation")
+	}
+}
+
+func TestLoadStack(t *testing.T) {
+	withTestHome(t)
+	t.Setenv("GCP_EMULATOR_STACK", "tenant-a")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Stack != "tenant-a" || !cfg.Ports.Auto {
+		t.Errorf("Expected stack tenant-a on automatic ports, got %q, auto %t", cfg.Stack, cfg.Ports.Auto)
+	}
+
+	t.Setenv("GCP_EMULATOR_STACK", DefaultStack)
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Stack != "" {
+		t.Errorf("Expected %s to be the default stack, got %q", DefaultStack, cfg.Stack)
+	}
+}
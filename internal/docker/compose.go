@@ -4,79 +4,275 @@
 package docker
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 
 	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
 )
 
-// getComposeCommand returns the appropriate docker compose command
-// Tries "docker compose" first (modern), falls back to "docker-compose" (legacy)
-func getComposeCommand() (string, []string) {
-	// Try modern "docker compose" first
-	cmd := exec.Command("docker", "compose", "version")
-	if err := cmd.Run(); err == nil {
-		return "docker", []string{"compose"}
-	}
-	
-	// Fall back to legacy "docker-compose"
-	return "docker-compose", []string{}
+// composeCommand returns the compose command running args in cfg's
+// project, with the compose files from ComposeFiles for the stack on ports
+func composeCommand(ctx context.Context, cfg *config.Config, ports Ports, args ...string) (*exec.Cmd, error) {
+	runtime, err := DetectRuntime(cfg)
+	if err != nil {
+		return nil, err
+	}
+	files, err := ComposeFiles(cfg, ports)
+	if err != nil {
+		return nil, err
+	}
+
+	base := append([]string{}, runtime.Compose[1:]...)
+	for _, file := range files {
+		base = append(base, "-f", file)
+	}
+	if len(files) > 1 && cfg.Docker.ComposeFile == "" {
+		// Compose resolves relative paths against the directory of the
+		// first file, which for the generated one is the state directory,
+		// so they are resolved against the first override's instead
+		base = append(base, "--project-directory", filepath.Dir(files[1]))
+	}
+	args = append(append(base, ProjectArgs(cfg)...), args...)
+	cmd := exec.CommandContext(ctx, runtime.Compose[0], args...)
+	vars := composeVars(cfg, ports)
+	cmd.Env = append(os.Environ(), vars...)
+	logCommand(cmd, vars)
+	return cmd, nil
+}
+
+// runCompose runs cmd, returning a *CommandError with its output if it
+// fails
+func runCompose(cmd *exec.Cmd, msg string) ([]byte, error) {
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return output, commandError(msg, err, string(output))
+	}
+	return output, nil
+}
+
+// ProjectName returns the compose project name: compose-project, with
+// the profile and then the named stack appended so stacks for different
+// profiles or of different names don't collide. It doesn't depend on the
+// directory the CLI is run from.
+func ProjectName(cfg *config.Config) string {
+	name := cfg.Docker.Project
+	if name == "" {
+		name = config.DefaultProject
+	}
+	if cfg.Profile != "" {
+		name += "-" + cfg.Profile
+	}
+	if cfg.Stack != "" {
+		name += "-" + cfg.Stack
+	}
+	return name
+}
+
+// NetworkName returns the stack's docker network: network-name, or
+// compose's default network of the project. A named stack's network-name
+// is suffixed with the stack, so stacks never share a network.
+func NetworkName(cfg *config.Config) string {
+	if cfg.Docker.Network != "" && cfg.Stack != "" {
+		return cfg.Docker.Network + "-" + cfg.Stack
+	}
+	if cfg.Docker.Network != "" {
+		return cfg.Docker.Network
+	}
+	return ProjectName(cfg) + "_default"
+}
+
+// The labels compose puts on the containers, networks, and volumes it
+// creates, naming their project and service
+const (
+	projectLabelKey = "com.docker.compose.project"
+	serviceLabelKey = "com.docker.compose.service"
+)
+
+// projectLabel returns the filter selecting the containers of cfg's
+// project, which compose labels with it
+func projectLabel(cfg *config.Config) string {
+	return projectFilter(ProjectName(cfg))
+}
+
+// projectFilter returns the filter selecting what compose created for
+// project. The label match is exact, so a project whose name shares a
+// prefix with it isn't selected.
+func projectFilter(project string) string {
+	return "label=" + projectLabelKey + "=" + project
+}
+
+// ProjectArgs returns the compose flags selecting the config's project
+func ProjectArgs(cfg *config.Config) []string {
+	return []string{"-p", ProjectName(cfg)}
+}
+
+// Env returns the environment for runtime commands, naming the stack's
+// network and selecting the configured docker context, or podman
+// connection. Without one, docker uses DOCKER_HOST or the current context
+// and podman CONTAINER_HOST or the default connection, as usual.
+func Env(cfg *config.Config) []string {
+	return append(os.Environ(), envVars(cfg)...)
 }
 
-// Start starts the docker compose stack
-func Start(cfg *config.Config) error {
-	// Generate environment variables for docker compose
-	env := os.Environ()
-	env = append(env, 
+// envVars are the variables Env adds to the CLI's environment
+func envVars(cfg *config.Config) []string {
+	env := []string{"NETWORK_NAME=" + NetworkName(cfg)}
+	if cfg.Docker.Context != "" {
+		if RuntimeName(cfg) == config.RuntimePodman {
+			env = append(env, "CONTAINER_CONNECTION="+cfg.Docker.Context)
+		} else {
+			env = append(env, "DOCKER_CONTEXT="+cfg.Docker.Context)
+		}
+	}
+	return env
+}
+
+// Target describes the docker daemon, or podman service, commands for cfg
+// run against
+func Target(cfg *config.Config) string {
+	if RuntimeName(cfg) == config.RuntimePodman {
+		return podmanTarget(cfg)
+	}
+	if cfg.Docker.Context != "" {
+		return "context " + cfg.Docker.Context
+	}
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		return host + " (DOCKER_HOST)"
+	}
+	if context := os.Getenv("DOCKER_CONTEXT"); context != "" {
+		return "context " + context + " (DOCKER_CONTEXT)"
+	}
+	return "current docker context"
+}
+
+// podmanTarget describes the podman service commands for cfg run against
+func podmanTarget(cfg *config.Config) string {
+	if cfg.Docker.Context != "" {
+		return "podman connection " + cfg.Docker.Context
+	}
+	if host := os.Getenv("CONTAINER_HOST"); host != "" {
+		return host + " (CONTAINER_HOST)"
+	}
+	if connection := os.Getenv("CONTAINER_CONNECTION"); connection != "" {
+		return "podman connection " + connection + " (CONTAINER_CONNECTION)"
+	}
+	return "default podman connection"
+}
+
+// composeVars returns the variables passing cfg and the host ports to a
+// compose file given with compose-file, such as the repository's
+// docker-compose.yml
+func composeVars(cfg *config.Config, ports Ports) []string {
+	env := append(envVars(cfg),
 		fmt.Sprintf("IAM_MODE=%s", cfg.IAMMode),
-		fmt.Sprintf("IAM_PORT=%d", cfg.Ports.IAM),
-		fmt.Sprintf("SECRET_MANAGER_PORT=%d", cfg.Ports.SecretManager),
-		fmt.Sprintf("KMS_PORT=%d", cfg.Ports.KMS),
+		fmt.Sprintf("IAM_PORT=%d", ports.IAM),
+		fmt.Sprintf("IAM_HTTP_PORT=%d", ports.IAMHTTP()),
+		fmt.Sprintf("IAM_TRACE=%t", cfg.Trace),
+		fmt.Sprintf("SECRET_MANAGER_PORT=%d", ports.SecretManager),
+		fmt.Sprintf("SECRET_MANAGER_HTTP_PORT=%d", ports.SecretManagerHTTP),
+		fmt.Sprintf("KMS_PORT=%d", ports.KMS),
+		fmt.Sprintf("KMS_HTTP_PORT=%d", ports.KMSHTTP),
 	)
 
-	// Get appropriate compose command
-	binary, baseArgs := getComposeCommand()
-	args := append(baseArgs, "up", "-d")
-	
-	// Run docker compose up
-	cmd := exec.Command(binary, args...)
-	cmd.Env = env
+	// Unset images fall back to the defaults in docker-compose.yml
+	for _, image := range []struct{ name, ref string }{
+		{"IAM_IMAGE", cfg.Images.IAM},
+		{"SECRET_MANAGER_IMAGE", cfg.Images.SecretManager},
+		{"KMS_IMAGE", cfg.Images.KMS},
+	} {
+		if image.ref != "" {
+			env = append(env, image.name+"="+image.ref)
+		}
+	}
+	return env
+}
 
-	output, err := cmd.CombinedOutput()
+// Start starts the docker compose stack, publishing it on ports. Only
+// services are started if any are given; see SelectServices. The output
+// of compose is written to out as it comes, a line at a time.
+func Start(cfg *config.Config, ports Ports, services []string, out io.Writer) error {
+	args := []string{"up", "-d"}
+	if len(services) > 0 && !cfg.StartOrdered() {
+		// Without ordering the data planes start on their own, even if a
+		// compose file of the user's makes them depend on IAM
+		args = append(args, "--no-deps")
+	}
+
+	// Run compose up
+	cmd, err := composeCommand(context.Background(), cfg, ports, append(args, services...)...)
+	if err != nil {
+		return err
+	}
+
+	// The output is written to out as it comes, and kept to tell what
+	// went wrong
+	var output bytes.Buffer
+	lines := &lineWriter{line: composeLines(out, ProjectName(cfg))}
+	cmd.Stdout = io.MultiWriter(lines, &output)
+	cmd.Stderr = cmd.Stdout
+	err = cmd.Run()
+	if flushErr := lines.Flush(); err == nil {
+		err = flushErr
+	}
 	if err != nil {
-		return fmt.Errorf("docker compose up failed: %w\n%s", err, output)
+		e := commandError(composeFailed(cfg, "up"), err, output.String())
+		e.Output = ""
+		return e
 	}
 
 	return nil
 }
 
-// Stop stops the docker compose stack
-func Stop() error {
-	binary, baseArgs := getComposeCommand()
-	args := append(baseArgs, "down")
-	
-	cmd := exec.Command(binary, args...)
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("docker compose down failed: %w\n%s", err, output)
+// Stop stops the docker compose stack, or only services if any are given.
+// Their containers are removed, so status shows them as not enabled
+// rather than down.
+func Stop(cfg *config.Config, services []string) error {
+	commands := [][]string{{"down"}}
+	if len(services) > 0 {
+		commands = [][]string{
+			append([]string{"stop"}, services...),
+			append([]string{"rm", "-f"}, services...),
+		}
+	}
+	for _, args := range commands {
+		cmd, err := composeCommand(context.Background(), cfg, ActivePorts(cfg), args...)
+		if err != nil {
+			return err
+		}
+		if _, err := runCompose(cmd, composeFailed(cfg, args[0])); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-// Pull pulls the latest images
-func Pull() error {
-	binary, baseArgs := getComposeCommand()
-	args := append(baseArgs, "pull")
-	
-	cmd := exec.Command(binary, args...)
+// Running reports whether any container of the config's stack is running
+func Running(cfg *config.Config) (bool, error) {
+	var output []byte
+	var err error
+	if RuntimeName(cfg) == config.RuntimePodman {
+		// podman-compose's ps doesn't take compose's flags, so podman is
+		// asked directly, over whichever connection is in use
+		cmd := command(cfg, "ps", "-q", "--filter", projectLabel(cfg), "--filter", "status=running")
+		if output, err = cmd.Output(); err != nil {
+			return false, commandError(failed(cfg, "ps"), err, stderrOf(err))
+		}
+		return strings.TrimSpace(string(output)) != "", nil
+	}
 
-	output, err := cmd.CombinedOutput()
+	cmd, err := composeCommand(context.Background(), cfg, ActivePorts(cfg), "ps", "-q", "--status", "running")
 	if err != nil {
-		return fmt.Errorf("docker compose pull failed: %w\n%s", err, output)
+		return false, err
 	}
-
-	return nil
+	if output, err = cmd.Output(); err != nil {
+		return false, commandError(composeFailed(cfg, "ps"), err, stderrOf(err))
+	}
+	return strings.TrimSpace(string(output)) != "", nil
 }
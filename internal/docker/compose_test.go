@@ -0,0 +1,40 @@
+package docker
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+func TestProjectName(t *testing.T) {
+	cfg := config.Defaults()
+	if got := ProjectName(cfg); got != "gcp-emulator" {
+		t.Errorf("Expected the default project gcp-emulator, got %s", got)
+	}
+	if got := NetworkName(cfg); got != "gcp-emulator_default" {
+		t.Errorf("Expected the project's default network, got %s", got)
+	}
+
+	cfg.Docker.Project = "team-a"
+	cfg.Profile = "ci"
+	if got := ProjectName(cfg); got != "team-a-ci" {
+		t.Errorf("Expected the profile appended to compose-project, got %s", got)
+	}
+	if args := ProjectArgs(cfg); !slices.Equal(args, []string{"-p", "team-a-ci"}) {
+		t.Errorf("Expected -p team-a-ci, got %v", args)
+	}
+
+	cfg.Docker.Network = "shared"
+	if env := Env(cfg); !slices.Contains(env, "NETWORK_NAME=shared") {
+		t.Error("Expected network-name to be passed to compose as NETWORK_NAME")
+	}
+
+	cfg.Stack = "tenant-a"
+	if got := ProjectName(cfg); got != "team-a-ci-tenant-a" {
+		t.Errorf("Expected the stack appended after the profile, got %s", got)
+	}
+	if got := NetworkName(cfg); got != "shared-tenant-a" {
+		t.Errorf("Expected a named stack's network-name to be its own, got %s", got)
+	}
+}
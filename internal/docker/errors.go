@@ -0,0 +1,163 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+// Kinds of CommandError, matched with errors.Is
+var (
+	ErrComposeNotInstalled = errors.New("docker compose is not installed")
+	ErrDaemonUnreachable   = errors.New("docker daemon is not reachable")
+	ErrComposeFileInvalid  = errors.New("compose file is invalid")
+)
+
+// CommandError is returned when a docker, podman, or compose command fails
+type CommandError struct {
+	// Msg describes what failed, such as "docker compose up failed"
+	Msg string
+	Err error
+
+	// Output is what the command printed, if it was captured
+	Output string
+
+	// Kind is ErrComposeNotInstalled, ErrDaemonUnreachable, or
+	// ErrComposeFileInvalid when the failure is known to be one of those,
+	// and nil otherwise
+	Kind error
+}
+
+func (e *CommandError) Error() string {
+	msg := fmt.Sprintf("%s: %v", e.Msg, e.Err)
+	if output := strings.TrimSpace(e.Output); output != "" {
+		msg += "\n" + output
+	}
+	return msg
+}
+
+func (e *CommandError) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Err, e.Kind}
+}
+
+// DaemonDownError is returned when the docker daemon, or podman service,
+// commands run against isn't running or can't be reached, found before
+// running the command that would have failed on it
+type DaemonDownError struct {
+	// Runtime is docker or podman
+	Runtime string
+	Err     error
+}
+
+func (e *DaemonDownError) Error() string {
+	if e.Runtime == config.RuntimePodman {
+		return "podman service is not running — run 'podman machine start' or 'systemctl --user start podman.socket'"
+	}
+	return "Docker daemon is not running — start Docker Desktop or run 'sudo systemctl start docker'"
+}
+
+func (e *DaemonDownError) Unwrap() []error {
+	return []error{e.Err, ErrDaemonUnreachable}
+}
+
+// daemonErrors and composeFileErrors are printed by docker, podman, and
+// compose when the daemon can't be reached or the compose file doesn't load
+var (
+	daemonErrors = []string{
+		"Cannot connect to the Docker daemon",
+		"Is the docker daemon running",
+		"error during connect",
+		"docker daemon is not running",
+		"Cannot connect to Podman",
+		"unable to connect to Podman socket",
+	}
+	composeFileErrors = []string{
+		"no configuration file provided",
+		"Can't find a suitable configuration file",
+		"yaml: ",
+		"validating ",
+		"Additional property",
+		"additional properties",
+		"invalid compose project",
+	}
+)
+
+// commandError returns the *CommandError for a command that failed with
+// output, telling from the output what kind of failure it was
+func commandError(msg string, err error, output string) *CommandError {
+	slog.Debug("command failed", "message", msg, "error", err, "output", output)
+	e := &CommandError{Msg: msg, Err: err, Output: output}
+	switch {
+	case containsAny(output, daemonErrors):
+		e.Kind = ErrDaemonUnreachable
+	case containsAny(output, composeFileErrors):
+		e.Kind = ErrComposeFileInvalid
+	}
+	return e
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// stderrOf returns what a command run with Output printed to stderr
+func stderrOf(err error) string {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return string(exitErr.Stderr)
+	}
+	return ""
+}
+
+// ExitedError is returned when a service's container stopped right after
+// the stack started
+type ExitedError struct {
+	Service  string
+	ExitCode int
+}
+
+func (e *ExitedError) Error() string {
+	return fmt.Sprintf("%s exited with code %d", e.Service, e.ExitCode)
+}
+
+// UnhealthyError is returned when services didn't pass their health
+// checks in time
+type UnhealthyError struct {
+	Services []string
+	Timeout  time.Duration
+}
+
+func (e *UnhealthyError) Error() string {
+	return fmt.Sprintf("%s not healthy after %s", strings.Join(e.Services, ", "), e.Timeout)
+}
+
+// DigestMismatchError is returned when a pulled image doesn't have the
+// digest its reference pins
+type DigestMismatchError struct {
+	Service string
+	Ref     string
+
+	// Got are the repository digests the pulled image has
+	Got []string
+}
+
+func (e *DigestMismatchError) Error() string {
+	got := "none"
+	if len(e.Got) > 0 {
+		got = strings.Join(e.Got, ", ")
+	}
+	return fmt.Sprintf("pulled image for %s doesn't match %s (got %s)", e.Service, e.Ref, got)
+}
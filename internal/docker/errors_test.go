@@ -0,0 +1,47 @@
+package docker
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCommandErrorKind(t *testing.T) {
+	tests := []struct {
+		output string
+		want   error
+	}{
+		{"Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?", ErrDaemonUnreachable},
+		{"error during connect: Get \"http://devvm:2375/v1.45/containers/json\": dial tcp: lookup devvm: no such host", ErrDaemonUnreachable},
+		{"no configuration file provided: not found", ErrComposeFileInvalid},
+		{"yaml: line 12: mapping values are not allowed in this context", ErrComposeFileInvalid},
+		{"validating docker-compose.yml: services.kms Additional property imag is not allowed", ErrComposeFileInvalid},
+		{"Error response from daemon: manifest unknown", nil},
+	}
+	for _, tt := range tests {
+		err := commandError("docker compose up failed", &exec.ExitError{}, tt.output)
+		for _, kind := range []error{ErrComposeNotInstalled, ErrDaemonUnreachable, ErrComposeFileInvalid} {
+			if got := errors.Is(err, kind); got != (kind == tt.want) {
+				t.Errorf("%q: errors.Is(%v) = %t", tt.output, kind, got)
+			}
+		}
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			t.Errorf("%q: expected the exit error to stay reachable", tt.output)
+		}
+	}
+}
+
+func TestDaemonDownError(t *testing.T) {
+	cause := commandError("docker daemon not reachable", &exec.ExitError{}, "Cannot connect to the Docker daemon at unix:///var/run/docker.sock")
+	for _, runtime := range []string{"docker", "podman"} {
+		err := &DaemonDownError{Runtime: runtime, Err: cause}
+		if !errors.Is(err, ErrDaemonUnreachable) {
+			t.Errorf("%s: expected the error to be ErrDaemonUnreachable", runtime)
+		}
+		if !strings.Contains(err.Error(), "not running") || strings.Contains(err.Error(), "Cannot connect") {
+			t.Errorf("%s: expected one message saying the daemon isn't running, got %q", runtime, err)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+// Event is a lifecycle event of one of the stack's containers
+type Event struct {
+	Time time.Time
+
+	// Service is the compose service of the container, and Container its
+	// name
+	Service   string
+	Container string
+
+	// Action is start, restart, stop, kill, die, oom, or health_status
+	Action string
+
+	// ExitCode is the code a container died with, for die
+	ExitCode *int
+
+	// Signal is the signal a container was sent, for kill
+	Signal string
+
+	// Health is what the container's health check became, such as
+	// healthy or unhealthy, for health_status
+	Health string
+}
+
+// EventOptions select the events StreamEvents shows
+type EventOptions struct {
+	// Follow keeps streaming new events until the context is cancelled
+	Follow bool
+
+	// Since shows only events newer than a duration (10m) or timestamp.
+	// Without Follow it defaults to an hour ago, since there is nothing
+	// to show of no time at all.
+	Since string
+}
+
+// eventActions are the events shown; the runtimes report others, such as
+// exec_create or attach, that say nothing of the containers' lifecycle
+var eventActions = map[string]bool{
+	"start":         true,
+	"restart":       true,
+	"stop":          true,
+	"kill":          true,
+	"die":           true,
+	"oom":           true,
+	"health_status": true,
+}
+
+// StreamEvents calls fn with each lifecycle event of the containers of
+// cfg's compose project, in order, and of services only if any are
+// given. Cancelling ctx stops following and is not an error.
+func StreamEvents(ctx context.Context, cfg *config.Config, services []string, opts EventOptions, fn func(Event) error) error {
+	cmd := commandContext(ctx, cfg, eventsArgs(ProjectName(cfg), RuntimeName(cfg) == config.RuntimePodman, opts, time.Now())...)
+
+	var stderr bytes.Buffer
+	var fnErr error
+	lines := &lineWriter{line: func(line string) error {
+		event, ok, err := parseEvent([]byte(line))
+		if err != nil || !ok {
+			return err
+		}
+		if len(services) > 0 && !slices.Contains(services, event.Service) {
+			return nil
+		}
+		fnErr = fn(event)
+		return fnErr
+	}}
+	cmd.Stdout = lines
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if flushErr := lines.Flush(); err == nil {
+		err = flushErr
+	}
+	if fnErr != nil {
+		return fnErr
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		return commandError(failed(cfg, "events"), err, stderr.String())
+	}
+	return nil
+}
+
+// eventsArgs returns the events command of the runtime for the
+// containers of project, as of now. Without following, docker is given
+// --until and podman --stream=false, so they exit once past events are
+// shown.
+func eventsArgs(project string, podman bool, opts EventOptions, now time.Time) []string {
+	args := []string{"events", "--filter", "type=container", "--filter", projectFilter(project), "--format"}
+	if podman {
+		args = append(args, "json")
+	} else {
+		args = append(args, "{{json .}}")
+	}
+
+	since := opts.Since
+	if since == "" && !opts.Follow {
+		since = "1h"
+	}
+	if since != "" {
+		args = append(args, "--since", since)
+	}
+	if !opts.Follow {
+		if podman {
+			args = append(args, "--stream=false")
+		} else {
+			args = append(args, "--until", strconv.FormatInt(now.Unix(), 10))
+		}
+	}
+	return args
+}
+
+// rawEvent is an event as docker and podman print it. Docker names the
+// event Action, with the container's labels and details under Actor;
+// podman names it Status, with them at the top level.
+type rawEvent struct {
+	Action string
+	Status string
+
+	Actor struct {
+		Attributes map[string]string
+	}
+
+	// Podman's fields
+	Name              string
+	Attributes        map[string]string
+	ContainerExitCode *int
+	HealthStatus      string
+
+	// Time is in seconds since the epoch from docker and newer podman,
+	// and a timestamp from older podman; TimeNano is docker's, in
+	// nanoseconds
+	Time     json.RawMessage
+	TimeNano int64
+}
+
+// parseEvent parses a line of the runtime's events in JSON, reporting
+// whether it is a lifecycle event of one of the stack's services
+func parseEvent(line []byte) (Event, bool, error) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return Event{}, false, nil
+	}
+	var raw rawEvent
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return Event{}, false, fmt.Errorf("failed to parse event: %w", err)
+	}
+
+	attributes := raw.Actor.Attributes
+	if attributes == nil {
+		attributes = raw.Attributes
+	}
+	event := Event{
+		Time:      raw.eventTime(),
+		Service:   attributes[serviceLabelKey],
+		Container: attributes["name"],
+		Signal:    attributes["signal"],
+	}
+	if event.Container == "" {
+		event.Container = raw.Name
+	}
+
+	// Docker's health events are "health_status: healthy"
+	action := raw.Action
+	if action == "" {
+		action = raw.Status
+	}
+	action, health, _ := strings.Cut(action, ":")
+	event.Action = strings.TrimSpace(action)
+	event.Health = strings.TrimSpace(health)
+	if event.Health == "" {
+		event.Health = raw.HealthStatus
+	}
+	// Podman's die is died
+	if event.Action == "died" {
+		event.Action = "die"
+	}
+
+	if event.Action == "die" {
+		event.ExitCode = raw.ContainerExitCode
+		if code, err := strconv.Atoi(attributes["exitCode"]); err == nil {
+			event.ExitCode = &code
+		}
+	}
+	return event, eventActions[event.Action] && event.Service != "", nil
+}
+
+// eventTime returns when an event happened, from whichever time the
+// runtime gave
+func (raw rawEvent) eventTime() time.Time {
+	if raw.TimeNano != 0 {
+		return time.Unix(0, raw.TimeNano)
+	}
+	var seconds int64
+	if err := json.Unmarshal(raw.Time, &seconds); err == nil {
+		return time.Unix(seconds, 0)
+	}
+	var timestamp time.Time
+	if err := json.Unmarshal(raw.Time, &timestamp); err == nil {
+		return timestamp
+	}
+	return time.Time{}
+}
@@ -0,0 +1,95 @@
+package docker
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseEvent(t *testing.T) {
+	tests := []struct {
+		name, line string
+		want       Event
+		exitCode   int
+		ok         bool
+	}{
+		{
+			name:     "docker die",
+			line:     `{"status":"die","id":"4f1c","from":"ghcr.io/acme/kms:latest","Type":"container","Action":"die","Actor":{"ID":"4f1c","Attributes":{"com.docker.compose.project":"gcp-emulator","com.docker.compose.service":"kms","exitCode":"137","name":"gcp-emulator-kms-1"}},"scope":"local","time":1792000801,"timeNano":1792000801000000000}`,
+			want:     Event{Time: time.Unix(1792000801, 0), Service: "kms", Container: "gcp-emulator-kms-1", Action: "die"},
+			exitCode: 137,
+			ok:       true,
+		},
+		{
+			name: "docker health",
+			line: `{"Type":"container","Action":"health_status: unhealthy","Actor":{"Attributes":{"com.docker.compose.service":"iam","name":"gcp-emulator-iam-1"}},"time":1792000801}`,
+			want: Event{Time: time.Unix(1792000801, 0), Service: "iam", Container: "gcp-emulator-iam-1", Action: "health_status", Health: "unhealthy"},
+			ok:   true,
+		},
+		{
+			name:     "podman died",
+			line:     `{"ID":"9a02","Image":"ghcr.io/acme/kms:latest","Name":"gcp-emulator_kms_1","Status":"died","Time":"2026-10-14T10:00:01Z","Type":"container","Attributes":{"com.docker.compose.service":"kms"},"ContainerExitCode":1}`,
+			want:     Event{Time: time.Date(2026, 10, 14, 10, 0, 1, 0, time.UTC), Service: "kms", Container: "gcp-emulator_kms_1", Action: "die"},
+			exitCode: 1,
+			ok:       true,
+		},
+		{
+			name: "docker kill",
+			line: `{"Action":"kill","Actor":{"Attributes":{"com.docker.compose.service":"secret-manager","name":"gcp-emulator-secret-manager-1","signal":"9"}},"time":1792000801}`,
+			want: Event{Time: time.Unix(1792000801, 0), Service: "secret-manager", Container: "gcp-emulator-secret-manager-1", Action: "kill", Signal: "9"},
+			ok:   true,
+		},
+		{
+			name: "not lifecycle",
+			line: `{"Action":"exec_create: sh","Actor":{"Attributes":{"com.docker.compose.service":"iam"}},"time":1792000801}`,
+			ok:   false,
+		},
+	}
+	for _, tt := range tests {
+		event, ok, err := parseEvent([]byte(tt.line))
+		if err != nil {
+			t.Fatalf("%s: parseEvent failed: %v", tt.name, err)
+		}
+		if ok != tt.ok {
+			t.Errorf("%s: ok = %v, want %v", tt.name, ok, tt.ok)
+		}
+		if !ok {
+			continue
+		}
+		exitCode := event.ExitCode
+		event.ExitCode = nil
+		if !event.Time.Equal(tt.want.Time) {
+			t.Errorf("%s: Time = %v, want %v", tt.name, event.Time, tt.want.Time)
+		}
+		event.Time = tt.want.Time
+		if event != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, event, tt.want)
+		}
+		if tt.exitCode != 0 && (exitCode == nil || *exitCode != tt.exitCode) {
+			t.Errorf("%s: ExitCode = %v, want %d", tt.name, exitCode, tt.exitCode)
+		}
+	}
+}
+
+func TestEventsArgs(t *testing.T) {
+	now := time.Unix(1792000801, 0)
+	filters := []string{"events", "--filter", "type=container", "--filter", "label=com.docker.compose.project=gcp-emulator"}
+
+	got := eventsArgs("gcp-emulator", false, EventOptions{}, now)
+	want := append(slices.Clone(filters), "--format", "{{json .}}", "--since", "1h", "--until", "1792000801")
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	got = eventsArgs("gcp-emulator", false, EventOptions{Follow: true}, now)
+	want = append(slices.Clone(filters), "--format", "{{json .}}")
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected following to show only new events, got %v", got)
+	}
+
+	got = eventsArgs("gcp-emulator", true, EventOptions{Since: "10m"}, now)
+	want = append(slices.Clone(filters), "--format", "json", "--since", "10m", "--stream=false")
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,132 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+// LogOptions select the log lines StreamLogs shows
+type LogOptions struct {
+	// Follow keeps streaming new lines until the context is cancelled
+	Follow bool
+
+	// Since shows only lines newer than a duration (10m) or timestamp
+	Since string
+
+	// Tail is how many of the last lines of each service to show; zero
+	// shows all
+	Tail int
+
+	// Grep, if set, shows only the lines it matches
+	Grep *regexp.Regexp
+}
+
+// serviceColors tell the services apart in the prefixes of StreamLogs.
+// They follow color.NoColor like the rest of the output.
+var serviceColors = map[string]*color.Color{
+	"iam":            color.New(color.FgCyan),
+	"secret-manager": color.New(color.FgMagenta),
+	"kms":            color.New(color.FgYellow),
+}
+
+// StreamLogs writes the logs of services, or of the whole stack if none
+// are given, to out, each line prefixed with its service. Cancelling ctx
+// stops following and is not an error.
+func StreamLogs(ctx context.Context, cfg *config.Config, services []string, opts LogOptions, out io.Writer) error {
+	cmd, err := composeCommand(ctx, cfg, ActivePorts(cfg), append(logsArgs(opts), services...)...)
+	if err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+	lines := &lineWriter{line: logLines(out, ProjectName(cfg), opts.Grep)}
+	cmd.Stdout = lines
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if flushErr := lines.Flush(); err == nil {
+		err = flushErr
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		return commandError(composeFailed(cfg, "logs"), err, stderr.String())
+	}
+	return nil
+}
+
+// logsArgs returns the compose logs command for opts. Compose's own
+// colors are turned off, since the prefixes are colored here.
+func logsArgs(opts LogOptions) []string {
+	args := []string{"logs", "--no-color"}
+	if opts.Follow {
+		args = append(args, "--follow")
+	}
+	if opts.Tail > 0 {
+		args = append(args, "--tail", strconv.Itoa(opts.Tail))
+	}
+	if opts.Since != "" {
+		args = append(args, "--since", opts.Since)
+	}
+	return args
+}
+
+// logLines writes compose log lines to out with the service as a colored
+// prefix, leaving out lines grep doesn't match
+func logLines(out io.Writer, project string, grep *regexp.Regexp) func(string) error {
+	return func(line string) error {
+		line = strings.TrimRight(line, "\r")
+
+		// Compose prefixes each line with the container: "iam-1  | msg"
+		service := ""
+		if name, msg, ok := strings.Cut(line, "|"); ok {
+			if service = containerService(project, strings.TrimSpace(name)); service != "" {
+				line = strings.TrimPrefix(msg, " ")
+			}
+		}
+		if grep != nil && !grep.MatchString(line) {
+			return nil
+		}
+
+		if service == "" {
+			_, err := fmt.Fprintln(out, line)
+			return err
+		}
+		prefix := fmt.Sprintf("%-14s |", service)
+		if c, ok := serviceColors[service]; ok {
+			prefix = c.Sprint(prefix)
+		}
+		_, err := fmt.Fprintf(out, "%s %s\n", prefix, line)
+		return err
+	}
+}
+
+// containerService returns the service of a container name as compose
+// logs prints it: <service>-1, <project>-<service>-1, or, for
+// docker-compose v1, <project>_<service>_1. It returns "" for other names.
+func containerService(project, name string) string {
+	if i := strings.LastIndexAny(name, "-_"); i >= 0 {
+		if _, err := strconv.Atoi(name[i+1:]); err == nil {
+			name = name[:i]
+		}
+	}
+	for _, sep := range []string{"-", "_"} {
+		name = strings.TrimPrefix(name, project+sep)
+	}
+	for _, service := range Services {
+		if name == service {
+			return service
+		}
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package docker
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestLogLines(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	t.Cleanup(func() { color.NoColor = noColor })
+
+	var out strings.Builder
+	w := &lineWriter{line: logLines(&out, "gcp-emulator", regexp.MustCompile(`DENY|started`))}
+	input := "iam-1             | 2026/10/14 DENY user:a@example.com kms.keys.get\r\n" +
+		"kms-1             | started on :9090\n" +
+		"secret-manager-1  | request ok\n" +
+		"gcp-emulator_iam_1 | DENY user:b@example.com\n" +
+		"started without a prefix"
+	if _, err := w.Write([]byte(input)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "iam            | 2026/10/14 DENY user:a@example.com kms.keys.get\n" +
+		"kms            | started on :9090\n" +
+		"iam            | DENY user:b@example.com\n" +
+		"started without a prefix\n"
+	if out.String() != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func TestContainerService(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"iam-1", "iam"},
+		{"secret-manager-2", "secret-manager"},
+		{"gcp-emulator-kms-1", "kms"},
+		{"gcp-emulator_kms_1", "kms"},
+		{"kms", "kms"},
+		{"redis-1", ""},
+	}
+	for _, tt := range tests {
+		if got := containerService("gcp-emulator", tt.name); got != tt.want {
+			t.Errorf("containerService(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLogsArgs(t *testing.T) {
+	got := strings.Join(logsArgs(LogOptions{Follow: true, Since: "10m", Tail: 200}), " ")
+	if got != "logs --no-color --follow --tail 200 --since 10m" {
+		t.Errorf("Unexpected args: %s", got)
+	}
+	if got := strings.Join(logsArgs(LogOptions{}), " "); got != "logs --no-color" {
+		t.Errorf("Expected all lines without --tail, got %s", got)
+	}
+}
@@ -0,0 +1,80 @@
+package docker
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// lineWriter passes command output on a line at a time to line, without
+// the line's newline
+type lineWriter struct {
+	line func(line string) error
+	buf  []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := string(w.buf[:i])
+		w.buf = w.buf[i+1:]
+		if err := w.line(line); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// Flush passes on what is left of a last line without a newline
+func (w *lineWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	line := string(w.buf)
+	w.buf = nil
+	return w.line(line)
+}
+
+// composeLines writes compose progress output to out, prefixing each line
+// with the service it is about when that can be told
+func composeLines(out io.Writer, project string) func(string) error {
+	return func(line string) error {
+		// Progress output redraws lines with carriage returns; keep the last
+		if i := strings.LastIndexByte(strings.TrimRight(line, "\r"), '\r'); i >= 0 {
+			line = line[i+1:]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			return nil
+		}
+
+		var err error
+		if service := lineService(project, line); service != "" {
+			_, err = fmt.Fprintf(out, "  %-14s | %s\n", service, line)
+		} else {
+			_, err = fmt.Fprintf(out, "  %s\n", line)
+		}
+		return err
+	}
+}
+
+// lineService returns the service a line of compose output is about: a
+// word that is the service name, or the name of one of its containers
+// (<project>-<service>-1, or <project>_<service>_1 for docker-compose v1).
+// It returns "" for lines about the whole stack, such as its network.
+func lineService(project, line string) string {
+	for _, word := range strings.Fields(line) {
+		for _, service := range Services {
+			if word == service ||
+				strings.HasPrefix(word, project+"-"+service+"-") ||
+				strings.HasPrefix(word, project+"_"+service+"_") {
+				return service
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,51 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLineWriter(t *testing.T) {
+	var out strings.Builder
+	w := &lineWriter{line: composeLines(&out, "gcp-emulator-ci")}
+
+	// Written in pieces that split lines, as the pipe delivers them
+	for _, chunk := range []string{
+		" Network gcp-emulator-ci_default  Creating\n Container gcp-emu",
+		"lator-ci-iam-1  Starting\r Container gcp-emulator-ci-iam-1  Started\n",
+		"\n secret-manager Pulled\n Container gcp-emulator-ci-kms-1  Started",
+	} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "  Network gcp-emulator-ci_default  Creating\n" +
+		"  iam            | Container gcp-emulator-ci-iam-1  Started\n" +
+		"  secret-manager | secret-manager Pulled\n" +
+		"  kms            | Container gcp-emulator-ci-kms-1  Started\n"
+	if out.String() != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func TestLineService(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"Container gcp-emulator-secret-manager-1  Started", "secret-manager"},
+		{"Creating gcp-emulator_kms_1 ... done", "kms"},
+		{"iam Pulling", "iam"},
+		{"Container other-iam-1  Started", ""},
+		{"Network gcp-emulator_default  Created", ""},
+	}
+	for _, tt := range tests {
+		if got := lineService("gcp-emulator", tt.line); got != tt.want {
+			t.Errorf("lineService(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+// composeServices is the part of a compose file overrides are checked
+// against
+type composeServices struct {
+	Services map[string]map[string]any
+}
+
+// ComposeFiles returns the compose files to run cfg's stack with, as -f
+// arguments in order: ComposeFile, then the compose-overrides files. Each
+// override may only change services that exist, in the base file or as
+// new services of an override, and only refer to those; otherwise a
+// *CommandError of kind ErrComposeFileInvalid is returned before compose
+// is run.
+func ComposeFiles(cfg *config.Config, ports Ports) ([]string, error) {
+	base, err := ComposeFile(cfg, ports)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{base}
+	if len(cfg.Docker.ComposeOverrides) == 0 {
+		return files, nil
+	}
+
+	file, err := readComposeServices(base, "compose file")
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]bool{}
+	for service := range file.Services {
+		known[service] = true
+	}
+
+	// Services an override defines, with an image or a build of their
+	// own, may be referred to by any of them
+	overrides := make([]*composeServices, len(cfg.Docker.ComposeOverrides))
+	for i, path := range cfg.Docker.ComposeOverrides {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		if overrides[i], err = readComposeServices(path, "compose override"); err != nil {
+			return nil, err
+		}
+		for name, service := range overrides[i].Services {
+			if defined(service) {
+				known[name] = true
+			}
+		}
+		files = append(files, abs)
+	}
+
+	for i, override := range overrides {
+		invalid := func(format string, args ...any) error {
+			return &CommandError{
+				Msg:  "compose override " + cfg.Docker.ComposeOverrides[i] + " is invalid",
+				Err:  fmt.Errorf(format, args...),
+				Kind: ErrComposeFileInvalid,
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(override.Services)) {
+			if !known[name] {
+				return nil, invalid("service %s doesn't exist (services: %s)", name, strings.Join(slices.Sorted(maps.Keys(known)), ", "))
+			}
+			for _, ref := range serviceRefs(override.Services[name]) {
+				if !known[ref] {
+					return nil, invalid("service %s refers to %s, which doesn't exist", name, ref)
+				}
+			}
+		}
+	}
+	return files, nil
+}
+
+// readComposeServices reads the services of the compose file at path,
+// described as what for errors
+func readComposeServices(path, what string) (*composeServices, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &CommandError{Msg: what + " " + path + " not readable", Err: err, Kind: ErrComposeFileInvalid}
+	}
+	var file composeServices
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, &CommandError{Msg: what + " " + path + " is invalid", Err: err, Kind: ErrComposeFileInvalid}
+	}
+	return &file, nil
+}
+
+// defined reports whether service is a service of its own rather than a
+// change to one defined elsewhere
+func defined(service map[string]any) bool {
+	for _, key := range []string{"image", "build", "extends"} {
+		if _, ok := service[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceRefs returns the services service refers to, in depends_on,
+// links, volumes_from, and a service: network_mode
+func serviceRefs(service map[string]any) []string {
+	refs := map[string]bool{}
+	switch deps := service["depends_on"].(type) {
+	case []any:
+		for _, dep := range deps {
+			if name, ok := dep.(string); ok {
+				refs[name] = true
+			}
+		}
+	case map[string]any:
+		for name := range deps {
+			refs[name] = true
+		}
+	}
+	for _, key := range []string{"links", "volumes_from"} {
+		values, _ := service[key].([]any)
+		for _, value := range values {
+			s, ok := value.(string)
+			if !ok || strings.HasPrefix(s, "container:") {
+				continue
+			}
+			// service, service:alias, or service:ro
+			name, _, _ := strings.Cut(s, ":")
+			refs[name] = true
+		}
+	}
+	if mode, ok := service["network_mode"].(string); ok {
+		if name, ok := strings.CutPrefix(mode, "service:"); ok {
+			refs[name] = true
+		}
+	}
+	return slices.Sorted(maps.Keys(refs))
+}
+
+// RenderMerged returns the effective compose config of cfg's stack, with
+// the overrides merged in as compose merges them, from docker compose
+// config
+func RenderMerged(cfg *config.Config, ports Ports) ([]byte, error) {
+	cmd, err := composeCommand(context.Background(), cfg, ports, "config")
+	if err != nil {
+		return nil, err
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, commandError(composeFailed(cfg, "config"), err, stderrOf(err))
+	}
+	return output, nil
+}
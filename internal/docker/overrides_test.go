@@ -0,0 +1,59 @@
+package docker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+func TestComposeFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_STATE_HOME", "")
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	write := func(name, content string) string {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+	sidecar := write("metadata.yaml", "services:\n  metadata:\n    image: fake-metadata\n  iam:\n    depends_on: [metadata]\n    volumes: [./creds:/creds]\n")
+	uses := write("uses.yaml", "services:\n  kms:\n    network_mode: service:metadata\n    links: [\"metadata:md\"]\n")
+
+	cfg := config.Defaults()
+	cfg.Docker.Project = "ci"
+	cfg.Docker.ComposeOverrides = []string{uses, sidecar}
+	files, err := ComposeFiles(cfg, ConfiguredPorts(cfg))
+	if err != nil {
+		t.Fatalf("ComposeFiles failed: %v", err)
+	}
+	if len(files) != 3 || filepath.Base(files[0]) != "ci.yaml" || files[1] != filepath.Join(dir, uses) || files[2] != filepath.Join(dir, sidecar) {
+		t.Errorf("Expected the base file, then the overrides by absolute path, got %v", files)
+	}
+
+	tests := []struct {
+		name, content, want string
+	}{
+		{"typo", "services:\n  secretmanager:\n    environment: [DEBUG=1]\n", "service secretmanager doesn't exist (services: iam, kms, metadata, secret-manager)"},
+		{"depends_on", "services:\n  kms:\n    depends_on:\n      vault:\n        condition: service_started\n", "service kms refers to vault, which doesn't exist"},
+		{"volumes_from", "services:\n  kms:\n    volumes_from: [\"vault:ro\", \"container:other\"]\n", "service kms refers to vault, which doesn't exist"},
+		{"yaml", "services: [", "compose override bad.yaml is invalid"},
+	}
+	for _, tt := range tests {
+		cfg.Docker.ComposeOverrides = []string{sidecar, write("bad.yaml", tt.content)}
+		_, err := ComposeFiles(cfg, ConfiguredPorts(cfg))
+		if !errors.Is(err, ErrComposeFileInvalid) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	cfg.Docker.ComposeOverrides = []string{"missing.yaml"}
+	if _, err := ComposeFiles(cfg, ConfiguredPorts(cfg)); !errors.Is(err, ErrComposeFileInvalid) {
+		t.Errorf("Expected a missing override to fail with ErrComposeFileInvalid, got %v", err)
+	}
+}
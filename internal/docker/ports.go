@@ -0,0 +1,271 @@
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+// Ports holds the host port of everything the stack publishes. The IAM
+// emulator also serves its admin and health API on IAM+1000.
+type Ports struct {
+	IAM               int `json:"iam"`
+	SecretManager     int `json:"secretManager"`
+	SecretManagerHTTP int `json:"secretManagerHttp"`
+	KMS               int `json:"kms"`
+	KMSHTTP           int `json:"kmsHttp"`
+}
+
+// IAMHTTP returns the port of the IAM emulator's admin and health API
+func (p Ports) IAMHTTP() int {
+	return p.IAM + config.IAMHTTPOffset
+}
+
+// portBinding is one published host port, named for error messages
+type portBinding struct {
+	name string
+	port int
+}
+
+func (p Ports) bindings() []portBinding {
+	return []portBinding{
+		{"IAM", p.IAM},
+		{"IAM HTTP", p.IAMHTTP()},
+		{"Secret Manager gRPC", p.SecretManager},
+		{"Secret Manager HTTP", p.SecretManagerHTTP},
+		{"KMS gRPC", p.KMS},
+		{"KMS HTTP", p.KMSHTTP},
+	}
+}
+
+// ConfiguredPorts returns the ports set in cfg, using the
+// docker-compose.yml defaults for HTTP ports that aren't set
+func ConfiguredPorts(cfg *config.Config) Ports {
+	p := Ports{
+		IAM:               cfg.Ports.IAM,
+		SecretManager:     cfg.Ports.SecretManager,
+		SecretManagerHTTP: cfg.Ports.SecretManagerHTTP,
+		KMS:               cfg.Ports.KMS,
+		KMSHTTP:           cfg.Ports.KMSHTTP,
+	}
+	if p.SecretManagerHTTP == 0 {
+		p.SecretManagerHTTP = 8081
+	}
+	if p.KMSHTTP == 0 {
+		p.KMSHTTP = 8082
+	}
+	return p
+}
+
+// PortInUseError is returned by CheckPorts for a port something else is
+// listening on
+type PortInUseError struct {
+	Port int
+	Name string
+
+	// Owner describes the listening process, or is "" if it couldn't be
+	// found out
+	Owner string
+}
+
+func (e *PortInUseError) Error() string {
+	msg := fmt.Sprintf("port %d (%s) is already in use", e.Port, e.Name)
+	if e.Owner != "" {
+		msg += " by " + e.Owner
+	}
+	return msg
+}
+
+// CheckPorts checks that every port can be listened on, so a conflict is
+// reported before docker compose fails with a bind error. It returns a
+// *PortInUseError for each port that is taken.
+func CheckPorts(p Ports) error {
+	var errs []error
+	for _, b := range p.bindings() {
+		l, err := net.Listen("tcp", fmt.Sprintf(":%d", b.port))
+		if err != nil {
+			errs = append(errs, &PortInUseError{Port: b.port, Name: b.name, Owner: portOwner(b.port)})
+			continue
+		}
+		l.Close()
+	}
+	return errors.Join(errs...)
+}
+
+// portOwner describes the process listening on port, such as
+// "nginx (pid 4242)", or returns "" if lsof is unavailable or finds none
+func portOwner(port int) string {
+	out, err := exec.Command("lsof", "-nP", fmt.Sprintf("-iTCP:%d", port), "-sTCP:LISTEN", "-Fpc").Output()
+	if err != nil {
+		return ""
+	}
+
+	var pid, command string
+	for _, line := range strings.Split(string(out), "\n") {
+		if v, ok := strings.CutPrefix(line, "p"); ok && pid == "" {
+			pid = v
+		} else if v, ok := strings.CutPrefix(line, "c"); ok && command == "" {
+			command = v
+		}
+	}
+	if pid == "" {
+		return ""
+	}
+	if command == "" {
+		return "pid " + pid
+	}
+	return fmt.Sprintf("%s (pid %s)", command, pid)
+}
+
+// FreePorts picks free ephemeral ports for the whole stack, keeping the
+// IAM port's HTTP port (IAM+1000) free as well
+func FreePorts() (Ports, error) {
+	var listeners []net.Listener
+	defer func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}()
+
+	// Hold each port open until all are picked, so none is picked twice
+	listen := func(port int) (int, error) {
+		l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+		if err != nil {
+			return 0, err
+		}
+		listeners = append(listeners, l)
+		return l.Addr().(*net.TCPAddr).Port, nil
+	}
+	free := func() (int, error) {
+		port, err := listen(0)
+		if err != nil {
+			return 0, fmt.Errorf("failed to find a free port: %w", err)
+		}
+		return port, nil
+	}
+
+	var p Ports
+	for attempt := 0; p.IAM == 0; attempt++ {
+		if attempt == 20 {
+			return Ports{}, errors.New("failed to find a free IAM port with a free port 1000 above it")
+		}
+		port, err := free()
+		if err != nil {
+			return Ports{}, err
+		}
+		if port+1000 > 65535 {
+			continue
+		}
+		if _, err := listen(port + 1000); err == nil {
+			p.IAM = port
+		}
+	}
+
+	for _, dst := range []*int{&p.SecretManager, &p.SecretManagerHTTP, &p.KMS, &p.KMSHTTP} {
+		port, err := free()
+		if err != nil {
+			return Ports{}, err
+		}
+		*dst = port
+	}
+	return p, nil
+}
+
+// portsStatePath returns the file recording the ports each stack was
+// started on: one for the default stack, keyed by project, and one in
+// each named stack's state directory
+func portsStatePath(cfg *config.Config) (string, error) {
+	return cfg.StatePath("ports.json")
+}
+
+// loadPortsState reads the recorded ports of every stack in cfg's file
+func loadPortsState(cfg *config.Config) (map[string]Ports, error) {
+	path, err := portsStatePath(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	state := make(map[string]Ports)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ports state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse ports state %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// savePortsState writes the recorded ports of every stack in cfg's file
+func savePortsState(cfg *config.Config, state map[string]Ports) error {
+	path, err := portsStatePath(cfg)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal ports state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write ports state: %w", err)
+	}
+	return nil
+}
+
+// RecordPorts records the ports the config's stack was started on
+func RecordPorts(cfg *config.Config, p Ports) error {
+	state, err := loadPortsState(cfg)
+	if err != nil {
+		return err
+	}
+	state[ProjectName(cfg)] = p
+	return savePortsState(cfg, state)
+}
+
+// ForgetPorts removes the record of the config's stack, once it is stopped
+func ForgetPorts(cfg *config.Config) error {
+	state, err := loadPortsState(cfg)
+	if err != nil {
+		return err
+	}
+	if _, ok := state[ProjectName(cfg)]; !ok {
+		return nil
+	}
+	delete(state, ProjectName(cfg))
+	return savePortsState(cfg, state)
+}
+
+// RecordedPorts returns the ports the config's stack was last started on,
+// and whether any are recorded
+func RecordedPorts(cfg *config.Config) (Ports, bool, error) {
+	state, err := loadPortsState(cfg)
+	if err != nil {
+		return Ports{}, false, err
+	}
+	p, ok := state[ProjectName(cfg)]
+	return p, ok, nil
+}
+
+// ActivePorts returns the ports the config's stack is running on: those
+// recorded by start, which differ from the config with automatic ports,
+// or the configured ports if none are recorded
+func ActivePorts(cfg *config.Config) Ports {
+	if p, ok, err := RecordedPorts(cfg); err == nil && ok {
+		return p
+	}
+	return ConfiguredPorts(cfg)
+}
@@ -0,0 +1,104 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+func TestCheckPorts(t *testing.T) {
+	ports, err := FreePorts()
+	if err != nil {
+		t.Fatalf("FreePorts failed: %v", err)
+	}
+	if err := CheckPorts(ports); err != nil {
+		t.Fatalf("Expected free ports to pass, got %v", err)
+	}
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", ports.KMS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	err = CheckPorts(ports)
+	var inUse *PortInUseError
+	if !errors.As(err, &inUse) {
+		t.Fatalf("Expected a PortInUseError, got %v", err)
+	}
+	if inUse.Port != ports.KMS || inUse.Name != "KMS gRPC" {
+		t.Errorf("Expected the KMS gRPC port to be reported, got %+v", inUse)
+	}
+}
+
+func TestFreePorts(t *testing.T) {
+	ports, err := FreePorts()
+	if err != nil {
+		t.Fatalf("FreePorts failed: %v", err)
+	}
+
+	seen := make(map[int]bool)
+	for _, b := range ports.bindings() {
+		if b.port <= 0 || b.port > 65535 {
+			t.Errorf("Invalid %s port %d", b.name, b.port)
+		}
+		if seen[b.port] {
+			t.Errorf("Port %d picked twice", b.port)
+		}
+		seen[b.port] = true
+	}
+}
+
+func TestRecordedPorts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_STATE_HOME", "")
+
+	cfg := config.Defaults()
+	if got := ActivePorts(cfg); got != ConfiguredPorts(cfg) {
+		t.Errorf("Expected the configured ports without a record, got %+v", got)
+	}
+
+	auto := Ports{IAM: 40000, SecretManager: 40001, SecretManagerHTTP: 40002, KMS: 40003, KMSHTTP: 40004}
+	if err := RecordPorts(cfg, auto); err != nil {
+		t.Fatalf("RecordPorts failed: %v", err)
+	}
+	if got := ActivePorts(cfg); got != auto {
+		t.Errorf("Expected the recorded ports, got %+v", got)
+	}
+
+	staging := config.Defaults()
+	staging.Profile = "staging"
+	if got := ActivePorts(staging); got != ConfiguredPorts(staging) {
+		t.Errorf("Expected records to be kept per profile, got %+v", got)
+	}
+
+	tenant := config.Defaults()
+	tenant.Stack = "tenant-a"
+	if got := ActivePorts(tenant); got != ConfiguredPorts(tenant) {
+		t.Errorf("Expected records to be kept per stack, got %+v", got)
+	}
+	if err := RecordPorts(tenant, Ports{IAM: 41000}); err != nil {
+		t.Fatalf("RecordPorts failed: %v", err)
+	}
+	if path, _ := tenant.StatePath("ports.json"); !strings.Contains(path, filepath.Join("stacks", "tenant-a")) {
+		t.Errorf("Expected the stack's record in its state directory, got %s", path)
+	} else if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected the stack's record at %s, got %v", path, err)
+	}
+	if got := ActivePorts(cfg); got != auto {
+		t.Errorf("Expected the default stack's record to be kept, got %+v", got)
+	}
+
+	if err := ForgetPorts(cfg); err != nil {
+		t.Fatalf("ForgetPorts failed: %v", err)
+	}
+	if _, ok, _ := RecordedPorts(cfg); ok {
+		t.Error("Expected the record to be removed")
+	}
+}
@@ -0,0 +1,101 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+// DaemonVersion returns the version of the docker daemon, or podman
+// service, cfg targets, failing if the CLI is missing or the daemon can't
+// be reached
+func DaemonVersion(cfg *config.Config) (string, error) {
+	output, err := versionCommand(context.Background(), cfg).CombinedOutput()
+	if err != nil {
+		return "", &CommandError{Msg: RuntimeName(cfg) + " daemon not reachable", Err: err, Output: string(output), Kind: ErrDaemonUnreachable}
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// versionCommand returns the command asking the daemon, or podman service,
+// for its version, which fails unless it can be reached
+func versionCommand(ctx context.Context, cfg *config.Config) *exec.Cmd {
+	if RuntimeName(cfg) == config.RuntimePodman {
+		// podman version succeeds without a service; info needs one
+		return commandContext(ctx, cfg, "info", "--format", "{{.Version.Version}}")
+	}
+	return commandContext(ctx, cfg, "version", "--format", "{{.Server.Version}}")
+}
+
+// pingTimeout is how long Ping waits for the daemon, which a Docker
+// Desktop that is still starting leaves hanging
+const pingTimeout = 10 * time.Second
+
+// Ping checks that the docker daemon, or podman service, cfg targets is
+// running, returning a *DaemonDownError if it isn't or doesn't answer in
+// time. Other failures, such as a missing CLI, are left to the command run
+// next to report.
+func Ping(cfg *config.Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+
+	output, err := versionCommand(ctx, cfg).CombinedOutput()
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		err = fmt.Errorf("no answer after %s", pingTimeout)
+	case !containsAny(string(output), daemonErrors):
+		return nil
+	}
+	return &DaemonDownError{Runtime: RuntimeName(cfg), Err: commandError(RuntimeName(cfg)+" daemon not reachable", err, string(output))}
+}
+
+// ComposeVersion returns the compose command cfg's stack runs with, such
+// as "docker compose" or "podman-compose", and its version
+func ComposeVersion(cfg *config.Config) (command, version string, err error) {
+	runtime, err := DetectRuntime(cfg)
+	if err != nil {
+		return "", "", err
+	}
+	args := append(append([]string{}, runtime.Compose[1:]...), "version")
+	if !runtime.Podman() {
+		args = append(args, "--short")
+	}
+
+	cmd := exec.Command(runtime.Compose[0], args...)
+	logCommand(cmd, nil)
+	output, err := cmd.CombinedOutput()
+	command = runtime.ComposeName()
+	if err != nil {
+		return command, "", &CommandError{Msg: command + " is not available", Err: err}
+	}
+	return command, composeVersion(string(output)), nil
+}
+
+// composeVersion returns the version in the output of a compose version
+// command: all of it for docker compose --short, the last line naming a
+// version for podman-compose, which also reports podman's
+func composeVersion(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if _, version, ok := strings.Cut(lines[i], " version "); ok {
+			return strings.TrimSpace(version)
+		}
+	}
+	return strings.TrimSpace(output)
+}
+
+// ImagePullable checks that the registry serves the image, without
+// pulling it
+func ImagePullable(cfg *config.Config, ref string) error {
+	output, err := command(cfg, "manifest", "inspect", ref).CombinedOutput()
+	if err != nil {
+		return &CommandError{Msg: fmt.Sprintf("image %s is not pullable", ref), Err: err, Output: string(output)}
+	}
+	return nil
+}
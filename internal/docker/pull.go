@@ -0,0 +1,107 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+// ErrImageNotPresent is returned when start may not pull an image that
+// isn't in the local image store
+var ErrImageNotPresent = errors.New("image not present locally")
+
+// ServiceImage returns the image cfg's stack runs for service
+func ServiceImage(cfg *config.Config, service string) string {
+	images := Images(cfg)
+	switch service {
+	case "iam":
+		return images.IAM
+	case "secret-manager":
+		return images.SecretManager
+	case "kms":
+		return images.KMS
+	}
+	return ""
+}
+
+// ImagePresent reports whether the image ref is in the local image store
+func ImagePresent(cfg *config.Config, ref string) (bool, error) {
+	cmd := command(cfg, "image", "inspect", "--format", "{{.Id}}", ref)
+
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return true, nil
+	}
+	if containsAny(string(output), []string{"No such image", "No such object", "image not known"}) {
+		return false, nil
+	}
+	return false, commandError(failed(cfg, "image inspect"), err, string(output))
+}
+
+// PullImage pulls the image of service, writing the runtime's progress to out
+// a line at a time prefixed with the service. A digest pinned in ref is
+// checked against the pulled image.
+func PullImage(cfg *config.Config, service string, out io.Writer) error {
+	ref := ServiceImage(cfg, service)
+
+	var stderr bytes.Buffer
+	lines := &lineWriter{line: func(line string) error {
+		if line = strings.TrimSpace(line); line == "" {
+			return nil
+		}
+		_, err := fmt.Fprintf(out, "  %-14s | %s\n", service, line)
+		return err
+	}}
+	cmd := command(cfg, "pull", ref)
+	cmd.Stdout = lines
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if flushErr := lines.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		return commandError(failed(cfg, "pull "+ref), err, stderr.String())
+	}
+
+	if _, digest, pinned := strings.Cut(ref, "@"); pinned {
+		digests, err := RepoDigests(cfg, ref)
+		if err != nil {
+			return err
+		}
+		if !digestMatches(digests, digest) {
+			return &DigestMismatchError{Service: service, Ref: ref, Got: digests}
+		}
+	}
+	return nil
+}
+
+// RepoDigests returns the repository digests of a local image, as
+// repository@sha256:...
+func RepoDigests(cfg *config.Config, ref string) ([]string, error) {
+	cmd := command(cfg, "image", "inspect", "--format", "{{json .RepoDigests}}", ref)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, commandError(failed(cfg, "image inspect"), err, stderrOf(err))
+	}
+	var digests []string
+	if err := json.Unmarshal(output, &digests); err != nil {
+		return nil, fmt.Errorf("failed to parse digests of %s: %w", ref, err)
+	}
+	return digests, nil
+}
+
+// digestMatches reports whether one of the repository digests is digest
+func digestMatches(repoDigests []string, digest string) bool {
+	return slices.ContainsFunc(repoDigests, func(repoDigest string) bool {
+		_, d, _ := strings.Cut(repoDigest, "@")
+		return d == digest
+	})
+}
@@ -0,0 +1,39 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+func TestDigestMatches(t *testing.T) {
+	digests := []string{
+		"ghcr.io/blackwell-systems/gcp-kms-emulator-dual@sha256:1111",
+		"mirror.internal/gcp-kms-emulator-dual@sha256:2222",
+	}
+	for digest, want := range map[string]bool{
+		"sha256:1111": true,
+		"sha256:2222": true,
+		"sha256:3333": false,
+	} {
+		if got := digestMatches(digests, digest); got != want {
+			t.Errorf("digestMatches(%s) = %t, want %t", digest, got, want)
+		}
+	}
+	if digestMatches(nil, "sha256:1111") {
+		t.Error("digestMatches without digests = true")
+	}
+}
+
+func TestServiceImage(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Images.KMS = "ghcr.io/blackwell-systems/gcp-kms-emulator-dual:v0.4.0"
+	cfg.Images.IAM = ""
+
+	if got := ServiceImage(cfg, "kms"); got != cfg.Images.KMS {
+		t.Errorf("ServiceImage(kms) = %s", got)
+	}
+	if got, want := ServiceImage(cfg, "iam"), Images(cfg).IAM; got != want || got == "" {
+		t.Errorf("ServiceImage(iam) = %q, want the default %q", got, want)
+	}
+}
@@ -0,0 +1,213 @@
+package docker
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+// composeTemplateText is the compose file of the stack, filled in from the
+// config by RenderCompose
+//
+//go:embed compose.yaml.tmpl
+var composeTemplateText string
+
+var composeTemplate = template.Must(template.New("compose").
+	Funcs(template.FuncMap{"quote": strconv.Quote}).
+	Parse(composeTemplateText))
+
+// RenderCompose returns the compose file generated from cfg, publishing
+// the stack on ports. The data planes depend on a healthy IAM emulator
+// only with ordered startup. The policy file is mounted by its absolute
+// path, so the file works from any directory. Resource limits are written
+// as deploy.resources for docker compose, and as the mem_limit and cpus
+// that docker-compose and podman-compose also understand otherwise.
+func RenderCompose(cfg *config.Config, ports Ports) ([]byte, error) {
+	policyFile, err := filepath.Abs(cfg.PolicyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve policy file %s: %w", cfg.PolicyFile, err)
+	}
+
+	var buf bytes.Buffer
+	if err := composeTemplate.Execute(&buf, struct {
+		IAMMode    string
+		Ordered    bool
+		Trace      bool
+		PolicyFile string
+		Ports      Ports
+		Images     config.ImageConfig
+		Network    string
+		Resources  map[string]serviceResources
+	}{
+		IAMMode:    cfg.IAMMode,
+		Ordered:    cfg.StartOrdered(),
+		Trace:      cfg.Trace,
+		PolicyFile: policyFile,
+		Ports:      ports,
+		Images:     Images(cfg),
+		Network:    NetworkName(cfg),
+		Resources:  resources(cfg),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to render compose file: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// serviceResources are the resources of a service as rendered, Deploy
+// telling deploy.resources from mem_limit and cpus
+type serviceResources struct {
+	config.ServiceResources
+	Deploy bool
+}
+
+// resources returns the resources of each service. The runtime is only
+// detected when there are limits to write, so a file without any is the
+// same on every machine; if it can't be, the compose spec's
+// deploy.resources is assumed.
+func resources(cfg *config.Config) map[string]serviceResources {
+	r, limited := map[string]serviceResources{}, false
+	for _, service := range Services {
+		own := cfg.Resources.For(service)
+		r[service] = serviceResources{ServiceResources: own}
+		limited = limited || own.Memory != "" || own.CPUs != ""
+	}
+	if !limited {
+		return r
+	}
+	runtime, err := DetectRuntime(cfg)
+	deploy := err != nil || runtime.ComposeName() == "docker compose"
+	for service, own := range r {
+		own.Deploy = deploy
+		r[service] = own
+	}
+	return r
+}
+
+// ServiceSpec is a service of the compose file generated by
+// RenderCompose, for running its container without compose
+type ServiceSpec struct {
+	Service     string
+	Image       string
+	Ports       []string // host:container
+	Volumes     []string
+	Environment []string
+	Command     []string
+	DependsOn   []string
+
+	// HealthPort is the container port of the /health endpoint
+	HealthPort int
+
+	// Labels are the labels compose would give the container, so compose
+	// and the CLI see it as one of the project's
+	Labels map[string]string
+}
+
+// ServiceSpecs returns the services of the compose file generated from
+// cfg for ports, in the order of Services. composeFile is the file the
+// labels name as the project's, as returned by ComposeFile.
+func ServiceSpecs(cfg *config.Config, ports Ports, composeFile string) ([]ServiceSpec, error) {
+	data, err := RenderCompose(cfg, ports)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Services map[string]struct {
+			Image       string
+			Ports       []string
+			Volumes     []string
+			Environment []string
+			Command     []string
+			DependsOn   map[string]any `yaml:"depends_on"`
+		}
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(composeFile))
+	if err != nil {
+		return nil, err
+	}
+	var specs []ServiceSpec
+	for _, service := range Services {
+		s := file.Services[service]
+		spec := ServiceSpec{
+			Service:     service,
+			Image:       s.Image,
+			Ports:       s.Ports,
+			Volumes:     s.Volumes,
+			Environment: s.Environment,
+			Command:     s.Command,
+			// The data planes serve health on their HTTP port
+			HealthPort: 8080,
+			Labels: map[string]string{
+				projectLabelKey:                           ProjectName(cfg),
+				serviceLabelKey:                           service,
+				"com.docker.compose.oneoff":               "False",
+				"com.docker.compose.container-number":     "1",
+				"com.docker.compose.project.config_files": composeFile,
+				"com.docker.compose.project.working_dir":  dir,
+			},
+		}
+		if service == "iam" {
+			spec.HealthPort = 9080
+		}
+		spec.DependsOn = slices.Sorted(maps.Keys(s.DependsOn))
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// Images returns the images cfg's stack runs, with unset images falling
+// back to the defaults
+func Images(cfg *config.Config) config.ImageConfig {
+	images, defaults := cfg.Images, config.Defaults().Images
+	if images.IAM == "" {
+		images.IAM = defaults.IAM
+	}
+	if images.SecretManager == "" {
+		images.SecretManager = defaults.SecretManager
+	}
+	if images.KMS == "" {
+		images.KMS = defaults.KMS
+	}
+	return images
+}
+
+// ComposeFile returns the compose file to run cfg's stack with: the
+// compose-file key if set, or the file generated by RenderCompose,
+// written to compose/<project>.yaml in the state directory
+func ComposeFile(cfg *config.Config, ports Ports) (string, error) {
+	if file := cfg.Docker.ComposeFile; file != "" {
+		if _, err := os.Stat(file); err != nil {
+			return "", &CommandError{Msg: "compose file " + file + " not readable", Err: err, Kind: ErrComposeFileInvalid}
+		}
+		return filepath.Abs(file)
+	}
+
+	data, err := RenderCompose(cfg, ports)
+	if err != nil {
+		return "", err
+	}
+	path, err := cfg.StatePath(filepath.Join("compose", ProjectName(cfg)+".yaml"))
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write compose file: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,189 @@
+package docker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+func TestRenderCompose(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	cfg := config.Defaults()
+	cfg.IAMMode = "strict"
+	cfg.Trace = true
+	cfg.PolicyFile = "iam/policy.yaml"
+	cfg.Images.KMS = "ghcr.io/blackwell-systems/gcp-kms-emulator-dual:v0.4.0"
+	cfg.Images.IAM = ""
+	cfg.Docker.Network = "ci-net"
+	ports := Ports{IAM: 18080, SecretManager: 19090, SecretManagerHTTP: 18081, KMS: 19091, KMSHTTP: 18082}
+
+	data, err := RenderCompose(cfg, ports)
+	if err != nil {
+		t.Fatalf("RenderCompose failed: %v", err)
+	}
+	var file struct {
+		Services map[string]struct {
+			Image       string
+			Ports       []string
+			Volumes     []string
+			Environment []string
+			DependsOn   map[string]any `yaml:"depends_on"`
+		}
+		Networks map[string]struct{ Name string }
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		t.Fatalf("Rendered compose file doesn't parse: %v\n%s", err, data)
+	}
+
+	iam, kms := file.Services["iam"], file.Services["kms"]
+	if strings.Join(iam.Ports, ",") != "18080:8080,19080:9080" {
+		t.Errorf("Expected the IAM ports, got %v", iam.Ports)
+	}
+	if iam.Image != config.Defaults().Images.IAM {
+		t.Errorf("Expected the default IAM image, got %s", iam.Image)
+	}
+	if want := filepath.Join(dir, "iam", "policy.yaml") + ":/policy.yaml:ro"; len(iam.Volumes) != 1 || iam.Volumes[0] != want {
+		t.Errorf("Expected the policy file mounted by absolute path %s, got %v", want, iam.Volumes)
+	}
+	if strings.Join(iam.Environment, ",") != "IAM_TRACE=true" {
+		t.Errorf("Expected trace on, got %v", iam.Environment)
+	}
+	if kms.Image != cfg.Images.KMS || strings.Join(kms.Ports, ",") != "19091:9090,18082:8080" {
+		t.Errorf("Unexpected kms service: %+v", kms)
+	}
+	if strings.Join(file.Services["secret-manager"].Environment, ",") != "IAM_MODE=strict,IAM_HOST=iam:8080" {
+		t.Errorf("Expected the data planes in strict mode, got %v", file.Services["secret-manager"].Environment)
+	}
+	if _, ok := kms.DependsOn["iam"]; !ok {
+		t.Errorf("Expected kms to depend on iam with ordered startup, got %v", kms.DependsOn)
+	}
+	if file.Networks["default"].Name != "ci-net" {
+		t.Errorf("Expected network ci-net, got %+v", file.Networks)
+	}
+
+	cfg.IAMMode = "off"
+	data, err = RenderCompose(cfg, ports)
+	if err != nil {
+		t.Fatalf("RenderCompose failed: %v", err)
+	}
+	if strings.Contains(string(data), "depends_on") {
+		t.Errorf("Expected no dependencies in off mode, got\n%s", data)
+	}
+}
+
+func TestRenderComposeResources(t *testing.T) {
+	t.Chdir(t.TempDir())
+	type limits struct {
+		Memory string
+		CPUs   string
+	}
+	type service struct {
+		Restart  string
+		MemLimit string `yaml:"mem_limit"`
+		CPUs     string
+		Deploy   struct {
+			Resources struct{ Limits limits }
+		}
+	}
+	render := func(cfg *config.Config) map[string]service {
+		t.Helper()
+		data, err := RenderCompose(cfg, ConfiguredPorts(cfg))
+		if err != nil {
+			t.Fatalf("RenderCompose failed: %v", err)
+		}
+		var file struct{ Services map[string]service }
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			t.Fatalf("Rendered compose file doesn't parse: %v\n%s", err, data)
+		}
+		return file.Services
+	}
+
+	cfg := config.Defaults()
+	if data, _ := RenderCompose(cfg, ConfiguredPorts(cfg)); strings.Contains(string(data), "restart:") || strings.Contains(string(data), "deploy:") {
+		t.Errorf("Expected no limits by default, got\n%s", data)
+	}
+
+	cfg.Resources.Restart = "unless-stopped"
+	cfg.Resources.Memory = "1g"
+	cfg.Resources.KMS = config.ServiceResources{Memory: "512m", CPUs: "0.5"}
+
+	fakeCommands(t, []string{"docker"})
+	services := render(cfg)
+	if kms := services["kms"]; kms.Deploy.Resources.Limits != (limits{"512m", "0.5"}) || kms.Restart != "unless-stopped" || kms.MemLimit != "" {
+		t.Errorf("Expected kms's limits under deploy.resources for docker compose, got %+v", kms)
+	}
+	if iam := services["iam"]; iam.Deploy.Resources.Limits != (limits{Memory: "1g"}) {
+		t.Errorf("Expected iam to get the limits of all services, got %+v", iam)
+	}
+
+	fakeCommands(t, []string{"docker", "docker-compose"}, "docker")
+	services = render(cfg)
+	if kms := services["kms"]; kms.MemLimit != "512m" || kms.CPUs != "0.5" || kms.Deploy.Resources.Limits != (limits{}) {
+		t.Errorf("Expected kms's limits as mem_limit and cpus for docker-compose, got %+v", kms)
+	}
+}
+
+func TestComposeFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_STATE_HOME", "")
+
+	cfg := config.Defaults()
+	cfg.Docker.Project = "ci"
+	path, err := ComposeFile(cfg, ConfiguredPorts(cfg))
+	if err != nil {
+		t.Fatalf("ComposeFile failed: %v", err)
+	}
+	if filepath.Base(path) != "ci.yaml" {
+		t.Errorf("Expected the file named for the project, got %s", path)
+	}
+	if data, err := os.ReadFile(path); err != nil || !strings.Contains(string(data), `"8080:8080"`) {
+		t.Errorf("Expected the generated file at %s, got %v", path, err)
+	}
+
+	own := filepath.Join(t.TempDir(), "docker-compose.yml")
+	cfg.Docker.ComposeFile = own
+	if _, err := ComposeFile(cfg, ConfiguredPorts(cfg)); !errors.Is(err, ErrComposeFileInvalid) {
+		t.Errorf("Expected a missing compose-file to fail with ErrComposeFileInvalid, got %v", err)
+	}
+	if err := os.WriteFile(own, []byte("services: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if path, err := ComposeFile(cfg, ConfiguredPorts(cfg)); err != nil || path != own {
+		t.Errorf("Expected compose-file to be used as is, got %s, %v", path, err)
+	}
+}
+
+func TestServiceSpecs(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	cfg := config.Defaults()
+	cfg.IAMMode = "strict"
+	cfg.Docker.Project = "tc"
+	ports := Ports{IAM: 18080, SecretManager: 19090, SecretManagerHTTP: 18081, KMS: 19091, KMSHTTP: 18082}
+
+	specs, err := ServiceSpecs(cfg, ports, "/state/compose/tc.yaml")
+	if err != nil {
+		t.Fatalf("ServiceSpecs failed: %v", err)
+	}
+	if len(specs) != len(Services) {
+		t.Fatalf("Expected a spec per service, got %d", len(specs))
+	}
+	iam, sm := specs[0], specs[1]
+	if iam.Service != "iam" || strings.Join(iam.Command, " ") != "./server --config /policy.yaml" || iam.HealthPort != 9080 || len(iam.DependsOn) != 0 {
+		t.Errorf("Unexpected iam spec: %+v", iam)
+	}
+	if strings.Join(sm.Ports, ",") != "19090:9090,18081:8080" || strings.Join(sm.Environment, ",") != "IAM_MODE=strict,IAM_HOST=iam:8080" || strings.Join(sm.DependsOn, ",") != "iam" {
+		t.Errorf("Unexpected secret-manager spec: %+v", sm)
+	}
+	if sm.Labels["com.docker.compose.project"] != "tc" || sm.Labels["com.docker.compose.service"] != "secret-manager" || sm.Labels["com.docker.compose.oneoff"] != "False" || sm.Labels["com.docker.compose.project.working_dir"] != "/state/compose" {
+		t.Errorf("Expected compose's labels, got %v", sm.Labels)
+	}
+}
@@ -1,26 +1,42 @@
 package docker
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
+	"strings"
 
 	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
 )
 
-// Restart restarts the stack or a specific service
-func Restart(cfg *config.Config, service *string) error {
-	args := []string{"restart"}
-
-	if service != nil {
-		args = append(args, *service)
+// LookupService returns the compose service for a service name as given
+// on the command line, or an error listing the valid names
+func LookupService(name string) (string, error) {
+	for _, service := range Services {
+		if name == service {
+			return service, nil
+		}
 	}
+	return "", fmt.Errorf("unknown service: %s (valid services: %s)", name, strings.Join(Services, ", "))
+}
 
-	cmd := exec.Command("docker-compose", args...)
+// Restart restarts services of the stack, or all of them if none are
+// given. With recreate, their containers are recreated instead, so a
+// changed image or setting takes effect; the services they depend on are
+// left as they are, and volumes are kept.
+func Restart(cfg *config.Config, services []string, recreate bool) error {
+	args := []string{"restart"}
+	if recreate {
+		args = []string{"up", "-d", "--force-recreate"}
+		if len(services) > 0 {
+			args = append(args, "--no-deps")
+		}
+	}
 
-	output, err := cmd.CombinedOutput()
+	// The stack keeps the ports it runs on
+	cmd, err := composeCommand(context.Background(), cfg, ActivePorts(cfg), append(args, services...)...)
 	if err != nil {
-		return fmt.Errorf("docker-compose restart failed: %w\n%s", err, output)
+		return err
 	}
-
-	return nil
+	_, err = runCompose(cmd, composeFailed(cfg, args[0]))
+	return err
 }
@@ -0,0 +1,19 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLookupService(t *testing.T) {
+	for _, name := range Services {
+		if service, err := LookupService(name); err != nil || service != name {
+			t.Errorf("LookupService(%q) = %q, %v", name, service, err)
+		}
+	}
+
+	_, err := LookupService("secretmanager")
+	if err == nil || !strings.Contains(err.Error(), "valid services: iam, secret-manager, kms") {
+		t.Errorf("Expected an unknown service to list the valid ones, got %v", err)
+	}
+}
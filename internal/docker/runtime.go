@@ -0,0 +1,164 @@
+package docker
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+// Runtime is the container runtime the stack runs on, and the compose
+// command that drives it
+type Runtime struct {
+	// Name is the runtime's CLI, docker or podman, which also runs the
+	// commands on single containers, images, and volumes
+	Name string
+
+	// Compose is the compose command: docker compose, docker-compose,
+	// podman compose, or podman-compose
+	Compose []string
+}
+
+// ComposeName returns the compose command as it is typed, for messages
+func (r Runtime) ComposeName() string {
+	return strings.Join(r.Compose, " ")
+}
+
+// Podman reports whether the runtime is podman
+func (r Runtime) Podman() bool {
+	return r.Name == config.RuntimePodman
+}
+
+// composeCommands are the compose commands tried, in order of preference
+var composeCommands = []Runtime{
+	{Name: config.RuntimeDocker, Compose: []string{"docker", "compose"}},
+	{Name: config.RuntimeDocker, Compose: []string{"docker-compose"}},
+	{Name: config.RuntimePodman, Compose: []string{"podman", "compose"}},
+	{Name: config.RuntimePodman, Compose: []string{"podman-compose"}},
+}
+
+// detection is the result of detecting a runtime
+type detection struct {
+	runtime Runtime
+	err     error
+}
+
+// detected caches detections by container-runtime and PATH, since each
+// runs commands
+var detected sync.Map
+
+// DetectRuntime returns the runtime cfg's stack runs on: the first of
+// docker compose, docker-compose, podman compose, and podman-compose that
+// is installed, only among those of the runtime container-runtime forces
+func DetectRuntime(cfg *config.Config) (Runtime, error) {
+	key := cfg.Docker.Runtime + "\x00" + os.Getenv("PATH")
+	if d, ok := detected.Load(key); ok {
+		return d.(detection).runtime, d.(detection).err
+	}
+	runtime, err := detectRuntime(cfg.Docker.Runtime)
+	detected.Store(key, detection{runtime, err})
+	return runtime, err
+}
+
+func detectRuntime(forced string) (Runtime, error) {
+	var tried []string
+	for _, candidate := range composeCommands {
+		if forced != "" && forced != config.RuntimeAuto && forced != candidate.Name {
+			continue
+		}
+		tried = append(tried, "'"+candidate.ComposeName()+"'")
+		if composeInstalled(candidate.Compose) {
+			slog.Debug("container runtime detected", "runtime", candidate.Name, "compose", candidate.ComposeName())
+			return candidate, nil
+		}
+	}
+
+	msg := "neither " + strings.Join(tried, " nor ") + " was found"
+	if len(tried) > 2 {
+		msg = "no compose command was found (tried " + strings.Join(tried, ", ") + ")"
+	}
+	return Runtime{Name: fallbackRuntime(forced)}, &CommandError{Msg: msg, Err: exec.ErrNotFound, Kind: ErrComposeNotInstalled}
+}
+
+// composeInstalled reports whether a compose command runs. A compose
+// plugin is asked for its version, since the runtime's CLI alone doesn't
+// mean it has one.
+func composeInstalled(compose []string) bool {
+	if _, err := exec.LookPath(compose[0]); err != nil {
+		return false
+	}
+	if len(compose) == 1 {
+		return true
+	}
+	return exec.Command(compose[0], append(compose[1:], "version")...).Run() == nil
+}
+
+// fallbackRuntime returns the runtime single-container commands use when
+// no compose command was found: the one forced, or the first installed
+func fallbackRuntime(forced string) string {
+	if forced == config.RuntimeDocker || forced == config.RuntimePodman {
+		return forced
+	}
+	if _, err := exec.LookPath(config.RuntimeDocker); err != nil {
+		if _, err := exec.LookPath(config.RuntimePodman); err == nil {
+			return config.RuntimePodman
+		}
+	}
+	return config.RuntimeDocker
+}
+
+// RuntimeName returns the CLI of the runtime cfg's stack runs on, docker
+// or podman
+func RuntimeName(cfg *config.Config) string {
+	runtime, _ := DetectRuntime(cfg)
+	return runtime.Name
+}
+
+// ComposeName returns the compose command cfg's stack runs with, for
+// messages, or "<runtime> compose" if none is installed
+func ComposeName(cfg *config.Config) string {
+	runtime, err := DetectRuntime(cfg)
+	if err != nil {
+		return runtime.Name + " compose"
+	}
+	return runtime.ComposeName()
+}
+
+// command returns a command of the runtime's CLI, such as docker volume
+// ls, with the environment selecting cfg's daemon
+func command(cfg *config.Config, args ...string) *exec.Cmd {
+	cmd := exec.Command(RuntimeName(cfg), args...)
+	cmd.Env = Env(cfg)
+	logCommand(cmd, envVars(cfg))
+	return cmd
+}
+
+// commandContext is command, killed when ctx is done
+func commandContext(ctx context.Context, cfg *config.Config, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, RuntimeName(cfg), args...)
+	cmd.Env = Env(cfg)
+	logCommand(cmd, envVars(cfg))
+	return cmd
+}
+
+// logCommand records a command at debug level, with the variables it is
+// given beyond the CLI's own environment
+func logCommand(cmd *exec.Cmd, env []string) {
+	slog.Debug("exec", "command", strings.Join(cmd.Args, " "), "env", env)
+}
+
+// failed returns the message of a failed runtime command, such as
+// "podman volume ls failed"
+func failed(cfg *config.Config, command string) string {
+	return RuntimeName(cfg) + " " + command + " failed"
+}
+
+// composeFailed returns the message of a failed compose command, such as
+// "podman-compose up failed"
+func composeFailed(cfg *config.Config, command string) string {
+	return ComposeName(cfg) + " " + command + " failed"
+}
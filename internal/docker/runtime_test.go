@@ -0,0 +1,98 @@
+package docker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+// fakeCommands puts executables named commands on a PATH of their own.
+// Each exits 0, or 1 when its name is in failing, such as a docker whose
+// compose plugin is missing.
+func fakeCommands(t *testing.T, commands []string, failing ...string) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range commands {
+		script := "#!/bin/sh\nexit 0\n"
+		for _, f := range failing {
+			if f == name {
+				script = "#!/bin/sh\nexit 1\n"
+			}
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+}
+
+func TestDetectRuntime(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands []string
+		failing  []string
+		forced   string
+		want     string
+	}{
+		{"docker compose", []string{"docker", "podman", "podman-compose"}, nil, config.RuntimeAuto, "docker compose"},
+		{"legacy compose", []string{"docker", "docker-compose"}, []string{"docker"}, config.RuntimeAuto, "docker-compose"},
+		{"podman compose", []string{"podman"}, nil, config.RuntimeAuto, "podman compose"},
+		{"podman-compose", []string{"podman", "podman-compose"}, []string{"podman"}, config.RuntimeAuto, "podman-compose"},
+		{"forced podman", []string{"docker", "podman"}, nil, config.RuntimePodman, "podman compose"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeCommands(t, tt.commands, tt.failing...)
+			runtime, err := DetectRuntime(&config.Config{Docker: config.DockerConfig{Runtime: tt.forced}})
+			if err != nil {
+				t.Fatalf("DetectRuntime failed: %v", err)
+			}
+			if got := runtime.ComposeName(); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDetectRuntimeNotFound(t *testing.T) {
+	fakeCommands(t, []string{"podman", "podman-compose"})
+	cfg := &config.Config{Docker: config.DockerConfig{Runtime: config.RuntimeDocker}}
+
+	_, err := DetectRuntime(cfg)
+	if !errors.Is(err, ErrComposeNotInstalled) {
+		t.Fatalf("Expected ErrComposeNotInstalled with docker forced, got %v", err)
+	}
+	if want := "neither 'docker compose' nor 'docker-compose' was found"; err.(*CommandError).Msg != want {
+		t.Errorf("Expected %q, got %q", want, err.(*CommandError).Msg)
+	}
+	if got := composeFailed(cfg, "up"); got != "docker compose up failed" {
+		t.Errorf("Expected the forced runtime in messages, got %q", got)
+	}
+
+	fakeCommands(t, nil)
+	_, err = DetectRuntime(&config.Config{Docker: config.DockerConfig{Runtime: config.RuntimeAuto}})
+	if !errors.Is(err, ErrComposeNotInstalled) {
+		t.Fatalf("Expected ErrComposeNotInstalled, got %v", err)
+	}
+}
+
+func TestPodmanEnv(t *testing.T) {
+	fakeCommands(t, []string{"podman", "podman-compose"})
+	t.Setenv("CONTAINER_HOST", "")
+	t.Setenv("CONTAINER_CONNECTION", "")
+	cfg := &config.Config{Docker: config.DockerConfig{Runtime: config.RuntimeAuto, Context: "rootless"}}
+
+	if got := Target(cfg); got != "podman connection rootless" {
+		t.Errorf("Expected the podman connection, got %q", got)
+	}
+	env := Env(cfg)
+	if last := env[len(env)-1]; last != "CONTAINER_CONNECTION=rootless" {
+		t.Errorf("Expected docker-context to select the podman connection, got %q", last)
+	}
+	if got := failed(cfg, "volume ls"); got != "podman volume ls failed" {
+		t.Errorf("Expected the runtime used in messages, got %q", got)
+	}
+}
@@ -0,0 +1,236 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+// Services are the services of the stack, as named in docker-compose.yml
+var Services = []string{"iam", "secret-manager", "kms"}
+
+// SelectServices returns the services named, in the order of Services and
+// without repeats. The data planes check requests with the IAM emulator
+// unless mode is off, so iam is added then if it is missing; addedIAM
+// reports that it was.
+func SelectServices(names []string, mode string) (services []string, addedIAM bool, err error) {
+	selected := map[string]bool{}
+	for _, name := range names {
+		service, err := LookupService(name)
+		if err != nil {
+			return nil, false, err
+		}
+		selected[service] = true
+	}
+	if len(selected) > 0 && !selected["iam"] && mode != "off" {
+		selected["iam"] = true
+		addedIAM = true
+	}
+
+	for _, service := range Services {
+		if selected[service] {
+			services = append(services, service)
+		}
+	}
+	return services, addedIAM, nil
+}
+
+// ServiceState is the state of a service's container, as reported by
+// docker compose ps or podman ps
+type ServiceState struct {
+	Service string
+
+	// ID is the container's ID, for Inspect
+	ID string
+
+	// State is running, exited, restarting, created, paused, or dead
+	State string
+
+	// ExitCode is the container's exit code, once it has exited
+	ExitCode int
+
+	// Status describes the state, such as "Up 3 seconds"
+	Status string
+}
+
+// Exited reports whether the container has stopped
+func (s ServiceState) Exited() bool {
+	return s.State == "exited" || s.State == "dead"
+}
+
+// States returns the state of each container of the stack, stopped ones
+// included
+func States(cfg *config.Config) ([]ServiceState, error) {
+	if RuntimeName(cfg) == config.RuntimePodman {
+		cmd := command(cfg, "ps", "--all", "--filter", projectLabel(cfg), "--format", "json")
+		output, err := cmd.Output()
+		if err != nil {
+			return nil, commandError(failed(cfg, "ps"), err, stderrOf(err))
+		}
+		return parsePodmanStates(output)
+	}
+
+	cmd, err := composeCommand(context.Background(), cfg, ActivePorts(cfg), "ps", "--all", "--format", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, commandError(composeFailed(cfg, "ps"), err, stderrOf(err))
+	}
+	return parseStates(output)
+}
+
+// parseStates parses docker compose ps --format json: one object per
+// line since compose 2.21, a single array before
+func parseStates(data []byte) ([]ServiceState, error) {
+	var states []ServiceState
+
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		if err := json.Unmarshal(data, &states); err != nil {
+			return nil, fmt.Errorf("failed to parse docker compose ps output: %w", err)
+		}
+		return states, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		var state ServiceState
+		if err := decoder.Decode(&state); err != nil {
+			return nil, fmt.Errorf("failed to parse docker compose ps output: %w", err)
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// parsePodmanStates parses podman ps --format json, an array of
+// containers naming their compose service in a label
+func parsePodmanStates(data []byte) ([]ServiceState, error) {
+	var containers []struct {
+		ID       string
+		Labels   map[string]string
+		State    string
+		ExitCode int
+		Status   string
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	if err := json.Unmarshal(data, &containers); err != nil {
+		return nil, fmt.Errorf("failed to parse podman ps output: %w", err)
+	}
+
+	states := make([]ServiceState, 0, len(containers))
+	for _, c := range containers {
+		status := c.Status
+		if status == "" {
+			status = c.State
+		}
+		states = append(states, ServiceState{
+			Service:  c.Labels[serviceLabelKey],
+			ID:       c.ID,
+			State:    c.State,
+			ExitCode: c.ExitCode,
+			Status:   status,
+		})
+	}
+	return states, nil
+}
+
+// Container is what the runtime's inspect reports of a service's
+// container
+type Container struct {
+	Service string
+
+	// State is running, restarting, exited, created, paused, or dead
+	State string
+
+	// Health is the state of the container's own health check, healthy,
+	// unhealthy, or starting, and "" if it has none
+	Health string
+
+	// ExitCode is the code the container last exited with
+	ExitCode int
+
+	// RestartCount is how often the runtime restarted the container, as a
+	// restart policy does when it keeps exiting
+	RestartCount int
+
+	// StartedAt is when the container last started, and FinishedAt when
+	// it last exited; both are zero if it never did
+	StartedAt  time.Time
+	FinishedAt time.Time
+
+	// Image is the image the container was created from, as referenced
+	Image string
+}
+
+// Inspect returns the containers with ids, by the service they run
+func Inspect(cfg *config.Config, ids []string) (map[string]Container, error) {
+	if len(ids) == 0 {
+		return map[string]Container{}, nil
+	}
+	cmd := command(cfg, append([]string{"inspect", "--type", "container"}, ids...)...)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, commandError(failed(cfg, "inspect"), err, stderrOf(err))
+	}
+	return parseInspect(output)
+}
+
+// parseInspect parses the array docker and podman inspect print,
+// finding each container's service by the label compose gives it
+func parseInspect(data []byte) (map[string]Container, error) {
+	var inspected []struct {
+		RestartCount int
+		State        struct {
+			Status     string
+			ExitCode   int
+			StartedAt  time.Time
+			FinishedAt time.Time
+			Health     *struct{ Status string }
+		}
+		Config struct {
+			Image  string
+			Labels map[string]string
+		}
+	}
+	if err := json.Unmarshal(data, &inspected); err != nil {
+		return nil, fmt.Errorf("failed to parse inspect output: %w", err)
+	}
+
+	containers := make(map[string]Container, len(inspected))
+	for _, c := range inspected {
+		container := Container{
+			Service:      c.Config.Labels[serviceLabelKey],
+			State:        c.State.Status,
+			ExitCode:     c.State.ExitCode,
+			RestartCount: c.RestartCount,
+			StartedAt:    c.State.StartedAt,
+			FinishedAt:   c.State.FinishedAt,
+			Image:        c.Config.Image,
+		}
+		if c.State.Health != nil {
+			container.Health = c.State.Health.Status
+		}
+		containers[container.Service] = container
+	}
+	return containers, nil
+}
+
+// Logs returns the last lines of a service's logs
+func Logs(cfg *config.Config, service string, lines int) (string, error) {
+	cmd, err := composeCommand(context.Background(), cfg, ActivePorts(cfg), append(logsArgs(LogOptions{Tail: lines}), service)...)
+	if err != nil {
+		return "", err
+	}
+	output, err := runCompose(cmd, composeFailed(cfg, "logs"))
+	return string(output), err
+}
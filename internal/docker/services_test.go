@@ -0,0 +1,130 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseStates(t *testing.T) {
+	want := []ServiceState{
+		{Service: "iam", State: "running", Status: "Up 5 seconds (healthy)"},
+		{Service: "kms", State: "exited", ExitCode: 1, Status: "Exited (1) 2 seconds ago"},
+	}
+
+	for name, output := range map[string]string{
+		// compose 2.21 and later
+		"lines": `{"Name":"gcp-emulator-iam-1","Service":"iam","State":"running","ExitCode":0,"Status":"Up 5 seconds (healthy)"}
+{"Name":"gcp-emulator-kms-1","Service":"kms","State":"exited","ExitCode":1,"Status":"Exited (1) 2 seconds ago"}
+`,
+		// earlier versions
+		"array": `[{"Name":"gcp-emulator-iam-1","Service":"iam","State":"running","ExitCode":0,"Status":"Up 5 seconds (healthy)"},` +
+			`{"Name":"gcp-emulator-kms-1","Service":"kms","State":"exited","ExitCode":1,"Status":"Exited (1) 2 seconds ago"}]`,
+	} {
+		states, err := parseStates([]byte(output))
+		if err != nil {
+			t.Fatalf("%s: parseStates failed: %v", name, err)
+		}
+		if len(states) != len(want) {
+			t.Fatalf("%s: expected %d states, got %v", name, len(want), states)
+		}
+		for i := range want {
+			if states[i] != want[i] {
+				t.Errorf("%s: expected %+v, got %+v", name, want[i], states[i])
+			}
+		}
+		if states[0].Exited() || !states[1].Exited() {
+			t.Errorf("%s: expected only kms to have exited", name)
+		}
+	}
+
+	if states, err := parseStates(nil); err != nil || len(states) != 0 {
+		t.Errorf("Expected no states for empty output, got %v, %v", states, err)
+	}
+	if _, err := parseStates([]byte("not json")); err == nil {
+		t.Error("Expected an error for malformed output")
+	}
+}
+
+func TestParsePodmanStates(t *testing.T) {
+	output := `[{"Id":"4f1c","Names":["gcp-emulator-iam-1"],"Labels":{"com.docker.compose.project":"gcp-emulator","com.docker.compose.service":"iam"},"State":"running","ExitCode":0,"Status":"Up 5 seconds (healthy)"},` +
+		`{"Id":"9a02","Names":["gcp-emulator-kms-1"],"Labels":{"com.docker.compose.service":"kms"},"State":"exited","ExitCode":1,"Status":""}]`
+
+	states, err := parsePodmanStates([]byte(output))
+	if err != nil {
+		t.Fatalf("parsePodmanStates failed: %v", err)
+	}
+	want := []ServiceState{
+		{Service: "iam", ID: "4f1c", State: "running", Status: "Up 5 seconds (healthy)"},
+		{Service: "kms", ID: "9a02", State: "exited", ExitCode: 1, Status: "exited"},
+	}
+	if len(states) != len(want) {
+		t.Fatalf("Expected %d states, got %v", len(want), states)
+	}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Errorf("Expected %+v, got %+v", want[i], states[i])
+		}
+	}
+
+	if states, err := parsePodmanStates([]byte("\n")); err != nil || len(states) != 0 {
+		t.Errorf("Expected no states for empty output, got %v, %v", states, err)
+	}
+}
+
+func TestParseInspect(t *testing.T) {
+	output := `[
+  {"Id": "4f1c", "RestartCount": 7,
+   "State": {"Status": "restarting", "ExitCode": 1, "StartedAt": "2026-10-14T09:59:58Z", "FinishedAt": "2026-10-14T10:00:01Z"},
+   "Config": {"Image": "ghcr.io/acme/kms:v0.3.1", "Labels": {"com.docker.compose.service": "kms"}}},
+  {"Id": "9a02", "RestartCount": 0,
+   "State": {"Status": "running", "ExitCode": 0, "StartedAt": "2026-10-14T09:00:00Z", "FinishedAt": "0001-01-01T00:00:00Z", "Health": {"Status": "healthy"}},
+   "Config": {"Image": "ghcr.io/acme/iam:latest", "Labels": {"com.docker.compose.service": "iam"}}}
+]`
+	containers, err := parseInspect([]byte(output))
+	if err != nil {
+		t.Fatalf("parseInspect failed: %v", err)
+	}
+
+	kms := containers["kms"]
+	if kms.State != "restarting" || kms.ExitCode != 1 || kms.RestartCount != 7 || kms.Health != "" {
+		t.Errorf("Expected kms restarting after exiting 1, got %+v", kms)
+	}
+	if want := time.Date(2026, 10, 14, 10, 0, 1, 0, time.UTC); !kms.FinishedAt.Equal(want) {
+		t.Errorf("Expected kms to have last exited at %s, got %s", want, kms.FinishedAt)
+	}
+	if iam := containers["iam"]; iam.Health != "healthy" || iam.Image != "ghcr.io/acme/iam:latest" || !iam.FinishedAt.IsZero() {
+		t.Errorf("Expected iam running and healthy, got %+v", iam)
+	}
+
+	if _, err := parseInspect([]byte("not json")); err == nil {
+		t.Error("Expected an error for malformed output")
+	}
+}
+
+func TestSelectServices(t *testing.T) {
+	tests := []struct {
+		names    []string
+		mode     string
+		want     string
+		addedIAM bool
+	}{
+		{nil, "strict", "", false},
+		{[]string{"kms", "iam", "kms"}, "strict", "iam,kms", false},
+		{[]string{"kms"}, "permissive", "iam,kms", true},
+		{[]string{"secret-manager"}, "off", "secret-manager", false},
+	}
+	for _, tt := range tests {
+		services, addedIAM, err := SelectServices(tt.names, tt.mode)
+		if err != nil {
+			t.Fatalf("SelectServices(%v, %s) failed: %v", tt.names, tt.mode, err)
+		}
+		if got := strings.Join(services, ","); got != tt.want || addedIAM != tt.addedIAM {
+			t.Errorf("SelectServices(%v, %s) = %s, %t, want %s, %t", tt.names, tt.mode, got, addedIAM, tt.want, tt.addedIAM)
+		}
+	}
+
+	if _, _, err := SelectServices([]string{"kms", "pubsub"}, "off"); err == nil || !strings.Contains(err.Error(), "valid services") {
+		t.Errorf("Expected an unknown service to fail, got %v", err)
+	}
+}
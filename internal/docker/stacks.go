@@ -0,0 +1,54 @@
+package docker
+
+import (
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+// StackInfo is a running stack of the CLI, as stacks list shows it
+type StackInfo struct {
+	// Name is the stack's name, config.DefaultStack for the default stack
+	Name string
+
+	// Project is the stack's compose project
+	Project string
+
+	// Ports are the ports the stack was started on
+	Ports Ports
+
+	// Running is how many of the stack's containers are running
+	Running int
+}
+
+// ListStacks returns the running stacks of cfg's profile: the default
+// stack, then each named stack with a state directory, by name. A stack
+// is running while any of its containers is.
+func ListStacks(cfg *config.Config) ([]StackInfo, error) {
+	names, err := config.ListStacks()
+	if err != nil {
+		return nil, err
+	}
+
+	var stacks []StackInfo
+	for _, name := range append([]string{""}, names...) {
+		c := *cfg
+		c.Stack = name
+		states, err := States(&c)
+		if err != nil {
+			return nil, err
+		}
+		running := 0
+		for _, state := range states {
+			if state.State == "running" {
+				running++
+			}
+		}
+		if running == 0 {
+			continue
+		}
+		if name == "" {
+			name = config.DefaultStack
+		}
+		stacks = append(stacks, StackInfo{Name: name, Project: ProjectName(&c), Ports: ActivePorts(&c), Running: running})
+	}
+	return stacks, nil
+}
@@ -0,0 +1,110 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+// ContainerStats is the resource usage of a service's container, as docker
+// stats reports it
+type ContainerStats struct {
+	Service string
+
+	// CPU is the share of a CPU in use, such as "1.25%"
+	CPU string
+
+	// Memory is the memory in use and the limit, such as
+	// "82.5MiB / 512MiB"; without a limit, the limit is the host's memory
+	Memory string
+
+	// MemoryPercent is Memory's share of the limit, such as "16.11%"
+	MemoryPercent string
+
+	// NetIO is the data received and sent over the network, such as
+	// "1.2kB / 648B"
+	NetIO string
+
+	// PIDs is the number of processes and threads in the container
+	PIDs string
+}
+
+// stats is a line of docker stats --format '{{json .}}'. podman names
+// PIDs PIDS, which encoding/json matches as well.
+type stats struct {
+	Container string
+	ID        string
+	CPUPerc   string
+	MemUsage  string
+	MemPerc   string
+	NetIO     string
+	PIDs      string
+}
+
+// Stats returns the resource usage of the stack's running containers, in
+// the order of Services, from one sample of docker stats. It is empty when
+// none is running.
+func Stats(cfg *config.Config) ([]ContainerStats, error) {
+	states, err := States(cfg)
+	if err != nil {
+		return nil, err
+	}
+	services := map[string]string{}
+	var ids []string
+	for _, state := range states {
+		if state.State != "running" || state.ID == "" {
+			continue
+		}
+		services[state.ID] = state.Service
+		ids = append(ids, state.ID)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	cmd := command(cfg, append([]string{"stats", "--no-stream", "--format", "{{json .}}"}, ids...)...)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, commandError(failed(cfg, "stats"), err, stderrOf(err))
+	}
+	return parseStats(output, services)
+}
+
+// parseStats parses docker stats --format '{{json .}}', one object per
+// line, naming each container's service from services, by container ID.
+// The IDs docker stats prints may be shortened.
+func parseStats(data []byte, services map[string]string) ([]ContainerStats, error) {
+	var result []ContainerStats
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		var s stats
+		if err := decoder.Decode(&s); err != nil {
+			return nil, fmt.Errorf("failed to parse docker stats output: %w", err)
+		}
+		service := services[s.Container]
+		for id, name := range services {
+			if service == "" && s.ID != "" && strings.HasPrefix(id, s.ID) {
+				service = name
+			}
+		}
+		if service == "" {
+			continue
+		}
+		result = append(result, ContainerStats{
+			Service:       service,
+			CPU:           s.CPUPerc,
+			Memory:        s.MemUsage,
+			MemoryPercent: s.MemPerc,
+			NetIO:         s.NetIO,
+			PIDs:          s.PIDs,
+		})
+	}
+	slices.SortFunc(result, func(a, b ContainerStats) int {
+		return slices.Index(Services, a.Service) - slices.Index(Services, b.Service)
+	})
+	return result, nil
+}
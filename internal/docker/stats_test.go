@@ -0,0 +1,36 @@
+package docker
+
+import "testing"
+
+func TestParseStats(t *testing.T) {
+	services := map[string]string{
+		"4f1c2a9d8e7b6a5c4f1c2a9d8e7b6a5c": "kms",
+		"9e8d7c6b5a4f3e2d9e8d7c6b5a4f3e2d": "iam",
+	}
+	// docker names the container as it was asked for; podman only gives
+	// a short ID, and PIDS
+	output := `{"BlockIO":"0B / 0B","CPUPerc":"97.20%","Container":"4f1c2a9d8e7b6a5c4f1c2a9d8e7b6a5c","ID":"4f1c2a9d8e7b","MemPerc":"93.75%","MemUsage":"480MiB / 512MiB","Name":"gcp-emulator-kms-1","NetIO":"1kB / 0B","PIDs":"12"}
+{"CPUPerc":"0.10%","ID":"9e8d7c6b5a4f","MemPerc":"0.52%","MemUsage":"41.2MB / 7.77GB","Name":"gcp-emulator-iam-1","NetIO":"3.1kB / 2kB","PIDS":"8"}
+{"CPUPerc":"0.00%","Container":"0123456789ab","ID":"0123456789ab","MemUsage":"1MiB / 1GiB"}
+`
+	stats, err := parseStats([]byte(output), services)
+	if err != nil {
+		t.Fatalf("parseStats failed: %v", err)
+	}
+	want := []ContainerStats{
+		{Service: "iam", CPU: "0.10%", Memory: "41.2MB / 7.77GB", MemoryPercent: "0.52%", NetIO: "3.1kB / 2kB", PIDs: "8"},
+		{Service: "kms", CPU: "97.20%", Memory: "480MiB / 512MiB", MemoryPercent: "93.75%", NetIO: "1kB / 0B", PIDs: "12"},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("Expected %d containers, got %+v", len(want), stats)
+	}
+	for i := range want {
+		if stats[i] != want[i] {
+			t.Errorf("Expected %+v, got %+v", want[i], stats[i])
+		}
+	}
+
+	if _, err := parseStats([]byte("not json"), services); err == nil {
+		t.Error("Expected invalid output to fail")
+	}
+}
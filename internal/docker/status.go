@@ -1,10 +1,12 @@
 package docker
 
 import (
-	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
+	"golang.org/x/sync/errgroup"
+
 	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
 )
 
@@ -16,45 +18,331 @@ const (
 	ServiceUp
 	ServiceDown
 	ServiceStarting
+
+	// ServiceNotEnabled is a service left out of a running stack, as with
+	// start --services
+	ServiceNotEnabled
+
+	// ServiceDockerUnavailable is a service that wasn't checked because
+	// the docker daemon isn't running
+	ServiceDockerUnavailable
 )
 
+// String returns the status as status --output json reports it
+func (s ServiceStatus) String() string {
+	switch s {
+	case ServiceUp:
+		return "up"
+	case ServiceDown:
+		return "down"
+	case ServiceStarting:
+		return "starting"
+	case ServiceNotEnabled:
+		return "not-enabled"
+	case ServiceDockerUnavailable:
+		return "docker-unavailable"
+	default:
+		return "unknown"
+	}
+}
+
+// Overall states of the stack
+const (
+	// StackHealthy is a stack whose enabled services are all up
+	StackHealthy = "healthy"
+
+	// StackDegraded is a stack with some enabled services up and some not
+	StackDegraded = "degraded"
+
+	// StackDown is a stack without any service up
+	StackDown = "down"
+)
+
+// ContainerNotCreated is the State of a service without a container
+const ContainerNotCreated = "not created"
+
+// ServiceInfo is the status of a service: the state of its container and
+// the result of its health check
+type ServiceInfo struct {
+	Service string
+
+	// State is the container's state, such as running, restarting, or
+	// exited, or ContainerNotCreated. It is "" if the runtime couldn't be
+	// asked.
+	State string
+
+	// Health is the result of the health check, and Latency the round
+	// trip of a check that passed
+	Health  ServiceStatus
+	Latency time.Duration
+
+	// ExitCode is the code the container last exited with
+	ExitCode int
+
+	// RestartCount is how often the container was restarted
+	RestartCount int
+
+	// StartedAt is when the container last started; Uptime is how long
+	// it has been running since, and zero unless it is running
+	StartedAt time.Time
+	Uptime    time.Duration
+
+	// Image is the image the container runs
+	Image string
+}
+
+// Unhealthy reports whether the service is enabled but not up
+func (s ServiceInfo) Unhealthy() bool {
+	return s.Health != ServiceUp && s.Health != ServiceNotEnabled
+}
+
+// healthRetryDelay is the pause between attempts of a failing health check
+const healthRetryDelay = time.Second
+
 // StackStatus represents the status of all services
 type StackStatus struct {
-	IAM           ServiceStatus
-	SecretManager ServiceStatus
-	KMS           ServiceStatus
+	IAM           ServiceInfo
+	SecretManager ServiceInfo
+	KMS           ServiceInfo
+
+	// Ports are the host ports the stack is running on
+	Ports Ports
+
+	// RuntimeErr is why the runtime couldn't be asked for the stack's
+	// containers, if it couldn't; their states are unknown then. It is a
+	// *DaemonDownError if the daemon isn't running, and the services are
+	// then ServiceDockerUnavailable.
+	RuntimeErr error
+}
+
+// Services returns the status of each service, in the order of Services
+func (s *StackStatus) Services() []ServiceInfo {
+	return []ServiceInfo{s.IAM, s.SecretManager, s.KMS}
+}
+
+// Down returns the enabled services that aren't up
+func (s *StackStatus) Down() []string {
+	var down []string
+	for _, info := range s.Services() {
+		if info.Unhealthy() {
+			down = append(down, info.Service)
+		}
+	}
+	return down
+}
+
+// Overall returns StackHealthy, StackDegraded, or StackDown
+func (s *StackStatus) Overall() string {
+	enabled := 0
+	for _, info := range s.Services() {
+		if info.Health != ServiceNotEnabled {
+			enabled++
+		}
+	}
+	switch down := len(s.Down()); {
+	case down == 0 && enabled > 0:
+		return StackHealthy
+	case down < enabled:
+		return StackDegraded
+	default:
+		return StackDown
+	}
 }
 
-// Status returns health status of all services
+// HealthURLs returns the health endpoint of each service: the override in
+// cfg, or one derived from the ports the stack is running on
+func HealthURLs(cfg *config.Config) config.HealthURLs {
+	ports := ActivePorts(cfg)
+	urls := config.HealthURLs{
+		// Health server on gRPC port + 1000
+		IAM: "http://" + cfg.Docker.Address(ports.IAMHTTP()) + "/health",
+
+		// HTTP ports, mapped from container port 8080
+		SecretManager: "http://" + cfg.Docker.Address(ports.SecretManagerHTTP) + "/health",
+		KMS:           "http://" + cfg.Docker.Address(ports.KMSHTTP) + "/health",
+	}
+
+	overrides := cfg.Health.URLs
+	if overrides.IAM != "" {
+		urls.IAM = overrides.IAM
+	}
+	if overrides.SecretManager != "" {
+		urls.SecretManager = overrides.SecretManager
+	}
+	if overrides.KMS != "" {
+		urls.KMS = overrides.KMS
+	}
+	return urls
+}
+
+// Status returns the container state and health of all services
 func Status(cfg *config.Config) (*StackStatus, error) {
-	status := &StackStatus{}
+	status := &StackStatus{Ports: ActivePorts(cfg)}
+	urls := HealthURLs(cfg)
 
-	// Check IAM health (health server on gRPC port + 1000)
-	status.IAM = checkHealth(fmt.Sprintf("http://localhost:%d/health", cfg.Ports.IAM+1000))
+	timeout := cfg.Health.Timeout
+	if timeout == 0 {
+		timeout = config.DefaultHealthTimeout
+	}
+	client := &http.Client{
+		Timeout: timeout,
+	}
+
+	// Without a daemon there are no containers to check, and calling the
+	// services down would claim it was asked
+	if err := Ping(cfg); err != nil {
+		status.RuntimeErr = err
+		infos := make([]ServiceInfo, len(Services))
+		for i, service := range Services {
+			infos[i] = ServiceInfo{Service: service, Health: ServiceDockerUnavailable}
+		}
+		status.IAM, status.SecretManager, status.KMS = infos[0], infos[1], infos[2]
+		return status, nil
+	}
 
-	// Check Secret Manager health (HTTP port is 8081, mapped from container 8080)
-	status.SecretManager = checkHealth("http://localhost:8081/health")
+	// A service without a container in a running stack wasn't started.
+	// If compose can't be asked, every service is checked.
+	states, err := States(cfg)
+	known := err == nil
+	status.RuntimeErr = err
+	containers := containersOf(cfg, states)
 
-	// Check KMS health (HTTP port is 8082, mapped from container 8080)
-	status.KMS = checkHealth("http://localhost:8082/health")
+	// A named stack without recorded ports was never started, and the
+	// configured ports may be another stack's
+	_, recorded, _ := RecordedPorts(cfg)
+	unstarted := cfg.Stack != "" && !recorded
+
+	check := func(service string) ServiceInfo {
+		container, created := containers[service]
+		info := serviceInfo(service, container, created, known)
+		if known && len(states) > 0 && !created {
+			info.Health = ServiceNotEnabled
+			return info
+		}
+		if unstarted && !created {
+			info.Health = ServiceDown
+			return info
+		}
+		for attempt := 0; ; attempt++ {
+			info.Health, info.Latency = checkHealth(client, healthURL(urls, service))
+			if info.Health == ServiceUp || attempt >= cfg.Health.Retries {
+				break
+			}
+			time.Sleep(healthRetryDelay)
+		}
+		// The container's own health check tells one still starting from
+		// one that is down
+		if info.Health == ServiceDown && container.State == "running" && container.Health == "starting" {
+			info.Health = ServiceStarting
+		}
+		return info
+	}
+
+	// The services are checked at once, so a dead stack takes one
+	// timeout to report rather than one per service
+	infos := probe(Services, check)
+	status.IAM, status.SecretManager, status.KMS = infos[0], infos[1], infos[2]
 
 	return status, nil
 }
 
-func checkHealth(url string) ServiceStatus {
-	client := &http.Client{
-		Timeout: 2 * time.Second,
+// probe runs check for each of services concurrently, returning the
+// results in the order of services
+func probe[T any](services []string, check func(service string) T) []T {
+	results := make([]T, len(services))
+	var g errgroup.Group
+	for i, service := range services {
+		g.Go(func() error {
+			results[i] = check(service)
+			return nil
+		})
 	}
+	_ = g.Wait()
+	return results
+}
+
+// EnabledServices returns the services with a container in the stack, or
+// every service if it has none or the runtime can't be asked, as Status
+// checks them
+func EnabledServices(cfg *config.Config) []string {
+	states, err := States(cfg)
+	if err != nil || len(states) == 0 {
+		return Services
+	}
+	var services []string
+	for _, service := range Services {
+		for _, state := range states {
+			if state.Service == service {
+				services = append(services, service)
+				break
+			}
+		}
+	}
+	return services
+}
+
+// containersOf returns the containers of states, by service. What inspect
+// adds is left out if it fails, leaving what ps reported.
+func containersOf(cfg *config.Config, states []ServiceState) map[string]Container {
+	containers := map[string]Container{}
+	var ids []string
+	for _, state := range states {
+		containers[state.Service] = Container{Service: state.Service, State: state.State, ExitCode: state.ExitCode}
+		if state.ID != "" {
+			ids = append(ids, state.ID)
+		}
+	}
+	inspected, err := Inspect(cfg, ids)
+	if err != nil {
+		return containers
+	}
+	for service, container := range inspected {
+		containers[service] = container
+	}
+	return containers
+}
+
+// serviceInfo returns what container tells of service, or that it has
+// none if it wasn't created. known is false if the runtime couldn't be
+// asked, leaving the state unknown.
+func serviceInfo(service string, container Container, created, known bool) ServiceInfo {
+	info := ServiceInfo{Service: service}
+	switch {
+	case !known:
+		return info
+	case !created:
+		info.State = ContainerNotCreated
+		return info
+	}
+
+	info.State = container.State
+	info.ExitCode = container.ExitCode
+	info.RestartCount = container.RestartCount
+	info.StartedAt = container.StartedAt
+	info.Image = container.Image
+	if container.State == "running" && !container.StartedAt.IsZero() {
+		info.Uptime = time.Since(container.StartedAt).Round(time.Second)
+	}
+	return info
+}
 
+// checkHealth requests a health endpoint, returning the round-trip time
+// of a request that succeeded
+func checkHealth(client *http.Client, url string) (ServiceStatus, time.Duration) {
+	start := time.Now()
 	resp, err := client.Get(url)
 	if err != nil {
-		return ServiceDown
+		slog.Debug("health check", "url", url, "status", ServiceDown.String(), "error", err)
+		return ServiceDown, 0
 	}
 	defer resp.Body.Close()
 
+	latency := time.Since(start)
 	if resp.StatusCode == 200 {
-		return ServiceUp
+		slog.Debug("health check", "url", url, "status", ServiceUp.String(), "latency", latency)
+		return ServiceUp, latency
 	}
 
-	return ServiceDown
+	slog.Debug("health check", "url", url, "status", ServiceDown.String(), "code", resp.StatusCode)
+	return ServiceDown, 0
 }
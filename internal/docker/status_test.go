@@ -0,0 +1,187 @@
+package docker
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+func TestHealthURLs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_STATE_HOME", "")
+
+	cfg := config.Defaults()
+	cfg.Ports.IAM = 18080
+	cfg.Ports.KMSHTTP = 18082
+	cfg.Health.URLs.SecretManager = "http://sm.internal:8081/health"
+
+	urls := HealthURLs(cfg)
+	if urls.IAM != "http://localhost:19080/health" {
+		t.Errorf("Expected the IAM URL to follow port-iam, got %s", urls.IAM)
+	}
+	if urls.KMS != "http://localhost:18082/health" {
+		t.Errorf("Expected the KMS URL to follow port-kms-http, got %s", urls.KMS)
+	}
+	if urls.SecretManager != "http://sm.internal:8081/health" {
+		t.Errorf("Expected the Secret Manager override, got %s", urls.SecretManager)
+	}
+
+	cfg.Docker.Host = "docker.internal"
+	if urls := HealthURLs(cfg); urls.IAM != "http://docker.internal:19080/health" {
+		t.Errorf("Expected the IAM URL to use the docker host, got %s", urls.IAM)
+	}
+}
+
+func TestStatusRetries(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_STATE_HOME", "")
+
+	var calls atomic.Int32
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer flaky.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer up.Close()
+
+	cfg := config.Defaults()
+	cfg.Health.URLs = config.HealthURLs{IAM: flaky.URL, SecretManager: up.URL, KMS: up.URL}
+
+	status, err := Status(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.IAM.Health != ServiceDown {
+		t.Errorf("Expected a failing check without retries to be down, got %v", status.IAM.Health)
+	}
+	if status.SecretManager.Health != ServiceUp || status.KMS.Health != ServiceUp {
+		t.Errorf("Expected healthy services to be up, got %+v", status)
+	}
+
+	calls.Store(0)
+	cfg.Health.Retries = 1
+	if status, _ := Status(cfg); status.IAM.Health != ServiceUp {
+		t.Errorf("Expected a retry to succeed, got %v", status.IAM.Health)
+	}
+}
+
+func TestServiceInfo(t *testing.T) {
+	started := time.Now().Add(-90 * time.Second)
+	running := Container{Service: "kms", State: "running", RestartCount: 2, StartedAt: started, Image: "kms:v1"}
+
+	info := serviceInfo("kms", running, true, true)
+	if info.State != "running" || info.RestartCount != 2 || info.Image != "kms:v1" {
+		t.Errorf("Expected the container's state, got %+v", info)
+	}
+	if info.Uptime < 90*time.Second || info.Uptime > 95*time.Second {
+		t.Errorf("Expected an uptime of about 90s, got %s", info.Uptime)
+	}
+
+	restarting := Container{Service: "kms", State: "restarting", ExitCode: 1, StartedAt: started}
+	if info := serviceInfo("kms", restarting, true, true); info.Uptime != 0 || info.ExitCode != 1 {
+		t.Errorf("Expected a restarting container to have no uptime and its exit code, got %+v", info)
+	}
+	if info := serviceInfo("kms", Container{}, false, true); info.State != ContainerNotCreated {
+		t.Errorf("Expected a service without a container to be not created, got %q", info.State)
+	}
+	if info := serviceInfo("kms", Container{}, false, false); info.State != "" {
+		t.Errorf("Expected an unknown state when the runtime can't be asked, got %q", info.State)
+	}
+}
+
+func TestOverall(t *testing.T) {
+	up := ServiceInfo{Health: ServiceUp}
+	down := ServiceInfo{Service: "kms", Health: ServiceDown}
+	off := ServiceInfo{Health: ServiceNotEnabled}
+
+	tests := []struct {
+		status StackStatus
+		want   string
+	}{
+		{StackStatus{IAM: up, SecretManager: up, KMS: up}, StackHealthy},
+		{StackStatus{IAM: up, SecretManager: off, KMS: up}, StackHealthy},
+		{StackStatus{IAM: up, SecretManager: up, KMS: down}, StackDegraded},
+		{StackStatus{IAM: down, SecretManager: down, KMS: off}, StackDown},
+		{StackStatus{IAM: off, SecretManager: off, KMS: off}, StackDown},
+	}
+	for i, tt := range tests {
+		if got := tt.status.Overall(); got != tt.want {
+			t.Errorf("%d: Overall() = %s, want %s", i, got, tt.want)
+		}
+	}
+	if down := (&StackStatus{IAM: up, SecretManager: off, KMS: down}).Down(); len(down) != 1 || down[0] != "kms" {
+		t.Errorf("Expected only kms down, got %v", down)
+	}
+}
+
+func TestStatusConcurrent(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_STATE_HOME", "")
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	cfg := config.Defaults()
+	cfg.Health.URLs = config.HealthURLs{IAM: slow.URL, SecretManager: slow.URL, KMS: slow.URL}
+
+	start := time.Now()
+	status, err := Status(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 800*time.Millisecond {
+		t.Errorf("Expected the checks to run at once, took %s", elapsed)
+	}
+	for _, info := range status.Services() {
+		if info.Health != ServiceUp || info.Latency < 300*time.Millisecond {
+			t.Errorf("Expected %s up with its round trip, got %v after %s", info.Service, info.Health, info.Latency)
+		}
+	}
+}
+
+func TestStatusDockerUnavailable(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_STATE_HOME", "")
+
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho 'Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?' >&2\nexit 1\n"
+	if err := os.WriteFile(filepath.Join(dir, "docker"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+
+	var calls atomic.Int32
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { calls.Add(1) }))
+	defer up.Close()
+	cfg := config.Defaults()
+	cfg.Docker.Runtime = config.RuntimeDocker
+	cfg.Health.URLs = config.HealthURLs{IAM: up.URL, SecretManager: up.URL, KMS: up.URL}
+
+	status, err := Status(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var daemonDown *DaemonDownError
+	if !errors.As(status.RuntimeErr, &daemonDown) || !errors.Is(status.RuntimeErr, ErrDaemonUnreachable) {
+		t.Errorf("Expected a *DaemonDownError, got %v", status.RuntimeErr)
+	}
+	for _, info := range status.Services() {
+		if info.Health != ServiceDockerUnavailable {
+			t.Errorf("Expected %s docker unavailable, got %v", info.Service, info.Health)
+		}
+	}
+	if calls.Load() != 0 {
+		t.Errorf("Expected no health checks without a daemon, got %d", calls.Load())
+	}
+}
@@ -0,0 +1,125 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+// Volumes returns the volumes of cfg's compose project, by the
+// label compose puts on the volumes it creates
+func Volumes(cfg *config.Config) ([]string, error) {
+	cmd := command(cfg, volumesArgs(ProjectName(cfg))...)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, commandError(failed(cfg, "volume ls"), err, stderrOf(err))
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// volumesArgs returns the docker command listing the volumes of project
+func volumesArgs(project string) []string {
+	return []string{"volume", "ls", "--quiet", "--filter", projectFilter(project)}
+}
+
+// Reset removes the stack and its volumes, wiping the emulators' state:
+// compose down -v, then any volume of the project that is left, such as
+// one of a service no longer in the compose file. Volumes of other
+// projects are never touched. It returns the volumes removed.
+func Reset(cfg *config.Config) ([]string, error) {
+	volumes, err := Volumes(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd, err := composeCommand(context.Background(), cfg, ActivePorts(cfg), "down", "--volumes", "--remove-orphans")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := runCompose(cmd, composeFailed(cfg, "down")); err != nil {
+		return nil, err
+	}
+
+	left, err := Volumes(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(left) == 0 {
+		return volumes, nil
+	}
+	rm := command(cfg, append([]string{"volume", "rm"}, left...)...)
+	if output, err := rm.CombinedOutput(); err != nil {
+		return nil, commandError(failed(cfg, "volume rm"), err, string(output))
+	}
+
+	for _, volume := range left {
+		if !slices.Contains(volumes, volume) {
+			volumes = append(volumes, volume)
+		}
+	}
+	return volumes, nil
+}
+
+// volumeHelperImage runs tar against a volume for ExportVolume and
+// ImportVolume
+const volumeHelperImage = "busybox:1.36"
+
+// VolumeLabels returns the labels of a volume. Compose checks its own
+// labels on the volumes of a project, so ImportVolume recreates them.
+func VolumeLabels(cfg *config.Config, volume string) (map[string]string, error) {
+	cmd := command(cfg, "volume", "inspect", "--format", "{{json .Labels}}", volume)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, commandError(failed(cfg, "volume inspect"), err, stderrOf(err))
+	}
+	var labels map[string]string
+	if err := json.Unmarshal(output, &labels); err != nil {
+		return nil, fmt.Errorf("failed to parse labels of volume %s: %w", volume, err)
+	}
+	return labels, nil
+}
+
+// ExportVolume writes the contents of a volume to w as a tar archive
+func ExportVolume(cfg *config.Config, volume string, w io.Writer) error {
+	var stderr bytes.Buffer
+	cmd := command(cfg, "run", "--rm", "-v", volume+":/volume:ro", volumeHelperImage, "tar", "-C", "/volume", "-cf", "-", ".")
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return commandError("failed to export volume "+volume, err, stderr.String())
+	}
+	return nil
+}
+
+// ImportVolume creates a volume with labels and fills it from the tar
+// archive r, as written by ExportVolume. The volume must not exist yet.
+func ImportVolume(cfg *config.Config, volume string, labels map[string]string, r io.Reader) error {
+	args := []string{"volume", "create"}
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		args = append(args, "--label", key+"="+labels[key])
+	}
+	create := command(cfg, append(args, volume)...)
+	if output, err := create.CombinedOutput(); err != nil {
+		return commandError(failed(cfg, "volume create"), err, string(output))
+	}
+
+	var stderr bytes.Buffer
+	cmd := command(cfg, "run", "--rm", "-i", "-v", volume+":/volume", volumeHelperImage, "tar", "-C", "/volume", "-xf", "-")
+	cmd.Stdin = r
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return commandError("failed to import volume "+volume, err, stderr.String())
+	}
+	return nil
+}
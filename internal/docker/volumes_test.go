@@ -0,0 +1,14 @@
+package docker
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestVolumesArgs(t *testing.T) {
+	got := volumesArgs("gcp-emulator-dev")
+	want := []string{"volume", "ls", "--quiet", "--filter", "label=com.docker.compose.project=gcp-emulator-dev"}
+	if !slices.Equal(got, want) {
+		t.Errorf("volumesArgs = %q, want %q", got, want)
+	}
+}
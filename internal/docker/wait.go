@@ -0,0 +1,100 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+// Bounds of the pause between health polls in WaitHealthy, which doubles
+// after each round
+const (
+	waitInitialDelay = 250 * time.Millisecond
+	waitMaxDelay     = 4 * time.Second
+)
+
+// WaitHealthy polls the health endpoint of each of services until all are
+// up or timeout elapses, backing off exponentially between rounds.
+// healthy, if not nil, is called as each service comes up, with the time
+// it took. It returns the services that never came up, or nil.
+func WaitHealthy(cfg *config.Config, services []string, timeout time.Duration, healthy func(service string, elapsed time.Duration)) []string {
+	urls := HealthURLs(cfg)
+	requestTimeout := cfg.Health.Timeout
+	if requestTimeout == 0 {
+		requestTimeout = config.DefaultHealthTimeout
+	}
+	client := &http.Client{Timeout: requestTimeout}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	start := time.Now()
+	pending := append([]string(nil), services...)
+	Poll(ctx, waitInitialDelay, waitMaxDelay, func() bool {
+		results := probe(pending, func(service string) ServiceStatus {
+			health, _ := checkHealth(client, healthURL(urls, service))
+			return health
+		})
+		var down []string
+		for i, service := range pending {
+			if results[i] != ServiceUp {
+				down = append(down, service)
+				continue
+			}
+			if healthy != nil {
+				healthy(service, time.Since(start))
+			}
+		}
+		pending = down
+		return len(pending) == 0
+	})
+	return pending
+}
+
+// Poll runs poll in rounds until it returns true or ctx is done, pausing
+// between rounds for a delay that starts at initial and doubles after
+// each round up to max, so a fixed interval is an initial equal to max.
+// A last round is run at the deadline of ctx, if it has one. Poll reports
+// whether poll returned true.
+func Poll(ctx context.Context, initial, max time.Duration, poll func() bool) bool {
+	for delay := initial; ; delay = min(2*delay, max) {
+		if poll() {
+			return true
+		}
+		wait := delay
+		if deadline, ok := ctx.Deadline(); ok {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return false
+			}
+			wait = min(delay, remaining)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return poll()
+			}
+			return false
+		case <-timer.C:
+		}
+	}
+}
+
+// healthURL returns the URL of service in urls
+func healthURL(urls config.HealthURLs, service string) string {
+	switch service {
+	case "iam":
+		return urls.IAM
+	case "secret-manager":
+		return urls.SecretManager
+	case "kms":
+		return urls.KMS
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,90 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+func TestWaitHealthy(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_STATE_HOME", "")
+
+	var calls atomic.Int32
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer slow.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	cfg := config.Defaults()
+	cfg.Health.URLs = config.HealthURLs{IAM: slow.URL, SecretManager: up.URL, KMS: down.URL}
+
+	var healthy []string
+	unhealthy := WaitHealthy(cfg, []string{"iam", "secret-manager"}, 5*time.Second, func(service string, elapsed time.Duration) {
+		healthy = append(healthy, service)
+	})
+	if len(unhealthy) != 0 {
+		t.Errorf("Expected every service to come up, got %v still down", unhealthy)
+	}
+	if strings.Join(healthy, ",") != "secret-manager,iam" {
+		t.Errorf("Expected secret-manager, then iam once its checks pass, got %v", healthy)
+	}
+
+	unhealthy = WaitHealthy(cfg, Services, 300*time.Millisecond, nil)
+	if strings.Join(unhealthy, ",") != "kms" {
+		t.Errorf("Expected kms to time out, got %v", unhealthy)
+	}
+}
+
+func TestPoll(t *testing.T) {
+	rounds := 0
+	if !Poll(context.Background(), time.Millisecond, 4*time.Millisecond, func() bool {
+		rounds++
+		return rounds == 4
+	}) {
+		t.Error("Expected Poll to report the round that succeeded")
+	}
+	if rounds != 4 {
+		t.Errorf("Expected 4 rounds, got %d", rounds)
+	}
+
+	// A last round runs at the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	var last time.Duration
+	if Poll(ctx, 10*time.Millisecond, time.Second, func() bool {
+		last = time.Since(start)
+		return false
+	}) {
+		t.Error("Expected Poll to fail at the deadline")
+	}
+	if last < 50*time.Millisecond {
+		t.Errorf("Expected a round at the deadline, the last was after %s", last)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	rounds = 0
+	Poll(ctx, time.Hour, time.Hour, func() bool {
+		rounds++
+		return false
+	})
+	if rounds != 1 {
+		t.Errorf("Expected a cancelled poll to stop after its first round, got %d", rounds)
+	}
+}
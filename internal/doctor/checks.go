@@ -0,0 +1,254 @@
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+// Options selects optional checks
+type Options struct {
+	// Network checks that the configured images can be pulled, which
+	// needs registry access
+	Network bool
+
+	// PolicyLoad reads the policy file, decrypting an encrypted one
+	PolicyLoad policy.LoadOptions
+}
+
+// Checks returns the standard checks. configErr is the error from reading
+// the configuration, if any; cfg should then be the defaults.
+func Checks(cfg *config.Config, configErr error, opts Options) []Check {
+	podman := docker.RuntimeName(cfg) == config.RuntimePodman
+	checks := []Check{
+		ConfigCheck{File: config.FileUsed(), Err: configErr},
+		DockerCheck{Version: func() (string, error) { return docker.DaemonVersion(cfg) }, Podman: podman},
+		ComposeCheck{Version: func() (string, string, error) { return docker.ComposeVersion(cfg) }, Podman: podman},
+	}
+	if opts.Network {
+		checks = append(checks, ImagesCheck{
+			Images:   imageRefs(cfg),
+			Pullable: func(ref string) error { return docker.ImagePullable(cfg, ref) },
+		})
+	}
+	return append(checks,
+		PortsCheck{
+			Ports:   docker.ConfiguredPorts(cfg),
+			Auto:    cfg.Ports.Auto,
+			Remote:  cfg.Docker.Remote(),
+			Running: func() (bool, error) { return docker.Running(cfg) },
+			Check:   docker.CheckPorts,
+		},
+		PolicyCheck{Config: cfg, Load: opts.PolicyLoad},
+	)
+}
+
+// imageRefs returns the image of each service, filling in the default for
+// those that aren't set
+func imageRefs(cfg *config.Config) []string {
+	defaults := config.Defaults().Images
+	var refs []string
+	for _, image := range []struct{ ref, fallback string }{
+		{cfg.Images.IAM, defaults.IAM},
+		{cfg.Images.SecretManager, defaults.SecretManager},
+		{cfg.Images.KMS, defaults.KMS},
+	} {
+		if image.ref == "" {
+			image.ref = image.fallback
+		}
+		refs = append(refs, image.ref)
+	}
+	return refs
+}
+
+// ConfigCheck reports whether the configuration could be read
+type ConfigCheck struct {
+	// File is the config file in use, or "" if there is none
+	File string
+	Err  error
+}
+
+func (c ConfigCheck) Name() string { return "Config file" }
+
+func (c ConfigCheck) Run() Result {
+	if c.Err != nil {
+		return Result{
+			Status:  Fail,
+			Message: c.Err.Error(),
+			Hint:    "Fix or remove the config file; the other checks use the defaults",
+		}
+	}
+	if c.File == "" {
+		return Result{Status: Pass, Message: "no config file, using defaults"}
+	}
+	return Result{Status: Pass, Message: c.File}
+}
+
+// DockerCheck reports whether the docker daemon, or podman service, is
+// reachable
+type DockerCheck struct {
+	Version func() (string, error)
+
+	// Podman is set when the stack runs on podman
+	Podman bool
+}
+
+func (c DockerCheck) Name() string {
+	if c.Podman {
+		return "Podman service"
+	}
+	return "Docker daemon"
+}
+
+func (c DockerCheck) Run() Result {
+	version, err := c.Version()
+	if err != nil {
+		hint := "Start Docker Desktop, or the docker service (sudo systemctl start docker)"
+		if c.Podman {
+			hint = "Start the podman machine (podman machine start), or the socket (systemctl --user start podman.socket)"
+		}
+		return Result{
+			Status:  Fail,
+			Message: firstLine(err),
+			Hint:    hint,
+		}
+	}
+	return Result{Status: Pass, Message: "reachable, server " + version}
+}
+
+// ComposeCheck reports which compose command is available
+type ComposeCheck struct {
+	Version func() (command, version string, err error)
+
+	// Podman is set when the stack runs on podman
+	Podman bool
+}
+
+func (c ComposeCheck) Name() string {
+	if c.Podman {
+		return "Podman compose"
+	}
+	return "Docker compose"
+}
+
+func (c ComposeCheck) Run() Result {
+	command, version, err := c.Version()
+	if err != nil {
+		hint := "Install the compose plugin: https://docs.docker.com/compose/install/"
+		if c.Podman {
+			hint = "Install podman-compose (pip install podman-compose), or docker-compose for podman compose to run"
+		}
+		return Result{
+			Status:  Fail,
+			Message: firstLine(err),
+			Hint:    hint,
+		}
+	}
+	if command == "docker-compose" {
+		return Result{
+			Status:  Warn,
+			Message: fmt.Sprintf("%s %s (legacy standalone binary)", command, version),
+			Hint:    "Install the compose v2 plugin; docker-compose v1 is no longer maintained",
+		}
+	}
+	return Result{Status: Pass, Message: fmt.Sprintf("%s %s", command, version)}
+}
+
+// ImagesCheck reports whether every image can be pulled
+type ImagesCheck struct {
+	Images   []string
+	Pullable func(ref string) error
+}
+
+func (c ImagesCheck) Name() string { return "Images" }
+
+func (c ImagesCheck) Run() Result {
+	var missing []string
+	for _, ref := range c.Images {
+		if err := c.Pullable(ref); err != nil {
+			missing = append(missing, ref)
+		}
+	}
+	if len(missing) > 0 {
+		return Result{
+			Status:  Fail,
+			Message: "not pullable: " + strings.Join(missing, ", "),
+			Hint:    "Check the image-* config keys, and log in to the registry (docker login ghcr.io)",
+		}
+	}
+	return Result{Status: Pass, Message: fmt.Sprintf("%d images pullable", len(c.Images))}
+}
+
+// PortsCheck reports whether the configured ports are free, or held by
+// the stack itself
+type PortsCheck struct {
+	Ports docker.Ports
+	Auto  bool
+
+	// Remote skips the check, since only local ports can be probed
+	Remote  bool
+	Running func() (bool, error)
+	Check   func(docker.Ports) error
+}
+
+func (c PortsCheck) Name() string { return "Ports" }
+
+func (c PortsCheck) Run() Result {
+	if c.Remote {
+		return Result{Status: Pass, Message: "not checked, the stack runs on a remote host"}
+	}
+	if c.Auto {
+		return Result{Status: Pass, Message: "picked when the stack starts (ports.auto)"}
+	}
+	if running, _ := c.Running(); running {
+		return Result{Status: Pass, Message: "in use by the running stack"}
+	}
+
+	err := c.Check(c.Ports)
+	if err == nil {
+		return Result{Status: Pass, Message: "all free"}
+	}
+	return Result{
+		Status:  Fail,
+		Message: strings.ReplaceAll(err.Error(), "\n", "; "),
+		Hint:    "Free the ports, change them with 'gcp-emulator config set port-iam|port-secret-manager|port-kms', or start with --auto-ports",
+	}
+}
+
+// PolicyCheck reports whether the policy file loads. In off mode the IAM
+// emulator doesn't use it, so a problem only warns.
+type PolicyCheck struct {
+	Config *config.Config
+	Load   policy.LoadOptions
+}
+
+func (c PolicyCheck) Name() string { return "Policy file" }
+
+func (c PolicyCheck) Run() Result {
+	err := c.Config.ValidatePolicyFile(c.Load)
+	if err == nil {
+		return Result{Status: Pass, Message: c.Config.PolicyFile + " is valid"}
+	}
+
+	result := Result{Status: Fail, Message: firstLine(err)}
+	if errors.Is(err, config.ErrNoPolicyFile) {
+		result.Hint = "Create one with 'gcp-emulator policy init', or set policy-file"
+	} else {
+		result.Hint = fmt.Sprintf("See the details with 'gcp-emulator policy validate %s'", c.Config.PolicyFile)
+	}
+	if c.Config.IAMMode == "off" {
+		result.Status = Warn
+		result.Message += " (unused in off mode)"
+	}
+	return result
+}
+
+// firstLine returns the first line of err, leaving out command output
+func firstLine(err error) string {
+	line, _, _ := strings.Cut(err.Error(), "\n")
+	return line
+}
@@ -0,0 +1,66 @@
+// Package doctor diagnoses the local setup the emulator stack needs:
+// docker, compose, images, ports, the policy file, and the config file.
+//
+// Each diagnostic is a Check. New ones implement Check and are added to
+// the list returned by Checks.
+package doctor
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	Pass Status = "pass"
+	Warn Status = "warn"
+	Fail Status = "fail"
+)
+
+// Result is what a check found
+type Result struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+
+	// Hint tells how to fix a warning or failure
+	Hint string `json:"hint,omitempty"`
+}
+
+// Check is one diagnostic
+type Check interface {
+	// Name labels the check in the report
+	Name() string
+
+	// Run performs the check. The result's Name is filled in by Run.
+	Run() Result
+}
+
+// Report is the result of every check, in order
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// Run performs each check in turn
+func Run(checks []Check) Report {
+	report := Report{Results: make([]Result, 0, len(checks))}
+	for _, check := range checks {
+		result := check.Run()
+		result.Name = check.Name()
+		report.Results = append(report.Results, result)
+	}
+	return report
+}
+
+// Count returns the number of results with the status
+func (r Report) Count(status Status) int {
+	n := 0
+	for _, result := range r.Results {
+		if result.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed reports whether any check failed. Warnings don't count.
+func (r Report) Failed() bool {
+	return r.Count(Fail) > 0
+}
@@ -0,0 +1,138 @@
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+)
+
+// fakeCheck returns a fixed result
+type fakeCheck struct {
+	name   string
+	result Result
+}
+
+func (c fakeCheck) Name() string { return c.name }
+func (c fakeCheck) Run() Result  { return c.result }
+
+func TestRun(t *testing.T) {
+	report := Run([]Check{
+		fakeCheck{"a", Result{Status: Pass}},
+		fakeCheck{"b", Result{Status: Warn}},
+	})
+	if report.Failed() {
+		t.Error("Expected warnings not to fail the report")
+	}
+	if report.Results[1].Name != "b" {
+		t.Errorf("Expected results to be named after their checks, got %+v", report.Results)
+	}
+
+	report = Run([]Check{fakeCheck{"a", Result{Status: Pass}}, fakeCheck{"c", Result{Status: Fail}}})
+	if !report.Failed() || report.Count(Fail) != 1 || report.Count(Pass) != 1 {
+		t.Errorf("Expected one pass and one failure, got %+v", report)
+	}
+}
+
+func TestDockerCheck(t *testing.T) {
+	pass := DockerCheck{Version: func() (string, error) { return "27.1.1", nil }}.Run()
+	if pass.Status != Pass || !strings.Contains(pass.Message, "27.1.1") {
+		t.Errorf("Expected a pass with the version, got %+v", pass)
+	}
+
+	fail := DockerCheck{Version: func() (string, error) {
+		return "", errors.New("docker daemon not reachable: exit status 1\nCannot connect to the Docker daemon")
+	}}.Run()
+	if fail.Status != Fail || strings.Contains(fail.Message, "\n") || fail.Hint == "" {
+		t.Errorf("Expected a one-line failure with a hint, got %+v", fail)
+	}
+}
+
+func TestComposeCheck(t *testing.T) {
+	tests := []struct {
+		command string
+		err     error
+		want    Status
+	}{
+		{command: "docker compose", want: Pass},
+		{command: "docker-compose", want: Warn},
+		{command: "docker-compose", err: errors.New("not found"), want: Fail},
+	}
+	for _, tt := range tests {
+		result := ComposeCheck{Version: func() (string, string, error) { return tt.command, "2.29.1", tt.err }}.Run()
+		if result.Status != tt.want {
+			t.Errorf("%s (err %v): expected %s, got %+v", tt.command, tt.err, tt.want, result)
+		}
+	}
+}
+
+func TestImagesCheck(t *testing.T) {
+	check := ImagesCheck{
+		Images: []string{"ghcr.io/a/iam:latest", "ghcr.io/a/kms:v0.4.0-rc1"},
+		Pullable: func(ref string) error {
+			if strings.HasSuffix(ref, "rc1") {
+				return errors.New("manifest unknown")
+			}
+			return nil
+		},
+	}
+	result := check.Run()
+	if result.Status != Fail || !strings.Contains(result.Message, "ghcr.io/a/kms:v0.4.0-rc1") {
+		t.Errorf("Expected the unpullable image to be named, got %+v", result)
+	}
+}
+
+func TestPortsCheck(t *testing.T) {
+	conflict := func(docker.Ports) error {
+		return &docker.PortInUseError{Port: 8080, Name: "IAM", Owner: "java (pid 1)"}
+	}
+	stopped := func() (bool, error) { return false, nil }
+
+	result := PortsCheck{Running: stopped, Check: conflict}.Run()
+	if result.Status != Fail || !strings.Contains(result.Message, "8080") {
+		t.Errorf("Expected the taken port to fail, got %+v", result)
+	}
+
+	result = PortsCheck{Running: func() (bool, error) { return true, nil }, Check: conflict}.Run()
+	if result.Status != Pass {
+		t.Errorf("Expected ports held by the running stack to pass, got %+v", result)
+	}
+
+	result = PortsCheck{Auto: true, Running: stopped, Check: conflict}.Run()
+	if result.Status != Pass {
+		t.Errorf("Expected automatic ports to pass, got %+v", result)
+	}
+
+	result = PortsCheck{Remote: true, Running: stopped, Check: conflict}.Run()
+	if result.Status != Pass || !strings.Contains(result.Message, "remote") {
+		t.Errorf("Expected ports on a remote host not to be checked, got %+v", result)
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.PolicyFile = t.TempDir() + "/policy.yaml"
+
+	result := PolicyCheck{Config: cfg}.Run()
+	if result.Status != Fail || !strings.Contains(result.Hint, "policy init") {
+		t.Errorf("Expected a missing policy to fail pointing to policy init, got %+v", result)
+	}
+
+	cfg.IAMMode = "off"
+	if result := (PolicyCheck{Config: cfg}).Run(); result.Status != Warn {
+		t.Errorf("Expected a missing policy to only warn in off mode, got %+v", result)
+	}
+}
+
+func TestConfigCheck(t *testing.T) {
+	err := fmt.Errorf("failed to read config file: %w", errors.New("yaml: line 2: mapping values are not allowed"))
+	if result := (ConfigCheck{Err: err}).Run(); result.Status != Fail {
+		t.Errorf("Expected an unreadable config to fail, got %+v", result)
+	}
+	if result := (ConfigCheck{}).Run(); result.Status != Pass {
+		t.Errorf("Expected no config file to pass, got %+v", result)
+	}
+}
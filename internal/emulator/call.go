@@ -0,0 +1,143 @@
+package emulator
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	kmspb "cloud.google.com/go/kms/apiv1/kmspb"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// CallVerbs are the verbs Call supports, by service
+var CallVerbs = map[string][]string{
+	"secretmanager": {"access", "create", "list"},
+	"kms":           {"encrypt", "decrypt"},
+}
+
+// CallRequest is a data plane request made as a principal
+type CallRequest struct {
+	Service  string
+	Verb     string
+	Resource string
+
+	// Data is the payload of a secret created or of a plaintext
+	// encrypted, or the ciphertext decrypted
+	Data []byte
+
+	Principal string
+
+	// Token is sent as the bearer token of the request, if not ""
+	Token string
+}
+
+// Call makes req over gRPC to address, the gRPC port of its service, and
+// returns the response. Resources are full names: a secret version, or a
+// secret for its latest version, to access; a secret to create; a
+// project to list the secrets of; and a crypto key to encrypt or decrypt
+// with.
+func Call(ctx context.Context, address string, req CallRequest) (proto.Message, error) {
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	ctx = metadata.AppendToOutgoingContext(ctx, principalHeader, req.Principal)
+	if req.Token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+req.Token)
+	}
+
+	switch req.Service + " " + req.Verb {
+	case "secretmanager access":
+		name := req.Resource
+		if !strings.Contains(name, "/versions/") {
+			name += "/versions/latest"
+		}
+		return secretmanagerpb.NewSecretManagerServiceClient(conn).AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{Name: name})
+	case "secretmanager create":
+		parent, secretID, ok := strings.Cut(req.Resource, "/secrets/")
+		if !ok || secretID == "" || strings.Contains(secretID, "/") {
+			return nil, fmt.Errorf("expected a secret name like projects/<project>/secrets/<secret>, got %s", req.Resource)
+		}
+		client := secretmanagerpb.NewSecretManagerServiceClient(conn)
+		secret, err := client.CreateSecret(ctx, &secretmanagerpb.CreateSecretRequest{
+			Parent:   parent,
+			SecretId: secretID,
+			Secret: &secretmanagerpb.Secret{
+				Replication: &secretmanagerpb.Replication{
+					Replication: &secretmanagerpb.Replication_Automatic_{Automatic: &secretmanagerpb.Replication_Automatic{}},
+				},
+			},
+		})
+		if err != nil || req.Data == nil {
+			return secret, err
+		}
+		return client.AddSecretVersion(ctx, &secretmanagerpb.AddSecretVersionRequest{
+			Parent:  secret.GetName(),
+			Payload: &secretmanagerpb.SecretPayload{Data: req.Data},
+		})
+	case "secretmanager list":
+		client := secretmanagerpb.NewSecretManagerServiceClient(conn)
+		all := &secretmanagerpb.ListSecretsResponse{}
+		listReq := &secretmanagerpb.ListSecretsRequest{Parent: req.Resource}
+		for {
+			resp, err := client.ListSecrets(ctx, listReq)
+			if err != nil {
+				return nil, err
+			}
+			all.Secrets = append(all.Secrets, resp.GetSecrets()...)
+			all.TotalSize = resp.GetTotalSize()
+			if resp.GetNextPageToken() == "" {
+				return all, nil
+			}
+			listReq.PageToken = resp.GetNextPageToken()
+		}
+	case "kms encrypt":
+		return kmspb.NewKeyManagementServiceClient(conn).Encrypt(ctx, &kmspb.EncryptRequest{Name: req.Resource, Plaintext: req.Data})
+	case "kms decrypt":
+		return kmspb.NewKeyManagementServiceClient(conn).Decrypt(ctx, &kmspb.DecryptRequest{Name: req.Resource, Ciphertext: req.Data})
+	}
+	return nil, fmt.Errorf("unknown call: %s %s", req.Service, req.Verb)
+}
+
+// decisionIDKeys are the ErrorInfo metadata keys a denial's IAM decision
+// ID may be under
+var decisionIDKeys = []string{"decision_id", "decisionId", "decision"}
+
+// decisionIDPattern finds a decision ID in a denial's message, for
+// emulators that don't send it as an error detail
+var decisionIDPattern = regexp.MustCompile(`(?i)decision[ _-]?id[:=]?\s*([A-Za-z0-9-]+)`)
+
+// DecisionID returns the IAM decision ID carried by err, a gRPC
+// PERMISSION_DENIED error from a data plane, or "" if it has none
+func DecisionID(err error) string {
+	for _, info := range ErrorInfo(err) {
+		for _, key := range decisionIDKeys {
+			if id := info.GetMetadata()[key]; id != "" {
+				return id
+			}
+		}
+	}
+	if m := decisionIDPattern.FindStringSubmatch(status.Convert(err).Message()); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// ErrorInfo returns the ErrorInfo details of err, a gRPC error
+func ErrorInfo(err error) []*errdetails.ErrorInfo {
+	var infos []*errdetails.ErrorInfo
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
@@ -0,0 +1,109 @@
+package emulator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// recordingSecretManager serves secrets to alice and denies everyone
+// else, recording the metadata of the last request
+type recordingSecretManager struct {
+	secretmanagerpb.UnimplementedSecretManagerServiceServer
+	md metadata.MD
+}
+
+func (s *recordingSecretManager) AccessSecretVersion(ctx context.Context, req *secretmanagerpb.AccessSecretVersionRequest) (*secretmanagerpb.AccessSecretVersionResponse, error) {
+	s.md, _ = metadata.FromIncomingContext(ctx)
+	if s.md.Get("x-emulator-principal")[0] != "user:alice@example.com" {
+		return nil, deniedError("d42")
+	}
+	return &secretmanagerpb.AccessSecretVersionResponse{
+		Name:    req.GetName(),
+		Payload: &secretmanagerpb.SecretPayload{Data: []byte("hunter2")},
+	}, nil
+}
+
+// deniedError is a data plane's denial of decision id
+func deniedError(id string) error {
+	st, err := status.New(codes.PermissionDenied, "permission denied").WithDetails(&errdetails.ErrorInfo{
+		Reason:   "IAM_PERMISSION_DENIED",
+		Domain:   "gcp-emulator",
+		Metadata: map[string]string{"decision_id": id, "permission": "secretmanager.versions.access"},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return st.Err()
+}
+
+func TestCall(t *testing.T) {
+	server := &recordingSecretManager{}
+	address := testGRPCServer(t, func(s *grpc.Server) {
+		secretmanagerpb.RegisterSecretManagerServiceServer(s, server)
+	})
+
+	resp, err := Call(context.Background(), address, CallRequest{
+		Service:   "secretmanager",
+		Verb:      "access",
+		Resource:  "projects/p/secrets/s",
+		Principal: "user:alice@example.com",
+		Token:     "tok",
+	})
+	if err != nil {
+		t.Fatalf("Call() error: %v", err)
+	}
+	access := resp.(*secretmanagerpb.AccessSecretVersionResponse)
+	if access.GetName() != "projects/p/secrets/s/versions/latest" || string(access.GetPayload().GetData()) != "hunter2" {
+		t.Errorf("Expected the latest version, got %v", access)
+	}
+	if got := server.md.Get("authorization"); len(got) != 1 || got[0] != "Bearer tok" {
+		t.Errorf("Expected the bearer token, got %v", got)
+	}
+
+	_, err = Call(context.Background(), address, CallRequest{
+		Service:   "secretmanager",
+		Verb:      "access",
+		Resource:  "projects/p/secrets/s/versions/1",
+		Principal: "user:bob@example.com",
+	})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("Expected PERMISSION_DENIED, got %v", err)
+	}
+	if id := DecisionID(err); id != "d42" {
+		t.Errorf("DecisionID() = %q, want d42", id)
+	}
+	if infos := ErrorInfo(err); len(infos) != 1 || infos[0].GetReason() != "IAM_PERMISSION_DENIED" {
+		t.Errorf("Expected the ErrorInfo detail, got %v", infos)
+	}
+
+	if _, err := Call(context.Background(), address, CallRequest{Service: "secretmanager", Verb: "create", Resource: "projects/p"}); err == nil {
+		t.Error("Expected an error for a create without a secret name")
+	}
+}
+
+func TestDecisionID(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"error info", deniedError("7f3c9a2e"), "7f3c9a2e"},
+		{"message", status.Error(codes.PermissionDenied, "permission denied (decision id: 7f3c9a2e)"), "7f3c9a2e"},
+		{"none", status.Error(codes.PermissionDenied, "permission denied"), ""},
+		{"not grpc", errors.New("decision_id=abc"), "abc"},
+		{"nil", nil, ""},
+	}
+	for _, tt := range tests {
+		if got := DecisionID(tt.err); got != tt.want {
+			t.Errorf("%s: DecisionID() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package emulator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ErrTraceUnavailable is returned when the emulator does not serve its
+// decision log, because trace mode is off or the image predates it
+var ErrTraceUnavailable = errors.New("IAM emulator decision log is not available")
+
+// ErrDecisionNotFound is returned when a decision ID is not in the log,
+// because trace mode was off when it was made or it has been evicted
+var ErrDecisionNotFound = errors.New("decision not found in the IAM emulator decision log")
+
+// decisionsPath is the admin endpoint that serves the decision log as
+// newline-delimited JSON
+const decisionsPath = "/admin/decisions"
+
+// Decision is an authorization decision recorded by the IAM emulator
+type Decision struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	Principal  string    `json:"principal"`
+	Permission string    `json:"permission"`
+	Resource   string    `json:"resource"`
+
+	// Result is "allow" or "deny"
+	Result string `json:"decision"`
+
+	// Binding is the binding that granted the permission, if any
+	Binding *DecisionBinding `json:"binding,omitempty"`
+
+	// Condition is the result of the matched binding's condition
+	Condition *DecisionCondition `json:"condition,omitempty"`
+}
+
+// DecisionBinding identifies a binding in the emulator's policy
+type DecisionBinding struct {
+	Project string `json:"project"`
+	Index   int    `json:"index"`
+	Role    string `json:"role"`
+}
+
+// DecisionCondition is the outcome of evaluating a binding condition
+type DecisionCondition struct {
+	Expression string `json:"expression"`
+	Result     bool   `json:"result"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Decisions reads the emulator's decision log, calling fn for each
+// decision in order. With follow, the connection stays open and new
+// decisions are delivered as they happen until ctx is cancelled or fn
+// returns an error.
+func (c *IAMClient) Decisions(ctx context.Context, follow bool, fn func(Decision) error) error {
+	endpoint := c.BaseURL + decisionsPath
+	client := c.HTTP
+	if follow {
+		endpoint += "?follow=true"
+		// The stream is open-ended, so only ctx ends it
+		client = &http.Client{Transport: c.HTTP.Transport}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.send(client, req, ErrTraceUnavailable)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var d Decision
+		if err := dec.Decode(&d); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to decode decision: %w", err)
+		}
+		if err := fn(d); err != nil {
+			return err
+		}
+	}
+}
+
+// Decision fetches a single decision by ID
+func (c *IAMClient) Decision(id string) (*Decision, error) {
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+decisionsPath+"/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.send(c.HTTP, req, ErrDecisionNotFound)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var d Decision
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return nil, fmt.Errorf("failed to decode decision: %w", err)
+	}
+
+	return &d, nil
+}
@@ -0,0 +1,76 @@
+package emulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDecisions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/decisions" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, `{"id":"d1","principal":"user:alice@example.com","permission":"secretmanager.secrets.get","decision":"allow","binding":{"project":"test-project","index":0,"role":"roles/viewer"}}`)
+		fmt.Fprintln(w, `{"id":"d2","principal":"user:bob@example.com","permission":"secretmanager.secrets.get","decision":"deny"}`)
+	}))
+	defer server.Close()
+
+	var got []Decision
+	err := newTestClient(server.URL).Decisions(context.Background(), false, func(d Decision) error {
+		got = append(got, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Decisions() error: %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 decisions, got %d", len(got))
+	}
+	if got[0].Binding == nil || got[0].Binding.Role != "roles/viewer" {
+		t.Errorf("Expected binding on first decision, got %+v", got[0].Binding)
+	}
+	if got[1].Result != "deny" {
+		t.Errorf("Expected second decision deny, got %s", got[1].Result)
+	}
+}
+
+func TestDecisionsUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	err := newTestClient(server.URL).Decisions(context.Background(), true, func(Decision) error { return nil })
+	if !errors.Is(err, ErrTraceUnavailable) {
+		t.Errorf("Expected ErrTraceUnavailable, got %v", err)
+	}
+}
+
+func TestDecisionByID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/decisions/d2" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, `{"id":"d2","principal":"user:bob@example.com","decision":"deny"}`)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+
+	d, err := client.Decision("d2")
+	if err != nil {
+		t.Fatalf("Decision() error: %v", err)
+	}
+	if d.Principal != "user:bob@example.com" || d.Result != "deny" {
+		t.Errorf("Unexpected decision: %+v", d)
+	}
+
+	if _, err := client.Decision("missing"); !errors.Is(err, ErrDecisionNotFound) {
+		t.Errorf("Expected ErrDecisionNotFound, got %v", err)
+	}
+}
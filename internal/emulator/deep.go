@@ -0,0 +1,171 @@
+package emulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	iampb "cloud.google.com/go/iam/apiv1/iampb"
+	kmspb "cloud.google.com/go/kms/apiv1/kmspb"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	grpchealth "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Layers of a service that a deep check reports, in order. DeepCheck
+// calls the gRPC and API layers; the container and HTTP health endpoint
+// are what status found.
+const (
+	LayerContainer = "container"
+	LayerHTTP      = "http"
+	LayerGRPC      = "grpc"
+	LayerAPI       = "api"
+)
+
+// Outcomes of a layer of a deep check
+const (
+	LayerPass    = "pass"
+	LayerFail    = "fail"
+	LayerSkipped = "skipped"
+)
+
+// deepCheckProject is the project the no-op API calls of a deep check
+// name. Nothing is created in it.
+const deepCheckProject = "gcp-emulator-deep-check"
+
+// deepCheckPrincipal is the principal the API calls are made as. Outside
+// off mode the data planes deny it, which still proves the API answers.
+const deepCheckPrincipal = "serviceAccount:deep-check@gcp-emulator.iam.gserviceaccount.com"
+
+// LayerResult is what a deep check found of one layer
+type LayerResult struct {
+	Layer  string
+	Status string
+
+	// Detail describes a layer that passed or was skipped, such as
+	// "SERVING"; Err is why one failed
+	Detail string
+	Err    error
+
+	// Latency is the round trip of a call that passed
+	Latency time.Duration
+}
+
+// DeepCheck calls the gRPC surface of service on address, the gRPC
+// port: the gRPC health service, then a call of its API that changes
+// nothing, TestIamPermissions for IAM, ListSecrets for Secret Manager,
+// and ListKeyRings for KMS. Each call times out after timeout.
+func DeepCheck(ctx context.Context, service, address string, timeout time.Duration) []LayerResult {
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return []LayerResult{
+			{Layer: LayerGRPC, Status: LayerFail, Err: err},
+			{Layer: LayerAPI, Status: LayerSkipped, Detail: "no gRPC connection"},
+		}
+	}
+	defer conn.Close()
+	ctx = metadata.AppendToOutgoingContext(ctx, principalHeader, deepCheckPrincipal)
+
+	health := callLayer(ctx, LayerGRPC, timeout, func(ctx context.Context) (string, error) {
+		resp, err := grpchealth.NewHealthClient(conn).Check(ctx, &grpchealth.HealthCheckRequest{})
+		if err != nil {
+			return "", err
+		}
+		if resp.GetStatus() != grpchealth.HealthCheckResponse_SERVING {
+			return "", fmt.Errorf("gRPC health is %s", resp.GetStatus())
+		}
+		return resp.GetStatus().String(), nil
+	})
+	switch {
+	case errors.Is(health.Err, errUnimplemented):
+		// Emulators that don't serve gRPC health are checked by their API
+		health = LayerResult{Layer: LayerGRPC, Status: LayerSkipped, Detail: "gRPC health service not served"}
+	case errors.Is(health.Err, errUnavailable):
+		return []LayerResult{health, {Layer: LayerAPI, Status: LayerSkipped, Detail: "gRPC port unreachable"}}
+	}
+
+	api := callLayer(ctx, LayerAPI, timeout, func(ctx context.Context) (string, error) {
+		parent := "projects/" + deepCheckProject
+		switch service {
+		case "iam":
+			_, err := iampb.NewIAMPolicyClient(conn).TestIamPermissions(ctx, &iampb.TestIamPermissionsRequest{
+				Resource:    parent,
+				Permissions: []string{"secretmanager.secrets.list"},
+			})
+			return "TestIamPermissions", err
+		case "secret-manager":
+			_, err := secretmanagerpb.NewSecretManagerServiceClient(conn).ListSecrets(ctx, &secretmanagerpb.ListSecretsRequest{
+				Parent:   parent,
+				PageSize: 1,
+			})
+			return "ListSecrets", err
+		case "kms":
+			_, err := kmspb.NewKeyManagementServiceClient(conn).ListKeyRings(ctx, &kmspb.ListKeyRingsRequest{
+				Parent:   parent + "/locations/global",
+				PageSize: 1,
+			})
+			return "ListKeyRings", err
+		}
+		return "", fmt.Errorf("unknown service %q", service)
+	})
+	return []LayerResult{health, api}
+}
+
+// errUnimplemented marks a layer whose service isn't served, and
+// errUnavailable one whose port doesn't answer
+var (
+	errUnimplemented = errors.New("unimplemented")
+	errUnavailable   = errors.New("not reachable")
+)
+
+// callLayer runs call as layer, within timeout, timing a call that passes.
+// A denial or missing resource passes, since the API answered.
+func callLayer(ctx context.Context, layer string, timeout time.Duration, call func(context.Context) (string, error)) LayerResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	detail, err := call(ctx)
+	latency := time.Since(start)
+	switch status.Code(err) {
+	case codes.OK:
+		return LayerResult{Layer: layer, Status: LayerPass, Detail: detail, Latency: latency}
+	case codes.PermissionDenied, codes.NotFound:
+		return LayerResult{Layer: layer, Status: LayerPass, Detail: detail + " answered " + status.Code(err).String(), Latency: latency}
+	case codes.Unimplemented:
+		return LayerResult{Layer: layer, Status: LayerFail, Err: fmt.Errorf("%w: %s", errUnimplemented, status.Convert(err).Message())}
+	case codes.Unavailable:
+		return LayerResult{Layer: layer, Status: LayerFail, Err: fmt.Errorf("%w: %s", errUnavailable, status.Convert(err).Message())}
+	}
+	msg := status.Convert(err).Message()
+	if detail != "" {
+		msg = detail + ": " + msg
+	}
+	return LayerResult{Layer: layer, Status: LayerFail, Err: fmt.Errorf("%s (%s)", msg, status.Code(err))}
+}
+
+// DeepCheckAll runs DeepCheck concurrently for each service in
+// addresses, which maps services to their gRPC address
+func DeepCheckAll(ctx context.Context, addresses map[string]string, timeout time.Duration) map[string][]LayerResult {
+	results := make(map[string][]LayerResult, len(addresses))
+	var mu sync.Mutex
+	var g errgroup.Group
+	for service, address := range addresses {
+		g.Go(func() error {
+			layers := DeepCheck(ctx, service, address, timeout)
+			mu.Lock()
+			results[service] = layers
+			mu.Unlock()
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return results
+}
@@ -0,0 +1,93 @@
+package emulator
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	kmspb "cloud.google.com/go/kms/apiv1/kmspb"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	grpchealth "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// deniedSecretManager denies every call, as the data plane does outside
+// off mode
+type deniedSecretManager struct {
+	secretmanagerpb.UnimplementedSecretManagerServiceServer
+}
+
+func (deniedSecretManager) ListSecrets(context.Context, *secretmanagerpb.ListSecretsRequest) (*secretmanagerpb.ListSecretsResponse, error) {
+	return nil, status.Error(codes.PermissionDenied, "permission denied")
+}
+
+// testGRPCServer serves register on a local port and returns its address
+func testGRPCServer(t *testing.T, register func(*grpc.Server)) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error: %v", err)
+	}
+	server := grpc.NewServer()
+	register(server)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+func TestDeepCheck(t *testing.T) {
+	address := testGRPCServer(t, func(s *grpc.Server) {
+		grpchealth.RegisterHealthServer(s, health.NewServer())
+		secretmanagerpb.RegisterSecretManagerServiceServer(s, deniedSecretManager{})
+	})
+
+	layers := DeepCheck(context.Background(), "secret-manager", address, 5*time.Second)
+	if len(layers) != 2 || layers[0].Layer != LayerGRPC || layers[1].Layer != LayerAPI {
+		t.Fatalf("Expected the grpc and api layers, got %+v", layers)
+	}
+	if layers[0].Status != LayerPass || layers[0].Detail != "SERVING" {
+		t.Errorf("Expected gRPC health SERVING to pass, got %+v", layers[0])
+	}
+	if layers[1].Status != LayerPass {
+		t.Errorf("Expected a denied ListSecrets to pass, got %+v", layers[1])
+	}
+}
+
+func TestDeepCheckUnimplemented(t *testing.T) {
+	// KMS serves no gRPC health service and no ListKeyRings
+	address := testGRPCServer(t, func(s *grpc.Server) {
+		kmspb.RegisterKeyManagementServiceServer(s, kmspb.UnimplementedKeyManagementServiceServer{})
+	})
+
+	layers := DeepCheckAll(context.Background(), map[string]string{"kms": address}, 5*time.Second)["kms"]
+	if len(layers) != 2 {
+		t.Fatalf("Expected 2 layers, got %+v", layers)
+	}
+	if layers[0].Status != LayerSkipped {
+		t.Errorf("Expected a missing gRPC health service to be skipped, got %+v", layers[0])
+	}
+	if layers[1].Status != LayerFail || layers[1].Err == nil {
+		t.Errorf("Expected an unimplemented ListKeyRings to fail, got %+v", layers[1])
+	}
+}
+
+func TestDeepCheckUnreachable(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error: %v", err)
+	}
+	address := lis.Addr().String()
+	lis.Close()
+
+	layers := DeepCheck(context.Background(), "iam", address, time.Second)
+	if layers[0].Status != LayerFail {
+		t.Errorf("Expected grpc to fail with nothing listening, got %+v", layers[0])
+	}
+	if layers[1].Status != LayerSkipped {
+		t.Errorf("Expected api to be skipped when grpc is unreachable, got %+v", layers[1])
+	}
+}
@@ -0,0 +1,191 @@
+// Package emulator provides clients for the admin APIs of running emulators.
+//
+// These APIs change emulator state without a container restart, so the
+// control plane can push configuration such as IAM policy to a live stack.
+package emulator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+// ErrNotRunning is returned when the emulator cannot be reached
+var ErrNotRunning = errors.New("IAM emulator is not running")
+
+// ErrReloadUnsupported is returned when the emulator is reachable but does
+// not serve the admin policy endpoint, typically because it predates it
+var ErrReloadUnsupported = errors.New("IAM emulator does not support policy reload")
+
+// ErrPolicyChanged is returned when the emulator's policy no longer has
+// the ETag an apply was conditioned on
+var ErrPolicyChanged = errors.New("policy changed on the IAM emulator")
+
+// hashETagPrefix marks ETags computed from the policy content, used when
+// the emulator does not send an ETag header of its own
+const hashETagPrefix = "sha256:"
+
+// policyPath is the admin endpoint that serves and replaces the loaded policy
+const policyPath = "/admin/policy"
+
+// IAMClient talks to the IAM emulator's admin API, which is served on the
+// same HTTP port as its health endpoint (gRPC port + 1000)
+type IAMClient struct {
+	BaseURL string
+	HTTP    *http.Client
+}
+
+// NewIAMClient returns a client for the IAM emulator on the configured
+// host and port
+func NewIAMClient(cfg *config.Config) *IAMClient {
+	return &IAMClient{
+		BaseURL: "http://" + cfg.Docker.Address(cfg.Ports.IAM+config.IAMHTTPOffset),
+		HTTP: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+	}
+}
+
+// Policy returns the policy the emulator currently has loaded
+func (c *IAMClient) Policy() (*policy.Policy, error) {
+	current, _, err := c.PolicyWithETag()
+	return current, err
+}
+
+// PolicyWithETag returns the emulator's policy along with a token that
+// identifies its version: the emulator's ETag header if it sends one,
+// otherwise a hash of the policy content
+func (c *IAMClient) PolicyWithETag() (*policy.Policy, string, error) {
+	resp, err := c.do(http.MethodGet, nil, "")
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	var current policy.Policy
+	if err := json.NewDecoder(resp.Body).Decode(&current); err != nil {
+		return nil, "", fmt.Errorf("failed to decode emulator policy: %w", err)
+	}
+
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		if etag, err = contentETag(&current); err != nil {
+			return nil, "", err
+		}
+	}
+
+	return &current, etag, nil
+}
+
+// contentETag hashes a policy's canonical JSON encoding. Map keys are
+// sorted when encoding, so equal policies hash the same.
+func contentETag(pol *policy.Policy) (string, error) {
+	data, err := json.Marshal(pol)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal policy: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hashETagPrefix + hex.EncodeToString(sum[:]), nil
+}
+
+// ApplyPolicy replaces the emulator's loaded policy. The emulator swaps it
+// in atomically; requests in flight finish against the old policy.
+func (c *IAMClient) ApplyPolicy(pol *policy.Policy) error {
+	return c.put(pol, "")
+}
+
+// ApplyPolicyIfMatch replaces the emulator's policy only if its current
+// version is etag, as returned by PolicyWithETag, and returns
+// ErrPolicyChanged otherwise. Emulator ETags are sent as an If-Match
+// precondition; content hashes are compared against a fresh fetch, which
+// narrows but does not close the window for a concurrent change.
+func (c *IAMClient) ApplyPolicyIfMatch(pol *policy.Policy, etag string) error {
+	if etag == "" {
+		return c.ApplyPolicy(pol)
+	}
+
+	ifMatch := etag
+	if strings.HasPrefix(etag, hashETagPrefix) {
+		_, current, err := c.PolicyWithETag()
+		if err != nil {
+			return err
+		}
+		if current != etag {
+			return ErrPolicyChanged
+		}
+		ifMatch = ""
+	}
+
+	return c.put(pol, ifMatch)
+}
+
+// put sends pol to the admin policy endpoint
+func (c *IAMClient) put(pol *policy.Policy, ifMatch string) error {
+	data, err := json.Marshal(pol)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy: %w", err)
+	}
+
+	resp, err := c.do(http.MethodPut, data, ifMatch)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// do sends a request to the admin policy endpoint, with an If-Match
+// precondition unless ifMatch is ""
+func (c *IAMClient) do(method string, body []byte, ifMatch string) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.BaseURL+policyPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+
+	return c.send(c.HTTP, req, ErrReloadUnsupported)
+}
+
+// send sends a request to the emulator and maps connection failures and
+// error statuses to descriptive errors. unsupported is returned when the
+// emulator does not serve the endpoint.
+func (c *IAMClient) send(client *http.Client, req *http.Request, unsupported error) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w at %s: %v", ErrNotRunning, c.BaseURL, err)
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return nil, fmt.Errorf("%w (%s %s returned %s)", unsupported, req.Method, req.URL.Path, resp.Status)
+	case http.StatusPreconditionFailed:
+		return nil, ErrPolicyChanged
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if detail := strings.TrimSpace(string(msg)); detail != "" {
+		return nil, fmt.Errorf("IAM emulator returned %s: %s", resp.Status, detail)
+	}
+	return nil, fmt.Errorf("IAM emulator returned %s", resp.Status)
+}
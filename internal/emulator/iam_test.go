@@ -0,0 +1,156 @@
+package emulator
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+func newTestClient(url string) *IAMClient {
+	return &IAMClient{BaseURL: url, HTTP: http.DefaultClient}
+}
+
+func TestIAMClientRoundTrip(t *testing.T) {
+	var loaded policy.Policy
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/policy" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(loaded)
+		case http.MethodPut:
+			if err := json.NewDecoder(r.Body).Decode(&loaded); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			}
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+
+	pol := &policy.Policy{
+		Roles: map[string]policy.Role{
+			"roles/custom.reader": {Permissions: []string{"secretmanager.secrets.get"}},
+		},
+	}
+	if err := client.ApplyPolicy(pol); err != nil {
+		t.Fatalf("ApplyPolicy() error: %v", err)
+	}
+
+	current, err := client.Policy()
+	if err != nil {
+		t.Fatalf("Policy() error: %v", err)
+	}
+	if _, ok := current.Roles["roles/custom.reader"]; !ok {
+		t.Errorf("Expected applied role, got %v", current.Roles)
+	}
+}
+
+func TestIAMClientErrors(t *testing.T) {
+	t.Run("not running", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		url := server.URL
+		server.Close()
+
+		_, err := newTestClient(url).Policy()
+		if !errors.Is(err, ErrNotRunning) {
+			t.Errorf("Expected ErrNotRunning, got %v", err)
+		}
+	})
+
+	t.Run("reload unsupported", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		err := newTestClient(server.URL).ApplyPolicy(&policy.Policy{})
+		if !errors.Is(err, ErrReloadUnsupported) {
+			t.Errorf("Expected ErrReloadUnsupported, got %v", err)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "role roles/x is not defined", http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		err := newTestClient(server.URL).ApplyPolicy(&policy.Policy{})
+		if err == nil || !strings.Contains(err.Error(), "role roles/x is not defined") {
+			t.Errorf("Expected emulator message in error, got %v", err)
+		}
+	})
+}
+
+func TestIAMClientETag(t *testing.T) {
+	t.Run("if-match header", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				w.Header().Set("ETag", `"v2"`)
+				json.NewEncoder(w).Encode(policy.Policy{})
+			case http.MethodPut:
+				if r.Header.Get("If-Match") != `"v2"` {
+					w.WriteHeader(http.StatusPreconditionFailed)
+				}
+			}
+		}))
+		defer server.Close()
+
+		client := newTestClient(server.URL)
+		_, etag, err := client.PolicyWithETag()
+		if err != nil {
+			t.Fatalf("PolicyWithETag failed: %v", err)
+		}
+		if etag != `"v2"` {
+			t.Errorf("Expected ETag header, got %q", etag)
+		}
+
+		if err := client.ApplyPolicyIfMatch(&policy.Policy{}, etag); err != nil {
+			t.Errorf("Expected matching apply to succeed, got %v", err)
+		}
+		if err := client.ApplyPolicyIfMatch(&policy.Policy{}, `"v1"`); !errors.Is(err, ErrPolicyChanged) {
+			t.Errorf("Expected ErrPolicyChanged, got %v", err)
+		}
+	})
+
+	t.Run("content hash", func(t *testing.T) {
+		loaded := policy.Policy{Groups: map[string]policy.Group{"devs": {Members: []string{"user:a@example.com"}}}}
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				json.NewEncoder(w).Encode(loaded)
+			case http.MethodPut:
+				json.NewDecoder(r.Body).Decode(&loaded)
+			}
+		}))
+		defer server.Close()
+
+		client := newTestClient(server.URL)
+		_, etag, err := client.PolicyWithETag()
+		if err != nil {
+			t.Fatalf("PolicyWithETag failed: %v", err)
+		}
+		if !strings.HasPrefix(etag, hashETagPrefix) {
+			t.Errorf("Expected content hash, got %q", etag)
+		}
+
+		// Someone else changes the policy after our read
+		loaded.Groups["devs"] = policy.Group{Members: []string{"user:b@example.com"}}
+
+		if err := client.ApplyPolicyIfMatch(&policy.Policy{}, etag); !errors.Is(err, ErrPolicyChanged) {
+			t.Errorf("Expected ErrPolicyChanged, got %v", err)
+		}
+		if len(loaded.Groups) == 0 {
+			t.Error("Policy was replaced despite the conflict")
+		}
+	})
+}
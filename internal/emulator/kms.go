@@ -0,0 +1,59 @@
+package emulator
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+// KMSClient talks to the KMS emulator's REST API
+type KMSClient struct {
+	BaseURL string
+	HTTP    *http.Client
+
+	// Principal, unless "", is sent as X-Emulator-Principal; outside off
+	// mode the emulator checks it against the IAM policy
+	Principal string
+}
+
+// NewKMSClient returns a client for the KMS emulator on the configured
+// host and HTTP port
+func NewKMSClient(cfg *config.Config) *KMSClient {
+	return &KMSClient{
+		BaseURL: "http://" + cfg.Docker.Address(cfg.Ports.KMSHTTP),
+		HTTP: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+	}
+}
+
+func (c *KMSClient) do(method, path string, body, out any) error {
+	return restDo(c.HTTP, "KMS emulator", c.BaseURL, c.Principal, method, path, body, out)
+}
+
+// CreateKeyRing creates a key ring. It returns ErrAlreadyExists if the key
+// ring exists.
+func (c *KMSClient) CreateKeyRing(project, location, id string) error {
+	path := fmt.Sprintf("%s/keyRings?keyRingId=%s", locationPath(project, location), url.QueryEscape(id))
+	return c.do(http.MethodPost, path, map[string]any{}, nil)
+}
+
+// CreateCryptoKey creates a key in a key ring with a purpose such as
+// ENCRYPT_DECRYPT and an algorithm such as GOOGLE_SYMMETRIC_ENCRYPTION. It
+// returns ErrAlreadyExists if the key exists.
+func (c *KMSClient) CreateCryptoKey(project, location, keyRing, id, purpose, algorithm string) error {
+	body := map[string]any{
+		"purpose":         purpose,
+		"versionTemplate": map[string]string{"algorithm": algorithm},
+	}
+	path := fmt.Sprintf("%s/keyRings/%s/cryptoKeys?cryptoKeyId=%s",
+		locationPath(project, location), url.PathEscape(keyRing), url.QueryEscape(id))
+	return c.do(http.MethodPost, path, body, nil)
+}
+
+func locationPath(project, location string) string {
+	return fmt.Sprintf("/v1/projects/%s/locations/%s", url.PathEscape(project), url.PathEscape(location))
+}
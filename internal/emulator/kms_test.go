@@ -0,0 +1,48 @@
+package emulator
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestKMSClient(t *testing.T) {
+	var key struct {
+		Purpose         string
+		VersionTemplate struct{ Algorithm string }
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/projects/test-project/locations/global/keyRings", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "key ring "+r.URL.Query().Get("keyRingId")+" exists", http.StatusConflict)
+	})
+	mux.HandleFunc("POST /v1/projects/test-project/locations/global/keyRings/app/cryptoKeys", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cryptoKeyId") != "data" {
+			http.Error(w, "wrong key", http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&key)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := &KMSClient{BaseURL: server.URL, HTTP: http.DefaultClient}
+
+	if err := client.CreateKeyRing("test-project", "global", "app"); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("CreateKeyRing = %v, want ErrAlreadyExists", err)
+	}
+	if err := client.CreateCryptoKey("test-project", "global", "app", "data", "ENCRYPT_DECRYPT", "GOOGLE_SYMMETRIC_ENCRYPTION"); err != nil {
+		t.Fatalf("CreateCryptoKey failed: %v", err)
+	}
+	if key.Purpose != "ENCRYPT_DECRYPT" || key.VersionTemplate.Algorithm != "GOOGLE_SYMMETRIC_ENCRYPTION" {
+		t.Errorf("key = %+v", key)
+	}
+
+	err := client.CreateCryptoKey("test-project", "global", "app", "other", "ENCRYPT_DECRYPT", "GOOGLE_SYMMETRIC_ENCRYPTION")
+	if err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("CreateCryptoKey of a rejected key = %v, want the status", err)
+	}
+}
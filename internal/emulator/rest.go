@@ -0,0 +1,77 @@
+package emulator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Errors of the data plane REST APIs, matched with errors.Is
+var (
+	ErrAlreadyExists = errors.New("already exists")
+	ErrNotFound      = errors.New("not found")
+)
+
+// principalHeader names the principal a data plane request is made as,
+// which outside off mode the emulator checks against the IAM policy
+const principalHeader = "X-Emulator-Principal"
+
+// restDo sends a request to the REST API a data plane emulator serves on
+// its HTTP port, which follows the Google Cloud REST API. body, if not
+// nil, is sent as JSON and the response is decoded into out, if not nil.
+// Conflicts and missing resources are returned as ErrAlreadyExists and
+// ErrNotFound; name is the emulator in other errors.
+func restDo(client *http.Client, name, baseURL, principal, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if principal != "" {
+		req.Header.Set(principalHeader, principal)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s is not reachable at %s: %v", name, baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if out == nil {
+			return nil
+		}
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode %s response: %w", name, err)
+		}
+		return nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	detail := strings.TrimSpace(string(msg))
+	switch resp.StatusCode {
+	case http.StatusConflict:
+		return fmt.Errorf("%w: %s", ErrAlreadyExists, path)
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	if detail != "" {
+		return fmt.Errorf("%s returned %s for %s %s: %s", name, resp.Status, method, path, detail)
+	}
+	return fmt.Errorf("%s returned %s for %s %s", name, resp.Status, method, path)
+}
@@ -0,0 +1,79 @@
+package emulator
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+// SecretManagerClient talks to the Secret Manager emulator's REST API
+type SecretManagerClient struct {
+	BaseURL string
+	HTTP    *http.Client
+
+	// Principal, unless "", is sent as X-Emulator-Principal; outside off
+	// mode the emulator checks it against the IAM policy
+	Principal string
+}
+
+// NewSecretManagerClient returns a client for the Secret Manager emulator
+// on the configured host and HTTP port
+func NewSecretManagerClient(cfg *config.Config) *SecretManagerClient {
+	return &SecretManagerClient{
+		BaseURL: "http://" + cfg.Docker.Address(cfg.Ports.SecretManagerHTTP),
+		HTTP: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+	}
+}
+
+func (c *SecretManagerClient) do(method, path string, body, out any) error {
+	return restDo(c.HTTP, "Secret Manager emulator", c.BaseURL, c.Principal, method, path, body, out)
+}
+
+// CreateSecret creates a secret without versions. It returns
+// ErrAlreadyExists if the secret exists.
+func (c *SecretManagerClient) CreateSecret(project, id string, labels map[string]string) error {
+	body := map[string]any{
+		"replication": map[string]any{"automatic": map[string]any{}},
+	}
+	if len(labels) > 0 {
+		body["labels"] = labels
+	}
+	path := fmt.Sprintf("/v1/projects/%s/secrets?secretId=%s", url.PathEscape(project), url.QueryEscape(id))
+	return c.do(http.MethodPost, path, body, nil)
+}
+
+// AddSecretVersion adds a version holding data to a secret
+func (c *SecretManagerClient) AddSecretVersion(project, id string, data []byte) error {
+	body := map[string]any{
+		"payload": map[string]string{"data": base64.StdEncoding.EncodeToString(data)},
+	}
+	return c.do(http.MethodPost, secretPath(project, id)+":addVersion", body, nil)
+}
+
+// LatestSecretVersion returns the data of a secret's latest version. It
+// returns ErrNotFound if the secret has no versions.
+func (c *SecretManagerClient) LatestSecretVersion(project, id string) ([]byte, error) {
+	var resp struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := c.do(http.MethodGet, secretPath(project, id)+"/versions/latest:access", nil, &resp); err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(resp.Payload.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode secret %s: %w", id, err)
+	}
+	return data, nil
+}
+
+func secretPath(project, id string) string {
+	return fmt.Sprintf("/v1/projects/%s/secrets/%s", url.PathEscape(project), url.PathEscape(id))
+}
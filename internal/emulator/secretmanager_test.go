@@ -0,0 +1,70 @@
+package emulator
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecretManagerClient(t *testing.T) {
+	var versions []string
+	var principal string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/projects/test-project/secrets", func(w http.ResponseWriter, r *http.Request) {
+		principal = r.Header.Get("X-Emulator-Principal")
+		if r.URL.Query().Get("secretId") != "db-password" {
+			http.Error(w, "wrong secret", http.StatusBadRequest)
+			return
+		}
+		if versions != nil {
+			http.Error(w, "exists", http.StatusConflict)
+			return
+		}
+		versions = []string{}
+	})
+	mux.HandleFunc("POST /v1/projects/test-project/secrets/db-password:addVersion", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Payload struct{ Data string } `json:"payload"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		versions = append(versions, body.Payload.Data)
+	})
+	mux.HandleFunc("GET /v1/projects/test-project/secrets/db-password/versions/latest:access", func(w http.ResponseWriter, r *http.Request) {
+		if len(versions) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"payload": map[string]string{"data": versions[len(versions)-1]}})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := &SecretManagerClient{BaseURL: server.URL, HTTP: http.DefaultClient, Principal: "user:seed@example.com"}
+
+	if err := client.CreateSecret("test-project", "db-password", nil); err != nil {
+		t.Fatalf("CreateSecret failed: %v", err)
+	}
+	if principal != "user:seed@example.com" {
+		t.Errorf("principal = %q", principal)
+	}
+	if err := client.CreateSecret("test-project", "db-password", nil); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("second CreateSecret = %v, want ErrAlreadyExists", err)
+	}
+	if _, err := client.LatestSecretVersion("test-project", "db-password"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("LatestSecretVersion without versions = %v, want ErrNotFound", err)
+	}
+
+	if err := client.AddSecretVersion("test-project", "db-password", []byte("hunter2")); err != nil {
+		t.Fatalf("AddSecretVersion failed: %v", err)
+	}
+	data, err := client.LatestSecretVersion("test-project", "db-password")
+	if err != nil {
+		t.Fatalf("LatestSecretVersion failed: %v", err)
+	}
+	if string(data) != "hunter2" {
+		t.Errorf("latest version = %q, want hunter2", data)
+	}
+}
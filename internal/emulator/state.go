@@ -0,0 +1,68 @@
+package emulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+// State records what this machine last saw of each IAM emulator, so
+// 'policy apply' can detect changes made by someone else in between
+type State struct {
+	// Policies is keyed by the emulator's admin URL
+	Policies map[string]PolicyState `json:"policies"`
+}
+
+// PolicyState is the version of an emulator's policy last pulled or applied
+type PolicyState struct {
+	ETag      string    `json:"etag"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// DefaultStatePath returns state.json in the user state directory, such
+// as ~/.local/state/gcp-emulator/state.json
+func DefaultStatePath() (string, error) {
+	return config.StatePath("state.json")
+}
+
+// LoadState reads the state file at path. A missing file is an empty state.
+func LoadState(path string) (*State, error) {
+	state := &State{Policies: make(map[string]PolicyState)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	if state.Policies == nil {
+		state.Policies = make(map[string]PolicyState)
+	}
+	return state, nil
+}
+
+// Save writes the state file, creating its directory if needed
+func (s *State) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
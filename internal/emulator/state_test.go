@@ -0,0 +1,34 @@
+package emulator
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "state.json")
+
+	state, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState of missing file failed: %v", err)
+	}
+	if len(state.Policies) != 0 {
+		t.Errorf("Expected empty state, got %v", state.Policies)
+	}
+
+	updated := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	state.Policies["http://localhost:8080"] = PolicyState{ETag: `"v1"`, UpdatedAt: updated}
+	if err := state.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	got := loaded.Policies["http://localhost:8080"]
+	if got.ETag != `"v1"` || !got.UpdatedAt.Equal(updated) {
+		t.Errorf("Expected recorded state, got %+v", got)
+	}
+}
@@ -0,0 +1,220 @@
+// Package metrics exposes the health of the emulator stack as Prometheus
+// metrics, in the text exposition format: served for scrapes by a
+// Collector, or written once to a file for the node_exporter textfile
+// collector.
+//
+// The metric names and labels are what alerts and dashboards are written
+// against, so they only ever gain new ones.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+)
+
+// ContentType is the content type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// family is a metric, with the samples of a status
+type family struct {
+	name, kind, help string
+	samples          func(status *docker.StackStatus) []sample
+}
+
+// sample is one value of a family; service is "" for stack metrics
+type sample struct {
+	service string
+	value   float64
+}
+
+// families are the metrics written, in order
+var families = []family{
+	{
+		name: "gcp_emulator_runtime_up",
+		kind: "gauge",
+		help: "Whether the container runtime could be asked for the stack's containers.",
+		samples: func(status *docker.StackStatus) []sample {
+			return []sample{{value: boolValue(status.RuntimeErr == nil)}}
+		},
+	},
+	{
+		name: "gcp_emulator_service_enabled",
+		kind: "gauge",
+		help: "Whether the service is part of the running stack.",
+		samples: func(status *docker.StackStatus) []sample {
+			return perService(status, func(info docker.ServiceInfo) (float64, bool) {
+				return boolValue(info.Health != docker.ServiceNotEnabled), true
+			})
+		},
+	},
+	{
+		name: "gcp_emulator_service_up",
+		kind: "gauge",
+		help: "Whether the service's health check passes, for enabled services.",
+		samples: func(status *docker.StackStatus) []sample {
+			return perService(status, func(info docker.ServiceInfo) (float64, bool) {
+				return boolValue(info.Health == docker.ServiceUp), info.Health != docker.ServiceNotEnabled
+			})
+		},
+	},
+	{
+		name: "gcp_emulator_service_probe_duration_seconds",
+		kind: "gauge",
+		help: "Round trip of the service's health check, for checks that passed.",
+		samples: func(status *docker.StackStatus) []sample {
+			return perService(status, func(info docker.ServiceInfo) (float64, bool) {
+				return info.Latency.Seconds(), info.Health == docker.ServiceUp
+			})
+		},
+	},
+	{
+		name: "gcp_emulator_container_restarts_total",
+		kind: "counter",
+		help: "How often the service's container was restarted.",
+		samples: func(status *docker.StackStatus) []sample {
+			return perService(status, func(info docker.ServiceInfo) (float64, bool) {
+				return float64(info.RestartCount), created(info)
+			})
+		},
+	},
+	{
+		name: "gcp_emulator_container_uptime_seconds",
+		kind: "gauge",
+		help: "How long the service's container has been running, 0 unless it is.",
+		samples: func(status *docker.StackStatus) []sample {
+			return perService(status, func(info docker.ServiceInfo) (float64, bool) {
+				return info.Uptime.Seconds(), created(info)
+			})
+		},
+	},
+	{
+		name: "gcp_emulator_stack_uptime_seconds",
+		kind: "gauge",
+		help: "How long the longest running container of the stack has been up, 0 if none is.",
+		samples: func(status *docker.StackStatus) []sample {
+			var uptime time.Duration
+			for _, info := range status.Services() {
+				uptime = max(uptime, info.Uptime)
+			}
+			return []sample{{value: uptime.Seconds()}}
+		},
+	},
+}
+
+// perService returns a sample per service that value keeps
+func perService(status *docker.StackStatus, value func(docker.ServiceInfo) (float64, bool)) []sample {
+	var samples []sample
+	for _, info := range status.Services() {
+		if v, ok := value(info); ok {
+			samples = append(samples, sample{service: info.Service, value: v})
+		}
+	}
+	return samples
+}
+
+// created reports whether the service has a container the runtime knows
+func created(info docker.ServiceInfo) bool {
+	return info.State != "" && info.State != docker.ContainerNotCreated
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Write writes the metrics of status in the text exposition format
+func Write(w io.Writer, status *docker.StackStatus) error {
+	var buf bytes.Buffer
+	for _, f := range families {
+		samples := f.samples(status)
+		if len(samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(&buf, "# HELP %s %s\n", f.name, f.help)
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", f.name, f.kind)
+		for _, s := range samples {
+			labels := ""
+			if s.service != "" {
+				labels = fmt.Sprintf(`{service=%q}`, s.service)
+			}
+			fmt.Fprintf(&buf, "%s%s %s\n", f.name, labels, formatValue(s.value))
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// formatValue writes a value the shortest way that round-trips, as the
+// Prometheus client libraries do
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// WriteTextfile writes the metrics of status to path, through a temporary
+// file in the same directory, so the textfile collector never reads a
+// file half written
+func WriteTextfile(path string, status *docker.StackStatus) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := Write(tmp, status); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	// The collector runs as another user, and CreateTemp makes the file
+	// readable only by its owner
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	return nil
+}
+
+// Collector serves the metrics of the latest status it was given. Scrapes
+// read what the last poll found, so they are fast and don't run the
+// health checks themselves.
+type Collector struct {
+	mu   sync.RWMutex
+	body []byte
+}
+
+// Update replaces the metrics served with those of status
+func (c *Collector) Update(status *docker.StackStatus) {
+	var buf bytes.Buffer
+	_ = Write(&buf, status)
+	c.mu.Lock()
+	c.body = buf.Bytes()
+	c.mu.Unlock()
+}
+
+// ServeHTTP serves the metrics, or 503 before the first Update
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.RLock()
+	body := c.body
+	c.mu.RUnlock()
+	if body == nil {
+		http.Error(w, "no status collected yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+)
+
+// testStatus is a stack with IAM up, KMS in a crash loop, and Secret
+// Manager left out
+func testStatus() *docker.StackStatus {
+	return &docker.StackStatus{
+		IAM:           docker.ServiceInfo{Service: "iam", State: "running", Health: docker.ServiceUp, Latency: 3 * time.Millisecond, Uptime: 90 * time.Second},
+		SecretManager: docker.ServiceInfo{Service: "secret-manager", State: docker.ContainerNotCreated, Health: docker.ServiceNotEnabled},
+		KMS:           docker.ServiceInfo{Service: "kms", State: "restarting", Health: docker.ServiceDown, RestartCount: 7},
+	}
+}
+
+// The names, labels, and types are what alerts are written against; a
+// change here breaks them
+const wantMetrics = `# HELP gcp_emulator_runtime_up Whether the container runtime could be asked for the stack's containers.
+# TYPE gcp_emulator_runtime_up gauge
+gcp_emulator_runtime_up 1
+# HELP gcp_emulator_service_enabled Whether the service is part of the running stack.
+# TYPE gcp_emulator_service_enabled gauge
+gcp_emulator_service_enabled{service="iam"} 1
+gcp_emulator_service_enabled{service="secret-manager"} 0
+gcp_emulator_service_enabled{service="kms"} 1
+# HELP gcp_emulator_service_up Whether the service's health check passes, for enabled services.
+# TYPE gcp_emulator_service_up gauge
+gcp_emulator_service_up{service="iam"} 1
+gcp_emulator_service_up{service="kms"} 0
+# HELP gcp_emulator_service_probe_duration_seconds Round trip of the service's health check, for checks that passed.
+# TYPE gcp_emulator_service_probe_duration_seconds gauge
+gcp_emulator_service_probe_duration_seconds{service="iam"} 0.003
+# HELP gcp_emulator_container_restarts_total How often the service's container was restarted.
+# TYPE gcp_emulator_container_restarts_total counter
+gcp_emulator_container_restarts_total{service="iam"} 0
+gcp_emulator_container_restarts_total{service="kms"} 7
+# HELP gcp_emulator_container_uptime_seconds How long the service's container has been running, 0 unless it is.
+# TYPE gcp_emulator_container_uptime_seconds gauge
+gcp_emulator_container_uptime_seconds{service="iam"} 90
+gcp_emulator_container_uptime_seconds{service="kms"} 0
+# HELP gcp_emulator_stack_uptime_seconds How long the longest running container of the stack has been up, 0 if none is.
+# TYPE gcp_emulator_stack_uptime_seconds gauge
+gcp_emulator_stack_uptime_seconds 90
+`
+
+func TestWrite(t *testing.T) {
+	var b strings.Builder
+	if err := Write(&b, testStatus()); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	if b.String() != wantMetrics {
+		t.Errorf("Write() =\n%s\nwant\n%s", b.String(), wantMetrics)
+	}
+}
+
+func TestWriteRuntimeDown(t *testing.T) {
+	// Without the runtime, nothing is known of the containers
+	status := &docker.StackStatus{
+		RuntimeErr:    errors.New("docker daemon not reachable"),
+		IAM:           docker.ServiceInfo{Service: "iam", Health: docker.ServiceDown},
+		SecretManager: docker.ServiceInfo{Service: "secret-manager", Health: docker.ServiceDown},
+		KMS:           docker.ServiceInfo{Service: "kms", Health: docker.ServiceDown},
+	}
+	var b strings.Builder
+	if err := Write(&b, status); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	out := b.String()
+	if !strings.Contains(out, "gcp_emulator_runtime_up 0\n") || !strings.Contains(out, `gcp_emulator_service_up{service="kms"} 0`) {
+		t.Errorf("Expected the runtime and services down, got:\n%s", out)
+	}
+	if strings.Contains(out, "gcp_emulator_container_") {
+		t.Errorf("Expected no container metrics without the runtime, got:\n%s", out)
+	}
+}
+
+func TestWriteTextfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gcp_emulator.prom")
+	if err := WriteTextfile(path, testStatus()); err != nil {
+		t.Fatalf("WriteTextfile() error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	if string(data) != wantMetrics {
+		t.Errorf("Textfile =\n%s\nwant\n%s", data, wantMetrics)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("Expected only the textfile to be left, got %d files", len(entries))
+	}
+}
+
+func TestCollector(t *testing.T) {
+	c := &Collector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 before the first update, got %s", resp.Status)
+	}
+
+	c.Update(testStatus())
+	resp, err = http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.Header.Get("Content-Type") != ContentType {
+		t.Errorf("Expected Content-Type %q, got %q", ContentType, resp.Header.Get("Content-Type"))
+	}
+	if string(body) != wantMetrics {
+		t.Errorf("Scrape =\n%s\nwant\n%s", body, wantMetrics)
+	}
+}
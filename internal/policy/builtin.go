@@ -0,0 +1,67 @@
+package policy
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// BuiltinRole is a GCP predefined role from the embedded catalog
+type BuiltinRole struct {
+	Title       string   `json:"title"`
+	Permissions []string `json:"permissions"`
+}
+
+// builtinRolesJSON is the catalog of GCP predefined roles accepted in
+// bindings without a definition in the roles: section, limited to the
+// services the emulators cover. The IAM emulator resolves these.
+//
+//go:embed catalog/roles.json
+var builtinRolesJSON []byte
+
+var (
+	builtinRolesOnce sync.Once
+	builtinRoles     map[string]BuiltinRole
+)
+
+// getBuiltinRoles parses the embedded role catalog once
+func getBuiltinRoles() map[string]BuiltinRole {
+	builtinRolesOnce.Do(func() {
+		if err := json.Unmarshal(builtinRolesJSON, &builtinRoles); err != nil {
+			panic(fmt.Sprintf("policy: invalid built-in role catalog: %v", err))
+		}
+	})
+	return builtinRoles
+}
+
+// IsBuiltinRole reports whether role is a known GCP predefined role
+func IsBuiltinRole(role string) bool {
+	_, ok := getBuiltinRoles()[role]
+	return ok
+}
+
+// ResolveRole returns the permissions of a built-in role from the catalog,
+// sorted. Custom roles are resolved from the policy with RolePermissions.
+func ResolveRole(name string) ([]string, error) {
+	role, ok := getBuiltinRoles()[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown built-in role: %s", name)
+	}
+	return append([]string{}, role.Permissions...), nil
+}
+
+// DescribeRole returns a built-in role's catalog entry
+func DescribeRole(name string) (BuiltinRole, bool) {
+	role, ok := getBuiltinRoles()[name]
+	if !ok {
+		return BuiltinRole{}, false
+	}
+	role.Permissions = append([]string{}, role.Permissions...)
+	return role, true
+}
+
+// BuiltinRoles returns the names of all built-in roles in the catalog, sorted
+func BuiltinRoles() []string {
+	return sortedKeys(getBuiltinRoles())
+}
@@ -0,0 +1,94 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveRole(t *testing.T) {
+	perms, err := ResolveRole("roles/secretmanager.secretAccessor")
+	if err != nil {
+		t.Fatalf("ResolveRole() error = %v", err)
+	}
+	if len(perms) != 1 || perms[0] != "secretmanager.versions.access" {
+		t.Errorf("permissions = %v, want [secretmanager.versions.access]", perms)
+	}
+
+	if _, err := ResolveRole("roles/custom.developer"); err == nil {
+		t.Error("Expected error for role not in the catalog")
+	}
+}
+
+func TestBuiltinCatalog(t *testing.T) {
+	for _, name := range BuiltinRoles() {
+		role, _ := DescribeRole(name)
+		if role.Title == "" {
+			t.Errorf("%s has no title", name)
+		}
+		if len(role.Permissions) == 0 {
+			t.Errorf("%s has no permissions", name)
+		}
+		for _, perm := range role.Permissions {
+			if strings.Count(perm, ".") < 2 {
+				t.Errorf("%s: malformed permission %s", name, perm)
+			}
+		}
+	}
+
+	// Basic roles cover the predefined roles beneath them
+	owner, _ := ResolveRole("roles/owner")
+	has := make(map[string]bool)
+	for _, perm := range owner {
+		has[perm] = true
+	}
+	for _, perm := range []string{"secretmanager.versions.access", "cloudkms.cryptoKeys.decrypt", "resourcemanager.projects.setIamPolicy"} {
+		if !has[perm] {
+			t.Errorf("roles/owner is missing %s", perm)
+		}
+	}
+}
+
+func TestSimulateBuiltinRole(t *testing.T) {
+	pol := &Policy{
+		Projects: map[string]Project{
+			"test-project": {
+				Bindings: []Binding{
+					{Role: "roles/secretmanager.admin", Members: []string{"user:alice@example.com"}},
+					{Role: "roles/viewer", Members: []string{"user:bob@example.com"}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		principal   string
+		permission  string
+		wantAllowed bool
+	}{
+		{"user:alice@example.com", "secretmanager.secrets.create", true},
+		{"user:alice@example.com", "cloudkms.cryptoKeys.encrypt", false},
+		{"user:bob@example.com", "secretmanager.secrets.get", true},
+		{"user:bob@example.com", "secretmanager.versions.access", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.principal+" "+tt.permission, func(t *testing.T) {
+			decision, err := Simulate(pol, SimulateRequest{
+				Principal:  tt.principal,
+				Permission: tt.permission,
+				Resource:   "projects/test-project/secrets/db-password",
+			})
+			if err != nil {
+				t.Fatalf("Simulate() error = %v", err)
+			}
+			if decision.Allowed != tt.wantAllowed {
+				t.Errorf("Allowed = %v, want %v", decision.Allowed, tt.wantAllowed)
+			}
+		})
+	}
+
+	holders := PrincipalsWithPermission(pol, "secretmanager.secrets.list", "test-project")
+	if len(holders) != 2 {
+		t.Errorf("Expected 2 holders of secretmanager.secrets.list, got %d", len(holders))
+	}
+}
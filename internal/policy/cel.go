@@ -0,0 +1,161 @@
+package policy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	celast "github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+)
+
+var (
+	conditionEnvOnce sync.Once
+	conditionEnv     *cel.Env
+	conditionEnvErr  error
+)
+
+// ConditionAttributes lists the attributes the IAM emulator provides to
+// condition expressions. A condition referencing any other attribute
+// always fails at runtime, so validation rejects it.
+var ConditionAttributes = []string{
+	"resource.name",
+	"resource.type",
+	"request.time",
+}
+
+// getConditionEnv returns the CEL environment for IAM conditions.
+// resource and request are declared as maps; checkAttributes restricts
+// their keys to ConditionAttributes.
+func getConditionEnv() (*cel.Env, error) {
+	conditionEnvOnce.Do(func() {
+		conditionEnv, conditionEnvErr = cel.NewEnv(
+			cel.Variable("resource", cel.MapType(cel.StringType, cel.DynType)),
+			cel.Variable("request", cel.MapType(cel.StringType, cel.DynType)),
+		)
+	})
+	return conditionEnv, conditionEnvErr
+}
+
+// compileCondition parses and type-checks a condition expression
+func compileCondition(expression string) error {
+	env, err := getConditionEnv()
+	if err != nil {
+		return fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return issues.Err()
+	}
+
+	outputType := ast.OutputType()
+	if !outputType.IsExactType(cel.BoolType) && !outputType.IsExactType(cel.DynType) {
+		return fmt.Errorf("expression must evaluate to bool, got %s", outputType)
+	}
+
+	return checkAttributes(ast)
+}
+
+// checkAttributes reports the first resource or request attribute in a
+// compiled expression that is not in ConditionAttributes. Both field
+// selection (resource.name) and indexing (resource["name"]) are checked.
+func checkAttributes(compiled *cel.Ast) error {
+	var unsupported string
+
+	celast.PreOrderVisit(celast.NavigateAST(compiled.NativeRep()), celast.NewExprVisitor(func(e celast.Expr) {
+		if unsupported != "" {
+			return
+		}
+
+		var operand celast.Expr
+		var field string
+		switch e.Kind() {
+		case celast.SelectKind:
+			operand, field = e.AsSelect().Operand(), e.AsSelect().FieldName()
+		case celast.CallKind:
+			call := e.AsCall()
+			if call.FunctionName() != operators.Index || len(call.Args()) != 2 || call.Args()[1].Kind() != celast.LiteralKind {
+				return
+			}
+			key, ok := call.Args()[1].AsLiteral().Value().(string)
+			if !ok {
+				return
+			}
+			operand, field = call.Args()[0], key
+		default:
+			return
+		}
+
+		if operand.Kind() != celast.IdentKind {
+			return
+		}
+		root := operand.AsIdent()
+		if root != "resource" && root != "request" {
+			return
+		}
+
+		attr := root + "." + field
+		if !slices.Contains(ConditionAttributes, attr) {
+			unsupported = attr
+		}
+	}))
+
+	if unsupported != "" {
+		return fmt.Errorf("unsupported attribute %s (supported: %s)", unsupported, strings.Join(ConditionAttributes, ", "))
+	}
+	return nil
+}
+
+// ConditionContext holds the request attributes a condition is evaluated against
+type ConditionContext struct {
+	ResourceName string    `json:"resourceName"`
+	ResourceType string    `json:"resourceType"`
+	RequestTime  time.Time `json:"requestTime"`
+}
+
+// EvaluateCondition compiles and evaluates a condition expression
+func EvaluateCondition(expression string, ctx ConditionContext) (bool, error) {
+	env, err := getConditionEnv()
+	if err != nil {
+		return false, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return false, issues.Err()
+	}
+
+	prg, err := env.Program(ast)
+	if err != nil {
+		return false, err
+	}
+
+	requestTime := ctx.RequestTime
+	if requestTime.IsZero() {
+		requestTime = time.Now()
+	}
+
+	out, _, err := prg.Eval(map[string]any{
+		"resource": map[string]any{
+			"name": ctx.ResourceName,
+			"type": ctx.ResourceType,
+		},
+		"request": map[string]any{
+			"time": requestTime,
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	result, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression evaluated to %v, not bool", out.Value())
+	}
+
+	return result, nil
+}
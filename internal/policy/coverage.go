@@ -0,0 +1,156 @@
+package policy
+
+import (
+	"strings"
+)
+
+// ObservedRequest is an authorization check recorded by the IAM emulator
+type ObservedRequest struct {
+	SimulateRequest
+
+	// Allowed is the emulator's decision
+	Allowed bool
+}
+
+// Coverage reports which parts of a policy a set of observed requests
+// exercised
+type Coverage struct {
+	Requests int `json:"requests"`
+
+	// Unmatched counts requests the emulator allowed but no local binding
+	// grants, a sign the emulator is enforcing a different policy
+	Unmatched int `json:"unmatched"`
+
+	// Roles lists each role used by a binding, sorted by name
+	Roles []RoleCoverage `json:"roles"`
+
+	// UnusedBindings never granted an allowed request
+	UnusedBindings []ScopedBinding `json:"unusedBindings"`
+
+	// IdlePrincipals are granted access by some binding but made no
+	// request, allowed or denied
+	IdlePrincipals []string `json:"idlePrincipals"`
+}
+
+// RoleCoverage splits a role's permissions by whether any allowed request
+// was granted them through the role
+type RoleCoverage struct {
+	Role        string   `json:"role"`
+	Exercised   []string `json:"exercised"`
+	Unexercised []string `json:"unexercised"`
+}
+
+// Percent returns the share of the role's permissions that were exercised
+func (r RoleCoverage) Percent() float64 {
+	total := len(r.Exercised) + len(r.Unexercised)
+	if total == 0 {
+		return 0
+	}
+	return float64(len(r.Exercised)) * 100 / float64(total)
+}
+
+// ComputeCoverage correlates requests observed by the emulator with the
+// policy. Each allowed request is simulated against the policy and every
+// binding that grants it is counted as used, along with the permission
+// of its role. Groups are expanded when finding idle principals;
+// allUsers, allAuthenticatedUsers, and domain members are not principals
+// that make requests and are left out.
+func ComputeCoverage(policy *Policy, requests []ObservedRequest) *Coverage {
+	coverage := &Coverage{
+		Requests:       len(requests),
+		Roles:          []RoleCoverage{},
+		UnusedBindings: []ScopedBinding{},
+		IdlePrincipals: []string{},
+	}
+
+	used := make(map[string]map[int]bool)
+	exercised := make(map[string]map[string]bool)
+	active := make(map[string]bool)
+
+	for _, req := range requests {
+		active[req.Principal] = true
+		if !req.Allowed {
+			continue
+		}
+
+		// Resources outside a project cannot be matched to a binding
+		decision, err := Simulate(policy, req.SimulateRequest)
+		if err != nil || !decision.Allowed {
+			coverage.Unmatched++
+			continue
+		}
+
+		for _, match := range decision.Matches {
+			if !match.Granted {
+				continue
+			}
+			if used[match.Scope] == nil {
+				used[match.Scope] = make(map[int]bool)
+			}
+			used[match.Scope][match.Index] = true
+
+			role := match.Binding.Role
+			if exercised[role] == nil {
+				exercised[role] = make(map[string]bool)
+			}
+			exercised[role][req.Permission] = true
+		}
+	}
+
+	roles := make(map[string]bool)
+	principals := make(map[string]bool)
+	for _, scoped := range allScopedBindings(policy) {
+		roles[scoped.Binding.Role] = true
+		if !used[scoped.Scope][scoped.Index] {
+			coverage.UnusedBindings = append(coverage.UnusedBindings, scoped)
+		}
+		for principal := range ExpandMembers(policy, scoped.Binding.Members) {
+			if principal == "allUsers" || principal == "allAuthenticatedUsers" || strings.HasPrefix(principal, "domain:") {
+				continue
+			}
+			principals[principal] = true
+		}
+	}
+
+	for _, role := range sortedKeys(roles) {
+		rc := RoleCoverage{Role: role, Exercised: []string{}, Unexercised: []string{}}
+		for _, perm := range RolePermissions(policy, role) {
+			if exercised[role][perm] {
+				rc.Exercised = append(rc.Exercised, perm)
+			} else {
+				rc.Unexercised = append(rc.Unexercised, perm)
+			}
+		}
+		coverage.Roles = append(coverage.Roles, rc)
+	}
+
+	for _, principal := range sortedKeys(principals) {
+		if !active[principal] {
+			coverage.IdlePrincipals = append(coverage.IdlePrincipals, principal)
+		}
+	}
+
+	return coverage
+}
+
+// allScopedBindings returns every allow binding in the policy with its
+// scope: projects and their resources, then folders, then organizations,
+// each sorted by name
+func allScopedBindings(policy *Policy) []ScopedBinding {
+	var bindings []ScopedBinding
+	for _, name := range sortedKeys(policy.Projects) {
+		project := policy.Projects[name]
+		scope := "projects/" + name
+		bindings = append(bindings, scopedBindings(scope, project.Bindings)...)
+		for _, resource := range sortedKeys(project.Resources) {
+			bindings = append(bindings, scopedBindings(scope+"/"+resource, project.Resources[resource].Bindings)...)
+		}
+	}
+	for _, name := range sortedKeys(policy.Folders) {
+		bindings = append(bindings, scopedBindings("folders/"+name, policy.Folders[name].Bindings)...)
+	}
+	for _, name := range sortedKeys(policy.Organizations) {
+		bindings = append(bindings, scopedBindings("organizations/"+name, policy.Organizations[name].Bindings)...)
+	}
+	return bindings
+}
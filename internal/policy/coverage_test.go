@@ -0,0 +1,43 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComputeCoverage(t *testing.T) {
+	secret := "projects/test-project/secrets/db-password"
+	requests := []ObservedRequest{
+		{SimulateRequest: SimulateRequest{Principal: "user:alice@example.com", Permission: "secretmanager.secrets.get", Resource: secret}, Allowed: true},
+		{SimulateRequest: SimulateRequest{Principal: "user:alice@example.com", Permission: "secretmanager.secrets.get", Resource: secret}, Allowed: true},
+		// Allowed by the emulator but not by the local policy
+		{SimulateRequest: SimulateRequest{Principal: "user:bob@example.com", Permission: "secretmanager.secrets.get", Resource: secret}, Allowed: true},
+		{SimulateRequest: SimulateRequest{Principal: "user:bob@example.com", Permission: "secretmanager.secrets.delete", Resource: secret}, Allowed: false},
+	}
+
+	coverage := ComputeCoverage(simulatePolicy(), requests)
+
+	if coverage.Requests != 4 || coverage.Unmatched != 1 {
+		t.Errorf("Requests = %d, Unmatched = %d, want 4 and 1", coverage.Requests, coverage.Unmatched)
+	}
+
+	wantRoles := []RoleCoverage{
+		{Role: "roles/custom.admin", Exercised: []string{"secretmanager.secrets.get"}, Unexercised: []string{"secretmanager.secrets.create"}},
+		{Role: "roles/custom.ciRunner", Exercised: []string{}, Unexercised: []string{"secretmanager.secrets.get"}},
+	}
+	if !reflect.DeepEqual(coverage.Roles, wantRoles) {
+		t.Errorf("Roles = %+v, want %+v", coverage.Roles, wantRoles)
+	}
+	if got := coverage.Roles[0].Percent(); got != 50 {
+		t.Errorf("Percent = %v, want 50", got)
+	}
+
+	if len(coverage.UnusedBindings) != 1 || coverage.UnusedBindings[0].Index != 1 {
+		t.Errorf("UnusedBindings = %+v, want the ciRunner binding", coverage.UnusedBindings)
+	}
+
+	wantIdle := []string{"serviceAccount:ci@test-project.iam.gserviceaccount.com"}
+	if !reflect.DeepEqual(coverage.IdlePrincipals, wantIdle) {
+		t.Errorf("IdlePrincipals = %v, want %v", coverage.IdlePrincipals, wantIdle)
+	}
+}
@@ -0,0 +1,123 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DenyMatch is a deny binding that covers the requested permission and
+// principal
+type DenyMatch struct {
+	Index       int         `json:"index"`
+	DenyBinding DenyBinding `json:"denyBinding"`
+
+	// Member is the denied principal that matched
+	Member string `json:"member"`
+
+	// Via lists the groups the principal was matched through, innermost first
+	Via []string `json:"via,omitempty"`
+
+	// Applied is false when the deny's condition evaluated to false
+	Applied bool   `json:"applied"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// DenyPermission converts an allow permission such as
+// secretmanager.secrets.get to its deny form,
+// secretmanager.googleapis.com/secrets.get
+func DenyPermission(permission string) string {
+	service, rest, ok := strings.Cut(permission, ".")
+	if !ok {
+		return permission
+	}
+	return service + ".googleapis.com/" + rest
+}
+
+// AllowPermission converts a deny permission such as
+// secretmanager.googleapis.com/secrets.get to its allow form,
+// secretmanager.secrets.get
+func AllowPermission(permission string) (string, error) {
+	host, rest, ok := strings.Cut(permission, "/")
+	service, isService := strings.CutSuffix(host, ".googleapis.com")
+	if !ok || !isService || service == "" || strings.Count(rest, ".") < 1 {
+		return "", fmt.Errorf("invalid deny permission format: %s (expected service.googleapis.com/resource.verb)", permission)
+	}
+	return service + "." + rest, nil
+}
+
+// matchDeny reports whether a deny binding covers the permission for a
+// principal whose member matches are given. Exceptions win over denials.
+func matchDeny(deny DenyBinding, permission string, matches map[string][]string) (string, []string, bool) {
+	covered := false
+	for _, denied := range deny.DeniedPermissions {
+		if denied == DenyPermission(permission) {
+			covered = true
+			break
+		}
+	}
+	if !covered {
+		return "", nil, false
+	}
+
+	for _, exception := range deny.ExceptionPrincipals {
+		if _, ok := matches[exception]; ok {
+			return "", nil, false
+		}
+	}
+
+	for _, member := range deny.DeniedPrincipals {
+		if via, ok := matches[member]; ok {
+			return member, via, true
+		}
+	}
+	return "", nil, false
+}
+
+// evaluateDenies returns the project's deny bindings that cover the
+// permission for the principal. A deny whose condition fails to evaluate
+// is applied, so errors never widen access.
+func evaluateDenies(policy *Policy, project, permission string, matches map[string][]string, ctx *ConditionContext) []DenyMatch {
+	var denies []DenyMatch
+
+	for i, deny := range policy.Projects[project].DenyBindings {
+		member, via, ok := matchDeny(deny, permission, matches)
+		if !ok {
+			continue
+		}
+
+		match := DenyMatch{
+			Index:       i,
+			DenyBinding: deny,
+			Member:      member,
+			Via:         via,
+			Applied:     true,
+		}
+
+		if deny.Condition != nil && ctx != nil {
+			ok, err := EvaluateCondition(deny.Condition.Expression, *ctx)
+			switch {
+			case err != nil:
+				match.Reason = fmt.Sprintf("condition error, deny applied: %v", err)
+			case !ok:
+				match.Applied = false
+				match.Reason = "condition evaluated to false"
+			}
+		}
+
+		denies = append(denies, match)
+	}
+
+	return denies
+}
+
+// validateDenyPermission checks a deny permission's format and service
+func validateDenyPermission(permission string) (string, error) {
+	allow, err := AllowPermission(permission)
+	if err != nil {
+		return "", err
+	}
+	if err := validatePermission(allow); err != nil {
+		return "", err
+	}
+	return allow, nil
+}
@@ -0,0 +1,181 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func denyPolicy() *Policy {
+	pol := simulatePolicy()
+	project := pol.Projects["test-project"]
+	project.DenyBindings = []DenyBinding{
+		{
+			DeniedPrincipals:    []string{"group:admins"},
+			ExceptionPrincipals: []string{"user:breakglass@example.com"},
+			DeniedPermissions:   []string{"secretmanager.googleapis.com/secrets.create"},
+		},
+		{
+			DeniedPrincipals:  []string{"serviceAccount:ci@test-project.iam.gserviceaccount.com"},
+			DeniedPermissions: []string{"secretmanager.googleapis.com/secrets.get"},
+			Condition: &Condition{
+				Expression: `resource.name.endsWith("-root")`,
+				Title:      "No root secrets",
+			},
+		},
+	}
+	pol.Projects["test-project"] = project
+	pol.Groups["admins"] = Group{Members: []string{"group:developers", "user:breakglass@example.com"}}
+	return pol
+}
+
+func TestSimulateDeny(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         SimulateRequest
+		wantAllowed bool
+		wantDenied  bool
+	}{
+		{
+			name: "deny overrides nested group allow",
+			req: SimulateRequest{
+				Principal:  "user:alice@example.com",
+				Permission: "secretmanager.secrets.create",
+				Resource:   "projects/test-project/secrets/db-password",
+			},
+			wantDenied: true,
+		},
+		{
+			name: "exception principal keeps access",
+			req: SimulateRequest{
+				Principal:  "user:breakglass@example.com",
+				Permission: "secretmanager.secrets.create",
+				Resource:   "projects/test-project/secrets/db-password",
+			},
+			wantAllowed: true,
+		},
+		{
+			name: "other permission unaffected",
+			req: SimulateRequest{
+				Principal:  "user:alice@example.com",
+				Permission: "secretmanager.secrets.get",
+				Resource:   "projects/test-project/secrets/db-password",
+			},
+			wantAllowed: true,
+		},
+		{
+			name: "conditional deny applies",
+			req: SimulateRequest{
+				Principal:  "serviceAccount:ci@test-project.iam.gserviceaccount.com",
+				Permission: "secretmanager.secrets.get",
+				Resource:   "projects/test-project/secrets/prod-root",
+			},
+			wantDenied: true,
+		},
+		{
+			name: "conditional deny does not apply",
+			req: SimulateRequest{
+				Principal:  "serviceAccount:ci@test-project.iam.gserviceaccount.com",
+				Permission: "secretmanager.secrets.get",
+				Resource:   "projects/test-project/secrets/prod-db",
+			},
+			wantAllowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := Simulate(denyPolicy(), tt.req)
+			if err != nil {
+				t.Fatalf("Simulate() error = %v", err)
+			}
+			if decision.Allowed != tt.wantAllowed {
+				t.Errorf("Allowed = %v, want %v", decision.Allowed, tt.wantAllowed)
+			}
+			if decision.Denied != tt.wantDenied {
+				t.Errorf("Denied = %v, want %v", decision.Denied, tt.wantDenied)
+			}
+		})
+	}
+}
+
+func TestPrincipalsWithPermissionDeny(t *testing.T) {
+	pol := denyPolicy()
+
+	holders := PrincipalsWithPermission(pol, "secretmanager.secrets.create", "test-project")
+	if len(holders) != 1 || holders[0].Principal != "user:breakglass@example.com" {
+		t.Errorf("Expected only the exception principal, got %+v", holders)
+	}
+
+	for _, h := range PrincipalsWithPermission(pol, "secretmanager.secrets.get", "test-project") {
+		wantDeny := h.Principal == "serviceAccount:ci@test-project.iam.gserviceaccount.com"
+		if (len(h.DenyConditions) > 0) != wantDeny {
+			t.Errorf("%s: DenyConditions = %v, want set = %v", h.Principal, h.DenyConditions, wantDeny)
+		}
+	}
+}
+
+func TestPrincipalsWithPermissionConditionalDenies(t *testing.T) {
+	pol := denyPolicy()
+	project := pol.Projects["test-project"]
+	project.DenyBindings = append(project.DenyBindings, DenyBinding{
+		DeniedPrincipals:  []string{"serviceAccount:ci@test-project.iam.gserviceaccount.com"},
+		DeniedPermissions: []string{"secretmanager.googleapis.com/secrets.get"},
+		Condition:         &Condition{Expression: `resource.name.endsWith("-admin")`, Title: "No admin secrets"},
+	})
+	pol.Projects["test-project"] = project
+
+	for _, h := range PrincipalsWithPermission(pol, "secretmanager.secrets.get", "test-project") {
+		if h.Principal != "serviceAccount:ci@test-project.iam.gserviceaccount.com" {
+			continue
+		}
+		var titles []string
+		for _, c := range h.DenyConditions {
+			titles = append(titles, c.Title)
+		}
+		if !reflect.DeepEqual(titles, []string{"No root secrets", "No admin secrets"}) {
+			t.Errorf("DenyConditions = %v, want both conditional denies", titles)
+		}
+	}
+}
+
+func TestValidateDenyBindings(t *testing.T) {
+	if result := Validate(denyPolicy()); !result.Valid {
+		t.Errorf("Expected valid policy, got errors: %v", result.Errors)
+	}
+
+	pol := denyPolicy()
+	project := pol.Projects["test-project"]
+	project.DenyBindings = []DenyBinding{
+		{
+			DeniedPrincipals:  []string{"alice@example.com"},
+			DeniedPermissions: []string{"cloudkms.cryptoKeys.decrypt", "storage.googleapis.com/objects.get"},
+		},
+		{DeniedPrincipals: []string{"user:bob@example.com"}},
+	}
+	pol.Projects["test-project"] = project
+
+	result := Validate(pol)
+	for _, want := range []string{
+		"Project test-project deny binding 0: invalid principal format: alice@example.com",
+		"Project test-project deny binding 0: invalid deny permission format: cloudkms.cryptoKeys.decrypt (expected service.googleapis.com/resource.verb) (did you mean cloudkms.googleapis.com/cryptoKeys.decrypt?)",
+		"Project test-project deny binding 0: unknown service in permission: storage",
+		"Project test-project deny binding 1: no denied permissions specified",
+	} {
+		if !hasError(result, want) {
+			t.Errorf("Expected error %q, got: %v", want, result.Errors)
+		}
+	}
+}
+
+func TestAllowPermission(t *testing.T) {
+	got, err := AllowPermission("cloudkms.googleapis.com/cryptoKeys.decrypt")
+	if err != nil || got != "cloudkms.cryptoKeys.decrypt" {
+		t.Errorf("AllowPermission() = %q, %v", got, err)
+	}
+	if DenyPermission(got) != "cloudkms.googleapis.com/cryptoKeys.decrypt" {
+		t.Errorf("DenyPermission() did not round-trip: %s", DenyPermission(got))
+	}
+	if _, err := AllowPermission("cloudkms/cryptoKeys.decrypt"); err == nil {
+		t.Error("Expected error for missing googleapis.com host")
+	}
+}
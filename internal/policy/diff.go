@@ -0,0 +1,419 @@
+package policy
+
+import (
+	"cmp"
+	"slices"
+	"sort"
+)
+
+// PolicyDiff is a semantic comparison of two policies
+type PolicyDiff struct {
+	RolesAdded      []string      `json:"rolesAdded,omitempty"`
+	RolesRemoved    []string      `json:"rolesRemoved,omitempty"`
+	RolesChanged    []RoleDiff    `json:"rolesChanged,omitempty"`
+	GroupsAdded     []string      `json:"groupsAdded,omitempty"`
+	GroupsRemoved   []string      `json:"groupsRemoved,omitempty"`
+	GroupsChanged   []GroupDiff   `json:"groupsChanged,omitempty"`
+	ProjectsAdded   []string      `json:"projectsAdded,omitempty"`
+	ProjectsRemoved []string      `json:"projectsRemoved,omitempty"`
+	ProjectsChanged []ProjectDiff `json:"projectsChanged,omitempty"`
+
+	ServiceAccountsAdded   []string `json:"serviceAccountsAdded,omitempty"`
+	ServiceAccountsRemoved []string `json:"serviceAccountsRemoved,omitempty"`
+
+	// ServiceAccountsChanged lists accounts whose display name, project,
+	// or description changed
+	ServiceAccountsChanged []string `json:"serviceAccountsChanged,omitempty"`
+
+	OrganizationsAdded   []string    `json:"organizationsAdded,omitempty"`
+	OrganizationsRemoved []string    `json:"organizationsRemoved,omitempty"`
+	OrganizationsChanged []ScopeDiff `json:"organizationsChanged,omitempty"`
+	FoldersAdded         []string    `json:"foldersAdded,omitempty"`
+	FoldersRemoved       []string    `json:"foldersRemoved,omitempty"`
+	FoldersChanged       []ScopeDiff `json:"foldersChanged,omitempty"`
+}
+
+// RoleDiff describes permission and description changes in a role present
+// in both policies
+type RoleDiff struct {
+	Name               string       `json:"name"`
+	Description        *ValueChange `json:"description,omitempty"`
+	PermissionsAdded   []string     `json:"permissionsAdded,omitempty"`
+	PermissionsRemoved []string     `json:"permissionsRemoved,omitempty"`
+}
+
+// GroupDiff describes member changes in a group present in both policies
+type GroupDiff struct {
+	Name           string   `json:"name"`
+	MembersAdded   []string `json:"membersAdded,omitempty"`
+	MembersRemoved []string `json:"membersRemoved,omitempty"`
+}
+
+// ProjectDiff describes changes in a project present in both policies
+type ProjectDiff struct {
+	Name            string        `json:"name"`
+	Parent          *ValueChange  `json:"parent,omitempty"`
+	BindingsAdded   []Binding     `json:"bindingsAdded,omitempty"`
+	BindingsRemoved []Binding     `json:"bindingsRemoved,omitempty"`
+	BindingsChanged []BindingDiff `json:"bindingsChanged,omitempty"`
+
+	// Deny bindings have no key to match them by, so a changed one is
+	// reported as removed and added
+	DenyBindingsAdded   []DenyBinding `json:"denyBindingsAdded,omitempty"`
+	DenyBindingsRemoved []DenyBinding `json:"denyBindingsRemoved,omitempty"`
+
+	ResourcesAdded   []string    `json:"resourcesAdded,omitempty"`
+	ResourcesRemoved []string    `json:"resourcesRemoved,omitempty"`
+	ResourcesChanged []ScopeDiff `json:"resourcesChanged,omitempty"`
+}
+
+// ScopeDiff describes binding changes in a folder, organization, or
+// resource present in both policies. Parent is only set for folders.
+type ScopeDiff struct {
+	Name            string        `json:"name"`
+	Parent          *ValueChange  `json:"parent,omitempty"`
+	BindingsAdded   []Binding     `json:"bindingsAdded,omitempty"`
+	BindingsRemoved []Binding     `json:"bindingsRemoved,omitempty"`
+	BindingsChanged []BindingDiff `json:"bindingsChanged,omitempty"`
+}
+
+// ValueChange is a changed value, such as a project's parent or a role's
+// description; either side is "" when the value is not set
+type ValueChange struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// BindingDiff describes changes to a binding present in both policies.
+// Bindings are matched by role and condition expression.
+type BindingDiff struct {
+	Role           string     `json:"role"`
+	Condition      *Condition `json:"condition,omitempty"`
+	OldCondition   *Condition `json:"oldCondition,omitempty"`
+	MembersAdded   []string   `json:"membersAdded,omitempty"`
+	MembersRemoved []string   `json:"membersRemoved,omitempty"`
+}
+
+// Empty reports whether the diff contains no changes
+func (d *PolicyDiff) Empty() bool {
+	return len(d.RolesAdded) == 0 && len(d.RolesRemoved) == 0 && len(d.RolesChanged) == 0 &&
+		len(d.GroupsAdded) == 0 && len(d.GroupsRemoved) == 0 && len(d.GroupsChanged) == 0 &&
+		len(d.ProjectsAdded) == 0 && len(d.ProjectsRemoved) == 0 && len(d.ProjectsChanged) == 0 &&
+		len(d.ServiceAccountsAdded) == 0 && len(d.ServiceAccountsRemoved) == 0 && len(d.ServiceAccountsChanged) == 0 &&
+		len(d.OrganizationsAdded) == 0 && len(d.OrganizationsRemoved) == 0 && len(d.OrganizationsChanged) == 0 &&
+		len(d.FoldersAdded) == 0 && len(d.FoldersRemoved) == 0 && len(d.FoldersChanged) == 0
+}
+
+// Empty reports whether the project diff contains no changes
+func (d *ProjectDiff) Empty() bool {
+	return d.Parent == nil &&
+		len(d.BindingsAdded) == 0 && len(d.BindingsRemoved) == 0 && len(d.BindingsChanged) == 0 &&
+		len(d.DenyBindingsAdded) == 0 && len(d.DenyBindingsRemoved) == 0 &&
+		len(d.ResourcesAdded) == 0 && len(d.ResourcesRemoved) == 0 && len(d.ResourcesChanged) == 0
+}
+
+// Empty reports whether the scope diff contains no changes
+func (d *ScopeDiff) Empty() bool {
+	return d.Parent == nil && len(d.BindingsAdded) == 0 && len(d.BindingsRemoved) == 0 && len(d.BindingsChanged) == 0
+}
+
+// Diff compares two parsed policies. Ordering of map keys, permissions,
+// and members does not affect the result.
+func Diff(oldPolicy, newPolicy *Policy) *PolicyDiff {
+	diff := &PolicyDiff{}
+
+	// Roles
+	for _, name := range sortedKeys(oldPolicy.Roles) {
+		if _, ok := newPolicy.Roles[name]; !ok {
+			diff.RolesRemoved = append(diff.RolesRemoved, name)
+		}
+	}
+	for _, name := range sortedKeys(newPolicy.Roles) {
+		if _, ok := oldPolicy.Roles[name]; !ok {
+			diff.RolesAdded = append(diff.RolesAdded, name)
+			continue
+		}
+		// Compare resolved permissions so moving permissions into an
+		// included role is not reported as a change
+		added, removed := diffStrings(RolePermissions(oldPolicy, name), RolePermissions(newPolicy, name))
+		description := diffValue(oldPolicy.Roles[name].Description, newPolicy.Roles[name].Description)
+		if len(added) > 0 || len(removed) > 0 || description != nil {
+			diff.RolesChanged = append(diff.RolesChanged, RoleDiff{
+				Name:               name,
+				Description:        description,
+				PermissionsAdded:   added,
+				PermissionsRemoved: removed,
+			})
+		}
+	}
+
+	// Groups
+	for _, name := range sortedKeys(oldPolicy.Groups) {
+		if _, ok := newPolicy.Groups[name]; !ok {
+			diff.GroupsRemoved = append(diff.GroupsRemoved, name)
+		}
+	}
+	for _, name := range sortedKeys(newPolicy.Groups) {
+		oldGroup, ok := oldPolicy.Groups[name]
+		if !ok {
+			diff.GroupsAdded = append(diff.GroupsAdded, name)
+			continue
+		}
+		added, removed := diffStrings(oldGroup.Members, newPolicy.Groups[name].Members)
+		if len(added) > 0 || len(removed) > 0 {
+			diff.GroupsChanged = append(diff.GroupsChanged, GroupDiff{
+				Name:           name,
+				MembersAdded:   added,
+				MembersRemoved: removed,
+			})
+		}
+	}
+
+	// Projects
+	for _, name := range sortedKeys(oldPolicy.Projects) {
+		if _, ok := newPolicy.Projects[name]; !ok {
+			diff.ProjectsRemoved = append(diff.ProjectsRemoved, name)
+		}
+	}
+	for _, name := range sortedKeys(newPolicy.Projects) {
+		oldProject, ok := oldPolicy.Projects[name]
+		if !ok {
+			diff.ProjectsAdded = append(diff.ProjectsAdded, name)
+			continue
+		}
+		projectDiff := diffProject(name, oldProject, newPolicy.Projects[name])
+		if !projectDiff.Empty() {
+			diff.ProjectsChanged = append(diff.ProjectsChanged, projectDiff)
+		}
+	}
+
+	// Service accounts
+	for _, email := range sortedKeys(oldPolicy.ServiceAccounts) {
+		if _, ok := newPolicy.ServiceAccounts[email]; !ok {
+			diff.ServiceAccountsRemoved = append(diff.ServiceAccountsRemoved, email)
+		}
+	}
+	for _, email := range sortedKeys(newPolicy.ServiceAccounts) {
+		oldAccount, ok := oldPolicy.ServiceAccounts[email]
+		if !ok {
+			diff.ServiceAccountsAdded = append(diff.ServiceAccountsAdded, email)
+			continue
+		}
+		if oldAccount != newPolicy.ServiceAccounts[email] {
+			diff.ServiceAccountsChanged = append(diff.ServiceAccountsChanged, email)
+		}
+	}
+
+	// Organizations
+	for _, name := range sortedKeys(oldPolicy.Organizations) {
+		if _, ok := newPolicy.Organizations[name]; !ok {
+			diff.OrganizationsRemoved = append(diff.OrganizationsRemoved, name)
+		}
+	}
+	for _, name := range sortedKeys(newPolicy.Organizations) {
+		oldOrg, ok := oldPolicy.Organizations[name]
+		if !ok {
+			diff.OrganizationsAdded = append(diff.OrganizationsAdded, name)
+			continue
+		}
+		orgDiff := diffBindings(name, oldOrg.Bindings, newPolicy.Organizations[name].Bindings)
+		if !orgDiff.Empty() {
+			diff.OrganizationsChanged = append(diff.OrganizationsChanged, orgDiff)
+		}
+	}
+
+	// Folders
+	for _, name := range sortedKeys(oldPolicy.Folders) {
+		if _, ok := newPolicy.Folders[name]; !ok {
+			diff.FoldersRemoved = append(diff.FoldersRemoved, name)
+		}
+	}
+	for _, name := range sortedKeys(newPolicy.Folders) {
+		oldFolder, ok := oldPolicy.Folders[name]
+		if !ok {
+			diff.FoldersAdded = append(diff.FoldersAdded, name)
+			continue
+		}
+		newFolder := newPolicy.Folders[name]
+		folderDiff := diffBindings(name, oldFolder.Bindings, newFolder.Bindings)
+		folderDiff.Parent = diffValue(oldFolder.Parent, newFolder.Parent)
+		if !folderDiff.Empty() {
+			diff.FoldersChanged = append(diff.FoldersChanged, folderDiff)
+		}
+	}
+
+	return diff
+}
+
+// diffProject compares a project present in both policies
+func diffProject(name string, oldProject, newProject Project) ProjectDiff {
+	bindings := diffBindings(name, oldProject.Bindings, newProject.Bindings)
+	projectDiff := ProjectDiff{
+		Name:            name,
+		Parent:          diffValue(oldProject.Parent, newProject.Parent),
+		BindingsAdded:   bindings.BindingsAdded,
+		BindingsRemoved: bindings.BindingsRemoved,
+		BindingsChanged: bindings.BindingsChanged,
+	}
+
+	oldDenies := make(map[string]bool, len(oldProject.DenyBindings))
+	for _, d := range oldProject.DenyBindings {
+		oldDenies[denyBindingKey(d)] = true
+	}
+	newDenies := make(map[string]bool, len(newProject.DenyBindings))
+	for _, d := range newProject.DenyBindings {
+		newDenies[denyBindingKey(d)] = true
+		if !oldDenies[denyBindingKey(d)] {
+			projectDiff.DenyBindingsAdded = append(projectDiff.DenyBindingsAdded, d)
+		}
+	}
+	for _, d := range oldProject.DenyBindings {
+		if !newDenies[denyBindingKey(d)] {
+			projectDiff.DenyBindingsRemoved = append(projectDiff.DenyBindingsRemoved, d)
+		}
+	}
+
+	for _, resource := range sortedKeys(oldProject.Resources) {
+		if _, ok := newProject.Resources[resource]; !ok {
+			projectDiff.ResourcesRemoved = append(projectDiff.ResourcesRemoved, resource)
+		}
+	}
+	for _, resource := range sortedKeys(newProject.Resources) {
+		oldResource, ok := oldProject.Resources[resource]
+		if !ok {
+			projectDiff.ResourcesAdded = append(projectDiff.ResourcesAdded, resource)
+			continue
+		}
+		resourceDiff := diffBindings(resource, oldResource.Bindings, newProject.Resources[resource].Bindings)
+		if !resourceDiff.Empty() {
+			projectDiff.ResourcesChanged = append(projectDiff.ResourcesChanged, resourceDiff)
+		}
+	}
+
+	return projectDiff
+}
+
+// diffValue returns the change of a value, or nil if it is unchanged
+func diffValue(oldValue, newValue string) *ValueChange {
+	if oldValue == newValue {
+		return nil
+	}
+	return &ValueChange{Old: oldValue, New: newValue}
+}
+
+// bindingKey identifies a binding by role and condition expression
+func bindingKey(b Binding) string {
+	if b.Condition == nil {
+		return b.Role
+	}
+	return b.Role + "\x00" + b.Condition.Expression
+}
+
+// diffBindings compares the bindings of a project, folder, organization,
+// or resource present in both policies
+func diffBindings(name string, oldBindings, newBindings []Binding) ScopeDiff {
+	scopeDiff := ScopeDiff{Name: name}
+
+	oldByKey := groupBindings(oldBindings)
+	newByKey := groupBindings(newBindings)
+
+	for _, b := range oldBindings {
+		if _, ok := newByKey[bindingKey(b)]; !ok {
+			scopeDiff.BindingsRemoved = append(scopeDiff.BindingsRemoved, b)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, b := range newBindings {
+		key := bindingKey(b)
+		oldBinding, ok := oldByKey[key]
+		if !ok {
+			scopeDiff.BindingsAdded = append(scopeDiff.BindingsAdded, b)
+			continue
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		merged := newByKey[key]
+		added, removed := diffStrings(oldBinding.Members, merged.Members)
+		conditionChanged := !sameConditionMetadata(oldBinding.Condition, merged.Condition)
+		if len(added) > 0 || len(removed) > 0 || conditionChanged {
+			bindingDiff := BindingDiff{
+				Role:           b.Role,
+				Condition:      merged.Condition,
+				MembersAdded:   added,
+				MembersRemoved: removed,
+			}
+			if conditionChanged {
+				bindingDiff.OldCondition = oldBinding.Condition
+			}
+			scopeDiff.BindingsChanged = append(scopeDiff.BindingsChanged, bindingDiff)
+		}
+	}
+
+	return scopeDiff
+}
+
+// groupBindings indexes bindings by key, merging the members of bindings
+// that share a role and condition
+func groupBindings(bindings []Binding) map[string]Binding {
+	byKey := make(map[string]Binding, len(bindings))
+	for _, b := range bindings {
+		key := bindingKey(b)
+		if existing, ok := byKey[key]; ok {
+			existing.Members = append(append([]string{}, existing.Members...), b.Members...)
+			byKey[key] = existing
+			continue
+		}
+		byKey[key] = b
+	}
+	return byKey
+}
+
+// sameConditionMetadata compares condition titles and descriptions.
+// Expressions are already equal because they are part of the binding key.
+func sameConditionMetadata(a, b *Condition) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Title == b.Title && a.Description == b.Description
+}
+
+// diffStrings returns the sorted values only in newValues and only in oldValues
+func diffStrings(oldValues, newValues []string) (added, removed []string) {
+	oldSet := make(map[string]bool, len(oldValues))
+	for _, v := range oldValues {
+		oldSet[v] = true
+	}
+	newSet := make(map[string]bool, len(newValues))
+	for _, v := range newValues {
+		newSet[v] = true
+	}
+
+	for v := range newSet {
+		if !oldSet[v] {
+			added = append(added, v)
+		}
+	}
+	for v := range oldSet {
+		if !newSet[v] {
+			removed = append(removed, v)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[K cmp.Ordered, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
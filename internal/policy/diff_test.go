@@ -0,0 +1,309 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffIdentical(t *testing.T) {
+	yamlPolicy, err := Load("../../testdata/policy.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load YAML policy: %v", err)
+	}
+
+	jsonPolicy, err := Load("../../testdata/policy.json")
+	if err != nil {
+		t.Fatalf("Failed to load JSON policy: %v", err)
+	}
+
+	diff := Diff(yamlPolicy, jsonPolicy)
+	if !diff.Empty() {
+		t.Errorf("Expected no diff between YAML and JSON fixtures, got %+v", diff)
+	}
+}
+
+func TestDiffOrderingIgnored(t *testing.T) {
+	oldPolicy := &Policy{
+		Roles: map[string]Role{
+			"roles/custom.dev": {Permissions: []string{"secretmanager.secrets.get", "secretmanager.versions.access"}},
+		},
+		Groups: map[string]Group{
+			"devs": {Members: []string{"user:a@example.com", "user:b@example.com"}},
+		},
+	}
+	newPolicy := &Policy{
+		Roles: map[string]Role{
+			"roles/custom.dev": {Permissions: []string{"secretmanager.versions.access", "secretmanager.secrets.get"}},
+		},
+		Groups: map[string]Group{
+			"devs": {Members: []string{"user:b@example.com", "user:a@example.com"}},
+		},
+	}
+
+	if diff := Diff(oldPolicy, newPolicy); !diff.Empty() {
+		t.Errorf("Expected ordering changes to be ignored, got %+v", diff)
+	}
+}
+
+func TestDiffChanges(t *testing.T) {
+	oldPolicy := &Policy{
+		Roles: map[string]Role{
+			"roles/custom.dev":  {Permissions: []string{"secretmanager.secrets.get"}},
+			"roles/custom.gone": {Permissions: []string{"cloudkms.cryptoKeys.get"}},
+		},
+		Groups: map[string]Group{
+			"devs": {Members: []string{"user:a@example.com"}},
+		},
+		Projects: map[string]Project{
+			"test-project": {
+				Bindings: []Binding{
+					{Role: "roles/custom.dev", Members: []string{"group:devs"}},
+					{Role: "roles/viewer", Members: []string{"user:a@example.com"}},
+				},
+			},
+		},
+	}
+	newPolicy := &Policy{
+		Roles: map[string]Role{
+			"roles/custom.dev": {Permissions: []string{"secretmanager.secrets.get", "secretmanager.secrets.list"}},
+			"roles/custom.new": {Permissions: []string{"cloudkms.cryptoKeys.get"}},
+		},
+		Groups: map[string]Group{
+			"devs": {Members: []string{"user:b@example.com"}},
+		},
+		Projects: map[string]Project{
+			"test-project": {
+				Bindings: []Binding{
+					{Role: "roles/custom.dev", Members: []string{"group:devs", "user:c@example.com"}},
+					{Role: "roles/editor", Members: []string{"user:a@example.com"}},
+				},
+			},
+			"new-project": {},
+		},
+	}
+
+	diff := Diff(oldPolicy, newPolicy)
+
+	if !reflect.DeepEqual(diff.RolesAdded, []string{"roles/custom.new"}) {
+		t.Errorf("RolesAdded = %v", diff.RolesAdded)
+	}
+	if !reflect.DeepEqual(diff.RolesRemoved, []string{"roles/custom.gone"}) {
+		t.Errorf("RolesRemoved = %v", diff.RolesRemoved)
+	}
+	if len(diff.RolesChanged) != 1 || !reflect.DeepEqual(diff.RolesChanged[0].PermissionsAdded, []string{"secretmanager.secrets.list"}) {
+		t.Errorf("RolesChanged = %+v", diff.RolesChanged)
+	}
+
+	if len(diff.GroupsChanged) != 1 {
+		t.Fatalf("Expected 1 changed group, got %+v", diff.GroupsChanged)
+	}
+	if !reflect.DeepEqual(diff.GroupsChanged[0].MembersAdded, []string{"user:b@example.com"}) ||
+		!reflect.DeepEqual(diff.GroupsChanged[0].MembersRemoved, []string{"user:a@example.com"}) {
+		t.Errorf("GroupsChanged = %+v", diff.GroupsChanged[0])
+	}
+
+	if !reflect.DeepEqual(diff.ProjectsAdded, []string{"new-project"}) {
+		t.Errorf("ProjectsAdded = %v", diff.ProjectsAdded)
+	}
+
+	if len(diff.ProjectsChanged) != 1 {
+		t.Fatalf("Expected 1 changed project, got %+v", diff.ProjectsChanged)
+	}
+	project := diff.ProjectsChanged[0]
+	if len(project.BindingsAdded) != 1 || project.BindingsAdded[0].Role != "roles/editor" {
+		t.Errorf("BindingsAdded = %+v", project.BindingsAdded)
+	}
+	if len(project.BindingsRemoved) != 1 || project.BindingsRemoved[0].Role != "roles/viewer" {
+		t.Errorf("BindingsRemoved = %+v", project.BindingsRemoved)
+	}
+	if len(project.BindingsChanged) != 1 || !reflect.DeepEqual(project.BindingsChanged[0].MembersAdded, []string{"user:c@example.com"}) {
+		t.Errorf("BindingsChanged = %+v", project.BindingsChanged)
+	}
+}
+
+func TestDiffServiceAccounts(t *testing.T) {
+	oldPolicy := &Policy{
+		ServiceAccounts: map[string]ServiceAccount{
+			"ci@test-project.iam.gserviceaccount.com":   {DisplayName: "CI"},
+			"gone@test-project.iam.gserviceaccount.com": {},
+		},
+	}
+	newPolicy := &Policy{
+		ServiceAccounts: map[string]ServiceAccount{
+			"ci@test-project.iam.gserviceaccount.com":  {DisplayName: "Build"},
+			"new@test-project.iam.gserviceaccount.com": {},
+		},
+	}
+
+	diff := Diff(oldPolicy, newPolicy)
+	if diff.Empty() {
+		t.Fatal("Expected service account changes to be reported")
+	}
+	if !reflect.DeepEqual(diff.ServiceAccountsAdded, []string{"new@test-project.iam.gserviceaccount.com"}) {
+		t.Errorf("ServiceAccountsAdded = %v", diff.ServiceAccountsAdded)
+	}
+	if !reflect.DeepEqual(diff.ServiceAccountsRemoved, []string{"gone@test-project.iam.gserviceaccount.com"}) {
+		t.Errorf("ServiceAccountsRemoved = %v", diff.ServiceAccountsRemoved)
+	}
+	if !reflect.DeepEqual(diff.ServiceAccountsChanged, []string{"ci@test-project.iam.gserviceaccount.com"}) {
+		t.Errorf("ServiceAccountsChanged = %v", diff.ServiceAccountsChanged)
+	}
+}
+
+func TestDiffHierarchy(t *testing.T) {
+	oldPolicy := &Policy{
+		Organizations: map[string]Organization{
+			"acme": {Bindings: []Binding{{Role: "roles/viewer", Members: []string{"user:a@example.com"}}}},
+		},
+		Folders: map[string]Folder{
+			"eng":  {Parent: "organizations/acme"},
+			"gone": {},
+		},
+		Projects: map[string]Project{
+			"test-project": {Parent: "folders/eng"},
+		},
+	}
+	newPolicy := &Policy{
+		Organizations: map[string]Organization{
+			"acme": {Bindings: []Binding{{Role: "roles/viewer", Members: []string{"user:a@example.com", "user:b@example.com"}}}},
+		},
+		Folders: map[string]Folder{
+			"eng":   {Bindings: []Binding{{Role: "roles/editor", Members: []string{"user:c@example.com"}}}},
+			"infra": {Parent: "organizations/acme"},
+		},
+		Projects: map[string]Project{
+			"test-project": {Parent: "folders/infra"},
+		},
+	}
+
+	diff := Diff(oldPolicy, newPolicy)
+
+	if len(diff.OrganizationsChanged) != 1 || !reflect.DeepEqual(diff.OrganizationsChanged[0].BindingsChanged[0].MembersAdded, []string{"user:b@example.com"}) {
+		t.Errorf("OrganizationsChanged = %+v", diff.OrganizationsChanged)
+	}
+	if !reflect.DeepEqual(diff.FoldersAdded, []string{"infra"}) || !reflect.DeepEqual(diff.FoldersRemoved, []string{"gone"}) {
+		t.Errorf("FoldersAdded = %v, FoldersRemoved = %v", diff.FoldersAdded, diff.FoldersRemoved)
+	}
+	if len(diff.FoldersChanged) != 1 {
+		t.Fatalf("Expected 1 changed folder, got %+v", diff.FoldersChanged)
+	}
+	folder := diff.FoldersChanged[0]
+	if folder.Parent == nil || *folder.Parent != (ValueChange{Old: "organizations/acme", New: ""}) {
+		t.Errorf("Folder parent change = %+v", folder.Parent)
+	}
+	if len(folder.BindingsAdded) != 1 || folder.BindingsAdded[0].Role != "roles/editor" {
+		t.Errorf("Folder BindingsAdded = %+v", folder.BindingsAdded)
+	}
+	if len(diff.ProjectsChanged) != 1 || diff.ProjectsChanged[0].Parent == nil || diff.ProjectsChanged[0].Parent.New != "folders/infra" {
+		t.Errorf("ProjectsChanged = %+v, want the parent change", diff.ProjectsChanged)
+	}
+}
+
+func TestDiffDenyAndResourceBindings(t *testing.T) {
+	deny := DenyBinding{
+		DeniedPrincipals:  []string{"user:a@example.com"},
+		DeniedPermissions: []string{"cloudkms.googleapis.com/cryptoKeyVersions.useToDecrypt"},
+	}
+	oldPolicy := &Policy{
+		Projects: map[string]Project{
+			"test-project": {
+				DenyBindings: []DenyBinding{deny},
+				Resources: map[string]Resource{
+					"secrets/db-password": {Bindings: []Binding{{Role: "roles/secretmanager.secretAccessor", Members: []string{"user:a@example.com"}}}},
+					"secrets/gone":        {},
+				},
+			},
+		},
+	}
+	changedDeny := deny
+	changedDeny.ExceptionPrincipals = []string{"user:b@example.com"}
+	newPolicy := &Policy{
+		Projects: map[string]Project{
+			"test-project": {
+				DenyBindings: []DenyBinding{changedDeny},
+				Resources: map[string]Resource{
+					"secrets/db-password":          {Bindings: []Binding{{Role: "roles/secretmanager.secretAccessor", Members: []string{"user:b@example.com"}}}},
+					"keyRings/main/cryptoKeys/app": {},
+				},
+			},
+		},
+	}
+
+	diff := Diff(oldPolicy, newPolicy)
+	if len(diff.ProjectsChanged) != 1 {
+		t.Fatalf("Expected 1 changed project, got %+v", diff.ProjectsChanged)
+	}
+	project := diff.ProjectsChanged[0]
+
+	if len(project.DenyBindingsAdded) != 1 || !reflect.DeepEqual(project.DenyBindingsAdded[0], changedDeny) {
+		t.Errorf("DenyBindingsAdded = %+v", project.DenyBindingsAdded)
+	}
+	if len(project.DenyBindingsRemoved) != 1 || !reflect.DeepEqual(project.DenyBindingsRemoved[0], deny) {
+		t.Errorf("DenyBindingsRemoved = %+v", project.DenyBindingsRemoved)
+	}
+
+	if !reflect.DeepEqual(project.ResourcesAdded, []string{"keyRings/main/cryptoKeys/app"}) {
+		t.Errorf("ResourcesAdded = %v", project.ResourcesAdded)
+	}
+	if !reflect.DeepEqual(project.ResourcesRemoved, []string{"secrets/gone"}) {
+		t.Errorf("ResourcesRemoved = %v", project.ResourcesRemoved)
+	}
+	if len(project.ResourcesChanged) != 1 || project.ResourcesChanged[0].Name != "secrets/db-password" {
+		t.Fatalf("ResourcesChanged = %+v", project.ResourcesChanged)
+	}
+	resource := project.ResourcesChanged[0]
+	if !reflect.DeepEqual(resource.BindingsChanged[0].MembersAdded, []string{"user:b@example.com"}) ||
+		!reflect.DeepEqual(resource.BindingsChanged[0].MembersRemoved, []string{"user:a@example.com"}) {
+		t.Errorf("Resource BindingsChanged = %+v", resource.BindingsChanged)
+	}
+
+	if diff := Diff(oldPolicy, oldPolicy); !diff.Empty() {
+		t.Errorf("Expected no diff for identical deny and resource bindings, got %+v", diff)
+	}
+}
+
+// TestDiffSeesEverySection guards policy apply and drift, which push and
+// report only what Diff finds: a change to any section must show up
+func TestDiffSeesEverySection(t *testing.T) {
+	base := func() *Policy {
+		return &Policy{
+			Roles: map[string]Role{
+				"roles/custom.dev": {Description: "Developers", Permissions: []string{"secretmanager.secrets.get"}},
+			},
+			Projects: map[string]Project{"test-project": {}},
+		}
+	}
+
+	changes := map[string]func(p *Policy){
+		"role description": func(p *Policy) {
+			p.Roles["roles/custom.dev"] = Role{Description: "Everyone", Permissions: []string{"secretmanager.secrets.get"}}
+		},
+		"deny binding": func(p *Policy) {
+			p.Projects["test-project"] = Project{DenyBindings: []DenyBinding{{
+				DeniedPrincipals:  []string{"user:a@example.com"},
+				DeniedPermissions: []string{"secretmanager.googleapis.com/secrets.get"},
+			}}}
+		},
+		"service account": func(p *Policy) {
+			p.ServiceAccounts = map[string]ServiceAccount{"ci@test-project.iam.gserviceaccount.com": {}}
+		},
+		"organization": func(p *Policy) { p.Organizations = map[string]Organization{"acme": {}} },
+		"folder":       func(p *Policy) { p.Folders = map[string]Folder{"eng": {}} },
+		"project parent": func(p *Policy) {
+			p.Projects["test-project"] = Project{Parent: "folders/eng"}
+		},
+		"resource binding": func(p *Policy) {
+			p.Projects["test-project"] = Project{Resources: map[string]Resource{"secrets/db-password": {}}}
+		},
+	}
+
+	for name, change := range changes {
+		t.Run(name, func(t *testing.T) {
+			changed := base()
+			change(changed)
+			if Diff(base(), changed).Empty() {
+				t.Errorf("Expected a %s change to be reported", name)
+			}
+		})
+	}
+}
@@ -0,0 +1,445 @@
+package policy
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"slices"
+	"strings"
+)
+
+// Docs is a human-readable summary of who can access what, built by
+// BuildDocs and rendered with Markdown or HTML. Every list is sorted or
+// kept in policy order so the rendered output is stable across runs.
+type Docs struct {
+	Projects   []ProjectDocs   `json:"projects"`
+	Roles      []RoleDocs      `json:"roles"`
+	Conditions []ConditionDocs `json:"conditions"`
+}
+
+// ProjectDocs lists the principals with access to a project
+type ProjectDocs struct {
+	Name   string       `json:"name"`
+	Parent string       `json:"parent,omitempty"`
+	Access []AccessDocs `json:"access"`
+	Denies []DenyDocs   `json:"denies,omitempty"`
+}
+
+// AccessDocs is everything one principal is granted in a project
+type AccessDocs struct {
+	Principal   string           `json:"principal"`
+	Grants      []GrantDocs      `json:"grants"`
+	Permissions []PermissionDocs `json:"permissions"`
+}
+
+// GrantDocs is a single role grant to a principal
+type GrantDocs struct {
+	Role string `json:"role"`
+
+	// Via lists the groups the principal is a member through, outermost first
+	Via []string `json:"via,omitempty"`
+
+	// Source is the ancestor or resource the binding is attached to, empty
+	// for the project's own bindings
+	Source string `json:"source,omitempty"`
+
+	// Condition is the ID of the grant's entry in Docs.Conditions
+	Condition string `json:"condition,omitempty"`
+}
+
+// PermissionDocs is a permission a principal holds. Conditions lists the
+// conditions it depends on, and is empty when any grant is unconditional.
+type PermissionDocs struct {
+	Permission string   `json:"permission"`
+	Conditions []string `json:"conditions,omitempty"`
+}
+
+// DenyDocs is a deny binding in a project
+type DenyDocs struct {
+	Principals  []string `json:"principals"`
+	Exceptions  []string `json:"exceptions,omitempty"`
+	Permissions []string `json:"permissions"`
+	Condition   string   `json:"condition,omitempty"`
+}
+
+// RoleDocs describes a custom role, or a built-in role used by a binding
+type RoleDocs struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Builtin     bool     `json:"builtin"`
+	Includes    []string `json:"includes,omitempty"`
+	Permissions []string `json:"permissions"`
+}
+
+// ConditionDocs is a condition referenced by ID from grants and denies.
+// Identical conditions share an entry.
+type ConditionDocs struct {
+	ID          string `json:"id"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Expression  string `json:"expression"`
+}
+
+// BuildDocs summarizes a policy for review. Each project lists every
+// principal reached by its own, inherited, and resource bindings, with
+// groups expanded, the roles granting access, and the resulting
+// permissions. Deny bindings are listed separately and not applied.
+func BuildDocs(policy *Policy) *Docs {
+	docs := &Docs{
+		Projects:   []ProjectDocs{},
+		Roles:      []RoleDocs{},
+		Conditions: []ConditionDocs{},
+	}
+
+	conditionIDs := make(map[Condition]string)
+	conditionID := func(c *Condition) string {
+		if c == nil {
+			return ""
+		}
+		if id, ok := conditionIDs[*c]; ok {
+			return id
+		}
+		id := fmt.Sprintf("C%d", len(docs.Conditions)+1)
+		conditionIDs[*c] = id
+		docs.Conditions = append(docs.Conditions, ConditionDocs{
+			ID:          id,
+			Title:       c.Title,
+			Description: c.Description,
+			Expression:  c.Expression,
+		})
+		return id
+	}
+
+	usedRoles := make(map[string]bool)
+
+	for _, name := range sortedKeys(policy.Projects) {
+		project := policy.Projects[name]
+		projectDocs := ProjectDocs{Name: name, Parent: project.Parent, Access: []AccessDocs{}}
+
+		bindings := EffectiveBindings(policy, name)
+		for _, resource := range sortedKeys(project.Resources) {
+			bindings = append(bindings, scopedBindings(resource, project.Resources[resource].Bindings)...)
+		}
+
+		grants := make(map[string][]GrantDocs)
+		held := make(map[string]map[string][]string)
+
+		for _, scoped := range bindings {
+			binding := scoped.Binding
+			usedRoles[binding.Role] = true
+
+			source := scoped.Scope
+			if source == "projects/"+name {
+				source = ""
+			}
+			cond := conditionID(binding.Condition)
+			perms := RolePermissions(policy, binding.Role)
+
+			expanded := ExpandMembers(policy, binding.Members)
+			for _, principal := range sortedKeys(expanded) {
+				grants[principal] = append(grants[principal], GrantDocs{
+					Role:      binding.Role,
+					Via:       prefixGroups(expanded[principal]),
+					Source:    source,
+					Condition: cond,
+				})
+
+				if held[principal] == nil {
+					held[principal] = make(map[string][]string)
+				}
+				for _, perm := range perms {
+					conds, seen := held[principal][perm]
+					switch {
+					case seen && len(conds) == 0:
+						// Already held unconditionally
+					case cond == "":
+						held[principal][perm] = nil
+					case !slices.Contains(conds, cond):
+						held[principal][perm] = append(conds, cond)
+					default:
+						held[principal][perm] = conds
+					}
+				}
+			}
+		}
+
+		for _, principal := range sortedKeys(grants) {
+			access := AccessDocs{Principal: principal, Grants: grants[principal], Permissions: []PermissionDocs{}}
+			for _, perm := range sortedKeys(held[principal]) {
+				access.Permissions = append(access.Permissions, PermissionDocs{Permission: perm, Conditions: held[principal][perm]})
+			}
+			projectDocs.Access = append(projectDocs.Access, access)
+		}
+
+		for _, deny := range project.DenyBindings {
+			projectDocs.Denies = append(projectDocs.Denies, DenyDocs{
+				Principals:  deny.DeniedPrincipals,
+				Exceptions:  deny.ExceptionPrincipals,
+				Permissions: deny.DeniedPermissions,
+				Condition:   conditionID(deny.Condition),
+			})
+		}
+
+		docs.Projects = append(docs.Projects, projectDocs)
+	}
+
+	for _, name := range sortedKeys(policy.Roles) {
+		role := policy.Roles[name]
+		docs.Roles = append(docs.Roles, RoleDocs{
+			Name:        name,
+			Description: role.Description,
+			Includes:    role.IncludeRoles,
+			Permissions: RolePermissions(policy, name),
+		})
+		for _, included := range role.IncludeRoles {
+			usedRoles[included] = true
+		}
+	}
+	for _, name := range sortedKeys(usedRoles) {
+		if _, custom := policy.Roles[name]; custom {
+			continue
+		}
+		builtin, ok := DescribeRole(name)
+		if !ok {
+			continue
+		}
+		docs.Roles = append(docs.Roles, RoleDocs{
+			Name:        name,
+			Description: builtin.Title,
+			Builtin:     true,
+			Permissions: builtin.Permissions,
+		})
+	}
+
+	return docs
+}
+
+// prefixGroups turns a chain of group names into group:NAME members
+func prefixGroups(chain []string) []string {
+	if len(chain) == 0 {
+		return nil
+	}
+	members := make([]string, len(chain))
+	for i, name := range chain {
+		members[i] = "group:" + name
+	}
+	return members
+}
+
+// describe renders a grant as "role (C1) from folders/eng via group:a"
+func (g GrantDocs) describe() string {
+	var b strings.Builder
+	b.WriteString(g.Role)
+	if g.Condition != "" {
+		fmt.Fprintf(&b, " (%s)", g.Condition)
+	}
+	if g.Source != "" {
+		if strings.HasPrefix(g.Source, "folders/") || strings.HasPrefix(g.Source, "organizations/") {
+			fmt.Fprintf(&b, " from %s", g.Source)
+		} else {
+			fmt.Fprintf(&b, " on %s", g.Source)
+		}
+	}
+	if len(g.Via) > 0 {
+		fmt.Fprintf(&b, " via %s", strings.Join(g.Via, ", "))
+	}
+	return b.String()
+}
+
+// describe renders a permission with the conditions it depends on
+func (p PermissionDocs) describe() string {
+	if len(p.Conditions) == 0 {
+		return p.Permission
+	}
+	return fmt.Sprintf("%s (%s)", p.Permission, strings.Join(p.Conditions, " or "))
+}
+
+// Markdown renders the docs as a markdown document
+func (d *Docs) Markdown() []byte {
+	var b bytes.Buffer
+
+	b.WriteString("# IAM Policy\n\n")
+	b.WriteString("Generated by `gcp-emulator policy docs`. Group memberships are expanded; ")
+	b.WriteString("grants marked (C1), (C2), ... apply only when the condition of that name in the Conditions section is true.\n")
+
+	b.WriteString("\n## Projects\n")
+	for _, project := range d.Projects {
+		fmt.Fprintf(&b, "\n### %s\n\n", project.Name)
+		if project.Parent != "" {
+			fmt.Fprintf(&b, "Parent: `%s`\n\n", project.Parent)
+		}
+
+		if len(project.Access) == 0 {
+			b.WriteString("No principals have access.\n")
+		} else {
+			b.WriteString("| Principal | Roles | Permissions |\n")
+			b.WriteString("|-----------|-------|-------------|\n")
+			for _, access := range project.Access {
+				var grants, perms []string
+				for _, g := range access.Grants {
+					grants = append(grants, g.describe())
+				}
+				for _, p := range access.Permissions {
+					perms = append(perms, p.describe())
+				}
+				fmt.Fprintf(&b, "| %s | %s | %s |\n", markdownCell([]string{access.Principal}), markdownCell(grants), markdownCell(perms))
+			}
+		}
+
+		if len(project.Denies) > 0 {
+			b.WriteString("\n**Deny rules**\n\n")
+			b.WriteString("| Denied principals | Except | Denied permissions | Condition |\n")
+			b.WriteString("|-------------------|--------|--------------------|-----------|\n")
+			for _, deny := range project.Denies {
+				fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", markdownCell(deny.Principals), markdownCell(deny.Exceptions), markdownCell(deny.Permissions), deny.Condition)
+			}
+		}
+	}
+
+	if len(d.Roles) > 0 {
+		b.WriteString("\n## Roles\n")
+		for _, role := range d.Roles {
+			fmt.Fprintf(&b, "\n### %s\n\n", role.Name)
+			if role.Builtin {
+				b.WriteString("Built-in role")
+				if role.Description != "" {
+					fmt.Fprintf(&b, ": %s", role.Description)
+				}
+				b.WriteString("\n\n")
+			} else if role.Description != "" {
+				fmt.Fprintf(&b, "%s\n\n", role.Description)
+			}
+			if len(role.Includes) > 0 {
+				fmt.Fprintf(&b, "Includes: %s\n\n", strings.Join(role.Includes, ", "))
+			}
+			if len(role.Permissions) == 0 {
+				b.WriteString("No permissions.\n")
+			}
+			for _, perm := range role.Permissions {
+				fmt.Fprintf(&b, "- `%s`\n", perm)
+			}
+		}
+	}
+
+	if len(d.Conditions) > 0 {
+		b.WriteString("\n## Conditions\n")
+		for _, cond := range d.Conditions {
+			fmt.Fprintf(&b, "\n### %s", cond.ID)
+			if cond.Title != "" {
+				fmt.Fprintf(&b, ": %s", cond.Title)
+			}
+			b.WriteString("\n\n")
+			if cond.Description != "" {
+				fmt.Fprintf(&b, "%s\n\n", cond.Description)
+			}
+			fmt.Fprintf(&b, "```cel\n%s\n```\n", strings.TrimSpace(cond.Expression))
+		}
+	}
+
+	return b.Bytes()
+}
+
+// markdownCell joins values into a table cell, one per line, escaping
+// characters that would break the table
+func markdownCell(values []string) string {
+	escaped := make([]string, len(values))
+	for i, v := range values {
+		v = strings.ReplaceAll(v, "|", `\|`)
+		escaped[i] = strings.ReplaceAll(v, "\n", " ")
+	}
+	return strings.Join(escaped, "<br>")
+}
+
+// docsHTMLTemplate renders Docs as a standalone HTML page
+var docsHTMLTemplate = template.Must(template.New("docs").Funcs(template.FuncMap{
+	"grant":      GrantDocs.describe,
+	"permission": PermissionDocs.describe,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>IAM Policy</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+code, pre { font-family: monospace; }
+</style>
+</head>
+<body>
+<h1>IAM Policy</h1>
+<p>Generated by <code>gcp-emulator policy docs</code>. Group memberships are expanded; grants marked (C1), (C2), ... apply only when the condition of that name in the Conditions section is true.</p>
+
+<h2>Projects</h2>
+{{- range .Projects}}
+<h3 id="project-{{.Name}}">{{.Name}}</h3>
+{{- if .Parent}}
+<p>Parent: <code>{{.Parent}}</code></p>
+{{- end}}
+{{- if .Access}}
+<table>
+<tr><th>Principal</th><th>Roles</th><th>Permissions</th></tr>
+{{- range .Access}}
+<tr><td>{{.Principal}}</td><td>{{range $i, $g := .Grants}}{{if $i}}<br>{{end}}{{grant $g}}{{end}}</td><td>{{range $i, $p := .Permissions}}{{if $i}}<br>{{end}}{{permission $p}}{{end}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>No principals have access.</p>
+{{- end}}
+{{- if .Denies}}
+<p><strong>Deny rules</strong></p>
+<table>
+<tr><th>Denied principals</th><th>Except</th><th>Denied permissions</th><th>Condition</th></tr>
+{{- range .Denies}}
+<tr><td>{{range $i, $v := .Principals}}{{if $i}}<br>{{end}}{{$v}}{{end}}</td><td>{{range $i, $v := .Exceptions}}{{if $i}}<br>{{end}}{{$v}}{{end}}</td><td>{{range $i, $v := .Permissions}}{{if $i}}<br>{{end}}{{$v}}{{end}}</td><td>{{.Condition}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+{{- if .Roles}}
+
+<h2>Roles</h2>
+{{- range .Roles}}
+<h3>{{.Name}}</h3>
+{{- if .Builtin}}
+<p>Built-in role{{if .Description}}: {{.Description}}{{end}}</p>
+{{- else if .Description}}
+<p>{{.Description}}</p>
+{{- end}}
+{{- if .Includes}}
+<p>Includes: {{range $i, $v := .Includes}}{{if $i}}, {{end}}{{$v}}{{end}}</p>
+{{- end}}
+{{- if .Permissions}}
+<ul>
+{{- range .Permissions}}
+<li><code>{{.}}</code></li>
+{{- end}}
+</ul>
+{{- else}}
+<p>No permissions.</p>
+{{- end}}
+{{- end}}
+{{- end}}
+{{- if .Conditions}}
+
+<h2>Conditions</h2>
+{{- range .Conditions}}
+<h3 id="{{.ID}}">{{.ID}}{{if .Title}}: {{.Title}}{{end}}</h3>
+{{- if .Description}}
+<p>{{.Description}}</p>
+{{- end}}
+<pre>{{.Expression}}</pre>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// HTML renders the docs as a standalone HTML page
+func (d *Docs) HTML() ([]byte, error) {
+	var b bytes.Buffer
+	if err := docsHTMLTemplate.Execute(&b, d); err != nil {
+		return nil, fmt.Errorf("failed to render HTML: %w", err)
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,126 @@
+package policy
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildDocs(t *testing.T) {
+	pol := simulatePolicy()
+	pol.Roles["roles/custom.admin"] = Role{
+		Description: "Manages secrets",
+		Permissions: pol.Roles["roles/custom.admin"].Permissions,
+	}
+	pol.Projects["test-project"] = Project{
+		Bindings: append(pol.Projects["test-project"].Bindings,
+			Binding{Role: "roles/secretmanager.secretAccessor", Members: []string{"user:bob@example.com"}}),
+	}
+
+	docs := BuildDocs(pol)
+
+	if len(docs.Projects) != 1 {
+		t.Fatalf("got %d projects, want 1", len(docs.Projects))
+	}
+	access := docs.Projects[0].Access
+	var principals []string
+	for _, a := range access {
+		principals = append(principals, a.Principal)
+	}
+	want := []string{"serviceAccount:ci@test-project.iam.gserviceaccount.com", "user:alice@example.com", "user:bob@example.com"}
+	if !reflect.DeepEqual(principals, want) {
+		t.Fatalf("principals = %v, want %v", principals, want)
+	}
+
+	// alice is reached through admins -> developers
+	alice := access[1]
+	if len(alice.Grants) != 1 || !reflect.DeepEqual(alice.Grants[0].Via, []string{"group:admins", "group:developers"}) {
+		t.Errorf("alice grants = %+v", alice.Grants)
+	}
+
+	// The CI runner's permission depends on its condition
+	ci := access[0]
+	if len(ci.Permissions) != 1 || !reflect.DeepEqual(ci.Permissions[0].Conditions, []string{"C1"}) {
+		t.Errorf("ci permissions = %+v", ci.Permissions)
+	}
+	if len(docs.Conditions) != 1 || docs.Conditions[0].Title != "CI limited to production secrets" {
+		t.Errorf("conditions = %+v", docs.Conditions)
+	}
+
+	// Custom roles first, then the built-in roles bindings use
+	var roles []string
+	for _, r := range docs.Roles {
+		roles = append(roles, r.Name)
+	}
+	wantRoles := []string{"roles/custom.admin", "roles/custom.ciRunner", "roles/secretmanager.secretAccessor"}
+	if !reflect.DeepEqual(roles, wantRoles) {
+		t.Errorf("roles = %v, want %v", roles, wantRoles)
+	}
+	if docs.Roles[0].Description != "Manages secrets" || !docs.Roles[2].Builtin {
+		t.Errorf("roles = %+v", docs.Roles)
+	}
+}
+
+func TestBuildDocsSharedCondition(t *testing.T) {
+	cond := &Condition{Title: "Business hours", Expression: "request.time.getHours() >= 9"}
+	pol := &Policy{
+		Roles: map[string]Role{"roles/custom.reader": {Permissions: []string{"secretmanager.secrets.get"}}},
+		Projects: map[string]Project{
+			"test-project": {
+				Bindings: []Binding{
+					{Role: "roles/custom.reader", Members: []string{"user:alice@example.com"}, Condition: cond},
+					{Role: "roles/custom.reader", Members: []string{"user:bob@example.com"}, Condition: &Condition{Title: cond.Title, Expression: cond.Expression}},
+					{Role: "roles/custom.reader", Members: []string{"user:bob@example.com"}},
+				},
+			},
+		},
+	}
+
+	docs := BuildDocs(pol)
+
+	if len(docs.Conditions) != 1 {
+		t.Errorf("got %d conditions, want identical conditions to share one", len(docs.Conditions))
+	}
+	// bob also holds the role unconditionally
+	bob := docs.Projects[0].Access[1]
+	if len(bob.Permissions[0].Conditions) != 0 {
+		t.Errorf("bob permission conditions = %v, want none", bob.Permissions[0].Conditions)
+	}
+}
+
+func TestDocsRendering(t *testing.T) {
+	pol := simulatePolicy()
+	pol.Projects["test-project"].Bindings[1].Condition.Expression = `resource.name.startsWith("a") || resource.name.startsWith("b")`
+
+	md := BuildDocs(pol).Markdown()
+	for _, want := range []string{
+		"### test-project",
+		"| user:alice@example.com | roles/custom.admin via group:admins, group:developers | secretmanager.secrets.create<br>secretmanager.secrets.get |",
+		"roles/custom.ciRunner (C1)",
+		"secretmanager.secrets.get (C1)",
+		"### C1: CI limited to production secrets",
+		`resource.name.startsWith("a") || resource.name.startsWith("b")`,
+	} {
+		if !strings.Contains(string(md), want) {
+			t.Errorf("markdown missing %q:\n%s", want, md)
+		}
+	}
+	if !bytes.Equal(md, BuildDocs(pol).Markdown()) {
+		t.Error("markdown is not deterministic")
+	}
+
+	html, err := BuildDocs(pol).HTML()
+	if err != nil {
+		t.Fatalf("HTML() error: %v", err)
+	}
+	for _, want := range []string{
+		"<h3 id=\"project-test-project\">test-project</h3>",
+		"roles/custom.admin via group:admins, group:developers",
+		"resource.name.startsWith(&#34;a&#34;) || resource.name.startsWith(&#34;b&#34;)",
+	} {
+		if !strings.Contains(string(html), want) {
+			t.Errorf("HTML missing %q:\n%s", want, html)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package policy
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EncryptedExt marks a policy file encrypted with Encrypt, as in
+// policy.yaml.enc. The extension before it gives the plaintext format.
+const EncryptedExt = ".enc"
+
+// PolicyKeyEnv names the environment variable holding the passphrase for
+// encrypted policy files
+const PolicyKeyEnv = "GCP_EMULATOR_POLICY_KEY"
+
+// LoadOptions configures how policy files are read. The zero value reads
+// the passphrase for encrypted files from PolicyKeyEnv; Load uses it.
+type LoadOptions struct {
+	// KeyFile, when set, names a file holding the passphrase for encrypted
+	// policy files. It takes precedence over PolicyKeyEnv.
+	KeyFile string
+}
+
+// SaveOptions configures how policy files are written. The zero value
+// refuses to replace an encrypted file; Save uses it.
+type SaveOptions struct {
+	// AllowDecrypt lets plaintext be written over a file that is encrypted
+	AllowDecrypt bool
+}
+
+// ErrNoPolicyKey is returned when an encrypted policy is loaded without a
+// passphrase
+var ErrNoPolicyKey = errors.New("policy file is encrypted; set " + PolicyKeyEnv + " or use --policy-key-file")
+
+// ErrWouldDecrypt is returned by Save instead of replacing an encrypted
+// file with plaintext
+var ErrWouldDecrypt = errors.New("refusing to write a decrypted policy over an encrypted file (use --decrypt to allow it)")
+
+// encryptedHeader starts every encrypted policy file, followed by the
+// base64 of salt, nonce, and AES-256-GCM ciphertext
+const encryptedHeader = "gcp-emulator-policy-encrypted v1\n"
+
+// Key derivation and ciphertext layout
+const (
+	saltSize         = 16
+	nonceSize        = 12
+	keySize          = 32
+	pbkdf2Iterations = 600000
+)
+
+// IsEncrypted reports whether data was produced by Encrypt
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encryptedHeader))
+}
+
+// PolicyKey returns the passphrase for encrypted policies from o.KeyFile or
+// PolicyKeyEnv, or ErrNoPolicyKey if neither is set
+func (o LoadOptions) PolicyKey() (string, error) {
+	if o.KeyFile != "" {
+		data, err := os.ReadFile(o.KeyFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read policy key file: %w", err)
+		}
+		key := strings.TrimRight(string(data), "\r\n")
+		if key == "" {
+			return "", fmt.Errorf("policy key file %s is empty", o.KeyFile)
+		}
+		return key, nil
+	}
+
+	if key := os.Getenv(PolicyKeyEnv); key != "" {
+		return key, nil
+	}
+	return "", ErrNoPolicyKey
+}
+
+// Encrypt seals a policy document with a key derived from passphrase
+func Encrypt(plaintext []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	aead, err := policyCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := append(append(salt, nonce...), aead.Seal(nil, nonce, plaintext, []byte(encryptedHeader))...)
+
+	var out bytes.Buffer
+	out.WriteString(encryptedHeader)
+	out.WriteString(base64.StdEncoding.EncodeToString(sealed))
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// Decrypt opens a document sealed by Encrypt
+func Decrypt(data []byte, passphrase string) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, fmt.Errorf("not an encrypted policy file")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data[len(encryptedHeader):])))
+	if err != nil || len(sealed) < saltSize+nonceSize {
+		return nil, fmt.Errorf("encrypted policy file is corrupt")
+	}
+	salt, nonce, ciphertext := sealed[:saltSize], sealed[saltSize:saltSize+nonceSize], sealed[saltSize+nonceSize:]
+
+	aead, err := policyCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(encryptedHeader))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt policy: wrong key or corrupt file")
+	}
+	return plaintext, nil
+}
+
+// policyCipher derives the AES-256-GCM cipher for passphrase and salt
+func policyCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	if passphrase == "" {
+		return nil, ErrNoPolicyKey
+	}
+
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, pbkdf2Iterations, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive policy key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// CheckPlaintextWrite returns ErrWouldDecrypt if writing a plaintext policy
+// to path would replace an encrypted one: path ends in .enc or holds an
+// encrypted policy
+func CheckPlaintextWrite(path string) error {
+	return SaveOptions{}.CheckPlaintextWrite(path)
+}
+
+// CheckPlaintextWrite is CheckPlaintextWrite, passing when o.AllowDecrypt
+// is set
+func (o SaveOptions) CheckPlaintextWrite(path string) error {
+	if o.AllowDecrypt {
+		return nil
+	}
+	existing, err := os.ReadFile(path)
+	if strings.EqualFold(filepath.Ext(path), EncryptedExt) || err == nil && IsEncrypted(existing) {
+		return fmt.Errorf("%s: %w", path, ErrWouldDecrypt)
+	}
+	return nil
+}
+
+// readPolicyFile reads a policy file, decrypting it if it ends in .enc.
+// It returns the plaintext and the lowercased extension of its format.
+func (o LoadOptions) readPolicyFile(path string) ([]byte, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != EncryptedExt {
+		return data, ext, nil
+	}
+
+	key, err := o.PolicyKey()
+	if err != nil {
+		return nil, "", err
+	}
+	plaintext, err := Decrypt(data, key)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", path, err)
+	}
+	return plaintext, strings.ToLower(filepath.Ext(strings.TrimSuffix(path, filepath.Ext(path)))), nil
+}
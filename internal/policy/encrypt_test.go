@@ -0,0 +1,104 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const encryptTestPolicy = `roles:
+  roles/custom.ciRunner:
+    permissions:
+      - secretmanager.secrets.get
+projects:
+  test-project:
+    bindings:
+      - role: roles/custom.ciRunner
+        members:
+          - serviceAccount:ci@test-project.iam.gserviceaccount.com
+`
+
+func TestEncryptDecrypt(t *testing.T) {
+	encrypted, err := Encrypt([]byte(encryptTestPolicy), "secret")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if !IsEncrypted(encrypted) {
+		t.Error("Encrypted output is not recognized as encrypted")
+	}
+
+	plaintext, err := Decrypt(encrypted, "secret")
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if string(plaintext) != encryptTestPolicy {
+		t.Errorf("Decrypt = %q, want the original policy", plaintext)
+	}
+
+	if _, err := Decrypt(encrypted, "wrong"); err == nil {
+		t.Error("Expected error decrypting with the wrong key")
+	}
+}
+
+func TestLoadEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.yaml.enc")
+
+	encrypted, err := Encrypt([]byte(encryptTestPolicy), "secret")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	writeFiles(t, dir, map[string]string{"policy.yaml.enc": string(encrypted)})
+
+	t.Setenv(PolicyKeyEnv, "")
+	if _, err := Load(path); !errors.Is(err, ErrNoPolicyKey) {
+		t.Errorf("Expected ErrNoPolicyKey without a key, got %v", err)
+	}
+
+	t.Setenv(PolicyKeyEnv, "secret")
+	pol, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if _, ok := pol.Roles["roles/custom.ciRunner"]; !ok {
+		t.Errorf("Decrypted policy is missing its role: %+v", pol.Roles)
+	}
+
+	// Positions refer to the plaintext
+	if pos := pol.rolePosition("roles/custom.ciRunner", ""); pos.Line != 2 {
+		t.Errorf("role position = %v, want line 2", pos)
+	}
+
+	// The key file takes precedence over the environment
+	keyFile := filepath.Join(dir, "key")
+	writeFiles(t, dir, map[string]string{"key": "wrong\n"})
+	if _, err := (LoadOptions{KeyFile: keyFile}).Load(path); err == nil {
+		t.Error("Expected the key file's wrong key to be used")
+	}
+}
+
+func TestSaveOverEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	encrypted, err := Encrypt([]byte(encryptTestPolicy), "secret")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	// An encrypted file without the .enc extension is protected too
+	writeFiles(t, dir, map[string]string{"policy.yaml": string(encrypted)})
+
+	pol := simulatePolicy()
+	for _, name := range []string{"policy.yaml", "new.yaml.enc"} {
+		if err := Save(pol, filepath.Join(dir, name)); !errors.Is(err, ErrWouldDecrypt) {
+			t.Errorf("Save(%s) = %v, want ErrWouldDecrypt", name, err)
+		}
+	}
+
+	if err := (SaveOptions{AllowDecrypt: true}).Save(pol, filepath.Join(dir, "policy.yaml")); err != nil {
+		t.Fatalf("Save with AllowDecrypt failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "policy.yaml"))
+	if err != nil || IsEncrypted(data) {
+		t.Errorf("Expected plaintext policy after Save, got %v", err)
+	}
+}
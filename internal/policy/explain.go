@@ -0,0 +1,135 @@
+package policy
+
+import (
+	"sort"
+)
+
+// Grant is a binding that gives a principal a set of permissions
+type Grant struct {
+	// Scope is the project, folder, or organization the binding is attached to
+	Scope   string  `json:"scope"`
+	Index   int     `json:"index"`
+	Binding Binding `json:"binding"`
+
+	// Member is the binding member that matched the principal
+	Member string `json:"member"`
+
+	// Via lists the groups the principal was matched through, innermost first
+	Via []string `json:"via,omitempty"`
+
+	Permissions []string `json:"permissions"`
+}
+
+// Conditional reports whether the grant is restricted by a condition
+func (g Grant) Conditional() bool {
+	return g.Binding.Condition != nil
+}
+
+// EffectivePermissions returns every binding in the project, including those
+// inherited from folders and the organization, that applies to the
+// principal, with the permissions each one grants. Conditions are not
+// evaluated; conditional grants are returned and marked by Conditional.
+func EffectivePermissions(policy *Policy, principal, project string) []Grant {
+	var grants []Grant
+
+	matches := MemberMatches(policy, principal)
+	for _, scoped := range EffectiveBindings(policy, project) {
+		binding := scoped.Binding
+		member, via, ok := matchBindingMember(binding, matches)
+		if !ok {
+			continue
+		}
+
+		grants = append(grants, Grant{
+			Scope:       scoped.Scope,
+			Index:       scoped.Index,
+			Binding:     binding,
+			Member:      member,
+			Via:         via,
+			Permissions: RolePermissions(policy, binding.Role),
+		})
+	}
+
+	return grants
+}
+
+// Holder is a principal that holds a permission through a binding
+type Holder struct {
+	Principal string `json:"principal"`
+	Role      string `json:"role"`
+	Scope     string `json:"scope"`
+	Index     int    `json:"binding"`
+
+	// Via lists the groups expanded to reach the principal, outermost first
+	Via       []string   `json:"via,omitempty"`
+	Condition *Condition `json:"condition,omitempty"`
+
+	// DenyConditions lists the conditions of the conditional deny bindings
+	// that may override this holder's access
+	DenyConditions []Condition `json:"denyConditions,omitempty"`
+}
+
+// Conditional reports whether the holder's access is restricted by a condition
+func (h Holder) Conditional() bool {
+	return h.Condition != nil
+}
+
+// Public reports whether the holder is allUsers or allAuthenticatedUsers
+func (h Holder) Public() bool {
+	return h.Principal == "allUsers" || h.Principal == "allAuthenticatedUsers"
+}
+
+// PrincipalsWithPermission returns every individual principal holding the
+// permission in the project, with groups expanded and inherited bindings
+// and bindings on the project's resources included. A principal appears once
+// per binding that grants it the permission. Principals covered by an
+// unconditional deny binding are omitted; conditional denies are recorded in
+// DenyConditions. Results are sorted by principal.
+func PrincipalsWithPermission(policy *Policy, permission, project string) []Holder {
+	var holders []Holder
+
+	bindings := EffectiveBindings(policy, project)
+	resources := policy.Projects[project].Resources
+	for _, name := range sortedKeys(resources) {
+		for i, binding := range resources[name].Bindings {
+			bindings = append(bindings, ScopedBinding{Scope: "projects/" + project + "/" + name, Index: i, Binding: binding})
+		}
+	}
+
+	for _, scoped := range bindings {
+		binding := scoped.Binding
+		if !roleGrants(policy, binding.Role, permission) {
+			continue
+		}
+
+		expanded := ExpandMembers(policy, binding.Members)
+		for _, principal := range sortedKeys(expanded) {
+			holder := Holder{
+				Principal: principal,
+				Role:      binding.Role,
+				Scope:     scoped.Scope,
+				Index:     scoped.Index,
+				Via:       expanded[principal],
+				Condition: binding.Condition,
+			}
+
+			denied := false
+			for _, deny := range evaluateDenies(policy, project, permission, MemberMatches(policy, principal), nil) {
+				if deny.DenyBinding.Condition == nil {
+					denied = true
+					break
+				}
+				holder.DenyConditions = append(holder.DenyConditions, *deny.DenyBinding.Condition)
+			}
+			if !denied {
+				holders = append(holders, holder)
+			}
+		}
+	}
+
+	sort.SliceStable(holders, func(a, b int) bool {
+		return holders[a].Principal < holders[b].Principal
+	})
+
+	return holders
+}
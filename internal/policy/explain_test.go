@@ -0,0 +1,114 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEffectivePermissions(t *testing.T) {
+	pol := simulatePolicy()
+
+	grants := EffectivePermissions(pol, "user:alice@example.com", "test-project")
+	if len(grants) != 1 {
+		t.Fatalf("Expected 1 grant for alice, got %d", len(grants))
+	}
+	if grants[0].Binding.Role != "roles/custom.admin" {
+		t.Errorf("Role = %s, want roles/custom.admin", grants[0].Binding.Role)
+	}
+	if !reflect.DeepEqual(grants[0].Permissions, []string{"secretmanager.secrets.create", "secretmanager.secrets.get"}) {
+		t.Errorf("Permissions = %v", grants[0].Permissions)
+	}
+	if grants[0].Conditional() {
+		t.Error("admin grant should not be conditional")
+	}
+
+	grants = EffectivePermissions(pol, "serviceAccount:ci@test-project.iam.gserviceaccount.com", "test-project")
+	if len(grants) != 1 || !grants[0].Conditional() {
+		t.Fatalf("Expected 1 conditional grant for ci, got %+v", grants)
+	}
+
+	if grants := EffectivePermissions(pol, "user:nobody@example.com", "test-project"); len(grants) != 0 {
+		t.Errorf("Expected no grants for unknown principal, got %+v", grants)
+	}
+}
+
+func TestExpandMembers(t *testing.T) {
+	pol := &Policy{
+		Groups: map[string]Group{
+			"developers": {Members: []string{"user:alice@example.com", "group:juniors"}},
+			"juniors":    {Members: []string{"user:bob@example.com", "group:developers"}},
+		},
+	}
+
+	expanded := ExpandMembers(pol, []string{"group:developers", "serviceAccount:ci@test-project.iam.gserviceaccount.com"})
+
+	want := map[string][]string{
+		"user:alice@example.com":                                 {"developers"},
+		"user:bob@example.com":                                   {"developers", "juniors"},
+		"serviceAccount:ci@test-project.iam.gserviceaccount.com": nil,
+	}
+	if !reflect.DeepEqual(expanded, want) {
+		t.Errorf("ExpandMembers() = %v, want %v", expanded, want)
+	}
+}
+
+func TestMentionsPrincipal(t *testing.T) {
+	pol := &Policy{
+		Groups: map[string]Group{
+			"developers": {Members: []string{"user:alice@example.com"}},
+		},
+		ServiceAccounts: map[string]ServiceAccount{
+			"ci@test-project.iam.gserviceaccount.com": {},
+		},
+		Folders: map[string]Folder{
+			"eng": {Bindings: []Binding{{Role: "roles/viewer", Members: []string{"user:bob@example.com"}}}},
+		},
+	}
+
+	for principal, want := range map[string]bool{
+		"user:alice@example.com":                                 true,
+		"user:bob@example.com":                                   true,
+		"serviceAccount:ci@test-project.iam.gserviceaccount.com": true,
+		"user:carol@example.com":                                 false,
+		"user:ci@test-project.iam.gserviceaccount.com":           false,
+	} {
+		if got := MentionsPrincipal(pol, principal); got != want {
+			t.Errorf("MentionsPrincipal(%s) = %t, want %t", principal, got, want)
+		}
+	}
+}
+
+func TestPrincipalsWithPermission(t *testing.T) {
+	pol := simulatePolicy()
+	pol.Projects["test-project"] = Project{
+		Bindings: append(pol.Projects["test-project"].Bindings, Binding{
+			Role:    "roles/custom.ciRunner",
+			Members: []string{"allUsers"},
+		}),
+	}
+
+	holders := PrincipalsWithPermission(pol, "secretmanager.secrets.get", "test-project")
+
+	var got []string
+	for _, h := range holders {
+		got = append(got, h.Principal)
+	}
+	want := []string{"allUsers", "serviceAccount:ci@test-project.iam.gserviceaccount.com", "user:alice@example.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("principals = %v, want %v", got, want)
+	}
+
+	if !holders[0].Public() {
+		t.Error("allUsers should be reported as public")
+	}
+	if !holders[1].Conditional() {
+		t.Error("ci access should be conditional")
+	}
+	if !reflect.DeepEqual(holders[2].Via, []string{"admins", "developers"}) {
+		t.Errorf("alice Via = %v, want [admins developers]", holders[2].Via)
+	}
+
+	if holders := PrincipalsWithPermission(pol, "secretmanager.secrets.create", "test-project"); len(holders) != 1 {
+		t.Errorf("Expected only alice to hold create, got %+v", holders)
+	}
+}
@@ -0,0 +1,156 @@
+package policy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Fix applies mechanical corrections that cannot change what the policy
+// grants: surrounding whitespace is trimmed, role names missing the roles/
+// prefix are prefixed, duplicate permissions and members are removed, and
+// permission and member lists are sorted. Issues that need a decision, such
+// as undefined roles or invalid conditions, are left for Validate to report.
+// It returns a description of each change made.
+func Fix(policy *Policy) []string {
+	var changes []string
+
+	// Role definitions
+	for _, name := range sortedKeys(policy.Roles) {
+		role := policy.Roles[name]
+
+		fixedName := strings.TrimSpace(name)
+		if !strings.HasPrefix(fixedName, "roles/") {
+			fixedName = "roles/" + fixedName
+		}
+		// When both spellings are defined, renaming would drop one
+		if _, exists := policy.Roles[fixedName]; fixedName != name && !exists {
+			delete(policy.Roles, name)
+			renameRoleReferences(policy, name, fixedName)
+			changes = append(changes, fmt.Sprintf("Renamed role %q to %s", name, fixedName))
+			name = fixedName
+		}
+
+		loc := fmt.Sprintf("Role %s", name)
+		role.Permissions = fixList(role.Permissions, loc, "permission", &changes)
+		role.IncludeRoles = fixList(role.IncludeRoles, loc, "included role", &changes)
+		policy.Roles[name] = role
+	}
+
+	// Group members
+	for _, name := range sortedKeys(policy.Groups) {
+		group := policy.Groups[name]
+		group.Members = fixList(group.Members, fmt.Sprintf("Group %s", name), "member", &changes)
+		policy.Groups[name] = group
+	}
+
+	// Bindings
+	for _, name := range sortedKeys(policy.Projects) {
+		project := policy.Projects[name]
+		fixBindings(policy, project.Bindings, fmt.Sprintf("Project %s", name), &changes)
+		for _, resource := range sortedKeys(project.Resources) {
+			fixBindings(policy, project.Resources[resource].Bindings, fmt.Sprintf("Project %s resource %s", name, resource), &changes)
+		}
+	}
+	for _, name := range sortedKeys(policy.Folders) {
+		fixBindings(policy, policy.Folders[name].Bindings, fmt.Sprintf("Folder %s", name), &changes)
+	}
+	for _, name := range sortedKeys(policy.Organizations) {
+		fixBindings(policy, policy.Organizations[name].Bindings, fmt.Sprintf("Organization %s", name), &changes)
+	}
+
+	return changes
+}
+
+// fixBindings corrects binding roles and members in place
+func fixBindings(policy *Policy, bindings []Binding, scope string, changes *[]string) {
+	for i := range bindings {
+		binding := &bindings[i]
+		loc := fmt.Sprintf("%s binding %d", scope, i)
+
+		role := strings.TrimSpace(binding.Role)
+		if !strings.HasPrefix(role, "roles/") {
+			// Only prefix when the result names a role that exists
+			if _, defined := policy.Roles["roles/"+role]; defined || IsBuiltinRole("roles/"+role) {
+				role = "roles/" + role
+			}
+		}
+		if role != binding.Role {
+			*changes = append(*changes, fmt.Sprintf("%s: changed role %q to %s", loc, binding.Role, role))
+			binding.Role = role
+		}
+
+		binding.Members = fixList(binding.Members, loc, "member", changes)
+	}
+}
+
+// fixList trims, deduplicates, and sorts a permission or member list,
+// recording a change for each kind of fix applied
+func fixList(values []string, loc, kind string, changes *[]string) []string {
+	if len(values) == 0 {
+		return values
+	}
+
+	fixed := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	trimmed, removed := 0, 0
+
+	for _, v := range values {
+		t := strings.TrimSpace(v)
+		if t != v {
+			trimmed++
+		}
+		if seen[t] {
+			removed++
+			continue
+		}
+		seen[t] = true
+		fixed = append(fixed, t)
+	}
+
+	if trimmed > 0 {
+		*changes = append(*changes, fmt.Sprintf("%s: trimmed whitespace from %d %s(s)", loc, trimmed, kind))
+	}
+	if removed > 0 {
+		*changes = append(*changes, fmt.Sprintf("%s: removed %d duplicate %s(s)", loc, removed, kind))
+	}
+	if !slices.IsSorted(fixed) {
+		slices.Sort(fixed)
+		*changes = append(*changes, fmt.Sprintf("%s: sorted %ss", loc, kind))
+	}
+
+	return fixed
+}
+
+// renameRoleReferences points every binding and includeRoles entry that
+// uses oldName at newName
+func renameRoleReferences(policy *Policy, oldName, newName string) {
+	for _, role := range policy.Roles {
+		for i := range role.IncludeRoles {
+			if role.IncludeRoles[i] == oldName {
+				role.IncludeRoles[i] = newName
+			}
+		}
+	}
+
+	rename := func(bindings []Binding) {
+		for i := range bindings {
+			if bindings[i].Role == oldName {
+				bindings[i].Role = newName
+			}
+		}
+	}
+
+	for _, project := range policy.Projects {
+		rename(project.Bindings)
+		for _, resource := range project.Resources {
+			rename(resource.Bindings)
+		}
+	}
+	for _, folder := range policy.Folders {
+		rename(folder.Bindings)
+	}
+	for _, org := range policy.Organizations {
+		rename(org.Bindings)
+	}
+}
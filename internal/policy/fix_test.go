@@ -0,0 +1,60 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFix(t *testing.T) {
+	pol := &Policy{
+		Roles: map[string]Role{
+			"custom.reader": {Permissions: []string{"secretmanager.versions.access ", "secretmanager.secrets.get", "secretmanager.secrets.get"}},
+		},
+		Groups: map[string]Group{
+			"devs": {Members: []string{"user:b@example.com", "user:a@example.com", "user:a@example.com"}},
+		},
+		Projects: map[string]Project{
+			"test-project": {
+				Bindings: []Binding{
+					{Role: "custom.reader", Members: []string{"group:devs"}},
+					{Role: "secretmanager.viewer", Members: []string{" group:devs"}},
+					{Role: "custom.missing", Members: []string{"group:devs"}},
+				},
+			},
+		},
+	}
+
+	changes := Fix(pol)
+	if len(changes) == 0 {
+		t.Fatal("Expected fixes to be applied")
+	}
+
+	role, ok := pol.Roles["roles/custom.reader"]
+	if !ok {
+		t.Fatalf("Expected role to be renamed, roles = %v", pol.Roles)
+	}
+	if want := []string{"secretmanager.secrets.get", "secretmanager.versions.access"}; !reflect.DeepEqual(role.Permissions, want) {
+		t.Errorf("permissions = %v, want %v", role.Permissions, want)
+	}
+
+	if want := []string{"user:a@example.com", "user:b@example.com"}; !reflect.DeepEqual(pol.Groups["devs"].Members, want) {
+		t.Errorf("group members = %v, want %v", pol.Groups["devs"].Members, want)
+	}
+
+	bindings := pol.Projects["test-project"].Bindings
+	if bindings[0].Role != "roles/custom.reader" {
+		t.Errorf("binding 0 role = %s, want roles/custom.reader", bindings[0].Role)
+	}
+	if bindings[1].Role != "roles/secretmanager.viewer" || bindings[1].Members[0] != "group:devs" {
+		t.Errorf("binding 1 = %+v, want trimmed member and prefixed built-in role", bindings[1])
+	}
+
+	// A prefix that names no known role is left for validation to report
+	if bindings[2].Role != "custom.missing" {
+		t.Errorf("binding 2 role = %s, want it unchanged", bindings[2].Role)
+	}
+
+	if again := Fix(pol); len(again) != 0 {
+		t.Errorf("Expected Fix to be idempotent, got %v", again)
+	}
+}
@@ -0,0 +1,46 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Flatten returns a copy of policy in the form the IAM emulator loads:
+// includes are dropped, each role lists its full permission set with
+// includeRoles resolved, and each group lists its individual principals, with
+// nested groups expanded. Service accounts, folders, organizations, and deny
+// bindings are carried over unchanged.
+func Flatten(policy *Policy) (*Policy, error) {
+	flat, err := clonePolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+	flat.Includes = nil
+
+	for name, role := range flat.Roles {
+		role.Permissions = RolePermissions(policy, name)
+		role.IncludeRoles = nil
+		flat.Roles[name] = role
+	}
+
+	for name := range flat.Groups {
+		flat.Groups[name] = Group{Members: GroupMembers(policy, name)}
+	}
+
+	return flat, nil
+}
+
+// clonePolicy returns a deep copy of policy. Include origins are not copied.
+func clonePolicy(policy *Policy) (*Policy, error) {
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy policy: %w", err)
+	}
+
+	var clone Policy
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return nil, fmt.Errorf("failed to copy policy: %w", err)
+	}
+
+	return &clone, nil
+}
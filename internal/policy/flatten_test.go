@@ -0,0 +1,57 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	pol := &Policy{
+		Includes: []string{"base.yaml"},
+		Roles: map[string]Role{
+			"roles/custom.reader": {Permissions: []string{"secretmanager.secrets.get"}},
+		},
+		Groups: map[string]Group{
+			"platform-team": {Members: []string{"group:sre", "user:lead@example.com"}},
+			"sre":           {Members: []string{"user:alice@example.com"}},
+		},
+		ServiceAccounts: map[string]ServiceAccount{
+			"ci@test-project.iam.gserviceaccount.com": {DisplayName: "CI"},
+		},
+		Projects: map[string]Project{
+			"test-project": {
+				Bindings: []Binding{
+					{Role: "roles/custom.reader", Members: []string{"group:platform-team"}},
+				},
+			},
+		},
+	}
+
+	flat, err := Flatten(pol)
+	if err != nil {
+		t.Fatalf("Flatten() error: %v", err)
+	}
+
+	if flat.Includes != nil {
+		t.Errorf("Expected includes dropped, got %v", flat.Includes)
+	}
+
+	want := []string{"user:alice@example.com", "user:lead@example.com"}
+	if got := flat.Groups["platform-team"].Members; !reflect.DeepEqual(got, want) {
+		t.Errorf("platform-team members = %v, want %v", got, want)
+	}
+
+	if _, ok := flat.ServiceAccounts["ci@test-project.iam.gserviceaccount.com"]; !ok {
+		t.Error("Expected service accounts carried over")
+	}
+
+	// Bindings still reference the group by name
+	if got := flat.Projects["test-project"].Bindings[0].Members; !reflect.DeepEqual(got, []string{"group:platform-team"}) {
+		t.Errorf("binding members = %v", got)
+	}
+
+	// The original is not modified
+	if len(pol.Groups["platform-team"].Members) != 2 || pol.Groups["platform-team"].Members[0] != "group:sre" {
+		t.Errorf("Flatten modified the original policy: %v", pol.Groups["platform-team"].Members)
+	}
+}
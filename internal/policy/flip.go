@@ -0,0 +1,131 @@
+package policy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Flips returns single policy changes that would turn a denied request
+// into an allowed one: exempting the principal from a deny binding,
+// removing a failing condition, adding the permission to a custom role the
+// principal already holds, or adding the principal to a binding that grants
+// the permission. Each candidate is applied to a copy of the policy and kept
+// only if Simulate then allows the request. Returns nil if req is allowed.
+func Flips(policy *Policy, req SimulateRequest) ([]string, error) {
+	decision, err := Simulate(policy, req)
+	if err != nil {
+		return nil, err
+	}
+	if decision.Allowed {
+		return nil, nil
+	}
+	project := decision.Project
+
+	var flips []string
+	try := func(description string, change func(p *Policy)) error {
+		if slices.Contains(flips, description) {
+			return nil
+		}
+		candidate, err := clonePolicy(policy)
+		if err != nil {
+			return err
+		}
+		change(candidate)
+		after, err := Simulate(candidate, req)
+		if err != nil {
+			return err
+		}
+		if after.Allowed {
+			flips = append(flips, description)
+		}
+		return nil
+	}
+
+	// Exempt the principal from a deny binding
+	for _, deny := range decision.Denials {
+		if !deny.Applied {
+			continue
+		}
+		index := deny.Index
+		err := try(fmt.Sprintf("Add %s as an exception to deny binding %d", req.Principal, index), func(p *Policy) {
+			d := &p.Projects[project].DenyBindings[index]
+			d.ExceptionPrincipals = append(d.ExceptionPrincipals, req.Principal)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Remove a condition that failed
+	for _, m := range decision.Matches {
+		if m.Granted {
+			continue
+		}
+		scope, index := m.Scope, m.Index
+		err := try(fmt.Sprintf("Remove the condition %q from %s binding %d", m.Binding.Condition.Expression, scope, index), func(p *Policy) {
+			if b := bindingAt(p, scope, index); b != nil {
+				b.Condition = nil
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Add the permission to a custom role the principal already holds
+	for _, grant := range EffectivePermissions(policy, req.Principal, project) {
+		role := grant.Binding.Role
+		if _, custom := policy.Roles[role]; !custom || slices.Contains(grant.Permissions, req.Permission) {
+			continue
+		}
+		err := try(fmt.Sprintf("Add %s to role %s (bound through %s binding %d)", req.Permission, role, grant.Scope, grant.Index), func(p *Policy) {
+			r := p.Roles[role]
+			r.Permissions = append(r.Permissions, req.Permission)
+			p.Roles[role] = r
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Add the principal to a binding that grants the permission
+	for _, scoped := range EffectiveBindings(policy, project) {
+		if !roleGrants(policy, scoped.Binding.Role, req.Permission) || slices.Contains(scoped.Binding.Members, req.Principal) {
+			continue
+		}
+		scope, index := scoped.Scope, scoped.Index
+		err := try(fmt.Sprintf("Add %s to %s binding %d (%s)", req.Principal, scope, index, scoped.Binding.Role), func(p *Policy) {
+			if b := bindingAt(p, scope, index); b != nil {
+				b.Members = append(b.Members, req.Principal)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return flips, nil
+}
+
+// bindingAt returns the binding at index in a projects/, folders/, or
+// organizations/ scope, or in a projects/<project>/<resource> scope of
+// resource bindings, or nil if there is none. A change that can't find
+// its binding leaves the candidate policy unchanged, so it isn't kept.
+func bindingAt(policy *Policy, scope string, index int) *Binding {
+	var bindings []Binding
+	if project, ok := strings.CutPrefix(scope, "projects/"); ok {
+		if project, resource, ok := strings.Cut(project, "/"); ok {
+			bindings = policy.Projects[project].Resources[resource].Bindings
+		} else {
+			bindings = policy.Projects[project].Bindings
+		}
+	} else {
+		bindings = policy.scopeBindings(scope)
+	}
+
+	if index < 0 || index >= len(bindings) {
+		return nil
+	}
+	return &bindings[index]
+}
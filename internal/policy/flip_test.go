@@ -0,0 +1,121 @@
+package policy
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFlips(t *testing.T) {
+	const ci = "serviceAccount:ci@test-project.iam.gserviceaccount.com"
+
+	tests := []struct {
+		name   string
+		policy *Policy
+		req    SimulateRequest
+		want   []string
+	}{
+		{
+			name:   "allowed request has no flips",
+			policy: simulatePolicy(),
+			req: SimulateRequest{
+				Principal:  "user:alice@example.com",
+				Permission: "secretmanager.secrets.get",
+				Resource:   "projects/test-project/secrets/db-password",
+			},
+		},
+		{
+			name:   "deny exception",
+			policy: denyPolicy(),
+			req: SimulateRequest{
+				Principal:  "user:alice@example.com",
+				Permission: "secretmanager.secrets.create",
+				Resource:   "projects/test-project/secrets/db-password",
+			},
+			want: []string{"Add user:alice@example.com as an exception to deny binding 0"},
+		},
+		{
+			name:   "failing condition",
+			policy: simulatePolicy(),
+			req: SimulateRequest{
+				Principal:  ci,
+				Permission: "secretmanager.secrets.get",
+				Resource:   "projects/test-project/secrets/db-password",
+			},
+			want: []string{
+				`Remove the condition "resource.name.startsWith(\"projects/test-project/secrets/prod-\") && request.time < timestamp(\"2030-01-01T00:00:00Z\")" from projects/test-project binding 1`,
+				"Add " + ci + " to projects/test-project binding 0 (roles/custom.admin)",
+			},
+		},
+		{
+			name:   "missing permission on held role",
+			policy: simulatePolicy(),
+			req: SimulateRequest{
+				Principal:  ci,
+				Permission: "secretmanager.secrets.create",
+				Resource:   "projects/test-project/secrets/prod-db",
+			},
+			want: []string{
+				"Add secretmanager.secrets.create to role roles/custom.ciRunner (bound through projects/test-project binding 1)",
+				"Add " + ci + " to projects/test-project binding 0 (roles/custom.admin)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Flips(tt.policy, tt.req)
+			if err != nil {
+				t.Fatalf("Flips() error: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Flips() =\n  %q\nwant\n  %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlipsResourceBindingCondition(t *testing.T) {
+	pol := resourcePolicy()
+	project := pol.Projects["test-project"]
+	project.Resources["secrets/db-password"] = Resource{Bindings: []Binding{{
+		Role:      "roles/custom.ciRunner",
+		Members:   []string{"user:bob@example.com"},
+		Condition: &Condition{Expression: `request.time < timestamp("2020-01-01T00:00:00Z")`},
+	}}}
+
+	got, err := Flips(pol, SimulateRequest{
+		Principal:  "user:bob@example.com",
+		Permission: "secretmanager.secrets.get",
+		Resource:   "projects/test-project/secrets/db-password",
+	})
+	if err != nil {
+		t.Fatalf("Flips() error: %v", err)
+	}
+
+	want := `Remove the condition "request.time < timestamp(\"2020-01-01T00:00:00Z\")" from projects/test-project/secrets/db-password binding 0`
+	if !slices.Contains(got, want) {
+		t.Errorf("Flips() = %q, want it to include %q", got, want)
+	}
+	if pol.Projects["test-project"].Resources["secrets/db-password"].Bindings[0].Condition == nil {
+		t.Error("Flips modified the resource binding's condition")
+	}
+}
+
+func TestFlipsLeavesPolicyUnchanged(t *testing.T) {
+	pol := simulatePolicy()
+	_, err := Flips(pol, SimulateRequest{
+		Principal:  "serviceAccount:ci@test-project.iam.gserviceaccount.com",
+		Permission: "secretmanager.secrets.create",
+		Resource:   "projects/test-project/secrets/prod-db",
+	})
+	if err != nil {
+		t.Fatalf("Flips() error: %v", err)
+	}
+
+	if perms := pol.Roles["roles/custom.ciRunner"].Permissions; len(perms) != 1 {
+		t.Errorf("Flips modified role permissions: %v", perms)
+	}
+	if members := pol.Projects["test-project"].Bindings[0].Members; len(members) != 1 {
+		t.Errorf("Flips modified binding members: %v", members)
+	}
+}
@@ -0,0 +1,323 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// customRoleIDPattern is GCP's constraint on the ID of a custom role, the
+// last part of projects/<project>/roles/<id> or organizations/<org>/roles/<id>
+var customRoleIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_.]{3,64}$`)
+
+// IAMPolicy is the JSON form of a google.iam.v1.Policy, as used by
+// gcloud projects get-iam-policy / set-iam-policy
+type IAMPolicy struct {
+	Version  int          `json:"version,omitempty"`
+	Bindings []IAMBinding `json:"bindings"`
+	Etag     string       `json:"etag,omitempty"`
+}
+
+// IAMBinding is a google.iam.v1.Binding
+type IAMBinding struct {
+	Role      string     `json:"role"`
+	Members   []string   `json:"members"`
+	Condition *Condition `json:"condition,omitempty"`
+}
+
+// GCPRole is a custom role definition in the YAML format accepted by
+// gcloud iam roles create --file
+type GCPRole struct {
+	Title               string   `yaml:"title"`
+	Description         string   `yaml:"description,omitempty"`
+	Stage               string   `yaml:"stage"`
+	IncludedPermissions []string `yaml:"includedPermissions"`
+}
+
+// ExportOptions controls conversion to GCP IAM policy format
+type ExportOptions struct {
+	// ExpandGroups replaces group:NAME members with the group's principals.
+	// Local group names are not Google group emails, so unexpanded groups
+	// must be edited before the policy is applied to real GCP.
+	ExpandGroups bool
+
+	// Effective includes bindings inherited from the project's folders and
+	// organization, producing the project's effective policy
+	Effective bool
+}
+
+// ExportGCP converts a project's bindings to a google.iam.v1.Policy,
+// optionally including inherited bindings (see ExportOptions.Effective).
+// Custom roles defined in the policy are referenced as
+// projects/<project>/roles/<id>. Warnings describe members that need
+// attention before applying the result to real GCP.
+func ExportGCP(policy *Policy, project string, opts ExportOptions) (*IAMPolicy, []string, error) {
+	proj, ok := policy.Projects[project]
+	if !ok {
+		return nil, nil, fmt.Errorf("project %s is not defined in the policy", project)
+	}
+
+	bindings := proj.Bindings
+	if opts.Effective {
+		bindings = nil
+		for _, scoped := range EffectiveBindings(policy, project) {
+			bindings = append(bindings, scoped.Binding)
+		}
+	}
+
+	return exportBindings(policy, project, bindings, opts)
+}
+
+// ExportResourceGCP converts the bindings set on one of the project's
+// resources, such as secrets/db-password, to a google.iam.v1.Policy for
+// that resource's set-iam-policy command. opts.Effective does not apply.
+func ExportResourceGCP(policy *Policy, project, resource string, opts ExportOptions) (*IAMPolicy, []string, error) {
+	res, ok := policy.Projects[project].Resources[resource]
+	if !ok {
+		return nil, nil, fmt.Errorf("resource %s is not defined in project %s", resource, project)
+	}
+
+	return exportBindings(policy, project, res.Bindings, opts)
+}
+
+// exportBindings converts bindings to a google.iam.v1.Policy. It fails if
+// a binding uses a custom role whose name GCP would not accept.
+func exportBindings(policy *Policy, project string, bindings []Binding, opts ExportOptions) (*IAMPolicy, []string, error) {
+	out := &IAMPolicy{Version: 1, Bindings: []IAMBinding{}}
+	var warnings []string
+	warned := make(map[string]bool)
+
+	for _, binding := range bindings {
+		members := binding.Members
+		if opts.ExpandGroups {
+			members = sortedKeys(ExpandMembers(policy, binding.Members))
+		} else {
+			for _, member := range members {
+				name, isGroup := strings.CutPrefix(member, "group:")
+				if isGroup && !strings.Contains(name, "@") && !warned[member] {
+					warned[member] = true
+					warnings = append(warnings, fmt.Sprintf("%s is a local group name; replace it with a Google group email or use --expand-groups", member))
+				}
+			}
+		}
+
+		if err := checkExportable(policy, binding.Role); err != nil {
+			return nil, nil, err
+		}
+
+		exported := IAMBinding{
+			Role:    gcpRoleName(policy, project, binding.Role),
+			Members: append([]string{}, members...),
+		}
+		if binding.Condition != nil {
+			condition := *binding.Condition
+			exported.Condition = &condition
+			out.Version = 3
+		}
+
+		out.Bindings = append(out.Bindings, exported)
+	}
+
+	return out, warnings, nil
+}
+
+// ExportRole converts a custom role to a gcloud role definition.
+// It returns the GCP role ID along with the definition.
+func ExportRole(policy *Policy, name string) (string, *GCPRole, error) {
+	if _, ok := policy.Roles[name]; !ok {
+		return "", nil, fmt.Errorf("role %s is not defined in the policy", name)
+	}
+
+	if err := checkExportable(policy, name); err != nil {
+		return "", nil, err
+	}
+
+	// GCP roles cannot include other roles, so export the resolved set
+	id := strings.TrimPrefix(name, "roles/")
+	perms := RolePermissions(policy, name)
+
+	description := policy.Roles[name].Description
+	if description == "" {
+		description = "Exported from gcp-emulator policy"
+	}
+
+	return id, &GCPRole{
+		Title:               id,
+		Description:         description,
+		Stage:               "GA",
+		IncludedPermissions: perms,
+	}, nil
+}
+
+// CustomRoleID returns the ID a custom role is exported under in GCP, the
+// name without its roles/ prefix. It fails if GCP would not accept the ID:
+// custom role IDs are 3 to 64 letters, digits, underscores, and periods.
+func CustomRoleID(name string) (string, error) {
+	id, ok := strings.CutPrefix(name, "roles/")
+	if !ok {
+		return "", fmt.Errorf("role name must start with roles/")
+	}
+	if !customRoleIDPattern.MatchString(id) {
+		return "", fmt.Errorf("role ID %q must be 3 to 64 letters, digits, underscores, or periods", id)
+	}
+	return id, nil
+}
+
+// SanitizeRoleID derives a valid GCP custom role ID from id: other
+// characters become underscores, and the result is padded or truncated to
+// GCP's length limits
+func SanitizeRoleID(id string) string {
+	sanitized := []rune(strings.Map(func(r rune) rune {
+		if isRoleIDChar(r) {
+			return r
+		}
+		return '_'
+	}, id))
+
+	for len(sanitized) < 3 {
+		sanitized = append(sanitized, '_')
+	}
+	if len(sanitized) > 64 {
+		sanitized = sanitized[:64]
+	}
+	return string(sanitized)
+}
+
+// isRoleIDChar reports whether r may appear in a GCP custom role ID
+func isRoleIDChar(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.'
+}
+
+// SuggestRoleName returns a name for a custom role that can be exported to
+// GCP, for a role whose name cannot be
+func SuggestRoleName(role string) string {
+	id := strings.TrimPrefix(role, "roles/")
+	if IsBuiltinRole(role) {
+		id = "custom." + id
+	}
+	return "roles/" + SanitizeRoleID(id)
+}
+
+// checkExportable reports an error if role is a custom role whose ID GCP
+// would not accept, suggesting a name that it would
+func checkExportable(policy *Policy, role string) error {
+	if _, custom := policy.Roles[role]; !custom || IsBuiltinRole(role) {
+		return nil
+	}
+	if _, err := CustomRoleID(role); err != nil {
+		return fmt.Errorf("role %s cannot be exported to GCP: %v (rename it to %s)", role, err, SuggestRoleName(role))
+	}
+	return nil
+}
+
+// gcpRoleName maps a policy role to the name a real GCP binding uses.
+// Custom roles live under the project; built-in roles are unchanged.
+func gcpRoleName(policy *Policy, project, role string) string {
+	if _, custom := policy.Roles[role]; custom && !IsBuiltinRole(role) {
+		return fmt.Sprintf("projects/%s/roles/%s", project, strings.TrimPrefix(role, "roles/"))
+	}
+	return role
+}
+
+// LoadIAMPolicy reads a google.iam.v1.Policy JSON document, such as the
+// output of gcloud projects get-iam-policy --format json
+func LoadIAMPolicy(path string) (*IAMPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IAM policy: %w", err)
+	}
+
+	var iamPolicy IAMPolicy
+	if err := json.Unmarshal(data, &iamPolicy); err != nil {
+		return nil, fmt.Errorf("failed to parse IAM policy JSON: %w", err)
+	}
+
+	return &iamPolicy, nil
+}
+
+// ImportResult summarizes the changes made by ImportGCP
+type ImportResult struct {
+	BindingsAdded []Binding `json:"bindingsAdded,omitempty"`
+	MembersAdded  []string  `json:"membersAdded,omitempty"`
+	GroupsCreated []string  `json:"groupsCreated,omitempty"`
+	Warnings      []string  `json:"warnings,omitempty"`
+}
+
+// ImportGCP merges the bindings of a GCP IAM policy into the project,
+// modifying policy in place. Bindings matching an existing role and
+// condition gain any missing members; others are appended. Group members
+// get empty stub groups so the policy validates. Roles that are neither
+// built-in nor defined in the policy produce warnings.
+func ImportGCP(policy *Policy, project string, iamPolicy *IAMPolicy) *ImportResult {
+	result := &ImportResult{}
+
+	if policy.Groups == nil {
+		policy.Groups = make(map[string]Group)
+	}
+	if policy.Projects == nil {
+		policy.Projects = make(map[string]Project)
+	}
+
+	proj := policy.Projects[project]
+	warnedRoles := make(map[string]bool)
+
+	for _, iamBinding := range iamPolicy.Bindings {
+		binding := Binding{
+			Role:      localRoleName(project, iamBinding.Role),
+			Members:   append([]string{}, iamBinding.Members...),
+			Condition: iamBinding.Condition,
+		}
+
+		if _, defined := policy.Roles[binding.Role]; !defined && !IsBuiltinRole(binding.Role) && !warnedRoles[binding.Role] {
+			warnedRoles[binding.Role] = true
+			result.Warnings = append(result.Warnings, fmt.Sprintf("role %s is not built in or defined in the policy; add it to roles: before using strict mode", binding.Role))
+		}
+
+		for _, member := range binding.Members {
+			name, isGroup := strings.CutPrefix(member, "group:")
+			if _, exists := policy.Groups[name]; isGroup && !exists {
+				policy.Groups[name] = Group{Members: []string{}}
+				result.GroupsCreated = append(result.GroupsCreated, name)
+			}
+		}
+
+		existing := -1
+		for i, b := range proj.Bindings {
+			if bindingKey(b) == bindingKey(binding) {
+				existing = i
+				break
+			}
+		}
+
+		if existing < 0 {
+			proj.Bindings = append(proj.Bindings, binding)
+			result.BindingsAdded = append(result.BindingsAdded, binding)
+			continue
+		}
+
+		added, _ := diffStrings(proj.Bindings[existing].Members, binding.Members)
+		for _, member := range added {
+			proj.Bindings[existing].Members = append(proj.Bindings[existing].Members, member)
+			result.MembersAdded = append(result.MembersAdded, fmt.Sprintf("%s → %s", member, binding.Role))
+		}
+	}
+
+	policy.Projects[project] = proj
+	return result
+}
+
+// localRoleName maps a GCP role to its policy name: the inverse of gcpRoleName.
+// projects/<project>/roles/<id> and organizations/<org>/roles/<id> become roles/<id>.
+func localRoleName(project, role string) string {
+	if id, ok := strings.CutPrefix(role, "projects/"+project+"/roles/"); ok {
+		return "roles/" + id
+	}
+	if strings.HasPrefix(role, "organizations/") {
+		if i := strings.Index(role, "/roles/"); i >= 0 {
+			return "roles/" + role[i+len("/roles/"):]
+		}
+	}
+	return role
+}
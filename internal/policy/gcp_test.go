@@ -0,0 +1,183 @@
+package policy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportGCP(t *testing.T) {
+	pol := simulatePolicy()
+	pol.Projects["test-project"] = Project{
+		Bindings: append(pol.Projects["test-project"].Bindings, Binding{
+			Role:    "roles/viewer",
+			Members: []string{"user:auditor@example.com"},
+		}),
+	}
+
+	exported, warnings, err := ExportGCP(pol, "test-project", ExportOptions{})
+	if err != nil {
+		t.Fatalf("ExportGCP() error = %v", err)
+	}
+
+	if exported.Version != 3 {
+		t.Errorf("Version = %d, want 3 for conditional bindings", exported.Version)
+	}
+	if len(exported.Bindings) != 3 {
+		t.Fatalf("Expected 3 bindings, got %d", len(exported.Bindings))
+	}
+	if exported.Bindings[0].Role != "projects/test-project/roles/custom.admin" {
+		t.Errorf("custom role = %s, want projects/test-project/roles/custom.admin", exported.Bindings[0].Role)
+	}
+	if exported.Bindings[2].Role != "roles/viewer" {
+		t.Errorf("built-in role = %s, want roles/viewer", exported.Bindings[2].Role)
+	}
+	if exported.Bindings[1].Condition == nil || exported.Bindings[1].Condition.Title != "CI limited to production secrets" {
+		t.Errorf("condition not exported: %+v", exported.Bindings[1].Condition)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("Expected 1 local group warning, got %v", warnings)
+	}
+
+	expanded, warnings, err := ExportGCP(pol, "test-project", ExportOptions{ExpandGroups: true})
+	if err != nil {
+		t.Fatalf("ExportGCP() error = %v", err)
+	}
+	if !reflect.DeepEqual(expanded.Bindings[0].Members, []string{"user:alice@example.com"}) {
+		t.Errorf("expanded members = %v", expanded.Bindings[0].Members)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Expected no warnings with expanded groups, got %v", warnings)
+	}
+
+	if _, _, err := ExportGCP(pol, "missing", ExportOptions{}); err == nil {
+		t.Error("Expected error for undefined project")
+	}
+}
+
+func TestExportRole(t *testing.T) {
+	id, role, err := ExportRole(simulatePolicy(), "roles/custom.admin")
+	if err != nil {
+		t.Fatalf("ExportRole() error = %v", err)
+	}
+	if id != "custom.admin" {
+		t.Errorf("id = %s, want custom.admin", id)
+	}
+	if !reflect.DeepEqual(role.IncludedPermissions, []string{"secretmanager.secrets.create", "secretmanager.secrets.get"}) {
+		t.Errorf("IncludedPermissions = %v", role.IncludedPermissions)
+	}
+}
+
+func TestExportNonCompliantRole(t *testing.T) {
+	pol := simulatePolicy()
+	pol.Roles["roles/custom.ci-runner"] = Role{Permissions: []string{"secretmanager.secrets.get"}}
+	pol.Projects["test-project"] = Project{
+		Bindings: []Binding{{Role: "roles/custom.ci-runner", Members: []string{"user:ci@example.com"}}},
+	}
+
+	_, _, err := ExportGCP(pol, "test-project", ExportOptions{})
+	if err == nil || !strings.Contains(err.Error(), "rename it to roles/custom.ci_runner") {
+		t.Errorf("Expected export to refuse with a suggested name, got %v", err)
+	}
+	if _, _, err := ExportRole(pol, "roles/custom.ci-runner"); err == nil {
+		t.Error("Expected ExportRole to refuse a non-compliant ID")
+	}
+}
+
+func TestCustomRoleID(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "roles/custom.admin", want: "custom.admin"},
+		{name: "roles/ci_runner2", want: "ci_runner2"},
+		{name: "roles/ci-runner", wantErr: true},
+		{name: "roles/ab", wantErr: true},
+		{name: "roles/" + strings.Repeat("a", 65), wantErr: true},
+		{name: "roles/projects/p/roles/x", wantErr: true},
+		{name: "custom.admin", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := CustomRoleID(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("CustomRoleID(%q) = %q, %v", tt.name, got, err)
+		}
+	}
+
+	for id, want := range map[string]string{
+		"ci-runner":             "ci_runner",
+		"x":                     "x__",
+		"team/app":              "team_app",
+		strings.Repeat("a", 70): strings.Repeat("a", 64),
+	} {
+		if got := SanitizeRoleID(id); got != want {
+			t.Errorf("SanitizeRoleID(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestValidateGCPCompat(t *testing.T) {
+	pol := &Policy{
+		Roles: map[string]Role{
+			"roles/custom.ci-runner": {Permissions: []string{"secretmanager.secrets.get"}},
+			"roles/viewer":           {Permissions: []string{"secretmanager.secrets.get"}},
+			"roles/custom.admin":     {Permissions: []string{"secretmanager.secrets.get"}},
+		},
+	}
+
+	if result := Validate(pol); !result.Valid {
+		t.Fatalf("Names should only be checked with GCPCompat, got %v", result.Errors)
+	}
+
+	result := ValidateWithOptions(pol, ValidateOptions{GCPCompat: true})
+	if !hasError(result, "Role roles/custom.ci-runner cannot be exported to GCP") || !hasError(result, "rename it to roles/custom.ci_runner") {
+		t.Errorf("Expected invalid ID error, got %v", result.Errors)
+	}
+	if !hasError(result, "Role roles/viewer is a predefined GCP role") || !hasError(result, "rename it to roles/custom.viewer") {
+		t.Errorf("Expected predefined role error, got %v", result.Errors)
+	}
+	if hasError(result, "roles/custom.admin") {
+		t.Errorf("Compliant role should not be flagged: %v", result.Errors)
+	}
+}
+
+func TestImportGCP(t *testing.T) {
+	pol := simulatePolicy()
+
+	iamPolicy := &IAMPolicy{
+		Bindings: []IAMBinding{
+			{Role: "projects/test-project/roles/custom.admin", Members: []string{"group:admins", "user:new@example.com"}},
+			{Role: "roles/storage.admin", Members: []string{"group:eng@example.com"}},
+		},
+	}
+
+	result := ImportGCP(pol, "test-project", iamPolicy)
+
+	if !reflect.DeepEqual(result.MembersAdded, []string{"user:new@example.com → roles/custom.admin"}) {
+		t.Errorf("MembersAdded = %v", result.MembersAdded)
+	}
+	if len(result.BindingsAdded) != 1 || result.BindingsAdded[0].Role != "roles/storage.admin" {
+		t.Errorf("BindingsAdded = %+v", result.BindingsAdded)
+	}
+	if !reflect.DeepEqual(result.GroupsCreated, []string{"eng@example.com"}) {
+		t.Errorf("GroupsCreated = %v", result.GroupsCreated)
+	}
+	if len(result.Warnings) != 1 {
+		t.Errorf("Expected a warning for roles/storage.admin, got %v", result.Warnings)
+	}
+
+	bindings := pol.Projects["test-project"].Bindings
+	if len(bindings) != 3 {
+		t.Fatalf("Expected 3 bindings after import, got %d", len(bindings))
+	}
+	if !reflect.DeepEqual(bindings[0].Members, []string{"group:admins", "user:new@example.com"}) {
+		t.Errorf("merged members = %v", bindings[0].Members)
+	}
+
+	// Importing again is a no-op
+	again := ImportGCP(pol, "test-project", iamPolicy)
+	if len(again.BindingsAdded) != 0 || len(again.MembersAdded) != 0 || len(again.GroupsCreated) != 0 {
+		t.Errorf("Expected re-import to be a no-op, got %+v", again)
+	}
+}
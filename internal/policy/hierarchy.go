@@ -0,0 +1,160 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ScopedBinding is a binding together with the hierarchy node it is attached to
+type ScopedBinding struct {
+	// Scope is projects/<id>, folders/<name>, or organizations/<name>
+	Scope   string  `json:"scope"`
+	Index   int     `json:"index"`
+	Binding Binding `json:"binding"`
+}
+
+// Inherited reports whether the binding is attached to a folder or
+// organization rather than the project itself
+func (b ScopedBinding) Inherited() bool {
+	return !strings.HasPrefix(b.Scope, "projects/")
+}
+
+// EffectiveBindings returns the project's own bindings followed by those
+// inherited from its folders and organization, nearest ancestor first.
+// Dangling parents and cycles end the walk; Validate reports them.
+func EffectiveBindings(policy *Policy, project string) []ScopedBinding {
+	proj, ok := policy.Projects[project]
+	if !ok {
+		return nil
+	}
+
+	var bindings []ScopedBinding
+	for i, binding := range proj.Bindings {
+		bindings = append(bindings, ScopedBinding{Scope: "projects/" + project, Index: i, Binding: binding})
+	}
+
+	for _, scope := range Ancestors(policy, proj.Parent) {
+		for i, binding := range policy.scopeBindings(scope) {
+			bindings = append(bindings, ScopedBinding{Scope: scope, Index: i, Binding: binding})
+		}
+	}
+
+	return bindings
+}
+
+// Ancestors returns parent followed by its own ancestors, nearest first.
+// The walk stops at an organization, an undefined node, or a repeat.
+func Ancestors(policy *Policy, parent string) []string {
+	var chain []string
+	seen := make(map[string]bool)
+
+	for parent != "" && !seen[parent] {
+		kind, name, err := parseParent(parent)
+		if err != nil {
+			break
+		}
+		seen[parent] = true
+
+		switch kind {
+		case "organizations":
+			if _, ok := policy.Organizations[name]; !ok {
+				return chain
+			}
+			return append(chain, parent)
+		case "folders":
+			folder, ok := policy.Folders[name]
+			if !ok {
+				return chain
+			}
+			chain = append(chain, parent)
+			parent = folder.Parent
+		}
+	}
+
+	return chain
+}
+
+// HierarchyCycles returns each folder parent cycle once, as a list of
+// folders/<name> scopes ending with the first repeated
+func HierarchyCycles(policy *Policy) [][]string {
+	var cycles [][]string
+	reported := make(map[string]bool)
+
+	for _, start := range sortedKeys(policy.Folders) {
+		var path []string
+		index := make(map[string]int)
+
+		name := start
+		for {
+			if i, onPath := index[name]; onPath {
+				cycle := canonicalCycle(path[i:])
+				key := strings.Join(cycle, "\x00")
+				if !reported[key] {
+					reported[key] = true
+					scoped := make([]string, len(cycle))
+					for j, folder := range cycle {
+						scoped[j] = "folders/" + folder
+					}
+					cycles = append(cycles, scoped)
+				}
+				break
+			}
+
+			folder, ok := policy.Folders[name]
+			if !ok {
+				break
+			}
+			index[name] = len(path)
+			path = append(path, name)
+
+			next, isFolder := strings.CutPrefix(folder.Parent, "folders/")
+			if !isFolder {
+				break
+			}
+			name = next
+		}
+	}
+
+	return cycles
+}
+
+// parseParent splits a parent reference into its collection and name
+func parseParent(parent string) (string, string, error) {
+	kind, name, ok := strings.Cut(parent, "/")
+	if !ok || name == "" || strings.Contains(name, "/") || (kind != "folders" && kind != "organizations") {
+		return "", "", fmt.Errorf("invalid parent %q (expected folders/<name> or organizations/<name>)", parent)
+	}
+	return kind, name, nil
+}
+
+// checkParent verifies that a parent reference names a defined node
+func checkParent(policy *Policy, parent string) error {
+	kind, name, err := parseParent(parent)
+	if err != nil {
+		return err
+	}
+
+	exists := false
+	switch kind {
+	case "folders":
+		_, exists = policy.Folders[name]
+	case "organizations":
+		_, exists = policy.Organizations[name]
+	}
+	if !exists {
+		return fmt.Errorf("parent %s is not defined", parent)
+	}
+	return nil
+}
+
+// scopeBindings returns the bindings attached to a folder or organization scope
+func (p *Policy) scopeBindings(scope string) []Binding {
+	kind, name, err := parseParent(scope)
+	if err != nil {
+		return nil
+	}
+	if kind == "folders" {
+		return p.Folders[name].Bindings
+	}
+	return p.Organizations[name].Bindings
+}
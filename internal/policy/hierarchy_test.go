@@ -0,0 +1,182 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func hierarchyPolicy() *Policy {
+	return &Policy{
+		Roles: map[string]Role{
+			"roles/custom.reader": {Permissions: []string{"secretmanager.secrets.get"}},
+		},
+		Organizations: map[string]Organization{
+			"acme": {
+				Bindings: []Binding{
+					{Role: "roles/custom.reader", Members: []string{"user:auditor@example.com"}},
+				},
+			},
+		},
+		Folders: map[string]Folder{
+			"engineering": {Parent: "organizations/acme"},
+			"platform": {
+				Parent: "folders/engineering",
+				Bindings: []Binding{
+					{Role: "roles/custom.reader", Members: []string{"user:alice@example.com"}},
+				},
+			},
+		},
+		Projects: map[string]Project{
+			"test-project": {
+				Parent: "folders/platform",
+				Bindings: []Binding{
+					{Role: "roles/custom.reader", Members: []string{"user:bob@example.com"}},
+				},
+			},
+		},
+	}
+}
+
+func TestEffectiveBindings(t *testing.T) {
+	bindings := EffectiveBindings(hierarchyPolicy(), "test-project")
+
+	var scopes []string
+	for _, b := range bindings {
+		scopes = append(scopes, b.Scope)
+	}
+	want := []string{"projects/test-project", "folders/platform", "organizations/acme"}
+	if !reflect.DeepEqual(scopes, want) {
+		t.Errorf("scopes = %v, want %v", scopes, want)
+	}
+
+	if bindings[0].Inherited() || !bindings[1].Inherited() {
+		t.Error("Expected only folder and organization bindings to be inherited")
+	}
+}
+
+func TestSimulateInheritedBinding(t *testing.T) {
+	tests := []struct {
+		principal string
+		wantScope string
+	}{
+		{"user:bob@example.com", "projects/test-project"},
+		{"user:alice@example.com", "folders/platform"},
+		{"user:auditor@example.com", "organizations/acme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.principal, func(t *testing.T) {
+			decision, err := Simulate(hierarchyPolicy(), SimulateRequest{
+				Principal:  tt.principal,
+				Permission: "secretmanager.secrets.get",
+				Resource:   "projects/test-project/secrets/db-password",
+			})
+			if err != nil {
+				t.Fatalf("Simulate() error = %v", err)
+			}
+			if !decision.Allowed {
+				t.Fatal("Expected access to be allowed")
+			}
+			if got := decision.Matches[0].Scope; got != tt.wantScope {
+				t.Errorf("Scope = %s, want %s", got, tt.wantScope)
+			}
+		})
+	}
+}
+
+func TestExportGCPEffective(t *testing.T) {
+	pol := hierarchyPolicy()
+
+	own, _, err := ExportGCP(pol, "test-project", ExportOptions{})
+	if err != nil {
+		t.Fatalf("ExportGCP() error = %v", err)
+	}
+	if len(own.Bindings) != 1 {
+		t.Errorf("Expected 1 project binding, got %d", len(own.Bindings))
+	}
+
+	effective, _, err := ExportGCP(pol, "test-project", ExportOptions{Effective: true})
+	if err != nil {
+		t.Fatalf("ExportGCP() error = %v", err)
+	}
+	if len(effective.Bindings) != 3 {
+		t.Errorf("Expected 3 effective bindings, got %d", len(effective.Bindings))
+	}
+}
+
+func TestValidateHierarchy(t *testing.T) {
+	t.Run("valid hierarchy", func(t *testing.T) {
+		result := Validate(hierarchyPolicy())
+		if !result.Valid {
+			t.Errorf("Expected valid policy, got errors: %v", result.Errors)
+		}
+	})
+
+	t.Run("dangling parent", func(t *testing.T) {
+		pol := hierarchyPolicy()
+		project := pol.Projects["test-project"]
+		project.Parent = "folders/missing"
+		pol.Projects["test-project"] = project
+
+		result := Validate(pol)
+		if !hasError(result, "Project test-project: parent folders/missing is not defined") {
+			t.Errorf("Expected dangling parent error, got: %v", result.Errors)
+		}
+	})
+
+	t.Run("malformed parent", func(t *testing.T) {
+		pol := hierarchyPolicy()
+		pol.Folders["engineering"] = Folder{Parent: "acme"}
+
+		result := Validate(pol)
+		if !hasError(result, `Folder engineering: invalid parent "acme"`) {
+			t.Errorf("Expected malformed parent error, got: %v", result.Errors)
+		}
+	})
+
+	t.Run("folder cycle", func(t *testing.T) {
+		pol := hierarchyPolicy()
+		pol.Folders["engineering"] = Folder{Parent: "folders/platform"}
+
+		result := Validate(pol)
+		if !hasError(result, "Folder hierarchy cycle: folders/engineering -> folders/platform -> folders/engineering") {
+			t.Errorf("Expected cycle error, got: %v", result.Errors)
+		}
+
+		// Resolution must still terminate
+		if got := len(EffectiveBindings(pol, "test-project")); got != 2 {
+			t.Errorf("Expected 2 bindings from cyclic hierarchy, got %d", got)
+		}
+	})
+
+	t.Run("folder binding checks", func(t *testing.T) {
+		pol := hierarchyPolicy()
+		pol.Folders["platform"] = Folder{
+			Parent:   "folders/engineering",
+			Bindings: []Binding{{Role: "roles/custom.missing", Members: []string{"user:alice@example.com"}}},
+		}
+
+		result := Validate(pol)
+		if !hasError(result, "Folder platform binding 0: undefined role roles/custom.missing") {
+			t.Errorf("Expected undefined role error, got: %v", result.Errors)
+		}
+	})
+}
+
+func TestMergeConflictingParents(t *testing.T) {
+	base := &Policy{Projects: map[string]Project{"p": {Parent: "folders/a"}}}
+	other := &Policy{Projects: map[string]Project{"p": {Parent: "folders/b"}}}
+
+	if _, err := Merge(base, other); err == nil {
+		t.Error("Expected error for conflicting project parents")
+	}
+
+	other.Projects["p"] = Project{Bindings: []Binding{{Role: "roles/viewer", Members: []string{"user:a@example.com"}}}}
+	merged, err := Merge(base, other)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if merged.Projects["p"].Parent != "folders/a" {
+		t.Errorf("Parent = %s, want folders/a", merged.Projects["p"].Parent)
+	}
+}
@@ -0,0 +1,320 @@
+package policy
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// origins maps policy definitions to the file they were loaded from
+type origins struct {
+	roles           map[string]string
+	groups          map[string]string
+	serviceAccounts map[string]string
+	projects        map[string][]bindingOrigin
+}
+
+// bindingOrigin is the source file and file-local index of a merged binding
+type bindingOrigin struct {
+	file  string
+	index int
+}
+
+// Merge combines two policies. Roles, groups, and service accounts must not
+// be defined in both; project, folder, and organization bindings and project
+// deny bindings from other are appended after those in base, and parents
+// must agree where both set one. Neither input is modified.
+func Merge(base, other *Policy) (*Policy, error) {
+	merged := &Policy{
+		Version:  base.Version,
+		Roles:    make(map[string]Role),
+		Groups:   make(map[string]Group),
+		Projects: make(map[string]Project),
+
+		AllowUnknownPermissions: base.AllowUnknownPermissions || other.AllowUnknownPermissions,
+	}
+	if other.Version > merged.Version {
+		merged.Version = other.Version
+	}
+
+	for name, role := range base.Roles {
+		merged.Roles[name] = role
+	}
+	for _, name := range sortedKeys(other.Roles) {
+		if _, exists := merged.Roles[name]; exists {
+			return nil, fmt.Errorf("role %s is defined more than once", name)
+		}
+		merged.Roles[name] = other.Roles[name]
+	}
+
+	for name, group := range base.Groups {
+		merged.Groups[name] = group
+	}
+	for _, name := range sortedKeys(other.Groups) {
+		if _, exists := merged.Groups[name]; exists {
+			return nil, fmt.Errorf("group %s is defined more than once", name)
+		}
+		merged.Groups[name] = other.Groups[name]
+	}
+
+	if len(base.ServiceAccounts) > 0 || len(other.ServiceAccounts) > 0 {
+		merged.ServiceAccounts = make(map[string]ServiceAccount)
+	}
+	for email, sa := range base.ServiceAccounts {
+		merged.ServiceAccounts[email] = sa
+	}
+	for _, email := range sortedKeys(other.ServiceAccounts) {
+		if _, exists := merged.ServiceAccounts[email]; exists {
+			return nil, fmt.Errorf("service account %s is defined more than once", email)
+		}
+		merged.ServiceAccounts[email] = other.ServiceAccounts[email]
+	}
+
+	for name, project := range base.Projects {
+		merged.Projects[name] = Project{
+			Parent:       project.Parent,
+			Bindings:     append([]Binding{}, project.Bindings...),
+			DenyBindings: append([]DenyBinding(nil), project.DenyBindings...),
+			Resources:    mergeResources(nil, project.Resources),
+		}
+	}
+	for _, name := range sortedKeys(other.Projects) {
+		project := merged.Projects[name]
+		parent, err := mergeParent("project", name, project.Parent, other.Projects[name].Parent)
+		if err != nil {
+			return nil, err
+		}
+		project.Parent = parent
+		project.Bindings = append(project.Bindings, other.Projects[name].Bindings...)
+		project.DenyBindings = append(project.DenyBindings, other.Projects[name].DenyBindings...)
+		project.Resources = mergeResources(project.Resources, other.Projects[name].Resources)
+		merged.Projects[name] = project
+	}
+
+	if len(base.Folders) > 0 || len(other.Folders) > 0 {
+		merged.Folders = make(map[string]Folder)
+	}
+	for name, folder := range base.Folders {
+		merged.Folders[name] = Folder{
+			Parent:   folder.Parent,
+			Bindings: append([]Binding{}, folder.Bindings...),
+		}
+	}
+	for _, name := range sortedKeys(other.Folders) {
+		folder := merged.Folders[name]
+		parent, err := mergeParent("folder", name, folder.Parent, other.Folders[name].Parent)
+		if err != nil {
+			return nil, err
+		}
+		folder.Parent = parent
+		folder.Bindings = append(folder.Bindings, other.Folders[name].Bindings...)
+		merged.Folders[name] = folder
+	}
+
+	if len(base.Organizations) > 0 || len(other.Organizations) > 0 {
+		merged.Organizations = make(map[string]Organization)
+	}
+	for name, org := range base.Organizations {
+		merged.Organizations[name] = Organization{
+			Bindings: append([]Binding{}, org.Bindings...),
+		}
+	}
+	for _, name := range sortedKeys(other.Organizations) {
+		org := merged.Organizations[name]
+		org.Bindings = append(org.Bindings, other.Organizations[name].Bindings...)
+		merged.Organizations[name] = org
+	}
+
+	return merged, nil
+}
+
+// mergeResources returns base with other's resource bindings appended.
+// base is modified; nil is returned when both are empty.
+func mergeResources(base, other map[string]Resource) map[string]Resource {
+	if len(other) == 0 {
+		return base
+	}
+	if base == nil {
+		base = make(map[string]Resource)
+	}
+	for _, name := range sortedKeys(other) {
+		resource := base[name]
+		resource.Bindings = append(append([]Binding{}, resource.Bindings...), other[name].Bindings...)
+		base[name] = resource
+	}
+	return base
+}
+
+// mergeParent reconciles the parent of a node declared in two files
+func mergeParent(kind, name, base, other string) (string, error) {
+	switch {
+	case other == "" || other == base:
+		return base, nil
+	case base == "":
+		return other, nil
+	default:
+		return "", fmt.Errorf("%s %s has conflicting parents %s and %s", kind, name, base, other)
+	}
+}
+
+// resolveIncludes merges a root policy with its includes, depth first.
+// Included files are merged in listed order before the including file's
+// own definitions. A file reached twice is merged once; a file that
+// includes itself (directly or indirectly) is an error.
+func resolveIncludes(path string, root *Policy, opts LoadOptions) (*Policy, error) {
+	merged := &Policy{Version: CurrentVersion}
+	merged.origins = &origins{
+		roles:           make(map[string]string),
+		groups:          make(map[string]string),
+		serviceAccounts: make(map[string]string),
+		projects:        make(map[string][]bindingOrigin),
+	}
+
+	loaded := make(map[string]bool)
+	var stack []string
+
+	var visit func(path string, policy *Policy) error
+	visit = func(path string, policy *Policy) error {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+
+		for i, entry := range stack {
+			if entry == abs {
+				cycle := append(append([]string{}, stack[i:]...), abs)
+				return fmt.Errorf("include cycle: %s", strings.Join(relPaths(cycle), " -> "))
+			}
+		}
+		if loaded[abs] {
+			return nil
+		}
+
+		stack = append(stack, abs)
+		defer func() { stack = stack[:len(stack)-1] }()
+
+		if policy == nil {
+			policy, err = opts.LoadFile(path)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+
+		for _, include := range policy.Includes {
+			includePath := include
+			if !filepath.IsAbs(includePath) {
+				includePath = filepath.Join(filepath.Dir(path), include)
+			}
+			if err := visit(includePath, nil); err != nil {
+				return err
+			}
+		}
+
+		loaded[abs] = true
+		return merged.mergeFrom(path, policy)
+	}
+
+	if err := visit(path, root); err != nil {
+		return nil, err
+	}
+
+	return merged, nil
+}
+
+// mergeFrom merges a single file's definitions into p, recording origins
+func (p *Policy) mergeFrom(file string, other *Policy) error {
+	for _, name := range sortedKeys(other.Roles) {
+		if prev, exists := p.origins.roles[name]; exists {
+			return fmt.Errorf("role %s is defined in both %s and %s", name, prev, file)
+		}
+		p.origins.roles[name] = file
+	}
+	for _, name := range sortedKeys(other.Groups) {
+		if prev, exists := p.origins.groups[name]; exists {
+			return fmt.Errorf("group %s is defined in both %s and %s", name, prev, file)
+		}
+		p.origins.groups[name] = file
+	}
+	for _, email := range sortedKeys(other.ServiceAccounts) {
+		if prev, exists := p.origins.serviceAccounts[email]; exists {
+			return fmt.Errorf("service account %s is defined in both %s and %s", email, prev, file)
+		}
+		p.origins.serviceAccounts[email] = file
+	}
+	for _, name := range sortedKeys(other.Projects) {
+		for i := range other.Projects[name].Bindings {
+			p.origins.projects[name] = append(p.origins.projects[name], bindingOrigin{file: file, index: i})
+		}
+	}
+
+	origins := p.origins
+	unknownKeys := append(p.unknownKeys, other.unknownKeys...)
+	positions := append(p.positions, other.positions...)
+	merged, err := Merge(p, other)
+	if err != nil {
+		return err
+	}
+	*p = *merged
+	p.origins = origins
+	p.unknownKeys = unknownKeys
+	p.positions = positions
+	return nil
+}
+
+// IsMerged reports whether the policy was assembled from includes.
+// Saving a merged policy writes a single flattened file.
+func (p *Policy) IsMerged() bool {
+	return p.origins != nil
+}
+
+// roleOrigin returns the file a role was defined in, or "" for single-file policies
+func (p *Policy) roleOrigin(name string) string {
+	if p.origins == nil {
+		return ""
+	}
+	return p.origins.roles[name]
+}
+
+// groupOrigin returns the file a group was defined in, or "" for single-file policies
+func (p *Policy) groupOrigin(name string) string {
+	if p.origins == nil {
+		return ""
+	}
+	return p.origins.groups[name]
+}
+
+// serviceAccountOrigin returns the file a service account was defined in,
+// or "" for single-file policies
+func (p *Policy) serviceAccountOrigin(email string) string {
+	if p.origins == nil {
+		return ""
+	}
+	return p.origins.serviceAccounts[email]
+}
+
+// bindingOrigin returns the file and file-local index of a project binding.
+// For single-file policies the file is "" and the index is unchanged.
+func (p *Policy) bindingOrigin(project string, i int) (string, int) {
+	if p.origins == nil || i >= len(p.origins.projects[project]) {
+		return "", i
+	}
+	o := p.origins.projects[project][i]
+	return o.file, o.index
+}
+
+// relPaths shortens absolute paths relative to the working directory for display
+func relPaths(paths []string) []string {
+	wd, err := filepath.Abs(".")
+	if err != nil {
+		return paths
+	}
+	out := make([]string, len(paths))
+	for i, p := range paths {
+		if rel, err := filepath.Rel(wd, p); err == nil {
+			out[i] = rel
+		} else {
+			out[i] = p
+		}
+	}
+	return out
+}
@@ -0,0 +1,169 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes name->content pairs under dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestLoadIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"policy.yaml": `includes:
+  - teams/a.yaml
+  - teams/b.yaml
+projects:
+  test-project:
+    bindings:
+      - role: roles/viewer
+        members: [user:root@example.com]
+`,
+		"teams/a.yaml": `roles:
+  roles/custom.a:
+    permissions: [secretmanager.secrets.get]
+projects:
+  test-project:
+    bindings:
+      - role: roles/custom.a
+        members: [user:a@example.com]
+`,
+		"teams/b.yaml": `includes:
+  - a.yaml
+groups:
+  b-team:
+    members: [user:b@example.com]
+projects:
+  test-project:
+    bindings:
+      - role: roles/custom.undefined
+        members: [group:b-team]
+`,
+	})
+
+	policy, err := Load(filepath.Join(dir, "policy.yaml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if len(policy.Includes) != 0 {
+		t.Errorf("merged policy should not keep includes, got %v", policy.Includes)
+	}
+	if _, ok := policy.Roles["roles/custom.a"]; !ok {
+		t.Error("Missing role from include")
+	}
+	if _, ok := policy.Groups["b-team"]; !ok {
+		t.Error("Missing group from include")
+	}
+
+	bindings := policy.Projects["test-project"].Bindings
+	if len(bindings) != 3 {
+		t.Fatalf("Expected 3 bindings, got %d", len(bindings))
+	}
+	// Includes are merged in order before the including file
+	wantRoles := []string{"roles/custom.a", "roles/custom.undefined", "roles/viewer"}
+	for i, role := range wantRoles {
+		if bindings[i].Role != role {
+			t.Errorf("binding %d role = %s, want %s", i, bindings[i].Role, role)
+		}
+	}
+
+	result := Validate(policy)
+	wantErr := filepath.Join(dir, "teams/b.yaml") + ": Project test-project binding 0: undefined role roles/custom.undefined"
+	if !hasError(result, wantErr) {
+		t.Errorf("expected error with originating file %q, got %v", wantErr, result.Errors)
+	}
+}
+
+func TestLoadIncludeConflict(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"policy.yaml": `includes: [other.yaml]
+roles:
+  roles/custom.dup:
+    permissions: [secretmanager.secrets.get]
+`,
+		"other.yaml": `roles:
+  roles/custom.dup:
+    permissions: [secretmanager.secrets.list]
+`,
+	})
+
+	_, err := Load(filepath.Join(dir, "policy.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "role roles/custom.dup is defined in both") {
+		t.Errorf("Expected role conflict error, got %v", err)
+	}
+}
+
+func TestLoadIncludeServiceAccountConflict(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"policy.yaml": `includes: [other.yaml]
+serviceAccounts:
+  ci@test-project.iam.gserviceaccount.com:
+    displayName: CI
+`,
+		"other.yaml": `serviceAccounts:
+  ci@test-project.iam.gserviceaccount.com:
+    displayName: Build
+`,
+	})
+
+	_, err := Load(filepath.Join(dir, "policy.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "service account ci@test-project.iam.gserviceaccount.com is defined in both") || !strings.Contains(err.Error(), "other.yaml") {
+		t.Errorf("Expected service account conflict naming other.yaml, got %v", err)
+	}
+}
+
+func TestLoadIncludeCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.yaml": "includes: [b.yaml]\n",
+		"b.yaml": "includes: [a.yaml]\n",
+	})
+
+	_, err := Load(filepath.Join(dir, "a.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("Expected include cycle error, got %v", err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := &Policy{
+		Roles:    map[string]Role{"roles/custom.a": {}},
+		Projects: map[string]Project{"p": {Bindings: []Binding{{Role: "roles/custom.a"}}}},
+	}
+	other := &Policy{
+		Groups:   map[string]Group{"g": {}},
+		Projects: map[string]Project{"p": {Bindings: []Binding{{Role: "roles/viewer"}}}},
+	}
+
+	merged, err := Merge(base, other)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if len(merged.Projects["p"].Bindings) != 2 {
+		t.Errorf("Expected bindings to be appended, got %+v", merged.Projects["p"].Bindings)
+	}
+	if len(base.Projects["p"].Bindings) != 1 {
+		t.Error("Merge modified base policy")
+	}
+
+	if _, err := Merge(base, base); err == nil {
+		t.Error("Expected conflict merging a policy with itself")
+	}
+}
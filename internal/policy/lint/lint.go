@@ -0,0 +1,226 @@
+// Package lint implements configurable style and safety rules for policy files.
+//
+// Unlike the validator in the parent package, which rejects policies the IAM
+// emulator cannot load, lint rules flag policies that load fine but are
+// probably not what the author intended. Each rule has a stable ID (GCP001,
+// GCP002, ...) that can be disabled from the CLI or the config file.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+// Severity is the severity of a lint finding
+type Severity string
+
+const (
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// Finding is a single rule violation
+type Finding struct {
+	RuleID   string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Location string   `json:"location"`
+	Message  string   `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s %s [%s] %s", f.Severity, f.RuleID, f.Location, f.Message)
+}
+
+// Rule is a named lint check
+type Rule struct {
+	ID          string
+	Name        string
+	Severity    Severity
+	Description string
+	check       func(pol *policy.Policy, report func(location, message string))
+}
+
+// Options controls which rules run
+type Options struct {
+	// Disable lists rule IDs to skip
+	Disable []string
+}
+
+// coveredServices are the permission prefixes the emulator stack implements
+var coveredServices = map[string]bool{
+	"secretmanager": true,
+	"cloudkms":      true,
+	"iam":           true,
+}
+
+// Rules is the catalog of lint rules in ID order
+var Rules = []Rule{
+	{
+		ID:          "GCP001",
+		Name:        "role-no-permissions",
+		Severity:    SeverityWarning,
+		Description: "Role has no permissions",
+		check: func(pol *policy.Policy, report func(string, string)) {
+			for _, name := range sortedKeys(pol.Roles) {
+				if len(policy.RolePermissions(pol, name)) == 0 {
+					report("roles."+name, "role has no permissions")
+				}
+			}
+		},
+	},
+	{
+		ID:          "GCP002",
+		Name:        "group-no-members",
+		Severity:    SeverityWarning,
+		Description: "Group has no members",
+		check: func(pol *policy.Policy, report func(string, string)) {
+			for _, name := range sortedKeys(pol.Groups) {
+				if len(pol.Groups[name].Members) == 0 {
+					report("groups."+name, "group has no members")
+				}
+			}
+		},
+	},
+	{
+		ID:          "GCP003",
+		Name:        "project-no-bindings",
+		Severity:    SeverityWarning,
+		Description: "Project has no bindings of its own or inherited",
+		check: func(pol *policy.Policy, report func(string, string)) {
+			for _, name := range sortedKeys(pol.Projects) {
+				if len(policy.EffectiveBindings(pol, name)) == 0 {
+					report("projects."+name, "project has no bindings")
+				}
+			}
+		},
+	},
+	{
+		ID:          "GCP004",
+		Name:        "binding-public-member",
+		Severity:    SeverityError,
+		Description: "Binding grants access to allUsers or allAuthenticatedUsers",
+		check: func(pol *policy.Policy, report func(string, string)) {
+			for _, name := range sortedKeys(pol.Projects) {
+				for i, binding := range pol.Projects[name].Bindings {
+					for _, member := range binding.Members {
+						if member == "allUsers" || member == "allAuthenticatedUsers" {
+							report(bindingLocation(name, i), fmt.Sprintf("%s is granted %s", member, binding.Role))
+						}
+					}
+				}
+			}
+		},
+	},
+	{
+		ID:          "GCP005",
+		Name:        "role-naming",
+		Severity:    SeverityWarning,
+		Description: "Custom role name does not follow the roles/custom.* convention",
+		check: func(pol *policy.Policy, report func(string, string)) {
+			for _, name := range sortedKeys(pol.Roles) {
+				if !strings.HasPrefix(name, "roles/custom.") {
+					report("roles."+name, "custom role names should start with roles/custom.")
+				}
+			}
+		},
+	},
+	{
+		ID:          "GCP006",
+		Name:        "unsupported-service",
+		Severity:    SeverityWarning,
+		Description: "Permission belongs to a service not covered by the emulators",
+		check: func(pol *policy.Policy, report func(string, string)) {
+			for _, name := range sortedKeys(pol.Roles) {
+				for _, perm := range pol.Roles[name].Permissions {
+					service, _, _ := strings.Cut(perm, ".")
+					if !coveredServices[service] {
+						report("roles."+name, fmt.Sprintf("permission %s is not implemented by any emulator (covered: secretmanager, cloudkms, iam)", perm))
+					}
+				}
+			}
+		},
+	},
+	{
+		ID:          "GCP007",
+		Name:        "redundant-binding",
+		Severity:    SeverityWarning,
+		Description: "Member grant is made unnecessary by another binding",
+		check: func(pol *policy.Policy, report func(string, string)) {
+			for _, r := range policy.FindRedundancies(pol) {
+				report(scopeLocation(r.Scope, r.Index), r.Message)
+			}
+		},
+	},
+}
+
+// Run checks the policy against all enabled rules. Findings are returned
+// in rule order, then by location.
+func Run(pol *policy.Policy, opts Options) []Finding {
+	disabled := make(map[string]bool, len(opts.Disable))
+	for _, id := range opts.Disable {
+		disabled[normalizeRuleID(id)] = true
+	}
+
+	var findings []Finding
+	for _, rule := range Rules {
+		if disabled[rule.ID] {
+			continue
+		}
+		rule.check(pol, func(location, message string) {
+			findings = append(findings, Finding{
+				RuleID:   rule.ID,
+				Severity: rule.Severity,
+				Location: location,
+				Message:  message,
+			})
+		})
+	}
+
+	return findings
+}
+
+// LookupRule returns the rule with the given ID
+func LookupRule(id string) (Rule, bool) {
+	id = normalizeRuleID(id)
+	for _, rule := range Rules {
+		if rule.ID == id {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// normalizeRuleID puts a rule ID given by a user, as in --disable or the
+// lint section of the config, in the form of Rule.ID
+func normalizeRuleID(id string) string {
+	return strings.ToUpper(strings.TrimSpace(id))
+}
+
+func bindingLocation(project string, index int) string {
+	return fmt.Sprintf("projects.%s.bindings[%d]", project, index)
+}
+
+// scopeLocation formats the location of a binding identified by a
+// projects/, folders/, or organizations/ scope, including bindings set on
+// resources beneath a project
+func scopeLocation(scope string, index int) string {
+	kind, rest, _ := strings.Cut(scope, "/")
+	name, resource, _ := strings.Cut(rest, "/")
+	if resource != "" {
+		return fmt.Sprintf("%s.%s.resources[%s].bindings[%d]", kind, name, resource, index)
+	}
+	return fmt.Sprintf("%s.%s.bindings[%d]", kind, name, index)
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,118 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+func lintPolicy() *policy.Policy {
+	return &policy.Policy{
+		Roles: map[string]policy.Role{
+			"roles/custom.empty": {},
+			"roles/deployer": {
+				Permissions: []string{"storage.objects.get", "secretmanager.secrets.get"},
+			},
+		},
+		Groups: map[string]policy.Group{
+			"nobody": {},
+		},
+		Projects: map[string]policy.Project{
+			"empty-project": {},
+			"test-project": {
+				Bindings: []policy.Binding{
+					{Role: "roles/deployer", Members: []string{"allUsers"}},
+				},
+			},
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	findings := Run(lintPolicy(), Options{})
+
+	want := []struct {
+		rule     string
+		location string
+	}{
+		{"GCP001", "roles.roles/custom.empty"},
+		{"GCP002", "groups.nobody"},
+		{"GCP003", "projects.empty-project"},
+		{"GCP004", "projects.test-project.bindings[0]"},
+		{"GCP005", "roles.roles/deployer"},
+		{"GCP006", "roles.roles/deployer"},
+	}
+
+	if len(findings) != len(want) {
+		t.Fatalf("got %d findings, want %d: %v", len(findings), len(want), findings)
+	}
+
+	for i, w := range want {
+		if findings[i].RuleID != w.rule || findings[i].Location != w.location {
+			t.Errorf("finding %d = %s at %s, want %s at %s", i, findings[i].RuleID, findings[i].Location, w.rule, w.location)
+		}
+	}
+
+	if findings[3].Severity != SeverityError {
+		t.Errorf("GCP004 severity = %s, want error", findings[3].Severity)
+	}
+}
+
+func TestRunDisable(t *testing.T) {
+	findings := Run(lintPolicy(), Options{Disable: []string{"GCP004", "gcp006"}})
+
+	for _, f := range findings {
+		if f.RuleID == "GCP004" || f.RuleID == "GCP006" {
+			t.Errorf("disabled rule %s reported: %v", f.RuleID, f)
+		}
+	}
+
+	if len(findings) != 4 {
+		t.Errorf("got %d findings, want 4: %v", len(findings), findings)
+	}
+}
+
+func TestLookupRule(t *testing.T) {
+	if _, ok := LookupRule("GCP001"); !ok {
+		t.Error("GCP001 should exist")
+	}
+	if _, ok := LookupRule(" gcp001"); !ok {
+		t.Error("rule IDs should be looked up as Run applies them, trimmed and in any case")
+	}
+	if _, ok := LookupRule("GCP999"); ok {
+		t.Error("GCP999 should not exist")
+	}
+}
+
+func TestRedundantBindingRule(t *testing.T) {
+	pol := &policy.Policy{
+		Roles: map[string]policy.Role{
+			"roles/custom.dev": {Permissions: []string{"secretmanager.secrets.get"}},
+		},
+		Projects: map[string]policy.Project{
+			"test-project": {
+				Bindings: []policy.Binding{
+					{Role: "roles/custom.dev", Members: []string{"group:developers"}},
+				},
+				Resources: map[string]policy.Resource{
+					"secrets/db-password": {
+						Bindings: []policy.Binding{
+							{Role: "roles/custom.dev", Members: []string{"group:developers"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var locations []string
+	for _, f := range Run(pol, Options{}) {
+		if f.RuleID == "GCP007" {
+			locations = append(locations, f.Location)
+		}
+	}
+
+	if len(locations) != 1 || locations[0] != "projects.test-project.resources[secrets/db-password].bindings[0]" {
+		t.Errorf("GCP007 locations = %v", locations)
+	}
+}
@@ -8,8 +8,11 @@
 package policy
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -19,14 +22,46 @@ import (
 
 // Policy represents the policy file structure
 type Policy struct {
-	Roles    map[string]Role       `yaml:"roles" json:"roles"`
-	Groups   map[string]Group      `yaml:"groups" json:"groups"`
-	Projects map[string]Project    `yaml:"projects" json:"projects"`
+	Version  int                `yaml:"version,omitempty" json:"version,omitempty"`
+	Includes []string           `yaml:"includes,omitempty" json:"includes,omitempty"`
+	Roles    map[string]Role    `yaml:"roles" json:"roles"`
+	Groups   map[string]Group   `yaml:"groups" json:"groups"`
+	Projects map[string]Project `yaml:"projects" json:"projects"`
+
+	ServiceAccounts map[string]ServiceAccount `yaml:"serviceAccounts,omitempty" json:"serviceAccounts,omitempty"`
+
+	// AllowUnknownPermissions skips the permission catalog check for every
+	// role, for policies that intentionally use permissions it doesn't know
+	AllowUnknownPermissions bool `yaml:"allowUnknownPermissions,omitempty" json:"allowUnknownPermissions,omitempty"`
+
+	Organizations map[string]Organization `yaml:"organizations,omitempty" json:"organizations,omitempty"`
+	Folders       map[string]Folder       `yaml:"folders,omitempty" json:"folders,omitempty"`
+
+	// origins records the source file of each definition when the policy
+	// was assembled from includes; nil for single-file policies
+	origins *origins
+
+	// unknownKeys lists keys in the source files that the schema does not
+	// define; Validate reports them
+	unknownKeys []UnknownKey
+
+	// positions locates schema paths in each source file, in load order
+	positions []filePositions
 }
 
 // Role represents a custom role with permissions
 type Role struct {
+	// Description says what the role is for; it appears in policy docs
+	// and exported role definitions
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
 	Permissions []string `yaml:"permissions" json:"permissions"`
+
+	// IncludeRoles pulls in the permissions of other custom or built-in
+	// roles when the role is resolved
+	IncludeRoles []string `yaml:"includeRoles,omitempty" json:"includeRoles,omitempty"`
+
+	// AllowUnknownPermissions skips the permission catalog check for this role
+	AllowUnknownPermissions bool `yaml:"allowUnknownPermissions,omitempty" json:"allowUnknownPermissions,omitempty"`
 }
 
 // Group represents a group with members
@@ -34,9 +69,45 @@ type Group struct {
 	Members []string `yaml:"members" json:"members"`
 }
 
+// ServiceAccount declares a service account, keyed by email in Policy.ServiceAccounts
+type ServiceAccount struct {
+	DisplayName string `yaml:"displayName,omitempty" json:"displayName,omitempty"`
+	Project     string `yaml:"project,omitempty" json:"project,omitempty"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
 // Project represents a project with IAM bindings
 type Project struct {
+	// Parent is the folder or organization the project inherits bindings
+	// from, as folders/<name> or organizations/<name>
+	Parent   string    `yaml:"parent,omitempty" json:"parent,omitempty"`
 	Bindings []Binding `yaml:"bindings" json:"bindings"`
+
+	// DenyBindings deny permissions regardless of what bindings allow
+	DenyBindings []DenyBinding `yaml:"denyBindings,omitempty" json:"denyBindings,omitempty"`
+
+	// Resources holds bindings set on individual resources, keyed by name
+	// relative to the project, such as secrets/db-password
+	Resources map[string]Resource `yaml:"resources,omitempty" json:"resources,omitempty"`
+}
+
+// Resource holds the IAM bindings set on a single secret, key ring, or
+// crypto key. They apply to the resource and everything beneath it.
+type Resource struct {
+	Bindings []Binding `yaml:"bindings" json:"bindings"`
+}
+
+// Organization is the root of a resource hierarchy. Its bindings are
+// inherited by every folder and project beneath it.
+type Organization struct {
+	Bindings []Binding `yaml:"bindings,omitempty" json:"bindings,omitempty"`
+}
+
+// Folder groups projects under an organization or another folder.
+// Its bindings are inherited by everything beneath it.
+type Folder struct {
+	Parent   string    `yaml:"parent,omitempty" json:"parent,omitempty"`
+	Bindings []Binding `yaml:"bindings,omitempty" json:"bindings,omitempty"`
 }
 
 // Binding represents an IAM binding
@@ -46,6 +117,15 @@ type Binding struct {
 	Condition *Condition `yaml:"condition,omitempty" json:"condition,omitempty"`
 }
 
+// DenyBinding is an IAM deny rule. Permissions use the deny policy form
+// service.googleapis.com/resource.verb.
+type DenyBinding struct {
+	DeniedPrincipals    []string   `yaml:"deniedPrincipals" json:"deniedPrincipals"`
+	ExceptionPrincipals []string   `yaml:"exceptionPrincipals,omitempty" json:"exceptionPrincipals,omitempty"`
+	DeniedPermissions   []string   `yaml:"deniedPermissions" json:"deniedPermissions"`
+	Condition           *Condition `yaml:"condition,omitempty" json:"condition,omitempty"`
+}
+
 // Condition represents a CEL condition
 type Condition struct {
 	Expression  string `yaml:"expression" json:"expression"`
@@ -53,17 +133,109 @@ type Condition struct {
 	Description string `yaml:"description,omitempty" json:"description,omitempty"`
 }
 
-// Load loads and parses a policy file (supports .yaml, .yml, and .json)
+// ErrInvalidPolicy is returned for a policy that fails validation. Every
+// error from loading a policy also matches it with errors.Is.
+var ErrInvalidPolicy = errors.New("policy validation failed")
+
+// loadError marks an error from loading a policy as ErrInvalidPolicy,
+// keeping its message
+type loadError struct {
+	err error
+}
+
+func (e loadError) Error() string   { return e.err.Error() }
+func (e loadError) Unwrap() []error { return []error{e.err, ErrInvalidPolicy} }
+
+// Load loads and parses a policy file (supports .yaml, .yml, and .json).
+// Files listed under includes: are loaded recursively (paths relative to
+// the including file) and merged with Merge.
 func Load(path string) (*Policy, error) {
-	data, err := os.ReadFile(path)
+	return LoadOptions{}.Load(path)
+}
+
+// Load is Load, decrypting encrypted files with the passphrase o gives
+func (o LoadOptions) Load(path string) (*Policy, error) {
+	policy, err := o.LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(policy.Includes) == 0 {
+		return policy, nil
+	}
+
+	policy, err = resolveIncludes(path, policy, o)
+	if err != nil {
+		return nil, loadError{err}
+	}
+	return policy, nil
+}
+
+// LoadReader parses a policy from r. format is "yaml" or "json". Includes
+// are resolved relative to a file's location, so a policy read this way
+// must not use them.
+func LoadReader(r io.Reader, format string) (*Policy, error) {
+	policy, err := loadReader(r, format)
+	if err != nil {
+		return nil, loadError{err}
+	}
+	return policy, nil
+}
+
+func loadReader(r io.Reader, format string) (*Policy, error) {
+	if format != "yaml" && format != "json" {
+		return nil, fmt.Errorf("unsupported policy format: %s (must be yaml or json)", format)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+
+	var policy Policy
+	if format == "json" {
+		err = json.Unmarshal(data, &policy)
+	} else {
+		err = yaml.Unmarshal(data, &policy)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policy %s: %w", strings.ToUpper(format), err)
+	}
+
+	if err := finishLoad(&policy, data, ""); err != nil {
+		return nil, err
+	}
+	if len(policy.Includes) > 0 {
+		return nil, fmt.Errorf("includes are only supported when loading a policy from a file")
+	}
+	return &policy, nil
+}
+
+// LoadFile parses a single policy file without resolving includes, for
+// tools that work on the file as written rather than the assembled policy
+func LoadFile(path string) (*Policy, error) {
+	return LoadOptions{}.LoadFile(path)
+}
+
+// LoadFile is LoadFile, decrypting an encrypted file with the passphrase
+// o gives
+func (o LoadOptions) LoadFile(path string) (*Policy, error) {
+	policy, err := o.loadFile(path)
+	if err != nil {
+		return nil, loadError{err}
+	}
+	return policy, nil
+}
+
+func (o LoadOptions) loadFile(path string) (*Policy, error) {
+	data, ext, err := o.readPolicyFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read policy file: %w", err)
+		return nil, err
 	}
 
 	var policy Policy
-	
+
 	// Detect format by file extension
-	ext := strings.ToLower(filepath.Ext(path))
 	switch ext {
 	case ".json":
 		if err := json.Unmarshal(data, &policy); err != nil {
@@ -80,33 +252,48 @@ func Load(path string) (*Policy, error) {
 		}
 	}
 
+	if err := finishLoad(&policy, data, path); err != nil {
+		return nil, err
+	}
 	return &policy, nil
 }
 
-// Save saves policy to file (format determined by file extension)
+// finishLoad checks a decoded policy's version and records the positions
+// and unknown keys of its source document. file is "" when the document
+// was not read from a file.
+func finishLoad(policy *Policy, data []byte, file string) error {
+	if err := checkVersion(policy); err != nil {
+		return err
+	}
+
+	if root := parseNodes(data); root != nil {
+		policy.unknownKeys = findUnknownKeys(root, file)
+		policy.positions = []filePositions{indexPositions(root, file)}
+	}
+	return nil
+}
+
+// Save saves policy to file (format determined by file extension). It
+// does not encrypt, so it refuses to replace an encrypted file.
 func Save(policy *Policy, path string) error {
-	var data []byte
-	var err error
-	
-	// Detect format by file extension
-	ext := strings.ToLower(filepath.Ext(path))
-	switch ext {
-	case ".json":
-		data, err = json.MarshalIndent(policy, "", "  ")
-		if err != nil {
-			return fmt.Errorf("failed to marshal policy JSON: %w", err)
-		}
-	case ".yaml", ".yml":
-		data, err = yaml.Marshal(policy)
-		if err != nil {
-			return fmt.Errorf("failed to marshal policy YAML: %w", err)
-		}
-	default:
-		// Default to YAML for backwards compatibility
-		data, err = yaml.Marshal(policy)
-		if err != nil {
-			return fmt.Errorf("failed to marshal policy YAML: %w", err)
-		}
+	return SaveOptions{}.Save(policy, path)
+}
+
+// Save is Save, writing over an encrypted file if o.AllowDecrypt is set
+func (o SaveOptions) Save(policy *Policy, path string) error {
+	if err := o.CheckPlaintextWrite(path); err != nil {
+		return err
+	}
+
+	// YAML for .yaml/.yml, and as the default for backwards compatibility
+	format := "yaml"
+	if strings.ToLower(filepath.Ext(strings.TrimSuffix(path, EncryptedExt))) == ".json" {
+		format = "json"
+	}
+
+	data, err := Encode(policy, format)
+	if err != nil {
+		return err
 	}
 
 	if err := os.WriteFile(path, data, 0644); err != nil {
@@ -115,3 +302,35 @@ func Save(policy *Policy, path string) error {
 
 	return nil
 }
+
+// Encode serializes policy as "yaml" or "json", as Save writes it
+func Encode(policy *Policy, format string) ([]byte, error) {
+	if format == "json" {
+		data, err := json.MarshalIndent(policy, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal policy JSON: %w", err)
+		}
+		return append(data, '\n'), nil
+	}
+
+	data, err := marshalYAML(policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal policy YAML: %w", err)
+	}
+	return data, nil
+}
+
+// marshalYAML encodes policy as YAML with two-space indentation.
+// Map keys are emitted in sorted order so output is stable.
+func marshalYAML(policy *Policy) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(policy); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -3,7 +3,11 @@ package policy
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestValidatePermissionFormat(t *testing.T) {
@@ -176,6 +180,15 @@ func TestLoadYAML(t *testing.T) {
 			t.Errorf("Unexpected condition title: %s", testProject.Bindings[1].Condition.Title)
 		}
 	}
+
+	// Check service accounts
+	ci, ok := policy.ServiceAccounts["ci@test-project.iam.gserviceaccount.com"]
+	if !ok {
+		t.Error("Missing ci service account")
+	}
+	if ci.DisplayName != "CI Runner" || ci.Project != "test-project" {
+		t.Errorf("Unexpected service account: %+v", ci)
+	}
 }
 
 func TestLoadJSON(t *testing.T) {
@@ -237,6 +250,15 @@ func TestLoadJSON(t *testing.T) {
 			t.Errorf("Unexpected condition title: %s", testProject.Bindings[1].Condition.Title)
 		}
 	}
+
+	// Check service accounts
+	ci, ok := policy.ServiceAccounts["ci@test-project.iam.gserviceaccount.com"]
+	if !ok {
+		t.Error("Missing ci service account")
+	}
+	if ci.DisplayName != "CI Runner" || ci.Project != "test-project" {
+		t.Errorf("Unexpected service account: %+v", ci)
+	}
 }
 
 func TestSaveYAML(t *testing.T) {
@@ -408,3 +430,237 @@ func TestLoadInvalidYAML(t *testing.T) {
 		t.Error("Expected error for invalid YAML, got nil")
 	}
 }
+
+func TestConvertRoundTrip(t *testing.T) {
+	original, err := Load("../../testdata/policy.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load YAML policy: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	jsonPath := filepath.Join(tmpDir, "policy.json")
+	yamlPath := filepath.Join(tmpDir, "policy.yaml")
+
+	if err := Save(original, jsonPath); err != nil {
+		t.Fatalf("Failed to save JSON: %v", err)
+	}
+
+	fromJSON, err := Load(jsonPath)
+	if err != nil {
+		t.Fatalf("Failed to load JSON: %v", err)
+	}
+
+	if err := Save(fromJSON, yamlPath); err != nil {
+		t.Fatalf("Failed to save YAML: %v", err)
+	}
+
+	roundTripped, err := Load(yamlPath)
+	if err != nil {
+		t.Fatalf("Failed to load YAML: %v", err)
+	}
+
+	if diff := Diff(original, roundTripped); !diff.Empty() {
+		t.Errorf("Round trip changed policy: %+v", diff)
+	}
+
+	if !reflect.DeepEqual(original.ServiceAccounts, roundTripped.ServiceAccounts) {
+		t.Errorf("Service accounts not preserved: %+v", roundTripped.ServiceAccounts)
+	}
+
+	condition := roundTripped.Projects["test-project"].Bindings[1].Condition
+	if condition == nil || condition.Title != "CI limited to production secrets" {
+		t.Errorf("Condition not preserved: %+v", condition)
+	}
+
+	// Output should be stable across saves
+	first, _ := os.ReadFile(yamlPath)
+	if err := Save(roundTripped, yamlPath); err != nil {
+		t.Fatalf("Failed to re-save YAML: %v", err)
+	}
+	second, _ := os.ReadFile(yamlPath)
+	if string(first) != string(second) {
+		t.Error("YAML output is not stable across saves")
+	}
+}
+
+func TestLoadVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantVersion int
+		wantErr     bool
+	}{
+		{
+			name:        "missing version defaults to 1",
+			content:     "roles: {}\n",
+			wantVersion: 1,
+		},
+		{
+			name:        "explicit version 1",
+			content:     "version: 1\nroles: {}\n",
+			wantVersion: 1,
+		},
+		{
+			name:        "version 0 treated as missing",
+			content:     "version: 0\nroles: {}\n",
+			wantVersion: 1,
+		},
+		{
+			name:    "negative version rejected",
+			content: "version: -1\nroles: {}\n",
+			wantErr: true,
+		},
+		{
+			name:    "newer version rejected",
+			content: "version: 99\nroles: {}\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			policy, err := Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && policy.Version != tt.wantVersion {
+				t.Errorf("Version = %d, want %d", policy.Version, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	policy, from, err := Migrate("../../testdata/policy.yaml")
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	if from != 1 {
+		t.Errorf("from = %d, want 1", from)
+	}
+	if policy.Version != CurrentVersion {
+		t.Errorf("Version = %d, want %d", policy.Version, CurrentVersion)
+	}
+
+	original, err := Load("../../testdata/policy.yaml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if diff := Diff(original, policy); !diff.Empty() {
+		t.Errorf("Migrate changed policy content: %+v", diff)
+	}
+
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99}`), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if _, _, err := Migrate(path); err == nil {
+		t.Error("Expected error migrating a newer version")
+	}
+
+	// Load accepts version 0 as version 1, so Migrate must too
+	path = filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte("version: 0\nroles: {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if _, from, err := Migrate(path); err != nil || from != 1 {
+		t.Errorf("Migrate(version 0) = from %d, %v; want from 1", from, err)
+	}
+}
+
+func TestMigrateDocument(t *testing.T) {
+	// A version 1 document, upgraded by a step that renames the members
+	// of bindings, as a schema change would
+	var doc map[string]any
+	if err := yaml.Unmarshal([]byte(`version: 1
+roles:
+  roles/custom.dev:
+    permissions: [secretmanager.secrets.get]
+projects:
+  test-project:
+    bindings:
+      - role: roles/custom.dev
+        principals: [user:alice@example.com]
+`), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := map[int]func(doc map[string]any) error{
+		1: func(doc map[string]any) error {
+			for _, project := range doc["projects"].(map[string]any) {
+				for _, binding := range project.(map[string]any)["bindings"].([]any) {
+					b := binding.(map[string]any)
+					b["members"] = b["principals"]
+					delete(b, "principals")
+				}
+			}
+			return nil
+		},
+	}
+
+	from, err := migrateDocument(doc, 2, steps)
+	if err != nil {
+		t.Fatalf("migrateDocument() error = %v", err)
+	}
+	if from != 1 {
+		t.Errorf("from = %d, want 1", from)
+	}
+
+	policy, err := decodeDocument(doc)
+	if err != nil {
+		t.Fatalf("decodeDocument() error = %v", err)
+	}
+	if policy.Version != 2 {
+		t.Errorf("Version = %d, want 2", policy.Version)
+	}
+	members := policy.Projects["test-project"].Bindings[0].Members
+	if len(members) != 1 || members[0] != "user:alice@example.com" {
+		t.Errorf("Members = %v, want the renamed principals", members)
+	}
+
+	if _, err := migrateDocument(map[string]any{"version": 1}, 2, nil); err == nil {
+		t.Error("Expected error for a version without a migration step")
+	}
+}
+
+func TestLoadReader(t *testing.T) {
+	yamlPolicy, err := LoadReader(strings.NewReader(`roles:
+  roles/custom.test:
+    permissions: [secretmanager.secrets.get]
+projects:
+  test-project:
+    bindings:
+      - role: roles/custom.test
+        members: [user:alice@example.com]
+`), "yaml")
+	if err != nil {
+		t.Fatalf("LoadReader(yaml) error = %v", err)
+	}
+	if len(yamlPolicy.Roles) != 1 || len(yamlPolicy.Projects["test-project"].Bindings) != 1 {
+		t.Errorf("Unexpected policy: %+v", yamlPolicy)
+	}
+	if pos := yamlPolicy.rolePosition("roles/custom.test", ""); pos != (Position{Line: 2, Column: 3}) {
+		t.Errorf("rolePosition() = %+v, want line 2 column 3 with no file", pos)
+	}
+
+	jsonPolicy, err := LoadReader(strings.NewReader(`{"roles": {"roles/custom.test": {"permissions": ["secretmanager.secrets.get"]}}}`), "json")
+	if err != nil {
+		t.Fatalf("LoadReader(json) error = %v", err)
+	}
+	if len(jsonPolicy.Roles) != 1 {
+		t.Errorf("Expected 1 role, got %d", len(jsonPolicy.Roles))
+	}
+
+	if _, err := LoadReader(strings.NewReader("{}"), "toml"); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+	if _, err := LoadReader(strings.NewReader("includes: [other.yaml]\n"), "yaml"); err == nil || !strings.Contains(err.Error(), "includes") {
+		t.Errorf("Expected includes error, got %v", err)
+	}
+}
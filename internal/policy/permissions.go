@@ -0,0 +1,81 @@
+package policy
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// knownPermissionsJSON lists the real GCP permissions of the services the
+// emulators cover (secretmanager, cloudkms, iam). Custom role permissions
+// outside this list are reported as likely typos.
+//
+//go:embed catalog/permissions.json
+var knownPermissionsJSON []byte
+
+var (
+	knownPermissionsOnce sync.Once
+	knownPermissions     map[string]bool
+)
+
+// getKnownPermissions parses the embedded permission catalog once
+func getKnownPermissions() map[string]bool {
+	knownPermissionsOnce.Do(func() {
+		var perms []string
+		if err := json.Unmarshal(knownPermissionsJSON, &perms); err != nil {
+			panic(fmt.Sprintf("policy: invalid permission catalog: %v", err))
+		}
+		knownPermissions = make(map[string]bool, len(perms))
+		for _, perm := range perms {
+			knownPermissions[perm] = true
+		}
+	})
+	return knownPermissions
+}
+
+// IsKnownPermission reports whether perm is in the permission catalog
+func IsKnownPermission(perm string) bool {
+	return getKnownPermissions()[perm]
+}
+
+// suggestPermission returns the closest known permission of the same
+// service within a small edit distance, or "" if nothing is close
+func suggestPermission(perm string) string {
+	service, _, _ := strings.Cut(perm, ".")
+
+	best, bestDist := "", 3
+	for _, known := range sortedKeys(getKnownPermissions()) {
+		if !strings.HasPrefix(known, service+".") {
+			continue
+		}
+		if d := editDistance(perm, known); d < bestDist {
+			best, bestDist = known, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
@@ -0,0 +1,115 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Position is a location in a policy source file. Line and column are
+// 1-based; the zero Position means the location is unknown.
+type Position struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+// IsValid reports whether the position refers to a line
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String formats the position as file:line:column
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("line %d, column %d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// filePositions indexes where each schema path, such as
+// roles.roles/custom.dev.permissions[2], appears in one source file.
+// Mapping entries are located at their key, list items at the item.
+type filePositions struct {
+	file  string
+	paths map[string]Position
+}
+
+// indexPositions records the position of every mapping key and list item
+// under root
+func indexPositions(root *yaml.Node, file string) filePositions {
+	index := filePositions{file: file, paths: make(map[string]Position)}
+
+	var walk func(node *yaml.Node, path string)
+	walk = func(node *yaml.Node, path string) {
+		if node.Kind == yaml.AliasNode && node.Alias != nil {
+			node = node.Alias
+		}
+
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key := node.Content[i]
+				child := joinPath(path, key.Value)
+				index.paths[child] = Position{File: file, Line: key.Line, Column: key.Column}
+				walk(node.Content[i+1], child)
+			}
+		case yaml.SequenceNode:
+			for i, item := range node.Content {
+				child := fmt.Sprintf("%s[%d]", path, i)
+				index.paths[child] = Position{File: file, Line: item.Line, Column: item.Column}
+				walk(item, child)
+			}
+		}
+	}
+	walk(root, "")
+
+	return index
+}
+
+// position returns where path appears in file, falling back to the
+// nearest enclosing entry that was found. An empty file searches every
+// source file in load order. Policies not read by Load return the zero
+// Position.
+func (p *Policy) position(file, path string) Position {
+	for path != "" {
+		for _, index := range p.positions {
+			if file != "" && index.file != file {
+				continue
+			}
+			if pos, ok := index.paths[path]; ok {
+				return pos
+			}
+		}
+
+		cut := strings.LastIndexAny(path, ".[")
+		if cut < 0 {
+			break
+		}
+		path = path[:cut]
+	}
+	return Position{}
+}
+
+// rolePosition locates a path under a role definition
+func (p *Policy) rolePosition(name, sub string) Position {
+	return p.position(p.roleOrigin(name), "roles."+name+sub)
+}
+
+// groupPosition locates a path under a group definition
+func (p *Policy) groupPosition(name, sub string) Position {
+	return p.position(p.groupOrigin(name), "groups."+name+sub)
+}
+
+// serviceAccountPosition locates a path under a service account definition
+func (p *Policy) serviceAccountPosition(email, sub string) Position {
+	return p.position(p.serviceAccountOrigin(email), "serviceAccounts."+email+sub)
+}
+
+// bindingPosition locates a path under a project binding, translating the
+// merged binding index to the index in its source file
+func (p *Policy) bindingPosition(project string, i int, sub string) Position {
+	file, index := p.bindingOrigin(project, i)
+	return p.position(file, fmt.Sprintf("projects.%s.bindings[%d]%s", project, index, sub))
+}
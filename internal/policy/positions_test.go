@@ -0,0 +1,114 @@
+package policy
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// findingAt returns the finding whose message contains substr
+func findingAt(t *testing.T, result *ValidationResult, substr string) Finding {
+	t.Helper()
+	for _, f := range result.Findings {
+		if strings.Contains(f.Message, substr) {
+			return f
+		}
+	}
+	t.Fatalf("no finding containing %q in %+v", substr, result.Findings)
+	return Finding{}
+}
+
+func TestValidatePositions(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"policy.yaml": `roles:
+  roles/custom.dev:
+    permissions:
+      - secretmanager.secrets.get
+      - secretmanager.get
+projects:
+  test-project:
+    bindings:
+      - role: roles/custom.missing
+        members:
+          - user:alice@example.com
+          - alice@example.com
+`,
+	})
+	path := filepath.Join(dir, "policy.yaml")
+
+	pol, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	result := Validate(pol)
+
+	tests := []struct {
+		substr string
+		want   Position
+	}{
+		{"invalid permission format: secretmanager.get", Position{File: path, Line: 5, Column: 9}},
+		{"undefined role roles/custom.missing", Position{File: path, Line: 9, Column: 9}},
+		{"invalid principal format: alice@example.com", Position{File: path, Line: 12, Column: 13}},
+	}
+	for _, tt := range tests {
+		if got := findingAt(t, result, tt.substr).Position; got != tt.want {
+			t.Errorf("%s: position = %v, want %v", tt.substr, got, tt.want)
+		}
+	}
+
+	f := findingAt(t, result, "undefined role")
+	if want := path + ":9:9: Project test-project binding 0: undefined role roles/custom.missing"; f.String() != want {
+		t.Errorf("String() = %q, want %q", f.String(), want)
+	}
+}
+
+func TestValidatePositionsFromIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"policy.yaml": `includes: [team.yaml]
+roles:
+  roles/custom.dev:
+    permissions: [secretmanager.secrets.get]
+projects:
+  test-project:
+    bindings:
+      - role: roles/custom.dev
+        members: [user:alice@example.com]
+`,
+		"team.yaml": `projects:
+  test-project:
+    bindings:
+      - role: roles/custom.dev
+        members: [user:bob@example.com]
+      - role: roles/custom.other
+        members: [user:bob@example.com]
+`,
+	})
+
+	pol, err := Load(filepath.Join(dir, "policy.yaml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	result := Validate(pol)
+
+	// The merged binding index is translated back to team.yaml's own
+	f := findingAt(t, result, "undefined role roles/custom.other")
+	want := Position{File: filepath.Join(dir, "team.yaml"), Line: 6, Column: 9}
+	if f.Position != want {
+		t.Errorf("position = %v, want %v", f.Position, want)
+	}
+	if f.Message != "Project test-project binding 1: undefined role roles/custom.other" {
+		t.Errorf("message = %q, want the file prefix dropped", f.Message)
+	}
+}
+
+func TestValidatePositionsWithoutSource(t *testing.T) {
+	pol := simulatePolicy()
+	pol.Roles["roles/custom.bad"] = Role{Permissions: []string{"bad"}}
+
+	f := findingAt(t, Validate(pol), "Role roles/custom.bad")
+	if f.IsValid() {
+		t.Errorf("position = %v, want none for a policy not read from a file", f.Position)
+	}
+}
@@ -0,0 +1,214 @@
+package policy
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Kinds of redundant grant reported by FindRedundancies
+const (
+	// RedundantRepeat is a member granted the same role, with the same
+	// condition, by more than one binding
+	RedundantRepeat = "repeated"
+
+	// RedundantCondition is a conditional grant of a role the member also
+	// holds unconditionally, so the condition never matters
+	RedundantCondition = "shadowed-condition"
+
+	// RedundantSubset is a grant of a role whose permissions are a strict
+	// subset of another role the member holds unconditionally
+	RedundantSubset = "subset-role"
+)
+
+// Redundancy is a member's grant through a binding that can be deleted
+// without changing what the policy allows, because of another binding
+type Redundancy struct {
+	Kind   string `json:"kind"`
+	Member string `json:"member"`
+
+	// Scope and Index locate the redundant binding; Scope is projects/<id>,
+	// a resource under a project, folders/<name>, or organizations/<name>
+	Scope string `json:"scope"`
+	Index int    `json:"binding"`
+	Role  string `json:"role"`
+
+	// CoveredBy is the binding that makes the grant redundant
+	CoveredBy ScopedBinding `json:"coveredBy"`
+
+	Message string `json:"message"`
+}
+
+// FindRedundancies reports member grants made unnecessary by another
+// binding on the same node or one it inherits from. Each node's own
+// bindings are checked against its own and its ancestors' bindings, so a
+// redundancy is reported at the binding that should be deleted. Resource
+// bindings are also checked against their project's effective bindings.
+// Members are compared as written; grants reached through groups are
+// not expanded.
+func FindRedundancies(policy *Policy) []Redundancy {
+	var found []Redundancy
+
+	inherited := func(parent string) []ScopedBinding {
+		var bindings []ScopedBinding
+		for _, scope := range Ancestors(policy, parent) {
+			bindings = append(bindings, scopedBindings(scope, policy.scopeBindings(scope))...)
+		}
+		return bindings
+	}
+
+	for _, name := range sortedKeys(policy.Projects) {
+		project := policy.Projects[name]
+		scope := "projects/" + name
+		own := scopedBindings(scope, project.Bindings)
+		found = append(found, redundancies(policy, own, append(own, inherited(project.Parent)...))...)
+
+		effective := EffectiveBindings(policy, name)
+		for _, resource := range sortedKeys(project.Resources) {
+			own := scopedBindings(scope+"/"+resource, project.Resources[resource].Bindings)
+			found = append(found, redundancies(policy, own, append(own, effective...))...)
+		}
+	}
+	for _, name := range sortedKeys(policy.Folders) {
+		scope := "folders/" + name
+		own := scopedBindings(scope, policy.Folders[name].Bindings)
+		found = append(found, redundancies(policy, own, append(own, inherited(policy.Folders[name].Parent)...))...)
+	}
+	for _, name := range sortedKeys(policy.Organizations) {
+		own := scopedBindings("organizations/"+name, policy.Organizations[name].Bindings)
+		found = append(found, redundancies(policy, own, own)...)
+	}
+
+	return found
+}
+
+// scopedBindings attaches a scope to a node's bindings
+func scopedBindings(scope string, bindings []Binding) []ScopedBinding {
+	scoped := make([]ScopedBinding, 0, len(bindings))
+	for i, binding := range bindings {
+		scoped = append(scoped, ScopedBinding{Scope: scope, Index: i, Binding: binding})
+	}
+	return scoped
+}
+
+// redundancies checks each member of each candidate binding against the
+// coverers, reporting at most one redundancy per member grant. Coverers
+// include the candidates themselves; of two repeated grants on the same
+// node, the later one is reported.
+func redundancies(policy *Policy, candidates, coverers []ScopedBinding) []Redundancy {
+	var found []Redundancy
+
+	for _, candidate := range candidates {
+		for _, member := range dedupe(candidate.Binding.Members) {
+			if r, ok := findCover(policy, candidate, member, coverers); ok {
+				found = append(found, r)
+			}
+		}
+	}
+
+	return found
+}
+
+// findCover returns the first reason the member's grant through candidate
+// is redundant
+func findCover(policy *Policy, candidate ScopedBinding, member string, coverers []ScopedBinding) (Redundancy, bool) {
+	b := candidate.Binding
+	loc := fmt.Sprintf("%s binding %d", candidate.Scope, candidate.Index)
+	redundancy := func(kind string, cover ScopedBinding, message string) (Redundancy, bool) {
+		return Redundancy{
+			Kind:      kind,
+			Member:    member,
+			Scope:     candidate.Scope,
+			Index:     candidate.Index,
+			Role:      b.Role,
+			CoveredBy: cover,
+			Message:   message,
+		}, true
+	}
+	coverLoc := func(cover ScopedBinding) string {
+		return fmt.Sprintf("%s binding %d", cover.Scope, cover.Index)
+	}
+
+	for _, cover := range coverers {
+		c := cover.Binding
+		if !slices.Contains(c.Members, member) || c.Role != b.Role || !sameCondition(b.Condition, c.Condition) {
+			continue
+		}
+		// Only the later of two repeats on the same node is redundant
+		if cover.Scope == candidate.Scope && cover.Index >= candidate.Index {
+			continue
+		}
+		return redundancy(RedundantRepeat, cover, fmt.Sprintf("%s is already granted %s%s by %s; remove %s from %s",
+			member, b.Role, conditionLabel(b.Condition), coverLoc(cover), member, loc))
+	}
+
+	if b.Condition != nil {
+		for _, cover := range coverers {
+			c := cover.Binding
+			if c.Condition == nil && c.Role == b.Role && slices.Contains(c.Members, member) {
+				return redundancy(RedundantCondition, cover, fmt.Sprintf("%s is granted %s unconditionally by %s, so the condition on %s never matters; remove %s from that binding",
+					member, b.Role, coverLoc(cover), loc, member))
+			}
+		}
+	}
+
+	perms := RolePermissions(policy, b.Role)
+	if len(perms) == 0 {
+		return Redundancy{}, false
+	}
+	for _, cover := range coverers {
+		c := cover.Binding
+		if c.Condition != nil || c.Role == b.Role || !slices.Contains(c.Members, member) {
+			continue
+		}
+		if superset := RolePermissions(policy, c.Role); isStrictSubset(perms, superset) {
+			return redundancy(RedundantSubset, cover, fmt.Sprintf("%s's permissions are a subset of %s, which %s holds unconditionally through %s; remove %s from %s",
+				b.Role, c.Role, member, coverLoc(cover), member, loc))
+		}
+	}
+
+	return Redundancy{}, false
+}
+
+// sameCondition reports whether two bindings have the same condition
+// expression, or are both unconditional
+func sameCondition(a, b *Condition) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Expression == b.Expression
+}
+
+// conditionLabel formats a condition for redundancy messages
+func conditionLabel(c *Condition) string {
+	if c == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (when %s)", c.Expression)
+}
+
+// isStrictSubset reports whether sorted list a is a strict subset of
+// sorted list b
+func isStrictSubset(a, b []string) bool {
+	if len(a) >= len(b) {
+		return false
+	}
+	for _, v := range a {
+		if _, found := slices.BinarySearch(b, v); !found {
+			return false
+		}
+	}
+	return true
+}
+
+// dedupe returns values without repeats, in first-seen order
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var out []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,67 @@
+package policy
+
+import (
+	"testing"
+)
+
+func TestFindRedundancies(t *testing.T) {
+	devCondition := &Condition{Expression: `resource.name.startsWith("projects/test-project/secrets/dev-")`}
+
+	pol := &Policy{
+		Roles: map[string]Role{
+			"roles/custom.dev":    {Permissions: []string{"secretmanager.secrets.create", "secretmanager.secrets.get"}},
+			"roles/custom.reader": {Permissions: []string{"secretmanager.secrets.get"}},
+		},
+		Organizations: map[string]Organization{
+			"acme": {
+				Bindings: []Binding{{Role: "roles/custom.reader", Members: []string{"user:carol@example.com"}}},
+			},
+		},
+		Projects: map[string]Project{
+			"test-project": {
+				Parent: "organizations/acme",
+				Bindings: []Binding{
+					{Role: "roles/custom.dev", Members: []string{"group:developers"}},
+					{Role: "roles/custom.dev", Members: []string{"group:developers"}, Condition: devCondition},
+					{Role: "roles/custom.reader", Members: []string{"group:developers", "user:bob@example.com"}},
+					{Role: "roles/custom.dev", Members: []string{"group:developers"}},
+					{Role: "roles/custom.reader", Members: []string{"user:carol@example.com"}},
+					// A conditional superset does not make the reader grant redundant
+					{Role: "roles/custom.dev", Members: []string{"user:bob@example.com"}, Condition: devCondition},
+				},
+			},
+		},
+	}
+
+	want := []struct {
+		kind   string
+		member string
+		scope  string
+		index  int
+		cover  string
+	}{
+		{RedundantCondition, "group:developers", "projects/test-project", 1, "projects/test-project"},
+		{RedundantSubset, "group:developers", "projects/test-project", 2, "projects/test-project"},
+		{RedundantRepeat, "group:developers", "projects/test-project", 3, "projects/test-project"},
+		{RedundantRepeat, "user:carol@example.com", "projects/test-project", 4, "organizations/acme"},
+	}
+
+	got := FindRedundancies(pol)
+	if len(got) != len(want) {
+		t.Fatalf("FindRedundancies() returned %d results, want %d: %+v", len(got), len(want), got)
+	}
+
+	for i, w := range want {
+		r := got[i]
+		if r.Kind != w.kind || r.Member != w.member || r.Scope != w.scope || r.Index != w.index || r.CoveredBy.Scope != w.cover {
+			t.Errorf("result %d = %s %s at %s #%d (covered by %s), want %s %s at %s #%d (covered by %s)",
+				i, r.Kind, r.Member, r.Scope, r.Index, r.CoveredBy.Scope, w.kind, w.member, w.scope, w.index, w.cover)
+		}
+	}
+}
+
+func TestFindRedundanciesNone(t *testing.T) {
+	if got := FindRedundancies(simulatePolicy()); len(got) != 0 {
+		t.Errorf("Expected no redundancies, got %+v", got)
+	}
+}
@@ -0,0 +1,268 @@
+package policy
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// MemberMatches returns the binding members that apply to principal: the
+// principal itself, every group:NAME that contains it (directly or through
+// nested groups), and allUsers/allAuthenticatedUsers. The value for each
+// member is the chain of groups explaining the match, empty for direct matches.
+func MemberMatches(policy *Policy, principal string) map[string][]string {
+	matches := map[string][]string{
+		principal:  nil,
+		"allUsers": nil,
+	}
+	if principal != "allUsers" {
+		matches["allAuthenticatedUsers"] = nil
+	}
+
+	// Walk groups outward from the principal until no new groups are found.
+	// Each pass finds groups whose members include an already-matched member.
+	for changed := true; changed; {
+		changed = false
+		for _, groupName := range sortedKeys(policy.Groups) {
+			member := "group:" + groupName
+			if _, ok := matches[member]; ok {
+				continue
+			}
+			for _, m := range policy.Groups[groupName].Members {
+				if m == "allUsers" || m == "allAuthenticatedUsers" {
+					continue
+				}
+				if chain, ok := matches[m]; ok {
+					matches[member] = append(append([]string{}, chain...), groupName)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	return matches
+}
+
+// MentionsPrincipal reports whether principal appears in the policy: as
+// a member of a binding or group, or as a declared service account
+func MentionsPrincipal(policy *Policy, principal string) bool {
+	if email, ok := strings.CutPrefix(principal, "serviceAccount:"); ok {
+		if _, declared := policy.ServiceAccounts[email]; declared {
+			return true
+		}
+	}
+	for _, chain := range MemberMatches(policy, principal) {
+		if len(chain) > 0 {
+			return true
+		}
+	}
+	for _, binding := range allScopedBindings(policy) {
+		if slices.Contains(binding.Binding.Members, principal) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandMembers flattens a list of binding members into individual
+// principals by expanding group:NAME references, including nested groups.
+// Each result maps the principal to the group chain it was reached through,
+// outermost first.
+func ExpandMembers(policy *Policy, members []string) map[string][]string {
+	expanded := make(map[string][]string)
+	visiting := make(map[string]bool)
+
+	var expand func(member string, chain []string)
+	expand = func(member string, chain []string) {
+		name, isGroup := strings.CutPrefix(member, "group:")
+		if !isGroup {
+			if _, seen := expanded[member]; !seen {
+				expanded[member] = chain
+			}
+			return
+		}
+
+		// Cycles are reported by the validator; stop expanding here
+		if visiting[name] {
+			return
+		}
+		visiting[name] = true
+		defer delete(visiting, name)
+
+		for _, m := range policy.Groups[name].Members {
+			expand(m, append(append([]string{}, chain...), name))
+		}
+	}
+
+	for _, member := range members {
+		expand(member, nil)
+	}
+
+	return expanded
+}
+
+// RolePermissions returns the permissions granted by a role, sorted, with
+// the permissions of roles listed under includeRoles merged in. Roles
+// defined in the policy take precedence over the built-in catalog; roles
+// found in neither return nil.
+func RolePermissions(policy *Policy, role string) []string {
+	def, ok := policy.Roles[role]
+	if !ok {
+		perms, _ := ResolveRole(role)
+		return perms
+	}
+
+	if len(def.IncludeRoles) == 0 {
+		perms := append([]string{}, def.Permissions...)
+		sort.Strings(perms)
+		return perms
+	}
+
+	perms := make(map[string]bool)
+	visiting := make(map[string]bool)
+
+	// Cycles are reported by the validator; stop resolving here
+	var collect func(name string)
+	collect = func(name string) {
+		def, ok := policy.Roles[name]
+		if !ok {
+			builtin, _ := ResolveRole(name)
+			for _, perm := range builtin {
+				perms[perm] = true
+			}
+			return
+		}
+		if visiting[name] {
+			return
+		}
+		visiting[name] = true
+
+		for _, perm := range def.Permissions {
+			perms[perm] = true
+		}
+		for _, included := range def.IncludeRoles {
+			collect(included)
+		}
+	}
+	collect(role)
+
+	return sortedKeys(perms)
+}
+
+// roleGrants reports whether role includes permission
+func roleGrants(policy *Policy, role, permission string) bool {
+	for _, perm := range RolePermissions(policy, role) {
+		if perm == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupMembers returns the individual principals in a group, including
+// members of nested groups, sorted
+func GroupMembers(policy *Policy, name string) []string {
+	return sortedKeys(ExpandMembers(policy, []string{"group:" + name}))
+}
+
+// GroupCycles returns every group membership cycle in the policy. Each
+// cycle is a path that starts and ends with the same group, rotated to
+// begin at its alphabetically first group so it is reported once.
+func GroupCycles(policy *Policy) [][]string {
+	return findCycles(sortedKeys(policy.Groups), func(name string) []string {
+		var nested []string
+		for _, member := range policy.Groups[name].Members {
+			if group, ok := strings.CutPrefix(member, "group:"); ok {
+				if _, defined := policy.Groups[group]; defined {
+					nested = append(nested, group)
+				}
+			}
+		}
+		return nested
+	})
+}
+
+// RoleCycles returns every includeRoles cycle among the policy's custom
+// roles, in the same form as GroupCycles
+func RoleCycles(policy *Policy) [][]string {
+	return findCycles(sortedKeys(policy.Roles), func(name string) []string {
+		var included []string
+		for _, role := range policy.Roles[name].IncludeRoles {
+			if _, defined := policy.Roles[role]; defined {
+				included = append(included, role)
+			}
+		}
+		return included
+	})
+}
+
+// findCycles returns each distinct cycle in the graph formed by nodes and
+// edges, in canonical form
+func findCycles(nodes []string, edges func(string) []string) [][]string {
+	var cycles [][]string
+	seen := make(map[string]bool)
+
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := make(map[string]int)
+	var stack []string
+
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = inProgress
+		stack = append(stack, name)
+
+		for _, next := range edges(name) {
+			switch state[next] {
+			case unvisited:
+				visit(next)
+			case inProgress:
+				// Back edge: the cycle is the stack from next onward
+				start := 0
+				for i, s := range stack {
+					if s == next {
+						start = i
+						break
+					}
+				}
+				cycle := canonicalCycle(stack[start:])
+				key := strings.Join(cycle, "\x00")
+				if !seen[key] {
+					seen[key] = true
+					cycles = append(cycles, cycle)
+				}
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[name] = done
+	}
+
+	for _, name := range nodes {
+		if state[name] == unvisited {
+			visit(name)
+		}
+	}
+
+	return cycles
+}
+
+// canonicalCycle rotates a cycle to start at its smallest element and
+// closes it by repeating that element at the end
+func canonicalCycle(path []string) []string {
+	minIdx := 0
+	for i, name := range path {
+		if name < path[minIdx] {
+			minIdx = i
+		}
+	}
+
+	cycle := make([]string, 0, len(path)+1)
+	cycle = append(cycle, path[minIdx:]...)
+	cycle = append(cycle, path[:minIdx]...)
+	return append(cycle, path[minIdx])
+}
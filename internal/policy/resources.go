@@ -0,0 +1,56 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// resourceNamePattern matches the resources that accept their own IAM
+// bindings: secrets, key rings, and crypto keys. Key rings may be written
+// with or without their locations/<location>/ prefix.
+var resourceNamePattern = regexp.MustCompile(`^(secrets/[^/]+|(locations/[^/]+/)?keyRings/[^/]+(/cryptoKeys/[^/]+)?)$`)
+
+// validateResourceName checks a project-relative resource name
+func validateResourceName(name string) error {
+	if !resourceNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid resource name %s (expected secrets/<id>, keyRings/<ring>, or keyRings/<ring>/cryptoKeys/<key>, optionally under locations/<location>/)", name)
+	}
+	return nil
+}
+
+// ResourceBindings returns the bindings set on resources in the project
+// that cover resource: the resource itself or one of its parents, such as
+// the secret for one of its versions. Scope is the full resource name.
+func ResourceBindings(policy *Policy, project, resource string) []ScopedBinding {
+	relative, ok := strings.CutPrefix(resource, "projects/"+project+"/")
+	if !ok {
+		return nil
+	}
+
+	var bindings []ScopedBinding
+	resources := policy.Projects[project].Resources
+	for _, name := range sortedKeys(resources) {
+		if !resourceCovers(name, relative) {
+			continue
+		}
+		for i, binding := range resources[name].Bindings {
+			bindings = append(bindings, ScopedBinding{Scope: "projects/" + project + "/" + name, Index: i, Binding: binding})
+		}
+	}
+
+	return bindings
+}
+
+// resourceCovers reports whether bindings on the named resource apply to
+// relative. A key ring named without a location matches it in any location.
+func resourceCovers(name, relative string) bool {
+	if strings.HasPrefix(name, "keyRings/") {
+		if rest, ok := strings.CutPrefix(relative, "locations/"); ok {
+			if _, after, found := strings.Cut(rest, "/"); found {
+				relative = after
+			}
+		}
+	}
+	return relative == name || strings.HasPrefix(relative, name+"/")
+}
@@ -0,0 +1,173 @@
+package policy
+
+import (
+	"testing"
+)
+
+func resourcePolicy() *Policy {
+	pol := simulatePolicy()
+	project := pol.Projects["test-project"]
+	project.Resources = map[string]Resource{
+		"secrets/db-password": {
+			Bindings: []Binding{{Role: "roles/custom.ciRunner", Members: []string{"user:bob@example.com"}}},
+		},
+		"keyRings/main": {
+			Bindings: []Binding{{Role: "roles/cloudkms.cryptoKeyEncrypterDecrypter", Members: []string{"user:carol@example.com"}}},
+		},
+	}
+	pol.Projects["test-project"] = project
+	return pol
+}
+
+func TestSimulateResourceBindings(t *testing.T) {
+	pol := resourcePolicy()
+
+	tests := []struct {
+		name        string
+		req         SimulateRequest
+		wantAllowed bool
+		wantScope   string
+	}{
+		{
+			name: "resource binding grants access to the resource",
+			req: SimulateRequest{
+				Principal:  "user:bob@example.com",
+				Permission: "secretmanager.secrets.get",
+				Resource:   "projects/test-project/secrets/db-password",
+			},
+			wantAllowed: true,
+			wantScope:   "projects/test-project/secrets/db-password",
+		},
+		{
+			name: "resource binding does not grant access to other resources",
+			req: SimulateRequest{
+				Principal:  "user:bob@example.com",
+				Permission: "secretmanager.secrets.get",
+				Resource:   "projects/test-project/secrets/api-key",
+			},
+		},
+		{
+			name: "key ring binding covers keys in any location",
+			req: SimulateRequest{
+				Principal:  "user:carol@example.com",
+				Permission: "cloudkms.cryptoKeyVersions.useToEncrypt",
+				Resource:   "projects/test-project/locations/us/keyRings/main/cryptoKeys/app-key",
+			},
+			wantAllowed: true,
+			wantScope:   "projects/test-project/keyRings/main",
+		},
+		{
+			name: "prefix of a resource name is not its parent",
+			req: SimulateRequest{
+				Principal:  "user:carol@example.com",
+				Permission: "cloudkms.cryptoKeyVersions.useToEncrypt",
+				Resource:   "projects/test-project/locations/global/keyRings/main-backup/cryptoKeys/app-key",
+			},
+		},
+		{
+			name: "project binding still grants access",
+			req: SimulateRequest{
+				Principal:  "user:alice@example.com",
+				Permission: "secretmanager.secrets.get",
+				Resource:   "projects/test-project/secrets/db-password",
+			},
+			wantAllowed: true,
+			wantScope:   "projects/test-project",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := Simulate(pol, tt.req)
+			if err != nil {
+				t.Fatalf("Simulate() error: %v", err)
+			}
+			if decision.Allowed != tt.wantAllowed {
+				t.Errorf("Allowed = %v, want %v", decision.Allowed, tt.wantAllowed)
+			}
+			if tt.wantScope != "" && (len(decision.Matches) == 0 || decision.Matches[0].Scope != tt.wantScope) {
+				t.Errorf("Expected match in %s, got %+v", tt.wantScope, decision.Matches)
+			}
+		})
+	}
+}
+
+func TestPrincipalsWithPermissionResourceBindings(t *testing.T) {
+	var found *Holder
+	holders := PrincipalsWithPermission(resourcePolicy(), "secretmanager.secrets.get", "test-project")
+	for i, h := range holders {
+		if h.Principal == "user:bob@example.com" {
+			found = &holders[i]
+		}
+	}
+
+	if found == nil {
+		t.Fatalf("Expected bob, granted on a secret, among %+v", holders)
+	}
+	if found.Scope != "projects/test-project/secrets/db-password" || found.Index != 0 {
+		t.Errorf("bob's binding = %s #%d, want the secret's binding 0", found.Scope, found.Index)
+	}
+}
+
+func TestValidateResourceNames(t *testing.T) {
+	valid := []string{
+		"secrets/db-password",
+		"keyRings/main",
+		"keyRings/main/cryptoKeys/app-key",
+		"locations/us-east1/keyRings/main/cryptoKeys/app-key",
+	}
+	for _, name := range valid {
+		if err := validateResourceName(name); err != nil {
+			t.Errorf("validateResourceName(%q) unexpected error: %v", name, err)
+		}
+	}
+
+	invalid := []string{
+		"secret/db-password",
+		"secrets/db-password/versions/1",
+		"keyRings/main/keys/app-key",
+		"projects/test-project/secrets/db",
+	}
+	for _, name := range invalid {
+		if err := validateResourceName(name); err == nil {
+			t.Errorf("validateResourceName(%q) expected error", name)
+		}
+	}
+
+	pol := resourcePolicy()
+	pol.Projects["test-project"].Resources["secret/typo"] = Resource{
+		Bindings: []Binding{{Role: "roles/custom.undefined", Members: []string{"user:bob@example.com"}}},
+	}
+
+	result := Validate(pol)
+	if !hasError(result, "Project test-project: invalid resource name secret/typo") {
+		t.Errorf("Expected invalid resource name error, got %v", result.Errors)
+	}
+	if !hasError(result, "Project test-project resource secret/typo binding 0") {
+		t.Errorf("Expected resource binding to be validated, got %v", result.Errors)
+	}
+}
+
+func TestExportResourceGCP(t *testing.T) {
+	pol := resourcePolicy()
+
+	out, _, err := ExportResourceGCP(pol, "test-project", "secrets/db-password", ExportOptions{})
+	if err != nil {
+		t.Fatalf("ExportResourceGCP() error: %v", err)
+	}
+	if len(out.Bindings) != 1 || out.Bindings[0].Role != "projects/test-project/roles/custom.ciRunner" {
+		t.Errorf("Unexpected resource policy: %+v", out.Bindings)
+	}
+
+	project, _, err := ExportGCP(pol, "test-project", ExportOptions{})
+	if err != nil {
+		t.Fatalf("ExportGCP() error: %v", err)
+	}
+	if len(project.Bindings) != 2 {
+		t.Errorf("Expected resource bindings left out of the project policy, got %d bindings", len(project.Bindings))
+	}
+
+	if _, _, err := ExportResourceGCP(pol, "test-project", "secrets/missing", ExportOptions{}); err == nil {
+		t.Error("Expected error for undefined resource")
+	}
+}
@@ -0,0 +1,166 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	celast "github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+)
+
+// neverTrueReason runs a best-effort static check for conditions that can
+// never be true: literal false, resource.name prefixes and suffixes that
+// name a project other than the one the binding is attached to, and
+// request.time upper bounds already in the past. It returns an explanation,
+// or "" when the condition may be satisfiable. project is "" for bindings
+// not attached to a project. Conditions that fail to compile return "".
+func neverTrueReason(expression, project string, now time.Time) string {
+	env, err := getConditionEnv()
+	if err != nil {
+		return ""
+	}
+	compiled, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return ""
+	}
+
+	return unsatisfiable(celast.NavigateAST(compiled.NativeRep()), project, now)
+}
+
+// unsatisfiable explains why expr can never be true, or returns ""
+func unsatisfiable(expr celast.Expr, project string, now time.Time) string {
+	switch expr.Kind() {
+	case celast.LiteralKind:
+		if v, ok := expr.AsLiteral().Value().(bool); ok && !v {
+			return "it is the literal false"
+		}
+
+	case celast.CallKind:
+		call := expr.AsCall()
+		args := call.Args()
+
+		switch call.FunctionName() {
+		case operators.LogicalAnd:
+			// A conjunction fails if any part always fails
+			for _, arg := range args {
+				if reason := unsatisfiable(arg, project, now); reason != "" {
+					return reason
+				}
+			}
+
+		case operators.LogicalOr:
+			// A disjunction fails only if every part always fails
+			var reasons []string
+			for _, arg := range args {
+				reason := unsatisfiable(arg, project, now)
+				if reason == "" {
+					return ""
+				}
+				reasons = append(reasons, reason)
+			}
+			return strings.Join(reasons, "; and ")
+
+		case "startsWith", "endsWith":
+			if project == "" || !call.IsMemberFunction() || len(args) != 1 || !isAttribute(call.Target(), "resource", "name") {
+				return ""
+			}
+			value, ok := stringLiteral(args[0])
+			if !ok {
+				return ""
+			}
+			if call.FunctionName() == "startsWith" {
+				return prefixReason(value, project)
+			}
+			return suffixReason(value, project)
+
+		case operators.Less, operators.LessEquals:
+			// request.time < timestamp("...")
+			if len(args) == 2 && isAttribute(args[0], "request", "time") {
+				return pastDeadlineReason(args[1], now)
+			}
+
+		case operators.Greater, operators.GreaterEquals:
+			// timestamp("...") > request.time
+			if len(args) == 2 && isAttribute(args[1], "request", "time") {
+				return pastDeadlineReason(args[0], now)
+			}
+		}
+	}
+
+	return ""
+}
+
+// prefixReason explains a resource.name prefix that cannot match resources
+// in project
+func prefixReason(prefix, project string) string {
+	base := "projects/" + project + "/"
+	if strings.HasPrefix(prefix, base) || strings.HasPrefix(base, prefix) {
+		return ""
+	}
+	return fmt.Sprintf("resource.name always starts with %q in project %s, so it can never start with %q", "projects/"+project, project, prefix)
+}
+
+// suffixReason explains a resource.name suffix that names a complete
+// projects/<id> segment for a different project
+func suffixReason(suffix, project string) string {
+	i := strings.Index(suffix, "projects/")
+	if i < 0 || (i > 0 && suffix[i-1] != '/') {
+		return ""
+	}
+	id, _, _ := strings.Cut(suffix[i+len("projects/"):], "/")
+	if id == "" || id == project {
+		return ""
+	}
+	return fmt.Sprintf("resource names in project %s never contain \"projects/%s\", so they can never end with %q", project, id, suffix)
+}
+
+// pastDeadlineReason explains a request.time upper bound that has passed
+func pastDeadlineReason(bound celast.Expr, now time.Time) string {
+	if bound.Kind() != celast.CallKind || bound.AsCall().FunctionName() != "timestamp" || len(bound.AsCall().Args()) != 1 {
+		return ""
+	}
+	value, ok := stringLiteral(bound.AsCall().Args()[0])
+	if !ok {
+		return ""
+	}
+	deadline, err := time.Parse(time.RFC3339, value)
+	if err != nil || deadline.After(now) {
+		return ""
+	}
+	return fmt.Sprintf("it requires request.time before %s, which is in the past", value)
+}
+
+// isAttribute reports whether expr is root.field or root["field"]
+func isAttribute(expr celast.Expr, root, field string) bool {
+	var operand celast.Expr
+	var name string
+
+	switch expr.Kind() {
+	case celast.SelectKind:
+		operand, name = expr.AsSelect().Operand(), expr.AsSelect().FieldName()
+	case celast.CallKind:
+		call := expr.AsCall()
+		if call.FunctionName() != operators.Index || len(call.Args()) != 2 {
+			return false
+		}
+		key, ok := stringLiteral(call.Args()[1])
+		if !ok {
+			return false
+		}
+		operand, name = call.Args()[0], key
+	default:
+		return false
+	}
+
+	return name == field && operand.Kind() == celast.IdentKind && operand.AsIdent() == root
+}
+
+// stringLiteral returns the value of a string literal expression
+func stringLiteral(expr celast.Expr) (string, bool) {
+	if expr.Kind() != celast.LiteralKind {
+		return "", false
+	}
+	s, ok := expr.AsLiteral().Value().(string)
+	return s, ok
+}
@@ -0,0 +1,128 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNeverTrueReason(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		expression string
+		project    string
+		want       string
+	}{
+		{
+			name:       "literal false",
+			expression: `false`,
+			project:    "test-project",
+			want:       "literal false",
+		},
+		{
+			name:       "false conjunct",
+			expression: `resource.name.startsWith("projects/test-project/") && false`,
+			project:    "test-project",
+			want:       "literal false",
+		},
+		{
+			name:       "prefix for another project",
+			expression: `resource.name.startsWith("projects/prod-")`,
+			project:    "test-project",
+			want:       `can never start with "projects/prod-"`,
+		},
+		{
+			name:       "prefix for this project",
+			expression: `resource.name.startsWith("projects/test-project/secrets/prod-")`,
+			project:    "test-project",
+		},
+		{
+			name:       "partial prefix of this project",
+			expression: `resource.name.startsWith("projects/test")`,
+			project:    "test-project",
+		},
+		{
+			name:       "prefix unchecked outside a project",
+			expression: `resource.name.startsWith("projects/prod-")`,
+		},
+		{
+			name:       "suffix naming another project",
+			expression: `resource.name.endsWith("projects/prod-project/secrets/db")`,
+			project:    "test-project",
+			want:       `never contain "projects/prod-project"`,
+		},
+		{
+			name:       "plain suffix",
+			expression: `resource.name.endsWith("-prod")`,
+			project:    "test-project",
+		},
+		{
+			name:       "deadline in the past",
+			expression: `request.time < timestamp("2020-01-01T00:00:00Z")`,
+			project:    "test-project",
+			want:       "in the past",
+		},
+		{
+			name:       "reversed deadline in the past",
+			expression: `timestamp("2020-01-01T00:00:00Z") >= request.time`,
+			want:       "in the past",
+		},
+		{
+			name:       "deadline in the future",
+			expression: `request.time < timestamp("2030-01-01T00:00:00Z")`,
+			project:    "test-project",
+		},
+		{
+			name:       "one satisfiable disjunct",
+			expression: `resource.name.startsWith("projects/prod-") || resource.name.startsWith("projects/test-project/")`,
+			project:    "test-project",
+		},
+		{
+			name:       "no satisfiable disjunct",
+			expression: `false || resource.name.startsWith("projects/prod-")`,
+			project:    "test-project",
+			want:       "literal false; and",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := neverTrueReason(tt.expression, tt.project, now)
+			if tt.want == "" && got != "" {
+				t.Errorf("neverTrueReason() = %q, want satisfiable", got)
+			}
+			if tt.want != "" && !strings.Contains(got, tt.want) {
+				t.Errorf("neverTrueReason() = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateNeverTrueCondition(t *testing.T) {
+	pol := &Policy{
+		Projects: map[string]Project{
+			"test-project": {
+				Bindings: []Binding{
+					{
+						Role:    "roles/viewer",
+						Members: []string{"user:alice@example.com"},
+						Condition: &Condition{
+							Expression: `resource.name.startsWith("projects/prod-")`,
+							Title:      "Prod only",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	result := ValidateWithOptions(pol, ValidateOptions{StrictLint: true})
+	if !result.Valid {
+		t.Errorf("Never-true conditions must not be errors, got: %v", result.Errors)
+	}
+	if !hasError(result, `WARNING: Project test-project binding 0: condition "Prod only" can never be true`) {
+		t.Errorf("Expected never-true warning, got: %v", result.Errors)
+	}
+}
@@ -0,0 +1,167 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrDenied reports that a simulated request was denied, as opposed to
+// a request that could not be evaluated
+var ErrDenied = errors.New("access denied")
+
+// SimulateRequest describes an authorization check to simulate
+type SimulateRequest struct {
+	Principal   string
+	Permission  string
+	Resource    string
+	RequestTime time.Time
+}
+
+// Decision is the outcome of a simulated authorization check
+type Decision struct {
+	Allowed bool           `json:"allowed"`
+	Project string         `json:"project"`
+	Matches []BindingMatch `json:"matches,omitempty"`
+
+	// Denied is set when a deny binding overrides the matched allow bindings
+	Denied  bool        `json:"denied,omitempty"`
+	Denials []DenyMatch `json:"denials,omitempty"`
+
+	// Context holds the values conditions were evaluated against
+	Context ConditionContext `json:"context"`
+}
+
+// BindingMatch is a binding whose role includes the requested permission
+// and whose members include the principal
+type BindingMatch struct {
+	// Scope is the project, folder, or organization the binding is attached to
+	Scope   string  `json:"scope"`
+	Index   int     `json:"index"`
+	Binding Binding `json:"binding"`
+
+	// Member is the binding member that matched the principal
+	Member string `json:"member"`
+
+	// Via lists the groups the principal was matched through, innermost first
+	Via []string `json:"via,omitempty"`
+
+	// Granted is false when the binding's condition evaluated to false or failed
+	Granted bool   `json:"granted"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Simulate evaluates whether the principal holds the permission on the
+// resource. Group memberships are expanded, roles resolved to permissions,
+// and conditions evaluated against the resource and request time. Bindings
+// inherited from the project's folders and organization are included, as
+// are bindings set on the resource or its parents; any one of them can grant
+// access. A matching project deny binding overrides any allow.
+func Simulate(policy *Policy, req SimulateRequest) (*Decision, error) {
+	project, err := ProjectFromResource(req.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := MemberMatches(policy, req.Principal)
+	ctx := ConditionContext{
+		ResourceName: req.Resource,
+		ResourceType: resourceType(req.Resource),
+		RequestTime:  req.RequestTime,
+	}
+
+	decision := &Decision{Project: project, Context: ctx}
+
+	bindings := append(EffectiveBindings(policy, project), ResourceBindings(policy, project, req.Resource)...)
+	for _, scoped := range bindings {
+		binding := scoped.Binding
+		if !roleGrants(policy, binding.Role, req.Permission) {
+			continue
+		}
+
+		member, via, ok := matchBindingMember(binding, matches)
+		if !ok {
+			continue
+		}
+
+		match := BindingMatch{
+			Scope:   scoped.Scope,
+			Index:   scoped.Index,
+			Binding: binding,
+			Member:  member,
+			Via:     via,
+			Granted: true,
+		}
+
+		if binding.Condition != nil {
+			ok, err := EvaluateCondition(binding.Condition.Expression, ctx)
+			switch {
+			case err != nil:
+				match.Granted = false
+				match.Reason = fmt.Sprintf("condition error: %v", err)
+			case !ok:
+				match.Granted = false
+				match.Reason = "condition evaluated to false"
+			}
+		}
+
+		if match.Granted {
+			decision.Allowed = true
+		}
+		decision.Matches = append(decision.Matches, match)
+	}
+
+	decision.Denials = evaluateDenies(policy, project, req.Permission, matches, &ctx)
+	for _, deny := range decision.Denials {
+		if deny.Applied {
+			decision.Denied = true
+			decision.Allowed = false
+		}
+	}
+
+	return decision, nil
+}
+
+// matchBindingMember returns the first binding member that applies to the
+// principal along with the group chain explaining the match
+func matchBindingMember(binding Binding, matches map[string][]string) (string, []string, bool) {
+	for _, member := range binding.Members {
+		if via, ok := matches[member]; ok {
+			return member, via, true
+		}
+	}
+	return "", nil, false
+}
+
+// ProjectFromResource extracts the project ID from a resource name of the
+// form projects/<id>/...
+func ProjectFromResource(resource string) (string, error) {
+	parts := strings.Split(resource, "/")
+	if len(parts) < 2 || parts[0] != "projects" || parts[1] == "" {
+		return "", fmt.Errorf("invalid resource name: %s (expected projects/<project>/...)", resource)
+	}
+	return parts[1], nil
+}
+
+// resourceTypes maps resource collection names to IAM resource types
+var resourceTypes = map[string]string{
+	"secrets":           "secretmanager.googleapis.com/Secret",
+	"versions":          "secretmanager.googleapis.com/SecretVersion",
+	"keyRings":          "cloudkms.googleapis.com/KeyRing",
+	"cryptoKeys":        "cloudkms.googleapis.com/CryptoKey",
+	"cryptoKeyVersions": "cloudkms.googleapis.com/CryptoKeyVersion",
+	"locations":         "cloudkms.googleapis.com/Location",
+	"projects":          "cloudresourcemanager.googleapis.com/Project",
+}
+
+// resourceType derives resource.type from the last collection in a resource name
+func resourceType(resource string) string {
+	parts := strings.Split(resource, "/")
+	for i := len(parts) - 2; i >= 0; i -= 2 {
+		if t, ok := resourceTypes[parts[i]]; ok {
+			return t
+		}
+	}
+	return ""
+}
@@ -0,0 +1,160 @@
+package policy
+
+import (
+	"testing"
+	"time"
+)
+
+func simulatePolicy() *Policy {
+	return &Policy{
+		Roles: map[string]Role{
+			"roles/custom.admin": {
+				Permissions: []string{"secretmanager.secrets.create", "secretmanager.secrets.get"},
+			},
+			"roles/custom.ciRunner": {
+				Permissions: []string{"secretmanager.secrets.get"},
+			},
+		},
+		Groups: map[string]Group{
+			"developers": {Members: []string{"user:alice@example.com"}},
+			"admins":     {Members: []string{"group:developers"}},
+		},
+		Projects: map[string]Project{
+			"test-project": {
+				Bindings: []Binding{
+					{Role: "roles/custom.admin", Members: []string{"group:admins"}},
+					{
+						Role:    "roles/custom.ciRunner",
+						Members: []string{"serviceAccount:ci@test-project.iam.gserviceaccount.com"},
+						Condition: &Condition{
+							Expression: `resource.name.startsWith("projects/test-project/secrets/prod-") && request.time < timestamp("2030-01-01T00:00:00Z")`,
+							Title:      "CI limited to production secrets",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         SimulateRequest
+		wantAllowed bool
+		wantMatches int
+	}{
+		{
+			name: "nested group grant",
+			req: SimulateRequest{
+				Principal:  "user:alice@example.com",
+				Permission: "secretmanager.secrets.create",
+				Resource:   "projects/test-project/secrets/db-password",
+			},
+			wantAllowed: true,
+			wantMatches: 1,
+		},
+		{
+			name: "permission not in role",
+			req: SimulateRequest{
+				Principal:  "user:alice@example.com",
+				Permission: "cloudkms.cryptoKeys.encrypt",
+				Resource:   "projects/test-project/secrets/db-password",
+			},
+			wantAllowed: false,
+			wantMatches: 0,
+		},
+		{
+			name: "condition true",
+			req: SimulateRequest{
+				Principal:   "serviceAccount:ci@test-project.iam.gserviceaccount.com",
+				Permission:  "secretmanager.secrets.get",
+				Resource:    "projects/test-project/secrets/prod-db",
+				RequestTime: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			wantAllowed: true,
+			wantMatches: 1,
+		},
+		{
+			name: "condition false on resource",
+			req: SimulateRequest{
+				Principal:   "serviceAccount:ci@test-project.iam.gserviceaccount.com",
+				Permission:  "secretmanager.secrets.get",
+				Resource:    "projects/test-project/secrets/dev-db",
+				RequestTime: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			wantAllowed: false,
+			wantMatches: 1,
+		},
+		{
+			name: "condition false on request time",
+			req: SimulateRequest{
+				Principal:   "serviceAccount:ci@test-project.iam.gserviceaccount.com",
+				Permission:  "secretmanager.secrets.get",
+				Resource:    "projects/test-project/secrets/prod-db",
+				RequestTime: time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			wantAllowed: false,
+			wantMatches: 1,
+		},
+		{
+			name: "unknown project",
+			req: SimulateRequest{
+				Principal:  "user:alice@example.com",
+				Permission: "secretmanager.secrets.get",
+				Resource:   "projects/other-project/secrets/db",
+			},
+			wantAllowed: false,
+			wantMatches: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := Simulate(simulatePolicy(), tt.req)
+			if err != nil {
+				t.Fatalf("Simulate() error = %v", err)
+			}
+			if decision.Allowed != tt.wantAllowed {
+				t.Errorf("Allowed = %v, want %v (matches: %+v)", decision.Allowed, tt.wantAllowed, decision.Matches)
+			}
+			if len(decision.Matches) != tt.wantMatches {
+				t.Errorf("got %d matches, want %d", len(decision.Matches), tt.wantMatches)
+			}
+		})
+	}
+}
+
+func TestSimulateVia(t *testing.T) {
+	decision, err := Simulate(simulatePolicy(), SimulateRequest{
+		Principal:  "user:alice@example.com",
+		Permission: "secretmanager.secrets.get",
+		Resource:   "projects/test-project/secrets/db-password",
+	})
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	if len(decision.Matches) != 1 {
+		t.Fatalf("Expected 1 match, got %d", len(decision.Matches))
+	}
+
+	m := decision.Matches[0]
+	if m.Member != "group:admins" {
+		t.Errorf("Member = %s, want group:admins", m.Member)
+	}
+	if len(m.Via) != 2 || m.Via[0] != "developers" || m.Via[1] != "admins" {
+		t.Errorf("Via = %v, want [developers admins]", m.Via)
+	}
+}
+
+func TestSimulateInvalidResource(t *testing.T) {
+	_, err := Simulate(simulatePolicy(), SimulateRequest{
+		Principal:  "user:alice@example.com",
+		Permission: "secretmanager.secrets.get",
+		Resource:   "secrets/db-password",
+	})
+	if err == nil {
+		t.Error("Expected error for resource without project")
+	}
+}
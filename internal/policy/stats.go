@@ -0,0 +1,117 @@
+package policy
+
+import (
+	"sort"
+)
+
+// Stats summarizes the size and shape of a policy
+type Stats struct {
+	Roles           int `json:"roles"`
+	Groups          int `json:"groups"`
+	ServiceAccounts int `json:"serviceAccounts"`
+	Projects        int `json:"projects"`
+	Folders         int `json:"folders"`
+	Organizations   int `json:"organizations"`
+
+	// Bindings counts project, folder, and organization bindings
+	Bindings              int `json:"bindings"`
+	ConditionalBindings   int `json:"conditionalBindings"`
+	UnconditionalBindings int `json:"unconditionalBindings"`
+	DenyBindings          int `json:"denyBindings"`
+
+	// UniqueMembers counts individual principals reachable from bindings
+	// once groups are expanded
+	UniqueMembers int `json:"uniqueMembers"`
+
+	// PermissionsPerRole is a histogram of custom roles by permission
+	// count, with included roles resolved
+	PermissionsPerRole []RoleSizeBucket `json:"permissionsPerRole"`
+
+	// TopPermissions lists the permissions held by the most principals
+	TopPermissions []PermissionCount `json:"topPermissions"`
+}
+
+// RoleSizeBucket counts the roles that have a given number of permissions
+type RoleSizeBucket struct {
+	Permissions int `json:"permissions"`
+	Roles       int `json:"roles"`
+}
+
+// PermissionCount is the number of distinct principals granted a permission
+type PermissionCount struct {
+	Permission string `json:"permission"`
+	Principals int    `json:"principals"`
+}
+
+// topPermissionsLimit is the number of permissions reported in TopPermissions
+const topPermissionsLimit = 10
+
+// ComputeStats traverses a policy and summarizes it. Groups are expanded so
+// a principal reached through several bindings or groups counts once.
+func ComputeStats(policy *Policy) Stats {
+	stats := Stats{
+		Roles:              len(policy.Roles),
+		Groups:             len(policy.Groups),
+		ServiceAccounts:    len(policy.ServiceAccounts),
+		Projects:           len(policy.Projects),
+		Folders:            len(policy.Folders),
+		Organizations:      len(policy.Organizations),
+		PermissionsPerRole: []RoleSizeBucket{},
+		TopPermissions:     []PermissionCount{},
+	}
+
+	members := make(map[string]bool)
+	holders := make(map[string]map[string]bool)
+
+	for _, binding := range allBindings(policy) {
+		stats.Bindings++
+		if binding.Condition != nil {
+			stats.ConditionalBindings++
+		} else {
+			stats.UnconditionalBindings++
+		}
+
+		principals := ExpandMembers(policy, binding.Members)
+		for principal := range principals {
+			members[principal] = true
+		}
+
+		for _, perm := range RolePermissions(policy, binding.Role) {
+			if holders[perm] == nil {
+				holders[perm] = make(map[string]bool)
+			}
+			for principal := range principals {
+				holders[perm][principal] = true
+			}
+		}
+	}
+	stats.UniqueMembers = len(members)
+
+	for _, project := range policy.Projects {
+		stats.DenyBindings += len(project.DenyBindings)
+	}
+
+	sizes := make(map[int]int)
+	for name := range policy.Roles {
+		sizes[len(RolePermissions(policy, name))]++
+	}
+	for _, size := range sortedKeys(sizes) {
+		stats.PermissionsPerRole = append(stats.PermissionsPerRole, RoleSizeBucket{Permissions: size, Roles: sizes[size]})
+	}
+
+	for perm, principals := range holders {
+		stats.TopPermissions = append(stats.TopPermissions, PermissionCount{Permission: perm, Principals: len(principals)})
+	}
+	sort.Slice(stats.TopPermissions, func(a, b int) bool {
+		pa, pb := stats.TopPermissions[a], stats.TopPermissions[b]
+		if pa.Principals != pb.Principals {
+			return pa.Principals > pb.Principals
+		}
+		return pa.Permission < pb.Permission
+	})
+	if len(stats.TopPermissions) > topPermissionsLimit {
+		stats.TopPermissions = stats.TopPermissions[:topPermissionsLimit]
+	}
+
+	return stats
+}
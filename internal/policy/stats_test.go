@@ -0,0 +1,53 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComputeStats(t *testing.T) {
+	stats := ComputeStats(simulatePolicy())
+
+	if stats.Roles != 2 || stats.Groups != 2 || stats.Projects != 1 {
+		t.Errorf("counts = %d roles, %d groups, %d projects", stats.Roles, stats.Groups, stats.Projects)
+	}
+	if stats.Bindings != 2 || stats.ConditionalBindings != 1 || stats.UnconditionalBindings != 1 {
+		t.Errorf("bindings = %d (%d conditional, %d unconditional)", stats.Bindings, stats.ConditionalBindings, stats.UnconditionalBindings)
+	}
+
+	// alice is reached through admins -> developers and counts once
+	if stats.UniqueMembers != 2 {
+		t.Errorf("UniqueMembers = %d, want 2", stats.UniqueMembers)
+	}
+
+	wantBuckets := []RoleSizeBucket{{Permissions: 1, Roles: 1}, {Permissions: 2, Roles: 1}}
+	if !reflect.DeepEqual(stats.PermissionsPerRole, wantBuckets) {
+		t.Errorf("PermissionsPerRole = %v, want %v", stats.PermissionsPerRole, wantBuckets)
+	}
+
+	wantTop := []PermissionCount{
+		{Permission: "secretmanager.secrets.get", Principals: 2},
+		{Permission: "secretmanager.secrets.create", Principals: 1},
+	}
+	if !reflect.DeepEqual(stats.TopPermissions, wantTop) {
+		t.Errorf("TopPermissions = %v, want %v", stats.TopPermissions, wantTop)
+	}
+}
+
+func TestComputeStatsIncludedRoles(t *testing.T) {
+	pol := &Policy{
+		Roles: map[string]Role{
+			"roles/custom.reader": {Permissions: []string{"secretmanager.secrets.get", "secretmanager.secrets.list"}},
+			"roles/custom.writer": {
+				Permissions:  []string{"secretmanager.secrets.create"},
+				IncludeRoles: []string{"roles/custom.reader"},
+			},
+		},
+	}
+
+	// writer holds its own permission and reader's two
+	want := []RoleSizeBucket{{Permissions: 2, Roles: 1}, {Permissions: 3, Roles: 1}}
+	if stats := ComputeStats(pol); !reflect.DeepEqual(stats.PermissionsPerRole, want) {
+		t.Errorf("PermissionsPerRole = %v, want %v", stats.PermissionsPerRole, want)
+	}
+}
@@ -0,0 +1,163 @@
+package policy
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UnknownKey is a key in a policy file that does not correspond to any
+// field of the policy schema, usually a misspelling that the parser
+// silently dropped
+type UnknownKey struct {
+	Position
+	Key string `json:"key"`
+
+	// Path locates the mapping the key appears in, such as
+	// projects.test-project.bindings[0].condition
+	Path string `json:"path"`
+
+	// Suggestion is the closest valid key, if one is close
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// ignoredKeyPrefix marks keys that are intentionally not part of the
+// schema, such as annotations for other tools
+const ignoredKeyPrefix = "x-"
+
+// String describes the key without its position
+func (k UnknownKey) String() string {
+	msg := fmt.Sprintf("unknown key %q", k.Key)
+	if k.Path != "" {
+		msg += " in " + k.Path
+	}
+	if k.Suggestion != "" {
+		msg += fmt.Sprintf(" (did you mean %q?)", k.Suggestion)
+	}
+	return msg
+}
+
+// UnknownKeys returns the keys in the policy's source files that the
+// schema does not define, in file order. Keys starting with x- are
+// ignored. Policies not read by Load have none.
+func (p *Policy) UnknownKeys() []UnknownKey {
+	return p.unknownKeys
+}
+
+// findUnknownKeys walks a parsed policy document against the Policy schema
+func findUnknownKeys(root *yaml.Node, file string) []UnknownKey {
+	var keys []UnknownKey
+	walkUnknownKeys(root, reflect.TypeOf(Policy{}), "", file, &keys)
+	return keys
+}
+
+// parseNodes parses data as YAML (a superset of JSON) into a node tree
+// for position tracking. Documents that do not parse return nil; parse
+// errors are reported by the regular decoder.
+func parseNodes(data []byte) *yaml.Node {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	return doc.Content[0]
+}
+
+// walkUnknownKeys records mapping keys in node with no matching field in t
+func walkUnknownKeys(node *yaml.Node, t reflect.Type, path, file string, keys *[]UnknownKey) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		fields := schemaFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "<<" || strings.HasPrefix(key.Value, ignoredKeyPrefix) {
+				continue
+			}
+
+			field, ok := fields[key.Value]
+			if !ok {
+				*keys = append(*keys, UnknownKey{
+					Position:   Position{File: file, Line: key.Line, Column: key.Column},
+					Key:        key.Value,
+					Path:       path,
+					Suggestion: suggestKey(key.Value, fields),
+				})
+				continue
+			}
+			walkUnknownKeys(value, field, joinPath(path, key.Value), file, keys)
+		}
+
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			walkUnknownKeys(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value), file, keys)
+		}
+
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, item := range node.Content {
+			walkUnknownKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), file, keys)
+		}
+	}
+}
+
+// schemaFields maps the YAML names of a struct's exported fields to their types
+func schemaFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// suggestKey returns the closest valid key within a small edit distance
+func suggestKey(key string, fields map[string]reflect.Type) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	best, bestDist := "", 3
+	for _, name := range names {
+		if d := editDistance(strings.ToLower(key), strings.ToLower(name)); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// joinPath appends a key to a dotted location
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
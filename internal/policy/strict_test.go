@@ -0,0 +1,88 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnknownKeys(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"policy.yaml": `x-owner: platform-team
+roles:
+  roles/custom.dev:
+    permissions: [secretmanager.secrets.get]
+projects:
+  test-project:
+    bindings:
+      - role: roles/custom.dev
+        members: [user:alice@example.com]
+        condition:
+          experssion: "true"
+          x-ticket: SEC-12
+`,
+		"policy.json": `{
+  "roles": {"roles/custom.dev": {"permissions": ["secretmanager.secrets.get"]}},
+  "projets": {}
+}
+`,
+	})
+
+	pol, err := Load(filepath.Join(dir, "policy.yaml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := []UnknownKey{{
+		Position:   Position{File: filepath.Join(dir, "policy.yaml"), Line: 11, Column: 11},
+		Key:        "experssion",
+		Path:       "projects.test-project.bindings[0].condition",
+		Suggestion: "expression",
+	}}
+	if got := pol.UnknownKeys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("UnknownKeys() = %+v, want %+v", got, want)
+	}
+
+	result := Validate(pol)
+	if !hasError(result, `unknown key "experssion" in projects.test-project.bindings[0].condition (did you mean "expression"?)`) {
+		t.Errorf("Expected unknown key error, got %v", result.Errors)
+	}
+
+	pol, err = Load(filepath.Join(dir, "policy.json"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	keys := pol.UnknownKeys()
+	if len(keys) != 1 || keys[0].Key != "projets" || keys[0].Line != 3 || keys[0].Suggestion != "projects" {
+		t.Errorf("UnknownKeys() = %+v", keys)
+	}
+}
+
+func TestUnknownKeysFromIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"policy.yaml": "includes: [roles.yaml]\nprojects: {}\n",
+		"roles.yaml":  "roles:\n  roles/custom.dev:\n    permisions: [secretmanager.secrets.get]\n",
+	})
+
+	pol, err := Load(filepath.Join(dir, "policy.yaml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	keys := pol.UnknownKeys()
+	if len(keys) != 1 || !strings.HasSuffix(keys[0].File, "roles.yaml") || keys[0].Key != "permisions" {
+		t.Errorf("UnknownKeys() = %+v", keys)
+	}
+}
+
+func TestUnknownKeysNone(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/policy.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := findUnknownKeys(parseNodes(data), "policy.yaml"); len(keys) != 0 {
+		t.Errorf("findUnknownKeys() = %+v, want none", keys)
+	}
+}
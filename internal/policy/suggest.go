@@ -0,0 +1,215 @@
+package policy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Ways of grouping suggested permissions into roles
+const (
+	// SuggestByPrincipal creates one role per principal
+	SuggestByPrincipal = "principal"
+
+	// SuggestByService creates one role per principal and service, such
+	// as secretmanager or cloudkms
+	SuggestByService = "service"
+)
+
+// suggestDescriptionPrefix marks roles created by Suggest, so a later run
+// extends them instead of picking a new name
+const suggestDescriptionPrefix = "Draft: permissions used by "
+
+// SuggestOptions controls how Suggest builds roles
+type SuggestOptions struct {
+	// GroupBy is SuggestByPrincipal (the default) or SuggestByService
+	GroupBy string
+}
+
+// SuggestResult summarizes the changes made by Suggest
+type SuggestResult struct {
+	RolesAdded    []string `json:"rolesAdded,omitempty"`
+	RolesExtended []string `json:"rolesExtended,omitempty"`
+	BindingsAdded []string `json:"bindingsAdded,omitempty"`
+
+	// Skipped counts requests on resources outside a project
+	Skipped int `json:"skipped,omitempty"`
+}
+
+// Empty reports whether Suggest changed nothing
+func (r *SuggestResult) Empty() bool {
+	return len(r.RolesAdded) == 0 && len(r.RolesExtended) == 0 && len(r.BindingsAdded) == 0
+}
+
+// Suggest grants each principal exactly the permissions it used in the
+// observed requests, modifying policy in place. Permissions are collected
+// into draft custom roles named after the principal (and service, when
+// grouping by service), and each role is bound to its principal on every
+// project it was used in. Roles created by an earlier run are extended
+// rather than duplicated, so Suggest can be applied to an existing policy.
+func Suggest(policy *Policy, requests []SimulateRequest, opts SuggestOptions) (*SuggestResult, error) {
+	if opts.GroupBy == "" {
+		opts.GroupBy = SuggestByPrincipal
+	}
+	if opts.GroupBy != SuggestByPrincipal && opts.GroupBy != SuggestByService {
+		return nil, fmt.Errorf("invalid grouping %q (must be %s or %s)", opts.GroupBy, SuggestByPrincipal, SuggestByService)
+	}
+
+	result := &SuggestResult{}
+
+	// Permissions, and the projects they were used in, for each role to create
+	type roleKey struct{ principal, service string }
+	perms := make(map[roleKey]map[string]bool)
+	projects := make(map[roleKey]map[string]bool)
+
+	for _, req := range requests {
+		project, err := ProjectFromResource(req.Resource)
+		if err != nil {
+			result.Skipped++
+			continue
+		}
+
+		key := roleKey{principal: req.Principal}
+		if opts.GroupBy == SuggestByService {
+			key.service, _, _ = strings.Cut(req.Permission, ".")
+		}
+		if perms[key] == nil {
+			perms[key] = make(map[string]bool)
+			projects[key] = make(map[string]bool)
+		}
+		perms[key][req.Permission] = true
+		projects[key][project] = true
+	}
+
+	keys := make([]roleKey, 0, len(perms))
+	for key := range perms {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b roleKey) int {
+		if c := strings.Compare(a.principal, b.principal); c != 0 {
+			return c
+		}
+		return strings.Compare(a.service, b.service)
+	})
+
+	if policy.Roles == nil {
+		policy.Roles = make(map[string]Role)
+	}
+	if policy.Projects == nil {
+		policy.Projects = make(map[string]Project)
+	}
+
+	for _, key := range keys {
+		description := suggestDescriptionPrefix + key.principal
+		if key.service != "" {
+			description += " in " + key.service
+		}
+
+		name, existing := suggestRoleName(policy, key.principal, key.service, description)
+		role := policy.Roles[name]
+		role.Description = description
+
+		added := false
+		for _, perm := range sortedKeys(perms[key]) {
+			if !slices.Contains(role.Permissions, perm) {
+				role.Permissions = append(role.Permissions, perm)
+				added = true
+			}
+		}
+		slices.Sort(role.Permissions)
+		policy.Roles[name] = role
+
+		switch {
+		case !existing:
+			result.RolesAdded = append(result.RolesAdded, name)
+		case added:
+			result.RolesExtended = append(result.RolesExtended, name)
+		}
+
+		for _, project := range sortedKeys(projects[key]) {
+			if addSuggestedBinding(policy, project, name, key.principal) {
+				result.BindingsAdded = append(result.BindingsAdded, fmt.Sprintf("%s: %s → %s", project, key.principal, name))
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// suggestRoleName picks the role for a principal and service: the role a
+// previous run created with the same description, or the first free name
+// derived from the principal. It reports whether the role already exists.
+func suggestRoleName(policy *Policy, principal, service, description string) (string, bool) {
+	base := "roles/custom." + principalSlug(principal)
+	if service != "" {
+		base += "." + service
+	}
+
+	for n := 1; ; n++ {
+		name := base
+		if n > 1 {
+			name = fmt.Sprintf("%s%d", base, n)
+		}
+		role, exists := policy.Roles[name]
+		if !exists {
+			return name, false
+		}
+		if role.Description == description {
+			return name, true
+		}
+	}
+}
+
+// addSuggestedBinding grants role to principal on project, adding the
+// member to an unconditional binding of the role if there is one. It
+// reports whether the policy changed.
+func addSuggestedBinding(policy *Policy, project, role, principal string) bool {
+	proj := policy.Projects[project]
+	defer func() { policy.Projects[project] = proj }()
+
+	for i, binding := range proj.Bindings {
+		if binding.Role != role || binding.Condition != nil {
+			continue
+		}
+		if slices.Contains(binding.Members, principal) {
+			return false
+		}
+		proj.Bindings[i].Members = append(proj.Bindings[i].Members, principal)
+		return true
+	}
+
+	proj.Bindings = append(proj.Bindings, Binding{Role: role, Members: []string{principal}})
+	return true
+}
+
+// principalSlug turns a principal into a lower camel case role name part:
+// user:ci-runner@example.com becomes ciRunner
+func principalSlug(principal string) string {
+	_, identifier, found := strings.Cut(principal, ":")
+	if !found {
+		identifier = principal
+	}
+	identifier, _, _ = strings.Cut(identifier, "@")
+
+	var b strings.Builder
+	upper := false
+	for _, r := range identifier {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = b.Len() > 0
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		} else if b.Len() == 0 {
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+
+	if b.Len() == 0 {
+		return "principal"
+	}
+	return b.String()
+}
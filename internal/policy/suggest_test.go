@@ -0,0 +1,94 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func suggestRequests() []SimulateRequest {
+	return []SimulateRequest{
+		{Principal: "user:ci-runner@example.com", Permission: "secretmanager.versions.access", Resource: "projects/test-project/secrets/db/versions/1"},
+		{Principal: "user:ci-runner@example.com", Permission: "secretmanager.versions.access", Resource: "projects/test-project/secrets/api/versions/1"},
+		{Principal: "user:ci-runner@example.com", Permission: "cloudkms.cryptoKeyVersions.useToEncrypt", Resource: "projects/test-project/locations/global/keyRings/app/cryptoKeys/data"},
+		{Principal: "user:alice@example.com", Permission: "secretmanager.secrets.get", Resource: "projects/other-project/secrets/db"},
+		{Principal: "user:alice@example.com", Permission: "secretmanager.secrets.get", Resource: "invalid"},
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	pol := &Policy{}
+	result, err := Suggest(pol, suggestRequests(), SuggestOptions{})
+	if err != nil {
+		t.Fatalf("Suggest failed: %v", err)
+	}
+
+	if result.Skipped != 1 {
+		t.Errorf("Skipped = %d, want 1", result.Skipped)
+	}
+
+	wantRoles := []string{"roles/custom.alice", "roles/custom.ciRunner"}
+	if !reflect.DeepEqual(result.RolesAdded, wantRoles) {
+		t.Errorf("RolesAdded = %v, want %v", result.RolesAdded, wantRoles)
+	}
+
+	wantPerms := []string{"cloudkms.cryptoKeyVersions.useToEncrypt", "secretmanager.versions.access"}
+	if got := pol.Roles["roles/custom.ciRunner"].Permissions; !reflect.DeepEqual(got, wantPerms) {
+		t.Errorf("ciRunner permissions = %v, want %v", got, wantPerms)
+	}
+
+	want := []Binding{{Role: "roles/custom.alice", Members: []string{"user:alice@example.com"}}}
+	if got := pol.Projects["other-project"].Bindings; !reflect.DeepEqual(got, want) {
+		t.Errorf("other-project bindings = %v, want %v", got, want)
+	}
+
+	if errs := Validate(pol).Errors; len(errs) > 0 {
+		t.Errorf("Suggested policy is invalid: %v", errs)
+	}
+
+	// A second run over the same traffic changes nothing
+	again, err := Suggest(pol, suggestRequests(), SuggestOptions{})
+	if err != nil {
+		t.Fatalf("Suggest failed: %v", err)
+	}
+	if !again.Empty() {
+		t.Errorf("Expected no changes on a second run, got %+v", again)
+	}
+}
+
+func TestSuggestByService(t *testing.T) {
+	pol := &Policy{Roles: map[string]Role{
+		// Not created by Suggest, so its name is not reused
+		"roles/custom.ciRunner.secretmanager": {Permissions: []string{"secretmanager.secrets.list"}},
+	}}
+
+	result, err := Suggest(pol, suggestRequests(), SuggestOptions{GroupBy: SuggestByService})
+	if err != nil {
+		t.Fatalf("Suggest failed: %v", err)
+	}
+
+	want := []string{"roles/custom.alice.secretmanager", "roles/custom.ciRunner.cloudkms", "roles/custom.ciRunner.secretmanager2"}
+	if !reflect.DeepEqual(result.RolesAdded, want) {
+		t.Errorf("RolesAdded = %v, want %v", result.RolesAdded, want)
+	}
+	if got := pol.Roles["roles/custom.ciRunner.secretmanager"].Permissions; !reflect.DeepEqual(got, []string{"secretmanager.secrets.list"}) {
+		t.Errorf("Existing role was modified: %v", got)
+	}
+
+	if _, err := Suggest(pol, nil, SuggestOptions{GroupBy: "resource"}); err == nil {
+		t.Error("Expected error for invalid grouping")
+	}
+}
+
+func TestPrincipalSlug(t *testing.T) {
+	tests := map[string]string{
+		"user:alice@example.com": "alice",
+		"serviceAccount:ci-runner@test-project.iam.gserviceaccount.com": "ciRunner",
+		"user:Bob.Smith@example.com":                                    "bobSmith",
+		"user:@example.com":                                             "principal",
+	}
+	for principal, want := range tests {
+		if got := principalSlug(principal); got != want {
+			t.Errorf("principalSlug(%q) = %q, want %q", principal, got, want)
+		}
+	}
+}
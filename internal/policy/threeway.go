@@ -0,0 +1,361 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MergeConflict is a part of the policy that both sides of a three-way
+// merge changed in different ways. Path is a schema path such as
+// roles.roles/custom.dev.description; the values are rendered as YAML,
+// and an empty value means the entry is absent on that side.
+type MergeConflict struct {
+	Path   string `json:"path"`
+	Base   string `json:"base,omitempty"`
+	Ours   string `json:"ours,omitempty"`
+	Theirs string `json:"theirs,omitempty"`
+}
+
+// MergeThreeWay merges the changes made in ours and theirs since their
+// common ancestor base. Entries added on either side are kept, changes made
+// identically on both sides are taken once, and lists of permissions,
+// members, and bindings are merged element by element, so two branches
+// that each grant something new both land. A value changed differently on
+// both sides, or changed on one side and deleted on the other, is a
+// conflict: the merged policy keeps the value from ours (or the surviving
+// value) and the conflict is returned for the user to resolve. None of
+// the inputs is modified.
+func MergeThreeWay(base, ours, theirs *Policy) (*Policy, []MergeConflict) {
+	m := &merger{}
+
+	merged := &Policy{
+		Version:  max(base.Version, ours.Version, theirs.Version),
+		Includes: mergeStrings(base.Includes, ours.Includes, theirs.Includes),
+		Roles:    mergeEntries(m, "roles", base.Roles, ours.Roles, theirs.Roles, mergeRole),
+		Groups:   mergeEntries(m, "groups", base.Groups, ours.Groups, theirs.Groups, mergeGroup),
+		Projects: mergeEntries(m, "projects", base.Projects, ours.Projects, theirs.Projects, mergeProject),
+
+		ServiceAccounts: mergeEntries(m, "serviceAccounts", base.ServiceAccounts, ours.ServiceAccounts, theirs.ServiceAccounts, mergeServiceAccount),
+
+		AllowUnknownPermissions: mergeField(m, "allowUnknownPermissions", base.AllowUnknownPermissions, ours.AllowUnknownPermissions, theirs.AllowUnknownPermissions),
+
+		Organizations: mergeEntries(m, "organizations", base.Organizations, ours.Organizations, theirs.Organizations, mergeOrganization),
+		Folders:       mergeEntries(m, "folders", base.Folders, ours.Folders, theirs.Folders, mergeFolder),
+	}
+
+	return merged, m.conflicts
+}
+
+// merger collects the conflicts found during a three-way merge
+type merger struct {
+	conflicts []MergeConflict
+}
+
+// conflict records a conflict at path. Values absent on a side are nil.
+func (m *merger) conflict(path string, base, ours, theirs any) {
+	m.conflicts = append(m.conflicts, MergeConflict{
+		Path:   path,
+		Base:   renderMergeValue(base),
+		Ours:   renderMergeValue(ours),
+		Theirs: renderMergeValue(theirs),
+	})
+}
+
+// renderMergeValue formats a conflicting value as YAML, or "" if absent
+func renderMergeValue(v any) string {
+	if v == nil {
+		return ""
+	}
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// mergeFunc merges one entry that both sides changed differently. base is
+// the zero value when both sides added the entry.
+type mergeFunc[V any] func(m *merger, path string, base, ours, theirs V) V
+
+// mergeEntries merges the named entries of a policy section, such as roles
+// or groups. The result is nil when the section is empty on all sides.
+func mergeEntries[V any](m *merger, path string, base, ours, theirs map[string]V, merge mergeFunc[V]) map[string]V {
+	if base == nil && ours == nil && theirs == nil {
+		return nil
+	}
+
+	names := make(map[string]bool)
+	for _, section := range []map[string]V{base, ours, theirs} {
+		for name := range section {
+			names[name] = true
+		}
+	}
+
+	merged := make(map[string]V)
+	for _, name := range sortedKeys(names) {
+		b, inBase := base[name]
+		o, inOurs := ours[name]
+		t, inTheirs := theirs[name]
+		if v, keep := mergeEntry(m, path+"."+name, b, inBase, o, inOurs, t, inTheirs, merge); keep {
+			merged[name] = v
+		}
+	}
+	return merged
+}
+
+// mergeEntry merges one entry given its value and presence on each side.
+// It reports whether the entry survives the merge.
+func mergeEntry[V any](m *merger, path string, b V, inBase bool, o V, inOurs bool, t V, inTheirs bool, merge mergeFunc[V]) (V, bool) {
+	var zero V
+
+	switch {
+	case inOurs && inTheirs:
+		switch {
+		case reflect.DeepEqual(o, t):
+			return o, true
+		case inBase && reflect.DeepEqual(o, b):
+			return t, true
+		case inBase && reflect.DeepEqual(t, b):
+			return o, true
+		}
+		return merge(m, path, b, o, t), true
+
+	case inOurs:
+		if !inBase {
+			return o, true
+		}
+		if reflect.DeepEqual(o, b) {
+			return zero, false
+		}
+		m.conflict(path, b, o, nil)
+		return o, true
+
+	case inTheirs:
+		if !inBase {
+			return t, true
+		}
+		if reflect.DeepEqual(t, b) {
+			return zero, false
+		}
+		m.conflict(path, b, nil, t)
+		return t, true
+	}
+
+	return zero, false
+}
+
+// mergeField merges a single value: a change on one side wins, and
+// different changes on both sides are a conflict resolved to ours
+func mergeField[T any](m *merger, path string, base, ours, theirs T) T {
+	switch {
+	case reflect.DeepEqual(ours, theirs), reflect.DeepEqual(theirs, base):
+		return ours
+	case reflect.DeepEqual(ours, base):
+		return theirs
+	}
+	m.conflict(path, base, ours, theirs)
+	return ours
+}
+
+// mergeSet merges lists treated as sets of keys: an element added on
+// either side is kept and an element removed on either side is dropped.
+// Elements keep the order of ours, followed by those only in theirs.
+func mergeSet[V any](base, ours, theirs []V, key func(V) string) []V {
+	inBase := make(map[string]bool, len(base))
+	for _, v := range base {
+		inBase[key(v)] = true
+	}
+	inOurs := make(map[string]bool, len(ours))
+	for _, v := range ours {
+		inOurs[key(v)] = true
+	}
+	inTheirs := make(map[string]bool, len(theirs))
+	for _, v := range theirs {
+		inTheirs[key(v)] = true
+	}
+
+	var merged []V
+	seen := make(map[string]bool)
+	for _, v := range slices.Concat(ours, theirs) {
+		k := key(v)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		if inOurs[k] && inTheirs[k] || !inBase[k] {
+			merged = append(merged, v)
+		}
+	}
+
+	// Keep an explicitly empty list rather than turning it into null
+	if merged == nil && (ours != nil || theirs != nil) {
+		merged = []V{}
+	}
+	return merged
+}
+
+// mergeStrings merges string lists such as permissions or members
+func mergeStrings(base, ours, theirs []string) []string {
+	return mergeSet(base, ours, theirs, func(s string) string { return s })
+}
+
+func mergeRole(m *merger, path string, base, ours, theirs Role) Role {
+	return Role{
+		Description:             mergeField(m, path+".description", base.Description, ours.Description, theirs.Description),
+		Permissions:             mergeStrings(base.Permissions, ours.Permissions, theirs.Permissions),
+		IncludeRoles:            mergeStrings(base.IncludeRoles, ours.IncludeRoles, theirs.IncludeRoles),
+		AllowUnknownPermissions: mergeField(m, path+".allowUnknownPermissions", base.AllowUnknownPermissions, ours.AllowUnknownPermissions, theirs.AllowUnknownPermissions),
+	}
+}
+
+func mergeGroup(m *merger, path string, base, ours, theirs Group) Group {
+	return Group{Members: mergeStrings(base.Members, ours.Members, theirs.Members)}
+}
+
+func mergeServiceAccount(m *merger, path string, base, ours, theirs ServiceAccount) ServiceAccount {
+	return ServiceAccount{
+		DisplayName: mergeField(m, path+".displayName", base.DisplayName, ours.DisplayName, theirs.DisplayName),
+		Project:     mergeField(m, path+".project", base.Project, ours.Project, theirs.Project),
+		Description: mergeField(m, path+".description", base.Description, ours.Description, theirs.Description),
+	}
+}
+
+func mergeProject(m *merger, path string, base, ours, theirs Project) Project {
+	return Project{
+		Parent:       mergeField(m, path+".parent", base.Parent, ours.Parent, theirs.Parent),
+		Bindings:     mergeBindingList(m, path+".bindings", base.Bindings, ours.Bindings, theirs.Bindings),
+		DenyBindings: mergeSet(base.DenyBindings, ours.DenyBindings, theirs.DenyBindings, denyBindingKey),
+		Resources:    mergeEntries(m, path+".resources", base.Resources, ours.Resources, theirs.Resources, mergeResource),
+	}
+}
+
+func mergeResource(m *merger, path string, base, ours, theirs Resource) Resource {
+	return Resource{Bindings: mergeBindingList(m, path+".bindings", base.Bindings, ours.Bindings, theirs.Bindings)}
+}
+
+func mergeOrganization(m *merger, path string, base, ours, theirs Organization) Organization {
+	return Organization{Bindings: mergeBindingList(m, path+".bindings", base.Bindings, ours.Bindings, theirs.Bindings)}
+}
+
+func mergeFolder(m *merger, path string, base, ours, theirs Folder) Folder {
+	return Folder{
+		Parent:   mergeField(m, path+".parent", base.Parent, ours.Parent, theirs.Parent),
+		Bindings: mergeBindingList(m, path+".bindings", base.Bindings, ours.Bindings, theirs.Bindings),
+	}
+}
+
+// mergeBindingList merges bindings matched by role and condition
+// expression. Members are merged as a set, so grants added on both sides
+// are kept; a condition title or description changed differently on both
+// sides is a conflict. Bindings keep the order of ours, followed by those
+// only in theirs.
+func mergeBindingList(m *merger, path string, base, ours, theirs []Binding) []Binding {
+	baseKeyed, oursKeyed, theirsKeyed := keyBindings(base), keyBindings(ours), keyBindings(theirs)
+
+	var keys []string
+	seen := make(map[string]bool)
+	for _, list := range [][]keyedBinding{oursKeyed, theirsKeyed} {
+		for _, kb := range list {
+			if !seen[kb.key] {
+				seen[kb.key] = true
+				keys = append(keys, kb.key)
+			}
+		}
+	}
+
+	var merged []Binding
+	for _, key := range keys {
+		b, inBase := findKeyedBinding(baseKeyed, key)
+		o, inOurs := findKeyedBinding(oursKeyed, key)
+		t, inTheirs := findKeyedBinding(theirsKeyed, key)
+		if v, keep := mergeEntry(m, fmt.Sprintf("%s[%s]", path, key), b, inBase, o, inOurs, t, inTheirs, mergeBinding); keep {
+			merged = append(merged, v)
+		}
+	}
+
+	if merged == nil && (ours != nil || theirs != nil) {
+		merged = []Binding{}
+	}
+	return merged
+}
+
+func mergeBinding(m *merger, path string, base, ours, theirs Binding) Binding {
+	return Binding{
+		Role:      ours.Role,
+		Members:   mergeStrings(base.Members, ours.Members, theirs.Members),
+		Condition: mergeField(m, path+".condition", base.Condition, ours.Condition, theirs.Condition),
+	}
+}
+
+// keyedBinding is a binding with the key it is matched on across the
+// sides of a merge
+type keyedBinding struct {
+	key     string
+	binding Binding
+}
+
+// keyBindings keys bindings by role and condition expression, as in
+// roles/viewer or roles/viewer if request.time < ..., numbering repeats
+// of the same key so each can be matched separately
+func keyBindings(bindings []Binding) []keyedBinding {
+	keyed := make([]keyedBinding, 0, len(bindings))
+	counts := make(map[string]int)
+	for _, b := range bindings {
+		key := b.Role
+		if b.Condition != nil {
+			key += " if " + b.Condition.Expression
+		}
+		counts[key]++
+		if n := counts[key]; n > 1 {
+			key = fmt.Sprintf("%s #%d", key, n)
+		}
+		keyed = append(keyed, keyedBinding{key: key, binding: b})
+	}
+	return keyed
+}
+
+func findKeyedBinding(keyed []keyedBinding, key string) (Binding, bool) {
+	for _, kb := range keyed {
+		if kb.key == key {
+			return kb.binding, true
+		}
+	}
+	return Binding{}, false
+}
+
+// denyBindingKey identifies a deny binding by its full contents, so a
+// deny binding edited on one side is merged as a removal and an addition
+func denyBindingKey(d DenyBinding) string {
+	data, _ := json.Marshal(d)
+	return string(data)
+}
+
+// FormatConflicts renders conflicts in the style of git conflict markers,
+// one block per conflicting path
+func FormatConflicts(conflicts []MergeConflict) string {
+	var b strings.Builder
+	for i, c := range conflicts {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "# %s\n", c.Path)
+		fmt.Fprintf(&b, "<<<<<<< ours\n%s", conflictSide(c.Ours))
+		fmt.Fprintf(&b, "||||||| base\n%s", conflictSide(c.Base))
+		fmt.Fprintf(&b, "=======\n%s", conflictSide(c.Theirs))
+		b.WriteString(">>>>>>> theirs\n")
+	}
+	return b.String()
+}
+
+// conflictSide is the body of one side of a conflict block
+func conflictSide(value string) string {
+	if value == "" {
+		return "(absent)\n"
+	}
+	return value
+}
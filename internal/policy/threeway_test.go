@@ -0,0 +1,206 @@
+package policy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func threeWayBase() *Policy {
+	return &Policy{
+		Version: CurrentVersion,
+		Roles: map[string]Role{
+			"roles/custom.dev": {
+				Description: "Developers",
+				Permissions: []string{"secretmanager.secrets.get"},
+			},
+		},
+		Groups: map[string]Group{
+			"devs": {Members: []string{"user:a@example.com"}},
+		},
+		Projects: map[string]Project{
+			"test-project": {
+				Bindings: []Binding{
+					{Role: "roles/custom.dev", Members: []string{"group:devs"}},
+				},
+			},
+		},
+	}
+}
+
+func TestMergeThreeWayUnchanged(t *testing.T) {
+	merged, conflicts := MergeThreeWay(threeWayBase(), threeWayBase(), threeWayBase())
+	if len(conflicts) != 0 {
+		t.Fatalf("Expected no conflicts, got %+v", conflicts)
+	}
+	if !reflect.DeepEqual(merged, threeWayBase()) {
+		t.Errorf("Expected the base policy back, got %+v", merged)
+	}
+}
+
+func TestMergeThreeWayAdditionsFromBothSides(t *testing.T) {
+	ours := threeWayBase()
+	ours.Roles["roles/custom.ops"] = Role{Permissions: []string{"cloudkms.cryptoKeys.get"}}
+	dev := ours.Roles["roles/custom.dev"]
+	dev.Permissions = append(dev.Permissions, "secretmanager.versions.access")
+	ours.Roles["roles/custom.dev"] = dev
+
+	theirs := threeWayBase()
+	theirs.Groups["devs"] = Group{Members: []string{"user:a@example.com", "user:b@example.com"}}
+	dev = theirs.Roles["roles/custom.dev"]
+	dev.Permissions = append(dev.Permissions, "secretmanager.secrets.list")
+	theirs.Roles["roles/custom.dev"] = dev
+	proj := theirs.Projects["test-project"]
+	proj.Bindings = append(proj.Bindings, Binding{Role: "roles/viewer", Members: []string{"user:b@example.com"}})
+	theirs.Projects["test-project"] = proj
+
+	merged, conflicts := MergeThreeWay(threeWayBase(), ours, theirs)
+	if len(conflicts) != 0 {
+		t.Fatalf("Expected no conflicts, got %+v", conflicts)
+	}
+
+	if _, ok := merged.Roles["roles/custom.ops"]; !ok {
+		t.Error("Expected role added on our side to be kept")
+	}
+	wantPerms := []string{"secretmanager.secrets.get", "secretmanager.versions.access", "secretmanager.secrets.list"}
+	if got := merged.Roles["roles/custom.dev"].Permissions; !reflect.DeepEqual(got, wantPerms) {
+		t.Errorf("Expected permissions %v, got %v", wantPerms, got)
+	}
+	if got := merged.Groups["devs"].Members; len(got) != 2 {
+		t.Errorf("Expected member added on their side to be kept, got %v", got)
+	}
+	if got := merged.Projects["test-project"].Bindings; len(got) != 2 || got[1].Role != "roles/viewer" {
+		t.Errorf("Expected binding added on their side to be appended, got %+v", got)
+	}
+}
+
+func TestMergeThreeWayIdenticalChanges(t *testing.T) {
+	change := func() *Policy {
+		p := threeWayBase()
+		p.Roles["roles/custom.ops"] = Role{Description: "Operators", Permissions: []string{"cloudkms.cryptoKeys.get"}}
+		dev := p.Roles["roles/custom.dev"]
+		dev.Description = "Application developers"
+		p.Roles["roles/custom.dev"] = dev
+		return p
+	}
+
+	merged, conflicts := MergeThreeWay(threeWayBase(), change(), change())
+	if len(conflicts) != 0 {
+		t.Fatalf("Expected identical changes not to conflict, got %+v", conflicts)
+	}
+	if !reflect.DeepEqual(merged, change()) {
+		t.Errorf("Expected the shared change once, got %+v", merged)
+	}
+}
+
+func TestMergeThreeWayRemovals(t *testing.T) {
+	ours := threeWayBase()
+	delete(ours.Groups, "devs")
+
+	theirs := threeWayBase()
+	proj := theirs.Projects["test-project"]
+	proj.Bindings[0].Members = []string{}
+	theirs.Projects["test-project"] = proj
+	theirs.Roles["roles/custom.dev"] = Role{Description: "Developers", Permissions: []string{"secretmanager.secrets.get", "secretmanager.versions.access"}}
+
+	merged, conflicts := MergeThreeWay(threeWayBase(), ours, theirs)
+	if len(conflicts) != 0 {
+		t.Fatalf("Expected no conflicts, got %+v", conflicts)
+	}
+	if _, ok := merged.Groups["devs"]; ok {
+		t.Error("Expected group deleted on our side to be removed")
+	}
+	if got := merged.Projects["test-project"].Bindings[0].Members; len(got) != 0 {
+		t.Errorf("Expected member removed on their side to be dropped, got %v", got)
+	}
+}
+
+func TestMergeThreeWayConflicts(t *testing.T) {
+	ours := threeWayBase()
+	dev := ours.Roles["roles/custom.dev"]
+	dev.Description = "Backend developers"
+	dev.Permissions = append(dev.Permissions, "secretmanager.versions.access")
+	ours.Roles["roles/custom.dev"] = dev
+	delete(ours.Groups, "devs")
+
+	theirs := threeWayBase()
+	dev = theirs.Roles["roles/custom.dev"]
+	dev.Description = "Frontend developers"
+	theirs.Roles["roles/custom.dev"] = dev
+	theirs.Groups["devs"] = Group{Members: []string{"user:a@example.com", "user:b@example.com"}}
+
+	merged, conflicts := MergeThreeWay(threeWayBase(), ours, theirs)
+
+	paths := make([]string, 0, len(conflicts))
+	for _, c := range conflicts {
+		paths = append(paths, c.Path)
+	}
+	wantPaths := []string{"roles.roles/custom.dev.description", "groups.devs"}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Fatalf("Expected conflicts at %v, got %v", wantPaths, paths)
+	}
+
+	if got := merged.Roles["roles/custom.dev"]; got.Description != "Backend developers" || len(got.Permissions) != 2 {
+		t.Errorf("Expected our description with the merged permissions, got %+v", got)
+	}
+	if _, ok := merged.Groups["devs"]; !ok {
+		t.Error("Expected group modified on their side to survive our deletion")
+	}
+	if conflicts[1].Ours != "" || conflicts[1].Theirs == "" {
+		t.Errorf("Expected delete/modify conflict to show ours absent, got %+v", conflicts[1])
+	}
+
+	report := FormatConflicts(conflicts)
+	for _, want := range []string{"# roles.roles/custom.dev.description", "<<<<<<< ours\nBackend developers", "||||||| base\nDevelopers", "=======\nFrontend developers", ">>>>>>> theirs", "(absent)"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Expected conflict report to contain %q, got:\n%s", want, report)
+		}
+	}
+}
+
+func TestMergeThreeWayBindingsByCondition(t *testing.T) {
+	cond := &Condition{Expression: `request.time < timestamp("2030-01-01T00:00:00Z")`, Title: "temporary"}
+
+	ours := threeWayBase()
+	proj := ours.Projects["test-project"]
+	proj.Bindings = append(proj.Bindings, Binding{Role: "roles/custom.dev", Members: []string{"user:c@example.com"}, Condition: cond})
+	ours.Projects["test-project"] = proj
+
+	theirs := threeWayBase()
+	proj = theirs.Projects["test-project"]
+	proj.Bindings[0].Members = append(proj.Bindings[0].Members, "user:d@example.com")
+	theirs.Projects["test-project"] = proj
+
+	merged, conflicts := MergeThreeWay(threeWayBase(), ours, theirs)
+	if len(conflicts) != 0 {
+		t.Fatalf("Expected no conflicts, got %+v", conflicts)
+	}
+
+	bindings := merged.Projects["test-project"].Bindings
+	if len(bindings) != 2 {
+		t.Fatalf("Expected unconditional and conditional bindings, got %+v", bindings)
+	}
+	if !reflect.DeepEqual(bindings[0].Members, []string{"group:devs", "user:d@example.com"}) {
+		t.Errorf("Expected their member on the unconditional binding, got %v", bindings[0].Members)
+	}
+	if bindings[1].Condition == nil || bindings[1].Members[0] != "user:c@example.com" {
+		t.Errorf("Expected our conditional binding kept separately, got %+v", bindings[1])
+	}
+}
+
+func TestMergeThreeWayBothAdded(t *testing.T) {
+	ours := threeWayBase()
+	ours.Roles["roles/custom.ops"] = Role{Description: "Ops", Permissions: []string{"cloudkms.cryptoKeys.get"}}
+
+	theirs := threeWayBase()
+	theirs.Roles["roles/custom.ops"] = Role{Description: "Ops", Permissions: []string{"cloudkms.cryptoKeys.list"}}
+
+	merged, conflicts := MergeThreeWay(threeWayBase(), ours, theirs)
+	if len(conflicts) != 0 {
+		t.Fatalf("Expected matching descriptions not to conflict, got %+v", conflicts)
+	}
+	want := []string{"cloudkms.cryptoKeys.get", "cloudkms.cryptoKeys.list"}
+	if got := merged.Roles["roles/custom.ops"].Permissions; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected permissions from both additions %v, got %v", want, got)
+	}
+}
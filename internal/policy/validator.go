@@ -2,41 +2,160 @@ package policy
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
+	"time"
 )
 
 // ValidationResult represents policy validation results
 type ValidationResult struct {
 	Valid  bool
 	Errors []string
+
+	// Findings holds the same errors and warnings with their severity and,
+	// for policies read by Load, where each one is in the source file
+	Findings []Finding
+}
+
+// Finding is a validation error or warning. Position is the zero value
+// when the problem has no single location, such as a missing section.
+type Finding struct {
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Position
 }
 
-// Validate validates a policy structure
+// Finding severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// String formats the finding compiler style, as file:line:column: message
+func (f Finding) String() string {
+	msg := f.Message
+	if f.Severity == SeverityWarning {
+		msg = "WARNING: " + msg
+	}
+	if !f.IsValid() {
+		return msg
+	}
+	return f.Position.String() + ": " + msg
+}
+
+// ValidateOptions controls optional validation checks
+type ValidateOptions struct {
+	// SkipCEL disables compiling condition expressions with CEL
+	SkipCEL bool
+
+	// StrictLint reports lint findings such as duplicates as errors
+	// instead of warnings
+	StrictLint bool
+
+	// GCPCompat reports custom role names that cannot round-trip to real
+	// GCP as errors: IDs GCP does not accept, and names of predefined roles
+	GCPCompat bool
+}
+
+// Validate validates a policy structure with default options
 func Validate(policy *Policy) *ValidationResult {
+	return ValidateWithOptions(policy, ValidateOptions{})
+}
+
+// ValidateWithOptions validates a policy structure
+func ValidateWithOptions(policy *Policy, opts ValidateOptions) *ValidationResult {
 	result := &ValidationResult{
 		Valid:  true,
 		Errors: []string{},
 	}
 
+	// Check for misspelled or unsupported keys the parser dropped
+	for _, key := range policy.UnknownKeys() {
+		result.addErrorAt(key.Position, key.String())
+	}
+
 	// Check roles
 	if len(policy.Roles) == 0 {
 		result.addWarning("No roles defined")
 	}
 
-	for roleName, role := range policy.Roles {
+	for _, roleName := range sortedKeys(policy.Roles) {
+		role := policy.Roles[roleName]
 		if !strings.HasPrefix(roleName, "roles/") {
-			result.addError(fmt.Sprintf("Role name must start with 'roles/': %s", roleName))
+			result.addErrorAt(policy.rolePosition(roleName, ""), sourcePrefix(policy.roleOrigin(roleName))+fmt.Sprintf("Role name must start with 'roles/': %s", roleName))
 		}
 
-		if len(role.Permissions) == 0 {
-			result.addWarning(fmt.Sprintf("Role %s has no permissions", roleName))
+		if opts.GCPCompat && strings.HasPrefix(roleName, "roles/") {
+			pos := policy.rolePosition(roleName, "")
+			if IsBuiltinRole(roleName) {
+				result.addErrorAt(pos, sourcePrefix(policy.roleOrigin(roleName))+fmt.Sprintf("Role %s is a predefined GCP role; a custom role with this name would export as the predefined one (rename it to %s)", roleName, SuggestRoleName(roleName)))
+			} else if _, err := CustomRoleID(roleName); err != nil {
+				result.addErrorAt(pos, sourcePrefix(policy.roleOrigin(roleName))+fmt.Sprintf("Role %s cannot be exported to GCP: %v (rename it to %s)", roleName, err, SuggestRoleName(roleName)))
+			}
 		}
 
-		for _, perm := range role.Permissions {
+		if len(role.Permissions) == 0 && len(role.IncludeRoles) == 0 {
+			result.addWarningAt(policy.rolePosition(roleName, ""), sourcePrefix(policy.roleOrigin(roleName))+fmt.Sprintf("Role %s has no permissions", roleName))
+		}
+
+		for i, included := range role.IncludeRoles {
+			if _, defined := policy.Roles[included]; !defined && !IsBuiltinRole(included) {
+				result.addErrorAt(policy.rolePosition(roleName, fmt.Sprintf(".includeRoles[%d]", i)), sourcePrefix(policy.roleOrigin(roleName))+fmt.Sprintf("Role %s: included role %s is not defined", roleName, included))
+			}
+		}
+
+		for i, perm := range role.Permissions {
+			pos := policy.rolePosition(roleName, fmt.Sprintf(".permissions[%d]", i))
 			if err := validatePermission(perm); err != nil {
-				result.addError(fmt.Sprintf("Role %s: %v", roleName, err))
+				result.addErrorAt(pos, sourcePrefix(policy.roleOrigin(roleName))+fmt.Sprintf("Role %s: %v", roleName, err))
+				continue
+			}
+
+			if !IsKnownPermission(perm) && !policy.AllowUnknownPermissions && !role.AllowUnknownPermissions {
+				msg := fmt.Sprintf("Role %s: unknown permission %s", roleName, perm)
+				if suggestion := suggestPermission(perm); suggestion != "" {
+					msg += fmt.Sprintf(" (did you mean %s?)", suggestion)
+				}
+				result.addLintAt(pos, sourcePrefix(policy.roleOrigin(roleName))+msg, opts.StrictLint)
+			}
+		}
+
+		for _, perm := range duplicates(role.Permissions) {
+			pos := policy.rolePosition(roleName, fmt.Sprintf(".permissions[%d]", repeatIndex(role.Permissions, perm)))
+			result.addLintAt(pos, sourcePrefix(policy.roleOrigin(roleName))+fmt.Sprintf("Role %s: duplicate permission %s", roleName, perm), opts.StrictLint)
+		}
+	}
+
+	// Check groups
+	for _, groupName := range sortedKeys(policy.Groups) {
+		group := policy.Groups[groupName]
+		for i, member := range group.Members {
+			if err := ValidatePrincipal(member, policy); err != nil {
+				result.addErrorAt(policy.groupPosition(groupName, fmt.Sprintf(".members[%d]", i)), sourcePrefix(policy.groupOrigin(groupName))+fmt.Sprintf("Group %s: %v", groupName, err))
 			}
 		}
+
+		for _, member := range duplicates(group.Members) {
+			pos := policy.groupPosition(groupName, fmt.Sprintf(".members[%d]", repeatIndex(group.Members, member)))
+			result.addLintAt(pos, sourcePrefix(policy.groupOrigin(groupName))+fmt.Sprintf("Group %s: duplicate member %s", groupName, member), opts.StrictLint)
+		}
+	}
+
+	// Check service accounts
+	for _, email := range sortedKeys(policy.ServiceAccounts) {
+		if !emailPattern.MatchString(email) {
+			result.addErrorAt(policy.serviceAccountPosition(email, ""), sourcePrefix(policy.serviceAccountOrigin(email))+fmt.Sprintf("Service account %s: key must be the account email", email))
+		}
+	}
+
+	// Check role composition
+	for _, cycle := range RoleCycles(policy) {
+		result.addErrorAt(policy.rolePosition(cycle[0], ""), sourcePrefix(policy.roleOrigin(cycle[0]))+fmt.Sprintf("Role inclusion cycle: %s", strings.Join(cycle, " -> ")))
+	}
+
+	// Check group nesting
+	for _, cycle := range GroupCycles(policy) {
+		result.addErrorAt(policy.groupPosition(cycle[0], ""), sourcePrefix(policy.groupOrigin(cycle[0]))+fmt.Sprintf("Group membership cycle: %s", strings.Join(cycle, " -> ")))
 	}
 
 	// Check projects
@@ -44,54 +163,366 @@ func Validate(policy *Policy) *ValidationResult {
 		result.addWarning("No projects defined")
 	}
 
-	for projectName, project := range policy.Projects {
-		if len(project.Bindings) == 0 {
-			result.addWarning(fmt.Sprintf("Project %s has no bindings", projectName))
+	for _, projectName := range sortedKeys(policy.Projects) {
+		project := policy.Projects[projectName]
+		if project.Parent != "" {
+			if err := checkParent(policy, project.Parent); err != nil {
+				result.addErrorAt(policy.position("", "projects."+projectName+".parent"), fmt.Sprintf("Project %s: %v", projectName, err))
+			}
 		}
 
-		for i, binding := range project.Bindings {
-			// Check if role exists
-			if !strings.HasPrefix(binding.Role, "roles/") {
-				result.addError(fmt.Sprintf("Project %s binding %d: role must start with 'roles/'", projectName, i))
-			}
+		if len(EffectiveBindings(policy, projectName)) == 0 && len(project.Resources) == 0 {
+			result.addWarningAt(policy.position("", "projects."+projectName), fmt.Sprintf("Project %s has no bindings", projectName))
+		}
 
-			// Check if custom role is defined
-			if strings.HasPrefix(binding.Role, "roles/custom.") {
-				if _, exists := policy.Roles[binding.Role]; !exists {
-					result.addError(fmt.Sprintf("Project %s binding %d: undefined role %s", projectName, i, binding.Role))
+		validateBindings(result, policy, project.Bindings, projectName, opts,
+			func(i int, sub string) Position { return policy.bindingPosition(projectName, i, sub) },
+			func(i int) string { return bindingLabel(policy, projectName, i) },
+			func(i int) string {
+				if file, index := policy.bindingOrigin(projectName, i); file != "" {
+					return fmt.Sprintf("binding %d in %s", index, file)
 				}
+				return bindingRef(i)
+			})
+
+		for i, deny := range project.DenyBindings {
+			at := func(sub string) Position {
+				return policy.position("", fmt.Sprintf("projects.%s.denyBindings[%d]%s", projectName, i, sub))
 			}
+			validateDenyBinding(result, policy, deny, fmt.Sprintf("Project %s deny binding %d", projectName, i), at, opts)
+		}
 
-			// Check members
-			if len(binding.Members) == 0 {
-				result.addError(fmt.Sprintf("Project %s binding %d: no members specified", projectName, i))
+		for _, resourceName := range sortedKeys(project.Resources) {
+			resourcePath := fmt.Sprintf("projects.%s.resources.%s", projectName, resourceName)
+			if err := validateResourceName(resourceName); err != nil {
+				result.addErrorAt(policy.position("", resourcePath), fmt.Sprintf("Project %s: %v", projectName, err))
 			}
 
-			for _, member := range binding.Members {
-				if err := validatePrincipal(member, policy); err != nil {
-					result.addError(fmt.Sprintf("Project %s binding %d: %v", projectName, i, err))
+			validateBindings(result, policy, project.Resources[resourceName].Bindings, projectName, opts,
+				func(i int, sub string) Position {
+					return policy.position("", fmt.Sprintf("%s.bindings[%d]%s", resourcePath, i, sub))
+				},
+				func(i int) string {
+					return fmt.Sprintf("Project %s resource %s binding %d", projectName, resourceName, i)
+				},
+				bindingRef)
+		}
+	}
+
+	// Check folders and organizations
+	for _, folderName := range sortedKeys(policy.Folders) {
+		folder := policy.Folders[folderName]
+		if folder.Parent != "" {
+			if err := checkParent(policy, folder.Parent); err != nil {
+				result.addErrorAt(policy.position("", "folders."+folderName+".parent"), fmt.Sprintf("Folder %s: %v", folderName, err))
+			}
+		}
+
+		validateBindings(result, policy, folder.Bindings, "", opts,
+			func(i int, sub string) Position {
+				return policy.position("", fmt.Sprintf("folders.%s.bindings[%d]%s", folderName, i, sub))
+			},
+			func(i int) string { return fmt.Sprintf("Folder %s binding %d", folderName, i) },
+			bindingRef)
+	}
+
+	for _, cycle := range HierarchyCycles(policy) {
+		result.addErrorAt(policy.position("", "folders."+strings.TrimPrefix(cycle[0], "folders/")), fmt.Sprintf("Folder hierarchy cycle: %s", strings.Join(cycle, " -> ")))
+	}
+
+	for _, orgName := range sortedKeys(policy.Organizations) {
+		validateBindings(result, policy, policy.Organizations[orgName].Bindings, "", opts,
+			func(i int, sub string) Position {
+				return policy.position("", fmt.Sprintf("organizations.%s.bindings[%d]%s", orgName, i, sub))
+			},
+			func(i int) string { return fmt.Sprintf("Organization %s binding %d", orgName, i) },
+			bindingRef)
+	}
+
+	// Check for groups that no binding references
+	referenced := referencedGroups(policy)
+	for _, groupName := range sortedKeys(policy.Groups) {
+		if !referenced[groupName] {
+			result.addWarningAt(policy.groupPosition(groupName, ""), sourcePrefix(policy.groupOrigin(groupName))+fmt.Sprintf("Group %s is defined but never referenced by any binding", groupName))
+		}
+	}
+
+	return result
+}
+
+// validateBindings checks the bindings attached to one project, folder, or
+// organization. project is "" for folders and organizations. at locates a
+// path under a binding in the source file, label names a binding for
+// messages, and ref names an earlier binding in the same list.
+func validateBindings(result *ValidationResult, policy *Policy, bindings []Binding, project string, opts ValidateOptions, at func(i int, sub string) Position, label, ref func(int) string) {
+	// firstBinding tracks, per binding key and member, the first binding
+	// granting that member the role so copy-paste repeats can be flagged
+	firstBinding := make(map[string]int)
+
+	for i, binding := range bindings {
+		loc := label(i)
+
+		// Check if role exists
+		rolePos := at(i, ".role")
+		if !strings.HasPrefix(binding.Role, "roles/") {
+			result.addErrorAt(rolePos, fmt.Sprintf("%s: role must start with 'roles/'", loc))
+		}
+
+		// Check if role is defined or built-in
+		if strings.HasPrefix(binding.Role, "roles/") {
+			if _, exists := policy.Roles[binding.Role]; !exists && !IsBuiltinRole(binding.Role) {
+				result.addErrorAt(rolePos, fmt.Sprintf("%s: undefined role %s", loc, binding.Role))
+			}
+		}
+
+		// Check members
+		if len(binding.Members) == 0 {
+			result.addErrorAt(at(i, ""), fmt.Sprintf("%s: no members specified", loc))
+		}
+
+		for j, member := range binding.Members {
+			if err := ValidatePrincipal(member, policy); err != nil {
+				result.addErrorAt(at(i, fmt.Sprintf(".members[%d]", j)), fmt.Sprintf("%s: %v", loc, err))
+			}
+		}
+
+		for j, member := range binding.Members {
+			if email, ok := strings.CutPrefix(member, "serviceAccount:"); ok {
+				if _, declared := policy.ServiceAccounts[email]; !declared {
+					result.addWarningAt(at(i, fmt.Sprintf(".members[%d]", j)), fmt.Sprintf("%s: service account %s is not declared in serviceAccounts", loc, email))
 				}
 			}
+		}
+
+		for _, member := range duplicates(binding.Members) {
+			pos := at(i, fmt.Sprintf(".members[%d]", repeatIndex(binding.Members, member)))
+			result.addLintAt(pos, fmt.Sprintf("%s: duplicate member %s", loc, member), opts.StrictLint)
+		}
+
+		seen := make(map[string]bool, len(binding.Members))
+		for j, member := range binding.Members {
+			if seen[member] {
+				continue
+			}
+			seen[member] = true
 
-			// Check condition syntax (basic)
-			if binding.Condition != nil {
-				if binding.Condition.Expression == "" {
-					result.addError(fmt.Sprintf("Project %s binding %d: condition has empty expression", projectName, i))
+			key := bindingKey(binding) + "\x00" + member
+			if first, exists := firstBinding[key]; exists {
+				result.addLintAt(at(i, fmt.Sprintf(".members[%d]", j)), fmt.Sprintf("%s: member %s is also granted %s by %s", loc, member, binding.Role, ref(first)), opts.StrictLint)
+			} else {
+				firstBinding[key] = i
+			}
+		}
+
+		// Check condition syntax
+		if binding.Condition != nil {
+			condPos := at(i, ".condition.expression")
+			if binding.Condition.Expression == "" {
+				result.addErrorAt(at(i, ".condition"), fmt.Sprintf("%s: condition has empty expression", loc))
+			} else if !opts.SkipCEL {
+				if err := compileCondition(binding.Condition.Expression); err != nil {
+					result.addErrorAt(condPos, fmt.Sprintf("%s: condition %q: invalid CEL expression: %v", loc, binding.Condition.Title, err))
+				} else if reason := neverTrueReason(binding.Condition.Expression, project, time.Now()); reason != "" {
+					result.addWarningAt(condPos, fmt.Sprintf("%s: condition %q can never be true: %s", loc, binding.Condition.Title, reason))
 				}
 			}
 		}
 	}
+}
+
+// validateDenyBinding checks a deny binding. Deny permissions use the
+// service.googleapis.com/resource.verb form rather than the allow form.
+func validateDenyBinding(result *ValidationResult, policy *Policy, deny DenyBinding, loc string, at func(sub string) Position, opts ValidateOptions) {
+	if len(deny.DeniedPrincipals) == 0 {
+		result.addErrorAt(at(""), fmt.Sprintf("%s: no denied principals specified", loc))
+	}
 
-	return result
+	for i, member := range deny.DeniedPrincipals {
+		if err := ValidatePrincipal(member, policy); err != nil {
+			result.addErrorAt(at(fmt.Sprintf(".deniedPrincipals[%d]", i)), fmt.Sprintf("%s: %v", loc, err))
+		}
+	}
+	for i, member := range deny.ExceptionPrincipals {
+		if err := ValidatePrincipal(member, policy); err != nil {
+			result.addErrorAt(at(fmt.Sprintf(".exceptionPrincipals[%d]", i)), fmt.Sprintf("%s: %v", loc, err))
+		}
+	}
+
+	if len(deny.DeniedPermissions) == 0 {
+		result.addErrorAt(at(""), fmt.Sprintf("%s: no denied permissions specified", loc))
+	}
+
+	for i, perm := range deny.DeniedPermissions {
+		pos := at(fmt.Sprintf(".deniedPermissions[%d]", i))
+		allow, err := validateDenyPermission(perm)
+		if err != nil {
+			if validatePermission(perm) == nil {
+				err = fmt.Errorf("%v (did you mean %s?)", err, DenyPermission(perm))
+			}
+			result.addErrorAt(pos, fmt.Sprintf("%s: %v", loc, err))
+			continue
+		}
+
+		if !IsKnownPermission(allow) && !policy.AllowUnknownPermissions {
+			result.addLintAt(pos, fmt.Sprintf("%s: unknown permission %s", loc, perm), opts.StrictLint)
+		}
+	}
+
+	if deny.Condition != nil {
+		if deny.Condition.Expression == "" {
+			result.addErrorAt(at(".condition"), fmt.Sprintf("%s: condition has empty expression", loc))
+		} else if !opts.SkipCEL {
+			if err := compileCondition(deny.Condition.Expression); err != nil {
+				result.addErrorAt(at(".condition.expression"), fmt.Sprintf("%s: condition %q: invalid CEL expression: %v", loc, deny.Condition.Title, err))
+			}
+		}
+	}
+}
+
+// referencedGroups returns the groups reachable from project, folder, or
+// organization bindings or deny bindings, either directly or through
+// another referenced group's members
+func referencedGroups(policy *Policy) map[string]bool {
+	referenced := make(map[string]bool)
+	var queue []string
+
+	var members []string
+	for _, binding := range allBindings(policy) {
+		members = append(members, binding.Members...)
+	}
+	for _, project := range policy.Projects {
+		for _, deny := range project.DenyBindings {
+			members = append(members, deny.DeniedPrincipals...)
+			members = append(members, deny.ExceptionPrincipals...)
+		}
+	}
+
+	for _, member := range members {
+		if name, ok := strings.CutPrefix(member, "group:"); ok && !referenced[name] {
+			referenced[name] = true
+			queue = append(queue, name)
+		}
+	}
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		for _, member := range policy.Groups[name].Members {
+			if nested, ok := strings.CutPrefix(member, "group:"); ok && !referenced[nested] {
+				referenced[nested] = true
+				queue = append(queue, nested)
+			}
+		}
+	}
+
+	return referenced
+}
+
+// allBindings returns every binding in the policy regardless of where it is attached
+func allBindings(policy *Policy) []Binding {
+	var bindings []Binding
+	for _, project := range policy.Projects {
+		bindings = append(bindings, project.Bindings...)
+		for _, resource := range project.Resources {
+			bindings = append(bindings, resource.Bindings...)
+		}
+	}
+	for _, folder := range policy.Folders {
+		bindings = append(bindings, folder.Bindings...)
+	}
+	for _, org := range policy.Organizations {
+		bindings = append(bindings, org.Bindings...)
+	}
+	return bindings
 }
 
 func (r *ValidationResult) addError(msg string) {
+	r.addErrorAt(Position{}, msg)
+}
+
+func (r *ValidationResult) addWarning(msg string) {
+	r.addWarningAt(Position{}, msg)
+}
+
+// addErrorAt records an error found at pos
+func (r *ValidationResult) addErrorAt(pos Position, msg string) {
 	r.Valid = false
 	r.Errors = append(r.Errors, msg)
+	r.addFinding(SeverityError, pos, msg)
 }
 
-func (r *ValidationResult) addWarning(msg string) {
+// addWarningAt records a warning found at pos
+func (r *ValidationResult) addWarningAt(pos Position, msg string) {
 	r.Errors = append(r.Errors, "WARNING: "+msg)
+	r.addFinding(SeverityWarning, pos, msg)
+}
+
+// addFinding records a finding. The source file prefix that messages for
+// merged policies carry is dropped when the position already names it.
+func (r *ValidationResult) addFinding(severity string, pos Position, msg string) {
+	if pos.IsValid() {
+		msg = strings.TrimPrefix(msg, sourcePrefix(pos.File))
+	}
+	r.Findings = append(r.Findings, Finding{Severity: severity, Message: msg, Position: pos})
+}
+
+// bindingLabel names a project binding for messages. For policies assembled
+// from includes it is prefixed with the source file and uses the
+// file-local binding index.
+func bindingLabel(policy *Policy, project string, i int) string {
+	file, index := policy.bindingOrigin(project, i)
+	return sourcePrefix(file) + fmt.Sprintf("Project %s binding %d", project, index)
+}
+
+// bindingRef names a binding by index within its own list
+func bindingRef(i int) string {
+	return fmt.Sprintf("binding %d", i)
+}
+
+// sourcePrefix formats a source file as a message prefix
+func sourcePrefix(file string) string {
+	if file == "" {
+		return ""
+	}
+	return file + ": "
+}
+
+// addLintAt records a lint finding as an error in strict mode, otherwise as a warning
+func (r *ValidationResult) addLintAt(pos Position, msg string, strict bool) {
+	if strict {
+		r.addErrorAt(pos, msg)
+		return
+	}
+	r.addWarningAt(pos, msg)
+}
+
+// repeatIndex returns the index of the second occurrence of v in values
+func repeatIndex(values []string, v string) int {
+	first := true
+	for i, value := range values {
+		if value != v {
+			continue
+		}
+		if !first {
+			return i
+		}
+		first = false
+	}
+	return -1
+}
+
+// duplicates returns values that appear more than once, in first-seen order
+func duplicates(values []string) []string {
+	counts := make(map[string]int, len(values))
+	var dups []string
+	for _, v := range values {
+		counts[v]++
+		if counts[v] == 2 {
+			dups = append(dups, v)
+		}
+	}
+	return dups
 }
 
 func validatePermission(perm string) error {
@@ -101,21 +532,30 @@ func validatePermission(perm string) error {
 	}
 
 	service := parts[0]
-	if service != "secretmanager" && service != "cloudkms" {
-		return fmt.Errorf("unknown service in permission: %s (expected secretmanager or cloudkms)", service)
+	if service != "secretmanager" && service != "cloudkms" && service != "iam" {
+		return fmt.Errorf("unknown service in permission: %s (expected secretmanager, cloudkms, or iam)", service)
 	}
 
 	return nil
 }
 
-func validatePrincipal(principal string, policy *Policy) error {
+// emailPattern is a loose check that an identifier looks like an email address
+var emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+// ValidatePrincipal checks that principal is a valid binding member:
+// user: or serviceAccount: with an email, a group the policy defines, or
+// allUsers or allAuthenticatedUsers
+func ValidatePrincipal(principal string, policy *Policy) error {
 	if principal == "allUsers" || principal == "allAuthenticatedUsers" {
 		return nil
 	}
 
 	parts := strings.SplitN(principal, ":", 2)
 	if len(parts) != 2 {
-		return fmt.Errorf("invalid principal format: %s (expected type:identifier)", principal)
+		if suggestion := suggestPrincipal(principal, policy); suggestion != "" {
+			return fmt.Errorf("invalid principal format: %s (missing type prefix, did you mean %s?)", principal, suggestion)
+		}
+		return fmt.Errorf("invalid principal format: %s (expected user:<email>, serviceAccount:<email>, group:<name>, allUsers, or allAuthenticatedUsers)", principal)
 	}
 
 	principalType := parts[0]
@@ -123,7 +563,7 @@ func validatePrincipal(principal string, policy *Policy) error {
 
 	switch principalType {
 	case "user", "serviceAccount":
-		if !strings.Contains(identifier, "@") {
+		if !emailPattern.MatchString(identifier) {
 			return fmt.Errorf("invalid %s: %s (expected email format)", principalType, identifier)
 		}
 	case "group":
@@ -131,8 +571,28 @@ func validatePrincipal(principal string, policy *Policy) error {
 			return fmt.Errorf("undefined group: %s", identifier)
 		}
 	default:
+		if suggestion := suggestPrincipal(identifier, policy); suggestion != "" {
+			return fmt.Errorf("unknown principal type: %s (did you mean %s?)", principalType, suggestion)
+		}
 		return fmt.Errorf("unknown principal type: %s (expected user, serviceAccount, or group)", principalType)
 	}
 
 	return nil
 }
+
+// suggestPrincipal guesses the intended principal for an identifier that is
+// missing its type prefix or uses an unknown one. Returns "" if no guess.
+func suggestPrincipal(identifier string, policy *Policy) string {
+	if emailPattern.MatchString(identifier) {
+		if strings.HasSuffix(identifier, ".gserviceaccount.com") {
+			return "serviceAccount:" + identifier
+		}
+		return "user:" + identifier
+	}
+
+	if _, exists := policy.Groups[identifier]; exists {
+		return "group:" + identifier
+	}
+
+	return ""
+}
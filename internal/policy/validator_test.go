@@ -0,0 +1,605 @@
+package policy
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// hasError reports whether any validation message contains substr
+func hasError(result *ValidationResult, substr string) bool {
+	for _, msg := range result.Errors {
+		if strings.Contains(msg, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestValidateBindingRoles(t *testing.T) {
+	tests := []struct {
+		name      string
+		role      string
+		wantValid bool
+	}{
+		{
+			name:      "defined custom role",
+			role:      "roles/custom.developer",
+			wantValid: true,
+		},
+		{
+			name:      "built-in owner role",
+			role:      "roles/owner",
+			wantValid: true,
+		},
+		{
+			name:      "built-in service role",
+			role:      "roles/secretmanager.secretAccessor",
+			wantValid: true,
+		},
+		{
+			name:      "undefined custom role",
+			role:      "roles/custom.deployer",
+			wantValid: false,
+		},
+		{
+			name:      "unknown non-custom role",
+			role:      "roles/storage.madeUp",
+			wantValid: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pol := &Policy{
+				Roles: map[string]Role{
+					"roles/custom.developer": {
+						Permissions: []string{"secretmanager.secrets.get"},
+					},
+				},
+				Projects: map[string]Project{
+					"test-project": {
+						Bindings: []Binding{
+							{Role: tt.role, Members: []string{"user:alice@example.com"}},
+						},
+					},
+				},
+			}
+
+			result := Validate(pol)
+			if result.Valid != tt.wantValid {
+				t.Errorf("Validate() valid = %v, want %v (errors: %v)", result.Valid, tt.wantValid, result.Errors)
+			}
+
+			if !tt.wantValid && !hasError(result, "Project test-project binding 0: undefined role "+tt.role) {
+				t.Errorf("expected undefined role error with project and index, got %v", result.Errors)
+			}
+		})
+	}
+}
+
+func TestValidateGroupReferences(t *testing.T) {
+	pol := &Policy{
+		Roles: map[string]Role{
+			"roles/custom.developer": {
+				Permissions: []string{"secretmanager.secrets.get"},
+			},
+		},
+		Groups: map[string]Group{
+			"devs":    {Members: []string{"user:alice@example.com"}},
+			"juniors": {Members: []string{"user:bob@example.com"}},
+			"admins":  {Members: []string{"group:juniors"}},
+			"unused":  {Members: []string{"user:carol@example.com"}},
+		},
+		Projects: map[string]Project{
+			"test-project": {
+				Bindings: []Binding{
+					{Role: "roles/custom.developer", Members: []string{"group:developers"}},
+					{Role: "roles/custom.developer", Members: []string{"group:devs", "group:admins"}},
+				},
+			},
+		},
+	}
+
+	result := Validate(pol)
+
+	if result.Valid {
+		t.Error("expected policy with undefined group to be invalid")
+	}
+
+	if !hasError(result, "Project test-project binding 0: undefined group: developers") {
+		t.Errorf("expected undefined group error, got %v", result.Errors)
+	}
+
+	if !hasError(result, "WARNING: Group unused is defined but never referenced") {
+		t.Errorf("expected unused group warning, got %v", result.Errors)
+	}
+
+	for _, name := range []string{"devs", "admins", "juniors"} {
+		if hasError(result, "Group "+name+" is defined but never referenced") {
+			t.Errorf("group %s is referenced and should not be warned about", name)
+		}
+	}
+}
+
+func TestValidateConditionCEL(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		skipCEL    bool
+		wantValid  bool
+	}{
+		{
+			name:       "valid startsWith",
+			expression: `resource.name.startsWith("projects/test-project/secrets/prod-")`,
+			wantValid:  true,
+		},
+		{
+			name:       "valid request time comparison",
+			expression: `request.time < timestamp("2030-01-01T00:00:00Z")`,
+			wantValid:  true,
+		},
+		{
+			name:       "missing close paren",
+			expression: `resource.name.startsWith("projects/`,
+			wantValid:  false,
+		},
+		{
+			name:       "undeclared variable",
+			expression: `principal.email == "alice@example.com"`,
+			wantValid:  false,
+		},
+		{
+			name:       "non-bool result",
+			expression: `"projects/test-project"`,
+			wantValid:  false,
+		},
+		{
+			name:       "unsupported attribute",
+			expression: `request.auth.claims.email == "alice@example.com"`,
+			wantValid:  false,
+		},
+		{
+			name:       "unsupported attribute by index",
+			expression: `resource["service"] == "secretmanager.googleapis.com"`,
+			wantValid:  false,
+		},
+		{
+			name:       "supported attribute by index",
+			expression: `resource["type"] == "secretmanager.googleapis.com/Secret"`,
+			wantValid:  true,
+		},
+		{
+			name:       "invalid but skipped",
+			expression: `resource.name.startsWith("projects/`,
+			skipCEL:    true,
+			wantValid:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pol := &Policy{
+				Projects: map[string]Project{
+					"test-project": {
+						Bindings: []Binding{
+							{
+								Role:    "roles/viewer",
+								Members: []string{"user:alice@example.com"},
+								Condition: &Condition{
+									Expression: tt.expression,
+									Title:      "Prod only",
+								},
+							},
+						},
+					},
+				},
+			}
+
+			result := ValidateWithOptions(pol, ValidateOptions{SkipCEL: tt.skipCEL})
+			if result.Valid != tt.wantValid {
+				t.Errorf("ValidateWithOptions() valid = %v, want %v (errors: %v)", result.Valid, tt.wantValid, result.Errors)
+			}
+
+			if !tt.wantValid && !hasError(result, `Project test-project binding 0: condition "Prod only": invalid CEL expression`) {
+				t.Errorf("expected CEL error with project, index, and title, got %v", result.Errors)
+			}
+		})
+	}
+}
+
+func TestValidateConditionAttributeMessage(t *testing.T) {
+	err := compileCondition(`request.auth.claims.email == "alice@example.com"`)
+	if err == nil {
+		t.Fatal("Expected error for unsupported attribute")
+	}
+	want := "unsupported attribute request.auth (supported: resource.name, resource.type, request.time)"
+	if err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
+
+func TestValidateDuplicates(t *testing.T) {
+	pol := &Policy{
+		Roles: map[string]Role{
+			"roles/custom.ciRunner": {
+				Permissions: []string{"secretmanager.secrets.get", "secretmanager.secrets.get"},
+			},
+		},
+		Groups: map[string]Group{
+			"devs": {Members: []string{"user:alice@example.com", "user:alice@example.com"}},
+		},
+		Projects: map[string]Project{
+			"test-project": {
+				Bindings: []Binding{
+					{Role: "roles/custom.ciRunner", Members: []string{"user:ci@example.com", "user:ci@example.com"}},
+					{Role: "roles/custom.ciRunner", Members: []string{"user:ci@example.com", "group:devs"}},
+					{
+						Role:      "roles/custom.ciRunner",
+						Members:   []string{"user:ci@example.com"},
+						Condition: &Condition{Expression: `resource.name.startsWith("projects/test-project/secrets/prod-")`},
+					},
+				},
+			},
+		},
+	}
+
+	want := []string{
+		"Role roles/custom.ciRunner: duplicate permission secretmanager.secrets.get",
+		"Group devs: duplicate member user:alice@example.com",
+		"Project test-project binding 0: duplicate member user:ci@example.com",
+		"Project test-project binding 1: member user:ci@example.com is also granted roles/custom.ciRunner by binding 0",
+	}
+
+	result := Validate(pol)
+	if !result.Valid {
+		t.Errorf("duplicates should only warn by default, got errors: %v", result.Errors)
+	}
+	for _, msg := range want {
+		if !hasError(result, "WARNING: "+msg) {
+			t.Errorf("expected warning %q, got %v", msg, result.Errors)
+		}
+	}
+
+	// A binding with a different condition is not a repeat
+	if hasError(result, "binding 2: member") {
+		t.Errorf("conditional binding should not be flagged as a repeat: %v", result.Errors)
+	}
+
+	strict := ValidateWithOptions(pol, ValidateOptions{StrictLint: true})
+	if strict.Valid {
+		t.Error("duplicates should be errors with StrictLint")
+	}
+	for _, msg := range want {
+		if hasError(strict, "WARNING: "+msg) || !hasError(strict, msg) {
+			t.Errorf("expected error %q, got %v", msg, strict.Errors)
+		}
+	}
+}
+
+func TestValidatePrincipal(t *testing.T) {
+	pol := &Policy{
+		Groups: map[string]Group{
+			"developers": {Members: []string{"user:alice@example.com"}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		principal string
+		wantErr   string
+	}{
+		{name: "user", principal: "user:alice@example.com"},
+		{name: "service account", principal: "serviceAccount:ci@test-project.iam.gserviceaccount.com"},
+		{name: "group", principal: "group:developers"},
+		{name: "all users", principal: "allUsers"},
+		{name: "all authenticated users", principal: "allAuthenticatedUsers"},
+		{
+			name:      "missing user prefix",
+			principal: "alice@example.com",
+			wantErr:   "did you mean user:alice@example.com?",
+		},
+		{
+			name:      "missing serviceAccount prefix",
+			principal: "ci@test-project.iam.gserviceaccount.com",
+			wantErr:   "did you mean serviceAccount:ci@test-project.iam.gserviceaccount.com?",
+		},
+		{
+			name:      "missing group prefix",
+			principal: "developers",
+			wantErr:   "did you mean group:developers?",
+		},
+		{
+			name:      "misspelled prefix",
+			principal: "users:alice@example.com",
+			wantErr:   "unknown principal type: users (did you mean user:alice@example.com?)",
+		},
+		{
+			name:      "user without domain",
+			principal: "user:alice@example",
+			wantErr:   "invalid user: alice@example (expected email format)",
+		},
+		{
+			name:      "unrecognizable",
+			principal: "nobody",
+			wantErr:   "invalid principal format: nobody (expected",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePrincipal(tt.principal, pol)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidatePrincipal(%q) unexpected error: %v", tt.principal, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidatePrincipal(%q) error = %v, want containing %q", tt.principal, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateGroupMembers(t *testing.T) {
+	pol := &Policy{
+		Groups: map[string]Group{
+			"developers": {Members: []string{"alice@example.com", "group:missing"}},
+		},
+	}
+
+	result := Validate(pol)
+	if result.Valid {
+		t.Error("expected malformed group member to be invalid")
+	}
+	if !hasError(result, "Group developers: invalid principal format: alice@example.com (missing type prefix, did you mean user:alice@example.com?)") {
+		t.Errorf("expected group member error, got %v", result.Errors)
+	}
+	if !hasError(result, "Group developers: undefined group: missing") {
+		t.Errorf("expected undefined nested group error, got %v", result.Errors)
+	}
+}
+
+func TestValidateGroupCycles(t *testing.T) {
+	pol := &Policy{
+		Groups: map[string]Group{
+			"platform-team": {Members: []string{"group:sre", "user:lead@example.com"}},
+			"sre":           {Members: []string{"group:oncall"}},
+			"oncall":        {Members: []string{"group:platform-team"}},
+			"self":          {Members: []string{"group:self"}},
+			"leaf":          {Members: []string{"user:alice@example.com"}},
+		},
+	}
+
+	result := Validate(pol)
+	if result.Valid {
+		t.Error("Expected policy with group cycles to be invalid")
+	}
+
+	for _, want := range []string{
+		"Group membership cycle: oncall -> platform-team -> sre -> oncall",
+		"Group membership cycle: self -> self",
+	} {
+		if !hasError(result, want) {
+			t.Errorf("Expected %q, got %v", want, result.Errors)
+		}
+	}
+
+	if cycles := GroupCycles(pol); len(cycles) != 2 {
+		t.Errorf("Expected each cycle reported once, got %v", cycles)
+	}
+}
+
+func TestGroupMembersNested(t *testing.T) {
+	pol := &Policy{
+		Groups: map[string]Group{
+			"platform-team": {Members: []string{"group:sre", "user:lead@example.com"}},
+			"sre":           {Members: []string{"user:alice@example.com", "group:platform-team"}},
+		},
+	}
+
+	got := GroupMembers(pol, "platform-team")
+	want := []string{"user:alice@example.com", "user:lead@example.com"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("GroupMembers() = %v, want %v", got, want)
+	}
+}
+
+func TestValidateServiceAccounts(t *testing.T) {
+	pol := &Policy{
+		ServiceAccounts: map[string]ServiceAccount{
+			"ci@test-project.iam.gserviceaccount.com": {DisplayName: "CI"},
+			"not-an-email": {},
+		},
+		Projects: map[string]Project{
+			"test-project": {
+				Bindings: []Binding{
+					{
+						Role: "roles/viewer",
+						Members: []string{
+							"serviceAccount:ci@test-project.iam.gserviceaccount.com",
+							"serviceAccount:deploy@test-project.iam.gserviceaccount.com",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	result := Validate(pol)
+
+	if !hasError(result, "WARNING: Project test-project binding 0: service account deploy@test-project.iam.gserviceaccount.com is not declared") {
+		t.Errorf("Expected undeclared service account warning, got %v", result.Errors)
+	}
+	if hasError(result, "service account ci@test-project.iam.gserviceaccount.com is not declared") {
+		t.Errorf("Declared service account should not be warned about: %v", result.Errors)
+	}
+	if !hasError(result, "Service account not-an-email: key must be the account email") {
+		t.Errorf("Expected invalid key error, got %v", result.Errors)
+	}
+}
+
+func TestValidateUnknownPermissions(t *testing.T) {
+	newPolicy := func(role Role) *Policy {
+		return &Policy{
+			Roles: map[string]Role{"roles/custom.reader": role},
+			Projects: map[string]Project{
+				"test-project": {
+					Bindings: []Binding{
+						{Role: "roles/custom.reader", Members: []string{"user:alice@example.com"}},
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("typo warns with suggestion", func(t *testing.T) {
+		result := Validate(newPolicy(Role{Permissions: []string{"secretmanager.secrets.gett"}}))
+		if !result.Valid {
+			t.Errorf("Expected unknown permission to be a warning, got: %v", result.Errors)
+		}
+		if !hasError(result, "WARNING: Role roles/custom.reader: unknown permission secretmanager.secrets.gett (did you mean secretmanager.secrets.get?)") {
+			t.Errorf("Expected unknown permission warning, got: %v", result.Errors)
+		}
+	})
+
+	t.Run("strict lint makes it an error", func(t *testing.T) {
+		result := ValidateWithOptions(newPolicy(Role{Permissions: []string{"cloudkms.cryptoKeys.frobnicate"}}), ValidateOptions{StrictLint: true})
+		if result.Valid {
+			t.Error("Expected unknown permission to be an error with StrictLint")
+		}
+	})
+
+	t.Run("iam permissions are known", func(t *testing.T) {
+		result := Validate(newPolicy(Role{Permissions: []string{"iam.serviceAccounts.actAs"}}))
+		if hasError(result, "unknown permission") {
+			t.Errorf("Unexpected unknown permission finding: %v", result.Errors)
+		}
+	})
+
+	t.Run("role escape hatch", func(t *testing.T) {
+		result := Validate(newPolicy(Role{Permissions: []string{"secretmanager.secrets.preview"}, AllowUnknownPermissions: true}))
+		if hasError(result, "unknown permission") {
+			t.Errorf("Unexpected unknown permission finding: %v", result.Errors)
+		}
+	})
+
+	t.Run("policy escape hatch", func(t *testing.T) {
+		pol := newPolicy(Role{Permissions: []string{"secretmanager.secrets.preview"}})
+		pol.AllowUnknownPermissions = true
+		result := Validate(pol)
+		if hasError(result, "unknown permission") {
+			t.Errorf("Unexpected unknown permission finding: %v", result.Errors)
+		}
+	})
+}
+
+// composedRolesPolicy has a diamond (app includes reader and writer, which
+// both include base) and a deep chain ending in a built-in role
+func composedRolesPolicy() *Policy {
+	return &Policy{
+		Roles: map[string]Role{
+			"roles/custom.base":   {Permissions: []string{"secretmanager.secrets.list"}},
+			"roles/custom.reader": {Permissions: []string{"secretmanager.secrets.get"}, IncludeRoles: []string{"roles/custom.base"}},
+			"roles/custom.writer": {Permissions: []string{"secretmanager.secrets.create"}, IncludeRoles: []string{"roles/custom.base"}},
+			"roles/custom.app":    {IncludeRoles: []string{"roles/custom.reader", "roles/custom.writer"}},
+			"roles/custom.l1":     {IncludeRoles: []string{"roles/custom.l2"}},
+			"roles/custom.l2":     {IncludeRoles: []string{"roles/custom.l3"}},
+			"roles/custom.l3":     {Permissions: []string{"cloudkms.cryptoKeys.get"}, IncludeRoles: []string{"roles/secretmanager.secretAccessor"}},
+		},
+		Projects: map[string]Project{
+			"test-project": {
+				Bindings: []Binding{{Role: "roles/custom.app", Members: []string{"user:alice@example.com"}}},
+			},
+		},
+	}
+}
+
+func TestRolePermissionsIncludeRoles(t *testing.T) {
+	pol := composedRolesPolicy()
+
+	tests := []struct {
+		role string
+		want []string
+	}{
+		{"roles/custom.app", []string{"secretmanager.secrets.create", "secretmanager.secrets.get", "secretmanager.secrets.list"}},
+		{"roles/custom.l1", []string{"cloudkms.cryptoKeys.get", "secretmanager.versions.access"}},
+	}
+
+	for _, tt := range tests {
+		if got := RolePermissions(pol, tt.role); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("RolePermissions(%s) = %v, want %v", tt.role, got, tt.want)
+		}
+	}
+
+	if result := Validate(pol); len(result.Errors) != 0 {
+		t.Errorf("Expected composed roles to validate cleanly, got %v", result.Errors)
+	}
+
+	decision, err := Simulate(pol, SimulateRequest{
+		Principal:  "user:alice@example.com",
+		Permission: "secretmanager.secrets.list",
+		Resource:   "projects/test-project/secrets/db",
+	})
+	if err != nil || !decision.Allowed {
+		t.Errorf("Expected permission from diamond include to be granted, got %+v, %v", decision, err)
+	}
+}
+
+func TestValidateIncludeRoles(t *testing.T) {
+	pol := composedRolesPolicy()
+	pol.Roles["roles/custom.missing"] = Role{IncludeRoles: []string{"roles/custom.nope"}}
+	pol.Roles["roles/custom.a"] = Role{Permissions: []string{"secretmanager.secrets.get"}, IncludeRoles: []string{"roles/custom.b"}}
+	pol.Roles["roles/custom.b"] = Role{IncludeRoles: []string{"roles/custom.a"}}
+
+	result := Validate(pol)
+	if result.Valid {
+		t.Fatal("Expected validation to fail")
+	}
+	if !hasError(result, "Role roles/custom.missing: included role roles/custom.nope is not defined") {
+		t.Errorf("Expected undefined include error, got %v", result.Errors)
+	}
+	if !hasError(result, "Role inclusion cycle: roles/custom.a -> roles/custom.b -> roles/custom.a") {
+		t.Errorf("Expected cycle error, got %v", result.Errors)
+	}
+
+	// Resolution stops at the cycle instead of recursing forever
+	if got := RolePermissions(pol, "roles/custom.b"); !reflect.DeepEqual(got, []string{"secretmanager.secrets.get"}) {
+		t.Errorf("RolePermissions(roles/custom.b) = %v", got)
+	}
+}
+
+func TestIncludeRolesSaveAndFlatten(t *testing.T) {
+	pol := composedRolesPolicy()
+
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := Save(pol, path); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if got := loaded.Roles["roles/custom.app"]; len(got.Permissions) != 0 || len(got.IncludeRoles) != 2 {
+		t.Errorf("Expected includeRoles preserved by Save, got %+v", got)
+	}
+
+	flat, err := Flatten(pol)
+	if err != nil {
+		t.Fatalf("Flatten() error: %v", err)
+	}
+	app := flat.Roles["roles/custom.app"]
+	if app.IncludeRoles != nil || len(app.Permissions) != 3 {
+		t.Errorf("Expected Flatten to resolve includeRoles, got %+v", app)
+	}
+
+	_, exported, err := ExportRole(pol, "roles/custom.app")
+	if err != nil {
+		t.Fatalf("ExportRole() error: %v", err)
+	}
+	if len(exported.IncludedPermissions) != 3 {
+		t.Errorf("Expected exported role to have resolved permissions, got %v", exported.IncludedPermissions)
+	}
+}
@@ -0,0 +1,147 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the newest policy schema version this binary understands
+const CurrentVersion = 1
+
+// migrations upgrade a raw policy document from the keyed version to the
+// next one, typically by renaming or restructuring fields. A migration must
+// be registered here whenever CurrentVersion is bumped.
+var migrations = map[int]func(doc map[string]any) error{}
+
+// checkVersion defaults a missing version to 1 and rejects versions this
+// binary cannot parse correctly, or that need migrating first
+func checkVersion(policy *Policy) error {
+	version, err := supportedVersion(policy.Version)
+	if err != nil {
+		return err
+	}
+	policy.Version = version
+
+	if policy.Version < CurrentVersion {
+		return fmt.Errorf("policy version %d is outdated (current %d); run 'gcp-emulator policy migrate' to upgrade it", policy.Version, CurrentVersion)
+	}
+
+	return nil
+}
+
+// supportedVersion checks a policy's version against the versions this
+// binary can load or migrate, treating 0, a missing version, as 1. Load
+// and Migrate both go through it, so they accept the same files.
+func supportedVersion(version int) (int, error) {
+	if version == 0 {
+		return 1, nil
+	}
+
+	if version < 0 {
+		return 0, fmt.Errorf("invalid policy version: %d", version)
+	}
+
+	if version > CurrentVersion {
+		return 0, fmt.Errorf("policy version %d is newer than this gcp-emulator supports (max %d); upgrade gcp-emulator", version, CurrentVersion)
+	}
+
+	return version, nil
+}
+
+// Migrate reads a policy file of any supported version and upgrades it to
+// CurrentVersion. It returns the migrated policy and the version it started at.
+func Migrate(path string) (*Policy, int, error) {
+	return LoadOptions{}.Migrate(path)
+}
+
+// Migrate is Migrate, decrypting an encrypted file with the passphrase o
+// gives
+func (o LoadOptions) Migrate(path string) (*Policy, int, error) {
+	data, ext, err := o.readPolicyFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var doc map[string]any
+	if ext == ".json" {
+		err = json.Unmarshal(data, &doc)
+	} else {
+		err = yaml.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse policy: %w", err)
+	}
+	if doc == nil {
+		doc = map[string]any{}
+	}
+
+	from, err := migrateDocument(doc, CurrentVersion, migrations)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	policy, err := decodeDocument(doc)
+	if err != nil {
+		return nil, 0, err
+	}
+	return policy, from, nil
+}
+
+// migrateDocument upgrades a raw policy document in place to version to,
+// applying steps in order, and returns the version it started at
+func migrateDocument(doc map[string]any, to int, steps map[int]func(doc map[string]any) error) (int, error) {
+	from, err := documentVersion(doc)
+	if err != nil {
+		return 0, err
+	}
+
+	for v := from; v < to; v++ {
+		migrate, ok := steps[v]
+		if !ok {
+			return 0, fmt.Errorf("no migration from policy version %d to %d", v, v+1)
+		}
+		if err := migrate(doc); err != nil {
+			return 0, fmt.Errorf("failed to migrate policy from version %d to %d: %w", v, v+1, err)
+		}
+	}
+	doc["version"] = to
+
+	return from, nil
+}
+
+// decodeDocument decodes a generic policy document into a Policy by
+// round-tripping it through JSON
+func decodeDocument(doc map[string]any) (*Policy, error) {
+	normalized, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode migrated policy: %w", err)
+	}
+
+	var policy Policy
+	if err := json.Unmarshal(normalized, &policy); err != nil {
+		return nil, fmt.Errorf("failed to decode migrated policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// documentVersion reads the version field from a raw policy document,
+// checked as Load checks it
+func documentVersion(doc map[string]any) (int, error) {
+	raw, ok := doc["version"]
+	if !ok || raw == nil {
+		return supportedVersion(0)
+	}
+
+	switch v := raw.(type) {
+	case int:
+		return supportedVersion(v)
+	case float64:
+		if v == float64(int(v)) {
+			return supportedVersion(int(v))
+		}
+	}
+
+	return 0, fmt.Errorf("invalid policy version: %v (must be an integer)", raw)
+}
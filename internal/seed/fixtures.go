@@ -0,0 +1,154 @@
+package seed
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidFixtures is returned when a fixtures file doesn't parse or
+// declares a resource that can't be created
+var ErrInvalidFixtures = errors.New("invalid fixtures")
+
+// Fixtures are the resources a fixtures file declares
+type Fixtures struct {
+	// Project is the project the resources are created in
+	Project string `yaml:"project"`
+
+	// Principal, if set, is who the resources are created as; outside off
+	// mode it needs permission to create them
+	Principal string `yaml:"principal"`
+
+	Secrets  []Secret  `yaml:"secrets"`
+	KeyRings []KeyRing `yaml:"keyRings"`
+}
+
+// Secret is a secret with one version
+type Secret struct {
+	Name   string            `yaml:"name"`
+	Labels map[string]string `yaml:"labels"`
+
+	// Value is the secret's data. ValueFile names a file holding it
+	// instead, relative to the fixtures file.
+	Value     string `yaml:"value"`
+	ValueFile string `yaml:"valueFile"`
+
+	// data is Value or the contents of ValueFile
+	data []byte
+}
+
+// KeyRing is a KMS key ring and its keys
+type KeyRing struct {
+	Name string `yaml:"name"`
+
+	// Location defaults to global
+	Location string `yaml:"location"`
+
+	Keys []CryptoKey `yaml:"keys"`
+}
+
+// CryptoKey is a KMS key
+type CryptoKey struct {
+	Name string `yaml:"name"`
+
+	// Purpose defaults to ENCRYPT_DECRYPT
+	Purpose string `yaml:"purpose"`
+
+	// Algorithm defaults to GOOGLE_SYMMETRIC_ENCRYPTION for ENCRYPT_DECRYPT
+	// keys; other purposes need one
+	Algorithm string `yaml:"algorithm"`
+}
+
+// idPattern matches the IDs Secret Manager and KMS accept for secrets, key
+// rings, and keys
+var idPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,63}$`)
+
+// Load reads a fixtures file, filling in defaults and reading value files
+func Load(path string) (*Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+
+	var f Fixtures
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidFixtures, path, err)
+	}
+	if err := f.resolve(filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidFixtures, path, err)
+	}
+	return &f, nil
+}
+
+// resolve checks the fixtures, fills in defaults, and reads value files
+// relative to dir
+func (f *Fixtures) resolve(dir string) error {
+	if f.Project == "" {
+		return errors.New("project is required")
+	}
+
+	secrets := map[string]bool{}
+	for i := range f.Secrets {
+		s := &f.Secrets[i]
+		if !idPattern.MatchString(s.Name) {
+			return fmt.Errorf("secret %q: name must be 1-63 letters, digits, - or _", s.Name)
+		}
+		if secrets[s.Name] {
+			return fmt.Errorf("secret %s is declared twice", s.Name)
+		}
+		secrets[s.Name] = true
+
+		switch {
+		case s.Value != "" && s.ValueFile != "":
+			return fmt.Errorf("secret %s: set value or valueFile, not both", s.Name)
+		case s.ValueFile != "":
+			path := s.ValueFile
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("secret %s: %w", s.Name, err)
+			}
+			s.data = data
+		case s.Value != "":
+			s.data = []byte(s.Value)
+		default:
+			return fmt.Errorf("secret %s: value or valueFile is required", s.Name)
+		}
+	}
+
+	for i := range f.KeyRings {
+		ring := &f.KeyRings[i]
+		if !idPattern.MatchString(ring.Name) {
+			return fmt.Errorf("key ring %q: name must be 1-63 letters, digits, - or _", ring.Name)
+		}
+		if ring.Location == "" {
+			ring.Location = "global"
+		}
+		for j := range ring.Keys {
+			key := &ring.Keys[j]
+			if !idPattern.MatchString(key.Name) {
+				return fmt.Errorf("key %s/%q: name must be 1-63 letters, digits, - or _", ring.Name, key.Name)
+			}
+			if key.Purpose == "" {
+				key.Purpose = "ENCRYPT_DECRYPT"
+			}
+			if key.Algorithm == "" {
+				if key.Purpose != "ENCRYPT_DECRYPT" {
+					return fmt.Errorf("key %s/%s: algorithm is required for purpose %s", ring.Name, key.Name, key.Purpose)
+				}
+				key.Algorithm = "GOOGLE_SYMMETRIC_ENCRYPTION"
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package seed
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFixtures(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fixtures.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeFixtures(t, `
+project: test-project
+secrets:
+  - name: db-password
+    value: hunter2
+    labels: {env: dev}
+  - name: tls-cert
+    valueFile: tls.pem
+keyRings:
+  - name: app
+    keys:
+      - name: data
+      - name: signing
+        purpose: ASYMMETRIC_SIGN
+        algorithm: EC_SIGN_P256_SHA256
+`)
+	if err := os.WriteFile(filepath.Join(filepath.Dir(path), "tls.pem"), []byte("cert"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if string(f.Secrets[0].data) != "hunter2" || string(f.Secrets[1].data) != "cert" {
+		t.Errorf("secret data = %q, %q", f.Secrets[0].data, f.Secrets[1].data)
+	}
+	ring := f.KeyRings[0]
+	if ring.Location != "global" {
+		t.Errorf("location = %q, want global", ring.Location)
+	}
+	if key := ring.Keys[0]; key.Purpose != "ENCRYPT_DECRYPT" || key.Algorithm != "GOOGLE_SYMMETRIC_ENCRYPTION" {
+		t.Errorf("default key = %+v", key)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := map[string]string{
+		"no project":       "secrets: []\n",
+		"unknown field":    "project: p\nsecret: []\n",
+		"no value":         "project: p\nsecrets:\n  - name: a\n",
+		"value and file":   "project: p\nsecrets:\n  - {name: a, value: x, valueFile: y}\n",
+		"missing file":     "project: p\nsecrets:\n  - {name: a, valueFile: missing.txt}\n",
+		"duplicate secret": "project: p\nsecrets:\n  - {name: a, value: x}\n  - {name: a, value: y}\n",
+		"bad name":         "project: p\nkeyRings:\n  - name: a/b\n",
+		"no algorithm":     "project: p\nkeyRings:\n  - name: r\n    keys:\n      - {name: k, purpose: ASYMMETRIC_SIGN}\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Load(writeFixtures(t, content)); !errors.Is(err, ErrInvalidFixtures) {
+				t.Errorf("Load = %v, want ErrInvalidFixtures", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// Package seed creates fixture resources in a running emulator stack:
+// Secret Manager secrets and KMS key rings and keys, declared in a
+// fixtures file.
+//
+// Seeding is idempotent. Resources that exist are skipped, so a fixtures
+// file can be seeded after every start.
+package seed
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator"
+)
+
+// SecretManager is the part of the Secret Manager API seeding uses, as
+// implemented by emulator.SecretManagerClient
+type SecretManager interface {
+	CreateSecret(project, id string, labels map[string]string) error
+	AddSecretVersion(project, id string, data []byte) error
+	LatestSecretVersion(project, id string) ([]byte, error)
+}
+
+// KMS is the part of the KMS API seeding uses, as implemented by
+// emulator.KMSClient
+type KMS interface {
+	CreateKeyRing(project, location, id string) error
+	CreateCryptoKey(project, location, keyRing, id, purpose, algorithm string) error
+}
+
+// Options change how existing resources are handled
+type Options struct {
+	// Update adds a version to existing secrets whose latest version
+	// differs from the fixtures
+	Update bool
+}
+
+// Action is what seeding did with a resource
+type Action string
+
+const (
+	Created Action = "created"
+	Updated Action = "updated"
+	Skipped Action = "skipped"
+)
+
+// Result is what seeding did with one resource
+type Result struct {
+	// Kind is "secret", "key ring", or "key"
+	Kind string
+	Name string
+
+	Action Action
+
+	// Reason explains a skip, such as "exists"
+	Reason string
+}
+
+// Seed creates the resources of f that don't exist yet. A nil sm or kms
+// skips the resources of that service, for a stack started without it.
+// It stops at the first error, returning the results so far.
+func Seed(f *Fixtures, sm SecretManager, kms KMS, opts Options) ([]Result, error) {
+	var results []Result
+
+	for _, secret := range f.Secrets {
+		result, err := seedSecret(f.Project, secret, sm, opts)
+		if err != nil {
+			return results, fmt.Errorf("secret %s: %w", secret.Name, err)
+		}
+		results = append(results, result)
+	}
+
+	for _, ring := range f.KeyRings {
+		result := Result{Kind: "key ring", Name: ring.Name}
+		if kms == nil {
+			result.Action, result.Reason = Skipped, "kms not running"
+			results = append(results, result)
+			continue
+		}
+		action, err := create(func() error { return kms.CreateKeyRing(f.Project, ring.Location, ring.Name) })
+		if err != nil {
+			return results, fmt.Errorf("key ring %s: %w", ring.Name, err)
+		}
+		result.Action = action
+		if action == Skipped {
+			result.Reason = "exists"
+		}
+		results = append(results, result)
+
+		for _, key := range ring.Keys {
+			name := ring.Name + "/" + key.Name
+			action, err := create(func() error {
+				return kms.CreateCryptoKey(f.Project, ring.Location, ring.Name, key.Name, key.Purpose, key.Algorithm)
+			})
+			if err != nil {
+				return results, fmt.Errorf("key %s: %w", name, err)
+			}
+			result := Result{Kind: "key", Name: name, Action: action}
+			if action == Skipped {
+				result.Reason = "exists"
+			}
+			results = append(results, result)
+		}
+	}
+
+	return results, nil
+}
+
+// seedSecret creates a secret with its value as the first version, or with
+// opts.Update adds a version to an existing secret whose value changed
+func seedSecret(project string, secret Secret, sm SecretManager, opts Options) (Result, error) {
+	result := Result{Kind: "secret", Name: secret.Name}
+	if sm == nil {
+		result.Action, result.Reason = Skipped, "secret-manager not running"
+		return result, nil
+	}
+
+	action, err := create(func() error { return sm.CreateSecret(project, secret.Name, secret.Labels) })
+	if err != nil {
+		return result, err
+	}
+	if action == Created {
+		result.Action = Created
+		return result, sm.AddSecretVersion(project, secret.Name, secret.data)
+	}
+
+	result.Action = Skipped
+	if !opts.Update {
+		result.Reason = "exists"
+		return result, nil
+	}
+	latest, err := sm.LatestSecretVersion(project, secret.Name)
+	if err != nil && !errors.Is(err, emulator.ErrNotFound) {
+		return result, err
+	}
+	if err == nil && bytes.Equal(latest, secret.data) {
+		result.Reason = "unchanged"
+		return result, nil
+	}
+	result.Action = Updated
+	return result, sm.AddSecretVersion(project, secret.Name, secret.data)
+}
+
+// create runs a create call, turning ErrAlreadyExists into Skipped
+func create(call func() error) (Action, error) {
+	err := call()
+	if errors.Is(err, emulator.ErrAlreadyExists) {
+		return Skipped, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return Created, nil
+}
+
+// Count returns how many results have each action
+func Count(results []Result) map[Action]int {
+	counts := map[Action]int{}
+	for _, result := range results {
+		counts[result.Action]++
+	}
+	return counts
+}
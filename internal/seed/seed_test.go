@@ -0,0 +1,112 @@
+package seed
+
+import (
+	"testing"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator"
+)
+
+// fakeSecretManager keeps secrets as their versions
+type fakeSecretManager struct {
+	secrets map[string][][]byte
+}
+
+func (f *fakeSecretManager) CreateSecret(project, id string, labels map[string]string) error {
+	if _, ok := f.secrets[id]; ok {
+		return emulator.ErrAlreadyExists
+	}
+	f.secrets[id] = nil
+	return nil
+}
+
+func (f *fakeSecretManager) AddSecretVersion(project, id string, data []byte) error {
+	f.secrets[id] = append(f.secrets[id], data)
+	return nil
+}
+
+func (f *fakeSecretManager) LatestSecretVersion(project, id string) ([]byte, error) {
+	versions := f.secrets[id]
+	if len(versions) == 0 {
+		return nil, emulator.ErrNotFound
+	}
+	return versions[len(versions)-1], nil
+}
+
+// fakeKMS keeps the names of the key rings and keys created
+type fakeKMS struct {
+	created map[string]bool
+}
+
+func (f *fakeKMS) add(name string) error {
+	if f.created[name] {
+		return emulator.ErrAlreadyExists
+	}
+	f.created[name] = true
+	return nil
+}
+
+func (f *fakeKMS) CreateKeyRing(project, location, id string) error {
+	return f.add(location + "/" + id)
+}
+
+func (f *fakeKMS) CreateCryptoKey(project, location, keyRing, id, purpose, algorithm string) error {
+	return f.add(location + "/" + keyRing + "/" + id)
+}
+
+func testFixtures() *Fixtures {
+	return &Fixtures{
+		Project: "test-project",
+		Secrets: []Secret{
+			{Name: "db-password", data: []byte("hunter2")},
+			{Name: "api-key", data: []byte("key1")},
+		},
+		KeyRings: []KeyRing{
+			{Name: "app", Location: "global", Keys: []CryptoKey{{Name: "data"}}},
+		},
+	}
+}
+
+func TestSeedIdempotent(t *testing.T) {
+	sm := &fakeSecretManager{secrets: map[string][][]byte{}}
+	kms := &fakeKMS{created: map[string]bool{}}
+
+	results, err := Seed(testFixtures(), sm, kms, Options{})
+	if err != nil {
+		t.Fatalf("Seed failed: %v", err)
+	}
+	if counts := Count(results); counts[Created] != 4 {
+		t.Errorf("first seed = %v, want 4 created", counts)
+	}
+
+	results, err = Seed(testFixtures(), sm, kms, Options{})
+	if err != nil {
+		t.Fatalf("second Seed failed: %v", err)
+	}
+	if counts := Count(results); counts[Skipped] != 4 {
+		t.Errorf("second seed = %v, want 4 skipped", counts)
+	}
+	if len(sm.secrets["db-password"]) != 1 {
+		t.Errorf("db-password has %d versions, want 1", len(sm.secrets["db-password"]))
+	}
+}
+
+func TestSeedUpdate(t *testing.T) {
+	sm := &fakeSecretManager{secrets: map[string][][]byte{
+		"db-password": {[]byte("hunter2")},
+		"api-key":     {[]byte("old")},
+	}}
+
+	results, err := Seed(testFixtures(), sm, nil, Options{Update: true})
+	if err != nil {
+		t.Fatalf("Seed failed: %v", err)
+	}
+	want := map[string]Action{"db-password": Skipped, "api-key": Updated, "app": Skipped}
+	for _, result := range results {
+		if result.Action != want[result.Name] {
+			t.Errorf("%s %s = %s, want %s", result.Kind, result.Name, result.Action, want[result.Name])
+		}
+	}
+	if versions := sm.secrets["api-key"]; len(versions) != 2 || string(versions[1]) != "key1" {
+		t.Errorf("api-key versions = %q", versions)
+	}
+}
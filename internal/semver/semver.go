@@ -0,0 +1,215 @@
+// Package semver parses the semantic versions image tags are named with,
+// such as v0.3.1, and the constraints upgrade checks them against, such
+// as ~0.3.
+package semver
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidVersion is returned for a string that is not a version
+var ErrInvalidVersion = errors.New("not a semantic version")
+
+// Version is a semantic version. Build metadata is dropped, since it
+// doesn't take part in ordering.
+type Version struct {
+	Major, Minor, Patch int
+
+	// Pre is the pre-release, such as rc1 in 0.4.0-rc1, or ""
+	Pre string
+}
+
+// Parse parses a version of up to three numbers with an optional leading
+// v, pre-release and build metadata: v1.2.3, 1.2.3-rc1, 1.2
+func Parse(s string) (Version, error) {
+	v, _, err := parse(s)
+	return v, err
+}
+
+// parse parses a version as Parse does, and also returns how many of its
+// numbers were given
+func parse(s string) (Version, int, error) {
+	text := strings.TrimPrefix(s, "v")
+	text, _, _ = strings.Cut(text, "+")
+	text, pre, hasPre := strings.Cut(text, "-")
+	if hasPre && pre == "" {
+		return Version{}, 0, fmt.Errorf("%w: %q", ErrInvalidVersion, s)
+	}
+
+	parts := strings.Split(text, ".")
+	if len(parts) > 3 {
+		return Version{}, 0, fmt.Errorf("%w: %q", ErrInvalidVersion, s)
+	}
+	var numbers [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || (len(part) > 1 && part[0] == '0') {
+			return Version{}, 0, fmt.Errorf("%w: %q", ErrInvalidVersion, s)
+		}
+		numbers[i] = n
+	}
+	return Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2], Pre: pre}, len(parts), nil
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// Compare returns -1, 0, or 1 as v is older than, the same as, or newer
+// than o. A pre-release is older than its release; pre-releases of the
+// same version are compared as strings.
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	}
+	return strings.Compare(v.Pre, o.Pre)
+}
+
+func sign(n int) int {
+	if n < 0 {
+		return -1
+	}
+	return 1
+}
+
+// Constraint is a set of bounds a version must all meet
+type Constraint struct {
+	text   string
+	bounds []bound
+}
+
+// bound is a comparison with a version, such as >= 0.3.0
+type bound struct {
+	op string
+	v  Version
+}
+
+func (b bound) allows(v Version) bool {
+	c := v.Compare(b.v)
+	switch b.op {
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+	return c == 0
+}
+
+// ParseConstraint parses a constraint of bounds separated by spaces or
+// commas, all of which must hold:
+//
+//	~1.2    1.2.x: >= 1.2.0, < 1.3.0 (~1 is 1.x)
+//	^1.2    1.x from 1.2.0: >= 1.2.0, < 2.0.0 (^0.3 is 0.3.x)
+//	1.2     1.2.x, like ~1.2; 1.2.3 is that version only
+//	>=1.2 <1.5, >1.2, <=1.4
+//
+// "" and * allow any version.
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{text: s}
+	for _, term := range strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' }) {
+		if term == "*" {
+			continue
+		}
+		bounds, err := parseTerm(term)
+		if err != nil {
+			return Constraint{}, fmt.Errorf("invalid version constraint %q: %w", s, err)
+		}
+		c.bounds = append(c.bounds, bounds...)
+	}
+	return c, nil
+}
+
+// parseTerm returns the bounds of one term of a constraint
+func parseTerm(term string) ([]bound, error) {
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if rest, ok := strings.CutPrefix(term, op); ok {
+			v, _, err := parse(rest)
+			if err != nil {
+				return nil, err
+			}
+			return []bound{{op, v}}, nil
+		}
+	}
+
+	op := term[:1]
+	switch op {
+	case "~", "^":
+		term = term[1:]
+	default:
+		op = ""
+	}
+	term = strings.TrimSuffix(strings.TrimSuffix(term, ".x"), ".x")
+	v, given, err := parse(term)
+	if err != nil {
+		return nil, err
+	}
+	if v.Pre != "" && op != "" {
+		return nil, fmt.Errorf("%s can't be used with a pre-release: %s", op, term)
+	}
+
+	// The upper bound is the next version after the numbers that must stay
+	var upper Version
+	switch {
+	case op == "" && given == 3:
+		return []bound{{"=", v}}, nil
+	case op == "^" && v.Major > 0, given == 1:
+		upper = Version{Major: v.Major + 1}
+	case op == "^" && v.Minor == 0 && given == 3:
+		upper = Version{Minor: v.Minor, Patch: v.Patch + 1}
+	default:
+		upper = Version{Major: v.Major, Minor: v.Minor + 1}
+	}
+	return []bound{{">=", v}, {"<", upper}}, nil
+}
+
+// Allows reports whether v meets every bound. Pre-releases are allowed
+// only by a constraint that names one.
+func (c Constraint) Allows(v Version) bool {
+	if v.Pre != "" && !c.namesPre() {
+		return false
+	}
+	for _, b := range c.bounds {
+		if !b.allows(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// namesPre reports whether one of c's bounds is a pre-release
+func (c Constraint) namesPre() bool {
+	for _, b := range c.bounds {
+		if b.v.Pre != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Constraint) String() string {
+	if c.text == "" {
+		return "*"
+	}
+	return c.text
+}
@@ -0,0 +1,103 @@
+package semver
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Version
+	}{
+		{"v1.2.3", Version{Major: 1, Minor: 2, Patch: 3}},
+		{"0.4.0-rc1", Version{Minor: 4, Pre: "rc1"}},
+		{"1.2", Version{Major: 1, Minor: 2}},
+		{"v2", Version{Major: 2}},
+		{"1.0.0+build.5", Version{Major: 1}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"latest", "1.2.3.4", "01.2", "1.x", "v1.2-", ""} {
+		if _, err := Parse(in); !errors.Is(err, ErrInvalidVersion) {
+			t.Errorf("Parse(%q) = %v, want ErrInvalidVersion", in, err)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2.3", "1.10.0", -1},
+		{"2.0.0", "1.99.99", 1},
+		{"0.4.0-rc1", "0.4.0", -1},
+		{"0.4.0-rc2", "0.4.0-rc1", 1},
+	}
+	for _, tt := range tests {
+		a, _ := Parse(tt.a)
+		b, _ := Parse(tt.b)
+		if got := a.Compare(b); got != tt.want {
+			t.Errorf("%s.Compare(%s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestConstraintAllows(t *testing.T) {
+	tests := []struct {
+		constraint string
+		allowed    []string
+		denied     []string
+	}{
+		{"", []string{"0.1.0", "9.9.9"}, []string{"1.0.0-rc1"}},
+		{"*", []string{"0.1.0"}, nil},
+		{"~0.3", []string{"0.3.0", "0.3.9"}, []string{"0.2.9", "0.4.0", "0.3.5-rc1"}},
+		{"~0.3.2", []string{"0.3.2", "0.3.4"}, []string{"0.3.1", "0.4.0"}},
+		{"~1", []string{"1.0.0", "1.9.0"}, []string{"2.0.0"}},
+		{"^1.2", []string{"1.2.0", "1.9.9"}, []string{"1.1.9", "2.0.0"}},
+		{"^0.3", []string{"0.3.1"}, []string{"0.4.0"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"0.3", []string{"0.3.7"}, []string{"0.4.0"}},
+		{"0.3.x", []string{"0.3.7"}, []string{"0.4.0"}},
+		{"1.2.3", []string{"1.2.3"}, []string{"1.2.4"}},
+		{">=0.3 <0.5", []string{"0.3.0", "0.4.9"}, []string{"0.2.0", "0.5.0"}},
+		{">0.3.0, <=0.4.0", []string{"0.3.1", "0.4.0"}, []string{"0.3.0", "0.4.1"}},
+		{">=0.4.0-rc1", []string{"0.4.0-rc2", "0.4.0"}, []string{"0.4.0-beta"}},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Errorf("ParseConstraint(%q) failed: %v", tt.constraint, err)
+			continue
+		}
+		for _, s := range tt.allowed {
+			if v, _ := Parse(s); !c.Allows(v) {
+				t.Errorf("%q should allow %s", tt.constraint, s)
+			}
+		}
+		for _, s := range tt.denied {
+			if v, _ := Parse(s); c.Allows(v) {
+				t.Errorf("%q should not allow %s", tt.constraint, s)
+			}
+		}
+	}
+}
+
+func TestParseConstraintInvalid(t *testing.T) {
+	for _, s := range []string{"~latest", ">=", "~1.2.3-rc1", "1.2.3.4"} {
+		if _, err := ParseConstraint(s); err == nil {
+			t.Errorf("ParseConstraint(%q) should fail", s)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package snapshot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+// ImageMismatch is a service whose image changed major version since a
+// snapshot was taken
+type ImageMismatch struct {
+	Service  string
+	Snapshot string
+	Current  string
+}
+
+// ImageMismatchError is returned by CheckImages when the configured
+// images aren't the major versions a snapshot was taken with, whose
+// emulators may not read the restored state
+type ImageMismatchError struct {
+	Name       string
+	Mismatches []ImageMismatch
+}
+
+func (e *ImageMismatchError) Error() string {
+	changes := make([]string, len(e.Mismatches))
+	for i, m := range e.Mismatches {
+		changes[i] = fmt.Sprintf("%s %s, now %s", m.Service, m.Snapshot, m.Current)
+	}
+	return fmt.Sprintf("snapshot %s was taken with different image versions (%s); use --force to restore it anyway",
+		e.Name, strings.Join(changes, "; "))
+}
+
+// CheckImages checks that images, the images the stack is configured
+// with, have the major versions the snapshot was taken with
+func CheckImages(m *Manifest, images config.ImageConfig) error {
+	var mismatches []ImageMismatch
+	for _, image := range []struct{ service, snapshot, current string }{
+		{"iam", m.Images.IAM, images.IAM},
+		{"secret-manager", m.Images.SecretManager, images.SecretManager},
+		{"kms", m.Images.KMS, images.KMS},
+	} {
+		if imageMajor(image.snapshot) != imageMajor(image.current) {
+			mismatches = append(mismatches, ImageMismatch{
+				Service:  image.service,
+				Snapshot: image.snapshot,
+				Current:  image.current,
+			})
+		}
+	}
+	if len(mismatches) > 0 {
+		return &ImageMismatchError{Name: m.Name, Mismatches: mismatches}
+	}
+	return nil
+}
+
+// majorPattern matches the major version of a semver tag, v1.2.3 or 1.2
+var majorPattern = regexp.MustCompile(`^v?(\d+)(?:[.-]|$)`)
+
+// imageMajor returns an image reference cut down to its repository and
+// major version, so v0.4.0 and v0.5.1 of an image compare equal. A ref
+// whose tag isn't a version, such as latest, is returned whole without
+// its digest.
+func imageMajor(ref string) string {
+	ref, _, _ = strings.Cut(ref, "@")
+	repo, tag := ref, ""
+	if i := strings.LastIndexByte(ref, ':'); i > strings.LastIndexByte(ref, '/') {
+		repo, tag = ref[:i], ref[i+1:]
+	}
+	if match := majorPattern.FindStringSubmatch(tag); match != nil {
+		return repo + ":v" + match[1]
+	}
+	return ref
+}
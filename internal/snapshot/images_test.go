@@ -0,0 +1,48 @@
+package snapshot
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+func TestImageMajor(t *testing.T) {
+	tests := []struct{ ref, want string }{
+		{"ghcr.io/blackwell-systems/gcp-kms-emulator-dual:v0.4.0", "ghcr.io/blackwell-systems/gcp-kms-emulator-dual:v0"},
+		{"ghcr.io/blackwell-systems/gcp-kms-emulator-dual:1.2", "ghcr.io/blackwell-systems/gcp-kms-emulator-dual:v1"},
+		{"ghcr.io/blackwell-systems/gcp-kms-emulator-dual:v2.0.0-rc1@sha256:abc", "ghcr.io/blackwell-systems/gcp-kms-emulator-dual:v2"},
+		{"localhost:5000/kms:latest", "localhost:5000/kms:latest"},
+		{"localhost:5000/kms", "localhost:5000/kms"},
+	}
+	for _, tt := range tests {
+		if got := imageMajor(tt.ref); got != tt.want {
+			t.Errorf("imageMajor(%q) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestCheckImages(t *testing.T) {
+	m := &Manifest{Name: "demo1", Images: Images{
+		IAM:           "ghcr.io/blackwell-systems/gcp-iam-emulator:v0.5.0",
+		SecretManager: "ghcr.io/blackwell-systems/gcp-secret-manager-emulator:v1.2.0",
+		KMS:           "ghcr.io/blackwell-systems/gcp-kms-emulator:v0.3.0",
+	}}
+	images := config.ImageConfig{
+		IAM:           "ghcr.io/blackwell-systems/gcp-iam-emulator:v0.6.1",
+		SecretManager: "ghcr.io/blackwell-systems/gcp-secret-manager-emulator:v1.2.0",
+		KMS:           "ghcr.io/blackwell-systems/gcp-kms-emulator:v0.3.0",
+	}
+	if err := CheckImages(m, images); err != nil {
+		t.Errorf("CheckImages with the same major versions = %v", err)
+	}
+
+	images.SecretManager = "ghcr.io/blackwell-systems/gcp-secret-manager-emulator:v2.0.0"
+	var mismatch *ImageMismatchError
+	if err := CheckImages(m, images); !errors.As(err, &mismatch) {
+		t.Fatalf("CheckImages = %v, want ImageMismatchError", err)
+	}
+	if len(mismatch.Mismatches) != 1 || mismatch.Mismatches[0].Service != "secret-manager" {
+		t.Errorf("mismatches = %+v", mismatch.Mismatches)
+	}
+}
@@ -0,0 +1,502 @@
+// Package snapshot saves the state of the emulator stack and brings it
+// back: the docker volumes of its compose project and the policy file,
+// archived under the snapshots directory.
+//
+// Each snapshot is a gzipped tar file, <name>.tar.gz, next to a manifest,
+// <name>.json, that records what the archive holds and its checksum.
+// Create and Restore expect the stack to be stopped.
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var (
+	ErrNotFound = errors.New("snapshot not found")
+	ErrExists   = errors.New("snapshot already exists")
+	ErrChecksum = errors.New("snapshot checksum mismatch")
+)
+
+// namePattern keeps snapshot names usable as file names
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// policyEntry is the policy file's name in the archive, its first
+// entry when there is one; volumes are
+// stored as volumes/<volume>.tar
+const policyEntry = "policy.yaml"
+
+// Manifest describes a snapshot
+type Manifest struct {
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+
+	// Project is the compose project the volumes were taken from
+	Project string `json:"project"`
+
+	// Images are the images the stack was configured with
+	Images Images `json:"images"`
+
+	Volumes []Volume `json:"volumes"`
+
+	// PolicyFile is where the policy file was read from, or "" if there
+	// was none
+	PolicyFile string `json:"policyFile,omitempty"`
+
+	// SHA256 is the checksum of the archive
+	SHA256 string `json:"sha256"`
+
+	// Size is the size of the archive in bytes
+	Size int64 `json:"size"`
+}
+
+// Images are the images of a snapshot's stack, by service
+type Images struct {
+	IAM           string `json:"iam"`
+	SecretManager string `json:"secretManager"`
+	KMS           string `json:"kms"`
+}
+
+// Volume is a docker volume in a snapshot
+type Volume struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Dir returns the directory holding the snapshots: snapshots in
+// config.StateDir
+func Dir() (string, error) {
+	dir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "snapshots"), nil
+}
+
+// ValidateName checks that name can be used as a snapshot name
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name: %q (use letters, digits, ., - and _)", name)
+	}
+	return nil
+}
+
+// paths returns the archive and manifest of the snapshot called name
+func paths(name string) (archive, manifest string, err error) {
+	if err := ValidateName(name); err != nil {
+		return "", "", err
+	}
+	dir, err := Dir()
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Join(dir, name+".tar.gz"), filepath.Join(dir, name+".json"), nil
+}
+
+// Create archives volumes, which must belong to cfg's stack, and cfg's
+// policy file, if there is one, as the snapshot called name
+func Create(cfg *config.Config, name string, volumes []string) (*Manifest, error) {
+	archivePath, manifestPath, err := paths(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(manifestPath); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrExists, name)
+	}
+	if err := os.MkdirAll(filepath.Dir(archivePath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshots directory: %w", err)
+	}
+
+	images := docker.Images(cfg)
+	m := &Manifest{
+		Name:    name,
+		Created: time.Now().UTC().Truncate(time.Second),
+		Project: docker.ProjectName(cfg),
+		Images: Images{
+			IAM:           images.IAM,
+			SecretManager: images.SecretManager,
+			KMS:           images.KMS,
+		},
+	}
+
+	// Written to a temporary file first, so a failed snapshot leaves
+	// nothing behind
+	tmp, err := os.CreateTemp(filepath.Dir(archivePath), "."+name+"-*.tar.gz")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	sum := sha256.New()
+	counter := &countWriter{w: io.MultiWriter(tmp, sum)}
+	gz := gzip.NewWriter(counter)
+	tw := tar.NewWriter(gz)
+
+	if data, err := os.ReadFile(cfg.PolicyFile); err == nil {
+		if err := writeEntry(tw, policyEntry, data); err != nil {
+			return nil, err
+		}
+		if m.PolicyFile, err = filepath.Abs(cfg.PolicyFile); err != nil {
+			return nil, fmt.Errorf("failed to resolve policy file %s: %w", cfg.PolicyFile, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	for _, volume := range volumes {
+		labels, err := docker.VolumeLabels(cfg, volume)
+		if err != nil {
+			return nil, err
+		}
+		// Volumes are exported to a temporary file, since a tar entry needs
+		// its size up front
+		data, err := os.CreateTemp("", "gcp-emulator-volume-*.tar")
+		if err != nil {
+			return nil, fmt.Errorf("failed to export volume %s: %w", volume, err)
+		}
+		err = exportVolume(cfg, tw, volume, data)
+		data.Close()
+		os.Remove(data.Name())
+		if err != nil {
+			return nil, err
+		}
+		m.Volumes = append(m.Volumes, Volume{Name: volume, Labels: labels})
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	m.SHA256 = hex.EncodeToString(sum.Sum(nil))
+	m.Size = counter.n
+
+	if err := os.Rename(tmp.Name(), archivePath); err != nil {
+		return nil, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := writeManifest(manifestPath, m); err != nil {
+		os.Remove(archivePath)
+		return nil, err
+	}
+	return m, nil
+}
+
+// exportVolume exports volume into the file data, then copies it into tw
+func exportVolume(cfg *config.Config, tw *tar.Writer, volume string, data *os.File) error {
+	if err := docker.ExportVolume(cfg, volume, data); err != nil {
+		return err
+	}
+	info, err := data.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to export volume %s: %w", volume, err)
+	}
+	if _, err := data.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to export volume %s: %w", volume, err)
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    "volumes/" + volume + ".tar",
+		Mode:    0644,
+		Size:    info.Size(),
+		ModTime: time.Now(),
+	}); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if _, err := io.Copy(tw, data); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// writeEntry adds a file called name holding data to tw
+func writeEntry(tw *tar.Writer, name string, data []byte) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+func writeManifest(path string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot manifest: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write snapshot manifest: %w", err)
+	}
+	return nil
+}
+
+// countWriter counts the bytes written through it
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Load returns the manifest of the snapshot called name
+func Load(name string) (*Manifest, error) {
+	_, manifestPath, err := paths(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(manifestPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot manifest %s: %w", manifestPath, err)
+	}
+	return &m, nil
+}
+
+// List returns the manifests of all snapshots, oldest first
+func List() ([]Manifest, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshots directory: %w", err)
+	}
+
+	var manifests []Manifest
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		m, err := Load(name)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, *m)
+	}
+	slices.SortFunc(manifests, func(a, b Manifest) int {
+		return a.Created.Compare(b.Created)
+	})
+	return manifests, nil
+}
+
+// Delete removes the snapshot called name
+func Delete(name string) error {
+	archivePath, manifestPath, err := paths(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(manifestPath); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	} else if err != nil {
+		return fmt.Errorf("failed to delete snapshot: %w", err)
+	}
+	if err := os.Remove(archivePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete snapshot: %w", err)
+	}
+	return nil
+}
+
+// Verify checks the snapshot's archive against the checksum in its
+// manifest
+func Verify(m *Manifest) error {
+	archivePath, _, err := paths(m.Name)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	sum := sha256.New()
+	if _, err := io.Copy(sum, f); err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if got := hex.EncodeToString(sum.Sum(nil)); got != m.SHA256 {
+		return fmt.Errorf("%w: %s is %s, manifest has %s", ErrChecksum, archivePath, got, m.SHA256)
+	}
+	return nil
+}
+
+// CheckProject checks that cfg selects the compose project the snapshot
+// was taken from, which its volumes belong to
+func CheckProject(cfg *config.Config, m *Manifest) error {
+	if project := docker.ProjectName(cfg); project != m.Project {
+		return fmt.Errorf("snapshot %s was taken from compose project %s, not %s; select its profile or compose-project to restore it", m.Name, m.Project, project)
+	}
+	return nil
+}
+
+// PolicyFileMismatchError is returned by CheckPolicyFile when the
+// snapshot's policy file isn't the configured one
+type PolicyFileMismatchError struct {
+	Name     string
+	Snapshot string
+	Current  string
+}
+
+func (e *PolicyFileMismatchError) Error() string {
+	return fmt.Sprintf("snapshot %s was taken with policy file %s, not %s; use --force to restore it anyway",
+		e.Name, e.Snapshot, e.Current)
+}
+
+// CheckPolicyFile checks that the snapshot's policy file is cfg's, so
+// the stack restarts with the policy that was restored
+func CheckPolicyFile(cfg *config.Config, m *Manifest) error {
+	if m.PolicyFile == "" {
+		return nil
+	}
+	current, err := filepath.Abs(cfg.PolicyFile)
+	if err != nil {
+		return fmt.Errorf("failed to resolve policy file %s: %w", cfg.PolicyFile, err)
+	}
+	if current != m.PolicyFile {
+		return &PolicyFileMismatchError{Name: m.Name, Snapshot: m.PolicyFile, Current: current}
+	}
+	return nil
+}
+
+// CheckPolicyWrite checks that restoring the snapshot's policy file
+// doesn't write a plaintext policy over an encrypted one
+func CheckPolicyWrite(m *Manifest) error {
+	if m.PolicyFile == "" {
+		return nil
+	}
+	data, err := archivedPolicy(m)
+	if err != nil || policy.IsEncrypted(data) {
+		return err
+	}
+	return policy.CheckPlaintextWrite(m.PolicyFile)
+}
+
+// archivedPolicy reads the policy file archived in the snapshot
+func archivedPolicy(m *Manifest) ([]byte, error) {
+	archivePath, _, err := paths(m.Name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	header, err := tr.Next()
+	if err != nil || header.Name != policyEntry {
+		return nil, fmt.Errorf("failed to read snapshot: no %s entry", policyEntry)
+	}
+	data, err := io.ReadAll(tr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	return data, nil
+}
+
+// Restore writes the snapshot's policy file back where it was read from
+// and recreates its volumes, which must not exist. Verify the snapshot,
+// and check its policy file with CheckPolicyFile, first.
+func Restore(cfg *config.Config, m *Manifest) error {
+	if err := CheckProject(cfg, m); err != nil {
+		return err
+	}
+	if err := CheckPolicyWrite(m); err != nil {
+		return err
+	}
+	archivePath, _, err := paths(m.Name)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	volumes := make(map[string]Volume, len(m.Volumes))
+	for _, volume := range m.Volumes {
+		volumes[volume.Name] = volume
+	}
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot: %w", err)
+		}
+
+		if header.Name == policyEntry {
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return fmt.Errorf("failed to read snapshot: %w", err)
+			}
+			if err := os.WriteFile(m.PolicyFile, data, 0644); err != nil {
+				return fmt.Errorf("failed to restore policy file: %w", err)
+			}
+			continue
+		}
+
+		name, ok := strings.CutPrefix(header.Name, "volumes/")
+		name, isTar := strings.CutSuffix(name, ".tar")
+		volume, known := volumes[name]
+		if !ok || !isTar || !known {
+			return fmt.Errorf("failed to read snapshot: unexpected entry %s", header.Name)
+		}
+		if err := docker.ImportVolume(cfg, volume.Name, volume.Labels, tr); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package snapshot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+// withTestState points the state directory at a temporary directory and
+// returns a config whose policy file is in it
+func withTestState(t *testing.T) *config.Config {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_STATE_HOME", filepath.Join(dir, "state"))
+
+	cfg := config.Defaults()
+	cfg.PolicyFile = filepath.Join(dir, "policy.yaml")
+	return cfg
+}
+
+func TestCreateRestore(t *testing.T) {
+	cfg := withTestState(t)
+	if err := os.WriteFile(cfg.PolicyFile, []byte("roles: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Create(cfg, "demo1", nil)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if m.PolicyFile != cfg.PolicyFile || m.SHA256 == "" || m.Size == 0 {
+		t.Errorf("manifest = %+v", m)
+	}
+	if _, err := Create(cfg, "demo1", nil); !errors.Is(err, ErrExists) {
+		t.Errorf("Create of an existing snapshot = %v, want ErrExists", err)
+	}
+
+	loaded, err := Load("demo1")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := Verify(loaded); err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+
+	if err := os.WriteFile(cfg.PolicyFile, []byte("changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Restore(cfg, loaded); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if data, _ := os.ReadFile(cfg.PolicyFile); string(data) != "roles: {}\n" {
+		t.Errorf("restored policy = %q", data)
+	}
+
+	other := *cfg
+	other.Profile = "ci"
+	if err := Restore(&other, loaded); err == nil {
+		t.Error("Restore into another compose project succeeded")
+	}
+}
+
+// TestRestorePolicyFile checks the policy file is restored where it was
+// taken from, never over another policy or an encrypted one
+func TestRestorePolicyFile(t *testing.T) {
+	cfg := withTestState(t)
+	if err := os.WriteFile(cfg.PolicyFile, []byte("roles: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := Create(cfg, "demo1", nil)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	other := *cfg
+	other.PolicyFile = filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(other.PolicyFile, []byte("unrelated\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var mismatch *PolicyFileMismatchError
+	if err := CheckPolicyFile(&other, m); !errors.As(err, &mismatch) {
+		t.Errorf("CheckPolicyFile with another policy file = %v, want PolicyFileMismatchError", err)
+	}
+	if err := CheckPolicyFile(cfg, m); err != nil {
+		t.Errorf("CheckPolicyFile = %v", err)
+	}
+
+	if err := os.WriteFile(cfg.PolicyFile, []byte("changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Restore(&other, m); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if data, _ := os.ReadFile(cfg.PolicyFile); string(data) != "roles: {}\n" {
+		t.Errorf("restored policy = %q", data)
+	}
+	if data, _ := os.ReadFile(other.PolicyFile); string(data) != "unrelated\n" {
+		t.Errorf("Restore overwrote the configured policy file: %q", data)
+	}
+
+	encrypted, err := policy.Encrypt([]byte("roles: {}\n"), "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cfg.PolicyFile, encrypted, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Restore(cfg, m); !errors.Is(err, policy.ErrWouldDecrypt) {
+		t.Errorf("Restore over an encrypted policy = %v, want ErrWouldDecrypt", err)
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	cfg := withTestState(t)
+	m, err := Create(cfg, "demo1", nil)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	archive, _, _ := paths("demo1")
+	if err := os.WriteFile(archive, []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(m); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Verify of a changed archive = %v, want ErrChecksum", err)
+	}
+}
+
+func TestListDelete(t *testing.T) {
+	cfg := withTestState(t)
+	if list, err := List(); err != nil || len(list) != 0 {
+		t.Fatalf("List without snapshots = %v, %v", list, err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if _, err := Create(cfg, name, nil); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	list, err := List()
+	if err != nil || len(list) != 2 {
+		t.Fatalf("List = %v, %v", list, err)
+	}
+
+	if err := Delete("a"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := Delete("a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete = %v, want ErrNotFound", err)
+	}
+	if _, err := Load("a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Load of a deleted snapshot = %v, want ErrNotFound", err)
+	}
+	if list, _ := List(); len(list) != 1 || list[0].Name != "b" {
+		t.Errorf("List after Delete = %v", list)
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"demo1", "before-upgrade", "v0.4_a"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("ValidateName(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "../x", ".hidden", "a/b"} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("ValidateName(%q) succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package token
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMint(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	tok, err := Mint("user:alice@example.com", "https://secretmanager.googleapis.com/", time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(tok, "eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0.") || !strings.HasSuffix(tok, ".") {
+		t.Errorf("Expected an unsigned JWT, got %s", tok)
+	}
+
+	claims, err := Parse(tok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Claims{
+		Issuer:    Issuer,
+		Subject:   "user:alice@example.com",
+		Email:     "alice@example.com",
+		Audience:  "https://secretmanager.googleapis.com/",
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(time.Hour).Unix(),
+	}
+	if *claims != want {
+		t.Errorf("Parse() = %+v, want %+v", *claims, want)
+	}
+	if claims.Expired(now.Add(59*time.Minute)) || !claims.Expired(now.Add(time.Hour)) {
+		t.Error("Expected the token to expire after an hour")
+	}
+
+	if _, err := Mint("user:alice@example.com", "", 0, now); err == nil {
+		t.Error("Expected an error for a zero lifetime")
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, tok := range []string{
+		"",
+		"abc",
+		"eyJhbGciOiJSUzI1NiJ9.e30.c2ln",
+		"eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0.!!!.",
+		"eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0.e30.",
+	} {
+		if _, err := Parse(tok); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Parse(%q) = %v, want ErrInvalidToken", tok, err)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Reference is an image reference split into the parts a registry is
+// asked about
+type Reference struct {
+	// Domain is the registry as written in the reference: ghcr.io, or
+	// docker.io when none is given
+	Domain string
+
+	// Repository is the image's path in the registry, with library/
+	// added for official Docker Hub images
+	Repository string
+
+	// Tag and Digest are "" when the reference doesn't give them
+	Tag    string
+	Digest string
+
+	// name is the reference without tag or digest, as written
+	name string
+}
+
+// ParseReference splits an image reference the way docker does: the
+// first path component is the registry if it has a dot or port or is
+// localhost, and a reference without tag or digest means latest
+func ParseReference(ref string) (Reference, error) {
+	if ref == "" {
+		return Reference{}, errors.New("empty image reference")
+	}
+
+	r := Reference{}
+	name, digest, _ := strings.Cut(ref, "@")
+	r.Digest = digest
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, r.Tag = name[:i], name[i+1:]
+	}
+	if r.Tag == "" && r.Digest == "" {
+		r.Tag = "latest"
+	}
+	r.name = name
+
+	domain, path, ok := strings.Cut(name, "/")
+	if !ok || (!strings.ContainsAny(domain, ".:") && domain != "localhost") {
+		domain, path = "docker.io", name
+	}
+	if domain == "docker.io" && !strings.Contains(path, "/") {
+		path = "library/" + path
+	}
+	r.Domain, r.Repository = domain, path
+	return r, nil
+}
+
+// WithTag returns the reference with tag instead of its tag and digest
+func (r Reference) WithTag(tag string) string {
+	return r.name + ":" + tag
+}
+
+// apiHost returns the host serving the registry API of r's domain
+func (r Reference) apiHost() string {
+	if r.Domain == "docker.io" {
+		return "registry-1.docker.io"
+	}
+	return r.Domain
+}
+
+// Registry reads tags and manifests from image registries, with the
+// anonymous token a public image needs
+type Registry struct {
+	HTTP *http.Client
+}
+
+// Timeout bounds each registry or release request
+const Timeout = 30 * time.Second
+
+// NewRegistry returns a Registry with a client that times out
+func NewRegistry() *Registry {
+	return &Registry{HTTP: &http.Client{Timeout: Timeout}}
+}
+
+// manifestTypes are the manifests asked for, so a multi-platform image
+// reports the digest of its index, as docker records it
+var manifestTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Tags returns every tag of r's repository
+func (reg *Registry) Tags(ctx context.Context, r Reference) ([]string, error) {
+	next := reg.url(r, "/tags/list")
+	var tags []string
+	for next != "" {
+		resp, err := reg.get(ctx, http.MethodGet, next, "")
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse tags of %s: %w", r.name, err)
+		}
+		tags = append(tags, page.Tags...)
+
+		// Long tag lists come in pages, linked from each one
+		next, err = nextPage(resp, next)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tags, nil
+}
+
+// Digest returns the digest of the manifest r's tag points to
+func (reg *Registry) Digest(ctx context.Context, r Reference) (string, error) {
+	resp, err := reg.get(ctx, http.MethodHead, reg.url(r, "/manifests/"+r.Tag), strings.Join(manifestTypes, ", "))
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry %s returned no digest for %s:%s", r.Domain, r.name, r.Tag)
+	}
+	return digest, nil
+}
+
+// url returns the registry API URL of path in r's repository. Registries
+// on a loopback address are spoken to over plain HTTP, as docker does.
+func (reg *Registry) url(r Reference, path string) string {
+	scheme := "https"
+	host := r.apiHost()
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		scheme = "http"
+	}
+	return scheme + "://" + r.apiHost() + "/v2/" + r.Repository + path
+}
+
+// get sends a request, getting an anonymous bearer token and trying again
+// if the registry asks for one. It returns the response of a request that
+// succeeded, whose body the caller closes.
+func (reg *Registry) get(ctx context.Context, method, rawURL, accept string) (*http.Response, error) {
+	token := ""
+	for {
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := reg.HTTP.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("registry request failed: %w", err)
+		}
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusUnauthorized && token == "" {
+			if token, err = reg.token(ctx, resp.Header.Get("WWW-Authenticate")); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return nil, fmt.Errorf("registry returned %s for %s", resp.Status, rawURL)
+	}
+}
+
+// challengeParam matches a key="value" parameter of a WWW-Authenticate
+// challenge
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// token gets an anonymous pull token from the realm of a Bearer
+// challenge. Registries that want credentials refuse it.
+func (reg *Registry) token(ctx context.Context, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("registry wants %q authentication, which upgrade doesn't support", scheme)
+	}
+	values := url.Values{}
+	realm := ""
+	for _, m := range challengeParam.FindAllStringSubmatch(params, -1) {
+		if m[1] == "realm" {
+			realm = m[2]
+		} else {
+			values.Set(m[1], m[2])
+		}
+	}
+	if realm == "" {
+		return "", errors.New("registry sent a challenge without a realm")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm+"?"+values.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := reg.HTTP.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("registry token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry refused an anonymous token (%s); private images aren't supported", resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to parse registry token: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+// linkNext matches the next page in a Link header: <url>; rel="next"
+var linkNext = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="next"`)
+
+// nextPage returns the URL of the page after resp, resolved against the
+// URL it was fetched from, or "" on the last page
+func nextPage(resp *http.Response, current string) (string, error) {
+	m := linkNext.FindStringSubmatch(resp.Header.Get("Link"))
+	if m == nil {
+		return "", nil
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return "", err
+	}
+	next, err := base.Parse(m[1])
+	if err != nil {
+		return "", fmt.Errorf("invalid next page link %q: %w", m[1], err)
+	}
+	return next.String(), nil
+}
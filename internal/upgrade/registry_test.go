@@ -0,0 +1,119 @@
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		ref                             string
+		domain, repository, tag, digest string
+	}{
+		{"ghcr.io/blackwell-systems/gcp-kms-emulator-dual:v0.3.1", "ghcr.io", "blackwell-systems/gcp-kms-emulator-dual", "v0.3.1", ""},
+		{"ghcr.io/blackwell-systems/gcp-iam-emulator", "ghcr.io", "blackwell-systems/gcp-iam-emulator", "latest", ""},
+		{"localhost:5000/kms:1.0.0", "localhost:5000", "kms", "1.0.0", ""},
+		{"busybox:1.36", "docker.io", "library/busybox", "1.36", ""},
+		{"acme/kms", "docker.io", "acme/kms", "latest", ""},
+		{"ghcr.io/acme/kms@sha256:abc", "ghcr.io", "acme/kms", "", "sha256:abc"},
+	}
+	for _, tt := range tests {
+		r, err := ParseReference(tt.ref)
+		if err != nil {
+			t.Errorf("ParseReference(%q) failed: %v", tt.ref, err)
+			continue
+		}
+		if r.Domain != tt.domain || r.Repository != tt.repository || r.Tag != tt.tag || r.Digest != tt.digest {
+			t.Errorf("ParseReference(%q) = %+v", tt.ref, r)
+		}
+	}
+
+	r, _ := ParseReference("localhost:5000/kms:1.0.0")
+	if got := r.WithTag("1.1.0"); got != "localhost:5000/kms:1.1.0" {
+		t.Errorf("WithTag = %s", got)
+	}
+}
+
+// testRegistry serves tags and manifests of one repository, asking for a
+// bearer token like ghcr.io does. Tags come two to a page.
+func testRegistry(t *testing.T, repository string, tags []string, digests map[string]string) string {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.URL.Query().Get("scope") != "repository:"+repository+":pull" {
+				http.Error(w, "bad scope", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"token": "anon"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer anon" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:%s:pull"`, server.URL, repository))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		prefix := "/v2/" + repository
+		switch {
+		case r.URL.Path == prefix+"/tags/list":
+			start := 0
+			if last := r.URL.Query().Get("last"); last != "" {
+				start = slices.Index(tags, last) + 1
+			}
+			end := min(start+2, len(tags))
+			if end < len(tags) {
+				w.Header().Set("Link", fmt.Sprintf(`<%s/tags/list?n=2&last=%s>; rel="next"`, prefix, tags[end-1]))
+			}
+			fmt.Fprintf(w, `{"name": %q, "tags": ["%s"]}`, repository, strings.Join(tags[start:end], `", "`))
+		case strings.HasPrefix(r.URL.Path, prefix+"/manifests/"):
+			digest, ok := digests[strings.TrimPrefix(r.URL.Path, prefix+"/manifests/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", digest)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func TestRegistryTags(t *testing.T) {
+	tags := []string{"v0.3.0", "v0.3.1", "latest", "v0.4.0", "v0.3.2"}
+	host := testRegistry(t, "acme/kms", tags, nil)
+
+	ref, _ := ParseReference(host + "/acme/kms:v0.3.0")
+	got, err := NewRegistry().Tags(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("Tags failed: %v", err)
+	}
+	if !slices.Equal(got, tags) {
+		t.Errorf("Expected every page of tags, got %v", got)
+	}
+}
+
+func TestRegistryDigest(t *testing.T) {
+	host := testRegistry(t, "acme/kms", nil, map[string]string{"latest": "sha256:new"})
+
+	ref, _ := ParseReference(host + "/acme/kms")
+	digest, err := NewRegistry().Digest(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("Digest failed: %v", err)
+	}
+	if digest != "sha256:new" {
+		t.Errorf("Expected sha256:new, got %s", digest)
+	}
+
+	ref, _ = ParseReference(host + "/acme/kms:gone")
+	if _, err := NewRegistry().Digest(context.Background(), ref); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected a missing tag to fail, got %v", err)
+	}
+}
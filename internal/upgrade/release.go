@@ -0,0 +1,63 @@
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/semver"
+)
+
+// ReleasesURL is the GitHub API endpoint of the CLI's latest release
+const ReleasesURL = "https://api.github.com/repos/blackwell-systems/gcp-iam-control-plane/releases/latest"
+
+// InstallCommand installs the latest release of the CLI
+const InstallCommand = "go install github.com/blackwell-systems/gcp-iam-control-plane/cmd/gcp-emulator@latest"
+
+// CLIResult is what a check found for the CLI
+type CLIResult struct {
+	Current string
+	Latest  string
+
+	// Newer is whether Latest is newer than Current. A development build
+	// isn't a version, so it is never out of date.
+	Newer bool
+}
+
+// CheckCLI asks the GitHub releases API at url for the CLI's latest
+// release, and compares it with current, the running version
+func CheckCLI(ctx context.Context, client *http.Client, url, current string) (CLIResult, error) {
+	result := CLIResult{Current: current}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return result, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return result, fmt.Errorf("release check failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return result, fmt.Errorf("GitHub returned %s for the latest release", resp.Status)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return result, fmt.Errorf("failed to parse the latest release: %w", err)
+	}
+	result.Latest = release.TagName
+
+	latest, err := semver.Parse(release.TagName)
+	if err != nil {
+		return result, fmt.Errorf("latest release %q: %w", release.TagName, err)
+	}
+	if running, err := semver.Parse(current); err == nil {
+		result.Newer = latest.Compare(running) > 0
+	}
+	return result, nil
+}
@@ -0,0 +1,34 @@
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckCLI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"tag_name": "v0.5.0", "name": "v0.5.0"}`)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		current string
+		newer   bool
+	}{
+		{"v0.4.2", true},
+		{"0.5.0", false},
+		{"dev", false},
+	}
+	for _, tt := range tests {
+		result, err := CheckCLI(context.Background(), server.Client(), server.URL, tt.current)
+		if err != nil {
+			t.Fatalf("CheckCLI failed: %v", err)
+		}
+		if result.Latest != "v0.5.0" || result.Newer != tt.newer {
+			t.Errorf("CheckCLI(%s) = %+v, want newer %t", tt.current, result, tt.newer)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Package upgrade checks image registries for newer versions of the
+// stack's images, and GitHub for newer releases of the CLI.
+//
+// An image with a version tag, such as v0.3.1, is upgraded to the newest
+// version tag its constraint allows. An image with another tag, such as
+// latest, is compared by digest with the image pulled locally, since the
+// tag moves. An image pinned to a digest is never upgraded.
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/semver"
+)
+
+// ErrUpdatesAvailable is returned, wrapped with the count, when a check
+// finds updates
+var ErrUpdatesAvailable = errors.New("available")
+
+// Status is what a check found for an image
+type Status string
+
+const (
+	UpToDate   Status = "up to date"
+	NewVersion Status = "new version"
+	NewBuild   Status = "new build"
+	Pinned     Status = "pinned"
+	Failed     Status = "check failed"
+)
+
+// Image is an image of the stack to check
+type Image struct {
+	Service string
+
+	// Ref is the configured image reference
+	Ref string
+
+	// Constraint limits the version tags Ref may move to; "" allows any
+	Constraint string
+
+	// Local returns the repository digests of Ref as pulled locally, or
+	// nil if it isn't present. It is called only for tags that move.
+	Local func() ([]string, error)
+}
+
+// Result is what a check found for an image
+type Result struct {
+	Service string
+	Current string
+	Status  Status
+
+	// Available is the reference to pull: Current with the newer tag for
+	// NewVersion, Current itself for NewBuild, or ""
+	Available string
+
+	// Digest is the registry's digest of Current for NewBuild
+	Digest string
+
+	// Err is why the check failed, for Failed
+	Err error
+}
+
+// Changed reports whether the result is an update
+func (r Result) Changed() bool {
+	return r.Status == NewVersion || r.Status == NewBuild
+}
+
+// Check asks the registry of image whether there is a newer version of
+// it
+func Check(ctx context.Context, reg *Registry, image Image) Result {
+	result := Result{Service: image.Service, Current: image.Ref}
+	failed := func(err error) Result {
+		result.Status, result.Err = Failed, err
+		return result
+	}
+
+	ref, err := ParseReference(image.Ref)
+	if err != nil {
+		return failed(err)
+	}
+	if ref.Digest != "" {
+		result.Status = Pinned
+		return result
+	}
+
+	current, ok := version(ref.Tag)
+	if !ok {
+		// A tag that moves, such as latest, has a new build when the
+		// registry's digest isn't the one pulled
+		digest, err := reg.Digest(ctx, ref)
+		if err != nil {
+			return failed(err)
+		}
+		local, err := image.Local()
+		if err != nil {
+			return failed(err)
+		}
+		result.Status = UpToDate
+		if !slices.ContainsFunc(local, func(local string) bool {
+			return strings.HasSuffix(local, "@"+digest)
+		}) {
+			result.Status, result.Available, result.Digest = NewBuild, image.Ref, digest
+		}
+		return result
+	}
+
+	constraint, err := semver.ParseConstraint(image.Constraint)
+	if err != nil {
+		return failed(err)
+	}
+	tags, err := reg.Tags(ctx, ref)
+	if err != nil {
+		return failed(err)
+	}
+	result.Status = UpToDate
+	if tag := Newest(tags, current, constraint); tag != "" {
+		result.Status, result.Available = NewVersion, ref.WithTag(tag)
+	}
+	return result
+}
+
+// Newest returns the tag of the newest version in tags that is newer than
+// current and allowed by constraint, or "" if there is none. Tags that
+// aren't full versions are ignored.
+func Newest(tags []string, current semver.Version, constraint semver.Constraint) string {
+	newest, best := "", current
+	for _, tag := range tags {
+		v, ok := version(tag)
+		if !ok || !constraint.Allows(v) || v.Compare(best) <= 0 {
+			continue
+		}
+		newest, best = tag, v
+	}
+	return newest
+}
+
+// version parses a tag naming a full version, such as v0.3.1. Shorter
+// ones, such as v0.3, usually move with each release like latest does.
+func version(tag string) (semver.Version, bool) {
+	v, err := semver.Parse(tag)
+	numbers, _, _ := strings.Cut(strings.SplitN(tag, "+", 2)[0], "-")
+	return v, err == nil && strings.Count(numbers, ".") == 2
+}
+
+// Changes returns the results that are updates
+func Changes(results []Result) []Result {
+	var changes []Result
+	for _, result := range results {
+		if result.Changed() {
+			changes = append(changes, result)
+		}
+	}
+	return changes
+}
@@ -0,0 +1,72 @@
+package upgrade
+
+import (
+	"context"
+	"testing"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/semver"
+)
+
+func TestNewest(t *testing.T) {
+	tags := []string{"v0.3.0", "v0.3.1", "v0.3", "latest", "v0.4.0", "v0.3.2", "v0.3.3-rc1"}
+	current, _ := semver.Parse("v0.3.0")
+
+	tests := []struct {
+		constraint, want string
+	}{
+		{"", "v0.4.0"},
+		{"~0.3", "v0.3.2"},
+		{"<0.3.0", ""},
+	}
+	for _, tt := range tests {
+		constraint, _ := semver.ParseConstraint(tt.constraint)
+		if got := Newest(tags, current, constraint); got != tt.want {
+			t.Errorf("Newest(%q) = %q, want %q", tt.constraint, got, tt.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	host := testRegistry(t, "acme/kms", []string{"v0.3.0", "v0.3.4", "v0.4.0"}, map[string]string{"latest": "sha256:new"})
+	reg := NewRegistry()
+	ctx := context.Background()
+
+	result := Check(ctx, reg, Image{Service: "kms", Ref: host + "/acme/kms:v0.3.0", Constraint: "~0.3"})
+	if result.Status != NewVersion || result.Available != host+"/acme/kms:v0.3.4" {
+		t.Errorf("Expected v0.3.4 within ~0.3, got %+v", result)
+	}
+
+	result = Check(ctx, reg, Image{Service: "kms", Ref: host + "/acme/kms:v0.4.0"})
+	if result.Status != UpToDate || result.Changed() {
+		t.Errorf("Expected the newest version to be up to date, got %+v", result)
+	}
+
+	latest := host + "/acme/kms:latest"
+	result = Check(ctx, reg, Image{Service: "kms", Ref: latest, Local: localDigests(host + "/acme/kms@sha256:old")})
+	if result.Status != NewBuild || result.Available != latest || result.Digest != "sha256:new" {
+		t.Errorf("Expected a new build of latest, got %+v", result)
+	}
+	result = Check(ctx, reg, Image{Service: "kms", Ref: latest, Local: localDigests(host + "/acme/kms@sha256:new")})
+	if result.Status != UpToDate {
+		t.Errorf("Expected latest pulled at the registry's digest to be up to date, got %+v", result)
+	}
+
+	result = Check(ctx, reg, Image{Service: "kms", Ref: host + "/acme/kms@sha256:old"})
+	if result.Status != Pinned {
+		t.Errorf("Expected a digest reference to be pinned, got %+v", result)
+	}
+
+	result = Check(ctx, reg, Image{Service: "kms", Ref: host + "/acme/other:v1.0.0"})
+	if result.Status != Failed || result.Err == nil {
+		t.Errorf("Expected an unknown repository to fail, got %+v", result)
+	}
+
+	if changes := Changes([]Result{{Status: NewBuild}, {Status: UpToDate}, {Status: NewVersion}}); len(changes) != 2 {
+		t.Errorf("Expected 2 changes, got %v", changes)
+	}
+}
+
+// localDigests returns an Image.Local reporting digests
+func localDigests(digests ...string) func() ([]string, error) {
+	return func() ([]string, error) { return digests, nil }
+}
@@ -0,0 +1,76 @@
+package policy_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/pkg/policy"
+)
+
+func ExampleLoad() {
+	pol, err := policy.Load("../../testdata/policy.yaml")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	result := policy.Validate(pol)
+	fmt.Println("valid:", result.Valid)
+	fmt.Println("members:", pol.Groups["developers"].Members)
+	// Output:
+	// valid: true
+	// members: [user:alice@example.com user:bob@example.com]
+}
+
+func ExampleValidate() {
+	pol, err := policy.LoadReader(strings.NewReader(`
+roles:
+  roles/custom.dev:
+    permissions:
+      - secretmanager.secrets.get
+projects:
+  test-project:
+    bindings:
+      - role: roles/custom.missing
+        members:
+          - user:dev@example.com
+`), "yaml")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	for _, f := range policy.Validate(pol).Findings {
+		if f.Severity == policy.SeverityError {
+			fmt.Printf("line %d: %s\n", f.Line, f.Message)
+		}
+	}
+	// Output:
+	// line 9: Project test-project binding 0: undefined role roles/custom.missing
+}
+
+func ExampleSimulate() {
+	pol, err := policy.Load("../../testdata/policy.yaml")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	decision, err := policy.Simulate(pol, policy.SimulateRequest{
+		Principal:  "user:bob@example.com",
+		Permission: "cloudkms.cryptoKeys.get",
+		Resource:   "projects/test-project/locations/global/keyRings/app/cryptoKeys/data",
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println("allowed:", decision.Allowed)
+	for _, m := range decision.Matches {
+		fmt.Printf("via %s on %s\n", m.Binding.Role, m.Scope)
+	}
+	// Output:
+	// allowed: true
+	// via roles/custom.developer on projects/test-project
+}
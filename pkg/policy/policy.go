@@ -0,0 +1,84 @@
+// Package policy is the public Go API for gcp-emulator policy files.
+//
+// It loads, validates, and saves the policy.yaml used by the IAM emulator,
+// and simulates authorization checks against it, so integration tests can
+// work with the same policy the stack enforces instead of redefining its
+// structure:
+//
+//	pol, err := policy.Load("policy.yaml")
+//	if err != nil {
+//		return err
+//	}
+//	if result := policy.Validate(pol); !result.Valid {
+//		for _, f := range result.Findings {
+//			fmt.Println(f)
+//		}
+//	}
+//
+// The package is a stable subset of the implementation used by the
+// gcp-emulator CLI. Its types are aliases of the CLI's own, so the
+// semantics, including include resolution and encrypted .enc files, are
+// exactly those of gcp-emulator policy commands. Identifiers exported here
+// follow semantic versioning; everything under internal/ may change.
+package policy
+
+import (
+	"io"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+// CurrentVersion is the policy schema version written by Save
+const CurrentVersion = policy.CurrentVersion
+
+// Policy is a parsed policy file
+type Policy = policy.Policy
+
+// Role is a custom role with permissions
+type Role = policy.Role
+
+// Group is a named set of members
+type Group = policy.Group
+
+// ServiceAccount declares a service account, keyed by email in
+// Policy.ServiceAccounts
+type ServiceAccount = policy.ServiceAccount
+
+// Project holds the bindings, deny bindings, and resource bindings of one
+// project
+type Project = policy.Project
+
+// Resource holds the bindings set on a single resource in a project
+type Resource = policy.Resource
+
+// Organization is the root of a resource hierarchy
+type Organization = policy.Organization
+
+// Folder groups projects under an organization or another folder
+type Folder = policy.Folder
+
+// Binding grants a role to members, optionally under a condition
+type Binding = policy.Binding
+
+// DenyBinding denies permissions to principals regardless of bindings
+type DenyBinding = policy.DenyBinding
+
+// Condition is a CEL condition on a binding
+type Condition = policy.Condition
+
+// Load reads a policy file (.yaml, .yml, or .json), resolving includes
+// relative to the file and decrypting it if it ends in .enc
+func Load(path string) (*Policy, error) {
+	return policy.Load(path)
+}
+
+// LoadReader parses a policy from r. format is "yaml" or "json". The
+// policy must not use includes.
+func LoadReader(r io.Reader, format string) (*Policy, error) {
+	return policy.LoadReader(r, format)
+}
+
+// Save writes a policy to path, as YAML unless the extension is .json
+func Save(p *Policy, path string) error {
+	return policy.Save(p, path)
+}
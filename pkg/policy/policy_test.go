@@ -0,0 +1,154 @@
+package policy_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/pkg/policy"
+)
+
+func TestLoadAndValidate(t *testing.T) {
+	for _, file := range []string{"policy.yaml", "policy.json"} {
+		pol, err := policy.Load(filepath.Join("../../testdata", file))
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", file, err)
+		}
+
+		if _, ok := pol.Roles["roles/custom.developer"]; !ok {
+			t.Errorf("%s: expected roles/custom.developer", file)
+		}
+		if result := policy.Validate(pol); !result.Valid {
+			t.Errorf("%s: expected a valid policy, got %v", file, result.Errors)
+		}
+	}
+}
+
+func TestLoadReaderFindings(t *testing.T) {
+	const doc = `
+roles:
+  roles/custom.dev:
+    permissions:
+      - not-a-permission
+projects:
+  test-project:
+    bindings:
+      - role: roles/custom.dev
+        members:
+          - user:dev@example.com
+`
+	pol, err := policy.LoadReader(strings.NewReader(doc), "yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse policy: %v", err)
+	}
+
+	result := policy.Validate(pol)
+	if result.Valid {
+		t.Fatal("Expected an invalid permission to fail validation")
+	}
+
+	var found *policy.Finding
+	for i, f := range result.Findings {
+		if f.Severity == policy.SeverityError && strings.Contains(f.Message, "not-a-permission") {
+			found = &result.Findings[i]
+		}
+	}
+	if found == nil {
+		t.Fatalf("Expected an error finding for the permission, got %+v", result.Findings)
+	}
+	if found.Line != 5 {
+		t.Errorf("Expected the finding on line 5, got %s", found.Position)
+	}
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	pol := &policy.Policy{
+		Version: policy.CurrentVersion,
+		Roles: map[string]policy.Role{
+			"roles/custom.reader": {Permissions: []string{"secretmanager.secrets.get"}},
+		},
+		Groups: map[string]policy.Group{
+			"readers": {Members: []string{"user:alice@example.com"}},
+		},
+		Projects: map[string]policy.Project{
+			"test-project": {
+				Bindings: []policy.Binding{{
+					Role:      "roles/custom.reader",
+					Members:   []string{"group:readers"},
+					Condition: &policy.Condition{Expression: `resource.name.startsWith("projects/test-project/secrets/app-")`},
+				}},
+			},
+		},
+	}
+
+	for _, name := range []string{"policy.yaml", "policy.json"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := policy.Save(pol, path); err != nil {
+			t.Fatalf("Failed to save %s: %v", name, err)
+		}
+
+		loaded, err := policy.Load(path)
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", name, err)
+		}
+		if result := policy.Validate(loaded); !result.Valid {
+			t.Errorf("%s: expected a valid policy, got %v", name, result.Errors)
+		}
+		if got := loaded.Projects["test-project"].Bindings[0].Condition; got == nil || got.Expression != pol.Projects["test-project"].Bindings[0].Condition.Expression {
+			t.Errorf("%s: expected the condition to round-trip, got %+v", name, got)
+		}
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	pol, err := policy.Load("../../testdata/policy.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		req     policy.SimulateRequest
+		allowed bool
+	}{
+		{
+			name: "group member",
+			req: policy.SimulateRequest{
+				Principal:  "user:alice@example.com",
+				Permission: "secretmanager.secrets.get",
+				Resource:   "projects/test-project/secrets/db-password",
+			},
+			allowed: true,
+		},
+		{
+			name: "condition not met",
+			req: policy.SimulateRequest{
+				Principal:  "serviceAccount:ci@test-project.iam.gserviceaccount.com",
+				Permission: "secretmanager.secrets.get",
+				Resource:   "projects/test-project/secrets/dev-token",
+			},
+			allowed: false,
+		},
+		{
+			name: "unknown principal",
+			req: policy.SimulateRequest{
+				Principal:  "user:mallory@example.com",
+				Permission: "secretmanager.secrets.get",
+				Resource:   "projects/test-project/secrets/db-password",
+			},
+			allowed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := policy.Simulate(pol, tt.req)
+			if err != nil {
+				t.Fatalf("Simulate failed: %v", err)
+			}
+			if decision.Allowed != tt.allowed {
+				t.Errorf("Expected allowed=%v, got %+v", tt.allowed, decision)
+			}
+		})
+	}
+}
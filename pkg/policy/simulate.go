@@ -0,0 +1,26 @@
+package policy
+
+import "github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+
+// SimulateRequest describes an authorization check: whether Principal
+// holds Permission on Resource at RequestTime
+type SimulateRequest = policy.SimulateRequest
+
+// Decision is the outcome of Simulate, with the bindings that matched
+type Decision = policy.Decision
+
+// BindingMatch is a binding whose role includes the requested permission
+// and whose members include the principal
+type BindingMatch = policy.BindingMatch
+
+// DenyMatch is a deny binding that matched the request
+type DenyMatch = policy.DenyMatch
+
+// ConditionContext holds the values conditions were evaluated against
+type ConditionContext = policy.ConditionContext
+
+// Simulate evaluates a request against the policy the way the IAM
+// emulator does in strict mode, without a running stack
+func Simulate(p *Policy, req SimulateRequest) (*Decision, error) {
+	return policy.Simulate(p, req)
+}
@@ -0,0 +1,34 @@
+package policy
+
+import "github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+
+// ValidationResult is the outcome of Validate. Valid is false when there
+// is at least one error finding.
+type ValidationResult = policy.ValidationResult
+
+// Finding is a validation error or warning with, for policies read by
+// Load, its location in the source file
+type Finding = policy.Finding
+
+// Position is a 1-based location in a policy source file
+type Position = policy.Position
+
+// ValidateOptions controls optional validation checks
+type ValidateOptions = policy.ValidateOptions
+
+// Finding severities
+const (
+	SeverityError   = policy.SeverityError
+	SeverityWarning = policy.SeverityWarning
+)
+
+// Validate checks a policy with the default options, as gcp-emulator
+// policy validate does
+func Validate(p *Policy) *ValidationResult {
+	return policy.Validate(p)
+}
+
+// ValidateWithOptions checks a policy with the given options
+func ValidateWithOptions(p *Policy, opts ValidateOptions) *ValidationResult {
+	return policy.ValidateWithOptions(p, opts)
+}
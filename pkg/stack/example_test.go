@@ -0,0 +1,108 @@
+package stack_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/pkg/policy"
+	"github.com/blackwell-systems/gcp-iam-control-plane/pkg/stack"
+)
+
+// The example needs docker, so it is compiled but not run by go test
+func Example() {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+
+	s, err := stack.New(stack.Config{}, stack.WithMode(stack.ModeStrict))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	// Purge removes whatever Start brought up, even if it failed
+	defer s.Purge(context.Background())
+
+	// Policies can also be applied once the stack is running, to test
+	// a change to one
+	pol, err := policy.LoadReader(strings.NewReader(`
+roles:
+  roles/custom.app:
+    permissions:
+      - secretmanager.secrets.create
+      - secretmanager.versions.add
+      - secretmanager.versions.access
+projects:
+  test-project:
+    bindings:
+      - role: roles/custom.app
+        members:
+          - user:alice@example.com
+`), "yaml")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := s.ApplyPolicy(pol); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := s.Start(ctx); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	conn, err := grpc.NewClient(s.Endpoints().SecretManager.GRPC, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer conn.Close()
+	client, err := secretmanager.NewClient(ctx, option.WithGRPCConn(conn))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer client.Close()
+
+	// The emulators take the principal from metadata instead of
+	// credentials
+
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-emulator-principal", "user:alice@example.com")
+	secret, err := client.CreateSecret(ctx, &secretmanagerpb.CreateSecretRequest{
+		Parent:   "projects/test-project",
+		SecretId: "db-password",
+		Secret: &secretmanagerpb.Secret{
+			Replication: &secretmanagerpb.Replication{
+				Replication: &secretmanagerpb.Replication_Automatic_{Automatic: &secretmanagerpb.Replication_Automatic{}},
+			},
+		},
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if _, err := client.AddSecretVersion(ctx, &secretmanagerpb.AddSecretVersionRequest{
+		Parent:  secret.GetName(),
+		Payload: &secretmanagerpb.SecretPayload{Data: []byte("hunter2")},
+	}); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	resp, err := client.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{
+		Name: secret.GetName() + "/versions/latest",
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(string(resp.GetPayload().GetData()))
+}
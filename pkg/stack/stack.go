@@ -0,0 +1,389 @@
+// Package stack is the public Go API for running the emulator stack from
+// Go tests.
+//
+// It brings up the same compose project as 'gcp-emulator start', waits
+// for it to be healthy, and tears it down again, so integration tests
+// don't have to shell out to the CLI or hard-code its ports:
+//
+//	s, err := stack.New(stack.Config{}, stack.WithMode("strict"), stack.WithPolicyFile("policy.yaml"))
+//	if err != nil {
+//		return err
+//	}
+//	defer s.Purge(context.Background())
+//	if err := s.Start(ctx); err != nil {
+//		return err
+//	}
+//	client, err := secretmanager.NewClient(ctx,
+//		option.WithEndpoint(s.Endpoints().SecretManager.GRPC), ...)
+//
+// Each Stack is a compose project of its own, named uniquely and
+// published on free ports unless told otherwise, so test packages run in
+// parallel by go test don't collide with each other or with a stack
+// started by the CLI. Identifiers exported here follow semantic
+// versioning; everything under internal/ may change.
+package stack
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator"
+	ipolicy "github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+	"github.com/blackwell-systems/gcp-iam-control-plane/pkg/policy"
+)
+
+// DefaultTimeout is how long Start waits for the stack to be healthy when
+// its context has no deadline
+const DefaultTimeout = 2 * time.Minute
+
+// IAM modes, as for the CLI's iam-mode key
+const (
+	ModeOff        = "off"
+	ModePermissive = "permissive"
+	ModeStrict     = "strict"
+)
+
+// Config configures a Stack. The zero value is a permissive stack on free
+// ports, with the default images and an empty policy.
+type Config struct {
+	// Mode is the IAM mode: ModeOff, ModePermissive (the default), or
+	// ModeStrict
+	Mode string
+
+	// PolicyFile is the policy the IAM emulator loads. Without one it
+	// starts with an empty policy, which denies everything in strict
+	// mode; see also ApplyPolicy.
+	PolicyFile string
+
+	// Ports are the host ports to publish on. Ports left at zero are
+	// picked free when the stack starts.
+	Ports Ports
+
+	// Images override the emulator images; those left empty are the
+	// CLI's defaults
+	Images Images
+
+	// Project is the compose project name, "gcp-emulator-" and a random
+	// suffix by default
+	Project string
+
+	// Timeout bounds the wait for health when Start's context has no
+	// deadline, DefaultTimeout if zero
+	Timeout time.Duration
+
+	// Output, if not nil, is written the output of compose
+	Output io.Writer
+}
+
+// Ports are the host ports of the stack. The IAM emulator also serves its
+// admin and health API on IAM+1000.
+type Ports struct {
+	IAM               int
+	SecretManager     int
+	SecretManagerHTTP int
+	KMS               int
+	KMSHTTP           int
+}
+
+// Images are the emulator images to run
+type Images struct {
+	IAM           string
+	SecretManager string
+	KMS           string
+}
+
+// Option changes a Config passed to New
+type Option func(*Config)
+
+// WithMode sets the IAM mode
+func WithMode(mode string) Option {
+	return func(c *Config) { c.Mode = mode }
+}
+
+// WithPorts sets the host ports; those left at zero are picked free
+func WithPorts(ports Ports) Option {
+	return func(c *Config) { c.Ports = ports }
+}
+
+// WithImages sets the emulator images; those left empty are the defaults
+func WithImages(images Images) Option {
+	return func(c *Config) { c.Images = images }
+}
+
+// WithPolicyFile sets the policy file the IAM emulator loads
+func WithPolicyFile(path string) Option {
+	return func(c *Config) { c.PolicyFile = path }
+}
+
+// WithProject sets the compose project name
+func WithProject(name string) Option {
+	return func(c *Config) { c.Project = name }
+}
+
+// WithTimeout sets how long Start waits for health without a deadline
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Config) { c.Timeout = timeout }
+}
+
+// WithOutput writes the output of compose to w
+func WithOutput(w io.Writer) Option {
+	return func(c *Config) { c.Output = w }
+}
+
+// Endpoint is where a service of the stack is reachable from the host
+type Endpoint struct {
+	// GRPC is the host:port of the gRPC API, as for option.WithEndpoint
+	GRPC string
+
+	// HTTP is the base URL of the HTTP API, such as http://localhost:8081
+	HTTP string
+}
+
+// Endpoints are the addresses of the stack's services
+type Endpoints struct {
+	IAM           Endpoint
+	SecretManager Endpoint
+	KMS           Endpoint
+}
+
+// Stack is an emulator stack run with docker compose. Its methods are
+// not safe for concurrent use.
+type Stack struct {
+	cfg     *config.Config
+	ports   Ports
+	timeout time.Duration
+	output  io.Writer
+
+	// dir holds the policy written by ApplyPolicy, if it was called
+	dir     string
+	started bool
+}
+
+// New returns a Stack for cfg, changed by opts. Nothing is started until
+// Start.
+func New(cfg Config, opts ...Option) (*Stack, error) {
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	c := config.Defaults()
+	if cfg.Mode != "" {
+		c.IAMMode = cfg.Mode
+	}
+	c.PolicyFile = cfg.PolicyFile
+	if cfg.Images.IAM != "" {
+		c.Images.IAM = cfg.Images.IAM
+	}
+	if cfg.Images.SecretManager != "" {
+		c.Images.SecretManager = cfg.Images.SecretManager
+	}
+	if cfg.Images.KMS != "" {
+		c.Images.KMS = cfg.Images.KMS
+	}
+	c.Docker.Project = cfg.Project
+	if c.Docker.Project == "" {
+		suffix := make([]byte, 4)
+		if _, err := rand.Read(suffix); err != nil {
+			return nil, fmt.Errorf("failed to name the project: %w", err)
+		}
+		c.Docker.Project = config.DefaultProject + "-" + hex.EncodeToString(suffix)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &Stack{cfg: c, ports: cfg.Ports, timeout: cfg.Timeout, output: cfg.Output}
+	if s.timeout == 0 {
+		s.timeout = DefaultTimeout
+	}
+	if s.output == nil {
+		s.output = io.Discard
+	}
+	return s, nil
+}
+
+// Project returns the compose project name of the stack, for use with
+// the CLI's compose-project key
+func (s *Stack) Project() string {
+	return docker.ProjectName(s.cfg)
+}
+
+// Start brings up the stack and blocks until all its services are
+// healthy, or ctx is done. Ports left at zero are picked free first. If
+// Start fails, Stop or Purge still removes whatever it started.
+func (s *Stack) Start(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if s.cfg.PolicyFile == "" {
+		if err := s.ApplyPolicy(&policy.Policy{}); err != nil {
+			return err
+		}
+	}
+	if err := s.cfg.ValidatePolicyFile(ipolicy.LoadOptions{}); err != nil {
+		return err
+	}
+
+	ports, err := s.pickPorts()
+	if err != nil {
+		return err
+	}
+	s.ports = ports
+	s.cfg.Ports = config.PortConfig{
+		IAM:               ports.IAM,
+		SecretManager:     ports.SecretManager,
+		SecretManagerHTTP: ports.SecretManagerHTTP,
+		KMS:               ports.KMS,
+		KMSHTTP:           ports.KMSHTTP,
+	}
+
+	s.started = true
+	if err := docker.Start(s.cfg, docker.ConfiguredPorts(s.cfg), nil, s.output); err != nil {
+		return err
+	}
+
+	timeout := s.timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	if down := docker.WaitHealthy(s.cfg, docker.Services, timeout, nil); len(down) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("services not healthy after %s: %s", timeout.Round(time.Second), strings.Join(down, ", "))
+	}
+	return nil
+}
+
+// pickPorts returns the configured ports, with free ones for those left
+// at zero
+func (s *Stack) pickPorts() (Ports, error) {
+	p := s.ports
+	if p.IAM != 0 && p.SecretManager != 0 && p.SecretManagerHTTP != 0 && p.KMS != 0 && p.KMSHTTP != 0 {
+		return p, nil
+	}
+	free, err := docker.FreePorts()
+	if err != nil {
+		return Ports{}, err
+	}
+	for _, port := range []struct {
+		dst  *int
+		free int
+	}{
+		{&p.IAM, free.IAM},
+		{&p.SecretManager, free.SecretManager},
+		{&p.SecretManagerHTTP, free.SecretManagerHTTP},
+		{&p.KMS, free.KMS},
+		{&p.KMSHTTP, free.KMSHTTP},
+	} {
+		if *port.dst == 0 {
+			*port.dst = port.free
+		}
+	}
+	return p, nil
+}
+
+// Endpoints returns the addresses of the stack's services. The ports are
+// only known once Start has picked them.
+func (s *Stack) Endpoints() Endpoints {
+	address := s.cfg.Docker.Address
+	endpoint := func(grpc, http int) Endpoint {
+		return Endpoint{GRPC: address(grpc), HTTP: "http://" + address(http)}
+	}
+	return Endpoints{
+		IAM:           endpoint(s.ports.IAM, s.ports.IAM+config.IAMHTTPOffset),
+		SecretManager: endpoint(s.ports.SecretManager, s.ports.SecretManagerHTTP),
+		KMS:           endpoint(s.ports.KMS, s.ports.KMSHTTP),
+	}
+}
+
+// ApplyPolicy makes pol the IAM emulator's policy. Before Start it is
+// the policy the stack starts with, replacing PolicyFile; once started
+// it is hot-loaded, as by 'gcp-emulator policy apply', and kept for a
+// later Start.
+func (s *Stack) ApplyPolicy(pol *policy.Policy) error {
+	if pol == nil {
+		return errors.New("no policy to apply")
+	}
+	if s.dir == "" {
+		dir, err := os.MkdirTemp("", "gcp-emulator-stack-")
+		if err != nil {
+			return fmt.Errorf("failed to create policy directory: %w", err)
+		}
+		s.dir = dir
+	}
+	path := filepath.Join(s.dir, "policy.yaml")
+	if err := policy.Save(pol, path); err != nil {
+		return err
+	}
+	s.cfg.PolicyFile = path
+
+	if !s.started {
+		return nil
+	}
+	flat, err := ipolicy.Flatten(pol)
+	if err != nil {
+		return err
+	}
+	return emulator.NewIAMClient(s.cfg).ApplyPolicy(flat)
+}
+
+// Stop stops the stack and removes its containers, keeping its volumes
+// for a later Start. Compose isn't interrupted once it has begun, so ctx
+// is only checked beforehand.
+func (s *Stack) Stop(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := docker.Stop(s.cfg, nil); err != nil {
+		return err
+	}
+	s.started = false
+	return s.removeComposeFile()
+}
+
+// Purge stops the stack and removes its volumes, along with the files the
+// Stack wrote, including a policy given to ApplyPolicy, leaving nothing
+// behind. ctx is checked as by Stop.
+func (s *Stack) Purge(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := docker.Reset(s.cfg); err != nil {
+		return err
+	}
+	s.started = false
+	if s.dir != "" {
+		if err := os.RemoveAll(s.dir); err != nil {
+			return fmt.Errorf("failed to remove policy directory: %w", err)
+		}
+		if filepath.Dir(s.cfg.PolicyFile) == s.dir {
+			s.cfg.PolicyFile = ""
+		}
+		s.dir = ""
+	}
+	return s.removeComposeFile()
+}
+
+// removeComposeFile removes the compose file generated for the project,
+// which is only written again by the next compose command
+func (s *Stack) removeComposeFile() error {
+	path, err := config.StatePath(filepath.Join("compose", docker.ProjectName(s.cfg)+".yaml"))
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove compose file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package stack
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	ipolicy "github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+	"github.com/blackwell-systems/gcp-iam-control-plane/pkg/policy"
+)
+
+func TestNew(t *testing.T) {
+	a, err := New(Config{})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	b, err := New(Config{Mode: ModePermissive})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if !strings.HasPrefix(a.Project(), "gcp-emulator-") || a.Project() == b.Project() {
+		t.Errorf("Expected unique project names, got %q and %q", a.Project(), b.Project())
+	}
+	if a.cfg.IAMMode != ModePermissive || a.timeout != DefaultTimeout {
+		t.Errorf("Expected the defaults, got mode %q, timeout %s", a.cfg.IAMMode, a.timeout)
+	}
+
+	s, err := New(Config{Mode: ModePermissive},
+		WithMode(ModeStrict),
+		WithProject("tests"),
+		WithImages(Images{KMS: "kms:dev"}),
+		WithPorts(Ports{IAM: 18080, SecretManager: 19090}),
+	)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if s.cfg.IAMMode != ModeStrict || s.Project() != "tests" || s.cfg.Images.KMS != "kms:dev" || s.cfg.Images.IAM == "" {
+		t.Errorf("Expected the options to apply over the config, got %+v", s.cfg)
+	}
+
+	if _, err := New(Config{Mode: "open"}); err == nil {
+		t.Error("Expected an invalid mode to be refused")
+	}
+}
+
+func TestPorts(t *testing.T) {
+	s, err := New(Config{}, WithPorts(Ports{IAM: 18080, SecretManager: 19090}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ports, err := s.pickPorts()
+	if err != nil {
+		t.Fatalf("pickPorts() error: %v", err)
+	}
+	if ports.IAM != 18080 || ports.SecretManager != 19090 || ports.KMS == 0 || ports.SecretManagerHTTP == 0 || ports.KMSHTTP == 0 {
+		t.Errorf("Expected free ports for those not set, got %+v", ports)
+	}
+
+	s.ports = ports
+	endpoints := s.Endpoints()
+	if endpoints.IAM.GRPC != "localhost:18080" || endpoints.IAM.HTTP != "http://localhost:19080" || endpoints.SecretManager.GRPC != "localhost:19090" {
+		t.Errorf("Unexpected endpoints: %+v", endpoints)
+	}
+}
+
+func TestApplyPolicyBeforeStart(t *testing.T) {
+	s, err := New(Config{}, WithPolicyFile("policy.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pol := &policy.Policy{Groups: map[string]policy.Group{"developers": {Members: []string{"user:alice@example.com"}}}}
+	if err := s.ApplyPolicy(pol); err != nil {
+		t.Fatalf("ApplyPolicy() error: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(s.dir) })
+
+	// The stack starts with the policy instead of the file
+	if err := s.cfg.ValidatePolicyFile(ipolicy.LoadOptions{}); err != nil {
+		t.Fatalf("Expected the policy to be written, got %v", err)
+	}
+	saved, err := policy.Load(s.cfg.PolicyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if members := saved.Groups["developers"].Members; len(members) != 1 || members[0] != "user:alice@example.com" {
+		t.Errorf("Expected the applied policy, got %v", members)
+	}
+
+	// An empty policy is what a stack without one starts with
+	if err := s.ApplyPolicy(&policy.Policy{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.cfg.ValidatePolicyFile(ipolicy.LoadOptions{}); err != nil {
+		t.Errorf("Expected an empty policy to load, got %v", err)
+	}
+}
@@ -0,0 +1,318 @@
+// Package testcontainers runs the emulator stack as testcontainers-go
+// containers, for test suites that already manage their dependencies with
+// testcontainers.
+//
+// The containers are those of the compose file 'gcp-emulator start'
+// generates: the same images, commands, environment, policy mount, and
+// compose labels, so 'gcp-emulator status', logs, and stop see them as a
+// stack of their own project:
+//
+//	emulators, err := testcontainers.Run(ctx,
+//		testcontainers.WithMode(stack.ModeStrict),
+//		testcontainers.WithPolicyFile("policy.yaml"),
+//		testcontainers.WithServices("secret-manager"),
+//	)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	t.Cleanup(func() { emulators.Terminate(context.Background()) })
+//	address := emulators.Endpoints().SecretManager.GRPC
+//
+// Run waits for each container's health endpoint before starting the
+// next, IAM first, so the data planes find it up. The package is a module
+// of its own, so the CLI and pkg/policy don't depend on testcontainers-go;
+// it follows semantic versioning like them.
+package testcontainers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	mobynetwork "github.com/moby/moby/api/types/network"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/network"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+	"github.com/blackwell-systems/gcp-iam-control-plane/pkg/stack"
+)
+
+// StartupTimeout is how long Run waits for each container to be healthy
+const StartupTimeout = 2 * time.Minute
+
+// options are set by the Options passed to Run
+type options struct {
+	mode       string
+	policyFile string
+	project    string
+	services   []string
+	images     stack.Images
+}
+
+// Option configures Run
+type Option func(*options)
+
+// WithMode sets the IAM mode: stack.ModeOff, stack.ModePermissive (the
+// default), or stack.ModeStrict
+func WithMode(mode string) Option {
+	return func(o *options) { o.mode = mode }
+}
+
+// WithPolicyFile sets the policy file mounted into the IAM emulator,
+// which is needed whenever it runs
+func WithPolicyFile(path string) Option {
+	return func(o *options) { o.policyFile = path }
+}
+
+// WithServices runs only services, by the names the CLI takes, instead of
+// all three. iam is added unless the mode is off, since the data planes
+// check requests with it.
+func WithServices(services ...string) Option {
+	return func(o *options) { o.services = append(o.services, services...) }
+}
+
+// WithImages overrides the emulator images; those left empty are the
+// CLI's defaults
+func WithImages(images stack.Images) Option {
+	return func(o *options) { o.images = images }
+}
+
+// WithProject sets the compose project the containers are labeled with,
+// "gcp-emulator-" and a random suffix by default
+func WithProject(name string) Option {
+	return func(o *options) { o.project = name }
+}
+
+// Stack is the containers started by Run
+type Stack struct {
+	// Containers are the running containers by service name
+	Containers map[string]*testcontainers.DockerContainer
+
+	// Network is the network the containers share, on which the data
+	// planes reach the IAM emulator as iam
+	Network *testcontainers.DockerNetwork
+
+	cfg   *config.Config
+	ports docker.Ports
+	host  string
+}
+
+// Run starts the emulator containers on free host ports and waits for
+// them to be healthy. If it fails, the containers it started are
+// terminated.
+func Run(ctx context.Context, opts ...Option) (*Stack, error) {
+	o := options{mode: stack.ModePermissive}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	cfg := config.Defaults()
+	cfg.IAMMode = o.mode
+	cfg.PolicyFile = o.policyFile
+	if o.images.IAM != "" {
+		cfg.Images.IAM = o.images.IAM
+	}
+	if o.images.SecretManager != "" {
+		cfg.Images.SecretManager = o.images.SecretManager
+	}
+	if o.images.KMS != "" {
+		cfg.Images.KMS = o.images.KMS
+	}
+	cfg.Docker.Project = o.project
+	if cfg.Docker.Project == "" {
+		suffix := make([]byte, 4)
+		if _, err := rand.Read(suffix); err != nil {
+			return nil, fmt.Errorf("failed to name the project: %w", err)
+		}
+		cfg.Docker.Project = config.DefaultProject + "-" + hex.EncodeToString(suffix)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	services, _, err := docker.SelectServices(o.services, cfg.IAMMode)
+	if err != nil {
+		return nil, err
+	}
+	if len(services) == 0 {
+		services = docker.Services
+	}
+	if slices.Contains(services, "iam") {
+		if cfg.PolicyFile == "" {
+			return nil, errors.New("the IAM emulator needs a policy file; pass WithPolicyFile")
+		}
+		if err := cfg.ValidatePolicyFile(policy.LoadOptions{}); err != nil {
+			return nil, err
+		}
+	}
+
+	// The ports are picked as by start --auto-ports, keeping the IAM
+	// emulator's HTTP port at IAM+1000 as the CLI expects
+	ports, err := docker.FreePorts()
+	if err != nil {
+		return nil, err
+	}
+	nw, err := network.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create network: %w", err)
+	}
+	cfg.Docker.Network = nw.Name
+	s := &Stack{Containers: map[string]*testcontainers.DockerContainer{}, Network: nw, cfg: cfg, ports: ports, host: "localhost"}
+
+	composeFile, err := docker.ComposeFile(cfg, ports)
+	if err != nil {
+		_ = s.Terminate(ctx)
+		return nil, err
+	}
+	specs, err := docker.ServiceSpecs(cfg, ports, composeFile)
+	if err != nil {
+		_ = s.Terminate(ctx)
+		return nil, err
+	}
+	for _, spec := range specs {
+		if !slices.Contains(services, spec.Service) {
+			continue
+		}
+		c, err := testcontainers.Run(ctx, spec.Image, requestOptions(cfg, spec, nw.Name)...)
+		if c != nil {
+			s.Containers[spec.Service] = c
+		}
+		if err != nil {
+			_ = s.Terminate(ctx)
+			return nil, fmt.Errorf("failed to start %s: %w", spec.Service, err)
+		}
+		if host, err := c.Host(ctx); err == nil {
+			s.host = host
+		}
+	}
+
+	// Without the record, the CLI would check health on the configured
+	// ports rather than these
+	_ = docker.RecordPorts(cfg, ports)
+	return s, nil
+}
+
+// requestOptions returns the options running spec's container as compose
+// would, on the network named nw
+func requestOptions(cfg *config.Config, spec docker.ServiceSpec, nw string) []testcontainers.ContainerCustomizer {
+	env := map[string]string{}
+	for _, v := range spec.Environment {
+		name, value, _ := strings.Cut(v, "=")
+		env[name] = value
+	}
+
+	var exposed []string
+	bindings := mobynetwork.PortMap{}
+	for _, p := range spec.Ports {
+		host, port, _ := strings.Cut(p, ":")
+		containerPort := mobynetwork.MustParsePort(port + "/tcp")
+		exposed = append(exposed, containerPort.String())
+		bindings[containerPort] = []mobynetwork.PortBinding{{HostPort: host}}
+	}
+
+	opts := []testcontainers.ContainerCustomizer{
+		testcontainers.WithName(docker.ProjectName(cfg) + "-" + spec.Service + "-1"),
+		testcontainers.WithLabels(spec.Labels),
+		testcontainers.WithEnv(env),
+		testcontainers.WithExposedPorts(exposed...),
+		testcontainers.WithHostConfigModifier(func(hc *container.HostConfig) {
+			hc.Binds = append(hc.Binds, spec.Volumes...)
+			for port, binding := range bindings {
+				if hc.PortBindings == nil {
+					hc.PortBindings = mobynetwork.PortMap{}
+				}
+				hc.PortBindings[port] = binding
+			}
+		}),
+		network.WithNetworkName([]string{spec.Service}, nw),
+		testcontainers.WithWaitStrategy(wait.ForHTTP("/health").
+			WithPort(fmt.Sprintf("%d/tcp", spec.HealthPort)).
+			WithStartupTimeout(StartupTimeout)),
+	}
+	if len(spec.Command) > 0 {
+		opts = append(opts, testcontainers.WithCmd(spec.Command...))
+	}
+	return opts
+}
+
+// Ports returns the host ports of the services started; those of other
+// services are zero
+func (s *Stack) Ports() stack.Ports {
+	var p stack.Ports
+	if s.Containers["iam"] != nil {
+		p.IAM = s.ports.IAM
+	}
+	if s.Containers["secret-manager"] != nil {
+		p.SecretManager, p.SecretManagerHTTP = s.ports.SecretManager, s.ports.SecretManagerHTTP
+	}
+	if s.Containers["kms"] != nil {
+		p.KMS, p.KMSHTTP = s.ports.KMS, s.ports.KMSHTTP
+	}
+	return p
+}
+
+// Endpoints returns the addresses of the services started; those of
+// other services are empty
+func (s *Stack) Endpoints() stack.Endpoints {
+	endpoint := func(service string, grpc, http int) stack.Endpoint {
+		if s.Containers[service] == nil {
+			return stack.Endpoint{}
+		}
+		address := config.DockerConfig{Host: s.host}.Address
+		return stack.Endpoint{GRPC: address(grpc), HTTP: "http://" + address(http)}
+	}
+	return stack.Endpoints{
+		IAM:           endpoint("iam", s.ports.IAM, s.ports.IAMHTTP()),
+		SecretManager: endpoint("secret-manager", s.ports.SecretManager, s.ports.SecretManagerHTTP),
+		KMS:           endpoint("kms", s.ports.KMS, s.ports.KMSHTTP),
+	}
+}
+
+// Project returns the compose project the containers are labeled with,
+// for use with the CLI's compose-project key
+func (s *Stack) Project() string {
+	return docker.ProjectName(s.cfg)
+}
+
+// Terminate stops and removes the containers, data planes first, and
+// their network, along with the files Run wrote for the CLI
+func (s *Stack) Terminate(ctx context.Context) error {
+	var errs []error
+	for _, service := range slices.Backward(docker.Services) {
+		if c := s.Containers[service]; c != nil {
+			if err := c.Terminate(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("failed to terminate %s: %w", service, err))
+			}
+			delete(s.Containers, service)
+		}
+	}
+	if s.Network != nil {
+		if err := s.Network.Remove(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove network: %w", err))
+		}
+		s.Network = nil
+	}
+
+	if err := docker.ForgetPorts(s.cfg); err != nil {
+		errs = append(errs, err)
+	}
+	path, err := config.StatePath(filepath.Join("compose", docker.ProjectName(s.cfg)+".yaml"))
+	if err == nil {
+		err = os.Remove(path)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		errs = append(errs, fmt.Errorf("failed to remove compose file: %w", err))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,74 @@
+package testcontainers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/moby/moby/api/types/container"
+	mobynetwork "github.com/moby/moby/api/types/network"
+	"github.com/testcontainers/testcontainers-go"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+)
+
+func TestRequestOptions(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cfg := config.Defaults()
+	cfg.IAMMode = "strict"
+	cfg.Docker.Project = "tc"
+	cfg.Docker.Network = "tc-net"
+	ports := docker.Ports{IAM: 18080, SecretManager: 19090, SecretManagerHTTP: 18081, KMS: 19091, KMSHTTP: 18082}
+	specs, err := docker.ServiceSpecs(cfg, ports, "/state/compose/tc.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(spec docker.ServiceSpec) testcontainers.GenericContainerRequest {
+		var req testcontainers.GenericContainerRequest
+		for _, opt := range requestOptions(cfg, spec, "tc-net") {
+			if err := opt.Customize(&req); err != nil {
+				t.Fatalf("Customize() error: %v", err)
+			}
+		}
+		return req
+	}
+
+	iam := request(specs[0])
+	var hc container.HostConfig
+	iam.HostConfigModifier(&hc)
+	if iam.Name != "tc-iam-1" || iam.Labels["com.docker.compose.project"] != "tc" || iam.Labels["com.docker.compose.service"] != "iam" {
+		t.Errorf("Expected compose's name and labels, got %s %v", iam.Name, iam.Labels)
+	}
+	if strings.Join(iam.Cmd, " ") != "./server --config /policy.yaml" || len(hc.Binds) != 1 || !strings.HasSuffix(hc.Binds[0], ":/policy.yaml:ro") {
+		t.Errorf("Expected the policy mounted and loaded, got %v %v", iam.Cmd, hc.Binds)
+	}
+	if got := hc.PortBindings[mobynetwork.MustParsePort("9080/tcp")]; len(got) != 1 || got[0].HostPort != "19080" {
+		t.Errorf("Expected the IAM HTTP port at IAM+1000, got %v", got)
+	}
+	if !strings.Contains(fmt.Sprint(iam.WaitingFor), `HTTP GET request on port 9080 path "/health"`) {
+		t.Errorf("Expected a wait on the health endpoint, got %v", iam.WaitingFor)
+	}
+
+	sm := request(specs[1])
+	if sm.Env["IAM_MODE"] != "strict" || sm.Env["IAM_HOST"] != "iam:8080" || len(sm.Cmd) != 0 {
+		t.Errorf("Expected the IAM mode and host, got %v %v", sm.Env, sm.Cmd)
+	}
+	if strings.Join(sm.Networks, ",") != "tc-net" || strings.Join(sm.NetworkAliases["tc-net"], ",") != "secret-manager" {
+		t.Errorf("Expected the shared network, got %v %v", sm.Networks, sm.NetworkAliases)
+	}
+	if strings.Join(sm.ExposedPorts, ",") != "9090/tcp,8080/tcp" {
+		t.Errorf("Unexpected exposed ports: %v", sm.ExposedPorts)
+	}
+}
+
+func TestRunNeedsPolicy(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if _, err := Run(t.Context(), WithMode("strict")); err == nil || !strings.Contains(err.Error(), "needs a policy file") {
+		t.Errorf("Expected a missing policy file error, got %v", err)
+	}
+	if _, err := Run(t.Context(), WithServices("nosuch")); err == nil {
+		t.Error("Expected an unknown service error")
+	}
+}
@@ -28,12 +28,18 @@ const (
 	testPrincipal     = "user:alice@example.com"
 )
 
-var cliBinary string
+// cliBinary is the CLI TestMain builds. It runs in rootDir, the
+// repository root, so start finds ./policy.yaml there
+var (
+	cliBinary string
+	rootDir   string
+)
 
 // TestMain builds the CLI and manages stack lifecycle
 func TestMain(m *testing.M) {
 	// Get absolute path to root directory
-	rootDir, err := filepath.Abs(filepath.Join("..", ".."))
+	var err error
+	rootDir, err = filepath.Abs(filepath.Join("..", ".."))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to get root directory: %v\n", err)
 		os.Exit(1)
@@ -62,6 +68,7 @@ func TestStackLifecycle(t *testing.T) {
 	// Start stack
 	t.Log("Starting stack...")
 	startCmd := exec.Command(cliBinary, "start", "--mode=permissive")
+	startCmd.Dir = rootDir
 	if output, err := startCmd.CombinedOutput(); err != nil {
 		t.Fatalf("Failed to start stack: %v\n%s", err, output)
 	}
@@ -72,6 +79,7 @@ func TestStackLifecycle(t *testing.T) {
 	// Check status
 	t.Log("Checking status...")
 	statusCmd := exec.Command(cliBinary, "status")
+	statusCmd.Dir = rootDir
 	if output, err := statusCmd.CombinedOutput(); err != nil {
 		t.Fatalf("Failed to check status: %v\n%s", err, output)
 	}
@@ -80,6 +88,7 @@ func TestStackLifecycle(t *testing.T) {
 	defer func() {
 		t.Log("Stopping stack...")
 		stopCmd := exec.Command(cliBinary, "stop")
+		stopCmd.Dir = rootDir
 		if output, err := stopCmd.CombinedOutput(); err != nil {
 			t.Errorf("Failed to stop stack: %v\n%s", err, output)
 		}